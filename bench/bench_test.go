@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+)
+
+// testSizes keeps the corpus of the go test benchmarks small enough to
+// generate for each of them; go run ./bench measures the large files.
+var testSizes = map[string]int64{"mp3": 1 << 20, "flac": 4 << 20, "mp4": 4 << 20}
+
+func BenchmarkView(b *testing.B)  { benchOp(b, "view") }
+func BenchmarkEdit(b *testing.B)  { benchOp(b, "edit") }
+func BenchmarkStrip(b *testing.B) { benchOp(b, "strip") }
+
+// benchOp runs op on every sample of a freshly generated corpus, one
+// sub-benchmark per sample.
+func benchOp(b *testing.B, op string) {
+	root := b.TempDir()
+	samples, err := generate(root, testSizes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, s := range samples {
+		s := s
+		b.Run(s.name, func(b *testing.B) {
+			h, err := batch.HandlerFor(s.path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			st, err := os.Stat(s.path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			fn, err := operation(op, h, s, filepath.Join(root, "out-"+filepath.Base(s.path)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.SetBytes(st.Size())
+			b.ResetTimer()
+			err = quiet(func() error {
+				for i := 0; i < b.N; i++ {
+					if err := fn(); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+// Command bench measures view, edit and strip throughput and allocations
+// over a corpus of synthetic files — small JPEGs and PNGs, an MP3, a
+// 100 MiB FLAC and an MP4 whose size is set on the command line — and,
+// optionally, over every supported file in a directory of real samples.
+// Results can be saved and compared against a later run, so that changes
+// such as streaming rewrites can be shown to help and regressions caught.
+//
+//	go run ./bench
+//	go run ./bench -mp4-size 4GiB -benchtime 3x
+//	go run ./bench -corpus ~/samples
+//	go run ./bench -save before.json
+//	go run ./bench -compare before.json -threshold 10
+//
+// The corpus is generated in a temporary directory (or -dir) and removed
+// afterwards unless -keep is given. Files over 4 GiB cannot be edited or
+// stripped yet; those operations are reported as errors, not measured.
+//
+// The same operations also run as standard benchmarks, on a smaller
+// corpus, for benchstat and the like:
+//
+//	go test -bench . -benchmem ./bench
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+)
+
+// result is one measured operation, as saved by -save.
+type result struct {
+	NsPerOp     int64   `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	MBPerSec    float64 `json:"mb_per_s"`
+}
+
+// sample is one corpus file and the field its edit benchmark sets.
+type sample struct {
+	name  string
+	path  string
+	field string
+}
+
+func main() {
+	testing.Init()
+	dir := flag.String("dir", "", "Where to generate the corpus (default: a temporary directory)")
+	keep := flag.Bool("keep", false, "Keep the generated corpus")
+	corpus := flag.String("corpus", "", "Also benchmark every supported file in this directory")
+	mp3Size := flag.String("mp3-size", "8MiB", "Size of the synthetic MP3")
+	flacSize := flag.String("flac-size", "100MiB", "Size of the synthetic FLAC")
+	mp4Size := flag.String("mp4-size", "64MiB", "Size of the synthetic MP4 (4GiB for the large-file case)")
+	ops := flag.String("ops", "view,edit,strip", "Operations to measure")
+	run := flag.String("run", "", "Only benchmark samples whose name contains this")
+	benchtime := flag.String("benchtime", "1s", "Run time per benchmark, or Nx for N iterations")
+	save := flag.String("save", "", "Write the results to this JSON file")
+	compare := flag.String("compare", "", "Compare with results saved earlier by -save")
+	threshold := flag.Float64("threshold", 10, "With -compare, the slowdown or allocation growth (%) that counts as a regression")
+	flag.Parse()
+
+	if err := flag.Set("test.benchtime", *benchtime); err != nil {
+		fail(fmt.Errorf("-benchtime: %w", err))
+	}
+	sizes := map[string]int64{}
+	for name, v := range map[string]string{"mp3": *mp3Size, "flac": *flacSize, "mp4": *mp4Size} {
+		n, err := parseSize(v)
+		if err != nil {
+			fail(fmt.Errorf("-%s-size: %w", name, err))
+		}
+		sizes[name] = n
+	}
+
+	root := *dir
+	if root == "" {
+		tmp, err := os.MkdirTemp("", "surgery-bench-")
+		if err != nil {
+			fail(err)
+		}
+		root = tmp
+	} else if err := os.MkdirAll(root, 0755); err != nil {
+		fail(err)
+	}
+	if !*keep {
+		cleanup = func() { os.RemoveAll(root) }
+		defer cleanup()
+	}
+
+	fmt.Fprintf(os.Stderr, "Generating corpus in %s …\n", root)
+	samples, err := generate(root, sizes)
+	if err != nil {
+		fail(err)
+	}
+	if *corpus != "" {
+		samples = append(samples, corpusSamples(*corpus)...)
+	}
+
+	results := make(map[string]result)
+	fmt.Printf("%-36s %12s %10s %12s %10s\n", "Benchmark", "ns/op", "MB/s", "B/op", "allocs/op")
+	fmt.Println(strings.Repeat("─", 84))
+	for _, s := range samples {
+		if *run != "" && !strings.Contains(s.name, *run) {
+			continue
+		}
+		h, err := batch.HandlerFor(s.path)
+		if err != nil {
+			fmt.Printf("%-36s %s\n", s.name, err)
+			continue
+		}
+		st, err := os.Stat(s.path)
+		if err != nil {
+			fail(err)
+		}
+		out := filepath.Join(root, "out-"+filepath.Base(s.path))
+		for _, op := range strings.Split(*ops, ",") {
+			op = strings.TrimSpace(op)
+			name := s.name + "/" + op
+			fn, err := operation(op, h, s, out)
+			if err != nil {
+				fail(err)
+			}
+			if err := quiet(fn); err != nil {
+				fmt.Printf("%-36s error: %v\n", name, err)
+				continue
+			}
+			r := measure(st.Size(), fn)
+			results[name] = r
+			fmt.Printf("%-36s %12d %10.1f %12d %10d\n", name, r.NsPerOp, r.MBPerSec, r.BytesPerOp, r.AllocsPerOp)
+		}
+		os.Remove(out)
+	}
+
+	if *save != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		if err := os.WriteFile(*save, append(data, '\n'), 0644); err != nil {
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Results saved → %s\n", *save)
+	}
+	if *compare != "" {
+		if regressions := compareWith(*compare, results, *threshold); regressions > 0 {
+			cleanup()
+			os.Exit(1)
+		}
+	}
+}
+
+// operation returns the function one benchmark iteration runs.
+func operation(op string, h core.Handler, s sample, out string) (func() error, error) {
+	switch op {
+	case "view":
+		return func() error { _, err := h.View(s.path); return err }, nil
+	case "edit":
+		opts := core.EditOptions{Set: map[string]string{s.field: "Benchmark"}}
+		return func() error { return h.Edit(s.path, out, opts) }, nil
+	case "strip":
+		opts := core.StripOptions{StripAll: true}
+		return func() error { return h.Strip(s.path, out, opts) }, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q (want view, edit or strip)", op)
+}
+
+// measure runs fn under testing.Benchmark with stdout silenced, since
+// handlers print notes as they work.
+func measure(size int64, fn func() error) result {
+	var r testing.BenchmarkResult
+	quiet(func() error {
+		r = testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				if err := fn(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		return nil
+	})
+	res := result{NsPerOp: r.NsPerOp(), BytesPerOp: r.AllocedBytesPerOp(), AllocsPerOp: r.AllocsPerOp()}
+	if r.T > 0 {
+		res.MBPerSec = float64(r.Bytes) * float64(r.N) / 1e6 / r.T.Seconds()
+	}
+	return res
+}
+
+// quiet runs fn with os.Stdout pointed at the null device.
+func quiet(fn func() error) error {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fn()
+	}
+	defer null.Close()
+	stdout := os.Stdout
+	os.Stdout = null
+	defer func() { os.Stdout = stdout }()
+	return fn()
+}
+
+// compareWith prints the change against a saved run and returns the
+// number of regressions beyond threshold percent.
+func compareWith(path string, now map[string]result, threshold float64) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fail(err)
+	}
+	var before map[string]result
+	if err := json.Unmarshal(data, &before); err != nil {
+		fail(fmt.Errorf("%s: %w", path, err))
+	}
+	names := make([]string, 0, len(now))
+	for n := range now {
+		if _, ok := before[n]; ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\n%-36s %10s %10s\n", "Compared with "+filepath.Base(path), "time", "allocs")
+	fmt.Println(strings.Repeat("─", 58))
+	regressions := 0
+	for _, n := range names {
+		dt := change(before[n].NsPerOp, now[n].NsPerOp)
+		da := change(before[n].AllocsPerOp, now[n].AllocsPerOp)
+		mark := ""
+		if dt > threshold || da > threshold {
+			mark = "  ✗ regression"
+			regressions++
+		}
+		fmt.Printf("%-36s %+9.1f%% %+9.1f%%%s\n", n, dt, da, mark)
+	}
+	fmt.Printf("\nCompared: %d  |  Regressions: %d\n", len(names), regressions)
+	return regressions
+}
+
+func change(before, now int64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return 100 * float64(now-before) / float64(before)
+}
+
+// corpusSamples lists the files of dir that have a handler.
+func corpusSamples(dir string) []sample {
+	var out []sample
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		id, err := core.DetectFormat(path)
+		if err != nil || id == core.FmtUnknown {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		out = append(out, sample{name: "corpus/" + rel, path: path, field: editField[id]})
+		return nil
+	})
+	return out
+}
+
+// editField is the field the edit benchmark sets in each format.
+var editField = map[core.FormatID]string{
+	core.FmtJPEG: "Artist",
+	core.FmtPNG:  "Title",
+	core.FmtMP3:  "Title",
+	core.FmtFLAC: "TITLE",
+	core.FmtMP4:  "title",
+	core.FmtMOV:  "title",
+	core.FmtPDF:  "Title",
+}
+
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// cleanup removes the generated corpus unless -keep is given. fail and
+// the exit on regressions call it, since os.Exit skips deferred calls.
+var cleanup = func() {}
+
+func fail(err error) {
+	cleanup()
+	core.PrintError(err.Error())
+	os.Exit(1)
+}
+
+// ─── Corpus generation ───────────────────────────────────────────────────────
+// The synthetic files are structurally valid — real headers, tags written
+// by this tool's own editors — with filler where the payload would be.
+
+func generate(dir string, sizes map[string]int64) ([]sample, error) {
+	var samples []sample
+	add := func(name, file string, id core.FormatID, write func(string) error, tags map[string]string) error {
+		path := filepath.Join(dir, file)
+		if err := write(path); err != nil {
+			return fmt.Errorf("generating %s: %w", file, err)
+		}
+		if len(tags) > 0 {
+			h, err := batch.HandlerFor(path)
+			if err != nil {
+				return err
+			}
+			if err := quiet(func() error { return h.Edit(path, "", core.EditOptions{Set: tags}) }); err != nil {
+				return fmt.Errorf("tagging %s: %w", file, err)
+			}
+		}
+		samples = append(samples, sample{name: name, path: path, field: editField[id]})
+		return nil
+	}
+
+	steps := []error{
+		add("jpeg-640x480", "small.jpg", core.FmtJPEG, writeJPEG, map[string]string{
+			"Make": "Bench", "Model": "Synthetic", "Artist": "surgery", "DateTimeOriginal": "2024:01:02 03:04:05",
+			"GPSLatitude": "48.8584", "GPSLongitude": "2.2945", "Keywords": "bench; synthetic",
+		}),
+		add("png-640x480", "small.png", core.FmtPNG, writePNG, map[string]string{
+			"Title": "Bench", "Author": "surgery", "Keywords": "bench; synthetic",
+		}),
+		add("mp3-"+sizeLabel(sizes["mp3"]), "audio.mp3", core.FmtMP3, func(p string) error { return writeMP3(p, sizes["mp3"]) },
+			map[string]string{"Title": "Bench", "Artist": "surgery", "Album": "Synthetic"}),
+		add("flac-"+sizeLabel(sizes["flac"]), "audio.flac", core.FmtFLAC, func(p string) error { return writeFLAC(p, sizes["flac"]) },
+			map[string]string{"TITLE": "Bench", "ARTIST": "surgery", "ALBUM": "Synthetic"}),
+		add("mp4-"+sizeLabel(sizes["mp4"]), "video.mp4", core.FmtMP4, func(p string) error { return writeMP4(p, sizes["mp4"]) }, nil),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return samples, nil
+}
+
+func sizeLabel(n int64) string {
+	return strings.ReplaceAll(core.FormatSize(n), " ", "")
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	seed := uint32(1)
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			seed = seed*1664525 + 1013904223
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(seed >> 24), 255})
+		}
+	}
+	return img
+}
+
+func writeJPEG(path string) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), &jpeg.Options{Quality: 90}); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func writePNG(path string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeFiller writes n bytes of a repeating pattern after each header.
+func writeFiller(w io.Writer, n int64, header []byte, unit int) error {
+	frame := make([]byte, unit)
+	copy(frame, header)
+	for i := len(header); i < unit; i++ {
+		frame[i] = byte(i * 7)
+	}
+	for ; n >= int64(unit); n -= int64(unit) {
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(make([]byte, n))
+	return err
+}
+
+// writeMP3 writes MPEG-1 Layer III frames (128 kbit/s, 44.1 kHz, 417
+// bytes each) up to size.
+func writeMP3(path string, size int64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFiller(f, size-size%417, []byte{0xFF, 0xFB, 0x90, 0x00}, 417)
+}
+
+// writeFLAC writes a STREAMINFO block and filler frames up to size.
+func writeFLAC(path string, size int64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	si := make([]byte, 34)
+	binary.BigEndian.PutUint16(si[0:], 4096) // min block size
+	binary.BigEndian.PutUint16(si[2:], 4096) // max block size
+	// sample rate (20 bits) 44100, channels-1 (3 bits) 1, bps-1 (5 bits) 15, total samples (36 bits)
+	samples := uint64(size / 4)
+	v := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36 | samples&(1<<36-1)
+	binary.BigEndian.PutUint64(si[10:], v)
+	header := []byte{'f', 'L', 'a', 'C', 0x80, 0, 0, 34} // last block, STREAMINFO, 34 bytes
+	if _, err := f.Write(append(header, si...)); err != nil {
+		return err
+	}
+	return writeFiller(f, size-int64(len(header)+len(si)), []byte{0xFF, 0xF8}, 4096)
+}
+
+// writeMP4 writes ftyp, a moov with an iTunes title, and an mdat that
+// brings the file to size (with a 64-bit header when it must).
+func writeMP4(path string, size int64) error {
+	box := func(typ string, body ...[]byte) []byte {
+		b := make([]byte, 8)
+		copy(b[4:], typ)
+		for _, p := range body {
+			b = append(b, p...)
+		}
+		binary.BigEndian.PutUint32(b, uint32(len(b)))
+		return b
+	}
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)       // timescale
+	binary.BigEndian.PutUint32(mvhd[16:], 60000)      // duration
+	binary.BigEndian.PutUint32(mvhd[20:], 0x00010000) // rate
+	binary.BigEndian.PutUint16(mvhd[24:], 0x0100)     // volume
+	for i, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		binary.BigEndian.PutUint32(mvhd[36+4*i:], v)
+	}
+	binary.BigEndian.PutUint32(mvhd[96:], 2) // next track ID
+	hdlr := append(make([]byte, 8), []byte("mdir")...)
+	hdlr = append(hdlr, make([]byte, 13)...)
+	data := append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, "Bench"...)
+	meta := box("meta", make([]byte, 4), box("hdlr", hdlr), box("ilst", box("\xa9nam", box("data", data))))
+	head := append(box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41")), box("moov", box("mvhd", mvhd), box("udta", meta))...)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(head); err != nil {
+		return err
+	}
+	payload := size - int64(len(head)) - 8
+	mdat := make([]byte, 8)
+	copy(mdat[4:], "mdat")
+	if payload+8 > 1<<32-1 {
+		payload -= 8
+		mdat = append(mdat, make([]byte, 8)...)
+		binary.BigEndian.PutUint32(mdat, 1)
+		binary.BigEndian.PutUint64(mdat[8:], uint64(payload+16))
+	} else {
+		binary.BigEndian.PutUint32(mdat, uint32(payload+8))
+	}
+	if _, err := f.Write(mdat); err != nil {
+		return err
+	}
+	return writeFiller(f, payload, nil, 1<<16)
+}
@@ -8,22 +8,46 @@
 //   view     View all metadata for a file
 //   edit     Add or update metadata fields
 //   strip    Remove metadata from a file
+//   optimize Drop padding (FLAC, ID3, XMP, MP4 free boxes) without touching metadata
+//   copy-tags Copy tags between audio files
+//   lyrics   Export or import lyrics (LRC ↔ SYLT)
+//   cover    Embed cover art
+//   analyze  Compute BPM/key/loudness with a plug-in analyzer, optionally tagging
 //   info     Show format detection and capabilities for a file
+//   validate Check metadata for problems
+//   scan     Look for structures used to hide data in uploads
+//   verify   Check payload checksums stored by edit --checksum
+//   export   Write metadata to a sidecar (Kodi/Jellyfin NFO)
+//   import   Write sidecar (NFO, Google Takeout JSON) values back into files
 //   formats  List all supported formats and their capabilities
 //   batch    Run view/strip/edit on all files in a directory
 //   version  Print version information
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	audpkg "github.com/ankit-chaubey/media-metadata-surgery/core/audio"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
 	docpkg "github.com/ankit-chaubey/media-metadata-surgery/core/document"
 	imgpkg "github.com/ankit-chaubey/media-metadata-surgery/core/image"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/selftest"
+	subpkg "github.com/ankit-chaubey/media-metadata-surgery/core/subtitle"
 	vidpkg "github.com/ankit-chaubey/media-metadata-surgery/core/video"
 
 	"github.com/ankit-chaubey/media-metadata-surgery/core"
@@ -40,6 +64,168 @@ type kvFlags []string
 func (k *kvFlags) String() string  { return strings.Join(*k, ", ") }
 func (k *kvFlags) Set(v string) error { *k = append(*k, v); return nil }
 
+// setFlagsOps splits --set flags into the fields to set and, in order,
+// the KEY+=VALUE values to add, which may repeat a key.
+func setFlagsOps(kvs kvFlags) (map[string]string, []core.KV) {
+	var set kvFlags
+	var add []core.KV
+	for _, kv := range kvs {
+		k, v, ok := core.ParseKV(kv)
+		if ok && strings.HasSuffix(k, "+") {
+			add = append(add, core.KV{Key: strings.TrimSpace(strings.TrimSuffix(k, "+")), Value: v})
+			continue
+		}
+		set = append(set, kv)
+	}
+	return kvMap("set", set), add
+}
+
+// kvMap parses repeated KEY=VALUE flags, exiting on a malformed one.
+// A trailing "+" on the key ("Comment+=extra") is dropped for --append;
+// --prefix and --suffix keep surrounding spaces in the value. The
+// conditional operations run once per field, so giving one of them the
+// same field twice is an error rather than a silent last-one-wins.
+func kvMap(flagName string, kvs kvFlags) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	m := map[string]string{}
+	seen := map[string]bool{}
+	for _, kv := range kvs {
+		k, v, ok := core.ParseKV(kv)
+		if !ok {
+			core.PrintError(fmt.Sprintf("invalid --%s value %q — expected KEY=VALUE", flagName, kv))
+			os.Exit(1)
+		}
+		switch flagName {
+		case "append":
+			k = strings.TrimSuffix(k, "+")
+		case "prefix", "suffix":
+			v = kv[strings.Index(kv, "=")+1:]
+		}
+		switch flagName {
+		case "set-if-missing", "append", "prefix", "suffix", "clear-if-equals":
+			if seen[strings.ToLower(k)] {
+				core.PrintError(fmt.Sprintf("--%s is given %q more than once — combine the values into one", flagName, k))
+				os.Exit(1)
+			}
+			seen[strings.ToLower(k)] = true
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// editOpFlags are the flags shared by edit and batch edit that shape the
+// written values: conditional sets, regex replace, transforms and the
+// Unicode normalization form.
+type editOpFlags struct {
+	ifMissing, appendText, prefix, suffix, clearIfEquals kvFlags
+	replace                                              kvFlags
+	titleCase, upper, lower, trim, collapse              kvFlags
+	normalize                                            string
+	unicode                                              string
+	padNumbers                                           int
+	deterministic                                        bool
+	vendor                                               string
+	sign                                                 string
+}
+
+func (f *editOpFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.ifMissing, "set-if-missing", "Set KEY=VALUE only if the field is absent or empty (repeatable)")
+	fs.Var(&f.appendText, "append", "Append to a field: KEY+=TEXT, joined with a space (repeatable)")
+	fs.Var(&f.prefix, "prefix", "Prepend TEXT to a non-empty field: KEY=TEXT (repeatable)")
+	fs.Var(&f.suffix, "suffix", "Append TEXT to a non-empty field: KEY=TEXT (repeatable)")
+	fs.Var(&f.clearIfEquals, "clear-if-equals", "Delete a field whose value is exactly VALUE: KEY=VALUE (repeatable)")
+	fs.Var(&f.replace, "replace", "Regex replace in a field: KEY:s/PATTERN/REPLACEMENT/[gi], KEY * = all (repeatable)")
+	fs.Var(&f.titleCase, "titlecase", "Title-case a field, or \"all\" (repeatable)")
+	fs.Var(&f.upper, "uppercase", "Upper-case a field, or \"all\" (repeatable)")
+	fs.Var(&f.lower, "lowercase", "Lower-case a field, or \"all\" (repeatable)")
+	fs.Var(&f.trim, "trim", "Trim surrounding whitespace from a field, or \"all\" (repeatable)")
+	fs.Var(&f.collapse, "collapse-spaces", "Collapse runs of whitespace in a field, or \"all\" (repeatable)")
+	fs.StringVar(&f.normalize, "normalize", "", "Apply a normalization profile from the config file")
+	fs.StringVar(&f.unicode, "unicode", "nfc", "Unicode normalization of written values: nfc, nfd or none")
+	fs.IntVar(&f.padNumbers, "pad-numbers", 0, "Zero-pad track/disc numbers and totals to N digits (also re-pads existing ones)")
+	fs.BoolVar(&f.deterministic, "deterministic", false, "Byte-identical output for identical input: sort tags, never stamp dates")
+	fs.StringVar(&f.vendor, "vendor", "", "Replace the FLAC vendor string (default: keep the encoder's)")
+	fs.StringVar(&f.sign, "sign", "", "Record TEXT as the producing software (EXIF/PNG Software, TSSE, ENCODER, ©too, Producer)")
+}
+
+// signed adds --sign to opts for the format of path, with a note when the
+// format has nowhere to record it.
+func (f *editOpFlags) signed(opts core.EditOptions, path string) core.EditOptions {
+	if f.sign == "" {
+		return opts
+	}
+	format, _ := core.DetectFormat(path)
+	signed, ok := core.Sign(opts, format, f.sign)
+	if !ok {
+		fmt.Printf("  Note: %s has no software field — --sign not recorded\n", path)
+	}
+	return signed
+}
+
+// apply adds the parsed operations to opts, exiting on a malformed flag.
+func (f *editOpFlags) apply(opts *core.EditOptions) {
+	opts.SetIfMissing = kvMap("set-if-missing", f.ifMissing)
+	opts.Append = kvMap("append", f.appendText)
+	opts.Prefix = kvMap("prefix", f.prefix)
+	opts.Suffix = kvMap("suffix", f.suffix)
+	opts.ClearIfEquals = kvMap("clear-if-equals", f.clearIfEquals)
+	form, err := core.ParseUnicodeForm(f.unicode)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	opts.UnicodeForm = form
+	opts.NumberPad = f.padNumbers
+	opts.Deterministic = f.deterministic
+	opts.Vendor = f.vendor
+	for _, spec := range f.replace {
+		r, err := core.ParseReplace(spec)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		opts.Replace = append(opts.Replace, r)
+	}
+
+	if f.normalize != "" {
+		cfg, err := core.LoadConfig()
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		profile, ok := cfg.Normalize[f.normalize]
+		if !ok {
+			core.PrintError(fmt.Sprintf("no normalization profile %q in %s", f.normalize, core.ConfigPath()))
+			os.Exit(1)
+		}
+		opts.Transforms = append(opts.Transforms, profile.Transforms()...)
+	}
+	opts.Transforms = append(opts.Transforms, core.NormalizeProfile{
+		TitleCase:      f.titleCase,
+		Uppercase:      f.upper,
+		Lowercase:      f.lower,
+		Trim:           f.trim,
+		CollapseSpaces: f.collapse,
+	}.Transforms()...)
+}
+
+// resolveEditOps reads the file's current metadata when opts holds
+// conditional operations and turns them into plain Set/Delete entries,
+// then normalizes the values to be written.
+func resolveEditOps(h core.Handler, path string, opts core.EditOptions) (core.EditOptions, error) {
+	if !opts.HasConditional() {
+		return core.NormalizeValues(opts), nil
+	}
+	m, err := h.View(path)
+	if err != nil {
+		return opts, fmt.Errorf("cannot read current values: %w", err)
+	}
+	return core.NormalizeValues(core.ResolveEditOps(opts, m)), nil
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 // main
 // ──────────────────────────────────────────────────────────────────────────────
@@ -53,6 +239,15 @@ func main() {
 	cmd := os.Args[1]
 	args := os.Args[2:]
 
+	if core.ReadOnlyEnabled() && !readOnlyCommand(cmd, args) {
+		name := cmd
+		if len(args) > 0 && readOnlySubcommands[cmd] != nil {
+			name += " " + args[0]
+		}
+		core.PrintError(fmt.Sprintf("read-only mode is on (SURGERY_READ_ONLY or read_only in %s): %q can write and is disabled", core.ConfigPath(), name))
+		os.Exit(1)
+	}
+
 	switch cmd {
 	case "view":
 		runView(args)
@@ -60,8 +255,30 @@ func main() {
 		runEdit(args)
 	case "strip":
 		runStrip(args)
+	case "optimize":
+		runOptimize(args)
+	case "copy-tags":
+		runCopyTags(args)
+	case "lyrics":
+		runLyrics(args)
+	case "cover":
+		runCover(args)
+	case "analyze":
+		runAnalyze(args)
 	case "info":
 		runInfo(args)
+	case "validate":
+		runValidate(args)
+	case "scan":
+		runScan(args)
+	case "verify":
+		runVerify(args)
+	case "selftest":
+		runSelftest(args)
+	case "export":
+		runExport(args)
+	case "import":
+		runImport(args)
 	case "formats":
 		runFormats(args)
 	case "batch":
@@ -77,6 +294,31 @@ func main() {
 	}
 }
 
+// readOnlyCommands are the commands allowed in read-only mode: none of
+// them opens a file for writing.
+var readOnlyCommands = map[string]bool{
+	"view": true, "info": true, "validate": true, "scan": true, "verify": true, "formats": true,
+	"version": true, "--version": true, "-v": true,
+	"help": true, "--help": true, "-h": true,
+}
+
+// readOnlySubcommands are the reading forms of commands that otherwise
+// write; batch view, for one, is what a forensic look at a folder needs.
+var readOnlySubcommands = map[string]map[string]bool{
+	"batch":  {"view": true},
+	"lyrics": {"get": true},
+	"cover":  {"list": true},
+}
+
+// readOnlyCommand reports whether cmd, with its arguments, is allowed in
+// read-only mode.
+func readOnlyCommand(cmd string, args []string) bool {
+	if readOnlyCommands[cmd] {
+		return true
+	}
+	return len(args) > 0 && readOnlySubcommands[cmd][args[0]]
+}
+
 func printUsage() {
 	fmt.Printf(`Media Metadata Surgery v%s
 
@@ -87,7 +329,18 @@ COMMANDS
   view      View all metadata embedded in a file
   edit      Add or update metadata fields in a file
   strip     Remove metadata from a file
+  optimize  Drop padding to shrink a file, leaving metadata and payload untouched
+  copy-tags Copy tags between audio files (ID3 ↔ Vorbis ↔ iTunes), incl. cover
+  lyrics    Export or import lyrics, converting LRC ↔ ID3 SYLT
+  cover     Embed cover art, resized and converted to fit player limits
+  analyze   Compute BPM, key and loudness with an external analyzer and tag them
   info      Show format detection and capabilities for a file
+  validate  Check metadata for problems such as mixed Unicode normalization
+  scan      Report hidden-data indicators: trailing data, duplicate EXIF, odd chunks
+  verify    Re-compute payload checksums stored by 'edit --checksum' and compare
+  selftest  Round-trip sample files (or copies of yours) and check nothing is damaged
+  export    Write a Kodi/Jellyfin .nfo sidecar from container metadata
+  import    Write .nfo or Google Takeout .json values back into files
   formats   List all supported formats and their capabilities
   batch     Run view/strip/edit on all files in a directory
   version   Print version information
@@ -99,6 +352,7 @@ QUICK EXAMPLES
   surgery edit --set "Title=Report 2024" document.docx
   surgery strip photo.jpg
   surgery strip --out clean.jpg --keep xmp photo.jpg
+  surgery copy-tags track.flac track.mp3
   surgery info video.mp4
   surgery formats --type image
   surgery batch view ./photos
@@ -115,9 +369,12 @@ Run 'surgery <command> --help' for command-specific help.
 func runView(args []string) {
 	fs := flag.NewFlagSet("view", flag.ExitOnError)
 	jsonOut := fs.Bool("json", false, "Output metadata as JSON")
-	verbose := fs.Bool("verbose", false, "Include raw/low-level fields")
+	verbose := fs.Bool("verbose", false, "Include raw/low-level fields and, for JPEG, encoding details and a messaging-app re-encode check")
+	untouched := fs.Bool("verify-untouched", false, "Hash the file before and after reading and fail if it changed")
+	mapLink := fs.Bool("map-link", false, "Add a Google Maps link to the photo's GPS position")
+	reportUnknown := fs.Bool("report-unknown", false, "List the metadata structures the parser skipped (APP segments, atoms, chunks, EBML IDs) on stderr")
 	fs.Usage = func() {
-		fmt.Println("Usage: surgery view [--json] [--verbose] <file>")
+		fmt.Println("Usage: surgery view [--json] [--verbose] [--verify-untouched] [--map-link] [--report-unknown] <file>")
 		fmt.Println()
 		fmt.Println("View all metadata embedded in a file.")
 		fmt.Println()
@@ -128,6 +385,9 @@ func runView(args []string) {
 		fmt.Println("  surgery view photo.jpg")
 		fmt.Println("  surgery view --json audio.mp3")
 		fmt.Println("  surgery view --verbose document.pdf")
+		fmt.Println("  surgery view --map-link photo.jpg")
+		fmt.Println("  surgery view --report-unknown clip.mov")
+		fmt.Println("  SURGERY_READ_ONLY=1 surgery view --verify-untouched evidence.jpg")
 	}
 	fs.Parse(args)
 
@@ -139,12 +399,140 @@ func runView(args []string) {
 	path := fs.Arg(0)
 	p := core.NewPrinter(*jsonOut, *verbose)
 
+	var before core.FileState
+	if *untouched {
+		before = snapshotOrExit(path)
+	}
 	m, err := viewFile(path)
 	if err != nil {
 		core.PrintError(err.Error())
 		os.Exit(1)
 	}
+	core.AddFSFields(m, path)
+	if *mapLink {
+		imgpkg.AddGPSMapLink(m)
+	}
+	if *verbose {
+		m.Fields = append(m.Fields, imgpkg.JPEGEncodingFields(path)...)
+		m.Fields = append(m.Fields, imgpkg.ReencodeFields(path)...)
+	}
 	p.PrintMetadata(m)
+	if *reportUnknown {
+		found, err := unknownStructures(path)
+		if err != nil {
+			core.PrintError(err.Error())
+		} else {
+			printUnknown(path, found)
+		}
+	}
+	if *untouched {
+		checkUntouched(path, before)
+	}
+}
+
+// snapshotOrExit records the state of path for --verify-untouched.
+func snapshotOrExit(path string) core.FileState {
+	s, err := core.Snapshot(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	return s
+}
+
+// checkUntouched compares path with its earlier state and exits with
+// status 2 if it changed. The result goes to stderr so that --json output
+// stays parseable.
+func checkUntouched(path string, before core.FileState) {
+	after := snapshotOrExit(path)
+	if !before.Equal(after) {
+		core.PrintError(fmt.Sprintf("%s changed while it was read (sha256 %s → %s, size %d → %d, modified %s → %s)",
+			path, before.SHA256, after.SHA256, before.Size, after.Size,
+			before.ModTime.Format(time.RFC3339Nano), after.ModTime.Format(time.RFC3339Nano)))
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Verified untouched: %s (sha256 %s)\n", path, after.SHA256)
+}
+
+// readState records the state of path before a command reads it, for
+// guardedWrite. It returns nil when skip is set (--dry-run, --force).
+func readState(path string, skip bool) *core.FileState {
+	if skip {
+		return nil
+	}
+	s := snapshotOrExit(path)
+	return &s
+}
+
+// guardedWrite runs write, an edit or strip of path saved to out, as op in
+// the audit log, after checking there is room for about need bytes and
+// backing up the file it replaces when backup is set.
+// With a state from readState the result goes through core.GuardedWrite and
+// is dropped if path changed since; with nil, write saves to out directly.
+// A failed write is undone by core.RecoverWrite.
+func guardedWrite(op string, h core.Handler, path, out string, need int64, backup *core.BackupOptions, before *core.FileState, write func(out string) error) error {
+	if err := core.CheckSpace(path, out, need, backup); err != nil {
+		return err
+	}
+	copyPath, err := backupBefore(path, out, backup)
+	if err != nil {
+		return err
+	}
+	return core.RecoverWrite(op, path, out, copyPath, func() error {
+		return core.AuditWrite(op, h, path, out, func() error {
+			if before == nil {
+				return write(core.ResolveOutPath(path, out))
+			}
+			return core.GuardedWrite(path, out, *before, write)
+		})
+	})
+}
+
+// keepAttrs gives a new output file written from path the permissions,
+// owner and extended attributes of path. Files edited in place keep their
+// own.
+func keepAttrs(path, out string) {
+	if out = core.ResolveOutPath(path, out); out != path {
+		core.CopyAttrs(path, out)
+	}
+}
+
+// stripFSAttrs removes the filesystem attributes (extended attributes,
+// Zone.Identifier) of the stripped copy of path, or lists them on a dry run.
+func stripFSAttrs(path, out string, dryRun bool) {
+	if dryRun {
+		fields := core.FSFields(path)
+		if len(fields) > 0 {
+			fmt.Printf("Dry-run: %d filesystem attribute(s) would be removed:\n", len(fields))
+			for _, f := range fields {
+				fmt.Printf("  - %s: %s\n", f.Key, f.Value)
+			}
+		}
+		return
+	}
+	out = core.ResolveOutPath(path, out)
+	removed, err := core.StripFSMetadata(out)
+	if len(removed) > 0 {
+		fmt.Printf("  Note: removed filesystem attributes from %s: %s\n", out, strings.Join(removed, ", "))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Note: %s\n", err)
+	}
+}
+
+// printWriteError reports a failed edit or strip, with a hint when another
+// program changed the file.
+func printWriteError(err error) {
+	var werr *core.WriteError
+	if errors.As(err, &werr) {
+		core.PrintError(werr.Err.Error())
+		fmt.Fprintf(os.Stderr, "  State: %s\n", werr.Recovery())
+	} else {
+		core.PrintError(err.Error())
+	}
+	if errors.Is(err, core.ErrConflict) {
+		fmt.Fprintln(os.Stderr, "  Note: another program changed the file; run again, or use --force to write anyway")
+	}
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -159,6 +547,15 @@ func runEdit(args []string) {
 	dryRun := fs.Bool("dry-run", false, "Preview changes without writing to disk")
 	fs.Var(&setFlags, "set", "Set a metadata field:  KEY=VALUE  (repeatable)")
 	fs.Var(&delFlags, "delete", "Delete a metadata field by key (repeatable)")
+	touchModified := fs.Bool("touch-modified-now", false, "Set the document's modified date to now (DOCX/XLSX/PPTX, PDF, EPUB)")
+	checksum := fs.String("checksum", "", "Also store a checksum of the audio/image payload: sha256 or md5 (MP3, FLAC, OGG, Opus, JPEG, PNG)")
+	force := fs.Bool("force", false, "Write even if the file changed after it was read")
+	var optFlags kvFlags
+	fs.Var(&optFlags, "option", optionUsage)
+	var ops editOpFlags
+	ops.register(fs)
+	var backup backupFlags
+	backup.register(fs)
 	fs.Usage = func() {
 		fmt.Println("Usage: surgery edit [flags] <file>")
 		fmt.Println()
@@ -172,21 +569,51 @@ func runEdit(args []string) {
 		fmt.Println(`  surgery edit --set "Title=Report" --delete Author document.pdf`)
 		fmt.Println(`  surgery edit --set "Make=Canon" --out out.jpg photo.jpg`)
 		fmt.Println(`  surgery edit --dry-run --set "Title=Test" video.mp4`)
+		fmt.Println(`  surgery edit --set "Created=2024-01-02" --touch-modified-now report.docx`)
+		fmt.Println(`  surgery edit --set-if-missing "Genre=Jazz" --clear-if-equals "Comment=Ripped by X" song.flac`)
+		fmt.Println(`  surgery edit --replace "Artist:s/ Feat\. / feat. /g" song.mp3`)
+		fmt.Println(`  surgery edit --checksum sha256 master.flac   # check later with 'surgery verify'`)
+		fmt.Println(`  surgery edit --backup --replace "Title:s/ \(Remastered\)//" *.flac`)
+		fmt.Println(`  surgery edit --option id3.version=2.3 song.mp3   # for players that only read ID3v2.3`)
+		fmt.Println(`  surgery edit --option pdf.incremental=true --set "Title=Final" signed.pdf`)
 		fmt.Println()
 		fmt.Println("Editable fields by format:")
-		fmt.Println("  JPEG/TIFF : Make, Model, Software, Artist, Copyright, ImageDescription,")
+		fmt.Println("  JPEG      : Make, Model, Software, Artist, Copyright, ImageDescription,")
 		fmt.Println("              UserComment, DateTime, DateTimeOriginal, DateTimeDigitized")
+		fmt.Println("              GPSLatitude, GPSLongitude (decimal degrees), GPSAltitude (m)")
+		fmt.Println("  TIFF/DNG  : Artist, Copyright, ImageDescription, xmp:prefix:name —")
+		fmt.Println("              overwritten in place, refused when the value does not fit")
+		fmt.Println("  HEIC/HEIF : the JPEG EXIF fields, Keywords and xmp:prefix:name")
 		fmt.Println("  PNG       : Title, Author, Description, Copyright, Comment,")
 		fmt.Println("              Creation Time, Source, Software")
+		fmt.Println("  JPEG/PNG  : Keywords, HierarchicalKeywords (XMP, \"a; b\", paths as \"Animals|Birds|Owl\")")
+		fmt.Println("              any XMP property as xmp:prefix:name, e.g. xmp:dc:creator, xmp:photoshop:City")
+		fmt.Println("  JPEG IPTC : Caption, Headline, Byline, Credit, Source, CopyrightNotice, City,")
+		fmt.Println("              Province, Country, … (iptc:Keywords for the IPTC keywords alone)")
 		fmt.Println("  MP3       : Title, Artist, Album, Year, Genre, Comment,")
 		fmt.Println("              TrackNumber, AlbumArtist, Composer, Lyrics, Copyright")
 		fmt.Println("  FLAC      : TITLE, ARTIST, ALBUM, DATE, GENRE, COMMENT,")
 		fmt.Println("              TRACKNUMBER, ALBUMARTIST, COMPOSER, COPYRIGHT")
+		fmt.Println("              (--set KEY+=VALUE adds a value, --delete KEY=VALUE removes one)")
+		fmt.Println("  MP3/FLAC  : TrackNumber and DiscNumber take \"3\" or \"3/12\"; TrackTotal and")
+		fmt.Println("              DiscTotal set the total alone (TRCK/TPOS vs. TRACKTOTAL/DISCTOTAL)")
+		fmt.Println("              MusicBrainzRecordingID, MusicBrainzAlbumID, MusicBrainzArtistID, …")
+		fmt.Println("              PodcastEpisode, PodcastSeason, PodcastGUID, PodcastChaptersURL")
 		fmt.Println("  MP4/MOV   : title, artist, album, comment, year, genre,")
 		fmt.Println("              description, copyright")
+		fmt.Println("              TVShowName, TVSeason, TVEpisode, TVEpisodeName,")
+		fmt.Println("              MediaKind (\"TV Show\", \"Movie\", … or the stik number)")
+		fmt.Println("              (MP4: com.apple.quicktime.* keys go to the mdta keys box)")
+		fmt.Println("              Track2.Title, Track2.Language (ISO 639-2 or BCP 47, e.g. fra, pt-BR) per track")
+		fmt.Println("              Track1.Rotation (0, 90, 180 or 270, clockwise) of a video track")
+		fmt.Println("  MKV/WebM  : Track2.Title, Track2.Language, rewritten in place")
 		fmt.Println("  PDF       : Title, Author, Subject, Keywords, Creator, Producer")
+		fmt.Println("              CreationDate, ModDate (2024-01-02, RFC 3339 or \"now\")")
 		fmt.Println("  DOCX/XLSX/PPTX: Title, Subject, Author, Keywords, Description,")
-		fmt.Println("              LastModifiedBy, Category")
+		fmt.Println("              LastModifiedBy, Category, Created, Modified")
+		fmt.Println("  EPUB      : Modified (dcterms:modified, EPUB 3)")
+		fmt.Println("  CBZ       : Title, Series, Number, Volume, Writer, Penciller,")
+		fmt.Println("              Publisher, Year, … (any ComicInfo.xml field)")
 	}
 	fs.Parse(args)
 
@@ -194,28 +621,27 @@ func runEdit(args []string) {
 		fs.Usage()
 		os.Exit(1)
 	}
-	if len(setFlags) == 0 && len(delFlags) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: provide at least one --set or --delete flag")
-		fmt.Fprintln(os.Stderr, "Run 'surgery edit --help' for usage.")
-		os.Exit(1)
-	}
-
 	path := fs.Arg(0)
 
-	setMap := map[string]string{}
-	for _, kv := range setFlags {
-		k, v, ok := core.ParseKV(kv)
-		if !ok {
-			core.PrintError(fmt.Sprintf("invalid --set value %q — expected KEY=VALUE", kv))
-			os.Exit(1)
-		}
-		setMap[k] = v
-	}
-
+	set, add := setFlagsOps(setFlags)
 	opts := core.EditOptions{
-		Set:    setMap,
-		Delete: []string(delFlags),
-		DryRun: *dryRun,
+		Set:           set,
+		Add:           add,
+		Delete:        []string(delFlags),
+		DryRun:        *dryRun,
+		TouchModified: *touchModified,
+		Backup:        backup.options(),
+		Options:       kvMap("option", optFlags),
+	}
+	ops.apply(&opts)
+	if opts.Deterministic && opts.TouchModified {
+		core.PrintError("--deterministic cannot be combined with --touch-modified-now")
+		os.Exit(1)
+	}
+	if !opts.HasChanges() && len(opts.Options) == 0 && *checksum == "" {
+		fmt.Fprintln(os.Stderr, "Error: provide at least one --set, --delete, conditional, --checksum, --option or --touch-modified-now flag")
+		fmt.Fprintln(os.Stderr, "Run 'surgery edit --help' for usage.")
+		os.Exit(1)
 	}
 
 	h, err := getHandler(path)
@@ -223,20 +649,56 @@ func runEdit(args []string) {
 		core.PrintError(err.Error())
 		os.Exit(1)
 	}
+	before := readState(path, *dryRun || *force)
 
 	info := h.Info()
 	if !info.CanEdit {
 		core.PrintError(fmt.Sprintf(
 			"%s does not support metadata editing in v%s\n"+
-				"Formats that support editing: JPEG, PNG, MP3, FLAC, MP4, PDF, DOCX, XLSX, PPTX",
+				"Formats that support editing: JPEG, PNG, TIFF/DNG, HEIC, MP3, FLAC, OGG, Opus, M4A, MP4, MKV, WebM, PDF, DOCX, XLSX, PPTX, EPUB, CBZ",
 			info.Name, Version))
 		os.Exit(1)
 	}
+	checkFormatOptions(opts.Options, false, info)
+	if err := core.CheckAdd(path, opts); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
 
-	if err := h.Edit(path, *outPath, opts); err != nil {
+	opts, err = resolveEditOps(h, path, opts)
+	if err != nil {
 		core.PrintError(err.Error())
 		os.Exit(1)
 	}
+	if *checksum != "" {
+		field, sum, err := core.PayloadChecksum(h, path, *checksum)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		if opts.Set == nil {
+			opts.Set = map[string]string{}
+		}
+		opts.Set[field] = sum
+	}
+	if !opts.HasChanges() && len(opts.Options) == 0 {
+		fmt.Println("No changes: the current values already satisfy every condition")
+		return
+	}
+	opts = ops.signed(opts, path)
+
+	if *dryRun {
+		err = core.Audited(h).Edit(path, *outPath, opts)
+	} else {
+		catchInterrupts()
+		err = guardedWrite("edit", h, path, *outPath, core.EditEstimate(path, opts), opts.Backup, before, func(out string) error {
+			return h.Edit(path, out, opts)
+		})
+	}
+	if err != nil {
+		printWriteError(err)
+		os.Exit(1)
+	}
 
 	if !*dryRun {
 		out := core.ResolveOutPath(path, *outPath)
@@ -246,6 +708,7 @@ func runEdit(args []string) {
 			fmt.Printf("✓ Metadata updated → %s\n", out)
 		}
 	}
+	exitIfInterrupted()
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -257,8 +720,19 @@ func runStrip(args []string) {
 	outPath := fs.String("out", "", "Output file path (default: strip in-place)")
 	dryRun := fs.Bool("dry-run", false, "Preview without writing to disk")
 	gpsOnly := fs.Bool("gps-only", false, "Remove only GPS location fields (keep rest)")
+	regionsOnly := fs.Bool("regions-only", false, "Remove only XMP face regions — people's names and rectangles (JPEG, PNG, HEIC)")
 	var keepFlags kvFlags
-	fs.Var(&keepFlags, "keep", "Keep a metadata section (repeatable): exif, xmp, iptc, id3")
+	var removeFlags kvFlags
+	fs.Var(&keepFlags, "keep", "Keep a metadata section (repeatable): exif, xmp, iptc, id3, front-cover")
+	fs.Var(&removeFlags, "remove", "Also remove a structure kept by default (repeatable): cuesheet, application, notes, comments, filesystem")
+	privacyFlag := fs.Bool("privacy-flag", false, "Also turn on the document's \"remove personal information on save\" setting (XLSX, DOCX)")
+	vendor := fs.String("vendor", "", "Replace the FLAC vendor string (default: keep the encoder's)")
+	sign := fs.String("sign", "", "After stripping, record TEXT as the producing software")
+	force := fs.Bool("force", false, "Write even if the file changed after it was read")
+	var optFlags kvFlags
+	fs.Var(&optFlags, "option", optionUsage)
+	var backup backupFlags
+	backup.register(fs)
 	fs.Usage = func() {
 		fmt.Println("Usage: surgery strip [flags] <file>")
 		fmt.Println()
@@ -272,9 +746,16 @@ func runStrip(args []string) {
 		fmt.Println("  surgery strip --out clean.jpg photo.jpg")
 		fmt.Println("  surgery strip --keep exif photo.jpg        # remove XMP+IPTC, keep EXIF")
 		fmt.Println("  surgery strip --gps-only photo.jpg         # remove GPS only")
+		fmt.Println("  surgery strip --regions-only photo.jpg     # remove face regions only")
 		fmt.Println("  surgery strip --dry-run audio.mp3")
+		fmt.Println("  surgery strip --remove cuesheet album.flac  # also drop the CUESHEET block")
+		fmt.Println("  surgery strip --privacy-flag budget.xlsx   # also set Excel's privacy option")
+		fmt.Println("  surgery strip --gps-only --remove filesystem download.jpg  # and its Zone.Identifier")
+		fmt.Println("  surgery strip --backup-dir ~/originals photo.jpg  # keep the original elsewhere")
+		fmt.Println("  surgery strip --option jpeg.keep-icc=true photo.jpg  # keep the colour profile")
 		fmt.Println()
-		fmt.Println("Formats that support strip: JPEG, PNG, GIF, WebP, MP3, FLAC, WAV, MP4, MOV, PDF, DOCX, XLSX, PPTX")
+		fmt.Println("Formats that support strip: JPEG, PNG, GIF, WebP, HEIC, MP3, FLAC, OGG, Opus, M4A, WAV, MP4, MOV, MKV, WebM, PDF, DOCX, XLSX, PPTX")
+		fmt.Println("--gps-only also works on TIFF and DNG, in place; formats without GPS are left as they are.")
 	}
 	fs.Parse(args)
 
@@ -285,10 +766,27 @@ func runStrip(args []string) {
 
 	path := fs.Arg(0)
 
+	// Filesystem metadata is removed here, not by the handler.
+	var sections []string
+	stripFS := false
+	for _, sec := range removeFlags {
+		if strings.EqualFold(sec, "filesystem") {
+			stripFS = true
+		} else {
+			sections = append(sections, sec)
+		}
+	}
 	opts := core.StripOptions{
-		KeepFields: []string(keepFlags),
-		StripGPS:   *gpsOnly,
-		StripAll:   len(keepFlags) == 0 && !*gpsOnly,
+		KeepFields:     []string(keepFlags),
+		StripGPS:       *gpsOnly,
+		StripRegions:   *regionsOnly,
+		StripAll:       len(keepFlags) == 0 && !*gpsOnly && !*regionsOnly,
+		RemoveSections: sections,
+		PrivacyFlag:    *privacyFlag,
+		Vendor:         *vendor,
+		DryRun:         *dryRun,
+		Backup:         backup.options(),
+		Options:        kvMap("option", optFlags),
 	}
 
 	h, err := getHandler(path)
@@ -298,16 +796,43 @@ func runStrip(args []string) {
 	}
 
 	info := h.Info()
-	if !info.CanStrip {
+	if *gpsOnly && !info.StripsGPS {
+		fmt.Printf("  Note: %s holds no GPS location surgery can strip — %s left as it is\n", info.Name, path)
+		return
+	}
+	if !info.CanStrip && !*gpsOnly {
 		core.PrintError(fmt.Sprintf(
 			"%s does not support metadata stripping in v%s", info.Name, Version))
 		os.Exit(1)
 	}
+	checkFormatOptions(opts.Options, true, info)
 
-	if err := h.Strip(path, *outPath, opts); err != nil {
-		core.PrintError(err.Error())
+	if *dryRun {
+		err = core.Audited(h).Strip(path, *outPath, opts)
+	} else {
+		catchInterrupts()
+		err = guardedWrite("strip", h, path, *outPath, core.StripEstimate(path), opts.Backup, readState(path, *force), func(out string) error {
+			return h.Strip(path, out, opts)
+		})
+	}
+	if err != nil {
+		printWriteError(err)
 		os.Exit(1)
 	}
+	if *sign != "" && !*dryRun {
+		out := core.ResolveOutPath(path, *outPath)
+		format, _ := core.DetectFormat(out)
+		if signOpts, ok := core.Sign(core.EditOptions{}, format, *sign); !ok || !info.CanEdit {
+			fmt.Printf("  Note: %s has no software field — --sign not recorded\n", out)
+		} else if err := core.Audited(h).Edit(out, "", signOpts); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if opts.StripAll || stripFS {
+		stripFSAttrs(path, *outPath, *dryRun)
+	}
 
 	if !*dryRun {
 		out := core.ResolveOutPath(path, *outPath)
@@ -317,23 +842,31 @@ func runStrip(args []string) {
 			fmt.Printf("✓ Stripped → %s\n", out)
 		}
 	}
+	exitIfInterrupted()
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
-// info
+// optimize
 // ──────────────────────────────────────────────────────────────────────────────
 
-func runInfo(args []string) {
-	fs := flag.NewFlagSet("info", flag.ExitOnError)
-	jsonOut := fs.Bool("json", false, "Output as JSON")
+func runOptimize(args []string) {
+	fs := flag.NewFlagSet("optimize", flag.ExitOnError)
+	outPath := fs.String("out", "", "Output file path (default: optimize in-place)")
+	dryRun := fs.Bool("dry-run", false, "Report the bytes that would be saved without writing")
 	fs.Usage = func() {
-		fmt.Println("Usage: surgery info [--json] <file>")
+		fmt.Println("Usage: surgery optimize [--out <file>] [--dry-run] <file>")
 		fmt.Println()
-		fmt.Println("Show format detection result and capabilities for a file.")
+		fmt.Println("Remove the padding writers leave for later edits: FLAC PADDING blocks,")
+		fmt.Println("ID3v2 tag padding (MP3), XMP packet padding (JPEG, PNG), free and skip")
+		fmt.Println("boxes (MP4, MOV). Fields and payload are left exactly as they were and")
+		fmt.Println("checked before the file is written; use strip to remove metadata.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
 		fmt.Println()
 		fmt.Println("Examples:")
-		fmt.Println("  surgery info photo.jpg")
-		fmt.Println("  surgery info --json audio.mp3")
+		fmt.Println("  surgery optimize album/01.flac")
+		fmt.Println("  surgery optimize --dry-run video.mp4")
 	}
 	fs.Parse(args)
 
@@ -341,91 +874,1438 @@ func runInfo(args []string) {
 		fs.Usage()
 		os.Exit(1)
 	}
-
 	path := fs.Arg(0)
-	fmtID, err := core.DetectFormat(path)
+
+	h, err := getHandler(path)
 	if err != nil {
 		core.PrintError(err.Error())
 		os.Exit(1)
 	}
-
-	h, err := getHandler(path)
+	before, err := h.View(path)
 	if err != nil {
 		core.PrintError(err.Error())
 		os.Exit(1)
 	}
+	payload := readPayload(h, path)
+	state := readState(path, *dryRun)
 
-	info := h.Info()
+	saved, err := core.Optimize(h, path, *outPath, true)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if saved == 0 {
+		fmt.Printf("Nothing to optimize: %s has no padding\n", path)
+		return
+	}
+	if *dryRun {
+		fmt.Printf("Dry-run: would save %s (%d bytes)\n", core.FormatSize(saved), saved)
+		return
+	}
 
-	if *jsonOut {
-		fmt.Printf("{\n")
-		fmt.Printf("  \"file\": %q,\n", path)
-		fmt.Printf("  \"format_id\": %q,\n", fmtID)
-		fmt.Printf("  \"name\": %q,\n", info.Name)
-		fmt.Printf("  \"media_type\": %q,\n", info.MediaType)
-		fmt.Printf("  \"extensions\": %q,\n", strings.Join(info.Extensions, ", "))
-		fmt.Printf("  \"mime_types\": %q,\n", strings.Join(info.MIMETypes, ", "))
-		fmt.Printf("  \"can_view\": %v,\n", info.CanView)
-		fmt.Printf("  \"can_edit\": %v,\n", info.CanEdit)
-		fmt.Printf("  \"can_strip\": %v,\n", info.CanStrip)
-		fmt.Printf("  \"editable_fields\": %q,\n", strings.Join(info.EditableFields, ", "))
-		fmt.Printf("  \"notes\": %q\n", info.Notes)
-		fmt.Printf("}\n")
-	} else {
-		fmt.Printf("File            : %s\n", path)
-		fmt.Printf("Detected Format : %s  (id: %s)\n", info.Name, fmtID)
-		fmt.Printf("Media Type      : %s\n", info.MediaType)
-		fmt.Printf("Extensions      : %s\n", strings.Join(info.Extensions, ", "))
-		fmt.Printf("MIME Types      : %s\n", strings.Join(info.MIMETypes, ", "))
-		fmt.Printf("Can View        : %v\n", info.CanView)
-		fmt.Printf("Can Edit        : %v\n", info.CanEdit)
-		fmt.Printf("Can Strip       : %v\n", info.CanStrip)
-		if len(info.EditableFields) > 0 {
-			fmt.Printf("Editable Fields : %s\n", strings.Join(info.EditableFields, ", "))
+	// The result is checked in its temporary file, so a file whose fields
+	// or payload came out different is never written.
+	out := core.ResolveOutPath(path, *outPath)
+	err = guardedWrite("optimize", h, path, *outPath, core.StripEstimate(path), nil, state, func(tmp string) error {
+		if saved, err = core.Optimize(h, path, tmp, false); err != nil {
+			return err
 		}
-		if info.Notes != "" {
-			fmt.Printf("Notes           : %s\n", info.Notes)
+		after, err := h.View(tmp)
+		if err != nil {
+			return err
 		}
+		if !sameFields(before, after) {
+			return fmt.Errorf("%s: metadata fields changed while optimizing — please report this; nothing was written", out)
+		}
+		if payload != nil && !bytes.Equal(payload, readPayload(h, tmp)) {
+			return fmt.Errorf("%s: payload changed while optimizing — please report this; nothing was written", out)
+		}
+		return nil
+	})
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
 	}
+	fmt.Printf("✓ Optimized → %s (saved %s)\n", out, core.FormatSize(saved))
 }
 
-// ──────────────────────────────────────────────────────────────────────────────
-// formats
-// ──────────────────────────────────────────────────────────────────────────────
+// readPayload returns the payload of path, or nil when the handler has no
+// notion of one.
+func readPayload(h core.Handler, path string) []byte {
+	pr, ok := h.(core.PayloadReader)
+	if !ok {
+		return nil
+	}
+	p, err := pr.Payload(path)
+	if err != nil {
+		return nil
+	}
+	return p
+}
 
-type namedFormatInfo struct {
-	id core.FormatID
-	core.FormatInfo
+// sameFields reports whether two views of a file list the same fields
+// with the same values. Order is not compared: EXIF fields, among others,
+// come out in map order.
+func sameFields(a, b *core.Metadata) bool {
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	count := make(map[[2]string]int)
+	for _, f := range a.Fields {
+		count[[2]string{f.Key, f.Value}]++
+	}
+	for _, f := range b.Fields {
+		k := [2]string{f.Key, f.Value}
+		if count[k] == 0 {
+			return false
+		}
+		count[k]--
+	}
+	return true
 }
 
-func runFormats(args []string) {
-	fs := flag.NewFlagSet("formats", flag.ExitOnError)
-	mediaType := fs.String("type", "", "Filter by media type: image|audio|video|document")
+// ──────────────────────────────────────────────────────────────────────────────
+// copy-tags
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runCopyTags(args []string) {
+	fs := flag.NewFlagSet("copy-tags", flag.ExitOnError)
+	outPath := fs.String("out", "", "Output file path (default: update the target in-place)")
+	dryRun := fs.Bool("dry-run", false, "Show the field mapping without writing to disk")
+	noCover := fs.Bool("no-cover", false, "Do not copy the front cover")
+	var cover coverFlags
+	cover.register(fs)
 	fs.Usage = func() {
-		fmt.Println("Usage: surgery formats [--type image|audio|video|document]")
+		fmt.Println("Usage: surgery copy-tags [flags] <source> <target>")
 		fmt.Println()
-		fmt.Println("List all supported formats and their capabilities.")
+		fmt.Println("Copy tags from one audio file to another, translating field names")
+		fmt.Println("between ID3v2 frames, Vorbis comments and iTunes atoms.")
+		fmt.Println("Sources: MP3, FLAC, OGG, M4A. Targets: MP3, FLAC.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery copy-tags track.flac track.mp3")
+		fmt.Println("  surgery copy-tags --dry-run track.m4a track.flac")
+		fmt.Println("  surgery copy-tags --no-cover --out tagged.flac track.mp3 track.flac")
 	}
 	fs.Parse(args)
 
-	all := getAllFormatInfos()
-
-	fmt.Printf("\n%-12s %-22s %-10s  %-5s %-5s %-5s  %s\n",
-		"Format ID", "Name", "Type", "View", "Edit", "Strip", "Extensions")
-	fmt.Println(strings.Repeat("─", 82))
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	src, dst := fs.Arg(0), fs.Arg(1)
 
-	viewCount, editCount, stripCount := 0, 0, 0
-	total := 0
+	opts := audpkg.CopyOptions{NoCover: *noCover, DryRun: *dryRun, Cover: cover.options()}
+	if err := auditWrite("copy-tags", dst, *outPath, *dryRun, func() error { return audpkg.CopyTags(src, dst, *outPath, opts) }); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
 
-	for _, f := range all {
-		if *mediaType != "" && f.MediaType != *mediaType {
-			continue
+	if !*dryRun {
+		out := core.ResolveOutPath(dst, *outPath)
+		if out == dst {
+			fmt.Printf("✓ Tags copied in-place: %s\n", dst)
+		} else {
+			fmt.Printf("✓ Tags copied → %s\n", out)
 		}
-		total++
-		v := tick(f.CanView)
-		e := tick(f.CanEdit)
-		s := tick(f.CanStrip)
-		if f.CanView { viewCount++ }
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// analyze
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runAnalyze(args []string) {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	cmdFlag := fs.String("cmd", "", "Analyzer command, run with the file path as its last argument")
+	name := fs.String("analyzer", "", "Use an analyzer registered by name")
+	setTags := fs.Bool("set-tags", false, "Write the results into the file's tags (MP3, FLAC)")
+	dryRun := fs.Bool("dry-run", false, "With --set-tags, show the tags without writing")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery analyze (--cmd CMD | --analyzer NAME) [--set-tags] [--dry-run] <file> [<file> ...]")
+		fmt.Println()
+		fmt.Println("Compute BPM, musical key and loudness with an analyzer and, with")
+		fmt.Println("--set-tags, write them as TBPM/TKEY (ID3) or BPM/INITIALKEY (Vorbis)")
+		fmt.Println("and a ReplayGain track gain. The command prints KEY=VALUE lines or a")
+		fmt.Println("JSON object with any of: bpm, key, loudness (LUFS), replaygain_track_gain,")
+		fmt.Println("replaygain_track_peak, replaygain_album_gain, replaygain_album_peak.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery analyze --cmd ./bpm-and-loudness.sh song.flac")
+		fmt.Println("  surgery analyze --cmd ./bpm-and-loudness.sh --set-tags *.mp3")
+		if names := audpkg.AnalyzerNames(); len(names) > 0 {
+			fmt.Println()
+			fmt.Printf("Registered analyzers: %s\n", strings.Join(names, ", "))
+		}
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || (*cmdFlag == "") == (*name == "") {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var a audpkg.Analyzer = audpkg.CommandAnalyzer{Command: *cmdFlag}
+	if *name != "" {
+		var ok bool
+		if a, ok = audpkg.LookupAnalyzer(*name); !ok {
+			core.PrintError(fmt.Sprintf("no analyzer registered as %q", *name))
+			os.Exit(1)
+		}
+	}
+
+	analyzed, errors := 0, 0
+	for _, path := range fs.Args() {
+		h, err := getHandler(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			errors++
+			continue
+		}
+		format, _ := core.DetectFormat(path)
+		fields, err := audpkg.AnalysisFields(path, a, format)
+		if err != nil {
+			core.PrintError(fmt.Sprintf("%s: %v", path, err))
+			errors++
+			continue
+		}
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Println(path)
+		for _, k := range keys {
+			fmt.Printf("  %-24s %s\n", k+":", fields[k])
+		}
+		if *setTags && len(fields) > 0 {
+			if !h.Info().CanEdit {
+				core.PrintError(fmt.Sprintf("%s: %s does not support metadata editing", path, h.Info().Name))
+				errors++
+				continue
+			}
+			if err := core.Audited(h).Edit(path, "", core.EditOptions{Set: fields, DryRun: *dryRun}); err != nil {
+				core.PrintError(fmt.Sprintf("%s: %v", path, err))
+				errors++
+				continue
+			}
+			if !*dryRun {
+				fmt.Printf("✓ Tags written: %s\n", path)
+			}
+		}
+		analyzed++
+	}
+	fmt.Printf("\nAnalyzed: %d  |  Errors: %d\n", analyzed, errors)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// lyrics
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runLyrics(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: surgery lyrics <get|set> [flags] <file>")
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery lyrics get song.mp3 > song.lrc")
+		fmt.Println("  surgery lyrics get --plain song.flac")
+		fmt.Println("  surgery lyrics set --from song.lrc song.flac")
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "get":
+		runLyricsGet(args[1:])
+	case "set":
+		runLyricsSet(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown lyrics sub-command: %s\n", args[0])
+		fmt.Println("Valid sub-commands: get, set")
+		os.Exit(1)
+	}
+}
+
+func runLyricsGet(args []string) {
+	fs := flag.NewFlagSet("lyrics get", flag.ExitOnError)
+	plain := fs.Bool("plain", false, "Print the text only, without LRC timestamps")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery lyrics get [--plain] <file>")
+		fmt.Println()
+		fmt.Println("Print the lyrics of an MP3, FLAC, M4A, OGG or Opus file. Synchronised")
+		fmt.Println("lyrics (ID3 SYLT, or LRC text in a lyrics tag) are printed as LRC.")
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	text, err := audpkg.GetLyrics(fs.Arg(0), *plain)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	fmt.Print(text)
+}
+
+func runLyricsSet(args []string) {
+	fs := flag.NewFlagSet("lyrics set", flag.ExitOnError)
+	from := fs.String("from", "", "Read lyrics from this file (.lrc or plain text, \"-\" for stdin)")
+	outPath := fs.String("out", "", "Output file path (default: update in-place)")
+	dryRun := fs.Bool("dry-run", false, "Preview without writing to disk")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery lyrics set --from <file> [flags] <file>")
+		fmt.Println()
+		fmt.Println("Embed lyrics in an MP3, FLAC, OGG or Opus file. LRC input is written to")
+		fmt.Println("MP3 as a SYLT frame plus a plain USLT copy, and to the others as LRC")
+		fmt.Println("text in LYRICS.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || *from == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var data []byte
+	var err error
+	if *from == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*from)
+	}
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	path := fs.Arg(0)
+	if err := auditWrite("lyrics", path, *outPath, *dryRun, func() error { return audpkg.SetLyrics(path, *outPath, string(data), *dryRun) }); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if !*dryRun {
+		out := core.ResolveOutPath(path, *outPath)
+		if out == path {
+			fmt.Printf("✓ Lyrics updated in-place: %s\n", path)
+		} else {
+			fmt.Printf("✓ Lyrics updated → %s\n", out)
+		}
+	}
+}
+
+// backupFlags are the flags of the commands that replace files asking for
+// a copy of each original first.
+type backupFlags struct {
+	on          bool
+	suffix, dir string
+}
+
+func (f *backupFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.on, "backup", false, "Copy each file to <name>.bak before replacing it")
+	fs.StringVar(&f.suffix, "backup-suffix", "", "Suffix of backup copies (default \".bak\"; implies --backup)")
+	fs.StringVar(&f.dir, "backup-dir", "", "Put backup copies in this directory (implies --backup)")
+}
+
+// options returns the backup options, or nil when no backup is asked for.
+func (f *backupFlags) options() *core.BackupOptions {
+	if !f.on && f.suffix == "" && f.dir == "" {
+		return nil
+	}
+	return &core.BackupOptions{Suffix: f.suffix, Dir: f.dir}
+}
+
+// backupBefore backs up the file that writing path to out would replace,
+// saying where the copy went, and returns its path.
+func backupBefore(path, out string, b *core.BackupOptions) (string, error) {
+	p, err := core.BackupBefore(path, out, b)
+	if p != "" {
+		fmt.Printf("  Backup: %s\n", p)
+	}
+	return p, err
+}
+
+// optionUsage is the help text of --option.
+const optionUsage = "Format-specific option KEY=VALUE (repeatable), e.g. id3.version=2.3; 'surgery formats' lists them"
+
+// checkFormatOptions exits when opts holds an --option key or value that
+// none of infos accepts.
+func checkFormatOptions(opts map[string]string, strip bool, infos ...core.FormatInfo) {
+	if err := core.CheckOptions(opts, strip, infos...); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+}
+
+// allFormatInfos returns the FormatInfo of every handler, for checking
+// the --option flags of batch commands.
+func allFormatInfos() []core.FormatInfo {
+	var infos []core.FormatInfo
+	for _, f := range getAllFormatInfos() {
+		infos = append(infos, f.FormatInfo)
+	}
+	return infos
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// cover
+// ──────────────────────────────────────────────────────────────────────────────
+
+// coverFlags are the cover-art limits shared by cover set and copy-tags.
+type coverFlags struct {
+	maxDim, minDim, quality int
+	maxBytes                string
+	jpeg                    bool
+}
+
+func (f *coverFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.maxDim, "max-dim", 0, "Downscale the cover so its longest edge is at most N px")
+	fs.IntVar(&f.minDim, "min-dim", 0, "Refuse covers whose shortest edge is below N px")
+	fs.StringVar(&f.maxBytes, "max-bytes", "", "Re-encode the cover as JPEG until it fits, e.g. 500K or 2M")
+	fs.BoolVar(&f.jpeg, "jpeg", false, "Always store the cover as JPEG")
+	fs.IntVar(&f.quality, "quality", 90, "JPEG quality when re-encoding the cover")
+}
+
+func (f *coverFlags) options() audpkg.CoverOptions {
+	maxBytes, err := parseByteSize(f.maxBytes)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	return audpkg.CoverOptions{MaxDim: f.maxDim, MinDim: f.minDim, MaxBytes: maxBytes, JPEG: f.jpeg, Quality: f.quality}
+}
+
+// parseByteSize parses "500000", "500K" or "2M" (binary multiples).
+func parseByteSize(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mult := 1
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		mult, s = 1024, s[:len(s)-1]
+	case "M":
+		mult, s = 1024*1024, s[:len(s)-1]
+	}
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func runCover(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: surgery cover <set|list|extract|remove> [flags] <file>")
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery cover set --from cover.png song.mp3")
+		fmt.Println("  surgery cover set --from scan.png --max-dim 1000 --max-bytes 500K song.flac")
+		fmt.Println("  surgery cover list song.flac")
+		fmt.Println("  surgery cover extract --type back --out back.jpg song.flac")
+		fmt.Println("  surgery cover remove --type non-front song.mp3")
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "set":
+		runCoverSet(args[1:])
+	case "list":
+		runCoverList(args[1:])
+	case "extract":
+		runCoverExtract(args[1:])
+	case "remove":
+		runCoverRemove(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cover sub-command: %s\n", args[0])
+		fmt.Println("Valid sub-commands: set, list, extract, remove")
+		os.Exit(1)
+	}
+}
+
+func runCoverSet(args []string) {
+	fs := flag.NewFlagSet("cover set", flag.ExitOnError)
+	from := fs.String("from", "", "Image to embed (JPEG or PNG)")
+	outPath := fs.String("out", "", "Output file path (default: update in-place)")
+	dryRun := fs.Bool("dry-run", false, "Show the prepared cover without writing")
+	var cover coverFlags
+	cover.register(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery cover set --from <image> [flags] <file>")
+		fmt.Println()
+		fmt.Println("Embed an image as the front cover of an MP3, FLAC or M4A file, replacing")
+		fmt.Println("the existing front cover. Other pictures are kept.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || *from == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*from)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	c, err := audpkg.PrepareCover(data, cover.options())
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if c.Width != c.Height {
+		fmt.Printf("  Note: cover is not square (%d×%d); most players crop or letterbox it\n", c.Width, c.Height)
+	}
+	if *dryRun {
+		fmt.Printf("Dry-run: front cover would be set to %s\n", c)
+		return
+	}
+
+	path := fs.Arg(0)
+	if err := auditWrite("cover", path, *outPath, false, func() error { return audpkg.SetCover(path, *outPath, c) }); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	out := core.ResolveOutPath(path, *outPath)
+	if out == path {
+		fmt.Printf("✓ Front cover set in-place (%s): %s\n", c, path)
+	} else {
+		fmt.Printf("✓ Front cover set (%s) → %s\n", c, out)
+	}
+}
+
+func runCoverList(args []string) {
+	fs := flag.NewFlagSet("cover list", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery cover list <file>")
+		fmt.Println()
+		fmt.Println("List every embedded picture with its index and type code.")
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	pics, err := audpkg.ListPictures(fs.Arg(0))
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if len(pics) == 0 {
+		fmt.Println("No embedded pictures.")
+		return
+	}
+	for _, p := range pics {
+		fmt.Printf("  %d. %s\n", p.Index, p)
+	}
+}
+
+func runCoverExtract(args []string) {
+	fs := flag.NewFlagSet("cover extract", flag.ExitOnError)
+	typ := fs.String("type", "", "Picture type to extract: front, back, artist, … or a code (default: front, else the first)")
+	index := fs.Int("index", 0, "Extract the picture at this index (see 'cover list')")
+	outPath := fs.String("out", "", "Output image path, or - for stdout (default: <file>-<type>.<ext>)")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery cover extract [--type T | --index N] [--out file] <file>")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	path := fs.Arg(0)
+	pics, err := audpkg.ListPictures(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+
+	var pic *audpkg.Picture
+	switch {
+	case *index > 0:
+		if *index <= len(pics) {
+			pic = &pics[*index-1]
+		}
+	case *typ != "":
+		code, err := audpkg.ParsePictureType(*typ)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		for i := range pics {
+			if pics[i].Type == code {
+				pic = &pics[i]
+				break
+			}
+		}
+	default:
+		for i := range pics {
+			if audpkg.PictureTypeName(pics[i].Type) == "front" {
+				pic = &pics[i]
+				break
+			}
+		}
+		if pic == nil && len(pics) > 0 {
+			pic = &pics[0]
+		}
+	}
+	if pic == nil {
+		core.PrintError("no matching picture in " + path)
+		os.Exit(1)
+	}
+
+	if *outPath == "-" {
+		os.Stdout.Write(pic.Data)
+		return
+	}
+	out := *outPath
+	if out == "" {
+		out = strings.TrimSuffix(path, filepath.Ext(path)) + "-" + audpkg.PictureTypeName(pic.Type) + pic.Ext()
+	}
+	if err := os.WriteFile(out, pic.Data, 0644); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Extracted %s → %s\n", pic, out)
+}
+
+func runCoverRemove(args []string) {
+	fs := flag.NewFlagSet("cover remove", flag.ExitOnError)
+	var types kvFlags
+	fs.Var(&types, "type", "Picture type to remove (repeatable): back, artist, …, a code, or non-front")
+	outPath := fs.String("out", "", "Output file path (default: update in-place)")
+	dryRun := fs.Bool("dry-run", false, "List the pictures that would be removed")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery cover remove --type T [--type T ...] [flags] <file>")
+		fmt.Println()
+		fmt.Println("Remove embedded pictures of the given types from an MP3, FLAC or M4A file.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || len(types) == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	drop := make(map[byte]bool)
+	nonFront := false
+	for _, t := range types {
+		if strings.EqualFold(t, "non-front") {
+			nonFront = true
+			continue
+		}
+		code, err := audpkg.ParsePictureType(t)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		drop[code] = true
+	}
+	match := func(p audpkg.Picture) bool {
+		return drop[p.Type] || (nonFront && audpkg.PictureTypeName(p.Type) != "front")
+	}
+
+	path := fs.Arg(0)
+	if *dryRun {
+		pics, err := audpkg.ListPictures(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		for _, p := range pics {
+			if match(p) {
+				fmt.Printf("Dry-run: would remove %d. %s\n", p.Index, p)
+			}
+		}
+		return
+	}
+	var n int
+	err := auditWrite("cover", path, *outPath, false, func() (err error) {
+		n, err = audpkg.RemovePictures(path, *outPath, match)
+		return err
+	})
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	out := core.ResolveOutPath(path, *outPath)
+	if out == path {
+		fmt.Printf("✓ Removed %d picture(s) in-place: %s\n", n, path)
+	} else {
+		fmt.Printf("✓ Removed %d picture(s) → %s\n", n, out)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// export / import
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	nfo := fs.Bool("nfo", false, "Write a Kodi/Jellyfin .nfo sidecar")
+	outPath := fs.String("out", "", "Output path, or - for stdout (default: <file>.nfo next to the input)")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery export --nfo [--out file] <file>")
+		fmt.Println()
+		fmt.Println("Export container metadata to a sidecar file. An NFO is written as")
+		fmt.Println("<episodedetails> when the file has TV show fields, else as <movie>.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || !*nfo {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	path := fs.Arg(0)
+	m, err := viewFile(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	data, err := vidpkg.BuildNFO(m)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if *outPath == "-" {
+		os.Stdout.Write(data)
+		return
+	}
+	out := *outPath
+	if out == "" {
+		out = strings.TrimSuffix(path, filepath.Ext(path)) + ".nfo"
+	}
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("✓ NFO written → %s\n", out)
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	nfo := fs.Bool("nfo", false, "Read a Kodi/Jellyfin .nfo sidecar")
+	from := fs.String("from", "", "Sidecar to read (default: <file>.nfo next to the input)")
+	takeout := fs.String("google-takeout", "", "Merge Google Takeout JSON sidecars into the photos under this directory")
+	overwrite := fs.Bool("overwrite", false, "With --google-takeout: replace existing values instead of filling gaps")
+	outPath := fs.String("out", "", "Output file path (default: update in-place)")
+	dryRun := fs.Bool("dry-run", false, "Preview without writing to disk")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery import --nfo [--from file.nfo] [flags] <file>")
+		fmt.Println("       surgery import --google-takeout <dir> [--overwrite] [--dry-run]")
+		fmt.Println()
+		fmt.Println("--nfo writes the title, show, season, episode, date, plot, genre and cast")
+		fmt.Println("of an NFO back into the container. Supported for MP4 in v0.1.2.")
+		fmt.Println()
+		fmt.Println("--google-takeout writes the description, taken time and location from each")
+		fmt.Println("photo's Takeout .json sidecar into the JPEG (EXIF) or PNG (text chunks).")
+		fmt.Println("Values already in the photo are kept unless --overwrite is given.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *takeout != "" {
+		runTakeoutImport(*takeout, *overwrite, *dryRun)
+		return
+	}
+	if fs.NArg() < 1 || !*nfo {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	path := fs.Arg(0)
+	src := *from
+	if src == "" {
+		src = strings.TrimSuffix(path, filepath.Ext(path)) + ".nfo"
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	set, err := vidpkg.ParseNFO(data)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if len(set) == 0 {
+		fmt.Println("No changes: the NFO holds no fields that can be written")
+		return
+	}
+
+	if id, err := core.DetectFormat(path); err != nil || id != core.FmtMP4 {
+		core.PrintError(fmt.Sprintf("NFO import writes MP4 metadata; %s is not supported in v%s", path, Version))
+		os.Exit(1)
+	}
+	h, err := getHandler(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if err := core.Audited(h).Edit(path, *outPath, core.EditOptions{Set: set, DryRun: *dryRun}); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if !*dryRun {
+		keepAttrs(path, *outPath)
+		out := core.ResolveOutPath(path, *outPath)
+		if out == path {
+			fmt.Printf("✓ NFO imported in-place (%d fields): %s\n", len(set), path)
+		} else {
+			fmt.Printf("✓ NFO imported (%d fields) → %s\n", len(set), out)
+		}
+	}
+}
+
+// runTakeoutImport merges every Takeout sidecar under dir into its photo.
+func runTakeoutImport(dir string, overwrite, dryRun bool) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, p)
+		}
+		return err
+	})
+	if err != nil {
+		core.PrintError(fmt.Sprintf("cannot read directory %q: %s", dir, err))
+		os.Exit(1)
+	}
+	index := imgpkg.NewTakeoutIndex(files)
+
+	ok, errs, skipped := 0, 0, 0
+	for _, f := range files {
+		id, err := core.DetectFormat(f)
+		if err != nil || (id != core.FmtJPEG && id != core.FmtPNG && id != core.FmtHEIC) {
+			continue
+		}
+		sidecar := index.Lookup(f)
+		if sidecar == "" {
+			fmt.Printf("  Note: no Takeout sidecar for %s\n", f)
+			skipped++
+			continue
+		}
+		sc, err := imgpkg.ReadTakeoutSidecar(sidecar)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			errs++
+			continue
+		}
+		fields := sc.Fields(id)
+		opts := core.EditOptions{DryRun: dryRun}
+		if overwrite {
+			opts.Set = fields
+		} else {
+			opts.SetIfMissing = fields
+		}
+		h, err := getHandler(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			errs++
+			continue
+		}
+		if opts, err = resolveEditOps(h, f, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			errs++
+			continue
+		}
+		if !opts.HasChanges() {
+			fmt.Printf("= %s (unchanged)\n", f)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("[dry-run] would edit: %s (from %s)\n", f, filepath.Base(sidecar))
+			keys := make([]string, 0, len(opts.Set))
+			for k := range opts.Set {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("  %s = %s\n", k, opts.Set[k])
+			}
+			continue
+		}
+		if err := core.Audited(h).Edit(f, "", opts); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			errs++
+		} else {
+			fmt.Printf("✓ %s\n", f)
+			ok++
+		}
+	}
+	if !dryRun {
+		fmt.Printf("\nImported: %d  |  Errors: %d  |  Skipped: %d\n", ok, errs, skipped)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// validate
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	privacy := fs.Bool("privacy", false, "Also report GPS positions, the names of people in face regions, and filesystem metadata")
+	checkCRC := fs.Bool("check-crc", false, "Also recompute the container's own checksums (PNG chunk CRCs)")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery validate [--privacy] [--check-crc] <file> [<file> ...]")
+		fmt.Println()
+		fmt.Println("Check metadata values for problems. Exits with status 1 if any are found.")
+		fmt.Println()
+		fmt.Println("Checks:")
+		fmt.Println("  - values in NFD, or mixing NFC and NFD (fix with 'surgery edit --unicode nfc')")
+		fmt.Println("  - MusicBrainz IDs that are not UUIDs")
+		fmt.Println("  - podcast episode or season numbers that are not positive integers")
+		fmt.Println("  - with --privacy: GPS positions, people named in XMP face regions, and")
+		fmt.Println("    filesystem metadata (extended attributes, alternate data streams)")
+		fmt.Println("  - with --check-crc: PNG chunks whose CRC does not match their data")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	problems := 0
+	for _, path := range fs.Args() {
+		h, err := getHandler(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			problems++
+			continue
+		}
+		m, err := h.View(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			problems++
+			continue
+		}
+		issues := core.Validate(m)
+		if *privacy {
+			core.AddFSFields(m, path)
+			issues = append(issues, core.PrivacyAudit(m)...)
+		}
+		if cc, ok := h.(core.CRCChecker); ok && *checkCRC {
+			crcIssues, err := cc.CheckCRC(path)
+			if err != nil {
+				core.PrintError(fmt.Sprintf("%s: %v", path, err))
+				problems++
+				continue
+			}
+			issues = append(issues, crcIssues...)
+		}
+		if len(issues) == 0 {
+			fmt.Printf("✓ %s\n", path)
+			continue
+		}
+		fmt.Printf("✗ %s\n", path)
+		for _, issue := range issues {
+			fmt.Printf("  %s\n", issue)
+		}
+		problems++
+	}
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// scan
+// ──────────────────────────────────────────────────────────────────────────────
+
+// scanResult is one file of 'scan --json'.
+type scanResult struct {
+	File      string   `json:"file"`
+	Anomalies []string `json:"anomalies"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func runScan(args []string) {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	anomalies := fs.Bool("anomalies", false, "Report structures used to hide data (required)")
+	jsonOut := fs.Bool("json", false, "Output the findings as JSON")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery scan --anomalies [--json] <file> [<file> ...]")
+		fmt.Println()
+		fmt.Println("Walk the structure of JPEG, PNG, GIF and WebP files and report signs of")
+		fmt.Println("hidden data, for triaging uploads. Exits with status 1 if any are found.")
+		fmt.Println("A finding is a reason to look closer, not proof.")
+		fmt.Println()
+		fmt.Println("Reports:")
+		fmt.Println("  - data after the end of the image (EOI, IEND, trailer, RIFF size)")
+		fmt.Println("  - more than one EXIF block")
+		fmt.Printf("  - comments and text chunks over %d KiB\n", core.OversizedText>>10)
+		fmt.Println("  - unknown PNG or WebP chunks and GIF application extensions")
+		fmt.Println("  - text and metadata whose entropy or alphabet suggests an encoded payload")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery scan --anomalies upload.jpg")
+		fmt.Println("  surgery scan --anomalies --json uploads/*.png")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || !*anomalies {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var results []scanResult
+	flagged := 0
+	for _, path := range fs.Args() {
+		r := scanResult{File: path, Anomalies: []string{}}
+		h, err := getHandler(path)
+		if err == nil {
+			if sc, ok := h.(core.AnomalyScanner); ok {
+				var found []string
+				if found, err = sc.Anomalies(path); err != nil {
+					err = fmt.Errorf("%s: %w", path, err)
+				} else if found != nil {
+					r.Anomalies = found
+				}
+			} else {
+				err = fmt.Errorf("%s: anomaly scan is not supported for %s files", path, h.Info().Name)
+			}
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if err != nil || len(r.Anomalies) > 0 {
+			flagged++
+		}
+		if *jsonOut {
+			results = append(results, r)
+			continue
+		}
+		switch {
+		case err != nil:
+			core.PrintError(err.Error())
+		case len(r.Anomalies) == 0:
+			fmt.Printf("✓ %s\n", path)
+		default:
+			fmt.Printf("✗ %s\n", path)
+			for _, a := range r.Anomalies {
+				fmt.Printf("  %s\n", a)
+			}
+		}
+	}
+	if *jsonOut {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	}
+	if flagged > 0 {
+		os.Exit(1)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// verify
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	auditLog := fs.String("audit-log", "", "Instead, check the hash chain and signatures of an audit log")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery verify <file> [<file> ...]")
+		fmt.Println("       surgery verify --audit-log <log>")
+		fmt.Println()
+		fmt.Println("Re-compute the payload checksum stored by 'surgery edit --checksum'")
+		fmt.Println("(PayloadSHA256, PayloadMD5) and compare. The payload is the audio or")
+		fmt.Println("image data, so later tag edits do not affect it. Exits with status 1")
+		fmt.Println("if any file does not match or has no checksum.")
+		fmt.Println()
+		fmt.Println("With --audit-log, check that no line of the audit log was changed,")
+		fmt.Println("removed or reordered (signatures need the configured key).")
+	}
+	fs.Parse(args)
+
+	if *auditLog != "" {
+		n, err := core.VerifyAuditLog(*auditLog, core.AuditKey())
+		if err != nil {
+			core.PrintError(fmt.Sprintf("%s: %v (%d entries good before it)", *auditLog, err, n))
+			os.Exit(1)
+		}
+		signed := "hash chain"
+		if core.AuditKey() != "" {
+			signed = "hash chain and signatures"
+		}
+		fmt.Printf("✓ %s: %d entries, %s intact\n", *auditLog, n, signed)
+		return
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ok, failed, missing := 0, 0, 0
+	for _, path := range fs.Args() {
+		h, err := getHandler(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			failed++
+			continue
+		}
+		m, err := h.View(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			failed++
+			continue
+		}
+		results, err := core.VerifyPayload(h, path, m)
+		if err != nil {
+			core.PrintError(fmt.Sprintf("%s: %v", path, err))
+			failed++
+			continue
+		}
+		if len(results) == 0 {
+			fmt.Printf("? %s (no payload checksum)\n", path)
+			missing++
+			continue
+		}
+		good := true
+		for _, r := range results {
+			if !r.OK() {
+				good = false
+			}
+		}
+		if good {
+			fmt.Printf("✓ %s\n", path)
+			ok++
+			continue
+		}
+		fmt.Printf("✗ %s\n", path)
+		for _, r := range results {
+			if !r.OK() {
+				fmt.Printf("  %s: stored %s, computed %s\n", r.Field, r.Stored, r.Computed)
+			}
+		}
+		failed++
+	}
+	fmt.Printf("\nOK: %d  |  Failed: %d  |  No checksum: %d\n", ok, failed, missing)
+	if failed > 0 || missing > 0 {
+		os.Exit(1)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// selftest
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runSelftest(args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	writeGolden := fs.String("write-golden", "", "Write the golden files of the built-in samples to this directory and exit")
+	verbose := fs.Bool("verbose", false, "List every check, not only the failed ones")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery selftest [--verbose] [<file> ...]")
+		fmt.Println("       surgery selftest --write-golden <dir>")
+		fmt.Println()
+		fmt.Println("Round-trip files through view, edit and strip and check that nothing")
+		fmt.Println("was damaged: edited fields present, no other field lost, metadata gone")
+		fmt.Println("after strip and the image or audio payload unchanged throughout.")
+		fmt.Println()
+		fmt.Println("Without files, runs the built-in samples and compares them with their")
+		fmt.Println("golden files, then checks that a generated sample of every format")
+		fmt.Println("views with the metadata it was made with (see cmd/genmedia). With")
+		fmt.Println("files, works on copies in a temporary directory:")
+		fmt.Println("the files themselves are only read. Exits with status 1 if any check")
+		fmt.Println("fails.")
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery selftest")
+		fmt.Println("  surgery selftest --verbose photo.jpg song.flac")
+	}
+	fs.Parse(args)
+
+	if *writeGolden != "" {
+		if err := selftest.WriteGolden(*writeGolden); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("✓ golden files written to %s\n", *writeGolden)
+		return
+	}
+
+	var reports []*selftest.Report
+	passed, failed := 0, 0
+	if fs.NArg() == 0 {
+		r, err := selftest.Corpus()
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		reports = r
+		g, err := selftest.Generated()
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		reports = append(reports, g...)
+	}
+	for _, path := range fs.Args() {
+		r, err := selftest.File(path)
+		if err != nil {
+			core.PrintError(fmt.Sprintf("%s: %v", path, err))
+			failed++
+			continue
+		}
+		reports = append(reports, r)
+	}
+
+	for _, r := range reports {
+		if r.Failed() {
+			fmt.Printf("✗ %s (%s)\n", r.Path, r.Format)
+			failed++
+		} else {
+			fmt.Printf("✓ %s (%s)\n", r.Path, r.Format)
+			passed++
+		}
+		for _, c := range r.Checks {
+			switch {
+			case !c.OK:
+				fmt.Printf("  ✗ %s: %s\n", c.Name, c.Detail)
+			case *verbose && c.Detail != "":
+				fmt.Printf("  ✓ %s: %s\n", c.Name, c.Detail)
+			case *verbose:
+				fmt.Printf("  ✓ %s\n", c.Name)
+			}
+		}
+	}
+	fmt.Printf("\nPassed: %d  |  Failed: %d\n", passed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// info
+// ──────────────────────────────────────────────────────────────────────────────
+
+func runInfo(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	jsonOut := fs.Bool("json", false, "Output as JSON")
+	untouched := fs.Bool("verify-untouched", false, "Hash the file before and after reading and fail if it changed")
+	sizes := fs.Bool("size-breakdown", false, "Show the bytes taken by each metadata structure and by the payload (JPEG, PNG, MP3, FLAC, MP4, MOV)")
+	mimeOnly := fs.Bool("mime-only", false, "Print only the MIME type (application/octet-stream when unrecognised)")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery info [--json] [--verify-untouched] [--size-breakdown] [--mime-only] <file>")
+		fmt.Println()
+		fmt.Println("Show format detection result and capabilities for a file.")
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  surgery info photo.jpg")
+		fmt.Println("  surgery info --json audio.mp3")
+		fmt.Println("  surgery info --size-breakdown photo.jpg")
+		fmt.Println("  surgery info --mime-only upload.bin")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	path := fs.Arg(0)
+	if *untouched {
+		defer checkUntouched(path, snapshotOrExit(path))
+	}
+	if *mimeOnly {
+		mime, err := core.DetectMIME(path)
+		if err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(mime)
+		return
+	}
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+
+	h, err := getHandler(path)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+
+	info := h.Info()
+	fsAttrs := core.FSAttrs(path)
+	var reencode *imgpkg.ReencodeCheck
+	if fmtID == core.FmtJPEG {
+		reencode, _ = imgpkg.CheckReencode(path)
+	}
+	var parts []core.SizePart
+	if *sizes {
+		if parts, err = core.SizeBreakdown(h, path); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOut {
+		fmt.Printf("{\n")
+		fmt.Printf("  \"file\": %q,\n", path)
+		fmt.Printf("  \"format_id\": %q,\n", fmtID)
+		fmt.Printf("  \"name\": %q,\n", info.Name)
+		fmt.Printf("  \"media_type\": %q,\n", info.MediaType)
+		fmt.Printf("  \"extensions\": %q,\n", strings.Join(info.Extensions, ", "))
+		fmt.Printf("  \"mime_types\": %q,\n", strings.Join(info.MIMETypes, ", "))
+		fmt.Printf("  \"can_view\": %v,\n", info.CanView)
+		fmt.Printf("  \"can_edit\": %v,\n", info.CanEdit)
+		fmt.Printf("  \"can_strip\": %v,\n", info.CanStrip)
+		fmt.Printf("  \"editable_fields\": %q,\n", strings.Join(info.EditableFields, ", "))
+		fmt.Printf("  \"filesystem_metadata\": %q,\n", strings.Join(fsAttrs, ", "))
+		if reencode != nil {
+			fmt.Printf("  \"reencoded\": %q,\n", reencode.Verdict)
+			fmt.Printf("  \"reencode_signs\": %q,\n", strings.Join(reencode.Reasons, "; "))
+		}
+		if parts == nil {
+			fmt.Printf("  \"notes\": %q\n", info.Notes)
+		} else {
+			fmt.Printf("  \"notes\": %q,\n", info.Notes)
+			fmt.Printf("  \"size_breakdown\": [\n")
+			for i, p := range parts {
+				sep := ","
+				if i == len(parts)-1 {
+					sep = ""
+				}
+				fmt.Printf("    {\"name\": %q, \"bytes\": %d, \"metadata\": %v}%s\n", p.Name, p.Bytes, p.Metadata, sep)
+			}
+			fmt.Printf("  ],\n")
+			fmt.Printf("  \"metadata_bytes\": %d\n", core.MetadataBytes(parts))
+		}
+		fmt.Printf("}\n")
+	} else {
+		fmt.Printf("File            : %s\n", path)
+		fmt.Printf("Detected Format : %s  (id: %s)\n", info.Name, fmtID)
+		fmt.Printf("Media Type      : %s\n", info.MediaType)
+		fmt.Printf("Extensions      : %s\n", strings.Join(info.Extensions, ", "))
+		fmt.Printf("MIME Types      : %s\n", strings.Join(info.MIMETypes, ", "))
+		fmt.Printf("Can View        : %v\n", info.CanView)
+		fmt.Printf("Can Edit        : %v\n", info.CanEdit)
+		fmt.Printf("Can Strip       : %v\n", info.CanStrip)
+		if len(info.EditableFields) > 0 {
+			fmt.Printf("Editable Fields : %s\n", strings.Join(info.EditableFields, ", "))
+		}
+		if info.Notes != "" {
+			fmt.Printf("Notes           : %s\n", info.Notes)
+		}
+		if len(fsAttrs) > 0 {
+			fmt.Printf("Filesystem Meta : %s\n", strings.Join(fsAttrs, ", "))
+		}
+		if reencode != nil {
+			fmt.Printf("Re-encoded      : %s\n", reencode)
+		}
+		if parts != nil {
+			printSizeBreakdown(parts)
+		}
+	}
+}
+
+// printSizeBreakdown prints one line per structure and the total that a
+// default strip would remove.
+func printSizeBreakdown(parts []core.SizePart) {
+	var total int64
+	for _, p := range parts {
+		total += p.Bytes
+	}
+	pct := func(n int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(total)
+	}
+	fmt.Printf("\nSize breakdown  : %s total\n", core.FormatSize(total))
+	for _, p := range parts {
+		line := fmt.Sprintf("  %-30s %10s  %5.1f%%", p.Name, core.FormatSize(p.Bytes), pct(p.Bytes))
+		if p.Metadata {
+			line += "  metadata"
+		}
+		fmt.Println(line)
+	}
+	meta := core.MetadataBytes(parts)
+	fmt.Printf("Metadata        : %s (%.1f%%) — removed by strip\n", core.FormatSize(meta), pct(meta))
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// formats
+// ──────────────────────────────────────────────────────────────────────────────
+
+type namedFormatInfo struct {
+	id core.FormatID
+	core.FormatInfo
+}
+
+func runFormats(args []string) {
+	fs := flag.NewFlagSet("formats", flag.ExitOnError)
+	mediaType := fs.String("type", "", "Filter by media type: image|audio|video|document|subtitle")
+	jsonOut := fs.Bool("json", false, "Output the capability matrix as JSON, with the write support of each editable field")
+	fs.Usage = func() {
+		fmt.Println("Usage: surgery formats [--type image|audio|video|document|subtitle] [--json]")
+		fmt.Println()
+		fmt.Println("List all supported formats and their capabilities.")
+		fmt.Println()
+		fmt.Println("With --json each editable field has a write level: \"full\" (set and")
+		fmt.Println("delete) or \"set-only\" (the field can be set but not deleted).")
+	}
+	fs.Parse(args)
+
+	all := getAllFormatInfos()
+
+	if *jsonOut {
+		out := []formatJSON{}
+		for _, f := range all {
+			if *mediaType == "" || f.MediaType == *mediaType {
+				out = append(out, newFormatJSON(f))
+			}
+		}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		os.Stdout.Write(append(data, '\n'))
+		return
+	}
+
+	fmt.Printf("\n%-12s %-22s %-10s  %-5s %-5s %-5s  %s\n",
+		"Format ID", "Name", "Type", "View", "Edit", "Strip", "Extensions")
+	fmt.Println(strings.Repeat("─", 82))
+
+	viewCount, editCount, stripCount := 0, 0, 0
+	total := 0
+
+	for _, f := range all {
+		if *mediaType != "" && f.MediaType != *mediaType {
+			continue
+		}
+		total++
+		v := tick(f.CanView)
+		e := tick(f.CanEdit)
+		s := tick(f.CanStrip)
+		if f.CanView { viewCount++ }
 		if f.CanEdit { editCount++ }
 		if f.CanStrip { stripCount++ }
 		fmt.Printf("%-12s %-22s %-10s  %-5s %-5s %-5s  %s\n",
@@ -437,6 +2317,82 @@ func runFormats(args []string) {
 	fmt.Printf("%-12s %-22s %-10s  %-5d %-5d %-5d  (%d formats total)\n",
 		"", "TOTAL", "", viewCount, editCount, stripCount, total)
 	fmt.Println()
+
+	header := false
+	for _, f := range all {
+		if *mediaType != "" && f.MediaType != *mediaType {
+			continue
+		}
+		for _, o := range f.Options {
+			if !header {
+				fmt.Println("Format options (--option KEY=VALUE):")
+				header = true
+			}
+			fmt.Printf("  %-27s %-5s %-6s %s\n", o.Key+"="+strings.Join(o.Values, "|"), f.Name, optionOperation(o), o.Doc)
+		}
+	}
+	if header {
+		fmt.Println()
+	}
+}
+
+// optionOperation names the command a format option applies to.
+func optionOperation(o core.FormatOption) string {
+	if o.Strip {
+		return "strip"
+	}
+	return "edit"
+}
+
+// formatJSON is one entry of `formats --json`.
+type formatJSON struct {
+	ID             string      `json:"id"`
+	Name           string      `json:"name"`
+	MediaType      string      `json:"media_type"`
+	Extensions     []string    `json:"extensions"`
+	MIMETypes      []string    `json:"mime_types"`
+	CanView        bool        `json:"can_view"`
+	CanEdit        bool        `json:"can_edit"`
+	CanStrip       bool        `json:"can_strip"`
+	EditableFields []fieldJSON  `json:"editable_fields"`
+	Options        []optionJSON `json:"options"`
+	Notes          string       `json:"notes"`
+}
+
+// optionJSON is one format-specific --option key.
+type optionJSON struct {
+	Key       string   `json:"key"`
+	Values    []string `json:"values"`
+	Operation string   `json:"operation"` // "edit" or "strip"
+	Doc       string   `json:"doc"`
+}
+
+type fieldJSON struct {
+	Name  string `json:"name"`
+	Write string `json:"write"` // core.WriteFull or core.WriteSetOnly
+}
+
+func newFormatJSON(f namedFormatInfo) formatJSON {
+	j := formatJSON{
+		ID:             string(f.id),
+		Name:           f.Name,
+		MediaType:      f.MediaType,
+		Extensions:     append([]string{}, f.Extensions...),
+		MIMETypes:      append([]string{}, f.MIMETypes...),
+		CanView:        f.CanView,
+		CanEdit:        f.CanEdit,
+		CanStrip:       f.CanStrip,
+		EditableFields: []fieldJSON{},
+		Options:        []optionJSON{},
+		Notes:          f.Notes,
+	}
+	for _, name := range f.EditableFields {
+		j.EditableFields = append(j.EditableFields, fieldJSON{Name: name, Write: f.FieldSupport(name)})
+	}
+	for _, o := range f.Options {
+		j.Options = append(j.Options, optionJSON{Key: o.Key, Values: o.Values, Operation: optionOperation(o), Doc: o.Doc})
+	}
+	return j
 }
 
 func tick(b bool) string {
@@ -475,11 +2431,16 @@ func getAllFormatInfos() []namedFormatInfo {
 	// Documents
 	for _, id := range []core.FormatID{
 		core.FmtPDF, core.FmtDOCX, core.FmtXLSX, core.FmtPPTX,
-		core.FmtODT, core.FmtEPUB,
+		core.FmtODT, core.FmtEPUB, core.FmtCBZ,
 	} {
 		h := docpkg.New(id)
 		all = append(all, namedFormatInfo{id: id, FormatInfo: h.Info()})
 	}
+	// Subtitles
+	for _, id := range []core.FormatID{core.FmtSRT, core.FmtASS, core.FmtVTT} {
+		h := subpkg.New(id)
+		all = append(all, namedFormatInfo{id: id, FormatInfo: h.Info()})
+	}
 	return all
 }
 
@@ -489,7 +2450,7 @@ func getAllFormatInfos() []namedFormatInfo {
 
 func runBatch(args []string) {
 	if len(args) < 2 {
-		fmt.Println("Usage: surgery batch <view|strip|edit> [flags] <directory>")
+		fmt.Println("Usage: surgery batch <view|strip|edit|apply> [flags] <directory>")
 		fmt.Println()
 		fmt.Println("Examples:")
 		fmt.Println("  surgery batch view ./photos")
@@ -497,6 +2458,8 @@ func runBatch(args []string) {
 		fmt.Println("  surgery batch strip --out ./clean ./photos")
 		fmt.Println("  surgery batch strip --recursive ./media")
 		fmt.Println(`  surgery batch edit --set "Copyright=ACME Corp" ./docs`)
+		fmt.Println("  surgery batch edit --csv edits.csv ./music")
+		fmt.Println("  surgery batch apply --manifest edits.ndjson --result done.json ./music")
 		os.Exit(1)
 	}
 
@@ -510,9 +2473,11 @@ func runBatch(args []string) {
 		runBatchStrip(subargs)
 	case "edit":
 		runBatchEdit(subargs)
+	case "apply":
+		runBatchApply(subargs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown batch sub-command: %s\n", subcmd)
-		fmt.Println("Valid sub-commands: view, strip, edit")
+		fmt.Println("Valid sub-commands: view, strip, edit, apply")
 		os.Exit(1)
 	}
 }
@@ -521,10 +2486,11 @@ func runBatchView(args []string) {
 	fs := flag.NewFlagSet("batch view", flag.ExitOnError)
 	jsonOut := fs.Bool("json", false, "Output as JSON")
 	recursive := fs.Bool("recursive", false, "Recurse into subdirectories")
+	reportUnknown := fs.Bool("report-unknown", false, "Total the metadata structures the parsers skipped over all files, on stderr")
 	fs.Parse(args)
 
 	if fs.NArg() < 1 {
-		fmt.Println("Usage: surgery batch view [--json] [--recursive] <directory>")
+		fmt.Println("Usage: surgery batch view [--json] [--recursive] [--report-unknown] <directory>")
 		os.Exit(1)
 	}
 
@@ -532,6 +2498,8 @@ func runBatchView(args []string) {
 	p := core.NewPrinter(*jsonOut, false)
 	files := collectFiles(dir, *recursive)
 	errs := 0
+	var unknown []core.UnknownStructure
+	checked := 0
 
 	for _, f := range files {
 		m, err := viewFile(f)
@@ -540,10 +2508,17 @@ func runBatchView(args []string) {
 			errs++
 			continue
 		}
+		core.AddFSFields(m, f)
 		if !*jsonOut {
 			fmt.Println(strings.Repeat("═", 60))
 		}
 		p.PrintMetadata(m)
+		if *reportUnknown {
+			if found, err := unknownStructures(f); err == nil {
+				unknown = core.MergeUnknown(unknown, found)
+				checked++
+			}
+		}
 	}
 
 	if !*jsonOut {
@@ -553,6 +2528,9 @@ func runBatchView(args []string) {
 		}
 		fmt.Println()
 	}
+	if *reportUnknown {
+		printUnknownTally(unknown, checked, len(files)-errs-checked)
+	}
 }
 
 func runBatchStrip(args []string) {
@@ -560,24 +2538,50 @@ func runBatchStrip(args []string) {
 	outDir := fs.String("out", "", "Output directory (default: in-place)")
 	dryRun := fs.Bool("dry-run", false, "Preview without writing")
 	recursive := fs.Bool("recursive", false, "Recurse into subdirectories")
+	resume := fs.String("resume-from", "", "Start at this file, where an interrupted run stopped")
+	var state stateFlags
+	state.register(fs)
 	var keepFlags kvFlags
 	fs.Var(&keepFlags, "keep", "Keep a metadata section (repeatable)")
+	var optFlags kvFlags
+	fs.Var(&optFlags, "option", optionUsage)
+	var backup backupFlags
+	backup.register(fs)
 	fs.Parse(args)
 
 	if fs.NArg() < 1 {
-		fmt.Println("Usage: surgery batch strip [--out <dir>] [--recursive] [--dry-run] <directory>")
+		fmt.Println("Usage: surgery batch strip [--out <dir>] [--recursive] [--dry-run] [--backup] [--option KEY=VALUE] [--state <file> [--resume]] [--resume-from <file>] <directory>")
 		os.Exit(1)
 	}
 
 	dir := fs.Arg(0)
-	files := collectFiles(dir, *recursive)
+	files := resumeFrom(state.files(collectFiles(dir, *recursive)), dir, *resume)
 	opts := core.StripOptions{
 		KeepFields: []string(keepFlags),
 		StripAll:   len(keepFlags) == 0,
+		Backup:     backup.options(),
+		Options:    kvMap("option", optFlags),
 	}
+	checkFormatOptions(opts.Options, true, allFormatInfos()...)
 
-	ok, errs, skipped := 0, 0, 0
-	for _, f := range files {
+	st := state.open("batch strip", *dryRun)
+	if !*dryRun {
+		catchInterrupts()
+	}
+	ok, errs, skipped, resumed := 0, 0, 0, 0
+	var savings int64
+	measured := 0
+	for i, f := range files {
+		if interrupted.Load() {
+			fmt.Printf("\nStripped: %d  |  Errors: %d  |  Skipped (unsupported): %d\n", ok, errs, skipped)
+			closeState(st, resumed)
+			printInterrupted("batch strip", fs, args, i, len(files)-i, f)
+			os.Exit(130)
+		}
+		if st != nil && st.Completed(f) {
+			resumed++
+			continue
+		}
 		outPath := ""
 		if *outDir != "" {
 			rel, _ := filepath.Rel(dir, f)
@@ -587,25 +2591,42 @@ func runBatchStrip(args []string) {
 
 		h, err := getHandler(f)
 		if err != nil || !h.Info().CanStrip {
+			recordState(st, f, "skipped", nil)
 			skipped++
 			continue
 		}
 
 		if *dryRun {
-			fmt.Printf("[dry-run] would strip: %s\n", f)
+			if parts, err := core.SizeBreakdown(h, f); err == nil {
+				n := core.MetadataBytes(parts)
+				fmt.Printf("[dry-run] would strip: %s (saves %s)\n", f, core.FormatSize(n))
+				savings += n
+				measured++
+			} else {
+				fmt.Printf("[dry-run] would strip: %s\n", f)
+			}
 			continue
 		}
 
-		if err := h.Strip(f, outPath, opts); err != nil {
+		if err := core.Audited(h).Strip(f, outPath, opts); err != nil {
 			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			recordState(st, f, "error", err)
 			errs++
 		} else {
+			keepAttrs(f, outPath)
+			if opts.StripAll {
+				stripFSAttrs(f, outPath, false)
+			}
 			fmt.Printf("✓ %s\n", f)
+			recordState(st, f, "ok", nil)
 			ok++
 		}
 	}
 	if !*dryRun {
 		fmt.Printf("\nStripped: %d  |  Errors: %d  |  Skipped (unsupported): %d\n", ok, errs, skipped)
+		closeState(st, resumed)
+	} else if measured > 0 {
+		fmt.Printf("\nPotential savings: %s  |  Measured: %d  |  Skipped (unsupported): %d\n", core.FormatSize(savings), measured, skipped)
 	}
 }
 
@@ -616,57 +2637,444 @@ func runBatchEdit(args []string) {
 	dryRun := fs.Bool("dry-run", false, "Preview without writing")
 	recursive := fs.Bool("recursive", false, "Recurse into subdirectories")
 	fs.Var(&setFlags, "set", "Set KEY=VALUE (repeatable)")
+	csvPath := fs.String("csv", "", "CSV of per-file values: a path (or filename) column plus one column per field")
+	fpCmd := fs.String("fingerprint-cmd", "", "Fill AcoustID tags of audio files from this command's output (e.g. \"fpcalc\")")
+	transaction := fs.Bool("transaction", false, "All or nothing: change no file unless every edit succeeds")
+	resume := fs.String("resume-from", "", "Start at this file, where an interrupted run stopped")
+	var state stateFlags
+	state.register(fs)
+	var optFlags kvFlags
+	fs.Var(&optFlags, "option", optionUsage)
+	var ops editOpFlags
+	ops.register(fs)
+	var backup backupFlags
+	backup.register(fs)
 	fs.Parse(args)
 
-	if fs.NArg() < 1 || len(setFlags) == 0 {
-		fmt.Println("Usage: surgery batch edit --set KEY=VALUE [--recursive] [--out <dir>] <directory>")
+	setMap, add := setFlagsOps(setFlags)
+	if setMap == nil {
+		setMap = map[string]string{}
+	}
+	opts := core.EditOptions{
+		Set:     setMap,
+		Add:     add,
+		DryRun:  *dryRun,
+		Backup:  backup.options(),
+		Options: kvMap("option", optFlags),
+	}
+	ops.apply(&opts)
+	checkFormatOptions(opts.Options, false, allFormatInfos()...)
+	if fs.NArg() < 1 || (!opts.HasChanges() && *csvPath == "" && *fpCmd == "") {
+		fmt.Println("Usage: surgery batch edit [--set KEY=VALUE] [--set-if-missing KEY=VALUE] [--csv edits.csv] [--fingerprint-cmd CMD] [--transaction] [--backup] [--option KEY=VALUE] [--recursive] [--out <dir>] [--state <file> [--resume]] [--resume-from <file>] <directory>")
 		os.Exit(1)
 	}
 
 	dir := fs.Arg(0)
-	setMap := map[string]string{}
-	for _, kv := range setFlags {
-		k, v, ok := core.ParseKV(kv)
-		if !ok {
-			continue
+
+	var rows *editCSV
+	if *csvPath != "" {
+		var err error
+		if rows, err = loadEditCSV(*csvPath); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
 		}
-		setMap[k] = v
 	}
 
-	opts := core.EditOptions{Set: setMap, DryRun: *dryRun}
-	files := collectFiles(dir, *recursive)
-	ok, errs, skipped := 0, 0, 0
+	var tx *batch.Tx
+	if *transaction && !*dryRun {
+		var err error
+		if tx, err = batch.BeginTx(); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+		tx.Backup = opts.Backup
+	}
 
-	for _, f := range files {
+	files := resumeFrom(state.files(collectFiles(dir, *recursive)), dir, *resume)
+	st := state.open("batch edit", *dryRun)
+	ok, errs, skipped, resumed := 0, 0, 0, 0
+	if !*dryRun {
+		catchInterrupts()
+	}
+
+	// In a transaction nothing is done until the commit, so the files it
+	// staged are recorded only then.
+	var staged []string
+	stopped := -1
+	for i, f := range files {
+		if interrupted.Load() {
+			stopped = i
+			break
+		}
+		if st != nil && st.Completed(f) {
+			resumed++
+			continue
+		}
+		rel, _ := filepath.Rel(dir, f)
 		outPath := ""
 		if *outDir != "" {
-			rel, _ := filepath.Rel(dir, f)
 			outPath = filepath.Join(*outDir, rel)
 			os.MkdirAll(filepath.Dir(outPath), 0755)
 		}
 
+		fileOpts := opts
+		if rows != nil {
+			row := rows.lookup(rel)
+			if row == nil {
+				recordState(st, f, "skipped", nil)
+				skipped++
+				continue
+			}
+			fileOpts.Set = map[string]string{}
+			for k, v := range setMap {
+				fileOpts.Set[k] = v
+			}
+			for k, v := range row {
+				fileOpts.Set[k] = v
+			}
+			if !fileOpts.HasChanges() {
+				recordState(st, f, "skipped", nil)
+				skipped++
+				continue
+			}
+		}
+
 		h, err := getHandler(f)
 		if err != nil || !h.Info().CanEdit {
+			recordState(st, f, "skipped", nil)
 			skipped++
 			continue
 		}
 
+		if *fpCmd != "" && h.Info().MediaType == "audio" {
+			fields, err := audpkg.AcoustIDFields(f, audpkg.CommandFingerprinter{Command: *fpCmd})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+				recordState(st, f, "error", err)
+				errs++
+				continue
+			}
+			for k, v := range fileOpts.Set {
+				fields[k] = v
+			}
+			fileOpts.Set = fields
+		}
+
+		if err := core.CheckAdd(f, fileOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			recordState(st, f, "error", err)
+			errs++
+			continue
+		}
+		if fileOpts, err = resolveEditOps(h, f, fileOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			recordState(st, f, "error", err)
+			errs++
+			continue
+		}
+		if !fileOpts.HasChanges() {
+			fmt.Printf("= %s (unchanged)\n", f)
+			recordState(st, f, "unchanged", nil)
+			continue
+		}
+		fileOpts = ops.signed(fileOpts, f)
+
 		if *dryRun {
 			fmt.Printf("[dry-run] would edit: %s\n", f)
+			if rows != nil || opts.HasConditional() || *fpCmd != "" {
+				keys := make([]string, 0, len(fileOpts.Set))
+				for k := range fileOpts.Set {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					fmt.Printf("  %s = %s\n", k, fileOpts.Set[k])
+				}
+			}
 			continue
 		}
 
-		if err := h.Edit(f, outPath, opts); err != nil {
+		edit := func() error { return core.Audited(h).Edit(f, outPath, fileOpts) }
+		if tx != nil {
+			edit = func() error {
+				return tx.Write(f, outPath, func(src, tmp string) error { return core.Audited(h).Edit(src, tmp, fileOpts) })
+			}
+		}
+		if err := edit(); err != nil {
 			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", f, err)
+			recordState(st, f, "error", err)
 			errs++
+		} else if tx != nil {
+			fmt.Printf("✓ %s (staged)\n", f)
+			staged = append(staged, f)
+			ok++
 		} else {
+			keepAttrs(f, outPath)
 			fmt.Printf("✓ %s\n", f)
+			recordState(st, f, "ok", nil)
 			ok++
 		}
 	}
+	if tx != nil {
+		if stopped >= 0 {
+			tx.Rollback()
+			fmt.Fprintf(os.Stderr, "✗ Transaction rolled back: interrupted, %d staged edit(s) discarded, no file was changed\n", ok)
+			ok, stopped = 0, 0
+		} else if errs > 0 {
+			tx.Rollback()
+			fmt.Fprintf(os.Stderr, "✗ Transaction rolled back: %d staged edit(s) discarded, no file was changed\n", ok)
+			ok = 0
+		} else if err := tx.Commit(); err != nil {
+			core.PrintError(fmt.Sprintf("transaction rolled back, no file was changed: %s", err))
+			errs, ok = errs+1, 0
+		} else {
+			fmt.Printf("✓ Transaction committed: %d file(s)\n", tx.Len())
+			for _, f := range staged {
+				recordState(st, f, "ok", nil)
+			}
+		}
+	}
+	if rows != nil {
+		for _, name := range rows.unused() {
+			fmt.Printf("  Warning: CSV row %q matched no file\n", name)
+		}
+	}
 	if !*dryRun {
 		fmt.Printf("\nEdited: %d  |  Errors: %d  |  Skipped (unsupported): %d\n", ok, errs, skipped)
+		closeState(st, resumed)
+	}
+	if stopped >= 0 {
+		printInterrupted("batch edit", fs, args, stopped, len(files)-stopped, files[stopped])
+		os.Exit(130)
+	}
+	if tx != nil && errs > 0 {
+		os.Exit(1)
+	}
+}
+
+func runBatchApply(args []string) {
+	fs := flag.NewFlagSet("batch apply", flag.ExitOnError)
+	manifestPath := fs.String("manifest", "", "JSON array or NDJSON of {\"path\", \"set\", \"delete\", \"out\"} entries")
+	resultPath := fs.String("result", "", "Write a JSON result manifest to this file (\"-\" for stdout)")
+	dryRun := fs.Bool("dry-run", false, "Preview without writing")
+	unicode := fs.String("unicode", "nfc", "Unicode normalization of written values: nfc, nfd or none")
+	fpCmd := fs.String("fingerprint-cmd", "", "Fill AcoustID tags of audio entries from this command's output (e.g. \"fpcalc\")")
+	transaction := fs.Bool("transaction", false, "All or nothing: change no file unless every entry succeeds")
+	resume := fs.String("resume-from", "", "Start at the entry for this path, where an interrupted run stopped")
+	var state stateFlags
+	state.register(fs)
+	var optFlags kvFlags
+	fs.Var(&optFlags, "option", optionUsage)
+	var backup backupFlags
+	backup.register(fs)
+	fs.Parse(args)
+	form, err := core.ParseUnicodeForm(*unicode)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+
+	if *manifestPath == "" {
+		fmt.Println("Usage: surgery batch apply --manifest <file> [--result <file>] [--dry-run] [--state <file> [--resume]] [--resume-from <path>] [<directory>]")
+		fmt.Println()
+		fmt.Println("Relative paths in the manifest are resolved against <directory> (default: current directory).")
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*manifestPath)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	manifest, err := batch.ParseManifest(f)
+	f.Close()
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	if *resume != "" {
+		i := 0
+		for i < len(manifest) && manifest[i].Path != *resume {
+			i++
+		}
+		if i == len(manifest) {
+			core.PrintError(fmt.Sprintf("--resume-from: the manifest has no entry for %s", *resume))
+			os.Exit(1)
+		}
+		manifest = manifest[i:]
+	}
+
+	bopts := batch.Options{Dir: fs.Arg(0), DryRun: *dryRun, UnicodeForm: form, Transaction: *transaction, Backup: backup.options(),
+		FormatOptions: kvMap("option", optFlags)}
+	checkFormatOptions(bopts.FormatOptions, false, allFormatInfos()...)
+	if *fpCmd != "" {
+		bopts.Fingerprinter = audpkg.CommandFingerprinter{Command: *fpCmd}
+	}
+	if !*dryRun {
+		catchInterrupts()
+		bopts.Stop = interrupted.Load
+	}
+	bopts.State = state.open("batch apply", *dryRun)
+	results := batch.BatchApply(manifest, bopts)
+
+	ok, errs, skipped, rolledBack, resumed := 0, 0, 0, 0, 0
+	reason := ""
+	stopped := -1
+	for i, r := range results {
+		switch r.Status {
+		case "done":
+			resumed++
+		case "interrupted":
+			if stopped < 0 {
+				stopped = i
+			}
+		case "ok":
+			ok++
+		case "error":
+			errs++
+		case "skipped":
+			skipped++
+		case "rolled-back":
+			rolledBack++
+			reason = r.Error
+		}
+		if *resultPath == "-" {
+			continue
+		}
+		switch r.Status {
+		case "ok":
+			fmt.Printf("✓ %s\n", r.Path)
+		case "error":
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", r.Path, r.Error)
+		}
+	}
+
+	if *resultPath != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		data = append(data, '\n')
+		if *resultPath == "-" {
+			os.Stdout.Write(data)
+		} else if err := os.WriteFile(*resultPath, data, 0644); err != nil {
+			core.PrintError(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *resultPath != "-" && !*dryRun {
+		fmt.Printf("\nEdited: %d  |  Errors: %d  |  Skipped: %d\n", ok, errs, skipped)
+		closeState(bopts.State, resumed)
+		if rolledBack > 0 {
+			fmt.Fprintf(os.Stderr, "✗ Transaction rolled back (%s): %d staged edit(s) discarded, no file was changed\n", reason, rolledBack)
+		}
+	} else if bopts.State != nil {
+		bopts.State.Close()
+	}
+	if stopped >= 0 {
+		next := results[stopped].Path
+		if rolledBack > 0 {
+			next = results[0].Path
+		}
+		printInterrupted("batch apply", fs, args, stopped, len(results)-stopped, next)
+		os.Exit(130)
+	}
+	if errs > 0 || rolledBack > 0 {
+		os.Exit(1)
+	}
+}
+
+// editCSV holds per-file field values read from a batch edit CSV.
+// The header row names a path column ("path", "file" or "filename") and
+// one column per field; empty cells leave that field unchanged.
+type editCSV struct {
+	byPath map[string]map[string]string // slash-separated path relative to the directory
+	byName map[string]map[string]string // rows that give a bare filename
+	used   map[string]bool
+	order  []string
+}
+
+func loadEditCSV(path string) (*editCSV, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV %s: %w", path, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV %s has no data rows", path)
+	}
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF") // Excel writes a BOM
+	}
+	pathCol := -1
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "path", "file", "filename":
+			if pathCol < 0 {
+				pathCol = i
+			}
+		}
+	}
+	if pathCol < 0 {
+		return nil, fmt.Errorf("CSV %s needs a path, file or filename column", path)
+	}
+
+	c := &editCSV{byPath: map[string]map[string]string{}, byName: map[string]map[string]string{}, used: map[string]bool{}}
+	for _, rec := range records[1:] {
+		if pathCol >= len(rec) || strings.TrimSpace(rec[pathCol]) == "" {
+			continue
+		}
+		key := strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(rec[pathCol])), "./")
+		row := map[string]string{}
+		for i, v := range rec {
+			if i == pathCol || i >= len(header) || v == "" {
+				continue
+			}
+			row[strings.TrimSpace(header[i])] = v
+		}
+		if _, seen := c.byPath[key]; !seen {
+			c.order = append(c.order, key)
+		}
+		c.byPath[key] = row
+		if !strings.Contains(key, "/") {
+			c.byName[key] = row
+		}
+	}
+	return c, nil
+}
+
+// lookup returns the row for a file by relative path, falling back to a
+// row that gives only the file's base name. A row with a directory part
+// matches that path alone, so "a/track.flac" is not applied to
+// "b/track.flac".
+func (c *editCSV) lookup(rel string) map[string]string {
+	key := filepath.ToSlash(rel)
+	if row, ok := c.byPath[key]; ok {
+		c.used[key] = true
+		return row
+	}
+	base := filepath.Base(key)
+	if row, ok := c.byName[base]; ok {
+		c.used[base] = true
+		return row
+	}
+	return nil
+}
+
+// unused lists the CSV rows that matched no file, in file order.
+func (c *editCSV) unused() []string {
+	var out []string
+	for _, k := range c.order {
+		if !c.used[k] {
+			out = append(out, k)
+		}
 	}
+	return out
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -675,26 +3083,17 @@ func runBatchEdit(args []string) {
 
 // getHandler returns the appropriate Handler for the given file path.
 func getHandler(path string) (core.Handler, error) {
-	fmtID, err := core.DetectFormat(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot detect format of %s: %w", path, err)
-	}
-	if fmtID == core.FmtUnknown {
-		return nil, fmt.Errorf("unknown or unsupported format: %s", path)
-	}
+	return batch.HandlerFor(path)
+}
 
-	switch core.MediaTypeFor(fmtID) {
-	case "image":
-		return imgpkg.New(fmtID), nil
-	case "audio":
-		return audpkg.New(fmtID), nil
-	case "video":
-		return vidpkg.New(fmtID), nil
-	case "document":
-		return docpkg.New(fmtID), nil
-	default:
-		return nil, fmt.Errorf("no handler for format: %s", fmtID)
+// auditWrite records a write that does not go through a handler's Edit or
+// Strip in the audit log, when one is configured.
+func auditWrite(op, path, out string, dryRun bool, write func() error) error {
+	h, err := getHandler(path)
+	if dryRun || err != nil {
+		return write()
 	}
+	return core.AuditWrite(op, h, path, out, write)
 }
 
 // viewFile is a convenience wrapper.
@@ -706,6 +3105,226 @@ func viewFile(path string) (*core.Metadata, error) {
 	return h.View(path)
 }
 
+// unknownStructures lists the structures the parser of path skips.
+func unknownStructures(path string) ([]core.UnknownStructure, error) {
+	h, err := getHandler(path)
+	if err != nil {
+		return nil, err
+	}
+	return core.UnknownStructures(h, path)
+}
+
+// printUnknown prints the report of view --report-unknown. It goes to
+// stderr so that --json output stays parseable.
+func printUnknown(path string, found []core.UnknownStructure) {
+	if len(found) == 0 {
+		fmt.Fprintf(os.Stderr, "✓ No unknown structures in %s\n", path)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nUnknown structures in %s:\n", path)
+	kw, iw := unknownWidths(found)
+	for _, u := range found {
+		fmt.Fprintf(os.Stderr, "  %-*s  %-*s  %4d×  %10s\n", kw, u.Kind, iw, u.ID, u.Count, core.FormatSize(u.Bytes))
+	}
+}
+
+// printUnknownTally prints the totals of batch view --report-unknown, most
+// common first, for checked files; skipped files have formats without a
+// report.
+func printUnknownTally(total []core.UnknownStructure, checked, skipped int) {
+	core.SortUnknown(total)
+	fmt.Fprintf(os.Stderr, "\nUnknown structures in %d checked file(s)", checked)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, " (%d file(s) of formats without a report not checked)", skipped)
+	}
+	if len(total) == 0 {
+		fmt.Fprintln(os.Stderr, ": none")
+		return
+	}
+	fmt.Fprintln(os.Stderr, ":")
+	kw, iw := unknownWidths(total)
+	fw := 0
+	for _, u := range total {
+		fw = max(fw, len(u.Format))
+	}
+	for _, u := range total {
+		fmt.Fprintf(os.Stderr, "  %-*s  %-*s  %-*s  %5d×  in %d file(s)  %10s\n",
+			fw, u.Format, kw, u.Kind, iw, u.ID, u.Count, u.Files, core.FormatSize(u.Bytes))
+	}
+}
+
+// unknownWidths returns the widths of the kind and ID columns.
+func unknownWidths(list []core.UnknownStructure) (kind, id int) {
+	for _, u := range list {
+		kind = max(kind, len(u.Kind))
+		id = max(id, utf8.RuneCountInString(u.ID))
+	}
+	return kind, id
+}
+
+// ─── Interruption ────────────────────────────────────────────────────────────
+// Ctrl-C (SIGINT) or SIGTERM in the middle of a write would leave a
+// temporary file behind, or a half-written file with --force. Commands that
+// write catch them instead: the file being written is finished, a batch
+// stops before the next file, a transaction is rolled back, and the
+// summary says how to carry on with --resume-from (or --resume, given a
+// --state file). A second signal stops at once.
+
+// interrupted is set by the first signal after catchInterrupts.
+var interrupted atomic.Bool
+
+// catchInterrupts turns SIGINT and SIGTERM into the interrupted flag.
+func catchInterrupts() {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		interrupted.Store(true)
+		fmt.Fprintln(os.Stderr, "\n  Interrupted: finishing the current file (again to stop at once)")
+		<-ch
+		fmt.Fprintln(os.Stderr, "✗ Stopped: the file being written may be left half-written or as a temporary file beside it")
+		os.Exit(130)
+	}()
+}
+
+// exitIfInterrupted ends a single-file command that was interrupted once
+// its write has finished.
+func exitIfInterrupted() {
+	if interrupted.Load() {
+		os.Exit(130)
+	}
+}
+
+// resumeFrom drops the files before from, as given to --resume-from: a
+// path as the batch printed it, or relative to dir.
+func resumeFrom(files []string, dir, from string) []string {
+	if from == "" {
+		return files
+	}
+	for i, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		if filepath.Clean(from) == f || filepath.Clean(from) == rel {
+			return files[i:]
+		}
+	}
+	core.PrintError(fmt.Sprintf("--resume-from: %s is not among the files of %s", from, dir))
+	os.Exit(1)
+	return nil
+}
+
+// printInterrupted reports a batch run stopped before next, with the
+// command line that carries on from there: args with --resume-from next
+// (or --resume, when there is a state file) before the positional
+// arguments.
+func printInterrupted(cmd string, fs *flag.FlagSet, args []string, done, left int, next string) {
+	fmt.Fprintf(os.Stderr, "✗ Interrupted: %d file(s) processed, %d not started\n", done, left)
+	line := []string{"surgery", cmd}
+	flags := args[:len(args)-fs.NArg()]
+	for i := 0; i < len(flags); i++ {
+		switch name := strings.TrimLeft(flags[i], "-"); {
+		case name == "resume-from":
+			i++
+		case strings.HasPrefix(name, "resume-from="), flags[i] == "--":
+		default:
+			line = append(line, shellQuote(flags[i]))
+		}
+	}
+	if st := fs.Lookup("state"); st != nil && st.Value.String() != "" {
+		if r := fs.Lookup("resume"); r.Value.String() != "true" {
+			line = append(line, "--resume")
+		}
+	} else {
+		line = append(line, "--resume-from", shellQuote(next))
+	}
+	for _, a := range fs.Args() {
+		line = append(line, shellQuote(a))
+	}
+	fmt.Fprintf(os.Stderr, "  Resume with: %s\n", strings.Join(line, " "))
+}
+
+// stateFlags are the --state and --resume flags of the batch commands.
+// Unlike --resume-from, a state file also survives a crash or kill -9,
+// and a resumed run retries the files that failed.
+type stateFlags struct {
+	path   string
+	resume bool
+}
+
+func (f *stateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.path, "state", "", "Record the outcome of each file in this file as it is done")
+	fs.BoolVar(&f.resume, "resume", false, "Skip the files the --state file records as done")
+}
+
+// open opens the state file for cmd, or returns nil without --state.
+func (f *stateFlags) open(cmd string, dryRun bool) *batch.State {
+	switch {
+	case f.path == "" && f.resume:
+		core.PrintError("--resume needs --state <file>")
+		os.Exit(1)
+	case f.path == "":
+		return nil
+	case dryRun:
+		core.PrintError("--state records writes; it cannot be used with --dry-run")
+		os.Exit(1)
+	}
+	st, err := batch.OpenState(f.path, cmd, f.resume)
+	if err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+	return st
+}
+
+// files drops the state file from files, in case it lives among them.
+func (f *stateFlags) files(files []string) []string {
+	if f.path == "" {
+		return files
+	}
+	self, _ := filepath.Abs(f.path)
+	out := files[:0:0]
+	for _, p := range files {
+		if abs, _ := filepath.Abs(p); abs != self {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// recordState writes the outcome of path to st, if there is one.
+func recordState(st *batch.State, path, status string, err error) {
+	if st == nil {
+		return
+	}
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	st.Record(path, status, msg)
+}
+
+// closeState closes st and reports the files it skipped and any record it
+// could not write.
+func closeState(st *batch.State, resumed int) {
+	if st == nil {
+		return
+	}
+	if resumed > 0 {
+		fmt.Printf("  Resumed: %d file(s) done in an earlier run were skipped\n", resumed)
+	}
+	if err := st.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: %s; a resumed run will redo the files after it\n", err)
+	}
+	st.Close()
+}
+
+// shellQuote quotes s for a POSIX shell when it needs it.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_./:=@%+,-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // collectFiles gathers all regular files under dir.
 // If recursive is true, subdirectories are descended into.
 func collectFiles(dir string, recursive bool) []string {
@@ -723,6 +3342,10 @@ func collectFiles(dir string, recursive bool) []string {
 			}
 			continue
 		}
+		// Copies left by --backup are not files to process.
+		if core.IsBackupName(e.Name(), "") {
+			continue
+		}
 		// Only include files with recognised extensions
 		if _, err := core.DetectFormat(full); err == nil {
 			fid, _ := core.DetectFormat(full)
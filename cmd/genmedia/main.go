@@ -0,0 +1,75 @@
+// Command genmedia writes a small sample file of every format surgery
+// supports, each carrying known metadata, for tests, bug reports and
+// trying surgery out without touching real files:
+//
+//	go run ./cmd/genmedia ./samples
+//	go run ./cmd/genmedia -format jpeg,mp3 -list ./samples
+//
+// The files are the same on every run. With -list it also prints the
+// fields each file carries, as 'surgery view' reports them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/samplegen"
+)
+
+func main() {
+	formats := flag.String("format", "", "Comma-separated formats to write (default: all); see 'surgery formats'")
+	list := flag.Bool("list", false, "Print the fields each sample carries")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: genmedia [-format jpeg,png,...] [-list] [<dir>]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Write sample files with known metadata to dir (default: samples).")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "samples"
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		core.PrintError(err.Error())
+		os.Exit(1)
+	}
+
+	ids := samplegen.Formats()
+	if *formats != "" {
+		ids = nil
+		for _, f := range strings.Split(*formats, ",") {
+			ids = append(ids, core.FormatID(strings.ToLower(strings.TrimSpace(f))))
+		}
+	}
+	failed := false
+	for _, id := range ids {
+		s, ok := samplegen.For(id)
+		if !ok {
+			core.PrintError(fmt.Sprintf("no sample generator for format %q", id))
+			failed = true
+			continue
+		}
+		path := filepath.Join(dir, s.Name)
+		if err := os.WriteFile(path, s.Data, 0644); err != nil {
+			core.PrintError(err.Error())
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ %s (%d bytes)\n", path, len(s.Data))
+		if *list {
+			for _, k := range core.SortedKeys(s.Fields) {
+				fmt.Printf("    %s = %s\n", k, s.Fields[k])
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+// Command libsurgery builds surgery as a C shared library, so that Python,
+// Node, C# and C programs can call it without starting a process:
+//
+//	go build -buildmode=c-shared -o libsurgery.so ./cmd/libsurgery
+//
+// The build also writes libsurgery.h. Every function takes the contents of
+// a file. Results and error messages are allocated with malloc and must be
+// released with SurgeryFree; on failure the result is NULL and *err holds
+// the message. A parser that panics on a malformed file fails the call the
+// same way rather than aborting the host process.
+//
+//	char *ViewJSON(void *data, int len, char **err);
+//	void *StripBytes(void *data, int len, char *opts, int *outLen, char **err);
+//	void *EditBytes(void *data, int len, char *edits, int *outLen, char **err);
+//	void SurgeryFree(void *p);
+//
+// opts is a JSON object {"keep": [...], "gps_only": bool, "regions_only":
+// bool} and edits {"set": {...}, "delete": [...]}; either may be NULL.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"unsafe"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+)
+
+func main() {}
+
+// stripRequest is the opts argument of StripBytes.
+type stripRequest struct {
+	Keep        []string `json:"keep"`
+	GPSOnly     bool     `json:"gps_only"`
+	RegionsOnly bool     `json:"regions_only"`
+}
+
+// editRequest is the edits argument of EditBytes.
+type editRequest struct {
+	Set    map[string]string `json:"set"`
+	Delete []string          `json:"delete"`
+}
+
+//export ViewJSON
+func ViewJSON(data unsafe.Pointer, n C.int, errOut **C.char) *C.char {
+	defer recoverError(errOut)
+	m, err := batch.ViewBytes(C.GoBytes(data, n))
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	return C.CString(string(core.MetadataJSON(m)))
+}
+
+//export StripBytes
+func StripBytes(data unsafe.Pointer, n C.int, opts *C.char, outLen *C.int, errOut **C.char) unsafe.Pointer {
+	defer recoverError(errOut)
+	var req stripRequest
+	if err := decode(opts, &req); err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	out, err := batch.StripBytes(C.GoBytes(data, n), core.StripOptions{
+		KeepFields:   req.Keep,
+		StripGPS:     req.GPSOnly,
+		StripRegions: req.RegionsOnly,
+		StripAll:     len(req.Keep) == 0 && !req.GPSOnly && !req.RegionsOnly,
+	})
+	return result(out, err, outLen, errOut)
+}
+
+//export EditBytes
+func EditBytes(data unsafe.Pointer, n C.int, edits *C.char, outLen *C.int, errOut **C.char) unsafe.Pointer {
+	defer recoverError(errOut)
+	var req editRequest
+	if err := decode(edits, &req); err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	opts := core.NormalizeValues(core.EditOptions{Set: req.Set, Delete: req.Delete})
+	out, err := batch.EditBytes(C.GoBytes(data, n), opts)
+	return result(out, err, outLen, errOut)
+}
+
+//export SurgeryFree
+func SurgeryFree(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// decode parses the JSON string s into v; NULL leaves v as it is.
+func decode(s *C.char, v any) error {
+	if s == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(C.GoString(s)), v)
+}
+
+// result copies out to a malloc'd buffer, or reports err.
+func result(out []byte, err error, outLen *C.int, errOut **C.char) unsafe.Pointer {
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	if outLen != nil {
+		*outLen = C.int(len(out))
+	}
+	return C.CBytes(out)
+}
+
+// recoverError, deferred by each export, turns a panic into *errOut; the
+// export then returns NULL. A Go panic crossing into C would abort the
+// caller's process.
+func recoverError(errOut **C.char) {
+	if r := recover(); r != nil {
+		setError(errOut, fmt.Errorf("internal error: %v", r))
+	}
+}
+
+func setError(errOut **C.char, err error) {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+}
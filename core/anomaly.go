@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// ─── Anomaly scan ────────────────────────────────────────────────────────────
+// Uploads are a way into a network, and an image is a convenient carrier:
+// data appended after the end of the picture, a second EXIF block that
+// one reader honours and another ignores, a comment several kilobytes
+// long, or a metadata field holding ciphertext all pass through a viewer
+// unseen. An anomaly scan walks a file's structure looking for these. A
+// finding is a reason to look closer, not proof that anything is hidden.
+
+// AnomalyScanner is implemented by handlers that can scan a file for
+// structures used to hide data. Anomalies returns one message per
+// finding; a clean file returns none.
+type AnomalyScanner interface {
+	Anomalies(path string) ([]string, error)
+}
+
+// OversizedText is the size in bytes above which a comment or text field
+// is reported: real comments and captions are far shorter.
+const OversizedText = 16 << 10
+
+// A region is only judged by its entropy or alphabet once it is long
+// enough for the measure to mean something: random data of 512 bytes
+// measures about 7.6 bits per byte, text about 4.5 and base64 of random
+// data 6.
+const (
+	entropyMinLen = 512
+	entropyLimit  = 7.0
+	base64MinLen  = 1024
+	base64MinEnt  = 5.0 // a run of one letter is base64 too
+)
+
+// Entropy returns the Shannon entropy of b in bits per byte, from 0 for a
+// run of one value to 8 for uniformly random bytes.
+func Entropy(b []byte) float64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	var h float64
+	n := float64(len(b))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// PayloadSign returns why the contents of a metadata region that should
+// hold text or structured values look like a hidden payload: entropy close
+// to that of encrypted or compressed data, or a long run of base64. It
+// returns "" when b looks ordinary.
+func PayloadSign(b []byte) string {
+	if len(b) >= entropyMinLen {
+		if e := Entropy(b); e > entropyLimit {
+			return fmt.Sprintf("entropy %.2f bits/byte over %d bytes — looks encrypted or compressed, not text", e, len(b))
+		}
+	}
+	if len(b) >= base64MinLen && isBase64(b) && Entropy(b) > base64MinEnt {
+		return fmt.Sprintf("%d bytes of base64 — may encode a binary payload", len(b))
+	}
+	return ""
+}
+
+// isBase64 reports whether b is made only of the base64 alphabet, padding
+// and line breaks.
+func isBase64(b []byte) bool {
+	for _, c := range b {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '+', c == '/', c == '=', c == '\r', c == '\n':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// trailerSignatures are the starts of files commonly appended to images
+// to smuggle them past upload filters.
+var trailerSignatures = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("PK\x03\x04"), "ZIP archive"},
+	{[]byte("Rar!\x1a\x07"), "RAR archive"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), "7-Zip archive"},
+	{[]byte("\x1f\x8b"), "gzip stream"},
+	{[]byte("%PDF"), "PDF"},
+	{[]byte("MZ"), "Windows executable"},
+	{[]byte("\x7fELF"), "ELF executable"},
+	{[]byte("\xff\xd8\xff"), "JPEG"},
+	{[]byte("\x89PNG"), "PNG"},
+	{[]byte("<?php"), "PHP script"},
+	{[]byte("<script"), "script"},
+}
+
+// TrailerSign describes b, the bytes found after the end of a file's last
+// structure: what it starts with, or that it is only padding.
+func TrailerSign(b []byte) string {
+	s := fmt.Sprintf("%d bytes after the end of the file's data", len(b))
+	if len(bytes.Trim(b, "\x00")) == 0 {
+		return s + " (zero padding)"
+	}
+	for _, sig := range trailerSignatures {
+		if bytes.HasPrefix(b, sig.magic) {
+			return s + " (starts with a " + sig.name + ")"
+		}
+	}
+	if len(b) >= entropyMinLen {
+		return fmt.Sprintf("%s (entropy %.2f bits/byte)", s, Entropy(b))
+	}
+	return s
+}
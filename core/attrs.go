@@ -0,0 +1,38 @@
+package core
+
+import "os"
+
+// ─── File attributes ─────────────────────────────────────────────────────────
+// Rewriting a file gives the result a new inode: a handler that writes a
+// fresh file or renames one over the original drops its permissions,
+// owner and extended attributes (Finder tags, SELinux labels, user.*
+// notes) without anyone asking for that. CopyAttrs carries them from the
+// source to the output.
+
+// CopyAttrs gives dst the permissions of src and, where the platform and
+// the caller's privileges allow, its owner and extended attributes. Only a
+// failure to set the permissions is reported.
+func CopyAttrs(src, dst string) error {
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, st.Mode().Perm()); err != nil {
+		return err
+	}
+	copyOwner(st, dst)
+	copyXattrs(src, dst)
+	return nil
+}
+
+// copyXattrs copies every extended attribute of src that dst accepts.
+// Namespaces the caller may not write (security.*, trusted.* for non-root)
+// are skipped.
+func copyXattrs(src, dst string) {
+	names, _ := listXattrs(src)
+	for _, name := range names {
+		if v, err := getXattr(src, name); err == nil {
+			setXattr(dst, name, v)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package core
+
+import "os"
+
+func copyOwner(st os.FileInfo, dst string) {}
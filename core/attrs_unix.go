@@ -0,0 +1,24 @@
+//go:build unix
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives dst the owner and group of the file st describes. Only
+// root may give a file away, so for other users this changes at most the
+// group, and failures are ignored.
+func copyOwner(st os.FileInfo, dst string) {
+	sys, ok := st.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if cur, err := os.Stat(dst); err == nil {
+		if c, ok := cur.Sys().(*syscall.Stat_t); ok && c.Uid == sys.Uid && c.Gid == sys.Gid {
+			return
+		}
+	}
+	os.Chown(dst, int(sys.Uid), int(sys.Gid))
+}
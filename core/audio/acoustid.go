@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ─── AcoustID ────────────────────────────────────────────────────────────────
+// AcoustID tags hold a Chromaprint fingerprint of the audio and, once the
+// fingerprint has been looked up, the AcoustID it resolved to. Computing a
+// fingerprint means decoding the audio, which this tool does not do; a
+// Fingerprinter plugs in whatever does (fpcalc, a library binding, a
+// lookup service) so that batch edits can fill the tags.
+
+// Fingerprint is what a Fingerprinter found for one file. ID may be empty
+// when no lookup was made.
+type Fingerprint struct {
+	ID          string
+	Fingerprint string
+	Duration    int // seconds
+}
+
+// Fingerprinter computes the AcoustID fingerprint of an audio file.
+type Fingerprinter interface {
+	Fingerprint(path string) (Fingerprint, error)
+}
+
+// CommandFingerprinter runs an external command with the file path as its
+// last argument, e.g. "fpcalc" or "fpcalc -json". The output may be JSON
+// or fpcalc's KEY=VALUE lines; DURATION, FINGERPRINT and ID (or
+// ACOUSTID_ID) are read, so a wrapper script can add a lookup.
+type CommandFingerprinter struct {
+	Command string
+}
+
+func (c CommandFingerprinter) Fingerprint(path string) (Fingerprint, error) {
+	args := strings.Fields(c.Command)
+	if len(args) == 0 {
+		return Fingerprint{}, fmt.Errorf("no fingerprint command given")
+	}
+	out, err := exec.Command(args[0], append(args[1:], path)...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return Fingerprint{}, fmt.Errorf("%s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+		}
+		return Fingerprint{}, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return parseFingerprintOutput(out)
+}
+
+func parseFingerprintOutput(out []byte) (Fingerprint, error) {
+	var fp Fingerprint
+	if trimmed := bytes.TrimSpace(out); len(trimmed) > 0 && trimmed[0] == '{' {
+		var j struct {
+			ID          string  `json:"id"`
+			AcoustID    string  `json:"acoustid_id"`
+			Fingerprint string  `json:"fingerprint"`
+			Duration    float64 `json:"duration"`
+		}
+		if err := json.Unmarshal(trimmed, &j); err != nil {
+			return fp, fmt.Errorf("invalid fingerprint output: %w", err)
+		}
+		fp = Fingerprint{ID: j.ID, Fingerprint: j.Fingerprint, Duration: int(j.Duration + 0.5)}
+		if fp.ID == "" {
+			fp.ID = j.AcoustID
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(out))
+		sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for sc.Scan() {
+			k, v, ok := strings.Cut(sc.Text(), "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToUpper(strings.TrimSpace(k)) {
+			case "FINGERPRINT":
+				fp.Fingerprint = strings.TrimSpace(v)
+			case "ID", "ACOUSTID_ID":
+				fp.ID = strings.TrimSpace(v)
+			case "DURATION":
+				fmt.Sscanf(strings.TrimSpace(v), "%d", &fp.Duration)
+			}
+		}
+	}
+	if fp.Fingerprint == "" && fp.ID == "" {
+		return fp, fmt.Errorf("fingerprint command printed no FINGERPRINT or ID")
+	}
+	return fp, nil
+}
+
+// AcoustIDFields runs fp on path and returns the values to write, keyed
+// by the canonical field names accepted by edit.
+func AcoustIDFields(path string, fp Fingerprinter) (map[string]string, error) {
+	res, err := fp.Fingerprint(path)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]string)
+	if res.Fingerprint != "" {
+		set["AcoustIDFingerprint"] = res.Fingerprint
+	}
+	if res.ID != "" {
+		set["AcoustID"] = res.ID
+	}
+	return set, nil
+}
@@ -0,0 +1,165 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Audio analysis ──────────────────────────────────────────────────────────
+// BPM, musical key and loudness are computed from the decoded audio, which
+// this tool does not do. An Analyzer plugs in whatever does (a library
+// binding, aubio, essentia, ffmpeg's ebur128 filter in a script) and
+// AnalysisFields turns its results into the tags players read: TBPM and
+// TKEY in ID3, BPM and INITIALKEY in Vorbis, and ReplayGain for loudness.
+
+// Analyzer computes values for an audio file. Keys are matched
+// case-insensitively; see AnalysisFields for the ones that become tags.
+type Analyzer interface {
+	Analyze(path string) (map[string]string, error)
+}
+
+var analyzers = make(map[string]Analyzer)
+
+// RegisterAnalyzer makes a selectable by name (analyze --analyzer NAME).
+// Programs embedding this package call it from an init function.
+func RegisterAnalyzer(name string, a Analyzer) {
+	analyzers[strings.ToLower(name)] = a
+}
+
+// LookupAnalyzer returns the analyzer registered under name.
+func LookupAnalyzer(name string) (Analyzer, bool) {
+	a, ok := analyzers[strings.ToLower(name)]
+	return a, ok
+}
+
+// AnalyzerNames lists the registered analyzers.
+func AnalyzerNames() []string {
+	names := make([]string, 0, len(analyzers))
+	for n := range analyzers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CommandAnalyzer runs an external command with the file path as its last
+// argument. The output may be a JSON object or KEY=VALUE lines.
+type CommandAnalyzer struct {
+	Command string
+}
+
+func (c CommandAnalyzer) Analyze(path string) (map[string]string, error) {
+	args := strings.Fields(c.Command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no analyzer command given")
+	}
+	out, err := exec.Command(args[0], append(args[1:], path)...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return parseAnalyzerOutput(out)
+}
+
+func parseAnalyzerOutput(out []byte) (map[string]string, error) {
+	res := make(map[string]string)
+	if trimmed := bytes.TrimSpace(out); len(trimmed) > 0 && trimmed[0] == '{' {
+		var j map[string]interface{}
+		if err := json.Unmarshal(trimmed, &j); err != nil {
+			return nil, fmt.Errorf("invalid analyzer output: %w", err)
+		}
+		for k, v := range j {
+			switch v := v.(type) {
+			case string:
+				res[k] = v
+			case float64:
+				res[k] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	} else {
+		sc := bufio.NewScanner(bytes.NewReader(out))
+		for sc.Scan() {
+			if k, v, ok := strings.Cut(sc.Text(), "="); ok {
+				res[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("analyzer command printed no KEY=VALUE results")
+	}
+	return res, nil
+}
+
+// replayGainReference is the ReplayGain 2.0 target loudness, in LUFS.
+const replayGainReference = -18.0
+
+// AnalysisFields runs a on path and returns the tags to write for format,
+// keyed as that format's edit accepts them. BPM (or tempo) and key go to
+// the standard fields; an integrated loudness in LUFS (loudness, lufs)
+// becomes a ReplayGain track gain against -18 LUFS unless a gain is given.
+// ReplayGain and other known identifiers pass through under their
+// canonical names; anything else is skipped with a note.
+func AnalysisFields(path string, a Analyzer, format core.FormatID) (map[string]string, error) {
+	res, err := a.Analyze(path)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]string)
+	var lufs string
+	for k, v := range res {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		switch strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(k)) {
+		case "bpm", "tempo":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				v = strconv.Itoa(int(f + 0.5)) // TBPM is an integer
+			}
+			set[analysisTag("BPM", format)] = v
+		case "key", "initialkey":
+			set[analysisTag("InitialKey", format)] = v
+		case "loudness", "lufs", "integratedloudness":
+			lufs = v
+		default:
+			if f, ok := freeformTagFor(k); ok {
+				set[f.name] = v
+			} else {
+				fmt.Printf("  Note: analyzer result %q has no tag — skipped\n", k)
+			}
+		}
+	}
+	if _, ok := set["ReplayGainTrackGain"]; !ok && lufs != "" {
+		l, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(lufs), "LUFS"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("loudness: %q is not a number of LUFS", lufs)
+		}
+		set["ReplayGainTrackGain"] = fmt.Sprintf("%+.2f dB", replayGainReference-l)
+	}
+	return set, nil
+}
+
+// analysisTag returns the field name edit uses for name in format.
+func analysisTag(name string, format core.FormatID) string {
+	for _, m := range tagMappings {
+		if m.name != name {
+			continue
+		}
+		switch format {
+		case core.FmtMP3:
+			return m.id3
+		case core.FmtFLAC:
+			return m.vorbis
+		}
+	}
+	return name
+}
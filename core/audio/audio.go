@@ -10,9 +10,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
 	"github.com/bogem/id3v2/v2"
 	"github.com/dhowden/tag"
 )
@@ -38,12 +40,13 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "ID3v1 and ID3v2 tags. Full edit + strip support.",
+		Notes:       "ID3v1 and ID3v2 tags. Full edit + strip support. APEv2 and Lyrics3 are shown and removed on strip (--keep ape / lyrics3 / id3v1).",
 		EditableFields: []string{
 			"Title", "Artist", "Album", "Year", "Genre",
 			"Comment", "TrackNumber", "AlbumArtist", "Composer",
 			"Lyrics", "Copyright",
 		},
+		Options: []core.FormatOption{optID3Version},
 	},
 	core.FmtFLAC: {
 		Name:        "FLAC",
@@ -53,7 +56,7 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "Vorbis Comment metadata blocks.",
+		Notes:       "Vorbis Comment metadata blocks. APPLICATION and CUESHEET blocks are shown and kept on strip unless --remove names them.",
 		EditableFields: []string{
 			"TITLE", "ARTIST", "ALBUM", "DATE", "GENRE",
 			"COMMENT", "TRACKNUMBER", "ALBUMARTIST", "COMPOSER", "COPYRIGHT",
@@ -65,9 +68,13 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "audio",
 		MIMETypes:   []string{"audio/ogg"},
 		CanView:     true,
-		CanEdit:     false,
-		CanStrip:    false,
-		Notes:       "Vorbis Comment blocks. View only in v0.1.2.",
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "Vorbis comment header, rewritten page by page; audio pages are renumbered but not changed.",
+		EditableFields: []string{
+			"TITLE", "ARTIST", "ALBUM", "DATE", "GENRE",
+			"COMMENT", "TRACKNUMBER", "ALBUMARTIST", "COMPOSER", "COPYRIGHT",
+		},
 	},
 	core.FmtOpus: {
 		Name:        "Opus",
@@ -75,9 +82,13 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "audio",
 		MIMETypes:   []string{"audio/opus"},
 		CanView:     true,
-		CanEdit:     false,
-		CanStrip:    false,
-		Notes:       "Vorbis Comment blocks. View only in v0.1.2.",
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "OpusTags header, rewritten page by page; audio pages are renumbered but not changed.",
+		EditableFields: []string{
+			"TITLE", "ARTIST", "ALBUM", "DATE", "GENRE",
+			"COMMENT", "TRACKNUMBER", "ALBUMARTIST", "COMPOSER", "COPYRIGHT",
+		},
 	},
 	core.FmtM4A: {
 		Name:        "M4A/AAC",
@@ -85,9 +96,13 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "audio",
 		MIMETypes:   []string{"audio/mp4", "audio/aac"},
 		CanView:     true,
-		CanEdit:     false,
-		CanStrip:    false,
-		Notes:       "iTunes-style MP4 atoms (©nam, ©ART, etc.). View only in v0.1.2.",
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "iTunes-style MP4 atoms (©nam, ©ART, etc.), written by the same ilst writer as MP4. Strip keeps iTunSMPB (gapless playback).",
+		EditableFields: []string{
+			"Title", "Artist", "Album", "AlbumArtist", "Composer", "Genre",
+			"Year", "TrackNumber", "DiscNumber", "Comment", "Lyrics", "Copyright",
+		},
 	},
 	core.FmtWAV: {
 		Name:        "WAV",
@@ -117,11 +132,39 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 
 func (h *Handler) View(path string) (*core.Metadata, error) {
 	m := &core.Metadata{FilePath: path}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return m, err
+	}
 	ext := strings.ToLower(filepath.Ext(path))
 	_ = ext
 
 	switch h.format {
-	case core.FmtMP3, core.FmtFLAC, core.FmtOGG, core.FmtOpus, core.FmtM4A:
+	case core.FmtMP3:
+		m.Format = "MP3"
+		_, err := viewWithDhowden(path, m)
+		// APEv2 / Lyrics3 may be the only tags present, and an untagged
+		// file still has stream and encoder details worth showing.
+		hasTrailers := addMP3Trailers(path, m)
+		if addMP3StreamInfo(path, m) || hasTrailers {
+			err = nil
+		}
+		return m, err
+	case core.FmtFLAC:
+		m.Format = "FLAC"
+		_, err := viewWithDhowden(path, m)
+		addFLACBlockInfo(path, m)
+		return m, err
+	case core.FmtM4A:
+		m.Format = "M4A/AAC"
+		_, err := viewWithDhowden(path, m)
+		for _, f := range m.Fields {
+			if f.Key == "iTunSMPB" {
+				m.Fields = append(m.Fields, video.GaplessFields(f.Value, "Gapless Playback")...)
+				break
+			}
+		}
+		return m, err
+	case core.FmtOGG, core.FmtOpus:
 		m.Format = formatInfo[h.format].Name
 		return viewWithDhowden(path, m)
 	case core.FmtWAV:
@@ -144,6 +187,7 @@ func viewWithDhowden(path string, m *core.Metadata) (*core.Metadata, error) {
 	}
 	defer f.Close()
 
+	from := len(m.Fields)
 	t, err := tag.ReadFrom(f)
 	if err != nil {
 		return m, fmt.Errorf("could not read tags: %w", err)
@@ -166,7 +210,8 @@ func viewWithDhowden(path string, m *core.Metadata) (*core.Metadata, error) {
 		}
 	}
 
-	editable := (m.Format == "MP3" || m.Format == "FLAC")
+	editable := (m.Format == "MP3" || m.Format == "FLAC" || m.Format == "M4A/AAC" ||
+		m.Format == "OGG" || m.Format == "Opus")
 
 	add("Title", t.Title(), editable)
 	add("Artist", t.Artist(), editable)
@@ -198,9 +243,20 @@ func viewWithDhowden(path string, m *core.Metadata) (*core.Metadata, error) {
 		add("Lyrics", t.Lyrics(), editable)
 	}
 
+	used := addFreeformTags(t.Raw(), t.Format(), m, editable)
+	if pics, err := ListPictures(path); err == nil {
+		for _, p := range pics {
+			key := "Picture"
+			if len(pics) > 1 {
+				key = fmt.Sprintf("Picture%d", p.Index)
+			}
+			m.Fields = append(m.Fields, core.MetaField{Key: key, Value: p.String(), Category: "Cover Art", Editable: false})
+		}
+	}
+
 	// Raw tags
 	for k, v := range t.Raw() {
-		if v == nil {
+		if v == nil || used[k] {
 			continue
 		}
 		// Skip keys already displayed
@@ -210,6 +266,12 @@ func viewWithDhowden(path string, m *core.Metadata) (*core.Metadata, error) {
 			"disc", "discnumber", "lyrics":
 			continue
 		}
+		// M4A atoms already shown under their friendly names or as Cover Art
+		switch k {
+		case "\xa9nam", "\xa9ART", "\xa9alb", "aART", "\xa9wrt", "\xa9gen", "\xa9cmt",
+			"\xa9day", "\xa9lyr", "trkn", "trkn_count", "disk", "disk_count", "covr":
+			continue
+		}
 		valStr := ""
 		switch vt := v.(type) {
 		case string:
@@ -232,6 +294,16 @@ func viewWithDhowden(path string, m *core.Metadata) (*core.Metadata, error) {
 		}
 	}
 
+	// An ID3v2 tag is read from the start of the file, an ID3v1 tag from
+	// its last 128 bytes.
+	switch t.Format() {
+	case tag.ID3v2_2, tag.ID3v2_3, tag.ID3v2_4:
+		m.SetSource(from, core.FieldSource{Store: cat, Structure: "ID3v2 tag"})
+	case tag.ID3v1:
+		if st, err := f.Stat(); err == nil {
+			m.SetSource(from, core.FieldSource{Store: cat, Structure: "ID3v1 tag", Offset: st.Size() - 128})
+		}
+	}
 	return m, nil
 }
 
@@ -265,37 +337,54 @@ var infoChunkNames = map[string]string{
 }
 
 func viewWAV(path string, m *core.Metadata) (*core.Metadata, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return m, err
 	}
-	if len(data) < 12 {
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return m, err
+	}
+	if st.Size() < 12 {
 		return m, fmt.Errorf("WAV too short")
 	}
+	// Only chunk headers are read; the audio in "data" is seeked past.
+	chunks, err := core.RIFFChunks(f, 12, st.Size())
+	if err != nil {
+		return m, err
+	}
 
 	// RIFF header info
-	sampleRate := binary.LittleEndian.Uint32(data[24:28])
-	channels := binary.LittleEndian.Uint16(data[22:24])
-	bitsPerSample := binary.LittleEndian.Uint16(data[34:36])
-
-	m.Fields = append(m.Fields,
-		core.MetaField{Key: "SampleRate", Value: fmt.Sprintf("%d Hz", sampleRate), Category: "WAV Header", Editable: false},
-		core.MetaField{Key: "Channels", Value: fmt.Sprintf("%d", channels), Category: "WAV Header", Editable: false},
-		core.MetaField{Key: "BitsPerSample", Value: fmt.Sprintf("%d", bitsPerSample), Category: "WAV Header", Editable: false},
-	)
-
-	// Scan LIST INFO chunk
-	offset := 12
-	for offset+8 <= len(data) {
-		chunkID := string(data[offset : offset+4])
-		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
-		offset += 8
-		if offset+chunkSize > len(data) {
+	for _, c := range chunks {
+		if c.ID != "fmt " || c.Size < 16 {
+			continue
+		}
+		fmtData, err := core.ReadRange(f, c.Off, 16)
+		if err != nil || len(fmtData) < 16 {
 			break
 		}
-		if chunkID == "LIST" && offset+4 <= len(data) && string(data[offset:offset+4]) == "INFO" {
-			pos := offset + 4
-			end := offset + chunkSize
+		sampleRate := binary.LittleEndian.Uint32(fmtData[4:8])
+		channels := binary.LittleEndian.Uint16(fmtData[2:4])
+		bitsPerSample := binary.LittleEndian.Uint16(fmtData[14:16])
+
+		m.Fields = append(m.Fields,
+			core.MetaField{Key: "SampleRate", Value: fmt.Sprintf("%d Hz", sampleRate), Category: "WAV Header", Editable: false},
+			core.MetaField{Key: "Channels", Value: fmt.Sprintf("%d", channels), Category: "WAV Header", Editable: false},
+			core.MetaField{Key: "BitsPerSample", Value: fmt.Sprintf("%d", bitsPerSample), Category: "WAV Header", Editable: false},
+		)
+		break
+	}
+
+	// Scan LIST INFO chunk
+	for _, c := range chunks {
+		if c.ID == "LIST" && c.Size >= 4 {
+			data, err := c.Read(f)
+			if err != nil || len(data) < 4 || string(data[:4]) != "INFO" {
+				continue
+			}
+			pos := 4
+			end := len(data)
 			for pos+8 <= end {
 				infoID := string(data[pos : pos+4])
 				infoSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
@@ -323,24 +412,20 @@ func viewWAV(path string, m *core.Metadata) (*core.Metadata, error) {
 			}
 		}
 		// Also check for ID3 chunk
-		if chunkID == "id3 " || chunkID == "ID3 " {
-			f2, err := os.Open(path)
-			if err == nil {
-				defer f2.Close()
-				t, err := tag.ReadFrom(f2)
-				if err == nil && t.Title() != "" {
-					m.Fields = append(m.Fields, core.MetaField{
-						Key:      "Title",
-						Value:    t.Title(),
-						Category: "WAV ID3",
-						Editable: false,
-					})
-				}
+		if c.ID == "id3 " || c.ID == "ID3 " {
+			data, err := c.Read(f)
+			if err != nil {
+				continue
+			}
+			t, err := tag.ReadFrom(bytes.NewReader(data))
+			if err == nil && t.Title() != "" {
+				m.Fields = append(m.Fields, core.MetaField{
+					Key:      "Title",
+					Value:    t.Title(),
+					Category: "WAV ID3",
+					Editable: false,
+				})
 			}
-		}
-		offset += chunkSize
-		if chunkSize%2 != 0 {
-			offset++
 		}
 	}
 	return m, nil
@@ -436,12 +521,19 @@ func addFromTag(t tag.Metadata, m *core.Metadata, cat string) {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtMP3:
 		return editMP3(path, out, opts)
 	case core.FmtFLAC:
 		return editFLAC(path, out, opts)
+	case core.FmtM4A:
+		return editM4A(path, out, opts)
+	case core.FmtOGG, core.FmtOpus:
+		return editOgg(path, out, opts, formatInfo[h.format].Name)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanEdit {
@@ -454,85 +546,96 @@ func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error
 // ─── MP3 Edit ─────────────────────────────────────────────────────────────────
 
 func editMP3(path, outPath string, opts core.EditOptions) error {
+	version, err := id3Version(opts.Options)
+	if err != nil {
+		return err
+	}
 	if opts.DryRun {
 		fmt.Println("Dry-run: MP3 ID3 tags would be updated:")
 		for k, v := range opts.Set {
 			fmt.Printf("  %s = %s\n", k, v)
 		}
-		return nil
-	}
-
-	// Copy to outPath first if different
-	if path != outPath {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		if err := os.WriteFile(outPath, data, 0644); err != nil {
-			return err
+		if version != 0 {
+			fmt.Printf("  (tag saved as ID3v2.%d)\n", version)
 		}
+		return nil
 	}
 
-	t, err := id3v2.Open(outPath, id3v2.Options{Parse: true})
-	if err != nil {
-		return fmt.Errorf("could not open MP3: %w", err)
+	opts, numbers := takeNumberEdits(opts)
+	var after func(string) error
+	if opts.Deterministic {
+		after = sortID3Frames
 	}
-	defer t.Close()
-
-	// Apply deletions
-	for _, k := range opts.Delete {
-		// id3v2 uses 4-char frame IDs; map friendly names
-		if fid := mp3FrameID(k); fid != "" {
-			t.DeleteFrames(fid)
+	return rewriteMP3(path, outPath, func(t *id3v2.Tag) {
+		// Apply deletions
+		for _, k := range opts.Delete {
+			// id3v2 uses 4-char frame IDs; map friendly names
+			if f, ok := freeformTagFor(k); ok {
+				setID3Freeform(t, f, "")
+			} else if fid := mp3FrameID(k); fid != "" {
+				t.DeleteFrames(fid)
+			}
 		}
-	}
 
-	// Apply sets
-	for k, v := range opts.Set {
-		switch strings.ToLower(k) {
-		case "title":
-			t.SetTitle(v)
-		case "artist":
-			t.SetArtist(v)
-		case "album":
-			t.SetAlbum(v)
-		case "year":
-			t.SetYear(v)
-		case "genre":
-			t.SetGenre(v)
-		case "comment":
-			t.AddCommentFrame(id3v2.CommentFrame{
-				Encoding:    id3v2.EncodingUTF8,
-				Language:    "eng",
-				Description: "",
-				Text:        v,
-			})
-		case "tracknumber":
-			t.AddTextFrame(t.CommonID("Track number/Position in set"), id3v2.EncodingUTF8, v)
-		case "albumartist":
-			t.AddTextFrame("TPE2", id3v2.EncodingUTF8, v)
-		case "composer":
-			t.AddTextFrame(t.CommonID("Composer"), id3v2.EncodingUTF8, v)
-		case "lyrics":
-			t.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
-				Encoding:          id3v2.EncodingUTF8,
-				Language:          "eng",
-				ContentDescriptor: "",
-				Lyrics:            v,
-			})
-		case "copyright":
-			t.AddTextFrame("TCOP", id3v2.EncodingUTF8, v)
-		default:
-			// Try as raw frame ID (e.g. "TIT2")
-			if len(k) == 4 {
-				t.AddTextFrame(k, id3v2.EncodingUTF8, v)
-			} else {
-				fmt.Printf("  Warning: unknown MP3 field %q — skipped\n", k)
+		// Apply sets
+		for _, k := range core.SortedKeys(opts.Set) {
+			v := opts.Set[k]
+			switch strings.ToLower(k) {
+			case "title":
+				t.SetTitle(v)
+			case "artist":
+				t.SetArtist(v)
+			case "album":
+				t.SetAlbum(v)
+			case "year":
+				t.SetYear(v)
+			case "genre":
+				t.SetGenre(v)
+			case "comment":
+				t.AddCommentFrame(id3v2.CommentFrame{
+					Encoding:    id3v2.EncodingUTF8,
+					Language:    "eng",
+					Description: "",
+					Text:        v,
+				})
+			case "albumartist":
+				t.AddTextFrame("TPE2", id3v2.EncodingUTF8, v)
+			case "composer":
+				t.AddTextFrame(t.CommonID("Composer"), id3v2.EncodingUTF8, v)
+			case "lyrics":
+				t.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+					Encoding:          id3v2.EncodingUTF8,
+					Language:          "eng",
+					ContentDescriptor: "",
+					Lyrics:            v,
+				})
+			case "copyright":
+				t.AddTextFrame("TCOP", id3v2.EncodingUTF8, v)
+			case "bpm":
+				t.AddTextFrame("TBPM", id3v2.EncodingUTF8, v)
+			case "initialkey":
+				t.AddTextFrame("TKEY", id3v2.EncodingUTF8, v)
+			default:
+				if f, ok := freeformTagFor(k); ok {
+					setID3Freeform(t, f, v)
+					continue
+				}
+				// Try as raw frame ID (e.g. "TIT2")
+				if len(k) == 4 {
+					t.AddTextFrame(k, id3v2.EncodingUTF8, v)
+				} else {
+					fmt.Printf("  Warning: unknown MP3 field %q — skipped\n", k)
+				}
 			}
 		}
-	}
 
-	return t.Save()
+		for _, e := range numbers {
+			writeID3Number(t, e, opts.NumberPad)
+		}
+		if version != 0 {
+			convertID3Version(t, version)
+		}
+	}, after)
 }
 
 // mp3FrameID maps friendly names to ID3v2 frame IDs.
@@ -545,10 +648,13 @@ func mp3FrameID(name string) string {
 		"genre":        "TCON",
 		"comment":      "COMM",
 		"tracknumber":  "TRCK",
+		"discnumber":   "TPOS",
 		"albumartist":  "TPE2",
 		"composer":     "TCOM",
 		"lyrics":       "USLT",
 		"copyright":    "TCOP",
+		"bpm":          "TBPM",
+		"initialkey":   "TKEY",
 	}
 	return m[strings.ToLower(name)]
 }
@@ -573,6 +679,9 @@ func editFLAC(path, outPath string, opts core.EditOptions) error {
 		for k, v := range opts.Set {
 			fmt.Printf("  %s = %s\n", k, v)
 		}
+		for _, kv := range opts.Add {
+			fmt.Printf("  %s += %s\n", kv.Key, kv.Value)
+		}
 		return nil
 	}
 
@@ -592,21 +701,11 @@ func editFLAC(path, outPath string, opts core.EditOptions) error {
 	}
 
 	// Build updated Vorbis comment block
-	existing := map[string]string{}
+	var old []byte
 	if vcIdx >= 0 {
-		parseVorbisComments(blocks[vcIdx].data, existing)
-	}
-
-	// Apply sets (uppercase keys per Vorbis spec)
-	for k, v := range opts.Set {
-		existing[strings.ToUpper(k)] = v
-	}
-	// Apply deletes
-	for _, k := range opts.Delete {
-		delete(existing, strings.ToUpper(k))
+		old = blocks[vcIdx].data
 	}
-
-	newVC := buildVorbisComment(existing)
+	newVC := editVorbisComment(old, opts)
 	if vcIdx >= 0 {
 		blocks[vcIdx].data = newVC
 	} else {
@@ -645,17 +744,111 @@ func parseFLACBlocks(data []byte) ([]flacBlock, int, error) {
 	return blocks, i, nil
 }
 
-func parseVorbisComments(data []byte, out map[string]string) {
-	if len(data) < 4 {
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacPadding       = 1
+	flacApplication   = 2
+	flacSeekTable     = 3
+	flacVorbisComment = 4
+	flacCueSheet      = 5
+	flacPicture       = 6
+)
+
+// addFLACBlockInfo reports the structural metadata blocks that dhowden/tag
+// does not expose: APPLICATION and CUESHEET.
+func addFLACBlockInfo(path string, m *core.Metadata) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) < 4 || !bytes.Equal(data[0:4], []byte("fLaC")) {
 		return
 	}
+	blocks, _, _ := parseFLACBlocks(data)
+	off := int64(4)
+	count := map[byte]int{}
+	for _, b := range blocks {
+		from := len(m.Fields)
+		src := core.FieldSource{Store: "FLAC", Structure: flacBlockNames[b.blockType], Index: count[b.blockType], Offset: off}
+		off += 4 + int64(len(b.data))
+		count[b.blockType]++
+		switch b.blockType {
+		case flacApplication:
+			if len(b.data) < 4 {
+				continue
+			}
+			m.Fields = append(m.Fields, core.MetaField{
+				Key:      "Application",
+				Value:    fmt.Sprintf("%q (%d bytes)", string(b.data[0:4]), len(b.data)-4),
+				Category: "FLAC Blocks",
+				Editable: false,
+			})
+		case flacCueSheet:
+			addFLACCueSheet(b.data, m)
+		case flacVorbisComment:
+			// Read earlier by dhowden/tag, which does not say where.
+			src.Store = "VORBIS"
+			for i := range m.Fields[:from] {
+				if f := &m.Fields[i]; f.Source == nil && strings.HasPrefix(f.Category, "VORBIS") {
+					s := src
+					f.Source = &s
+				}
+			}
+		}
+		m.SetSource(from, src)
+	}
+}
+
+// addFLACCueSheet decodes the CUESHEET header and per-track ISRCs.
+// Layout: 128 catalog, 8 lead-in, 1 flags, 258 reserved, 1 track count,
+// then tracks of 36 bytes plus 12 bytes per index point.
+func addFLACCueSheet(data []byte, m *core.Metadata) {
+	if len(data) < 396 {
+		return
+	}
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "FLAC CueSheet", Editable: false})
+	}
+	if catalog := strings.TrimRight(string(data[0:128]), "\x00"); catalog != "" {
+		add("CatalogNumber", catalog)
+	}
+	add("IsCD", fmt.Sprintf("%v", data[136]&0x80 != 0))
+	tracks := int(data[395])
+	add("Tracks", fmt.Sprintf("%d", tracks))
+
+	pos := 396
+	for i := 0; i < tracks && pos+36 <= len(data); i++ {
+		num := data[pos+8]
+		isrc := strings.TrimRight(string(data[pos+9:pos+21]), "\x00")
+		indices := int(data[pos+35])
+		if isrc != "" && num != 170 { // 170 = lead-out
+			add(fmt.Sprintf("Track%02d.ISRC", num), isrc)
+		}
+		pos += 36 + indices*12
+	}
+}
+
+// vorbisComment is a single KEY=VALUE entry. Keys keep the case they were
+// written with; lookups are case-insensitive as the Vorbis spec requires.
+// Comments are kept as an ordered list because keys may repeat (several
+// ARTIST entries are common) and order is meaningful to players.
+type vorbisComment struct {
+	key   string
+	value string
+}
+
+type vorbisComments []vorbisComment
+
+func parseVorbisComments(data []byte) vorbisComments {
+	if len(data) < 4 {
+		return nil
+	}
 	vendorLen := int(binary.LittleEndian.Uint32(data[0:4]))
 	pos := 4 + vendorLen
 	if pos+4 > len(data) {
-		return
+		return nil
 	}
 	count := int(binary.LittleEndian.Uint32(data[pos : pos+4]))
 	pos += 4
+	var out vorbisComments
 	for i := 0; i < count && pos+4 <= len(data); i++ {
 		cLen := int(binary.LittleEndian.Uint32(data[pos : pos+4]))
 		pos += 4
@@ -666,13 +859,129 @@ func parseVorbisComments(data []byte, out map[string]string) {
 		pos += cLen
 		eq := strings.Index(comment, "=")
 		if eq > 0 {
-			out[strings.ToUpper(comment[:eq])] = comment[eq+1:]
+			out = append(out, vorbisComment{key: comment[:eq], value: comment[eq+1:]})
+		}
+	}
+	return out
+}
+
+// set replaces every value of key with a single value, keeping the position
+// (and key spelling) of the first existing entry. New keys are appended in
+// upper case.
+func (c vorbisComments) set(key, value string) vorbisComments {
+	var out vorbisComments
+	done := false
+	for _, e := range c {
+		if strings.EqualFold(e.key, key) {
+			if !done {
+				out = append(out, vorbisComment{key: e.key, value: value})
+				done = true
+			}
+			continue
 		}
+		out = append(out, e)
+	}
+	if !done {
+		out = append(out, vorbisComment{key: strings.ToUpper(key), value: value})
+	}
+	return out
+}
+
+// add appends one more value for key, after any existing values of it.
+// get returns the first value of key, or "".
+func (c vorbisComments) get(key string) string {
+	for _, e := range c {
+		if strings.EqualFold(e.key, key) {
+			return e.value
+		}
+	}
+	return ""
+}
+
+func (c vorbisComments) add(key, value string) vorbisComments {
+	last := -1
+	for i, e := range c {
+		if strings.EqualFold(e.key, key) {
+			last = i
+		}
+	}
+	if last < 0 {
+		return append(c, vorbisComment{key: strings.ToUpper(key), value: value})
+	}
+	out := append(vorbisComments{}, c[:last+1]...)
+	out = append(out, vorbisComment{key: c[last].key, value: value})
+	return append(out, c[last+1:]...)
+}
+
+// remove drops every value of key. If value is non-empty only entries with
+// exactly that value are dropped.
+func (c vorbisComments) remove(key, value string) vorbisComments {
+	var out vorbisComments
+	for _, e := range c {
+		if strings.EqualFold(e.key, key) && (value == "" || e.value == value) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// applyVorbisEdits applies EditOptions to a comment list:
+//   - Set "KEY=VALUE"   replaces all values of KEY
+//   - Set "KEY+=VALUE"  adds one more value of KEY
+//   - Add KEY, VALUE    adds one more value of KEY, in list order
+//   - Delete "KEY"      removes all values of KEY
+//   - Delete "KEY=VAL"  removes only the entry with that value
+func applyVorbisEdits(c vorbisComments, opts core.EditOptions) vorbisComments {
+	opts, numbers := takeNumberEdits(opts)
+	for _, k := range core.SortedKeys(opts.Set) {
+		v := opts.Set[k]
+		if f, ok := freeformTagFor(strings.TrimSuffix(k, "+")); ok {
+			k = f.vorbis + k[len(strings.TrimSuffix(k, "+")):]
+		}
+		if strings.HasSuffix(k, "+") {
+			c = c.add(strings.TrimSuffix(k, "+"), v)
+		} else {
+			c = c.set(k, v)
+		}
+	}
+	for _, kv := range opts.Add {
+		k := kv.Key
+		if f, ok := freeformTagFor(k); ok {
+			k = f.vorbis
+		}
+		c = c.add(k, kv.Value)
+	}
+	for _, d := range opts.Delete {
+		k, v, ok := core.ParseKV(d)
+		if !ok {
+			k, v = d, ""
+		}
+		if f, ok := freeformTagFor(k); ok {
+			k = f.vorbis
+		}
+		c = c.remove(k, v)
+	}
+	for _, e := range numbers {
+		c = applyVorbisNumber(c, e, opts.NumberPad)
+	}
+	return c
+}
+
+// vorbisVendor returns the vendor string of a comment block: the encoder
+// that wrote the file, which edits keep unless told otherwise.
+func vorbisVendor(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint32(data[0:4]))
+	if 4+n > len(data) {
+		return ""
 	}
+	return string(data[4 : 4+n])
 }
 
-func buildVorbisComment(fields map[string]string) []byte {
-	vendor := "Media Metadata Surgery v0.1.2"
+func buildVorbisComment(vendor string, comments vorbisComments) []byte {
 	var buf bytes.Buffer
 	le := binary.LittleEndian
 
@@ -685,11 +994,11 @@ func buildVorbisComment(fields map[string]string) []byte {
 
 	// Comment count
 	cntBuf := make([]byte, 4)
-	le.PutUint32(cntBuf, uint32(len(fields)))
+	le.PutUint32(cntBuf, uint32(len(comments)))
 	buf.Write(cntBuf)
 
-	for k, v := range fields {
-		comment := k + "=" + v
+	for _, c := range comments {
+		comment := c.key + "=" + c.value
 		cLen := make([]byte, 4)
 		le.PutUint32(cLen, uint32(len(comment)))
 		buf.Write(cLen)
@@ -721,6 +1030,12 @@ func writeFLAC(path string, blocks []flacBlock, audioData []byte) error {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return core.PreviewStrip(h, path, opts)
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtMP3:
@@ -729,6 +1044,10 @@ func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) err
 		return stripFLAC(path, out, opts)
 	case core.FmtWAV:
 		return stripWAV(path, out, opts)
+	case core.FmtM4A:
+		return stripM4A(path, out, opts)
+	case core.FmtOGG, core.FmtOpus:
+		return stripOgg(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanStrip {
@@ -739,45 +1058,58 @@ func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) err
 }
 
 func stripMP3(path, outPath string, opts core.StripOptions) error {
-	if opts.DryRun {
-		fmt.Println("Dry-run: MP3 ID3 tags would be removed")
-		return nil
+	keep := make(map[string]bool)
+	for _, k := range opts.KeepFields {
+		keep[strings.ToLower(k)] = true
 	}
-	if path != outPath {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		if err := os.WriteFile(outPath, data, 0644); err != nil {
-			return err
-		}
-	}
-
-	t, err := id3v2.Open(outPath, id3v2.Options{Parse: true})
-	if err != nil {
-		return err
-	}
-	defer t.Close()
-
+	// APEv2 and Lyrics3 are always removed unless kept; ID3v1 only goes on
+	// a full strip, matching the ID3v2 behaviour below.
 	if len(opts.KeepFields) > 0 {
-		keep := make(map[string]bool)
-		for _, k := range opts.KeepFields {
-			keep[strings.ToLower(k)] = true
-		}
-		// Delete all except kept
-		all := []string{"TIT2", "TPE1", "TALB", "TDRC", "TCON", "COMM",
-			"TRCK", "TPE2", "TCOM", "USLT", "TCOP", "TALB"}
-		for _, fid := range all {
-			name := mp3FrameNameFromID(fid)
-			if !keep[strings.ToLower(name)] && !keep[strings.ToLower(fid)] {
-				t.DeleteFrames(fid)
+		keep["id3v1"] = true
+	}
+	return rewriteMP3(path, outPath, func(t *id3v2.Tag) {
+		if len(opts.KeepFields) > 0 {
+			// Delete all except kept
+			all := []string{"TIT2", "TPE1", "TALB", "TDRC", "TCON", "COMM",
+				"TRCK", "TPE2", "TCOM", "USLT", "TCOP", "TALB"}
+			for _, fid := range all {
+				name := mp3FrameNameFromID(fid)
+				if !keep[strings.ToLower(name)] && !keep[strings.ToLower(fid)] {
+					t.DeleteFrames(fid)
+				}
 			}
+			// Pictures survive a partial strip; --keep front-cover narrows that
+			// to the front cover alone.
+			if keepsFrontCoverOnly(opts.KeepFields) {
+				frames := t.GetFrames("APIC")
+				t.DeleteFrames("APIC")
+				for _, f := range frames {
+					if pf, ok := f.(id3v2.PictureFrame); ok && pf.PictureType == id3v2.PTFrontCover {
+						t.AddFrame("APIC", f)
+					}
+				}
+			}
+		} else {
+			t.DeleteAllFrames()
 		}
-	} else {
-		t.DeleteAllFrames()
-	}
+	}, func(tmp string) error {
+		return stripMP3Trailers(tmp, keep)
+	})
+}
 
-	return t.Save()
+// keepsFrontCoverOnly reports whether a partial strip should drop every
+// picture except the front cover.
+func keepsFrontCoverOnly(keepFields []string) bool {
+	front, all := false, false
+	for _, k := range keepFields {
+		switch strings.ToLower(k) {
+		case "front-cover":
+			front = true
+		case "pictures":
+			all = true
+		}
+	}
+	return front && !all
 }
 
 func mp3FrameNameFromID(fid string) string {
@@ -795,41 +1127,56 @@ func stripFLAC(path, outPath string, opts core.StripOptions) error {
 	if err != nil {
 		return err
 	}
-	if opts.DryRun {
-		fmt.Println("Dry-run: FLAC Vorbis comment block would be cleared")
-		return nil
+	if len(data) < 4 || !bytes.Equal(data[0:4], []byte("fLaC")) {
+		return fmt.Errorf("not a valid FLAC file")
 	}
-
 	blocks, audioStart, err := parseFLACBlocks(data)
 	if err != nil {
 		return err
 	}
 
+	// APPLICATION and CUESHEET blocks are kept unless asked for by name.
+	remove := make(map[byte]bool)
+	for _, s := range opts.RemoveSections {
+		switch strings.ToLower(s) {
+		case "application":
+			remove[flacApplication] = true
+		case "cuesheet":
+			remove[flacCueSheet] = true
+		}
+	}
+	var kept []flacBlock
+	for _, b := range blocks {
+		if remove[b.blockType] {
+			continue
+		}
+		switch b.blockType {
+		case flacApplication:
+			fmt.Println("  Note: APPLICATION block kept (use --remove application to drop it)")
+		case flacCueSheet:
+			fmt.Println("  Note: CUESHEET block kept (use --remove cuesheet to drop it)")
+		}
+		kept = append(kept, b)
+	}
+	blocks = kept
+
+	for i, b := range blocks {
+		if b.blockType == flacVorbisComment {
+			blocks[i].data = strippedVorbisComment(b.data, opts)
+		}
+	}
 	if len(opts.KeepFields) > 0 {
-		// Clear only specified fields
-		for i, b := range blocks {
-			if b.blockType == 4 {
-				existing := map[string]string{}
-				parseVorbisComments(b.data, existing)
-				keep := make(map[string]bool)
-				for _, k := range opts.KeepFields {
-					keep[strings.ToUpper(k)] = true
-				}
-				for k := range existing {
-					if !keep[k] {
-						delete(existing, k)
-					}
+		if keepsFrontCoverOnly(opts.KeepFields) {
+			var filtered []flacBlock
+			for _, b := range blocks {
+				if typ, ok := flacPictureType(b.data); b.blockType == flacPicture && (!ok || typ != pictureFront) {
+					continue
 				}
-				blocks[i].data = buildVorbisComment(existing)
+				filtered = append(filtered, b)
 			}
+			blocks = filtered
 		}
 	} else {
-		// Clear entire Vorbis comment block
-		for i, b := range blocks {
-			if b.blockType == 4 {
-				blocks[i].data = buildVorbisComment(map[string]string{})
-			}
-		}
 		// Also remove PICTURE blocks (type 6)
 		var filtered []flacBlock
 		for _, b := range blocks {
@@ -844,6 +1191,47 @@ func stripFLAC(path, outPath string, opts core.StripOptions) error {
 	return writeFLAC(outPath, blocks, data[audioStart:])
 }
 
+// stripVendor returns the vendor string for a stripped comment block.
+// editVorbisComment applies opts to a Vorbis comment list (nil for none)
+// and returns the new list. FLAC and Ogg files both hold one.
+func editVorbisComment(block []byte, opts core.EditOptions) []byte {
+	comments := parseVorbisComments(block)
+	vendor := opts.Vendor
+	if vendor == "" {
+		vendor = vorbisVendor(block)
+	}
+	comments = applyVorbisEdits(comments, opts)
+	if opts.Deterministic {
+		sort.SliceStable(comments, func(i, j int) bool {
+			return strings.ToUpper(comments[i].key) < strings.ToUpper(comments[j].key)
+		})
+	}
+	return buildVorbisComment(vendor, comments)
+}
+
+// strippedVorbisComment empties a Vorbis comment list, keeping the
+// comments named in KeepFields and the vendor string.
+func strippedVorbisComment(block []byte, opts core.StripOptions) []byte {
+	keep := make(map[string]bool)
+	for _, k := range opts.KeepFields {
+		keep[strings.ToUpper(k)] = true
+	}
+	var kept vorbisComments
+	for _, c := range parseVorbisComments(block) {
+		if keep[strings.ToUpper(c.key)] {
+			kept = append(kept, c)
+		}
+	}
+	return buildVorbisComment(stripVendor(block, opts), kept)
+}
+
+func stripVendor(block []byte, opts core.StripOptions) string {
+	if opts.Vendor != "" {
+		return opts.Vendor
+	}
+	return vorbisVendor(block)
+}
+
 func stripWAV(path, outPath string, opts core.StripOptions) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,239 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+)
+
+// ─── Cover art ───────────────────────────────────────────────────────────────
+// Car stereos and older players choke on multi-megabyte PNG covers. When a
+// cover is embedded it can be downscaled to a maximum edge, re-encoded as
+// JPEG and squeezed under a byte cap; its dimensions can also be checked.
+
+// CoverOptions controls how a cover is prepared before embedding. Zero
+// values mean "no limit".
+type CoverOptions struct {
+	MaxDim   int  // longest edge in pixels
+	MinDim   int  // reject covers whose shortest edge is smaller
+	MaxBytes int  // encoded size cap
+	JPEG     bool // always store as JPEG
+	Quality  int  // JPEG quality, default 90
+}
+
+// Cover is an image ready to embed.
+type Cover struct {
+	Data          []byte
+	MIME          string
+	Width, Height int
+}
+
+func (c *Cover) String() string {
+	return fmt.Sprintf("%s, %d×%d, %d bytes", c.MIME, c.Width, c.Height, len(c.Data))
+}
+
+// PrepareCover checks data against opts and re-encodes it when it breaks
+// a limit. An image that already fits is returned untouched.
+func PrepareCover(data []byte, opts CoverOptions) (*Cover, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cover is not a JPEG or PNG image: %w", err)
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, fmt.Errorf("cover is %s; only JPEG and PNG can be embedded", format)
+	}
+	if opts.MinDim > 0 && min(cfg.Width, cfg.Height) < opts.MinDim {
+		return nil, fmt.Errorf("cover is %d×%d, smaller than the %d px minimum", cfg.Width, cfg.Height, opts.MinDim)
+	}
+	if opts.Quality <= 0 {
+		opts.Quality = 90
+	}
+
+	c := &Cover{Data: data, MIME: "image/" + format, Width: cfg.Width, Height: cfg.Height}
+	tooBig := opts.MaxDim > 0 && max(cfg.Width, cfg.Height) > opts.MaxDim
+	tooHeavy := opts.MaxBytes > 0 && len(data) > opts.MaxBytes
+	if !tooBig && !tooHeavy && !(opts.JPEG && format != "jpeg") {
+		return c, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode cover: %w", err)
+	}
+	if tooBig {
+		w, h := fitWithin(cfg.Width, cfg.Height, opts.MaxDim)
+		img = downscale(img, w, h)
+	}
+
+	// A PNG that only needed resizing stays a PNG if it now fits.
+	if format == "png" && !opts.JPEG {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		if opts.MaxBytes == 0 || buf.Len() <= opts.MaxBytes {
+			b := img.Bounds()
+			return &Cover{Data: buf.Bytes(), MIME: "image/png", Width: b.Dx(), Height: b.Dy()}, nil
+		}
+	}
+
+	// JPEG: lower the quality first, then shrink, until it fits.
+	img = flatten(img)
+	for {
+		for q := opts.Quality; q >= 40; q -= 10 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+				return nil, err
+			}
+			if opts.MaxBytes == 0 || buf.Len() <= opts.MaxBytes {
+				b := img.Bounds()
+				return &Cover{Data: buf.Bytes(), MIME: "image/jpeg", Width: b.Dx(), Height: b.Dy()}, nil
+			}
+		}
+		b := img.Bounds()
+		if b.Dx() < 64 || b.Dy() < 64 {
+			return nil, fmt.Errorf("cannot fit the cover under %d bytes", opts.MaxBytes)
+		}
+		img = downscale(img, b.Dx()*3/4, b.Dy()*3/4)
+	}
+}
+
+// fitWithin scales w×h down so that its longest edge is limit.
+func fitWithin(w, h, limit int) (int, int) {
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// downscale resizes src to w×h by averaging the source pixels under each
+// destination pixel (a box filter), which is sharp enough for shrinking.
+func downscale(src image.Image, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/h
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sb.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := sb.Min.X + x*sb.Dx()/w
+			x1 := max(x0+1, sb.Min.X+(x+1)*sb.Dx()/w)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
+
+// flatten composites src over white, since JPEG has no alpha channel.
+func flatten(src image.Image) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := src.At(x, y).RGBA()
+			bg := 0xffff - a
+			dst.SetRGBA(x, y, color.RGBA{uint8((r + bg) >> 8), uint8((g + bg) >> 8), uint8((bl + bg) >> 8), 0xff})
+		}
+	}
+	return dst
+}
+
+// coverSummary describes an embedded picture for view.
+func coverSummary(p *tag.Picture) string {
+	mime := pictureMIME(p)
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(p.Data)); err == nil {
+		return fmt.Sprintf("%s, %d×%d, %d bytes", mime, cfg.Width, cfg.Height, len(p.Data))
+	}
+	return fmt.Sprintf("%s, %d bytes (undecodable)", mime, len(p.Data))
+}
+
+// ─── Embedding ───────────────────────────────────────────────────────────────
+
+// SetCover embeds c as the front cover of an MP3, FLAC or M4A file,
+// replacing any existing front cover and keeping other pictures. An M4A
+// has no picture types, so its covr atom is replaced outright.
+func SetCover(path, outPath string, c *Cover) error {
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		return err
+	}
+	out := core.ResolveOutPath(path, outPath)
+	pic := &tag.Picture{MIMEType: c.MIME, Type: "Front Cover", Data: c.Data}
+	switch fmtID {
+	case core.FmtMP3:
+		return setMP3Cover(path, out, pic)
+	case core.FmtFLAC:
+		return setFLACCover(path, out, pic)
+	case core.FmtM4A:
+		return video.SetISOBMFFCover(path, out, c.MIME, c.Data)
+	}
+	return fmt.Errorf("cover art can be embedded in MP3, FLAC and M4A, not %s", fmtID)
+}
+
+func setMP3Cover(path, outPath string, pic *tag.Picture) error {
+	return rewriteMP3(path, outPath, func(t *id3v2.Tag) {
+		replaceID3FrontCover(t, pic)
+	}, nil)
+}
+
+// replaceID3FrontCover swaps the front-cover APIC frame for pic, keeping
+// pictures of other types.
+func replaceID3FrontCover(t *id3v2.Tag, pic *tag.Picture) {
+	frames := t.GetFrames("APIC")
+	t.DeleteFrames("APIC")
+	for _, f := range frames {
+		if pf, ok := f.(id3v2.PictureFrame); ok && pf.PictureType != id3v2.PTFrontCover {
+			t.AddAttachedPicture(pf)
+		}
+	}
+	t.AddAttachedPicture(id3v2.PictureFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		MimeType:    pictureMIME(pic),
+		PictureType: id3v2.PTFrontCover,
+		Description: pic.Description,
+		Picture:     pic.Data,
+	})
+}
+
+func setFLACCover(path, outPath string, pic *tag.Picture) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) < 4 || !bytes.Equal(data[0:4], []byte("fLaC")) {
+		return fmt.Errorf("not a valid FLAC file")
+	}
+	blocks, audioStart, err := parseFLACBlocks(data)
+	if err != nil {
+		return err
+	}
+	return writeFLAC(outPath, replaceFLACFrontCover(blocks, pic), data[audioStart:])
+}
+
+// replaceFLACFrontCover swaps the front-cover PICTURE block for pic,
+// keeping pictures of other types.
+func replaceFLACFrontCover(blocks []flacBlock, pic *tag.Picture) []flacBlock {
+	var kept []flacBlock
+	for _, b := range blocks {
+		if typ, ok := flacPictureType(b.data); ok && b.blockType == flacPicture && typ == pictureFront {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return append(kept, flacBlock{blockType: flacPicture, data: buildFLACPicture(pic)})
+}
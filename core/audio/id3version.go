@@ -0,0 +1,241 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+)
+
+// ─── ID3v2 version ───────────────────────────────────────────────────────────
+// Edits keep the ID3v2 version a tag was read with, and a new tag is
+// v2.4. Some players and car stereos still only read v2.3, which has no
+// UTF-8 and spells dates differently: the id3.version option converts the
+// tag while it is rewritten. Going to v2.3, UTF-8 text becomes Latin-1
+// or UTF-16 and the v2.4 timestamp frames TDRC and TDOR become
+// TYER/TDAT/TIME and TORY; going to v2.4, the reverse.
+
+var optID3Version = core.FormatOption{
+	Key:    "id3.version",
+	Values: []string{"2.3", "2.4"},
+	Doc:    "save the ID3v2 tag as v2.3 (Latin-1/UTF-16 text, TYER) or v2.4 (UTF-8, TDRC)",
+}
+
+// id3Version returns the ID3v2 major version opts asks for, or 0 to keep
+// the tag's own.
+func id3Version(opts map[string]string) (byte, error) {
+	v, err := optID3Version.Value(opts)
+	switch {
+	case err != nil:
+		return 0, err
+	case v == "2.3":
+		return 3, nil
+	case v == "2.4":
+		return 4, nil
+	}
+	return 0, nil
+}
+
+// convertID3Version rewrites the frames of t that differ between ID3v2.3
+// and v2.4 and sets its version to v (3 or 4).
+func convertID3Version(t *id3v2.Tag, v byte) {
+	if v == 3 {
+		id3DatesToV23(t)
+	} else {
+		id3DatesToV24(t)
+	}
+	t.SetVersion(v)
+	reencode := withoutUTF16
+	if v == 3 {
+		reencode = withoutUTF8
+	}
+	for id, frames := range t.AllFrames() {
+		changed := false
+		for i, f := range frames {
+			if g, ok := reencode(f); ok {
+				frames[i], changed = g, true
+			}
+		}
+		if changed {
+			t.DeleteFrames(id)
+			for _, f := range frames {
+				t.AddFrame(id, f)
+			}
+		}
+	}
+}
+
+// withoutUTF16 returns f as UTF-8 when it is a text-bearing frame in
+// UTF-16 with a byte order mark, which the id3v2 package writes with an
+// odd terminator that readers reject.
+func withoutUTF16(f id3v2.Framer) (id3v2.Framer, bool) {
+	utf16, utf8 := id3v2.EncodingUTF16, id3v2.EncodingUTF8
+	switch g := f.(type) {
+	case id3v2.TextFrame:
+		if g.Encoding.Equals(utf16) {
+			g.Encoding = utf8
+			return g, true
+		}
+	case id3v2.CommentFrame:
+		if g.Encoding.Equals(utf16) {
+			g.Encoding = utf8
+			return g, true
+		}
+	case id3v2.UnsynchronisedLyricsFrame:
+		if g.Encoding.Equals(utf16) {
+			g.Encoding = utf8
+			return g, true
+		}
+	case id3v2.UserDefinedTextFrame:
+		if g.Encoding.Equals(utf16) {
+			g.Encoding = utf8
+			return g, true
+		}
+	case id3v2.PictureFrame:
+		if g.Encoding.Equals(utf16) {
+			g.Encoding = utf8
+			return g, true
+		}
+	}
+	return f, false
+}
+
+// withoutUTF8 returns f in an encoding ID3v2.3 has, unless it is in
+// ISO-8859-1 already: ISO-8859-1 when the text fits, UTF-16 otherwise.
+// The UTF-16 body is built here rather than by the id3v2 package (see
+// withoutUTF16); UTF-16 frames are rebuilt for the same reason.
+func withoutUTF8(f id3v2.Framer) (id3v2.Framer, bool) {
+	iso := id3v2.EncodingISO
+	switch g := f.(type) {
+	case id3v2.TextFrame:
+		if g.Encoding.Equals(iso) {
+			break
+		}
+		if isLatin1(g.Text) {
+			g.Encoding = iso
+			return g, true
+		}
+		return id3UTF16Frame(nil, g.Text), true
+	case id3v2.CommentFrame:
+		if g.Encoding.Equals(iso) {
+			break
+		}
+		if isLatin1(g.Description + g.Text) {
+			g.Encoding = iso
+			return g, true
+		}
+		return id3UTF16Frame([]byte(g.Language), g.Description, g.Text), true
+	case id3v2.UnsynchronisedLyricsFrame:
+		if g.Encoding.Equals(iso) {
+			break
+		}
+		if isLatin1(g.ContentDescriptor + g.Lyrics) {
+			g.Encoding = iso
+			return g, true
+		}
+		return id3UTF16Frame([]byte(g.Language), g.ContentDescriptor, g.Lyrics), true
+	case id3v2.UserDefinedTextFrame:
+		if g.Encoding.Equals(iso) {
+			break
+		}
+		if isLatin1(g.Description + g.Value) {
+			g.Encoding = iso
+			return g, true
+		}
+		return id3UTF16Frame(nil, g.Description, g.Value), true
+	case id3v2.PictureFrame:
+		if g.Encoding.Equals(iso) {
+			break
+		}
+		if isLatin1(g.Description) {
+			g.Encoding = iso
+			return g, true
+		}
+		head := append([]byte(g.MimeType), 0, g.PictureType)
+		body := id3UTF16Frame(head, g.Description).Body
+		return id3v2.UnknownFrame{Body: append(append(body, 0, 0), g.Picture...)}, true
+	}
+	return f, false
+}
+
+// id3UTF16Frame returns the body of a frame in UTF-16: the encoding byte,
+// head, then each text with a byte order mark, null-terminated except the
+// last.
+func id3UTF16Frame(head []byte, texts ...string) id3v2.UnknownFrame {
+	body := append([]byte{1}, head...)
+	for i, t := range texts {
+		if i > 0 {
+			body = append(body, 0, 0)
+		}
+		body = append(body, 0xFF, 0xFE)
+		for _, u := range utf16.Encode([]rune(t)) {
+			body = append(body, byte(u), byte(u>>8))
+		}
+	}
+	return id3v2.UnknownFrame{Body: body}
+}
+
+// isLatin1 reports whether s can be written as ISO-8859-1.
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r > 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+// id3DatesToV23 splits the v2.4 recording time TDRC ("2006-03-14T10:30")
+// into TYER, TDAT ("1403") and TIME ("1030"), and the original release
+// time TDOR into TORY.
+func id3DatesToV23(t *id3v2.Tag) {
+	if ts := id3Text(t, "TDRC"); ts != "" {
+		t.DeleteFrames("TDRC")
+		date, clock, _ := strings.Cut(ts, "T")
+		parts := strings.Split(date, "-")
+		setID3Text(t, "TYER", parts[0])
+		if len(parts) == 3 {
+			setID3Text(t, "TDAT", parts[2]+parts[1])
+		}
+		if len(clock) >= 5 {
+			setID3Text(t, "TIME", clock[0:2]+clock[3:5])
+		}
+	}
+	if ts := id3Text(t, "TDOR"); ts != "" {
+		t.DeleteFrames("TDOR")
+		setID3Text(t, "TORY", strings.SplitN(ts, "-", 2)[0])
+	}
+}
+
+// id3DatesToV24 joins TYER, TDAT and TIME into TDRC, and TORY into TDOR.
+func id3DatesToV24(t *id3v2.Tag) {
+	if year := id3Text(t, "TYER"); year != "" {
+		ts := year
+		if d := id3Text(t, "TDAT"); len(d) == 4 {
+			ts += fmt.Sprintf("-%s-%s", d[2:4], d[0:2])
+			if c := id3Text(t, "TIME"); len(c) == 4 {
+				ts += fmt.Sprintf("T%s:%s", c[0:2], c[2:4])
+			}
+		}
+		setID3Text(t, "TDRC", ts)
+	}
+	if year := id3Text(t, "TORY"); year != "" {
+		setID3Text(t, "TDOR", year)
+	}
+	for _, id := range []string{"TYER", "TDAT", "TIME", "TORY"} {
+		t.DeleteFrames(id)
+	}
+}
+
+// id3Text returns the text of frame id, "" when t has none.
+func id3Text(t *id3v2.Tag, id string) string {
+	return strings.TrimSpace(t.GetTextFrame(id).Text)
+}
+
+// setID3Text replaces frame id with text, which is digits and separators.
+func setID3Text(t *id3v2.Tag, id, text string) {
+	t.DeleteFrames(id)
+	t.AddTextFrame(id, id3v2.EncodingISO, text)
+}
@@ -0,0 +1,366 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+)
+
+// ─── Lyrics ──────────────────────────────────────────────────────────────────
+// Unsynchronised lyrics live in ID3 USLT, Vorbis LYRICS (or UNSYNCEDLYRICS)
+// and the iTunes ©lyr atom. Synchronised lyrics are an ID3 SYLT frame of
+// (text, timestamp) pairs; elsewhere the convention is to store LRC text
+// ("[01:23.45]line") in the ordinary lyrics field. GetLyrics and SetLyrics
+// convert between LRC and SYLT so that one .lrc file round-trips through
+// every format.
+
+// LyricLine is one timed line of synchronised lyrics.
+type LyricLine struct {
+	Time int // milliseconds from the start
+	Text string
+}
+
+var lrcTimeRe = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+var lrcTagRe = regexp.MustCompile(`^\[([a-zA-Z]+):([^\]]*)\]\s*$`)
+
+// IsLRC reports whether text looks like LRC: at least one line starting
+// with a [mm:ss.xx] timestamp.
+func IsLRC(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if lrcTimeRe.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLRC returns the timed lines of an LRC document, sorted by time.
+// A line may carry several timestamps ("[00:12.00][00:45.00]chorus");
+// the [offset:±ms] tag is applied and other ID tags are ignored.
+func ParseLRC(text string) []LyricLine {
+	var lines []LyricLine
+	offset := 0
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if m := lrcTagRe.FindStringSubmatch(line); m != nil {
+			if strings.EqualFold(m[1], "offset") {
+				offset, _ = strconv.Atoi(strings.TrimSpace(m[2]))
+			}
+			continue
+		}
+		var times []int
+		for {
+			m := lrcTimeRe.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			min, _ := strconv.Atoi(m[1])
+			sec, _ := strconv.Atoi(m[2])
+			frac := 0
+			if m[3] != "" {
+				// ".5" is half a second, ".05" and ".050" are 50 ms
+				frac, _ = strconv.Atoi((m[3] + "00")[:3])
+			}
+			times = append(times, (min*60+sec)*1000+frac)
+			line = line[len(m[0]):]
+		}
+		for _, t := range times {
+			lines = append(lines, LyricLine{Time: t, Text: strings.TrimSpace(line)})
+		}
+	}
+	// A positive offset shows lyrics sooner.
+	for i := range lines {
+		if lines[i].Time -= offset; lines[i].Time < 0 {
+			lines[i].Time = 0
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+	return lines
+}
+
+// FormatLRC renders timed lines as LRC with centisecond timestamps.
+func FormatLRC(lines []LyricLine) string {
+	var b strings.Builder
+	for _, l := range lines {
+		cs := l.Time / 10
+		fmt.Fprintf(&b, "[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, l.Text)
+	}
+	return b.String()
+}
+
+// plainLyrics drops LRC timestamps and ID tags, leaving the text.
+func plainLyrics(text string) string {
+	if !IsLRC(text) {
+		return text
+	}
+	var out []string
+	for _, l := range ParseLRC(text) {
+		out = append(out, l.Text)
+	}
+	return strings.Join(out, "\n")
+}
+
+// ─── SYLT ────────────────────────────────────────────────────────────────────
+// encoding, language[3], timestamp format (2 = milliseconds), content type
+// (1 = lyrics), descriptor, then (text, uint32 timestamp) pairs. Strings
+// end in 0x00, or 0x00 0x00 for the UTF-16 encodings.
+
+const syltMilliseconds = 2 // the other format, 1, counts MPEG frames
+
+func decodeSYLT(body []byte) ([]LyricLine, error) {
+	if len(body) < 6 {
+		return nil, fmt.Errorf("SYLT frame too short")
+	}
+	enc, format := body[0], body[4]
+	if format != syltMilliseconds {
+		return nil, fmt.Errorf("SYLT timestamps are in MPEG frames, not milliseconds; cannot convert to LRC")
+	}
+	rest := body[6:]
+	_, rest = splitID3String(rest, enc) // content descriptor
+	var lines []LyricLine
+	for len(rest) > 0 {
+		var text string
+		text, rest = splitID3String(rest, enc)
+		if len(rest) < 4 {
+			break
+		}
+		t := binary.BigEndian.Uint32(rest[:4])
+		rest = rest[4:]
+		lines = append(lines, LyricLine{Time: int(t), Text: strings.TrimLeft(text, "\n")})
+	}
+	return lines, nil
+}
+
+// splitID3String returns the first terminated string of b, decoded, and
+// what follows it.
+func splitID3String(b []byte, enc byte) (string, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return decodeUTF16(b[:i], enc == 2), b[i+2:]
+			}
+		}
+		return decodeUTF16(b, enc == 2), nil
+	}
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		i = len(b)
+	}
+	s, rest := b[:i], b[min(i+1, len(b)):]
+	if enc == 0 {
+		r := make([]rune, len(s))
+		for j, c := range s {
+			r[j] = rune(c)
+		}
+		return string(r), rest
+	}
+	return string(s), rest
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xFF && b[1] == 0xFE:
+			order, b = binary.LittleEndian, b[2:]
+		case b[0] == 0xFE && b[1] == 0xFF:
+			order, b = binary.BigEndian, b[2:]
+		}
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = order.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeSYLT builds a UTF-8, millisecond SYLT body.
+func encodeSYLT(lines []LyricLine) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(3) // UTF-8
+	buf.WriteString("eng")
+	buf.WriteByte(syltMilliseconds)
+	buf.WriteByte(1) // lyrics
+	buf.WriteByte(0) // empty descriptor
+	for _, l := range lines {
+		buf.WriteString(l.Text)
+		buf.WriteByte(0)
+		binary.Write(&buf, binary.BigEndian, uint32(l.Time))
+	}
+	return buf.Bytes()
+}
+
+// ─── Get / Set ───────────────────────────────────────────────────────────────
+
+// GetLyrics returns the lyrics of path. Synchronised lyrics come back as
+// LRC; plain drops the timestamps.
+func GetLyrics(path string, plain bool) (string, error) {
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		return "", err
+	}
+	var text string
+	switch fmtID {
+	case core.FmtMP3:
+		text, err = mp3Lyrics(path, plain)
+	case core.FmtFLAC:
+		text, err = flacLyrics(path)
+	case core.FmtM4A, core.FmtOGG, core.FmtOpus:
+		text, err = dhowdenLyrics(path)
+	default:
+		return "", fmt.Errorf("lyrics are not supported for %s", fmtID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", fmt.Errorf("%s has no lyrics", path)
+	}
+	if plain {
+		text = plainLyrics(text)
+	}
+	return strings.TrimRight(text, "\n") + "\n", nil
+}
+
+func mp3Lyrics(path string, plain bool) (string, error) {
+	t, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		return "", fmt.Errorf("could not open MP3: %w", err)
+	}
+	defer t.Close()
+	if !plain {
+		for _, f := range t.GetFrames("SYLT") {
+			uf, ok := f.(id3v2.UnknownFrame)
+			if !ok {
+				continue
+			}
+			lines, err := decodeSYLT(uf.Body)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: %v — using USLT\n", err)
+				break
+			}
+			return FormatLRC(lines), nil
+		}
+	}
+	for _, f := range t.GetFrames("USLT") {
+		if uslf, ok := f.(id3v2.UnsynchronisedLyricsFrame); ok && uslf.Lyrics != "" {
+			return uslf.Lyrics, nil
+		}
+	}
+	return "", nil
+}
+
+func flacLyrics(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	blocks, _, err := parseFLACBlocks(data)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range blocks {
+		if b.blockType != flacVorbisComment {
+			continue
+		}
+		c := parseVorbisComments(b.data)
+		if v := c.get("LYRICS"); v != "" {
+			return v, nil
+		}
+		return c.get("UNSYNCEDLYRICS"), nil
+	}
+	return "", nil
+}
+
+func dhowdenLyrics(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	t, err := tag.ReadFrom(f)
+	if err != nil {
+		return "", fmt.Errorf("could not read tags: %w", err)
+	}
+	if l := t.Lyrics(); l != "" {
+		return l, nil
+	}
+	if v, ok := t.Raw()["unsyncedlyrics"].(string); ok {
+		return v, nil
+	}
+	return "", nil
+}
+
+// SetLyrics writes text as the lyrics of path (or outPath). LRC input
+// becomes a SYLT frame plus a plain USLT copy in MP3, and is stored as-is
+// in FLAC's LYRICS comment, where LRC-aware players look for it.
+func SetLyrics(path, outPath, text string, dryRun bool) error {
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	synced := IsLRC(text)
+
+	switch fmtID {
+	case core.FmtMP3, core.FmtFLAC, core.FmtOGG, core.FmtOpus:
+	case core.FmtM4A:
+		return fmt.Errorf("%s lyrics can be read but not written in v0.1.2", formatInfo[fmtID].Name)
+	default:
+		return fmt.Errorf("lyrics are not supported for %s", fmtID)
+	}
+
+	if dryRun {
+		kind := "plain"
+		if synced {
+			kind = fmt.Sprintf("synchronised, %d timed lines", len(ParseLRC(text)))
+		}
+		target := "USLT"
+		switch {
+		case fmtID == core.FmtFLAC, fmtID == core.FmtOGG, fmtID == core.FmtOpus:
+			target = "LYRICS"
+		case synced:
+			target = "SYLT + USLT"
+		}
+		fmt.Printf("Dry-run: lyrics (%s) would be written to %s\n", kind, target)
+		return nil
+	}
+
+	out := core.ResolveOutPath(path, outPath)
+	switch fmtID {
+	case core.FmtFLAC:
+		return editFLAC(path, out, core.EditOptions{Set: map[string]string{"LYRICS": text}})
+	case core.FmtOGG, core.FmtOpus:
+		return editOgg(path, out, core.EditOptions{Set: map[string]string{"LYRICS": text}}, formatInfo[fmtID].Name)
+	}
+	return setMP3Lyrics(path, out, text, synced)
+}
+
+func setMP3Lyrics(path, outPath, text string, synced bool) error {
+	return rewriteMP3(path, outPath, func(t *id3v2.Tag) {
+		t.SetVersion(4) // the frames below are UTF-8
+		t.DeleteFrames("USLT")
+		t.DeleteFrames("SYLT")
+		t.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+			Encoding: id3v2.EncodingUTF8,
+			Language: "eng",
+			Lyrics:   plainLyrics(text),
+		})
+		if synced {
+			t.AddFrame("SYLT", id3v2.UnknownFrame{Body: encodeSYLT(ParseLRC(text))})
+		}
+	}, nil)
+}
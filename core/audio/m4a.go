@@ -0,0 +1,72 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
+)
+
+// ─── M4A ─────────────────────────────────────────────────────────────────────
+// M4A tags are the iTunes ilst atoms of an MP4, so M4A edit, strip and
+// cover embedding go through the video package's ISOBMFF writer. This file
+// only translates names: the friendly names view shows (Title,
+// TrackNumber, MusicBrainzAlbumID, …) become atom types or
+// "----:com.apple.iTunes:…" freeform items via tagMappings and
+// freeformTags, and any other key is passed through for the writer to
+// resolve as it would in an MP4.
+
+// itunesFreeformMean is the mean of the freeform atoms iTunes and most
+// taggers write.
+const itunesFreeformMean = "com.apple.iTunes"
+
+// gaplessAtom is the freeform item holding encoder delay and padding.
+// Strip keeps it: it is playback data, and without it gapless albums
+// click between tracks.
+const gaplessAtom = "----:" + itunesFreeformMean + ":iTunSMPB"
+
+// m4aKey returns the ilst key for an edit key.
+func m4aKey(k string) string {
+	for _, m := range tagMappings {
+		if strings.EqualFold(k, m.name) {
+			return m.itunes
+		}
+	}
+	if f, ok := freeformTagFor(k); ok {
+		return "----:" + itunesFreeformMean + ":" + f.itunes
+	}
+	return k
+}
+
+func editM4A(path, outPath string, opts core.EditOptions) error {
+	if opts.DryRun {
+		fmt.Println("Dry-run: M4A iTunes atoms would be updated:")
+		for _, k := range core.SortedKeys(opts.Set) {
+			fmt.Printf("  %s = %s\n", k, opts.Set[k])
+		}
+		for _, k := range opts.Delete {
+			fmt.Printf("  %s (deleted)\n", k)
+		}
+		return nil
+	}
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		set[m4aKey(k)] = v
+	}
+	del := make([]string, len(opts.Delete))
+	for i, k := range opts.Delete {
+		del[i] = m4aKey(k)
+	}
+	opts.Set, opts.Delete = set, del
+	return video.EditISOBMFF(path, outPath, opts)
+}
+
+func stripM4A(path, outPath string, opts core.StripOptions) error {
+	keep := []string{gaplessAtom}
+	for _, k := range opts.KeepFields {
+		keep = append(keep, m4aKey(k))
+	}
+	opts.KeepFields = keep
+	return video.StripISOBMFF(path, outPath, opts)
+}
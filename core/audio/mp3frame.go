@@ -0,0 +1,312 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+)
+
+// ─── MP3 first frame / Xing / LAME ───────────────────────────────────────────
+// Encoders write a silent first frame whose payload is a Xing ("Xing" for
+// VBR, "Info" for CBR) or VBRI header, optionally followed by a LAME
+// extension holding the encoder version and gapless delay/padding. It is an
+// audio frame, not a tag: it must survive strip and edit byte-for-byte.
+
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},  // MPEG 2.5
+	{0, 0, 0},             // reserved
+	{22050, 24000, 16000}, // MPEG 2
+	{44100, 48000, 32000}, // MPEG 1
+}
+
+var mp3Bitrates = map[bool][16]int{
+	// MPEG 1 Layer III
+	true: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	// MPEG 2/2.5 Layer III
+	false: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+var lameVBRMethods = map[byte]string{
+	0: "unknown", 1: "CBR", 2: "ABR",
+	3: "VBR (rh)", 4: "VBR (mtrh)", 5: "VBR (rh)", 6: "VBR",
+	8: "CBR (2-pass)", 9: "ABR (2-pass)",
+}
+
+// mp3Frame describes the header of one MPEG audio frame.
+type mp3Frame struct {
+	offset     int
+	length     int
+	mpeg1      bool
+	version    string
+	mono       bool
+	bitrate    int // kbit/s
+	sampleRate int
+}
+
+// mp3AudioStart returns the offset just past a leading ID3v2 tag, or 0.
+func mp3AudioStart(data []byte) int {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return 0
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	start := 10 + size
+	if data[5]&0x10 != 0 {
+		start += 10 // footer present
+	}
+	if start > len(data) {
+		return len(data)
+	}
+	return start
+}
+
+// firstMP3Frame locates the first valid Layer III frame header at or after
+// the end of the ID3v2 tag.
+func firstMP3Frame(data []byte) (mp3Frame, bool) {
+	for i := mp3AudioStart(data); i+4 <= len(data); i++ {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		verBits := (data[i+1] >> 3) & 3
+		layer := (data[i+1] >> 1) & 3
+		brIdx := data[i+2] >> 4
+		srIdx := (data[i+2] >> 2) & 3
+		if verBits == 1 || layer != 1 || brIdx == 0 || brIdx == 15 || srIdx == 3 {
+			continue
+		}
+		f := mp3Frame{
+			offset:     i,
+			mpeg1:      verBits == 3,
+			mono:       data[i+3]>>6 == 3,
+			sampleRate: mp3SampleRates[verBits][srIdx],
+		}
+		f.version = map[byte]string{0: "MPEG-2.5", 2: "MPEG-2", 3: "MPEG-1"}[verBits]
+		f.bitrate = mp3Bitrates[f.mpeg1][brIdx]
+		padding := int(data[i+2]>>1) & 1
+		if f.mpeg1 {
+			f.length = 144000*f.bitrate/f.sampleRate + padding
+		} else {
+			f.length = 72000*f.bitrate/f.sampleRate + padding
+		}
+		return f, true
+	}
+	return mp3Frame{}, false
+}
+
+// xingOffset is where the Xing/Info tag sits inside the first frame:
+// after the 4-byte header and the side information.
+func (f mp3Frame) xingOffset() int {
+	switch {
+	case f.mpeg1 && !f.mono:
+		return 4 + 32
+	case f.mpeg1, !f.mono:
+		return 4 + 17
+	default:
+		return 4 + 9
+	}
+}
+
+// firstFrameBytes returns the raw bytes of the first audio frame, used to
+// check that a rewrite left it untouched.
+func firstFrameBytes(data []byte) []byte {
+	f, ok := firstMP3Frame(data)
+	if !ok {
+		return nil
+	}
+	end := f.offset + f.length
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[f.offset:end]
+}
+
+// verifyFirstFrame checks that the first audio frame of the file at path
+// still matches want. It guards against any tag writer mistaking the
+// Xing/LAME frame for tag data.
+func verifyFirstFrame(path string, want []byte) error {
+	if len(want) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(firstFrameBytes(data), want) {
+		return fmt.Errorf("first MP3 audio frame changed during rewrite: %s", path)
+	}
+	return nil
+}
+
+// rewriteMP3 applies edit to the ID3v2 tag of the MP3 at path and saves
+// the result to outPath. The work happens on a temporary copy, which after
+// runs on and which replaces outPath only once the first audio frame is
+// confirmed unchanged; if any step fails, outPath is left as it was.
+func rewriteMP3(path, outPath string, edit func(t *id3v2.Tag), after func(tmp string) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	firstFrame := firstFrameBytes(data)
+	return core.ReplaceVia(outPath, func(tmp string) error {
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		t, err := id3v2.Open(tmp, id3v2.Options{Parse: true})
+		if err != nil {
+			return fmt.Errorf("could not open MP3: %w", err)
+		}
+		edit(t)
+		err = t.Save()
+		t.Close()
+		if err != nil {
+			return err
+		}
+		if after != nil {
+			if err := after(tmp); err != nil {
+				return err
+			}
+		}
+		return verifyFirstFrame(tmp, firstFrame)
+	})
+}
+
+// mp3ScanWindow is how much of an MP3 a view reads past the ID3v2 tag to
+// find the first frame, and back from the end for trailing tags.
+const mp3ScanWindow = 1 << 20
+
+// readMP3Ends returns the front of the file at path (its ID3v2 tag and
+// mp3ScanWindow bytes more) and up to mp3ScanWindow bytes from its end,
+// which start at tailOff. A file no bigger than that is read once and
+// returned as both.
+func readMP3Ends(path string) (head, tail []byte, tailOff int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	hdr, err := core.ReadRange(f, 0, 10)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	n := int64(mp3ScanWindow)
+	if len(hdr) == 10 && bytes.HasPrefix(hdr, []byte("ID3")) {
+		n += 20 + int64(syncsafe(hdr[6:10])) // header, tag and footer
+	}
+	if n+mp3ScanWindow >= st.Size() {
+		data, err := core.ReadRange(f, 0, int(st.Size()))
+		return data, data, 0, err
+	}
+	if head, err = core.ReadRange(f, 0, int(n)); err != nil {
+		return nil, nil, 0, err
+	}
+	tailOff = st.Size() - mp3ScanWindow
+	tail, err = core.ReadRange(f, tailOff, mp3ScanWindow)
+	return head, tail, tailOff, err
+}
+
+// addMP3StreamInfo appends the MPEG stream details and any Xing/VBRI/LAME
+// encoder information found in the first frame. It reports whether an
+// audio frame was found at all.
+func addMP3StreamInfo(path string, m *core.Metadata) bool {
+	data, _, _, err := readMP3Ends(path)
+	if err != nil {
+		return false
+	}
+	f, ok := firstMP3Frame(data)
+	if !ok {
+		return false
+	}
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "MPEG Audio", Editable: false})
+	}
+	add("Version", f.version+" Layer III")
+	add("SampleRate", fmt.Sprintf("%d Hz", f.sampleRate))
+	if f.mono {
+		add("Channels", "1")
+	} else {
+		add("Channels", "2")
+	}
+
+	frame := data[f.offset:]
+	xo := f.xingOffset()
+	switch {
+	case len(frame) >= xo+8 && (bytes.Equal(frame[xo:xo+4], []byte("Xing")) || bytes.Equal(frame[xo:xo+4], []byte("Info"))):
+		parseXingLAME(frame[xo:], m)
+	case len(frame) >= 36+18 && bytes.Equal(frame[36:40], []byte("VBRI")):
+		add("VBRHeader", "VBRI")
+		add("Frames", fmt.Sprintf("%d", binary.BigEndian.Uint32(frame[36+14:36+18])))
+	default:
+		add("Bitrate", fmt.Sprintf("%d kbit/s", f.bitrate))
+	}
+	return true
+}
+
+func parseXingLAME(x []byte, m *core.Metadata) {
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "MPEG Audio", Editable: false})
+	}
+	kind := string(x[0:4])
+	if kind == "Xing" {
+		add("VBRHeader", "Xing (VBR)")
+	} else {
+		add("VBRHeader", "Info (CBR)")
+	}
+	flags := binary.BigEndian.Uint32(x[4:8])
+	pos := 8
+	if flags&0x1 != 0 && pos+4 <= len(x) {
+		add("Frames", fmt.Sprintf("%d", binary.BigEndian.Uint32(x[pos:pos+4])))
+		pos += 4
+	}
+	if flags&0x2 != 0 && pos+4 <= len(x) {
+		add("StreamBytes", fmt.Sprintf("%d", binary.BigEndian.Uint32(x[pos:pos+4])))
+		pos += 4
+	}
+	if flags&0x4 != 0 {
+		pos += 100 // seek TOC
+	}
+	if flags&0x8 != 0 {
+		pos += 4 // quality indicator
+	}
+
+	// LAME extension: 9-byte version string, then tag revision / VBR method,
+	// lowpass, replay gain, flags, bitrate and 3 bytes of delay/padding.
+	if pos+24 > len(x) {
+		return
+	}
+	lame := x[pos:]
+	enc := strings.TrimRight(string(lame[0:9]), "\x00 ")
+	if enc == "" || !isPrintable(enc) {
+		return
+	}
+	add("Encoder", enc)
+	if !strings.HasPrefix(enc, "LAME") && !strings.HasPrefix(enc, "Lavc") && !strings.HasPrefix(enc, "Lavf") {
+		return
+	}
+	if method, ok := lameVBRMethods[lame[9]&0x0F]; ok {
+		add("VBRMethod", method)
+	}
+	if lame[10] != 0 {
+		add("Lowpass", fmt.Sprintf("%d Hz", int(lame[10])*100))
+	}
+	delay := int(lame[21])<<4 | int(lame[22])>>4
+	padding := int(lame[22]&0x0F)<<8 | int(lame[23])
+	add("EncoderDelay", fmt.Sprintf("%d samples", delay))
+	add("EncoderPadding", fmt.Sprintf("%d samples", padding))
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7E {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── MP3 trailing tags ───────────────────────────────────────────────────────
+// Besides ID3v2 at the front, an MP3 may end with any mix of:
+//   - APEv2  : optional 32-byte header, items, 32-byte "APETAGEX" footer
+//   - Lyrics3: "LYRICSBEGIN" … "LYRICSEND" (v1) or 6-digit size + "LYRICS200" (v2)
+//   - ID3v1  : final 128 bytes starting with "TAG"
+// They are peeled off from the end of the file one at a time.
+
+type mp3Trailer struct {
+	kind  string // "id3v1", "ape", "lyrics3"
+	start int
+	end   int
+}
+
+const apeFooterLen = 32
+
+// findMP3Trailers returns the trailing tag blocks of an MP3 in file order.
+// Nothing overlapping the first audio frame is ever reported as a tag.
+func findMP3Trailers(data []byte) []mp3Trailer {
+	return trailersAbove(data, mp3TagFloor(data))
+}
+
+// mp3TagFloor returns the end of the first audio frame in head, or of the
+// ID3v2 tag when there is none.
+func mp3TagFloor(head []byte) int {
+	floor := mp3AudioStart(head)
+	if f, ok := firstMP3Frame(head); ok {
+		floor = f.offset + f.length
+	}
+	return floor
+}
+
+// trailersAbove peels tag blocks off the end of data down to floor.
+func trailersAbove(data []byte, floor int) []mp3Trailer {
+	var found []mp3Trailer
+	end := len(data)
+	for end > floor {
+		t, ok := trailerEndingAt(data, end)
+		if !ok || t.start < floor {
+			break
+		}
+		found = append([]mp3Trailer{t}, found...)
+		end = t.start
+	}
+	return found
+}
+
+func trailerEndingAt(data []byte, end int) (mp3Trailer, bool) {
+	// ID3v1 — only valid as the very last block
+	if end == len(data) && end >= 128 && bytes.Equal(data[end-128:end-125], []byte("TAG")) {
+		return mp3Trailer{kind: "id3v1", start: end - 128, end: end}, true
+	}
+
+	// APEv2 footer
+	if end >= apeFooterLen && bytes.Equal(data[end-apeFooterLen:end-24], []byte("APETAGEX")) {
+		foot := data[end-apeFooterLen : end]
+		size := int(binary.LittleEndian.Uint32(foot[12:16])) // items + footer
+		flags := binary.LittleEndian.Uint32(foot[20:24])
+		start := end - size
+		if flags&(1<<31) != 0 {
+			start -= apeFooterLen // header present
+		}
+		if size >= apeFooterLen && start >= 0 {
+			return mp3Trailer{kind: "ape", start: start, end: end}, true
+		}
+	}
+
+	// Lyrics3 v2: <LYRICSBEGIN…> <6-digit size> LYRICS200
+	if end >= 15 && bytes.Equal(data[end-9:end], []byte("LYRICS200")) {
+		size, err := strconv.Atoi(string(data[end-15 : end-9]))
+		start := end - 15 - size
+		if err == nil && start >= 0 && bytes.HasPrefix(data[start:], []byte("LYRICSBEGIN")) {
+			return mp3Trailer{kind: "lyrics3", start: start, end: end}, true
+		}
+	}
+
+	// Lyrics3 v1: LYRICSBEGIN … LYRICSEND, at most 5100 bytes of lyrics
+	if end >= 9 && bytes.Equal(data[end-9:end], []byte("LYRICSEND")) {
+		from := end - 9 - 5100 - 11
+		if from < 0 {
+			from = 0
+		}
+		if idx := bytes.LastIndex(data[from:end], []byte("LYRICSBEGIN")); idx >= 0 {
+			return mp3Trailer{kind: "lyrics3", start: from + idx, end: end}, true
+		}
+	}
+	return mp3Trailer{}, false
+}
+
+// addMP3Trailers appends fields found in APEv2 and Lyrics3 blocks.
+// It reports whether any trailing structure was found.
+// Only the end of the file is read, so a trailer bigger than mp3ScanWindow
+// is not seen.
+func addMP3Trailers(path string, m *core.Metadata) bool {
+	head, data, tailOff, err := readMP3Ends(path)
+	if err != nil {
+		return false
+	}
+	floor := mp3TagFloor(head) - int(tailOff)
+	if floor < 0 {
+		floor = 0
+	}
+	trailers := trailersAbove(data, floor)
+	for _, t := range trailers {
+		from := len(m.Fields)
+		switch t.kind {
+		case "ape":
+			parseAPEItems(data[t.start:t.end], m)
+		case "lyrics3":
+			parseLyrics3(data[t.start:t.end], m)
+		}
+		m.SetSource(from, core.FieldSource{Store: strings.TrimSuffix(mp3TrailerNames[t.kind], " tag"), Structure: mp3TrailerNames[t.kind], Offset: tailOff + int64(t.start)})
+	}
+	return len(trailers) > 0
+}
+
+// parseAPEItems reads the items of an APEv2 tag (with or without header).
+func parseAPEItems(tag []byte, m *core.Metadata) {
+	foot := tag[len(tag)-apeFooterLen:]
+	count := int(binary.LittleEndian.Uint32(foot[16:20]))
+	pos := 0
+	if bytes.HasPrefix(tag, []byte("APETAGEX")) {
+		pos = apeFooterLen
+	}
+	limit := len(tag) - apeFooterLen
+	for i := 0; i < count && pos+8 < limit; i++ {
+		valLen := int(binary.LittleEndian.Uint32(tag[pos : pos+4]))
+		flags := binary.LittleEndian.Uint32(tag[pos+4 : pos+8])
+		pos += 8
+		nul := bytes.IndexByte(tag[pos:limit], 0)
+		if nul < 0 {
+			break
+		}
+		key := string(tag[pos : pos+nul])
+		pos += nul + 1
+		if valLen < 0 || pos+valLen > limit {
+			break
+		}
+		val := tag[pos : pos+valLen]
+		pos += valLen
+
+		// Item type: bits 1-2 — 0 text, 1 binary, 2 external locator
+		var s string
+		if (flags>>1)&3 == 1 {
+			s = fmt.Sprintf("(binary, %d bytes)", len(val))
+		} else {
+			s = strings.ReplaceAll(string(val), "\x00", "; ")
+		}
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      key,
+			Value:    s,
+			Category: "APEv2",
+			Editable: false,
+		})
+	}
+}
+
+// Lyrics3 v2 field IDs → human names
+var lyrics3FieldNames = map[string]string{
+	"IND": "Indications",
+	"LYR": "Lyrics",
+	"INF": "Information",
+	"AUT": "Author",
+	"EAL": "Album",
+	"EAR": "Artist",
+	"ETT": "Title",
+	"IMG": "ImageLinks",
+}
+
+func parseLyrics3(block []byte, m *core.Metadata) {
+	body := block[len("LYRICSBEGIN"):]
+	if bytes.HasSuffix(body, []byte("LYRICSEND")) {
+		// v1: the whole body is lyrics
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "Lyrics",
+			Value:    string(body[:len(body)-9]),
+			Category: "Lyrics3",
+			Editable: false,
+		})
+		return
+	}
+	// v2: sequence of 3-char ID + 5-digit size + data
+	body = body[:len(body)-15]
+	for len(body) >= 8 {
+		id := string(body[:3])
+		size, err := strconv.Atoi(string(body[3:8]))
+		if err != nil || 8+size > len(body) {
+			break
+		}
+		val := string(body[8 : 8+size])
+		body = body[8+size:]
+		if id == "IND" || val == "" {
+			continue
+		}
+		name := lyrics3FieldNames[id]
+		if name == "" {
+			name = id
+		}
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      name,
+			Value:    val,
+			Category: "Lyrics3",
+			Editable: false,
+		})
+	}
+}
+
+// stripMP3Trailers removes APEv2, Lyrics3 and (optionally) ID3v1 blocks from
+// the end of the file at path, rewriting it in place. Blocks whose kind is
+// in keep are left untouched.
+func stripMP3Trailers(path string, keep map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	trailers := findMP3Trailers(data)
+	if len(trailers) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:trailers[0].start])
+	dropped := false
+	for _, t := range trailers {
+		if keep[t.kind] {
+			buf.Write(data[t.start:t.end])
+			continue
+		}
+		dropped = true
+	}
+	if !dropped {
+		return nil
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// ─── ID3v2 frame order ───────────────────────────────────────────────────────
+
+// sortID3Frames rewrites the ID3v2 tag at the start of path with its
+// frames sorted by frame ID. The library that writes the tag emits frames
+// in map order; frames with the same ID keep their relative order. The
+// tag's size does not change.
+func sortID3Frames(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	end := mp3AudioStart(data)
+	if end == 0 || data[5]&0x40 != 0 { // no tag, or an extended header
+		return nil
+	}
+	if data[5]&0x10 != 0 {
+		end -= 10 // footer
+	}
+	type frame struct {
+		id  string
+		raw []byte
+	}
+	var frames []frame
+	pos := 10
+	for pos+10 <= end && data[pos] != 0 {
+		var size int
+		if data[3] == 4 {
+			size = int(data[pos+4]&0x7F)<<21 | int(data[pos+5]&0x7F)<<14 | int(data[pos+6]&0x7F)<<7 | int(data[pos+7]&0x7F)
+		} else {
+			size = int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		}
+		if pos+10+size > end {
+			return nil // leave a tag we cannot walk alone
+		}
+		frames = append(frames, frame{string(data[pos : pos+4]), data[pos : pos+10+size]})
+		pos += 10 + size
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].id < frames[j].id })
+	var buf bytes.Buffer
+	for _, f := range frames {
+		buf.Write(f.raw)
+	}
+	copy(data[10:pos], buf.Bytes())
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+)
+
+// ─── Tagger identifiers ──────────────────────────────────────────────────────
+// Taggers such as MusicBrainz Picard store their identifiers outside the
+// standard fields: TXXX frames (and a UFID for the recording) in ID3,
+// MUSICBRAINZ_* and ACOUSTID_* comments in Vorbis and
+// "----:com.apple.iTunes" freeform atoms in M4A. view shows them under one canonical name per identifier,
+// and edit accepts that name for MP3, FLAC and M4A. Podcast episode fields
+// that RSS feeds carry (episode, season, GUID, chapters URL) and
+// ReplayGain loudness values and payload checksums are stored the same
+// way.
+
+// freeformTag names one identifier in each tag container.
+type freeformTag struct {
+	name     string // canonical name, as shown by view and accepted by edit
+	txxx     string // ID3 TXXX description; empty when ufid is used
+	ufid     string // ID3 UFID owner
+	vorbis   string // Vorbis comment key
+	itunes   string // iTunes freeform atom name
+	category string
+}
+
+const musicBrainzUFIDOwner = "http://musicbrainz.org"
+
+var freeformTags = []freeformTag{
+	{"MusicBrainzRecordingID", "", musicBrainzUFIDOwner, "MUSICBRAINZ_TRACKID", "MusicBrainz Track Id", "MusicBrainz"},
+	{"MusicBrainzReleaseTrackID", "MusicBrainz Release Track Id", "", "MUSICBRAINZ_RELEASETRACKID", "MusicBrainz Release Track Id", "MusicBrainz"},
+	{"MusicBrainzAlbumID", "MusicBrainz Album Id", "", "MUSICBRAINZ_ALBUMID", "MusicBrainz Album Id", "MusicBrainz"},
+	{"MusicBrainzReleaseGroupID", "MusicBrainz Release Group Id", "", "MUSICBRAINZ_RELEASEGROUPID", "MusicBrainz Release Group Id", "MusicBrainz"},
+	{"MusicBrainzArtistID", "MusicBrainz Artist Id", "", "MUSICBRAINZ_ARTISTID", "MusicBrainz Artist Id", "MusicBrainz"},
+	{"MusicBrainzAlbumArtistID", "MusicBrainz Album Artist Id", "", "MUSICBRAINZ_ALBUMARTISTID", "MusicBrainz Album Artist Id", "MusicBrainz"},
+	{"MusicBrainzWorkID", "MusicBrainz Work Id", "", "MUSICBRAINZ_WORKID", "MusicBrainz Work Id", "MusicBrainz"},
+	{"MusicBrainzDiscID", "MusicBrainz Disc Id", "", "MUSICBRAINZ_DISCID", "MusicBrainz Disc Id", "MusicBrainz"},
+	{"AcoustID", "Acoustid Id", "", "ACOUSTID_ID", "Acoustid Id", "AcoustID"},
+	{"AcoustIDFingerprint", "Acoustid Fingerprint", "", "ACOUSTID_FINGERPRINT", "Acoustid Fingerprint", "AcoustID"},
+	{"PodcastEpisode", "Podcast Episode", "", "PODCAST_EPISODE", "Podcast Episode", "Podcast"},
+	{"PodcastSeason", "Podcast Season", "", "PODCAST_SEASON", "Podcast Season", "Podcast"},
+	{"PodcastGUID", "Podcast GUID", "", "PODCAST_GUID", "Podcast GUID", "Podcast"},
+	{"PodcastChaptersURL", "Podcast Chapters URL", "", "PODCAST_CHAPTERS_URL", "Podcast Chapters URL", "Podcast"},
+	{"ReplayGainTrackGain", "REPLAYGAIN_TRACK_GAIN", "", "REPLAYGAIN_TRACK_GAIN", "replaygain_track_gain", "Loudness"},
+	{"ReplayGainTrackPeak", "REPLAYGAIN_TRACK_PEAK", "", "REPLAYGAIN_TRACK_PEAK", "replaygain_track_peak", "Loudness"},
+	{"ReplayGainAlbumGain", "REPLAYGAIN_ALBUM_GAIN", "", "REPLAYGAIN_ALBUM_GAIN", "replaygain_album_gain", "Loudness"},
+	{"ReplayGainAlbumPeak", "REPLAYGAIN_ALBUM_PEAK", "", "REPLAYGAIN_ALBUM_PEAK", "replaygain_album_peak", "Loudness"},
+	{"PayloadSHA256", "PAYLOAD_SHA256", "", "PAYLOAD_SHA256", "PAYLOAD_SHA256", "Fixity"},
+	{"PayloadMD5", "PAYLOAD_MD5", "", "PAYLOAD_MD5", "PAYLOAD_MD5", "Fixity"},
+}
+
+// freeformTagFor finds the identifier named key, by canonical name or by
+// any container's spelling.
+func freeformTagFor(key string) (freeformTag, bool) {
+	for _, f := range freeformTags {
+		if strings.EqualFold(key, f.name) || strings.EqualFold(key, f.vorbis) ||
+			(f.txxx != "" && strings.EqualFold(key, f.txxx)) {
+			return f, true
+		}
+	}
+	return freeformTag{}, false
+}
+
+// addFreeformTags reports the identifiers found in a dhowden/tag raw map
+// and returns the raw keys it consumed, so the raw listing can skip them.
+func addFreeformTags(raw map[string]interface{}, format tag.Format, m *core.Metadata, editable bool) map[string]bool {
+	used := make(map[string]bool)
+	values := make(map[string]string)
+	for k, v := range raw {
+		switch format {
+		case tag.ID3v2_2, tag.ID3v2_3, tag.ID3v2_4:
+			switch fv := v.(type) {
+			case *tag.Comm:
+				if f, ok := freeformTagFor(fv.Description); ok && f.txxx != "" {
+					text := strings.TrimRight(fv.Text, "\x00")
+					values[f.name], used[k] = strings.ReplaceAll(text, "\x00", "; "), true
+				}
+			case *tag.UFID:
+				for _, f := range freeformTags {
+					if f.ufid != "" && f.ufid == fv.Provider {
+						values[f.name], used[k] = string(fv.Identifier), true
+					}
+				}
+			}
+		case tag.VORBIS:
+			for _, f := range freeformTags {
+				if s, ok := v.(string); ok && strings.EqualFold(k, f.vorbis) {
+					values[f.name], used[k] = s, true
+				}
+			}
+		case tag.MP4:
+			for _, f := range freeformTags {
+				if s, ok := v.(string); ok && k == f.itunes {
+					values[f.name], used[k] = s, true
+				}
+			}
+		}
+	}
+	for _, f := range freeformTags {
+		if v := values[f.name]; v != "" {
+			m.Fields = append(m.Fields, core.MetaField{Key: f.name, Value: v, Category: f.category, Editable: editable})
+		}
+	}
+	return used
+}
+
+// setID3Freeform replaces the frame holding f with value; an empty value
+// removes it. Frames from other owners or with other descriptions are kept.
+func setID3Freeform(t *id3v2.Tag, f freeformTag, value string) {
+	id := "TXXX"
+	if f.ufid != "" {
+		id = "UFID"
+	}
+	frames := t.GetFrames(id)
+	t.DeleteFrames(id)
+	for _, fr := range frames {
+		switch fv := fr.(type) {
+		case id3v2.UFIDFrame:
+			if fv.OwnerIdentifier == f.ufid {
+				continue
+			}
+		case id3v2.UserDefinedTextFrame:
+			if f.txxx != "" && strings.EqualFold(fv.Description, f.txxx) {
+				continue
+			}
+		}
+		t.AddFrame(id, fr)
+	}
+	if value == "" {
+		return
+	}
+	if f.ufid != "" {
+		t.AddUFIDFrame(id3v2.UFIDFrame{OwnerIdentifier: f.ufid, Identifier: []byte(value)})
+		return
+	}
+	t.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{Encoding: id3v2.EncodingUTF8, Description: f.txxx, Value: value})
+}
@@ -0,0 +1,170 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+)
+
+// ─── Track and disc numbers ──────────────────────────────────────────────────
+// ID3 keeps position and total in one frame ("3/12" in TRCK and TPOS);
+// Vorbis comments split them (TRACKNUMBER=3, TRACKTOTAL=12). Edits accept
+// TrackNumber/DiscNumber as "n" or "n/total" and TrackTotal/DiscTotal on
+// their own, and each writer lays them out the way its format expects.
+// Setting only one half of a pair keeps the other.
+
+// trackDiscPair names one position/total pair in each tag container.
+type trackDiscPair struct {
+	id3         string // frame holding "n/total"
+	vorbisNum   string
+	vorbisTotal string
+	totalAlias  string // older Vorbis spelling of the total
+}
+
+var (
+	trackPair = trackDiscPair{"TRCK", "TRACKNUMBER", "TRACKTOTAL", "TOTALTRACKS"}
+	discPair  = trackDiscPair{"TPOS", "DISCNUMBER", "DISCTOTAL", "TOTALDISCS"}
+)
+
+// numberKeys maps the accepted edit keys to their pair and whether they
+// name the total.
+var numberKeys = map[string]struct {
+	pair  trackDiscPair
+	total bool
+}{
+	"tracknumber": {trackPair, false},
+	"track":       {trackPair, false},
+	"tracktotal":  {trackPair, true},
+	"totaltracks": {trackPair, true},
+	"discnumber":  {discPair, false},
+	"disc":        {discPair, false},
+	"disctotal":   {discPair, true},
+	"totaldiscs":  {discPair, true},
+}
+
+// numberEdit is the change requested for one pair.
+type numberEdit struct {
+	pair           trackDiscPair
+	n, total       string
+	setN, setTotal bool
+	remove         bool // delete the whole pair
+}
+
+// takeNumberEdits moves the track and disc keys out of opts.Set and
+// opts.Delete and returns them as one numberEdit per pair, track first.
+func takeNumberEdits(opts core.EditOptions) (core.EditOptions, []numberEdit) {
+	edits := map[string]*numberEdit{}
+	get := func(p trackDiscPair) *numberEdit {
+		if edits[p.id3] == nil {
+			edits[p.id3] = &numberEdit{pair: p}
+		}
+		return edits[p.id3]
+	}
+
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		nk, ok := numberKeys[strings.ToLower(k)]
+		if !ok {
+			set[k] = v
+			continue
+		}
+		e := get(nk.pair)
+		if nk.total {
+			e.total, e.setTotal = strings.TrimSpace(v), true
+			continue
+		}
+		n, total, hasTotal := strings.Cut(v, "/")
+		e.n, e.setN = strings.TrimSpace(n), true
+		if hasTotal && !e.setTotal {
+			e.total, e.setTotal = strings.TrimSpace(total), true
+		}
+	}
+	var del []string
+	for _, k := range opts.Delete {
+		nk, ok := numberKeys[strings.ToLower(k)]
+		if !ok {
+			del = append(del, k)
+			continue
+		}
+		e := get(nk.pair)
+		if nk.total {
+			e.total, e.setTotal = "", true
+		} else {
+			e.remove = true
+		}
+	}
+	opts.Set, opts.Delete = set, del
+
+	var out []numberEdit
+	for _, p := range []trackDiscPair{trackPair, discPair} {
+		if e := edits[p.id3]; e != nil {
+			out = append(out, *e)
+		}
+	}
+	return opts, out
+}
+
+// apply returns the new position and total given the current ones,
+// zero-padded to pad digits.
+func (e numberEdit) apply(n, total string, pad int) (string, string) {
+	if e.setN {
+		n = e.n
+	}
+	if e.setTotal {
+		total = e.total
+	}
+	return core.PadNumber(n, pad), core.PadNumber(total, pad)
+}
+
+// writeID3Number applies e to the frame holding its pair.
+func writeID3Number(t *id3v2.Tag, e numberEdit, pad int) {
+	if e.remove {
+		t.DeleteFrames(e.pair.id3)
+		return
+	}
+	n, total, _ := strings.Cut(t.GetTextFrame(e.pair.id3).Text, "/")
+	n, total = e.apply(strings.TrimSpace(n), strings.TrimSpace(total), pad)
+	switch {
+	case n == "" && total == "":
+		t.DeleteFrames(e.pair.id3)
+	case n == "":
+		fmt.Printf("  Warning: %s cannot hold a total without a number — skipped\n", e.pair.id3)
+	case total == "":
+		t.AddTextFrame(e.pair.id3, id3v2.EncodingUTF8, n)
+	default:
+		t.AddTextFrame(e.pair.id3, id3v2.EncodingUTF8, n+"/"+total)
+	}
+}
+
+// applyVorbisNumber applies e to the NUMBER and TOTAL comments of its pair.
+// A legacy "3/12" in the NUMBER comment is split on the way.
+func applyVorbisNumber(c vorbisComments, e numberEdit, pad int) vorbisComments {
+	p := e.pair
+	if e.remove {
+		return c.remove(p.vorbisNum, "").remove(p.vorbisTotal, "").remove(p.totalAlias, "")
+	}
+	n, total := c.get(p.vorbisNum), c.get(p.vorbisTotal)
+	if total == "" {
+		total = c.get(p.totalAlias)
+	}
+	if num, tot, ok := strings.Cut(n, "/"); ok {
+		n = strings.TrimSpace(num)
+		if total == "" {
+			total = strings.TrimSpace(tot)
+		}
+	}
+	n, total = e.apply(n, total, pad)
+
+	c = c.remove(p.totalAlias, "")
+	if n == "" {
+		c = c.remove(p.vorbisNum, "")
+	} else {
+		c = c.set(p.vorbisNum, n)
+	}
+	if total == "" {
+		return c.remove(p.vorbisTotal, "")
+	}
+	return c.set(p.vorbisTotal, total)
+}
@@ -0,0 +1,428 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Ogg Vorbis and Opus ─────────────────────────────────────────────────────
+// An Ogg stream is a sequence of pages, each with a serial number, a
+// sequence number, a CRC and a lacing table that cuts its body into packet
+// segments. Vorbis and Opus keep their tags in the second header packet
+// (the Vorbis comment packet or OpusTags), which holds the same Vorbis
+// comment list as a FLAC VORBIS_COMMENT block. That packet may span pages
+// and shares them with the Vorbis setup packet, so a rewrite reassembles
+// the header packets after the first page, swaps the comment packet, cuts
+// them into new pages, and renumbers the pages of the stream that follow.
+// Audio pages keep their bodies and granule positions byte for byte; only
+// their sequence numbers and CRCs change when the header takes a different
+// number of pages.
+
+// Ogg page header flags.
+const (
+	oggContinued = 0x01
+	oggBOS       = 0x02
+	oggEOS       = 0x04
+)
+
+// oggPage is one page of a file, as parsed from it.
+type oggPage struct {
+	start, end int // byte range of the whole page
+	flags      byte
+	granule    uint64
+	serial     uint32
+	seq        uint32
+	lacing     []byte
+	body       []byte
+}
+
+var oggCRCTable = func() (t [256]uint32) {
+	for i := range t {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return
+}()
+
+// oggCRC is the page checksum: CRC-32 with polynomial 0x04C11DB7, not
+// reflected, over the page with its checksum field zero.
+func oggCRC(page []byte) uint32 {
+	var c uint32
+	for i, b := range page {
+		if i >= 22 && i < 26 {
+			b = 0
+		}
+		c = c<<8 ^ oggCRCTable[byte(c>>24)^b]
+	}
+	return c
+}
+
+// parseOggPage reads the page at pos and checks its CRC.
+func parseOggPage(data []byte, pos int) (oggPage, error) {
+	if pos+27 > len(data) || string(data[pos:pos+4]) != "OggS" {
+		return oggPage{}, fmt.Errorf("no Ogg page at offset %d", pos)
+	}
+	h := data[pos:]
+	if h[4] != 0 {
+		return oggPage{}, fmt.Errorf("Ogg page at offset %d has unknown version %d", pos, h[4])
+	}
+	n := int(h[26])
+	if pos+27+n > len(data) {
+		return oggPage{}, fmt.Errorf("Ogg page at offset %d is truncated", pos)
+	}
+	lacing := h[27 : 27+n]
+	size := 0
+	for _, l := range lacing {
+		size += int(l)
+	}
+	end := pos + 27 + n + size
+	if end > len(data) {
+		return oggPage{}, fmt.Errorf("Ogg page at offset %d is truncated", pos)
+	}
+	if binary.LittleEndian.Uint32(h[22:26]) != oggCRC(data[pos:end]) {
+		return oggPage{}, fmt.Errorf("Ogg page at offset %d fails its CRC", pos)
+	}
+	return oggPage{
+		start: pos, end: end,
+		flags:   h[5],
+		granule: binary.LittleEndian.Uint64(h[6:14]),
+		serial:  binary.LittleEndian.Uint32(h[14:18]),
+		seq:     binary.LittleEndian.Uint32(h[18:22]),
+		lacing:  lacing,
+		body:    data[pos+27+n : end],
+	}, nil
+}
+
+// parseOggPages reads every page of data.
+func parseOggPages(data []byte) ([]oggPage, error) {
+	var pages []oggPage
+	for pos := 0; pos < len(data); {
+		p, err := parseOggPage(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+		pos = p.end
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("not an Ogg file")
+	}
+	return pages, nil
+}
+
+// oggCodec describes the header packets of a codec: how many there are,
+// and the prefix of the comment packet, which is the second.
+type oggCodec struct {
+	name    string
+	headers int
+	comment string
+}
+
+var (
+	oggVorbis = oggCodec{"Vorbis", 3, "\x03vorbis"}
+	oggOpus   = oggCodec{"Opus", 2, "OpusTags"}
+)
+
+// oggHeaders is the header of the first logical stream of a file.
+type oggHeaders struct {
+	codec   oggCodec
+	pages   []oggPage
+	first   int      // pages[first:last] carry the header packets after the first
+	last    int      // index of the first page after the headers
+	packets [][]byte // the header packets after the first
+}
+
+// readOggHeaders finds the header packets of the first stream. Every
+// header packet after the first must end before the audio starts on a
+// page of its own, as the Vorbis and Opus specifications require.
+func readOggHeaders(data []byte) (*oggHeaders, error) {
+	pages, err := parseOggPages(data)
+	if err != nil {
+		return nil, err
+	}
+	p0 := pages[0]
+	if p0.flags&oggBOS == 0 || len(p0.lacing) == 0 || p0.lacing[len(p0.lacing)-1] == 255 {
+		return nil, fmt.Errorf("the first Ogg page does not hold a whole identification header")
+	}
+	for _, l := range p0.lacing[:len(p0.lacing)-1] {
+		if l < 255 {
+			return nil, fmt.Errorf("the first Ogg page holds more than the identification header")
+		}
+	}
+	h := &oggHeaders{pages: pages, first: 1}
+	switch {
+	case bytes.HasPrefix(p0.body, []byte("\x01vorbis")):
+		h.codec = oggVorbis
+	case bytes.HasPrefix(p0.body, []byte("OpusHead")):
+		h.codec = oggOpus
+	default:
+		return nil, fmt.Errorf("only Ogg Vorbis and Opus streams can be rewritten")
+	}
+
+	var packet []byte
+	i := 1
+	for ; i < len(pages) && len(h.packets) < h.codec.headers-1; i++ {
+		p := pages[i]
+		if p.serial != p0.serial {
+			return nil, fmt.Errorf("multiplexed Ogg streams cannot be rewritten")
+		}
+		if (p.flags&oggContinued != 0) != (len(packet) > 0) {
+			return nil, fmt.Errorf("Ogg page %d breaks the header packets", p.seq)
+		}
+		off := 0
+		for j, l := range p.lacing {
+			packet = append(packet, p.body[off:off+int(l)]...)
+			off += int(l)
+			if l < 255 {
+				h.packets = append(h.packets, packet)
+				packet = nil
+				if len(h.packets) == h.codec.headers-1 && j != len(p.lacing)-1 {
+					return nil, fmt.Errorf("audio data shares a page with the %s headers", h.codec.name)
+				}
+			}
+		}
+	}
+	if len(h.packets) < h.codec.headers-1 {
+		return nil, fmt.Errorf("the %s header packets are incomplete", h.codec.name)
+	}
+	if !bytes.HasPrefix(h.packets[0], []byte(h.codec.comment)) {
+		return nil, fmt.Errorf("the second %s header is not a comment header", h.codec.name)
+	}
+	h.last = i
+	return h, nil
+}
+
+// oggPayload returns the bodies of the first stream's pages after its
+// headers. A comment rewrite renumbers those pages but leaves their bodies
+// as they were.
+func oggPayload(data []byte) ([]byte, error) {
+	h, err := readOggHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+	serial := h.pages[0].serial
+	var out []byte
+	for _, p := range h.pages[h.last:] {
+		if p.serial != serial {
+			continue
+		}
+		out = append(out, p.body...)
+		if p.flags&oggEOS != 0 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// comment returns the Vorbis comment list of the comment packet, and the
+// bytes after it: the framing bit of Vorbis, or the binary data Opus
+// allows there.
+func (h *oggHeaders) comment() (list, tail []byte) {
+	body := h.packets[0][len(h.codec.comment):]
+	n := vorbisCommentLen(body)
+	return body[:n], body[n:]
+}
+
+// vorbisCommentLen returns the length of the Vorbis comment list at the
+// start of b.
+func vorbisCommentLen(b []byte) int {
+	if len(b) < 4 {
+		return len(b)
+	}
+	pos := 4 + int(binary.LittleEndian.Uint32(b[0:4]))
+	if pos+4 > len(b) {
+		return len(b)
+	}
+	count := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+	pos += 4
+	for i := 0; i < count && pos+4 <= len(b); i++ {
+		pos += 4 + int(binary.LittleEndian.Uint32(b[pos:pos+4]))
+	}
+	if pos > len(b) {
+		return len(b)
+	}
+	return pos
+}
+
+// rewrite returns the file with the comment packet replaced by one
+// holding list.
+func (h *oggHeaders) rewrite(data, list []byte) []byte {
+	_, tail := h.comment()
+	packets := append([][]byte{nil}, h.packets[1:]...)
+	packets[0] = append(append([]byte(h.codec.comment), list...), tail...)
+
+	old := h.pages[h.first:h.last]
+	s := old[0]
+	newPages := buildOggPages(packets, s.serial, s.seq, old[len(old)-1].flags&oggEOS)
+
+	var out bytes.Buffer
+	out.Grow(len(data) + len(list))
+	out.Write(data[:s.start])
+	for _, p := range newPages {
+		out.Write(p)
+	}
+	rest := out.Len()
+	out.Write(data[old[len(old)-1].end:])
+
+	// Renumber the pages of the stream that follow, up to its last page.
+	delta := uint32(len(newPages) - len(old))
+	if delta == 0 {
+		return out.Bytes()
+	}
+	b := out.Bytes()
+	for _, p := range h.pages[h.last:] {
+		if p.serial != s.serial {
+			continue
+		}
+		at := rest + p.start - old[len(old)-1].end
+		page := b[at : at+p.end-p.start]
+		binary.LittleEndian.PutUint32(page[18:22], p.seq+delta)
+		binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+		if p.flags&oggEOS != 0 {
+			break
+		}
+	}
+	return b
+}
+
+// buildOggPages cuts packets into pages of the stream serial, numbered
+// from seq. A page on which no packet ends has granule position -1; the
+// others, being header pages, have 0. eos is set on the last page.
+func buildOggPages(packets [][]byte, serial, seq uint32, eos byte) [][]byte {
+	type segment struct {
+		data []byte
+		ends bool
+	}
+	var segs []segment
+	for _, p := range packets {
+		for len(p) >= 255 {
+			segs = append(segs, segment{p[:255], false})
+			p = p[255:]
+		}
+		segs = append(segs, segment{p, true})
+	}
+
+	var pages [][]byte
+	continued := false
+	for len(segs) > 0 {
+		n := min(len(segs), 255)
+		var flags byte
+		if continued {
+			flags |= oggContinued
+		}
+		granule := ^uint64(0)
+		page := []byte("OggS\x00")
+		page = append(page, flags)
+		page = binary.LittleEndian.AppendUint64(page, 0)
+		page = binary.LittleEndian.AppendUint32(page, serial)
+		page = binary.LittleEndian.AppendUint32(page, seq)
+		page = append(page, 0, 0, 0, 0, byte(n))
+		for _, s := range segs[:n] {
+			page = append(page, byte(len(s.data)))
+			if s.ends {
+				granule = 0
+			}
+		}
+		for _, s := range segs[:n] {
+			page = append(page, s.data...)
+		}
+		continued = !segs[n-1].ends
+		segs = segs[n:]
+		if len(segs) == 0 {
+			page[5] |= eos
+		}
+		binary.LittleEndian.PutUint64(page[6:14], granule)
+		binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+		pages = append(pages, page)
+		seq++
+	}
+	return pages
+}
+
+// verifyOggRewrite checks out against orig: every page parses with a good
+// CRC, the header packets other than the comment are unchanged, and the
+// pages after the header carry the same bodies and granule positions.
+func verifyOggRewrite(orig, out []byte, list []byte) error {
+	a, err := readOggHeaders(orig)
+	if err != nil {
+		return err
+	}
+	b, err := readOggHeaders(out)
+	if err != nil {
+		return fmt.Errorf("rewrite check failed: %w", err)
+	}
+	if got, _ := b.comment(); !bytes.Equal(got, list) {
+		return fmt.Errorf("rewrite check failed: the comment header was not written as built")
+	}
+	for i := 1; i < len(a.packets); i++ {
+		if !bytes.Equal(a.packets[i], b.packets[i]) {
+			return fmt.Errorf("rewrite check failed: %s header %d changed", a.codec.name, i+2)
+		}
+	}
+	if !bytes.Equal(orig[:a.pages[0].end], out[:b.pages[0].end]) {
+		return fmt.Errorf("rewrite check failed: the identification page changed")
+	}
+	x, y := a.pages[a.last:], b.pages[b.last:]
+	if len(x) != len(y) {
+		return fmt.Errorf("rewrite check failed: %d audio pages became %d", len(x), len(y))
+	}
+	for i := range x {
+		if x[i].serial != y[i].serial || x[i].granule != y[i].granule || !bytes.Equal(x[i].body, y[i].body) {
+			return fmt.Errorf("rewrite check failed: audio page %d changed", x[i].seq)
+		}
+	}
+	return nil
+}
+
+// rewriteOgg replaces the comment list of an Ogg Vorbis or Opus file with
+// what edit returns for the current one.
+func rewriteOgg(path, outPath string, edit func(list []byte) []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	h, err := readOggHeaders(data)
+	if err != nil {
+		return err
+	}
+	list, _ := h.comment()
+	list = edit(list)
+	out := h.rewrite(data, list)
+	if err := verifyOggRewrite(data, out, list); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
+
+func editOgg(path, outPath string, opts core.EditOptions, name string) error {
+	if opts.DryRun {
+		fmt.Printf("Dry-run: %s Vorbis comments would be updated:\n", name)
+		for k, v := range opts.Set {
+			fmt.Printf("  %s = %s\n", k, v)
+		}
+		for _, kv := range opts.Add {
+			fmt.Printf("  %s += %s\n", kv.Key, kv.Value)
+		}
+		return nil
+	}
+	return rewriteOgg(path, outPath, func(list []byte) []byte {
+		return editVorbisComment(list, opts)
+	})
+}
+
+func stripOgg(path, outPath string, opts core.StripOptions) error {
+	return rewriteOgg(path, outPath, func(list []byte) []byte {
+		return strippedVorbisComment(list, opts)
+	})
+}
@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Optimize removes FLAC PADDING blocks or the padding of an MP3's ID3v2
+// tag.
+func (h *Handler) Optimize(path, outPath string, dryRun bool) (int64, error) {
+	switch h.format {
+	case core.FmtMP3, core.FmtFLAC:
+	default:
+		return 0, fmt.Errorf("%s does not support optimize", formatInfo[h.format].Name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if h.format == core.FmtFLAC {
+		blocks, audioStart, err := parseFLACBlocks(data)
+		if err != nil {
+			return 0, err
+		}
+		var saved int64
+		var kept []flacBlock
+		for _, b := range blocks {
+			if b.blockType == flacPadding {
+				saved += int64(len(b.data)) + 4
+				continue
+			}
+			kept = append(kept, b)
+		}
+		if dryRun || saved == 0 {
+			return saved, nil
+		}
+		return saved, writeFLAC(outPath, kept, data[audioStart:])
+	}
+
+	pad := id3v2Padding(data)
+	if dryRun || pad == 0 {
+		return int64(pad), nil
+	}
+	start := mp3AudioStart(data)
+	tagEnd := start
+	if data[5]&0x10 != 0 {
+		tagEnd -= 10 // footer
+	}
+	size := tagEnd - 10 - pad
+	out := make([]byte, 0, len(data)-pad)
+	out = append(out, data[:6]...)
+	out = append(out, byte(size>>21)&0x7F, byte(size>>14)&0x7F, byte(size>>7)&0x7F, byte(size)&0x7F)
+	out = append(out, data[10:tagEnd-pad]...)
+	if tagEnd != start {
+		footer := append([]byte{}, data[tagEnd:start]...)
+		copy(footer[6:10], out[6:10])
+		out = append(out, footer...)
+	}
+	out = append(out, data[start:]...)
+	return int64(pad), os.WriteFile(outPath, out, 0644)
+}
@@ -0,0 +1,43 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Payload returns the audio frames of an MP3 (between the ID3v2 tag and
+// any trailing APE, Lyrics3 or ID3v1 tags), a FLAC file (after the
+// metadata blocks) or an Ogg Vorbis or Opus stream (the page bodies after
+// the headers). Other formats return nil.
+func (h *Handler) Payload(path string) ([]byte, error) {
+	switch h.format {
+	case core.FmtMP3, core.FmtFLAC, core.FmtOGG, core.FmtOpus:
+	default:
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch h.format {
+	case core.FmtFLAC:
+		_, audioStart, err := parseFLACBlocks(data)
+		if err != nil {
+			return nil, err
+		}
+		return data[audioStart:], nil
+	case core.FmtOGG, core.FmtOpus:
+		return oggPayload(data)
+	}
+	f, ok := firstMP3Frame(data)
+	if !ok {
+		return nil, fmt.Errorf("no MPEG audio frame found")
+	}
+	end := len(data)
+	if t := findMP3Trailers(data); len(t) > 0 {
+		end = t[0].start
+	}
+	return data[f.offset:end], nil
+}
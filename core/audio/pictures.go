@@ -0,0 +1,266 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+)
+
+// ─── Embedded pictures ───────────────────────────────────────────────────────
+// ID3 APIC frames and FLAC PICTURE blocks share one list of picture types
+// (3 = front cover, 4 = back cover, 8 = artist, …) and a file may hold one
+// of each. These helpers list, extract and selectively remove them.
+
+// pictureTypeNames are the short names of the ID3v2/FLAC picture types,
+// indexed by type code.
+var pictureTypeNames = []string{
+	"other", "icon", "other-icon", "front", "back", "leaflet", "media",
+	"lead-artist", "artist", "conductor", "band", "composer", "lyricist",
+	"location", "recording", "performance", "screen-capture", "fish",
+	"illustration", "band-logo", "publisher-logo",
+}
+
+const pictureFront = 3
+
+// PictureTypeName returns the short name of a picture type code.
+func PictureTypeName(code byte) string {
+	if int(code) < len(pictureTypeNames) {
+		return pictureTypeNames[code]
+	}
+	return fmt.Sprintf("type-%d", code)
+}
+
+// ParsePictureType accepts a short name ("front", "back", …) or a code.
+func ParsePictureType(s string) (byte, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, n := range pictureTypeNames {
+		if s == n {
+			return byte(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < 256 {
+		return byte(n), nil
+	}
+	return 0, fmt.Errorf("unknown picture type %q (want front, back, artist, … or 0-20)", s)
+}
+
+// Picture is one embedded image.
+type Picture struct {
+	Index         int // 1-based position in the file
+	Type          byte
+	MIME          string
+	Description   string
+	Data          []byte
+	Width, Height int
+}
+
+func (p Picture) String() string {
+	s := fmt.Sprintf("%s (%d): %s", PictureTypeName(p.Type), p.Type, p.MIME)
+	if p.Width > 0 {
+		s += fmt.Sprintf(", %d×%d", p.Width, p.Height)
+	}
+	s += fmt.Sprintf(", %d bytes", len(p.Data))
+	if p.Description != "" {
+		s += fmt.Sprintf(", %q", p.Description)
+	}
+	return s
+}
+
+// Ext returns a file extension for the picture's data.
+func (p Picture) Ext() string {
+	switch {
+	case strings.Contains(p.MIME, "png"):
+		return ".png"
+	case strings.Contains(p.MIME, "gif"):
+		return ".gif"
+	case strings.Contains(p.MIME, "webp"):
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+func newPicture(typ byte, mime, desc string, data []byte) Picture {
+	p := Picture{Type: typ, MIME: mime, Description: desc, Data: data}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		p.Width, p.Height = cfg.Width, cfg.Height
+	}
+	return p
+}
+
+// ListPictures returns every picture embedded in an MP3 or FLAC file, in
+// file order. Other formats report the single cover dhowden/tag exposes.
+func ListPictures(path string) ([]Picture, error) {
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	var pics []Picture
+	switch fmtID {
+	case core.FmtMP3:
+		t, err := id3v2.Open(path, id3v2.Options{Parse: true, ParseFrames: []string{"Attached picture"}})
+		if err != nil {
+			return nil, fmt.Errorf("could not open MP3: %w", err)
+		}
+		defer t.Close()
+		for _, f := range t.GetFrames("APIC") {
+			if pf, ok := f.(id3v2.PictureFrame); ok {
+				pics = append(pics, newPicture(pf.PictureType, pf.MimeType, pf.Description, pf.Picture))
+			}
+		}
+	case core.FmtFLAC:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		blocks, _, err := parseFLACBlocks(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blocks {
+			if b.blockType != flacPicture {
+				continue
+			}
+			if p, ok := parseFLACPicture(b.data); ok {
+				pics = append(pics, p)
+			}
+		}
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		t, err := tag.ReadFrom(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tags: %w", err)
+		}
+		if p := t.Picture(); p != nil {
+			pics = append(pics, newPicture(pictureFront, pictureMIME(p), p.Description, p.Data))
+		}
+	}
+	for i := range pics {
+		pics[i].Index = i + 1
+	}
+	return pics, nil
+}
+
+// parseFLACPicture decodes a METADATA_BLOCK_PICTURE body.
+func parseFLACPicture(b []byte) (Picture, bool) {
+	pos := 0
+	u32 := func() (uint32, bool) {
+		if pos+4 > len(b) {
+			return 0, false
+		}
+		v := binary.BigEndian.Uint32(b[pos:])
+		pos += 4
+		return v, true
+	}
+	str := func() (string, bool) {
+		n, ok := u32()
+		if !ok || pos+int(n) > len(b) {
+			return "", false
+		}
+		s := string(b[pos : pos+int(n)])
+		pos += int(n)
+		return s, true
+	}
+	typ, ok1 := u32()
+	mime, ok2 := str()
+	desc, ok3 := str()
+	pos += 16 // width, height, depth, colours: read from the image instead
+	data, ok4 := str()
+	if !ok1 || !ok2 || !ok3 || !ok4 || typ > 255 {
+		return Picture{}, false
+	}
+	return newPicture(byte(typ), mime, desc, []byte(data)), true
+}
+
+// flacPictureType returns the type code of a PICTURE block.
+func flacPictureType(b []byte) (byte, bool) {
+	if len(b) < 4 || b[0]|b[1]|b[2] != 0 {
+		return 0, false
+	}
+	return b[3], true
+}
+
+// RemovePictures drops the pictures for which drop returns true from an
+// MP3, FLAC or M4A file and reports how many were removed.
+func RemovePictures(path, outPath string, drop func(Picture) bool) (int, error) {
+	pics, err := ListPictures(path)
+	if err != nil {
+		return 0, err
+	}
+	removed := make(map[int]bool)
+	for _, p := range pics {
+		if drop(p) {
+			removed[p.Index] = true
+		}
+	}
+	fmtID, _ := core.DetectFormat(path)
+	out := core.ResolveOutPath(path, outPath)
+
+	switch fmtID {
+	case core.FmtMP3:
+		err := rewriteMP3(path, out, func(t *id3v2.Tag) {
+			frames := t.GetFrames("APIC")
+			t.DeleteFrames("APIC")
+			for i, f := range frames {
+				if !removed[i+1] {
+					t.AddFrame("APIC", f)
+				}
+			}
+		}, nil)
+		if err != nil {
+			return 0, err
+		}
+		return len(removed), nil
+
+	case core.FmtFLAC:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		blocks, audioStart, err := parseFLACBlocks(data)
+		if err != nil {
+			return 0, err
+		}
+		var kept []flacBlock
+		n := 0
+		for _, b := range blocks {
+			if b.blockType == flacPicture {
+				if _, ok := parseFLACPicture(b.data); ok {
+					n++
+					if removed[n] {
+						continue
+					}
+				}
+			}
+			kept = append(kept, b)
+		}
+		return len(removed), writeFLAC(out, kept, data[audioStart:])
+
+	case core.FmtM4A:
+		// The covr atom is the one picture ListPictures reports.
+		if len(removed) == 0 {
+			if out == path {
+				return 0, nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return 0, err
+			}
+			return 0, os.WriteFile(out, data, 0644)
+		}
+		return len(removed), video.EditISOBMFF(path, out, core.EditOptions{Delete: []string{"covr"}})
+	}
+	return 0, fmt.Errorf("pictures can be removed from MP3, FLAC and M4A, not %s", fmtID)
+}
@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// SizeBreakdown lists the tags and audio frames of an MP3 or the metadata
+// blocks and frames of a FLAC file. Other formats return nil.
+func (h *Handler) SizeBreakdown(path string) ([]core.SizePart, error) {
+	switch h.format {
+	case core.FmtMP3, core.FmtFLAC:
+	default:
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if h.format == core.FmtFLAC {
+		blocks, audioStart, err := parseFLACBlocks(data)
+		if err != nil {
+			return nil, err
+		}
+		parts := []core.SizePart{{Name: "fLaC marker", Bytes: 4}}
+		for _, b := range blocks {
+			parts = append(parts, core.SizePart{
+				Name:     flacBlockNames[b.blockType],
+				Bytes:    int64(len(b.data)) + 4,
+				Metadata: b.blockType == flacVorbisComment || b.blockType == flacPicture,
+			})
+		}
+		return append(parts, core.SizePart{Name: "Audio frames", Bytes: int64(len(data) - audioStart)}), nil
+	}
+
+	var parts []core.SizePart
+	start := mp3AudioStart(data)
+	if start > 0 {
+		pad := id3v2Padding(data)
+		parts = append(parts,
+			core.SizePart{Name: "ID3v2 tag", Bytes: int64(start - pad), Metadata: true},
+			core.SizePart{Name: "ID3v2 padding", Bytes: int64(pad), Metadata: true})
+	}
+	end := len(data)
+	trailers := findMP3Trailers(data)
+	if len(trailers) > 0 {
+		end = trailers[0].start
+	}
+	parts = append(parts, core.SizePart{Name: "Audio frames", Bytes: int64(end - start)})
+	for _, t := range trailers {
+		parts = append(parts, core.SizePart{Name: mp3TrailerNames[t.kind], Bytes: int64(t.end - t.start), Metadata: true})
+	}
+	return parts, nil
+}
+
+var flacBlockNames = map[byte]string{
+	flacStreamInfo:    "STREAMINFO",
+	flacPadding:       "PADDING",
+	flacApplication:   "APPLICATION",
+	flacSeekTable:     "SEEKTABLE",
+	flacVorbisComment: "VORBIS_COMMENT",
+	flacCueSheet:      "CUESHEET",
+	flacPicture:       "PICTURE",
+}
+
+var mp3TrailerNames = map[string]string{
+	"id3v1":   "ID3v1 tag",
+	"ape":     "APEv2 tag",
+	"lyrics3": "Lyrics3 tag",
+}
+
+// id3v2Padding returns the number of zero bytes after the last frame of
+// the ID3v2 tag at the start of data.
+func id3v2Padding(data []byte) int {
+	start := mp3AudioStart(data)
+	if start == 0 {
+		return 0
+	}
+	ver := data[3]
+	end := start
+	if data[5]&0x10 != 0 {
+		end -= 10 // footer
+	}
+	pos := 10
+	if data[5]&0x40 != 0 && pos+4 <= end { // extended header
+		n := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		if ver == 4 {
+			n = syncsafe(data[pos : pos+4])
+		} else {
+			n += 4
+		}
+		pos += n
+	}
+	idLen, hdrLen := 4, 10
+	if ver == 2 {
+		idLen, hdrLen = 3, 6
+	}
+	for pos+hdrLen <= end {
+		if data[pos] == 0 {
+			if len(bytes.Trim(data[pos:end], "\x00")) == 0 {
+				return end - pos
+			}
+			return 0
+		}
+		var size int
+		switch ver {
+		case 2:
+			size = int(data[pos+3])<<16 | int(data[pos+4])<<8 | int(data[pos+5])
+		case 4:
+			size = syncsafe(data[pos+idLen : pos+idLen+4])
+		default:
+			size = int(binary.BigEndian.Uint32(data[pos+idLen : pos+idLen+4]))
+		}
+		pos += hdrLen + size
+	}
+	if pos < end && len(bytes.Trim(data[pos:end], "\x00")) == 0 {
+		return end - pos
+	}
+	return 0
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
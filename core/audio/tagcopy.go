@@ -0,0 +1,315 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+)
+
+// ─── Tag copying between containers ──────────────────────────────────────────
+// The same track often exists as MP3, FLAC and M4A. Copying tags between
+// them means translating field names: ID3v2 frame IDs, Vorbis comment keys
+// and iTunes atoms each spell "album artist" differently. tagMappings is
+// the translation table; the source is read with dhowden/tag (so MP3, FLAC,
+// OGG and M4A all work as sources) and written to an MP3 or FLAC target.
+
+// tagMapping names one field in each tag container. An empty container
+// name means that container has no equivalent field.
+type tagMapping struct {
+	name   string // friendly name, as accepted by edit
+	id3    string // ID3v2.4 frame ID
+	vorbis string // Vorbis comment key
+	itunes string // iTunes atom; "----:mean:name" for freeform atoms
+}
+
+var tagMappings = []tagMapping{
+	{"Title", "TIT2", "TITLE", "\xa9nam"},
+	{"Artist", "TPE1", "ARTIST", "\xa9ART"},
+	{"Album", "TALB", "ALBUM", "\xa9alb"},
+	{"AlbumArtist", "TPE2", "ALBUMARTIST", "aART"},
+	{"Composer", "TCOM", "COMPOSER", "\xa9wrt"},
+	{"Genre", "TCON", "GENRE", "\xa9gen"},
+	{"Year", "TDRC", "DATE", "\xa9day"},
+	{"TrackNumber", "TRCK", "TRACKNUMBER", "trkn"},
+	{"DiscNumber", "TPOS", "DISCNUMBER", "disk"},
+	{"Grouping", "TIT1", "GROUPING", "\xa9grp"},
+	{"Comment", "COMM", "COMMENT", "\xa9cmt"},
+	{"Lyrics", "USLT", "LYRICS", "\xa9lyr"},
+	{"Copyright", "TCOP", "COPYRIGHT", "cprt"},
+	{"BPM", "TBPM", "BPM", "tmpo"},
+	{"InitialKey", "TKEY", "INITIALKEY", "----:com.apple.iTunes:initialkey"},
+	{"Publisher", "TPUB", "LABEL", "----:com.apple.iTunes:LABEL"},
+	{"ISRC", "TSRC", "ISRC", "----:com.apple.iTunes:ISRC"},
+	{"Conductor", "TPE3", "CONDUCTOR", "----:com.apple.iTunes:CONDUCTOR"},
+	{"Lyricist", "TEXT", "LYRICIST", "----:com.apple.iTunes:LYRICIST"},
+}
+
+// CopyOptions controls CopyTags.
+type CopyOptions struct {
+	NoCover bool         // do not copy the front cover
+	DryRun  bool         // print the mapping without writing
+	Cover   CoverOptions // limits applied to the copied cover
+}
+
+// copiedTag is one field read from the source.
+type copiedTag struct {
+	m     tagMapping
+	value string
+}
+
+// CopyTags reads the tags of src and writes them, translated, into dst
+// (or into outPath when set). Fields missing from src are left untouched
+// in dst.
+func CopyTags(src, dst, outPath string, opts CopyOptions) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	t, err := tag.ReadFrom(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("cannot read tags from %s: %w", src, err)
+	}
+
+	dstFmt, err := core.DetectFormat(dst)
+	if err != nil {
+		return err
+	}
+	if dstFmt != core.FmtMP3 && dstFmt != core.FmtFLAC {
+		return fmt.Errorf("copy-tags can write to MP3 and FLAC, not %s", dstFmt)
+	}
+
+	fields := readMappedTags(t)
+	var pic *tag.Picture
+	if !opts.NoCover {
+		pic = t.Picture()
+	}
+	if len(fields) == 0 && pic == nil {
+		return fmt.Errorf("%s has no tags to copy", src)
+	}
+	if pic != nil {
+		c, err := PrepareCover(pic.Data, opts.Cover)
+		if err != nil {
+			return err
+		}
+		pic = &tag.Picture{MIMEType: c.MIME, Type: pic.Type, Description: pic.Description, Data: c.Data}
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Dry-run: tags that would be copied (%s → %s):\n", t.Format(), dstFmt)
+		for _, c := range fields {
+			target := c.m.id3
+			if dstFmt == core.FmtFLAC {
+				target = c.m.vorbis
+			}
+			fmt.Printf("  %-12s → %-12s = %s\n", c.m.name, target, c.value)
+		}
+		if pic != nil {
+			fmt.Printf("  %-12s → %-12s = %s\n", "Cover", "picture", coverSummary(pic))
+		}
+		return nil
+	}
+
+	out := core.ResolveOutPath(dst, outPath)
+	if dstFmt == core.FmtMP3 {
+		return writeMP3Tags(dst, out, fields, pic)
+	}
+	return writeFLACTags(dst, out, fields, pic)
+}
+
+// readMappedTags returns the mapped fields present in t, in table order.
+func readMappedTags(t tag.Metadata) []copiedTag {
+	raw := t.Raw()
+	var out []copiedTag
+	for _, m := range tagMappings {
+		v := ""
+		switch m.name {
+		case "Title":
+			v = t.Title()
+		case "Artist":
+			v = t.Artist()
+		case "Album":
+			v = t.Album()
+		case "AlbumArtist":
+			v = t.AlbumArtist()
+		case "Genre":
+			v = t.Genre()
+		case "Comment":
+			v = t.Comment()
+		case "Lyrics":
+			v = t.Lyrics()
+		case "Year":
+			v = rawString(raw, t.Format(), m)
+			if v == "" && t.Year() > 0 {
+				v = fmt.Sprintf("%d", t.Year())
+			}
+		case "TrackNumber":
+			v = numberPair(t.Track())
+		case "DiscNumber":
+			v = numberPair(t.Disc())
+		default:
+			v = rawString(raw, t.Format(), m)
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, copiedTag{m: m, value: v})
+		}
+	}
+	return out
+}
+
+// rawString looks up the container-specific name of m in a raw tag map.
+func rawString(raw map[string]interface{}, format tag.Format, m tagMapping) string {
+	var key string
+	switch format {
+	case tag.ID3v2_3, tag.ID3v2_4:
+		key = m.id3
+	case tag.VORBIS:
+		key = strings.ToLower(m.vorbis)
+	case tag.MP4:
+		key = m.itunes
+		if i := strings.LastIndex(key, ":"); i >= 0 {
+			key = key[i+1:]
+		}
+	}
+	switch v := raw[key].(type) {
+	case string:
+		return v
+	case int:
+		if v != 0 {
+			return fmt.Sprintf("%d", v)
+		}
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// numberPair formats a track or disc position as "n" or "n/total".
+func numberPair(n, total int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case total > 0:
+		return fmt.Sprintf("%d/%d", n, total)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// ─── Writers ─────────────────────────────────────────────────────────────────
+
+func writeMP3Tags(path, outPath string, fields []copiedTag, pic *tag.Picture) error {
+	return rewriteMP3(path, outPath, func(t *id3v2.Tag) {
+		t.SetVersion(4)
+		for _, c := range fields {
+			t.DeleteFrames(c.m.id3)
+			switch c.m.id3 {
+			case "COMM":
+				t.AddCommentFrame(id3v2.CommentFrame{Encoding: id3v2.EncodingUTF8, Language: "eng", Text: c.value})
+			case "USLT":
+				t.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{Encoding: id3v2.EncodingUTF8, Language: "eng", Lyrics: c.value})
+			default:
+				t.AddTextFrame(c.m.id3, id3v2.EncodingUTF8, c.value)
+			}
+		}
+		if pic != nil {
+			replaceID3FrontCover(t, pic)
+		}
+	}, nil)
+}
+
+func writeFLACTags(path, outPath string, fields []copiedTag, pic *tag.Picture) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) < 4 || !bytes.Equal(data[0:4], []byte("fLaC")) {
+		return fmt.Errorf("not a valid FLAC file")
+	}
+	blocks, audioStart, err := parseFLACBlocks(data)
+	if err != nil {
+		return err
+	}
+
+	vcIdx := -1
+	for i, b := range blocks {
+		if b.blockType == flacVorbisComment {
+			vcIdx = i
+			break
+		}
+	}
+	var comments vorbisComments
+	vendor := ""
+	if vcIdx >= 0 {
+		comments = parseVorbisComments(blocks[vcIdx].data)
+		vendor = vorbisVendor(blocks[vcIdx].data)
+	}
+	for _, c := range fields {
+		// Vorbis keeps position and total apart: TRACKNUMBER + TRACKTOTAL.
+		if c.m.name == "TrackNumber" || c.m.name == "DiscNumber" {
+			n, total, _ := strings.Cut(c.value, "/")
+			comments = comments.set(c.m.vorbis, n)
+			if total != "" {
+				comments = comments.set(strings.TrimSuffix(c.m.vorbis, "NUMBER")+"TOTAL", total)
+			}
+			continue
+		}
+		comments = comments.set(c.m.vorbis, c.value)
+	}
+	if vcIdx >= 0 {
+		blocks[vcIdx].data = buildVorbisComment(vendor, comments)
+	} else {
+		newBlock := flacBlock{blockType: flacVorbisComment, data: buildVorbisComment(vendor, comments)}
+		blocks = append([]flacBlock{blocks[0], newBlock}, blocks[1:]...)
+	}
+
+	if pic != nil {
+		blocks = replaceFLACFrontCover(blocks, pic)
+	}
+	return writeFLAC(outPath, blocks, data[audioStart:])
+}
+
+// buildFLACPicture encodes a METADATA_BLOCK_PICTURE holding a front cover:
+// type, MIME, description, width, height, depth, colours, then the data.
+func buildFLACPicture(pic *tag.Picture) []byte {
+	var w, h uint32
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(pic.Data)); err == nil {
+		w, h = uint32(cfg.Width), uint32(cfg.Height)
+	}
+	mime := pictureMIME(pic)
+	var buf bytes.Buffer
+	put := func(v uint32) { binary.Write(&buf, binary.BigEndian, v) }
+	put(3) // front cover
+	put(uint32(len(mime)))
+	buf.WriteString(mime)
+	put(uint32(len(pic.Description)))
+	buf.WriteString(pic.Description)
+	put(w)
+	put(h)
+	put(24) // colour depth
+	put(0)  // not indexed
+	put(uint32(len(pic.Data)))
+	buf.Write(pic.Data)
+	return buf.Bytes()
+}
+
+// pictureMIME returns the MIME type of pic, which dhowden/tag reports as
+// a bare extension for some containers.
+func pictureMIME(pic *tag.Picture) string {
+	if strings.Contains(pic.MIMEType, "/") {
+		return pic.MIMEType
+	}
+	switch strings.ToLower(pic.Ext) {
+	case "png":
+		return "image/png"
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
+)
+
+// UnknownStructures lists the APPLICATION and reserved metadata blocks of
+// a FLAC file, the chunks of a WAV or AIFF file that view does not read,
+// and the atoms of an M4A as the MP4 report finds them.
+func (h *Handler) UnknownStructures(path string) ([]core.UnknownStructure, error) {
+	switch h.format {
+	case core.FmtM4A:
+		return video.UnknownISOBMFF(path)
+	case core.FmtWAV:
+		return wavUnknown(path)
+	case core.FmtFLAC, core.FmtAIFF:
+	default:
+		return nil, fmt.Errorf("unknown-structure report supports FLAC, M4A, WAV and AIFF audio, not %s", strings.ToUpper(string(h.format)))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if h.format == core.FmtAIFF {
+		return aiffUnknown(data), nil
+	}
+	blocks, _, err := parseFLACBlocks(data)
+	if err != nil {
+		return nil, err
+	}
+	var out []core.UnknownStructure
+	for _, b := range blocks {
+		u := core.UnknownStructure{Kind: "FLAC block", Bytes: int64(len(b.data)) + 4}
+		switch {
+		case b.blockType == flacApplication && len(b.data) >= 4:
+			u.ID = fmt.Sprintf("APPLICATION %q", b.data[:4])
+		case flacBlockNames[b.blockType] == "":
+			u.ID = fmt.Sprintf("type %d", b.blockType)
+		default:
+			continue
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// wavKnownChunks are the chunks view reads and those that carry the audio
+// or pad it.
+var wavKnownChunks = map[string]bool{
+	"fmt ": true, "data": true, "fact": true, "LIST": true, "id3 ": true,
+	"ID3 ": true, "JUNK": true, "junk": true, "PAD ": true, "ds64": true,
+}
+
+// wavUnknown lists the chunks of a WAV other than wavKnownChunks, and LIST
+// chunks other than INFO by their list type.
+func wavUnknown(path string) ([]core.UnknownStructure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := core.RIFFChunks(f, 12, st.Size())
+	if err != nil {
+		return nil, err
+	}
+	var out []core.UnknownStructure
+	for _, c := range chunks {
+		id := c.ID
+		if c.ID == "LIST" {
+			typ, err := core.ReadRange(f, c.Off, 4)
+			if err != nil || string(typ) == "INFO" {
+				continue
+			}
+			id = "LIST " + string(typ)
+		} else if wavKnownChunks[c.ID] {
+			continue
+		}
+		out = append(out, core.UnknownStructure{Kind: "RIFF chunk", ID: id, Bytes: c.Size + 8})
+	}
+	return out, nil
+}
+
+// aiffKnownChunks are the chunks viewAIFF reads, the sound data and the
+// AIFF-C version.
+var aiffKnownChunks = map[string]bool{
+	"COMM": true, "SSND": true, "FVER": true, "NAME": true, "AUTH": true,
+	"(c) ": true, "ANNO": true, "ID3 ": true,
+}
+
+func aiffUnknown(data []byte) []core.UnknownStructure {
+	var out []core.UnknownStructure
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+		if offset+8+size > len(data) {
+			break
+		}
+		if !aiffKnownChunks[id] {
+			out = append(out, core.UnknownStructure{Kind: "AIFF chunk", ID: id, Bytes: int64(size) + 8})
+		}
+		offset += 8 + size + size%2
+	}
+	return out
+}
@@ -0,0 +1,358 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ─── Audit log ───────────────────────────────────────────────────────────────
+// Organisations that must show what was removed from a file before it was
+// published can have every write recorded. With an "audit" section in the
+// config file, each edit or strip appends one JSON line to the log: who ran
+// it and when, the file's SHA-256 before and after, and every field whose
+// value changed. Each line carries the hash of the line before it, so lines
+// cannot be removed or reordered unnoticed, and an HMAC-SHA256 over the
+// line when a key is configured.
+//
+//	{ "audit": { "path": "/var/log/surgery-audit.jsonl", "key": "…" } }
+
+// AuditConfig enables the audit log.
+type AuditConfig struct {
+	Path string `json:"path"`
+	// Key signs each line with HMAC-SHA256; SURGERY_AUDIT_KEY overrides it.
+	Key string `json:"key,omitempty"`
+}
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time    string        `json:"time"`
+	User    string        `json:"user"`
+	Host    string        `json:"host,omitempty"`
+	Op      string        `json:"op"`
+	File    string        `json:"file"`
+	Out     string        `json:"out,omitempty"`
+	Before  string        `json:"sha256_before"`
+	After   string        `json:"sha256_after,omitempty"`
+	Changes []FieldChange `json:"changes"`
+	Error   string        `json:"error,omitempty"`
+	Prev    string        `json:"prev"`
+	Sig     string        `json:"sig,omitempty"`
+}
+
+// FieldChange is one field whose value an operation set, changed or removed.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+var (
+	auditOnce sync.Once
+	auditCfg  *AuditConfig
+	auditMu   sync.Mutex
+)
+
+// auditSettings returns the audit configuration, or nil when auditing is off.
+func auditSettings() *AuditConfig {
+	auditOnce.Do(func() {
+		cfg, err := LoadConfig()
+		if err != nil || cfg.Audit == nil || cfg.Audit.Path == "" {
+			return
+		}
+		a := *cfg.Audit
+		if k := os.Getenv("SURGERY_AUDIT_KEY"); k != "" {
+			a.Key = k
+		}
+		auditCfg = &a
+	})
+	return auditCfg
+}
+
+// AuditRecord is an operation being audited. A nil *AuditRecord is valid
+// and does nothing, so callers need not check whether auditing is on.
+type AuditRecord struct {
+	entry  AuditEntry
+	h      Handler
+	fields map[string]string
+}
+
+// BeginAudit records the state of path before op writes to it. It returns
+// nil when auditing is off.
+func BeginAudit(op string, h Handler, path string) *AuditRecord {
+	if auditSettings() == nil {
+		return nil
+	}
+	r := &AuditRecord{h: h, entry: AuditEntry{Op: op, File: path}}
+	if s, err := Snapshot(path); err == nil {
+		r.entry.Before = s.SHA256
+	}
+	r.fields = auditFields(h, path)
+	return r
+}
+
+// Finish appends the entry for the operation, which wrote out ("" or the
+// input path for in-place) and returned err. A failure to write the log is
+// returned so that the caller can report it.
+func (r *AuditRecord) Finish(out string, err error) error {
+	if r == nil {
+		return nil
+	}
+	target := r.entry.File
+	if out != "" && out != r.entry.File {
+		r.entry.Out = out
+		target = out
+	}
+	if err != nil {
+		r.entry.Error = err.Error()
+	} else {
+		if s, serr := Snapshot(target); serr == nil {
+			r.entry.After = s.SHA256
+		}
+		r.entry.Changes = diffFields(r.fields, auditFields(r.h, target))
+	}
+	if r.entry.Changes == nil {
+		r.entry.Changes = []FieldChange{}
+	}
+	return appendAuditEntry(auditSettings(), r.entry)
+}
+
+// Audited returns h with Edit and Strip recorded in the audit log, when
+// auditing is on, and with the file they replace backed up first, when
+// their options set Backup. A write the disk has no room for is refused
+// up front (see CheckSpace); the result is written to a temporary file
+// that replaces the output only if the input did not change meanwhile (see
+// GuardedWrite); one that fails is undone as far as it can be and returns
+// a *WriteError (see RecoverWrite). Dry runs do none of it.
+func Audited(h Handler) Handler {
+	if _, ok := h.(auditedHandler); ok {
+		return h
+	}
+	return auditedHandler{h}
+}
+
+type auditedHandler struct{ Handler }
+
+func (a auditedHandler) Edit(path, out string, opts EditOptions) error {
+	if opts.DryRun {
+		return a.Handler.Edit(path, out, opts)
+	}
+	if err := CheckSpace(path, out, EditEstimate(path, opts), opts.Backup); err != nil {
+		return err
+	}
+	before, err := Snapshot(path)
+	if err != nil {
+		return err
+	}
+	backup, err := BackupBefore(path, out, opts.Backup)
+	if err != nil {
+		return err
+	}
+	return RecoverWrite("edit", path, out, backup, func() error {
+		return AuditWrite("edit", a.Handler, path, out, func() error {
+			return GuardedWrite(path, out, before, func(tmp string) error { return a.Handler.Edit(path, tmp, opts) })
+		})
+	})
+}
+
+func (a auditedHandler) Strip(path, out string, opts StripOptions) error {
+	if opts.DryRun {
+		return a.Handler.Strip(path, out, opts)
+	}
+	if err := CheckSpace(path, out, StripEstimate(path), opts.Backup); err != nil {
+		return err
+	}
+	before, err := Snapshot(path)
+	if err != nil {
+		return err
+	}
+	backup, err := BackupBefore(path, out, opts.Backup)
+	if err != nil {
+		return err
+	}
+	return RecoverWrite("strip", path, out, backup, func() error {
+		return AuditWrite("strip", a.Handler, path, out, func() error {
+			return GuardedWrite(path, out, before, func(tmp string) error { return a.Handler.Strip(path, tmp, opts) })
+		})
+	})
+}
+
+// AuditWrite runs write, which changes path or saves it to out, and records
+// it as op. It is for writers other than a handler's Edit and Strip, which
+// Audited covers; h is used to read the fields before and after.
+func AuditWrite(op string, h Handler, path, out string, write func() error) error {
+	r := BeginAudit(op, h, path)
+	err := write()
+	if lerr := r.Finish(ResolveOutPath(path, out), err); lerr != nil && err == nil {
+		return lerr
+	}
+	return err
+}
+
+// auditFields views path and flattens its fields to key → value, joining
+// repeated keys.
+func auditFields(h Handler, path string) map[string]string {
+	fields := make(map[string]string)
+	m, err := h.View(path)
+	if err != nil {
+		return fields
+	}
+	for _, f := range m.Fields {
+		if v, ok := fields[f.Key]; ok {
+			fields[f.Key] = v + "; " + f.Value
+		} else {
+			fields[f.Key] = f.Value
+		}
+	}
+	return fields
+}
+
+func diffFields(before, after map[string]string) []FieldChange {
+	var changes []FieldChange
+	for k, v := range before {
+		if a, ok := after[k]; !ok || a != v {
+			changes = append(changes, FieldChange{Field: k, Before: v, After: after[k]})
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok {
+			changes = append(changes, FieldChange{Field: k, After: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func appendAuditEntry(cfg *AuditConfig, e AuditEntry) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	e.Time = time.Now().UTC().Format(time.RFC3339)
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	}
+	e.Host, _ = os.Hostname()
+
+	f, err := os.OpenFile(cfg.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	defer f.Close()
+	last, err := lastLine(f)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	e.Prev = lineHash(last)
+	line, err := signAuditEntry(e, cfg.Key)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// signAuditEntry serialises e, with its signature when key is set.
+func signAuditEntry(e AuditEntry, key string) ([]byte, error) {
+	e.Sig = ""
+	line, err := json.Marshal(e)
+	if err != nil || key == "" {
+		return line, err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(line)
+	e.Sig = hex.EncodeToString(mac.Sum(nil))
+	return json.Marshal(e)
+}
+
+// lineHash is the chain value for the line before an entry; the first
+// entry of a log chains to the hash of nothing.
+func lineHash(line []byte) string {
+	d := sha256.Sum256(line)
+	return hex.EncodeToString(d[:])
+}
+
+// lastLine returns the last complete line of f, without its newline.
+func lastLine(f *os.File) ([]byte, error) {
+	st, err := f.Stat()
+	if err != nil || st.Size() == 0 {
+		return nil, err
+	}
+	const maxLine = 1 << 20
+	n := st.Size()
+	if n > maxLine {
+		n = maxLine
+	}
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, st.Size()-n); err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = bytes.TrimRight(buf, "\n")
+	if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+		buf = buf[i+1:]
+	}
+	return buf, nil
+}
+
+// VerifyAuditLog checks the hash chain of the log at path and, when key
+// is not empty, every line's signature. It returns the number of entries
+// checked and an error naming the first bad line.
+func VerifyAuditLog(path, key string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	var prev []byte
+	n := 0
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		n++
+		var e AuditEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return n - 1, fmt.Errorf("line %d: %w", n, err)
+		}
+		if e.Prev != lineHash(prev) {
+			return n - 1, fmt.Errorf("line %d: chain broken — a line before it was changed, removed or reordered", n)
+		}
+		if key != "" {
+			want, err := signAuditEntry(e, key)
+			if err != nil {
+				return n - 1, err
+			}
+			if e.Sig == "" || !hmac.Equal(want, line) {
+				return n - 1, fmt.Errorf("line %d: signature does not match", n)
+			}
+		}
+		prev = append(prev[:0], line...)
+	}
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// AuditKey returns the signing key configured for the audit log.
+func AuditKey() string {
+	if a := auditSettings(); a != nil {
+		return a.Key
+	}
+	return strings.TrimSpace(os.Getenv("SURGERY_AUDIT_KEY"))
+}
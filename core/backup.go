@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─── Backups ─────────────────────────────────────────────────────────────────
+// A strip cannot be undone, and an edit with a wrong --replace pattern can
+// ruin a whole library. With Backup set in the options, the file a write
+// is about to replace is first copied to <name>.bak, or under another
+// suffix or into a backup directory. Audited applies it, so every handler
+// gets it; a copy that would overwrite an earlier backup is numbered
+// instead (<name>.bak.1, .bak.2, …).
+
+// DefaultBackupSuffix is added to the name of a backup when
+// BackupOptions.Suffix is empty.
+const DefaultBackupSuffix = ".bak"
+
+// BackupOptions says where the copy of a file goes before a write replaces
+// it.
+type BackupOptions struct {
+	// Suffix is added to the file name; "" for DefaultBackupSuffix.
+	Suffix string
+	// Dir is the directory for the copies, created if needed; "" puts each
+	// copy next to its file.
+	Dir string
+}
+
+// BackupBefore copies the file that writing path to out ("" for in place)
+// would replace, as b says, and returns the path of the copy. It does
+// nothing and returns "" when b is nil or the destination does not exist
+// yet, since a write to a new file destroys nothing.
+func BackupBefore(path, out string, b *BackupOptions) (string, error) {
+	if b == nil {
+		return "", nil
+	}
+	dest := ResolveOutPath(path, out)
+	if st, err := os.Stat(dest); err != nil || !st.Mode().IsRegular() {
+		return "", nil
+	}
+	p, err := backupFile(dest, *b)
+	if err != nil {
+		return "", fmt.Errorf("cannot back up %s: %w", dest, err)
+	}
+	return p, nil
+}
+
+// IsBackupName reports whether name is that of a backup copy made with
+// suffix ("" for DefaultBackupSuffix), numbered or not. Batch commands
+// skip such files.
+func IsBackupName(name, suffix string) bool {
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	if i := strings.LastIndex(name, suffix); i > 0 {
+		rest := name[i+len(suffix):]
+		if rest == "" {
+			return true
+		}
+		return rest[0] == '.' && len(rest) > 1 && strings.Trim(rest[1:], "0123456789") == ""
+	}
+	return false
+}
+
+// backupFile copies path to its backup name, with its permissions, owner
+// and extended attributes.
+func backupFile(path string, b BackupOptions) (string, error) {
+	suffix := b.Suffix
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	dir := filepath.Dir(path)
+	if b.Dir != "" {
+		if err := os.MkdirAll(b.Dir, 0755); err != nil {
+			return "", err
+		}
+		dir = b.Dir
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	base := filepath.Join(dir, filepath.Base(path)+suffix)
+	var f *os.File
+	p := base
+	for i := 1; ; i++ {
+		f, err = os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			break
+		}
+		p = fmt.Sprintf("%s.%d", base, i)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, in); err != nil {
+		f.Close()
+		os.Remove(p)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+	if err := CopyAttrs(path, p); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+	return p, nil
+}
@@ -0,0 +1,299 @@
+// Package batch applies metadata operations to many files at once, driven
+// by a manifest that lists per-file Set/Delete operations.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/audio"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/document"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/image"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/subtitle"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/video"
+)
+
+// HandlerFor detects the format of path and returns its Handler.
+func HandlerFor(path string) (core.Handler, error) {
+	fmtID, err := core.DetectFormat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot detect format of %s: %w", path, err)
+	}
+	if fmtID == core.FmtUnknown {
+		return nil, fmt.Errorf("unknown or unsupported format: %s", path)
+	}
+	return handlerForID(fmtID)
+}
+
+// handlerForID returns the Handler for a detected format.
+func handlerForID(fmtID core.FormatID) (core.Handler, error) {
+	switch core.MediaTypeFor(fmtID) {
+	case "image":
+		return image.New(fmtID), nil
+	case "audio":
+		return audio.New(fmtID), nil
+	case "video":
+		return video.New(fmtID), nil
+	case "document":
+		return document.New(fmtID), nil
+	case "subtitle":
+		return subtitle.New(fmtID), nil
+	default:
+		return nil, fmt.Errorf("no handler for format: %s", fmtID)
+	}
+}
+
+// HandlerForBytes detects the format of a file held in data and returns
+// its handler for in-memory use. Only image formats have one; see
+// core.MemoryHandler.
+func HandlerForBytes(data []byte) (core.MemoryHandler, error) {
+	fmtID := core.DetectFormatFromBytes(data)
+	if fmtID == core.FmtUnknown {
+		return nil, fmt.Errorf("unknown or unsupported format")
+	}
+	if core.MediaTypeFor(fmtID) != "image" {
+		return nil, fmt.Errorf("%s files cannot be processed in memory; use a file", strings.ToUpper(string(fmtID)))
+	}
+	return image.New(fmtID), nil
+}
+
+// Open detects the format of path and opens it for several reads and edits.
+func Open(path string) (*core.Document, error) {
+	h, err := HandlerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return core.OpenDocument(h, path)
+}
+
+// ─── Manifest ────────────────────────────────────────────────────────────────
+
+// Entry is one file's operations in a manifest.
+type Entry struct {
+	Path   string            `json:"path"`
+	Set    map[string]string `json:"set,omitempty"`
+	Delete []string          `json:"delete,omitempty"`
+	Out    string            `json:"out,omitempty"` // default: edit in place
+}
+
+// Manifest is an ordered list of per-file operations.
+type Manifest []Entry
+
+// Result reports what happened to one manifest entry.
+type Result struct {
+	Path   string `json:"path"`
+	Out    string `json:"out,omitempty"`
+	Status string `json:"status"` // "ok", "error", "skipped", "dry-run", "rolled-back", "interrupted" or "done" (by an earlier run; see Options.State)
+	Error  string `json:"error,omitempty"`
+}
+
+// ParseManifest reads a manifest written either as a JSON array of entries
+// or as NDJSON (one entry object per line).
+func ParseManifest(r io.Reader) (Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var m Manifest
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+		}
+		return m, nil
+	}
+
+	var m Manifest
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(text, &e); err != nil {
+			return nil, fmt.Errorf("invalid NDJSON manifest, line %d: %w", line, err)
+		}
+		m = append(m, e)
+	}
+	return m, sc.Err()
+}
+
+// Options controls BatchApply.
+type Options struct {
+	// Dir is the base directory for relative entry paths.
+	Dir string
+	// DryRun previews each edit without writing.
+	DryRun bool
+	// UnicodeForm normalizes the written values; see core.EditOptions.
+	UnicodeForm string
+	// Fingerprinter, when set, fills the AcoustID tags of every editable
+	// audio entry. Values given in the entry win.
+	Fingerprinter audio.Fingerprinter
+	// Transaction applies the edits all together or not at all: if any
+	// entry fails, no file is changed and the edited entries are reported
+	// as "rolled-back".
+	Transaction bool
+	// Backup copies each file before it is replaced; see core.BackupBefore.
+	Backup *core.BackupOptions
+	// FormatOptions are passed to every edit; each handler uses the keys
+	// of its format. See core.FormatOption.
+	FormatOptions map[string]string
+	// Stop, when set, is asked before each entry; once it reports true
+	// the entries left are not run and are reported as "interrupted". An
+	// entry being written is always finished.
+	Stop func() bool
+	// State, when set, skips the entries an earlier run completed and
+	// records the outcome of every entry run; see OpenState. With
+	// Transaction, outcomes are recorded once the transaction ends.
+	State *State
+}
+
+// BatchApply runs every entry of m through its format's Edit and returns
+// one Result per entry, in manifest order. A failing entry does not stop
+// the run; opts.Stop does.
+func BatchApply(m Manifest, opts Options) []Result {
+	var tx *Tx
+	if opts.Transaction && !opts.DryRun {
+		var err error
+		if tx, err = BeginTx(); err != nil {
+			results := make([]Result, len(m))
+			for i, e := range m {
+				results[i] = Result{Path: e.Path, Out: e.Out, Status: "error", Error: err.Error()}
+			}
+			return results
+		}
+		tx.Backup = opts.Backup
+	}
+	results := make([]Result, 0, len(m))
+	resumed := make([]bool, len(m))
+	for i, e := range m {
+		path, out := e.Path, e.Out
+		if opts.Dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(opts.Dir, path)
+		}
+		if opts.Dir != "" && out != "" && !filepath.IsAbs(out) {
+			out = filepath.Join(opts.Dir, out)
+		}
+		r := Result{Path: e.Path, Out: e.Out}
+
+		switch {
+		case opts.Stop != nil && opts.Stop():
+			r.Status = "interrupted"
+		case opts.State != nil && e.Path != "" && opts.State.Completed(path):
+			r.Status = "done"
+			resumed[i] = true
+		case e.Path == "":
+			r.Status, r.Error = "error", "entry has no path"
+		case len(e.Set) == 0 && len(e.Delete) == 0 && opts.Fingerprinter == nil:
+			r.Status = "skipped"
+		default:
+			r.Status, r.Error = apply(path, out, e, opts, tx)
+		}
+		if tx == nil && !resumed[i] {
+			recordState(opts, r)
+		}
+		results = append(results, r)
+	}
+	if tx != nil {
+		finishTx(tx, results)
+		for i, r := range results {
+			if !resumed[i] {
+				recordState(opts, r)
+			}
+		}
+	}
+	return results
+}
+
+// recordState writes the outcome of r to opts.State. Interrupted and
+// dry-run entries are not outcomes and are left for the next run. A
+// failed write is kept by the State; see State.Err.
+func recordState(opts Options, r Result) {
+	if opts.State == nil || opts.DryRun || r.Status == "interrupted" {
+		return
+	}
+	path := r.Path
+	if opts.Dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(opts.Dir, path)
+	}
+	opts.State.Record(path, r.Status, r.Error)
+}
+
+// finishTx commits tx when no entry failed or was interrupted, and
+// otherwise rolls it back and marks the edited entries as such.
+func finishTx(tx *Tx, results []Result) {
+	reason := ""
+	for _, r := range results {
+		if r.Status == "error" {
+			reason = "another entry failed: " + r.Path
+			break
+		}
+		if r.Status == "interrupted" {
+			reason = "interrupted"
+			break
+		}
+	}
+	if reason == "" {
+		err := tx.Commit()
+		if err == nil {
+			return
+		}
+		reason = "commit failed: " + err.Error()
+	} else {
+		tx.Rollback()
+	}
+	for i := range results {
+		if results[i].Status == "ok" {
+			results[i].Status, results[i].Error = "rolled-back", reason
+		}
+	}
+}
+
+func apply(path, out string, e Entry, o Options, tx *Tx) (string, string) {
+	h, err := HandlerFor(path)
+	if err != nil {
+		return "error", err.Error()
+	}
+	if !h.Info().CanEdit {
+		return "skipped", fmt.Sprintf("%s does not support metadata editing", h.Info().Name)
+	}
+	set := e.Set
+	if o.Fingerprinter != nil && h.Info().MediaType == "audio" {
+		fields, err := audio.AcoustIDFields(path, o.Fingerprinter)
+		if err != nil {
+			return "error", err.Error()
+		}
+		for k, v := range e.Set {
+			fields[k] = v
+		}
+		set = fields
+	}
+	opts := core.NormalizeValues(core.EditOptions{
+		Set: set, Delete: e.Delete, DryRun: o.DryRun, UnicodeForm: o.UnicodeForm, Backup: o.Backup,
+		Options: o.FormatOptions,
+	})
+	edit := func() error { return core.Audited(h).Edit(path, out, opts) }
+	if tx != nil {
+		edit = func() error {
+			return tx.Write(path, out, func(src, tmp string) error { return core.Audited(h).Edit(src, tmp, opts) })
+		}
+	}
+	if err := edit(); err != nil {
+		return "error", err.Error()
+	}
+	if o.DryRun {
+		return "dry-run", ""
+	}
+	if dest := core.ResolveOutPath(path, out); tx == nil && dest != path {
+		core.CopyAttrs(path, dest)
+	}
+	return "ok", ""
+}
@@ -0,0 +1,142 @@
+package batch
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── File contents ───────────────────────────────────────────────────────────
+// Library callers (the C shared library) pass the contents of a file, not
+// its path. Formats with a core.MemoryHandler are handled in memory; the
+// rest are written to a temporary file, given to their usual handler and
+// read back. Only formats that DetectFormatFromBytes recognises qualify.
+
+// ViewBytes returns the metadata of the file held in data.
+func ViewBytes(data []byte) (*core.Metadata, error) {
+	if h, err := memoryHandler(data); h != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return h.ViewBytes(data)
+	}
+	var m *core.Metadata
+	err := viaTemp(data, func(h core.Handler, path string) (err error) {
+		m, err = h.View(path)
+		if m != nil {
+			m.FilePath = ""
+		}
+		return err
+	})
+	return m, err
+}
+
+// EditBytes returns data with opts applied.
+func EditBytes(data []byte, opts core.EditOptions) ([]byte, error) {
+	opts.DryRun = false
+	if h, err := memoryHandler(data); h != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return h.EditBytes(data, opts)
+	}
+	var out []byte
+	err := viaTemp(data, func(h core.Handler, path string) (err error) {
+		if err = h.Edit(path, "", opts); err == nil {
+			out, err = os.ReadFile(path)
+		}
+		return err
+	})
+	return out, err
+}
+
+// StripBytes returns data with its metadata removed as opts asks.
+func StripBytes(data []byte, opts core.StripOptions) ([]byte, error) {
+	opts.DryRun = false
+	if h, err := memoryHandler(data); h != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return h.StripBytes(data, opts)
+	}
+	var out []byte
+	err := viaTemp(data, func(h core.Handler, path string) (err error) {
+		if err = h.Strip(path, "", opts); err == nil {
+			out, err = os.ReadFile(path)
+		}
+		return err
+	})
+	return out, err
+}
+
+// memoryHandler returns the in-memory handler for data, nil when its
+// format needs a file, or an error when the format is not recognised.
+func memoryHandler(data []byte) (core.MemoryHandler, error) {
+	id := core.DetectFormatFromBytes(data)
+	switch {
+	case id == core.FmtUnknown:
+		return nil, fmt.Errorf("unknown or unsupported format")
+	case id == core.FmtJPEG || id == core.FmtPNG || id == core.FmtGIF || id == core.FmtWebP:
+		return HandlerForBytes(data)
+	}
+	return nil, nil
+}
+
+// viaTemp writes data to a temporary file with the extension of its format
+// and calls fn with the format's handler and the file.
+func viaTemp(data []byte, fn func(h core.Handler, path string) error) error {
+	h, err := handlerForID(core.DetectFormatFromBytes(data))
+	if err != nil {
+		return err
+	}
+	ext := ""
+	if exts := h.Info().Extensions; len(exts) > 0 {
+		ext = exts[0]
+	}
+	f, err := os.CreateTemp("", "surgery-bytes-*"+ext)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return fn(h, f.Name())
+}
+
+// PreservableMetadata returns the metadata of the original image in data
+// that opts keeps, for ReattachMetadata. See core.MetadataPreserver.
+func PreservableMetadata(data []byte, opts core.PreserveOptions) (*core.PreservedMetadata, error) {
+	p, err := preserver(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.PreservableMetadata(data, opts)
+}
+
+// ReattachMetadata returns the derivative image in data with p written
+// into it. The derivative may be in a different format than the original.
+func ReattachMetadata(data []byte, p *core.PreservedMetadata) ([]byte, error) {
+	mp, err := preserver(data)
+	if err != nil {
+		return nil, err
+	}
+	return mp.ReattachMetadata(data, p)
+}
+
+func preserver(data []byte) (core.MetadataPreserver, error) {
+	h, err := HandlerForBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	mp, ok := h.(core.MetadataPreserver)
+	if !ok {
+		return nil, fmt.Errorf("format does not support metadata pipelines")
+	}
+	return mp, nil
+}
@@ -0,0 +1,156 @@
+package batch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Upload policy ───────────────────────────────────────────────────────────
+// An upload service wants one answer per file: store it or refuse it.
+// CheckPolicy reads an upload once and runs the checks surgery has for
+// that question — format detection against the types the service takes,
+// the metadata checks of validate (with the privacy audit), and the
+// anomaly scan — and says whether the Policy lets the file through, with
+// every finding so the refusal can be explained or logged.
+
+// Policy is what an upload service accepts. The zero Policy accepts any
+// recognised format of any size and only reports what it finds.
+type Policy struct {
+	// Allowed lists the accepted MIME types ("image/jpeg"), media types
+	// ("image/*") or format IDs ("jpeg"). Empty accepts every recognised
+	// format.
+	Allowed []string
+	// MaxSize is the largest upload accepted, in bytes; 0 for no limit.
+	MaxSize int64
+	// RejectPrivacy refuses files whose metadata locates them or names
+	// people in them (see core.PrivacyAudit).
+	RejectPrivacy bool
+	// RejectAnomalies refuses files in which the anomaly scan finds signs
+	// of hidden data (see core.AnomalyScanner).
+	RejectAnomalies bool
+}
+
+// Finding checks.
+const (
+	CheckRead     = "read"
+	CheckSize     = "size"
+	CheckType     = "type"
+	CheckFormat   = "format"
+	CheckMetadata = "metadata"
+	CheckPrivacy  = "privacy"
+	CheckAnomaly  = "anomaly"
+)
+
+// Finding is one result of CheckPolicy.
+type Finding struct {
+	Check    string // CheckType, CheckAnomaly, ...
+	Message  string
+	Blocking bool // the finding is why the file is refused
+}
+
+// String returns the finding as "check: message", marked when blocking.
+func (f Finding) String() string {
+	s := f.Check + ": " + f.Message
+	if f.Blocking {
+		s += " (blocking)"
+	}
+	return s
+}
+
+// CheckPolicy reads an upload from r and reports whether p accepts it,
+// with the findings of every check. Unreadable input, an unrecognised or
+// disallowed type, a file over MaxSize and a file its format's parser
+// rejects are always refused; privacy and anomaly findings refuse it when
+// p says so, and the other metadata checks only inform.
+func CheckPolicy(r io.Reader, p Policy) (allowed bool, findings []Finding) {
+	add := func(check string, blocking bool, format string, args ...any) {
+		findings = append(findings, Finding{Check: check, Message: fmt.Sprintf(format, args...), Blocking: blocking})
+	}
+	refused := func() bool {
+		for _, f := range findings {
+			if f.Blocking {
+				return true
+			}
+		}
+		return false
+	}
+
+	if p.MaxSize > 0 {
+		r = io.LimitReader(r, p.MaxSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		add(CheckRead, true, "cannot read upload: %v", err)
+		return false, findings
+	}
+	if p.MaxSize > 0 && int64(len(data)) > p.MaxSize {
+		add(CheckSize, true, "larger than the %s limit", core.FormatSize(p.MaxSize))
+		return false, findings
+	}
+	id := core.DetectFormatFromBytes(data)
+	if id == core.FmtUnknown {
+		add(CheckType, true, "unknown or unsupported format")
+		return false, findings
+	}
+
+	err = viaTemp(data, func(h core.Handler, path string) error {
+		mime, err := core.DetectMIME(path)
+		if err != nil {
+			mime = core.MIMEFor(id)
+		}
+		if !typeAllowed(p.Allowed, id, mime) {
+			add(CheckType, true, "%s (%s) is not an accepted type", h.Info().Name, mime)
+			return nil
+		}
+		if err := core.CheckLen(id, int64(len(data))); err != nil {
+			add(CheckFormat, true, "%v", err)
+			return nil
+		}
+		m, err := h.View(path)
+		if err != nil {
+			add(CheckFormat, true, "not a valid %s: %v", h.Info().Name, err)
+			return nil
+		}
+		for _, issue := range core.Validate(m) {
+			add(CheckMetadata, false, "%s", issue)
+		}
+		for _, issue := range core.PrivacyAudit(m) {
+			add(CheckPrivacy, p.RejectPrivacy, "%s", strings.TrimPrefix(issue, "privacy: "))
+		}
+		if sc, ok := h.(core.AnomalyScanner); ok {
+			found, err := sc.Anomalies(path)
+			if err != nil {
+				add(CheckFormat, true, "not a valid %s: %v", h.Info().Name, err)
+			}
+			for _, a := range found {
+				add(CheckAnomaly, p.RejectAnomalies, "%s", a)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		add(CheckRead, true, "%v", err)
+	}
+	return !refused(), findings
+}
+
+// typeAllowed reports whether a file of format id and MIME type mime
+// matches one of allowed; an empty list allows every type.
+func typeAllowed(allowed []string, id core.FormatID, mime string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case a == mime || a == string(id):
+			return true
+		case strings.HasSuffix(a, "/*") && strings.HasPrefix(mime, a[:len(a)-1]):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ─── Resumable state ─────────────────────────────────────────────────────────
+// A scrub of a million files can be ended by a reboot or kill -9, which no
+// signal handler sees. A state file records the outcome of each file as an
+// NDJSON line written the moment the file is done, so it survives the
+// process. A run that resumes from it skips the files recorded as done
+// ("ok", "skipped" or "unchanged"); files that failed or were not reached
+// are tried again. Files are recorded by absolute path, and a path that
+// appears twice (two manifest entries for one file) is counted, so the
+// second entry is not skipped because the first was done.
+
+// stateVersion is written in the header line of a state file.
+const stateVersion = 1
+
+// stateHeader is the first line of a state file.
+type stateHeader struct {
+	Version int    `json:"surgery_state"`
+	Command string `json:"command"`
+	Started string `json:"started"`
+}
+
+// StateRecord is one line of a state file after the header.
+type StateRecord struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Time   string `json:"time"`
+}
+
+// doneStatus reports whether a file recorded with status needs no retry.
+func doneStatus(status string) bool {
+	return status == "ok" || status == "skipped" || status == "unchanged"
+}
+
+// State is an open state file.
+type State struct {
+	file *os.File
+	done map[string]int // absolute path → times recorded as done
+	seen map[string]int // absolute path → times Completed asked this run
+	err  error          // first failed write
+	cut  bool           // the file does not end with a newline
+}
+
+// OpenState opens the state file at path for command ("batch strip", …).
+// With resume the files it records as done are read back and new records
+// are appended; a state file written by another command is refused.
+// Without resume any earlier state file is replaced.
+func OpenState(path, command string, resume bool) (*State, error) {
+	s := &State{done: map[string]int{}, seen: map[string]int{}}
+	if resume {
+		if err := s.load(path, command); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open state file: %w", err)
+		}
+		s.file = f
+		if s.cut {
+			// end the line a crash cut off, so the next record starts afresh
+			s.writeLine(nil)
+		}
+		return s, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create state file: %w", err)
+	}
+	s.file = f
+	if err := s.writeLine(stateHeader{stateVersion, command, time.Now().Format(time.RFC3339)}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the done files of an existing state file. A last line cut
+// off by a crash is ignored.
+func (s *State) load(path, command string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("state file %s does not exist; run once with --state before --resume", path)
+	}
+	if err != nil {
+		return err
+	}
+	s.cut = len(data) > 0 && data[len(data)-1] != '\n'
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if line == 1 {
+			var h stateHeader
+			if err := json.Unmarshal(text, &h); err != nil || h.Version == 0 {
+				return fmt.Errorf("%s is not a surgery state file", path)
+			}
+			if h.Version > stateVersion {
+				return fmt.Errorf("state file %s has version %d; this build reads version %d", path, h.Version, stateVersion)
+			}
+			if h.Command != command {
+				return fmt.Errorf("state file %s was written by %q, not %q", path, h.Command, command)
+			}
+			continue
+		}
+		var r StateRecord
+		if err := json.Unmarshal(text, &r); err != nil {
+			continue
+		}
+		if doneStatus(r.Status) {
+			s.done[r.Path]++
+		}
+	}
+	return sc.Err()
+}
+
+// Completed reports whether an earlier run finished path. Each call
+// stands for the next occurrence of path in this run.
+func (s *State) Completed(path string) bool {
+	abs := absPath(path)
+	s.seen[abs]++
+	return s.seen[abs] <= s.done[abs]
+}
+
+// Record appends the outcome of path. Statuses other than "ok", "skipped"
+// and "unchanged" are retried by a resumed run.
+func (s *State) Record(path, status, errMsg string) error {
+	return s.writeLine(StateRecord{Path: absPath(path), Status: status, Error: errMsg, Time: time.Now().Format(time.RFC3339)})
+}
+
+// writeLine appends v as one JSON line, or an empty line for nil. The file is unbuffered, so a line
+// is with the operating system as soon as this returns.
+func (s *State) writeLine(v any) error {
+	var line []byte
+	var err error
+	if v != nil {
+		line, err = json.Marshal(v)
+	}
+	if err == nil {
+		_, err = s.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		err = fmt.Errorf("cannot write state file: %w", err)
+		if s.err == nil {
+			s.err = err
+		}
+	}
+	return err
+}
+
+// Err returns the first write to the state file that failed. Files done
+// after it are not recorded and a resumed run does them again.
+func (s *State) Err() error {
+	return s.err
+}
+
+// Close closes the state file.
+func (s *State) Close() error {
+	return s.file.Close()
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,192 @@
+package batch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Transactions ────────────────────────────────────────────────────────────
+// An album retag should leave either every track retagged or none of them.
+// A Tx sends each write to a staging directory instead of its destination.
+// Commit swaps the staged files into place only once all of them exist,
+// and puts the originals back if a swap fails part-way. Rollback discards
+// the staged files, leaving every destination as it was. An audit log
+// records each staged write as it happens, with the staging file as its
+// output.
+
+// Tx is a set of writes that are applied together or not at all.
+type Tx struct {
+	// Backup, when set, has Commit copy each destination that exists
+	// before replacing it; see core.BackupBefore.
+	Backup *core.BackupOptions
+
+	dir    string
+	n      int
+	staged map[string]string // destination → staged file
+	order  []string
+}
+
+// BeginTx creates the staging directory for a transaction.
+func BeginTx() (*Tx, error) {
+	dir, err := os.MkdirTemp("", "surgery-tx-")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create staging directory: %w", err)
+	}
+	return &Tx{dir: dir, staged: map[string]string{}}, nil
+}
+
+// Write stages a change to path saved as out ("" for in place). write
+// reads src — path, or what an earlier Write staged for the same
+// destination — and writes the new content to tmp.
+func (t *Tx) Write(path, out string, write func(src, tmp string) error) error {
+	dest, err := filepath.Abs(core.ResolveOutPath(path, out))
+	if err != nil {
+		return err
+	}
+	src := path
+	prev, ok := t.staged[dest]
+	if ok {
+		src = prev
+	}
+	t.n++
+	tmp := filepath.Join(t.dir, fmt.Sprintf("%d-%s", t.n, filepath.Base(dest)))
+	if err := write(src, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	core.CopyAttrs(path, tmp) // for a new destination
+	if ok {
+		os.Remove(prev)
+	} else {
+		t.order = append(t.order, dest)
+	}
+	t.staged[dest] = tmp
+	return nil
+}
+
+// Len returns the number of files staged.
+func (t *Tx) Len() int { return len(t.order) }
+
+// Rollback discards every staged file.
+func (t *Tx) Rollback() error {
+	return os.RemoveAll(t.dir)
+}
+
+// Commit moves every staged file into place. The staged files are first
+// copied next to their destinations, so that the swap itself is a rename
+// on one file system. If any step fails, the destinations are restored
+// and the error returned.
+func (t *Tx) Commit() error {
+	defer os.RemoveAll(t.dir)
+
+	next := make(map[string]string, len(t.order))
+	cleanup := func() {
+		for _, p := range next {
+			os.Remove(p)
+		}
+	}
+	for _, dest := range t.order {
+		p, err := placeBeside(t.staged[dest], dest, ".surgery-new")
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("%s: %w", dest, err)
+		}
+		next[dest] = p
+	}
+	for _, dest := range t.order {
+		if _, err := core.BackupBefore(dest, "", t.Backup); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	backups := map[string]string{}
+	var done []string
+	undo := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			dest := done[i]
+			if b, ok := backups[dest]; ok {
+				os.Rename(b, dest)
+			} else {
+				os.Remove(dest)
+			}
+		}
+		cleanup()
+	}
+	for _, dest := range t.order {
+		if _, err := os.Stat(dest); err == nil {
+			b := sibling(dest, ".surgery-bak")
+			if err := os.Rename(dest, b); err != nil {
+				undo()
+				return fmt.Errorf("%s: %w", dest, err)
+			}
+			backups[dest] = b
+		}
+		if err := os.Rename(next[dest], dest); err != nil {
+			if b, ok := backups[dest]; ok {
+				os.Rename(b, dest)
+				delete(backups, dest)
+			}
+			undo()
+			return fmt.Errorf("%s: %w", dest, err)
+		}
+		delete(next, dest)
+		done = append(done, dest)
+	}
+	for _, b := range backups {
+		os.Remove(b)
+	}
+	return nil
+}
+
+// placeBeside copies src to a new file next to dest, with the attributes
+// of dest when it exists and of src otherwise.
+func placeBeside(src, dest, suffix string) (string, error) {
+	from := src
+	if _, err := os.Stat(dest); err == nil {
+		from = dest
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	p := sibling(dest, suffix)
+	out, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(p)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+	if err := core.CopyAttrs(from, p); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+	return p, nil
+}
+
+// sibling returns an unused hidden name next to path.
+func sibling(path, suffix string) string {
+	dir, base := filepath.Split(path)
+	for i := 0; ; i++ {
+		name := "." + base + suffix
+		if i > 0 {
+			name = fmt.Sprintf(".%s%s%d", base, suffix, i)
+		}
+		p := filepath.Join(dir, name)
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p
+		}
+	}
+}
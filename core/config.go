@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ─── Config file ─────────────────────────────────────────────────────────────
+// Optional user settings, read from $SURGERY_CONFIG or
+// <user config dir>/surgery/config.json. A missing file is not an error.
+//
+//	{
+//	  "normalize": {
+//	    "music": { "trim": ["all"], "collapse_spaces": ["all"], "titlecase": ["Artist", "Album"] }
+//	  }
+//	}
+
+// Config holds user settings.
+type Config struct {
+	// Normalize maps a profile name (as given to --normalize) to its transforms.
+	Normalize map[string]NormalizeProfile `json:"normalize,omitempty"`
+	// ReadOnly refuses every command that can write (see ReadOnlyEnabled).
+	ReadOnly bool `json:"read_only,omitempty"`
+	// Audit appends a line per write to an audit log (see BeginAudit).
+	Audit *AuditConfig `json:"audit,omitempty"`
+}
+
+// NormalizeProfile lists the fields each transform applies to; "all"
+// means every editable field.
+type NormalizeProfile struct {
+	TitleCase      []string `json:"titlecase,omitempty"`
+	Uppercase      []string `json:"uppercase,omitempty"`
+	Lowercase      []string `json:"lowercase,omitempty"`
+	Trim           []string `json:"trim,omitempty"`
+	CollapseSpaces []string `json:"collapse_spaces,omitempty"`
+}
+
+// Transforms returns the profile as ValueTransforms: whitespace clean-up
+// first, then case changes.
+func (p NormalizeProfile) Transforms() []ValueTransform {
+	var out []ValueTransform
+	for _, group := range []struct {
+		kind string
+		keys []string
+	}{
+		{TransformTrim, p.Trim},
+		{TransformCollapseSpaces, p.CollapseSpaces},
+		{TransformTitleCase, p.TitleCase},
+		{TransformUpper, p.Uppercase},
+		{TransformLower, p.Lowercase},
+	} {
+		for _, key := range group.keys {
+			t, _ := NewTransform(group.kind, key)
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ConfigPath returns the config file location.
+func ConfigPath() string {
+	if p := os.Getenv("SURGERY_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "surgery", "config.json")
+}
+
+// LoadConfig reads the config file, returning an empty Config if there is none.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+	path := ConfigPath()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
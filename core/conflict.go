@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ─── Conflict detection ──────────────────────────────────────────────────────
+// Watch folders and sync clients rewrite files while surgery works on
+// them. An edit that read the old file and writes over the new one loses
+// the other program's change without a word. A guarded write takes the
+// state of the file when it was first read, writes the result to a
+// temporary file, and moves it into place only if the file still has that
+// state; otherwise the write is abandoned with ErrConflict.
+
+// ErrConflict is returned when a file changed between being read and being
+// written.
+var ErrConflict = errors.New("file changed since it was read")
+
+// Unchanged returns an error wrapping ErrConflict when path no longer
+// matches s.
+func (s FileState) Unchanged(path string) error {
+	now, err := Snapshot(path)
+	if err != nil {
+		return err
+	}
+	if s.Equal(now) {
+		return nil
+	}
+	return fmt.Errorf("%s: %w (size %d → %d, modified %s → %s); nothing was written",
+		path, ErrConflict, s.Size, now.Size,
+		s.ModTime.Format(time.RFC3339Nano), now.ModTime.Format(time.RFC3339Nano))
+}
+
+// GuardedWrite saves path to out ("" for in place) through write, which
+// writes the new content to tmp. The result replaces out only if path still
+// matches before, the state taken when it was read, and it has the
+// permissions, owner and extended attributes of path.
+func GuardedWrite(path, out string, before FileState, write func(tmp string) error) error {
+	return replaceVia(ResolveOutPath(path, out), path, func(tmp string) error {
+		if err := write(tmp); err != nil {
+			return err
+		}
+		return before.Unchanged(path)
+	})
+}
@@ -1,10 +1,14 @@
 package core
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -43,6 +47,11 @@ const (
 	FmtPPTX FormatID = "pptx"
 	FmtODT  FormatID = "odt"
 	FmtEPUB FormatID = "epub"
+	FmtCBZ  FormatID = "cbz"
+
+	FmtSRT FormatID = "srt"
+	FmtASS FormatID = "ass"
+	FmtVTT FormatID = "vtt"
 
 	FmtUnknown FormatID = "unknown"
 )
@@ -56,6 +65,7 @@ var extMap = map[string]FormatID{
 	".webp": FmtWebP,
 	".tiff": FmtTIFF,
 	".tif":  FmtTIFF,
+	".dng":  FmtTIFF,
 	".bmp":  FmtBMP,
 	".heic": FmtHEIC,
 	".heif": FmtHEIC,
@@ -94,6 +104,12 @@ var extMap = map[string]FormatID{
 	".ods":  FmtODT,
 	".odp":  FmtODT,
 	".epub": FmtEPUB,
+	".cbz":  FmtCBZ,
+
+	".srt": FmtSRT,
+	".ass": FmtASS,
+	".ssa": FmtASS,
+	".vtt": FmtVTT,
 }
 
 // DetectFormat returns the FormatID for the given file, first by reading
@@ -105,14 +121,37 @@ func DetectFormat(path string) (FormatID, error) {
 	}
 	defer f.Close()
 
-	buf := make([]byte, 16)
+	buf := make([]byte, sniffLen)
 	n, err := io.ReadFull(f, buf)
-	if err != nil && n == 0 {
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return FmtUnknown, err
 	}
 	buf = buf[:n]
+	if n == 0 {
+		// Empty: the extension names the format, and the handler's
+		// CheckSize reports the file as empty.
+		if id, ok := extMap[strings.ToLower(filepath.Ext(path))]; ok {
+			return id, nil
+		}
+		return FmtUnknown, fmt.Errorf("file is empty")
+	}
 
 	if id := detectMagic(buf); id != FmtUnknown {
+		// All ZIP containers share one magic; the extension tells
+		// DOCX, XLSX, PPTX, ODT, EPUB and CBZ apart, and failing that
+		// the entries do.
+		if id == FmtDOCX {
+			if byExt, ok := extMap[strings.ToLower(filepath.Ext(path))]; ok && MediaTypeFor(byExt) == "document" && byExt != FmtPDF {
+				return byExt, nil
+			}
+			if st, err := f.Stat(); err == nil {
+				if zr, err := zip.NewReader(f, st.Size()); err == nil {
+					if byZip, _ := zipKind(zr); byZip != FmtUnknown {
+						return byZip, nil
+					}
+				}
+			}
+		}
 		return id, nil
 	}
 
@@ -127,6 +166,141 @@ func DetectFormat(path string) (FormatID, error) {
 	return FmtUnknown, nil
 }
 
+// DetectFormatFromBytes returns the FormatID of a file held in memory.
+// See DetectFormatFromReader.
+func DetectFormatFromBytes(b []byte) FormatID {
+	id, _ := DetectFormatFromReader(bytes.NewReader(b))
+	return id
+}
+
+// DetectFormatFromReader returns the FormatID of the content of r, such as
+// an upload a server holds in memory. With no file name there is no
+// extension to fall back on, so an SRT is known only by its first cue. A
+// ZIP container is classified by its entries, which needs the size of r:
+// r must have a Size method (bytes.Reader, io.SectionReader) or a Stat
+// method (os.File), or a ZIP is FmtUnknown.
+func DetectFormatFromReader(r io.ReaderAt) (FormatID, error) {
+	buf, err := ReadRange(r, 0, sniffLen)
+	if err != nil {
+		return FmtUnknown, err
+	}
+	id := detectMagic(buf)
+	if id != FmtDOCX {
+		return id, nil
+	}
+	var size int64 = -1
+	switch s := r.(type) {
+	case interface{ Size() int64 }:
+		size = s.Size()
+	case interface{ Stat() (os.FileInfo, error) }:
+		if st, err := s.Stat(); err == nil {
+			size = st.Size()
+		}
+	}
+	if size < 0 {
+		return FmtUnknown, nil
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return FmtUnknown, nil
+	}
+	id, _ = zipKind(zr)
+	return id, nil
+}
+
+// zipKind tells the ZIP containers apart by their entries and returns the
+// format and exact MIME type, or FmtUnknown and "" for a ZIP that is none of
+// them. ODF and EPUB name their type in a "mimetype" entry; OPC is
+// recognised by its main part, and by [Content_Types].xml declaring macros;
+// a comic book has ComicInfo.xml or holds only images.
+func zipKind(zr *zip.Reader) (FormatID, string) {
+	var contentTypes *zip.File
+	images, others := 0, 0
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "mimetype":
+			m := readZipText(f, 128)
+			switch {
+			case m == "application/epub+zip":
+				return FmtEPUB, m
+			case strings.HasPrefix(m, "application/vnd.oasis.opendocument."):
+				return FmtODT, m
+			}
+		case f.Name == "[Content_Types].xml":
+			contentTypes = f
+		case f.Name == "ComicInfo.xml":
+			return FmtCBZ, MIMEFor(FmtCBZ)
+		case strings.HasSuffix(f.Name, "/"):
+		case comicPage(f.Name):
+			images++
+		default:
+			others++
+		}
+	}
+	if contentTypes != nil {
+		macro := strings.Contains(readZipText(contentTypes, 1<<20), "macroEnabled")
+		for _, o := range opcParts {
+			for _, f := range zr.File {
+				if strings.HasPrefix(f.Name, o.part) {
+					if macro {
+						return o.id, o.macro
+					}
+					return o.id, MIMEFor(o.id)
+				}
+			}
+		}
+	}
+	if images > 0 && others == 0 {
+		return FmtCBZ, MIMEFor(FmtCBZ)
+	}
+	return FmtUnknown, ""
+}
+
+// opcParts maps the main part folder of an OPC package to its format and
+// the MIME type of its macro-enabled variant.
+var opcParts = []struct {
+	part  string
+	id    FormatID
+	macro string
+}{
+	{"word/", FmtDOCX, "application/vnd.ms-word.document.macroEnabled.12"},
+	{"xl/", FmtXLSX, "application/vnd.ms-excel.sheet.macroEnabled.12"},
+	{"ppt/", FmtPPTX, "application/vnd.ms-powerpoint.presentation.macroEnabled.12"},
+}
+
+// comicPage reports whether a ZIP entry name is an image a CBZ would hold.
+func comicPage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
+		return true
+	}
+	return false
+}
+
+// readZipText returns up to limit bytes of a ZIP entry, trimmed.
+func readZipText(f *zip.File, limit int64) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(io.LimitReader(rc, limit))
+	return string(bytes.TrimSpace(b))
+}
+
+// ─── Sniffing ────────────────────────────────────────────────────────────────
+// Most formats are known from their first four bytes, but some need more:
+// the compatible brands of an ftyp box tell HEIC from MP4, the EBML DocType
+// tells WebM from Matroska, the first Ogg page tells Opus from Vorbis, and
+// an SVG may start with an XML declaration, comments and a DOCTYPE before
+// its <svg> element. sniffLen bytes cover all of these in real files.
+
+// sniffLen is how much of a file detection reads.
+const sniffLen = 512
+
+// utf8BOM is skipped before text formats.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 func detectMagic(b []byte) FormatID {
 	if len(b) < 4 {
 		return FmtUnknown
@@ -141,9 +315,9 @@ func detectMagic(b []byte) FormatID {
 	// GIF: GIF87a or GIF89a
 	case bytes.HasPrefix(b, []byte("GIF87a")) || bytes.HasPrefix(b, []byte("GIF89a")):
 		return FmtGIF
-	// WebP: RIFF????WEBP
-	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP")):
-		return FmtWebP
+	// RIFF????WEBP, RIFF????WAVE, RIFF????AVI
+	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")):
+		return detectRIFFSubtype(b)
 	// TIFF: 49 49 2A 00 (little-endian) or 4D 4D 00 2A (big-endian)
 	case bytes.HasPrefix(b, []byte{0x49, 0x49, 0x2A, 0x00}) ||
 		bytes.HasPrefix(b, []byte{0x4D, 0x4D, 0x00, 0x2A}):
@@ -159,59 +333,217 @@ func detectMagic(b []byte) FormatID {
 	// FLAC: fLaC
 	case bytes.HasPrefix(b, []byte("fLaC")):
 		return FmtFLAC
-	// OGG: OggS
+	// OGG: OggS, Opus when the first packet is OpusHead
 	case bytes.HasPrefix(b, []byte("OggS")):
-		return FmtOGG
-	// WAV: RIFF????WAVE
-	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WAVE")):
-		return FmtWAV
+		return detectOggSubtype(b)
 	// AIFF: FORM????AIFF or AIFC
 	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("FORM")) &&
 		(bytes.Equal(b[8:12], []byte("AIFF")) || bytes.Equal(b[8:12], []byte("AIFC"))):
 		return FmtAIFF
-	// MP4/MOV: ftyp box at offset 4 — check ftypM4A , ftypmp42, ftypisom, ftypM4V
+	// MP4/MOV/M4A/HEIC: ftyp box at offset 4
 	case len(b) >= 8 && bytes.Equal(b[4:8], []byte("ftyp")):
 		return detectMP4Subtype(b)
 	// MKV/WebM: EBML header 0x1A45DFA3
-	case len(b) >= 4 && binary.BigEndian.Uint32(b[0:4]) == 0x1A45DFA3:
+	case binary.BigEndian.Uint32(b[0:4]) == 0x1A45DFA3:
 		return detectMKVSubtype(b)
-	// AVI: RIFF????AVI
-	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("AVI ")):
-		return FmtAVI
+	// WMV: ASF Header Object GUID
+	case bytes.HasPrefix(b, asfMagic):
+		return FmtWMV
 	// FLV: FLV
 	case bytes.HasPrefix(b, []byte("FLV")):
 		return FmtFLV
 	// PDF: %PDF
 	case bytes.HasPrefix(b, []byte("%PDF")):
 		return FmtPDF
-	// ZIP-based (DOCX/XLSX/PPTX/ODT/EPUB): PK\x03\x04
+	// WebVTT: WEBVTT, ASS/SSA: [Script Info] (either may follow a UTF-8 BOM)
+	case bytes.HasPrefix(bytes.TrimPrefix(b, utf8BOM), []byte("WEBVTT")):
+		return FmtVTT
+	case bytes.HasPrefix(bytes.TrimPrefix(b, utf8BOM), []byte("[Script Info")):
+		return FmtASS
+	// ZIP-based (DOCX/XLSX/PPTX/ODT/EPUB/CBZ): PK\x03\x04
 	case bytes.HasPrefix(b, []byte("PK\x03\x04")):
 		return FmtDOCX // resolved more precisely by extension later
+	case isSVG(b):
+		return FmtSVG
+	case reSRTStart.Match(bytes.TrimPrefix(b, utf8BOM)):
+		return FmtSRT
 	}
 	return FmtUnknown
 }
 
+// asfMagic is the GUID of the ASF Header Object that starts a WMV.
+var asfMagic = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+
+// reSRTStart matches the first cue of a SubRip file: a number and a
+// timing line.
+var reSRTStart = regexp.MustCompile(`^\s*\d+\r?\n\d{1,2}:\d{2}:\d{2}[,.]\d{1,3}\s*-->`)
+
+func detectRIFFSubtype(b []byte) FormatID {
+	switch string(b[8:12]) {
+	case "WEBP":
+		return FmtWebP
+	case "WAVE":
+		return FmtWAV
+	case "AVI ":
+		return FmtAVI
+	}
+	return FmtUnknown
+}
+
+// detectOggSubtype reads the first packet of the first Ogg page, which
+// starts after the 27-byte header and its segment table.
+func detectOggSubtype(b []byte) FormatID {
+	if len(b) > 27 {
+		if start := 27 + int(b[26]); start < len(b) && bytes.HasPrefix(b[start:], []byte("OpusHead")) {
+			return FmtOpus
+		}
+	}
+	return FmtOGG
+}
+
+// detectMP4Subtype reads the major brand and then the compatible brands of
+// the ftyp box; the first one that names a format decides.
 func detectMP4Subtype(b []byte) FormatID {
 	if len(b) < 12 {
 		return FmtMP4
 	}
-	brand := string(b[8:12])
-	switch brand {
-	case "M4A ", "M4B ":
-		return FmtM4A
-	case "qt  ":
-		return FmtMOV
-	default:
-		return FmtMP4
+	end := int(binary.BigEndian.Uint32(b[0:4]))
+	if end > len(b) {
+		end = len(b)
+	}
+	brands := []string{string(b[8:12])}
+	for i := 16; i+4 <= end; i += 4 {
+		brands = append(brands, string(b[i:i+4]))
 	}
+	for _, brand := range brands {
+		switch brand {
+		case "M4A ", "M4B ":
+			return FmtM4A
+		case "qt  ":
+			return FmtMOV
+		case "heic", "heix", "heim", "heis", "mif1", "msf1":
+			return FmtHEIC
+		}
+	}
+	return FmtMP4
 }
 
+// detectMKVSubtype reads the DocType element (ID 0x4282) of the EBML
+// header.
 func detectMKVSubtype(b []byte) FormatID {
-	// We'd need to dig into the EBML DocType to tell MKV vs WebM.
-	// For now default to MKV; video/webm extension will override.
+	if i := bytes.Index(b, []byte{0x42, 0x82}); i >= 0 && i+2 < len(b) {
+		// The size is a one-byte EBML vint for any real DocType.
+		if n := int(b[i+2] & 0x7F); b[i+2]&0x80 != 0 && i+3+n <= len(b) && string(b[i+3:i+3+n]) == "webm" {
+			return FmtWebM
+		}
+	}
 	return FmtMKV
 }
 
+// isSVG reports whether b starts an SVG document: an <svg> element, after
+// an optional BOM, XML declaration, comments, processing instructions and
+// DOCTYPE.
+func isSVG(b []byte) bool {
+	b = bytes.TrimPrefix(b, utf8BOM)
+	for {
+		b = bytes.TrimLeft(b, " \t\r\n")
+		var end []byte
+		switch {
+		case bytes.HasPrefix(b, []byte("<svg")):
+			return len(b) == 4 || strings.IndexByte(" \t\r\n>/", b[4]) >= 0
+		case bytes.HasPrefix(b, []byte("<?")):
+			end = []byte("?>")
+		case bytes.HasPrefix(b, []byte("<!--")):
+			end = []byte("-->")
+		case bytes.HasPrefix(b, []byte("<!DOCTYPE")):
+			end = []byte(">")
+			// An internal subset declares entities with > of their own.
+			if lb := bytes.IndexByte(b, '['); lb >= 0 && lb < bytes.IndexByte(b, '>') {
+				end = []byte("]>")
+			}
+		default:
+			return false
+		}
+		i := bytes.Index(b, end)
+		if i < 0 {
+			return false
+		}
+		b = b[i+len(end):]
+	}
+}
+
+// ─── Minimum sizes ───────────────────────────────────────────────────────────
+// A file shorter than the fixed header of its format cannot be one, and
+// handlers that index fixed offsets should not get that far with it.
+// CheckSize turns an empty or truncated file into one clear error before
+// any parsing starts.
+
+// minSizes is the smallest size of each format: its signature and the
+// header every file of it must have.
+var minSizes = map[FormatID]int64{
+	FmtJPEG: 4,  // SOI + EOI
+	FmtPNG:  33, // signature + IHDR
+	FmtGIF:  13, // header + logical screen descriptor
+	FmtWebP: 20, // RIFF header + one chunk header
+	FmtTIFF: 8,  // byte order, magic, IFD offset
+	FmtBMP:  26, // file header + BITMAPCOREHEADER
+	FmtHEIC: 16, // ftyp box
+	FmtSVG:  6,  // <svg/>
+
+	FmtMP3:  4,  // one frame header
+	FmtFLAC: 42, // fLaC + STREAMINFO
+	FmtOGG:  27, // page header
+	FmtOpus: 27,
+	FmtM4A:  8,  // one box header
+	FmtWAV:  12, // RIFF header
+	FmtAIFF: 12, // FORM header
+
+	FmtMP4:  8,
+	FmtMOV:  8,
+	FmtMKV:  5, // EBML ID + size
+	FmtWebM: 5,
+	FmtAVI:  12,
+	FmtWMV:  30, // ASF Header Object
+	FmtFLV:  9,
+
+	FmtPDF:  8,  // %PDF-1.x
+	FmtDOCX: 22, // ZIP end of central directory
+	FmtXLSX: 22,
+	FmtPPTX: 22,
+	FmtODT:  22,
+	FmtEPUB: 22,
+	FmtCBZ:  22,
+
+	FmtSRT: 1,
+	FmtASS: 1,
+	FmtVTT: 6, // WEBVTT
+}
+
+// CheckSize returns an error when the file at path is empty or too small
+// to be a file of format id.
+func CheckSize(id FormatID, path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if st.Size() == 0 && !st.Mode().IsRegular() {
+		return nil // a pipe or device reports no size
+	}
+	return CheckLen(id, st.Size())
+}
+
+// CheckLen is CheckSize for n bytes held in memory.
+func CheckLen(id FormatID, n int64) error {
+	name := strings.ToUpper(string(id))
+	switch {
+	case n == 0:
+		return fmt.Errorf("file is empty, not a %s file", name)
+	case n < minSizes[id]:
+		return fmt.Errorf("file too small to be %s: %d bytes, need at least %d", name, n, minSizes[id])
+	}
+	return nil
+}
+
 // MediaTypeFor returns the broad media category for a format.
 func MediaTypeFor(id FormatID) string {
 	switch id {
@@ -221,8 +553,10 @@ func MediaTypeFor(id FormatID) string {
 		return "audio"
 	case FmtMP4, FmtMOV, FmtMKV, FmtWebM, FmtAVI, FmtWMV, FmtFLV:
 		return "video"
-	case FmtPDF, FmtDOCX, FmtXLSX, FmtPPTX, FmtODT, FmtEPUB:
+	case FmtPDF, FmtDOCX, FmtXLSX, FmtPPTX, FmtODT, FmtEPUB, FmtCBZ:
 		return "document"
+	case FmtSRT, FmtASS, FmtVTT:
+		return "subtitle"
 	default:
 		return "unknown"
 	}
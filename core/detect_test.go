@@ -0,0 +1,159 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// ftyp returns an ftyp box of size bytes with major brand major, minor
+// version 0 and the compatible brands compat, padded or cut to size.
+func ftyp(size int, major string, compat ...string) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b[0:4], uint32(size))
+	copy(b[4:8], "ftyp")
+	copy(b[8:12], major)
+	for _, c := range compat {
+		b = append(b, c...)
+	}
+	for len(b) < size {
+		b = append(b, 0)
+	}
+	return b[:size]
+}
+
+// ebml returns an EBML header with the given DocType.
+func ebml(docType string) []byte {
+	body := append([]byte{0x42, 0x82, 0x80 | byte(len(docType))}, docType...)
+	return append([]byte{0x1A, 0x45, 0xDF, 0xA3, 0x80 | byte(len(body))}, body...)
+}
+
+// oggPage returns the first page of an Ogg stream holding packet.
+func oggPage(packet string) []byte {
+	b := append([]byte("OggS"), 0, 2)
+	b = append(b, make([]byte, 20)...) // granule, serial, sequence, CRC
+	b = append(b, 1, byte(len(packet)))
+	return append(b, packet...)
+}
+
+func zipOf(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(files); i += 2 {
+		w, err := zw.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(files[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormatFromBytes(t *testing.T) {
+	bom := string(utf8BOM)
+	tests := []struct {
+		name string
+		data []byte
+		want FormatID
+	}{
+		{"too short", []byte("ID"), FmtUnknown},
+		{"empty", nil, FmtUnknown},
+
+		{"ftyp isom", ftyp(24, "isom", "iso2", "mp41"), FmtMP4},
+		{"ftyp M4A major", ftyp(24, "M4A ", "isom"), FmtM4A},
+		{"ftyp qt", ftyp(20, "qt  ", "qt  "), FmtMOV},
+		{"ftyp heic major", ftyp(24, "heic", "mif1"), FmtHEIC},
+		{"ftyp heic in compatible brands", ftyp(32, "isom", "iso2", "mp41", "avc1", "heic"), FmtHEIC},
+		{"ftyp M4A past 12 bytes", ftyp(28, "isom", "iso2", "mp41", "M4A "), FmtM4A},
+		{"ftyp brand after box end", append(ftyp(20, "isom", "iso2"), "heic"...), FmtMP4},
+		{"ftyp header only", ftyp(12, "mp42"), FmtMP4},
+
+		{"EBML webm", ebml("webm"), FmtWebM},
+		{"EBML matroska", ebml("matroska"), FmtMKV},
+		{"EBML no DocType", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x80}, FmtMKV},
+
+		{"Ogg Opus", oggPage("OpusHead\x01\x02"), FmtOpus},
+		{"Ogg Vorbis", oggPage("\x01vorbis\x00\x00\x00\x00"), FmtOGG},
+		{"Ogg page header only", oggPage("")[:27], FmtOGG},
+
+		{"WMV", append(append([]byte{}, asfMagic...), make([]byte, 14)...), FmtWMV},
+		{"ASF GUID cut short", asfMagic[:8], FmtUnknown},
+
+		{"SVG bare", []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), FmtSVG},
+		{"SVG self-closing", []byte(`<svg/>`), FmtSVG},
+		{"SVG XML prolog", []byte("<?xml version=\"1.0\"?>\n<svg width=\"1\"></svg>"), FmtSVG},
+		{"SVG BOM and prolog", []byte(bom + "<?xml version=\"1.0\"?>\r\n<svg>"), FmtSVG},
+		{"SVG BOM without prolog", []byte(bom + "<svg>"), FmtSVG},
+		{"SVG comment and DOCTYPE", []byte("<?xml version=\"1.0\"?>\n<!-- made by hand -->\n" +
+			"<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd\">\n<svg>"), FmtSVG},
+		{"SVG DOCTYPE internal subset", []byte("<!DOCTYPE svg [ <!ENTITY a \"<b>\"> ]>\n<svg>"), FmtSVG},
+		{"XML that is not SVG", []byte("<?xml version=\"1.0\"?>\n<html></html>"), FmtUnknown},
+		{"element named svgx", []byte("<svgx/>"), FmtUnknown},
+		{"unterminated prolog", []byte("<?xml version=\"1.0\""), FmtUnknown},
+
+		{"SRT", []byte("1\n00:00:01,000 --> 00:00:02,500\nHello\n"), FmtSRT},
+		{"SRT CRLF and BOM", []byte(bom + "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n"), FmtSRT},
+		{"SRT leading blank line", []byte("\n12\n0:00:01.5 --> 0:00:02.5\nHi\n"), FmtSRT},
+		{"number without timing", []byte("1\nHello\n"), FmtUnknown},
+
+		{"WebVTT", []byte("WEBVTT\n\n00:01.000 --> 00:02.000\nHi\n"), FmtVTT},
+		{"ASS", []byte(bom + "[Script Info]\nTitle: x\n"), FmtASS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormatFromBytes(tt.data); got != tt.want {
+				t.Errorf("DetectFormatFromBytes = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormatFromReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want FormatID
+	}{
+		{"EPUB", zipOf(t, "mimetype", "application/epub+zip", "OEBPS/content.opf", "<package/>"), FmtEPUB},
+		{"ODT", zipOf(t, "mimetype", "application/vnd.oasis.opendocument.text", "content.xml", "<x/>"), FmtODT},
+		{"DOCX", zipOf(t, "[Content_Types].xml", "<Types/>", "word/document.xml", "<w/>"), FmtDOCX},
+		{"XLSX", zipOf(t, "[Content_Types].xml", "<Types/>", "xl/workbook.xml", "<w/>"), FmtXLSX},
+		{"CBZ pages", zipOf(t, "001.jpg", "x", "002.png", "y"), FmtCBZ},
+		{"plain ZIP", zipOf(t, "readme.txt", "hi"), FmtUnknown},
+		{"WebM", ebml("webm"), FmtWebM},
+		{"HEIC", ftyp(24, "mif1", "heic"), FmtHEIC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormatFromReader(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormatFromReader = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// A ZIP read through a reader with no size cannot be opened, so it is
+// reported as unknown rather than guessed.
+func TestDetectFormatFromReaderUnsized(t *testing.T) {
+	data := zipOf(t, "mimetype", "application/epub+zip")
+	got, err := DetectFormatFromReader(unsized{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != FmtUnknown {
+		t.Errorf("DetectFormatFromReader = %s, want %s", got, FmtUnknown)
+	}
+}
+
+type unsized struct{ r *bytes.Reader }
+
+func (u unsized) ReadAt(p []byte, off int64) (int, error) { return u.r.ReadAt(p, off) }
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ─── Disk space ──────────────────────────────────────────────────────────────
+// A rewrite that runs out of room half-way leaves a truncated file, or at
+// best a failed write to recover from. Most writers build the new file
+// beside the old one and rename it into place, so for a while both exist:
+// a 4 GB video needs 4 GB free on its volume, even when it shrinks. Before
+// a write, CheckSpace compares an estimate of the output with the space
+// free on the volume it goes to, backup copy included, and fails before a
+// byte is written. Where the free space cannot be read the check is
+// skipped.
+
+// ErrNoSpace is wrapped by the error CheckSpace returns when a volume is
+// too full for the write.
+var ErrNoSpace = errors.New("not enough disk space")
+
+// spaceHeadroom is added to every estimate: the padding writers leave for
+// later edits (ID3v2, FLAC, free boxes) and the file system's own
+// bookkeeping.
+const spaceHeadroom = 64 << 10
+
+// EditEstimate returns about how many bytes an edit of path with opts
+// writes: the file as it is, plus the values it sets, plus headroom.
+func EditEstimate(path string, opts EditOptions) int64 {
+	n := fileSize(path) + spaceHeadroom
+	for _, m := range []map[string]string{opts.Set, opts.SetIfMissing, opts.Append, opts.Prefix, opts.Suffix} {
+		for k, v := range m {
+			n += int64(len(k) + len(v))
+		}
+	}
+	for _, kv := range opts.Add {
+		n += int64(len(kv.Key) + len(kv.Value))
+	}
+	return n
+}
+
+// StripEstimate returns about how many bytes a strip of path writes. A
+// strip only removes, so it is the file as it is plus headroom.
+func StripEstimate(path string) int64 {
+	return fileSize(path) + spaceHeadroom
+}
+
+// CheckSpace returns an error wrapping ErrNoSpace when writing need bytes
+// for path to out ("" for in place), and the copy backup asks for, would
+// not fit on the volumes they go to.
+func CheckSpace(path, out string, need int64, backup *BackupOptions) error {
+	dest := ResolveOutPath(path, out)
+	type volume struct {
+		dir  string
+		need int64
+	}
+	vols := []volume{{filepath.Dir(dest), need}}
+	if backup != nil {
+		switch n := fileSize(dest); {
+		case backup.Dir == "":
+			vols[0].need += n
+		case n > 0:
+			vols = append(vols, volume{backup.Dir, n})
+		}
+	}
+	for _, v := range vols {
+		free, ok := freeSpace(existingDir(v.dir))
+		if ok && free < uint64(v.need) {
+			if abs, err := filepath.Abs(v.dir); err == nil {
+				v.dir = abs
+			}
+			return fmt.Errorf("%w on the volume of %s: the write needs about %s, %s is free",
+				ErrNoSpace, v.dir, FormatSize(v.need), FormatSize(int64(free)))
+		}
+	}
+	return nil
+}
+
+// fileSize returns the size of path, 0 when it cannot be read.
+func fileSize(path string) int64 {
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return st.Size()
+}
+
+// existingDir returns dir, or its nearest parent that exists: a backup
+// directory is created by the write.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package core
+
+import "syscall"
+
+// freeSpace returns the bytes on the volume of dir that an unprivileged
+// user may write, and false when it cannot be read.
+func freeSpace(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
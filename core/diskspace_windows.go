@@ -0,0 +1,23 @@
+package core
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the bytes on the volume of dir available to the
+// current user, quotas included, and false when it cannot be read.
+func freeSpace(dir string) (uint64, bool) {
+	p, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, false
+	}
+	var avail uint64
+	r, _, _ := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&avail)), 0, 0)
+	if r == 0 {
+		return 0, false
+	}
+	return avail, true
+}
@@ -0,0 +1,357 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── CBZ / ComicInfo.xml ─────────────────────────────────────────────────────
+// A .cbz is a plain ZIP of page images. Comic library managers (ComicRack,
+// Komga, Kavita, Mylar) read series information from a ComicInfo.xml at the
+// archive root, using the Anansi Project schema. That schema is an
+// xs:sequence, so new elements are inserted in schema order. Some older
+// taggers store ComicBookInfo JSON in the ZIP comment instead.
+
+const comicInfoName = "ComicInfo.xml"
+
+// comicInfoFields lists the simple ComicInfo elements in schema order.
+var comicInfoFields = []string{
+	"Title", "Series", "Number", "Count", "Volume",
+	"AlternateSeries", "AlternateNumber", "AlternateCount",
+	"Summary", "Notes", "Year", "Month", "Day",
+	"Writer", "Penciller", "Inker", "Colorist", "Letterer", "CoverArtist",
+	"Editor", "Translator", "Publisher", "Imprint", "Genre", "Tags", "Web",
+	"PageCount", "LanguageISO", "Format", "BlackAndWhite", "Manga",
+	"Characters", "Teams", "Locations", "ScanInformation",
+	"StoryArc", "StoryArcNumber", "SeriesGroup", "AgeRating",
+	"Pages", "CommunityRating", "MainCharacterOrTeam", "Review", "GTIN",
+}
+
+const comicInfoBlank = `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+</ComicInfo>
+`
+
+// comicInfoField returns the schema spelling of key, matched case-insensitively.
+func comicInfoField(key string) (string, bool) {
+	for _, f := range comicInfoFields {
+		if strings.EqualFold(f, key) && f != "Pages" {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+func comicInfoIndex(name string) int {
+	for i, f := range comicInfoFields {
+		if f == name {
+			return i
+		}
+	}
+	return len(comicInfoFields)
+}
+
+var comicImageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".bmp": true, ".avif": true, ".jxl": true,
+}
+
+// ─── CBZ View ────────────────────────────────────────────────────────────────
+
+func viewCBZ(path string, m *core.Metadata) (*core.Metadata, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return m, fmt.Errorf("cannot open as ZIP: %w", err)
+	}
+	defer r.Close()
+
+	images := 0
+	for _, f := range r.File {
+		if comicImageExts[strings.ToLower(filepath.Ext(f.Name))] {
+			images++
+		}
+		if f.Name != comicInfoName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return m, err
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parseComicInfo(data, m)
+	}
+
+	m.Fields = append(m.Fields, core.MetaField{Key: "ImageCount", Value: fmt.Sprintf("%d", images), Category: "Archive", Editable: false})
+	if r.Comment != "" {
+		key := "ArchiveComment"
+		if strings.Contains(r.Comment, "ComicBookInfo/1.0") {
+			key = "ComicBookInfo"
+		}
+		m.Fields = append(m.Fields, core.MetaField{Key: key, Value: r.Comment, Category: "Archive", Editable: false})
+	}
+	return m, nil
+}
+
+// parseComicInfo reports the top-level ComicInfo elements. Pages is
+// summarised as a page count and the front-cover index.
+func parseComicInfo(data []byte, m *core.Metadata) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth != 2 {
+				continue
+			}
+			if t.Name.Local == "Pages" {
+				var pages struct {
+					Page []struct {
+						Image string `xml:"Image,attr"`
+						Type  string `xml:"Type,attr"`
+					} `xml:"Page"`
+				}
+				dec.DecodeElement(&pages, &t)
+				depth--
+				m.Fields = append(m.Fields, core.MetaField{Key: "Pages", Value: fmt.Sprintf("%d entries", len(pages.Page)), Category: "ComicInfo", Editable: false})
+				for _, p := range pages.Page {
+					if p.Type == "FrontCover" {
+						m.Fields = append(m.Fields, core.MetaField{Key: "FrontCover", Value: "image " + p.Image, Category: "ComicInfo", Editable: false})
+						break
+					}
+				}
+				continue
+			}
+			var v string
+			if err := dec.DecodeElement(&v, &t); err != nil {
+				return
+			}
+			depth--
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			_, known := comicInfoField(t.Name.Local)
+			m.Fields = append(m.Fields, core.MetaField{Key: t.Name.Local, Value: v, Category: "ComicInfo", Editable: known})
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// ─── CBZ Edit ────────────────────────────────────────────────────────────────
+
+func editCBZ(path, outPath string, opts core.EditOptions) error {
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		name, ok := comicInfoField(k)
+		if !ok {
+			return fmt.Errorf("unknown ComicInfo field %q", k)
+		}
+		set[name] = v
+	}
+	var del []string
+	for _, k := range opts.Delete {
+		name, ok := comicInfoField(k)
+		if !ok {
+			return fmt.Errorf("unknown ComicInfo field %q", k)
+		}
+		del = append(del, name)
+	}
+
+	if opts.DryRun {
+		fmt.Println("Dry-run: ComicInfo.xml changes that would be applied:")
+		for k, v := range set {
+			fmt.Printf("  SET    %s = %s\n", k, v)
+		}
+		for _, k := range del {
+			fmt.Printf("  DELETE %s\n", k)
+		}
+		return nil
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("cannot open as ZIP: %w", err)
+	}
+	defer r.Close()
+
+	patch := func(data []byte) []byte { return patchComicInfo(data, set, del) }
+	found := false
+	for _, f := range r.File {
+		found = found || f.Name == comicInfoName
+	}
+	var extra []byte
+	if !found && len(set) > 0 {
+		extra = patch([]byte(comicInfoBlank))
+	}
+	return rewriteCBZ(r, outPath, r.Comment, func(data []byte) ([]byte, bool) {
+		return patch(data), true
+	}, extra)
+}
+
+var reComicInfoClose = regexp.MustCompile(`</ComicInfo\s*>`)
+
+// patchComicInfo sets and deletes simple elements, inserting new ones before
+// the first existing element that follows them in schema order.
+func patchComicInfo(data []byte, set map[string]string, del []string) []byte {
+	for _, k := range del {
+		re := regexp.MustCompile(`\s*<` + k + `\b[^>]*?(?:/>|>[^<]*</` + k + `\s*>)`)
+		data = re.ReplaceAll(data, nil)
+	}
+	for _, k := range sortedComicKeys(set) {
+		el := fmt.Sprintf("<%s>%s</%s>", k, xmlEscape(set[k]), k)
+		re := regexp.MustCompile(`<` + k + `\b[^>]*?(?:/>|>[^<]*</` + k + `\s*>)`)
+		if loc := re.FindIndex(data); loc != nil {
+			data = append(append(append([]byte{}, data[:loc[0]]...), el...), data[loc[1]:]...)
+			continue
+		}
+		at := -1
+		for _, next := range comicInfoFields[comicInfoIndex(k)+1:] {
+			if loc := regexp.MustCompile(`<` + next + `\b`).FindIndex(data); loc != nil {
+				at = loc[0]
+				break
+			}
+		}
+		if at >= 0 {
+			// Reuse the indentation of the element we insert before.
+			lineStart := bytes.LastIndexByte(data[:at], '\n') + 1
+			indent := string(data[lineStart:at])
+			if strings.TrimSpace(indent) != "" {
+				indent = ""
+			}
+			data = append(append(append([]byte{}, data[:at]...), el+"\n"+indent...), data[at:]...)
+			continue
+		}
+		if loc := reComicInfoClose.FindIndex(data); loc != nil {
+			data = append(append(append([]byte{}, data[:loc[0]]...), "  "+el+"\n"...), data[loc[0]:]...)
+		}
+	}
+	return data
+}
+
+// sortedComicKeys returns the keys of set in schema order so repeated
+// inserts land in a valid sequence.
+func sortedComicKeys(set map[string]string) []string {
+	var keys []string
+	for _, f := range comicInfoFields {
+		if _, ok := set[f]; ok {
+			keys = append(keys, f)
+		}
+	}
+	return keys
+}
+
+// rewriteCBZ copies the archive to outPath, passing ComicInfo.xml through
+// fn (return false to drop it) and appending it when extra is set. Pages
+// are copied still compressed, never inflated.
+func rewriteCBZ(r *zip.ReadCloser, outPath, comment string, fn func(data []byte) ([]byte, bool), extra []byte) error {
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	w := zip.NewWriter(outFile)
+	for _, f := range r.File {
+		if f.Name != comicInfoName {
+			if err := copyZipMember(w, f); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		content, keep := fn(content)
+		if !keep {
+			continue
+		}
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	if extra != nil {
+		fw, err := w.Create(comicInfoName)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(extra); err != nil {
+			return err
+		}
+	}
+	if err := w.SetComment(comment); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return outFile.Close()
+}
+
+// ─── CBZ Strip ───────────────────────────────────────────────────────────────
+
+func stripCBZ(path, outPath string, opts core.StripOptions) error {
+	keep := map[string]bool{}
+	for _, k := range opts.KeepFields {
+		if name, ok := comicInfoField(k); ok {
+			keep[name] = true
+		} else if strings.EqualFold(k, "Pages") {
+			keep["Pages"] = true
+		}
+	}
+	keepComment := false
+	for _, k := range opts.KeepFields {
+		keepComment = keepComment || strings.EqualFold(k, "ArchiveComment") || strings.EqualFold(k, "ComicBookInfo")
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("cannot open as ZIP: %w", err)
+	}
+	defer r.Close()
+
+	comment := ""
+	if keepComment {
+		comment = r.Comment
+	}
+	return rewriteCBZ(r, outPath, comment, func(data []byte) ([]byte, bool) {
+		if len(keep) == 0 {
+			return nil, false
+		}
+		var del []string
+		for _, f := range comicInfoFields {
+			if !keep[f] {
+				del = append(del, f)
+			}
+		}
+		data = patchComicInfo(data, nil, del)
+		if !keep["Pages"] {
+			data = regexp.MustCompile(`(?s)\s*<Pages\b(?:[^>]*/>|.*?</Pages\s*>)`).ReplaceAll(data, nil)
+		}
+		return data, true
+	}, nil)
+}
@@ -1,5 +1,5 @@
 // Package document handles metadata for all document formats:
-// PDF, DOCX, XLSX, PPTX, ODT, EPUB
+// PDF, DOCX, XLSX, PPTX, ODT, EPUB, CBZ
 package document
 
 import (
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/ankit-chaubey/media-metadata-surgery/core"
@@ -37,11 +38,12 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "Info dict and XMP stream metadata.",
+		Notes:       "Info dict and XMP stream metadata, including PDF 1.5+ object and xref streams (a compressed Info dict is edited with pdf.incremental=true).",
 		EditableFields: []string{
 			"Title", "Author", "Subject", "Keywords",
-			"Creator", "Producer",
+			"Creator", "Producer", "CreationDate", "ModDate",
 		},
+		Options: []core.FormatOption{optPDFIncremental},
 	},
 	core.FmtDOCX: {
 		Name:        "DOCX",
@@ -54,7 +56,7 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		Notes:       "OPC ZIP container. Reads docProps/core.xml and docProps/app.xml.",
 		EditableFields: []string{
 			"Title", "Subject", "Author", "Keywords",
-			"Description", "LastModifiedBy", "Category",
+			"Description", "LastModifiedBy", "Category", "Created", "Modified",
 		},
 	},
 	core.FmtXLSX: {
@@ -65,10 +67,10 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "OPC ZIP container. Reads docProps/core.xml and docProps/app.xml.",
+		Notes:       "OPC ZIP container. Reads docProps/core.xml, docProps/app.xml and the workbook sheet list, defined names and external links.",
 		EditableFields: []string{
 			"Title", "Subject", "Author", "Keywords",
-			"Description", "LastModifiedBy", "Category",
+			"Description", "LastModifiedBy", "Category", "Created", "Modified",
 		},
 	},
 	core.FmtPPTX: {
@@ -79,10 +81,10 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "OPC ZIP container. Reads docProps/core.xml and docProps/app.xml.",
+		Notes:       "OPC ZIP container. Reads docProps/core.xml, docProps/app.xml, slide titles, notes and comment authors. Strip --remove notes/comments.",
 		EditableFields: []string{
 			"Title", "Subject", "Author", "Keywords",
-			"Description", "LastModifiedBy", "Category",
+			"Description", "LastModifiedBy", "Category", "Created", "Modified",
 		},
 	},
 	core.FmtODT: {
@@ -101,9 +103,27 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "document",
 		MIMETypes:   []string{"application/epub+zip"},
 		CanView:     true,
-		CanEdit:     false,
+		CanEdit:     true,
 		CanStrip:    false,
-		Notes:       "OPS ZIP container. View only in v0.1.2.",
+		Notes:       "OPS ZIP container. Reports version, unique identifier and accessibility metadata; edit updates dcterms:modified.",
+		EditableFields: []string{"Modified"},
+		SetOnlyFields:  []string{"Modified"},
+	},
+	core.FmtCBZ: {
+		Name:        "CBZ",
+		Extensions:  []string{".cbz"},
+		MediaType:   "document",
+		MIMETypes:   []string{"application/vnd.comicbook+zip"},
+		CanView:     true,
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "Comic book ZIP. Reads and writes ComicInfo.xml; strip also clears ComicBookInfo in the ZIP comment. CBR (RAR) is not supported.",
+		EditableFields: []string{
+			"Title", "Series", "Number", "Count", "Volume", "Summary",
+			"Year", "Month", "Day", "Writer", "Penciller", "Inker",
+			"Colorist", "Letterer", "CoverArtist", "Editor", "Publisher",
+			"Genre", "LanguageISO", "Manga", "StoryArc", "AgeRating",
+		},
 	},
 }
 
@@ -113,6 +133,9 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 
 func (h *Handler) View(path string) (*core.Metadata, error) {
 	m := &core.Metadata{FilePath: path}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return m, err
+	}
 	ext := strings.ToLower(filepath.Ext(path))
 
 	switch h.format {
@@ -128,6 +151,9 @@ func (h *Handler) View(path string) (*core.Metadata, error) {
 	case core.FmtEPUB:
 		m.Format = "EPUB"
 		return viewEPUB(path, m)
+	case core.FmtCBZ:
+		m.Format = "CBZ"
+		return viewCBZ(path, m)
 	default:
 		m.Format = strings.ToUpper(strings.TrimPrefix(ext, "."))
 		return m, fmt.Errorf("unsupported document format: %s", ext)
@@ -143,22 +169,39 @@ var pdfInfoFields = []string{
 }
 
 func viewPDF(path string, m *core.Metadata) (*core.Metadata, error) {
-	data, err := os.ReadFile(path)
+	data, doc, err := readPDF(path)
 	if err != nil {
 		return m, err
 	}
 
-	// 1. Info dict — scan for "/Info" dictionary
+	// 1. Info dict — scan for "/Info" dictionary. The dictionary the trailer
+	// (or xref stream) actually points at wins, and is the only way to
+	// reach it when it sits in a compressed object stream.
 	infoFields := parsePDFInfoDict(data)
+	var infoSrc *core.FieldSource
+	if info := doc.infoDict(); info != nil {
+		// Earlier revisions of an incrementally updated file still hold
+		// the entries that were deleted since.
+		infoFields = parsePDFInfoDict(info)
+		infoSrc = doc.infoSource()
+	}
+	compressed := doc.infoCompressed()
 	for _, k := range pdfInfoFields {
 		if v, ok := infoFields[k]; ok {
-			editable := k != "CreationDate" && k != "ModDate" && k != "Trapped"
-			m.Fields = append(m.Fields, core.MetaField{
+			editable := k != "Trapped" && !compressed
+			f := core.MetaField{
 				Key:      k,
 				Value:    v,
 				Category: "PDF Info",
 				Editable: editable,
-			})
+			}
+			if pdfDateFields[k] {
+				f.Value = formatPDFDateISO(v)
+				if f.Value != v {
+					f.Raw = v
+				}
+			}
+			m.Fields = append(m.Fields, f)
 		}
 	}
 	// Any extra fields
@@ -180,10 +223,37 @@ func viewPDF(path string, m *core.Metadata) (*core.Metadata, error) {
 		}
 	}
 
+	if infoSrc != nil {
+		m.SetSource(0, *infoSrc)
+	}
+
 	// 2. XMP metadata stream
 	xmpData := extractPDFXMP(data)
+	streams := doc.metadataStreams()
+	nums := make([]int, 0, len(streams))
+	for num := range streams {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	if len(xmpData) == 0 {
+		// Flate-compressed /Metadata stream
+		for _, num := range nums {
+			if xmpData = extractPDFXMP(streams[num]); len(xmpData) > 0 {
+				break
+			}
+		}
+	}
 	if len(xmpData) > 0 {
+		from := len(m.Fields)
 		parseXMPIntoPDF(xmpData, m)
+		for _, num := range nums {
+			if bytes.Equal(extractPDFXMP(streams[num]), xmpData) {
+				if src := doc.source("XMP", num); src != nil {
+					m.SetSource(from, *src)
+				}
+				break
+			}
+		}
 	}
 
 	// 3. PDF version
@@ -195,6 +265,22 @@ func viewPDF(path string, m *core.Metadata) (*core.Metadata, error) {
 			Editable: false,
 		})
 	}
+	if doc.xrefStream {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "CrossReference",
+			Value:    "stream",
+			Category: "PDF Header",
+			Editable: false,
+		})
+	}
+	if n := doc.objectStreams(); n > 0 {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "ObjectStreams",
+			Value:    fmt.Sprintf("%d", n),
+			Category: "PDF Header",
+			Editable: false,
+		})
+	}
 
 	return m, nil
 }
@@ -379,9 +465,11 @@ func viewOPC(path string, m *core.Metadata, editable bool) (*core.Metadata, erro
 	}
 	defer r.Close()
 
+	corePart, _ := opcCorePart(r.File)
+	pptParts := map[string][]byte{}
 	for _, f := range r.File {
 		switch f.Name {
-		case "docProps/core.xml":
+		case corePart:
 			rc, err := f.Open()
 			if err != nil {
 				continue
@@ -398,8 +486,40 @@ func viewOPC(path string, m *core.Metadata, editable bool) (*core.Metadata, erro
 			data, _ := io.ReadAll(rc)
 			rc.Close()
 			parseAppProps(data, m)
+
+		case "xl/workbook.xml":
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			addXLSXWorkbook(data, m)
+
+		default:
+			if strings.HasPrefix(f.Name, "xl/externalLinks/_rels/") {
+				rc, err := f.Open()
+				if err != nil {
+					continue
+				}
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				addXLSXExternalLink(data, m)
+			}
+			if strings.HasPrefix(f.Name, "ppt/") && strings.HasSuffix(f.Name, ".xml") {
+				rc, err := f.Open()
+				if err != nil {
+					continue
+				}
+				data, _ := io.ReadAll(rc)
+				rc.Close()
+				pptParts[f.Name] = data
+			}
 		}
 	}
+	if len(pptParts) > 0 {
+		addPPTXInfo(pptParts, m)
+	}
 	return m, nil
 }
 
@@ -539,19 +659,7 @@ func viewEPUB(path string, m *core.Metadata) (*core.Metadata, error) {
 	defer r.Close()
 
 	// Find OPF file (container.xml points to it)
-	opfPath := ""
-	for _, f := range r.File {
-		if f.Name == "META-INF/container.xml" {
-			rc, _ := f.Open()
-			data, _ := io.ReadAll(rc)
-			rc.Close()
-			re := regexp.MustCompile(`full-path="([^"]+\.opf)"`)
-			if match := re.FindSubmatch(data); match != nil {
-				opfPath = string(match[1])
-			}
-			break
-		}
-	}
+	opfPath := epubOPFPath(r.File)
 
 	for _, f := range r.File {
 		if f.Name == opfPath || strings.HasSuffix(f.Name, ".opf") {
@@ -559,6 +667,7 @@ func viewEPUB(path string, m *core.Metadata) (*core.Metadata, error) {
 			data, _ := io.ReadAll(rc)
 			rc.Close()
 			parseEPUBOPF(data, m)
+			addEPUBPackageInfo(data, m)
 			break
 		}
 	}
@@ -616,12 +725,19 @@ func parseEPUBOPF(data []byte, m *core.Metadata) {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtPDF:
 		return editPDF(path, out, opts)
 	case core.FmtDOCX, core.FmtXLSX, core.FmtPPTX:
 		return editOPC(path, out, opts)
+	case core.FmtEPUB:
+		return editEPUB(path, out, opts)
+	case core.FmtCBZ:
+		return editCBZ(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanEdit {
@@ -634,11 +750,58 @@ func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error
 // ─── PDF Edit ─────────────────────────────────────────────────────────────────
 
 func editPDF(path, outPath string, opts core.EditOptions) error {
-	data, err := os.ReadFile(path)
+	incremental, err := optPDFIncremental.Value(opts.Options)
+	if err != nil {
+		return err
+	}
+	data, err := core.ReadFileProgress(path, opts.Progress)
 	if err != nil {
 		return err
 	}
 
+	doc := loadPDF(data)
+	if incremental != "true" && doc.infoCompressed() {
+		return fmt.Errorf("PDF Info dictionary is inside a compressed object stream: edit it as an incremental update (option pdf.incremental=true)")
+	}
+
+	// Keys match the Info dictionary's own without regard to case, so
+	// title=X replaces /Title rather than adding /title.
+	infoKeys, info := pdfDictEntries(doc.infoDict())
+	infoKey := func(k string) string {
+		for _, name := range infoKeys {
+			if strings.EqualFold(name, k) {
+				return name
+			}
+		}
+		if name, ok := pdfDateField(k); ok {
+			return name
+		}
+		return k
+	}
+	del := make([]string, len(opts.Delete))
+	for i, k := range opts.Delete {
+		del[i] = infoKey(k)
+	}
+	opts.Delete = del
+
+	// Dates are accepted in human form and stored as PDF date strings.
+	set := make(map[string]string, len(opts.Set)+1)
+	if opts.TouchModified {
+		set[infoKey("ModDate")], _ = encodePDFDate("now")
+	}
+	for k, v := range opts.Set {
+		k = infoKey(k)
+		if _, ok := pdfDateField(k); ok {
+			enc, err := encodePDFDate(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			v = enc
+		}
+		set[k] = v
+	}
+	opts.Set = set
+
 	if opts.DryRun {
 		fmt.Println("Dry-run: PDF Info dict would be updated:")
 		for k, v := range opts.Set {
@@ -647,20 +810,28 @@ func editPDF(path, outPath string, opts core.EditOptions) error {
 		return nil
 	}
 
+	// A key the Info dictionary lacks, or holds as other than a literal
+	// string, cannot be written in place without moving every object
+	// after it, so it goes in an incremental update.
+	for k := range opts.Set {
+		if v, ok := info[k]; !ok || !bytes.HasPrefix(v, []byte("(")) {
+			incremental = "true"
+		}
+	}
+
+	if incremental == "true" {
+		if data, err = appendPDFInfoUpdate(data, opts.Set, opts.Delete); err != nil {
+			return err
+		}
+		return core.WriteFileProgress(outPath, data, opts.Progress)
+	}
+
 	// Replace existing Info fields using regex substitution
-	for k, v := range opts.Set {
+	for _, k := range core.SortedKeys(opts.Set) {
+		v := opts.Set[k]
 		re := regexp.MustCompile(`/` + regexp.QuoteMeta(k) + `\s*\([^)]*\)`)
 		newEntry := fmt.Sprintf("/%s (%s)", k, v)
-		if re.Match(data) {
-			data = re.ReplaceAll(data, []byte(newEntry))
-		} else {
-			// Inject into Info dict
-			infoIdx := bytes.Index(data, []byte("<< /"))
-			if infoIdx >= 0 {
-				inject := []byte("\n/" + k + " (" + v + ")")
-				data = append(data[:infoIdx+3], append(inject, data[infoIdx+3:]...)...)
-			}
-		}
+		data = re.ReplaceAll(data, []byte(newEntry))
 	}
 
 	// Handle deletes
@@ -669,12 +840,31 @@ func editPDF(path, outPath string, opts core.EditOptions) error {
 		data = re.ReplaceAll(data, nil)
 	}
 
-	return os.WriteFile(outPath, data, 0644)
+	return core.WriteFileProgress(outPath, data, opts.Progress)
 }
 
 // ─── OPC Edit (DOCX/XLSX/PPTX) ──────────────────────────────────────────────
 
 func editOPC(path, outPath string, opts core.EditOptions) error {
+	// dcterms:created / dcterms:modified must be W3CDTF dates.
+	set := make(map[string]string, len(opts.Set)+1)
+	for k, v := range opts.Set {
+		set[k] = v
+	}
+	if opts.TouchModified && set["Modified"] == "" {
+		set["Modified"] = "now"
+	}
+	for _, k := range []string{"Created", "Modified"} {
+		if v, ok := set[k]; ok {
+			enc, err := encodeW3CDTF(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			set[k] = enc
+		}
+	}
+	opts.Set = set
+
 	if opts.DryRun {
 		fmt.Println("Dry-run: OPC core.xml properties would be updated:")
 		for k, v := range opts.Set {
@@ -699,7 +889,20 @@ func editOPC(path, outPath string, opts core.EditOptions) error {
 	w := zip.NewWriter(outFile)
 	defer w.Close()
 
+	// Some generators omit the core properties part entirely; create it
+	// (with its package relationship and content-type override) so that
+	// --set always has somewhere to go.
+	corePart, related := opcCorePart(r.File)
+	createCore := len(opts.Set) > 0 && !zipHasFile(r.File, corePart)
+	sawRels := false
+
 	for _, f := range r.File {
+		if f.Name != corePart && !(createCore && (f.Name == "_rels/.rels" || f.Name == "[Content_Types].xml")) {
+			if err := copyZipMember(w, f); err != nil {
+				return err
+			}
+			continue
+		}
 		rc, err := f.Open()
 		if err != nil {
 			return err
@@ -710,8 +913,16 @@ func editOPC(path, outPath string, opts core.EditOptions) error {
 			return err
 		}
 
-		if f.Name == "docProps/core.xml" {
+		switch {
+		case f.Name == corePart:
 			content = patchCoreXML(content, opts.Set, opts.Delete)
+		case createCore && f.Name == "_rels/.rels":
+			sawRels = true
+			if !related {
+				content = addOPCRelationship(content, opcCoreRelType, corePart)
+			}
+		case createCore && f.Name == "[Content_Types].xml":
+			content = addOPCOverride(content, "/"+corePart, opcCoreContentType)
 		}
 
 		fw, err := w.Create(f.Name)
@@ -721,9 +932,131 @@ func editOPC(path, outPath string, opts core.EditOptions) error {
 		fw.Write(content)
 	}
 
+	if createCore {
+		if !sawRels {
+			fw, err := w.Create("_rels/.rels")
+			if err != nil {
+				return err
+			}
+			fw.Write(addOPCRelationship([]byte(opcBlankRels), opcCoreRelType, corePart))
+		}
+		fw, err := w.Create(corePart)
+		if err != nil {
+			return err
+		}
+		fw.Write(patchCoreXML([]byte(opcBlankCoreXML), opts.Set, nil))
+		fmt.Printf("  Note: %s was missing and has been created\n", corePart)
+	}
+
 	return nil
 }
 
+// ─── OPC package parts ───────────────────────────────────────────────────────
+
+const (
+	opcCoreRelType     = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	opcCoreContentType = "application/vnd.openxmlformats-package.core-properties+xml"
+)
+
+const opcBlankCoreXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"
+  xmlns:dcterms="http://purl.org/dc/terms/"
+  xmlns:dcmitype="http://purl.org/dc/dcmitype/"
+  xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+</cp:coreProperties>`
+
+const opcBlankRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+</Relationships>`
+
+var reOPCRel = regexp.MustCompile(`<Relationship\b[^>]*>`)
+
+// opcCorePart returns the zip name of the core properties part as declared
+// in _rels/.rels, falling back to docProps/core.xml. related reports whether
+// the package relationship exists.
+func opcCorePart(files []*zip.File) (name string, related bool) {
+	for _, f := range files {
+		if f.Name != "_rels/.rels" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			break
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		for _, rel := range reOPCRel.FindAll(data, -1) {
+			if xmlAttr(rel, "Type") == opcCoreRelType {
+				if target := strings.TrimPrefix(xmlAttr(rel, "Target"), "/"); target != "" {
+					return target, true
+				}
+			}
+		}
+	}
+	return "docProps/core.xml", false
+}
+
+// copyZipMember copies f into w still compressed, so parts an edit does not
+// touch (slides, media, embedded video) are never inflated or held in
+// memory.
+func copyZipMember(w *zip.Writer, f *zip.File) error {
+	fh := f.FileHeader
+	fw, err := w.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	rc, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, rc)
+	return err
+}
+
+func zipHasFile(files []*zip.File, name string) bool {
+	for _, f := range files {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlAttr returns the value of attribute name in a single start tag.
+func xmlAttr(tag []byte, name string) string {
+	m := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\s*=\s*["']([^"']*)["']`).FindSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+// addOPCRelationship appends a package relationship with an unused rId.
+func addOPCRelationship(rels []byte, relType, target string) []byte {
+	used := map[string]bool{}
+	for _, rel := range reOPCRel.FindAll(rels, -1) {
+		used[xmlAttr(rel, "Id")] = true
+	}
+	id := ""
+	for n := 1; ; n++ {
+		if id = fmt.Sprintf("rId%d", n); !used[id] {
+			break
+		}
+	}
+	el := fmt.Sprintf(`<Relationship Id="%s" Type="%s" Target="%s"/>`, id, relType, target)
+	return bytes.Replace(rels, []byte("</Relationships>"), []byte(el+"</Relationships>"), 1)
+}
+
+// addOPCOverride adds a content-type override for partName unless one exists.
+func addOPCOverride(types []byte, partName, contentType string) []byte {
+	if bytes.Contains(types, []byte(`PartName="`+partName+`"`)) {
+		return types
+	}
+	el := fmt.Sprintf(`<Override PartName="%s" ContentType="%s"/>`, partName, contentType)
+	return bytes.Replace(types, []byte("</Types>"), []byte(el+"</Types>"), 1)
+}
+
 func patchCoreXML(data []byte, set map[string]string, del []string) []byte {
 	// Map friendly names to XML element names
 	xmlNames := map[string]string{
@@ -735,9 +1068,12 @@ func patchCoreXML(data []byte, set map[string]string, del []string) []byte {
 		"LastModifiedBy": "cp:lastModifiedBy",
 		"Category":       "cp:category",
 		"ContentStatus":  "cp:contentStatus",
+		"Created":        "dcterms:created",
+		"Modified":       "dcterms:modified",
 	}
 
-	for k, v := range set {
+	for _, k := range core.SortedKeys(set) {
+		v := set[k]
 		xmlTag := xmlNames[k]
 		if xmlTag == "" {
 			xmlTag = k
@@ -745,6 +1081,12 @@ func patchCoreXML(data []byte, set map[string]string, del []string) []byte {
 		// Try to replace existing element
 		re := regexp.MustCompile(`<` + regexp.QuoteMeta(xmlTag) + `[^>]*>[^<]*</` + regexp.QuoteMeta(xmlTag) + `>`)
 		newEl := fmt.Sprintf("<%s>%s</%s>", xmlTag, xmlEscape(v), xmlTag)
+		if strings.HasPrefix(xmlTag, "dcterms:") {
+			// dates carry xsi:type; make sure both prefixes are declared
+			newEl = fmt.Sprintf(`<%s xsi:type="dcterms:W3CDTF">%s</%s>`, xmlTag, xmlEscape(v), xmlTag)
+			data = declareCoreNamespace(data, "dcterms", "http://purl.org/dc/terms/")
+			data = declareCoreNamespace(data, "xsi", "http://www.w3.org/2001/XMLSchema-instance")
+		}
 		if re.Match(data) {
 			data = re.ReplaceAll(data, []byte(newEl))
 		} else {
@@ -766,6 +1108,16 @@ func patchCoreXML(data []byte, set map[string]string, del []string) []byte {
 	return data
 }
 
+// declareCoreNamespace adds xmlns:prefix to the coreProperties root element
+// if it is not declared yet.
+func declareCoreNamespace(data []byte, prefix, uri string) []byte {
+	if bytes.Contains(data, []byte("xmlns:"+prefix+"=")) {
+		return data
+	}
+	root := regexp.MustCompile(`<cp:coreProperties\b`)
+	return root.ReplaceAll(data, []byte(fmt.Sprintf(`<cp:coreProperties xmlns:%s="%s"`, prefix, uri)))
+}
+
 func xmlEscape(s string) string {
 	var buf bytes.Buffer
 	xml.EscapeText(&buf, []byte(s))
@@ -777,12 +1129,20 @@ func xmlEscape(s string) string {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return core.PreviewStrip(h, path, opts)
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtPDF:
 		return stripPDF(path, out, opts)
 	case core.FmtDOCX, core.FmtXLSX, core.FmtPPTX:
 		return stripOPC(path, out, opts)
+	case core.FmtCBZ:
+		return stripCBZ(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanStrip {
@@ -793,21 +1153,31 @@ func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) err
 }
 
 func stripPDF(path, outPath string, opts core.StripOptions) error {
-	data, err := os.ReadFile(path)
+	data, err := core.ReadFileProgress(path, opts.Progress)
 	if err != nil {
 		return err
 	}
 
-	if opts.DryRun {
-		fmt.Println("Dry-run: PDF Info dict and XMP would be cleared")
-		return nil
-	}
-
 	keepSet := make(map[string]bool)
 	for _, k := range opts.KeepFields {
 		keepSet[k] = true
 	}
 
+	// Compressed object streams are out of reach of the byte-level removal
+	// below. Writing the file anyway would report a strip that left the
+	// metadata in place, so it is refused.
+	doc := loadPDF(data)
+	keepInfo := true
+	for _, k := range pdfInfoFields {
+		keepInfo = keepInfo && keepSet[k]
+	}
+	if doc.infoCompressed() && !keepInfo {
+		return fmt.Errorf("PDF Info dictionary is inside a compressed object stream and cannot be removed; rewrite the PDF without object streams (e.g. qpdf --object-streams=disable) and strip again")
+	}
+	if extractPDFXMP(data) == nil && len(doc.metadataStreams()) > 0 && !keepSet["xmp"] && !keepSet["XMP"] {
+		return fmt.Errorf("PDF XMP stream is compressed and cannot be removed; rewrite the PDF with uncompressed streams (e.g. qpdf --stream-data=uncompress) and strip again")
+	}
+
 	// Remove Info dict entries
 	for _, k := range pdfInfoFields {
 		if keepSet[k] {
@@ -827,15 +1197,10 @@ func stripPDF(path, outPath string, opts core.StripOptions) error {
 		data = xmpRe2.ReplaceAll(data, nil)
 	}
 
-	return os.WriteFile(outPath, data, 0644)
+	return core.WriteFileProgress(outPath, data, opts.Progress)
 }
 
 func stripOPC(path, outPath string, opts core.StripOptions) error {
-	if opts.DryRun {
-		fmt.Println("Dry-run: OPC docProps would be cleared")
-		return nil
-	}
-
 	r, err := zip.OpenReader(path)
 	if err != nil {
 		return fmt.Errorf("cannot open as ZIP: %w", err)
@@ -851,13 +1216,26 @@ func stripOPC(path, outPath string, opts core.StripOptions) error {
 	w := zip.NewWriter(outFile)
 	defer w.Close()
 
-	blankCoreXML := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
-  xmlns:dc="http://purl.org/dc/elements/1.1/"
-  xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
-</cp:coreProperties>`
+	corePart, _ := opcCorePart(r.File)
+	remove := map[string]bool{}
+	for _, sec := range opts.RemoveSections {
+		remove[strings.ToLower(sec)] = true
+	}
+	drop := func(name string) bool { return pptxDropPart(name, remove) }
 
 	for _, f := range r.File {
+		if drop(f.Name) {
+			continue
+		}
+		touched := (f.Name == corePart && len(opts.KeepFields) == 0) ||
+			(len(remove) > 0 && (strings.HasSuffix(f.Name, ".rels") || f.Name == "[Content_Types].xml")) ||
+			(opts.PrivacyFlag && (f.Name == "xl/workbook.xml" || f.Name == "word/settings.xml"))
+		if !touched {
+			if err := copyZipMember(w, f); err != nil {
+				return err
+			}
+			continue
+		}
 		rc, err := f.Open()
 		if err != nil {
 			return err
@@ -868,8 +1246,24 @@ func stripOPC(path, outPath string, opts core.StripOptions) error {
 			return err
 		}
 
-		if f.Name == "docProps/core.xml" && len(opts.KeepFields) == 0 {
-			content = []byte(blankCoreXML)
+		if f.Name == corePart && len(opts.KeepFields) == 0 {
+			content = []byte(opcBlankCoreXML)
+		}
+		if len(remove) > 0 {
+			switch {
+			case strings.HasSuffix(f.Name, ".rels"):
+				content = dropRelationships(f.Name, content, drop)
+			case f.Name == "[Content_Types].xml":
+				content = dropOverrides(content, drop)
+			}
+		}
+		if opts.PrivacyFlag {
+			switch f.Name {
+			case "xl/workbook.xml":
+				content = setXLSXFilterPrivacy(content)
+			case "word/settings.xml":
+				content = setDOCXRemovePersonalInfo(content)
+			}
 		}
 
 		fw, err := w.Create(f.Name)
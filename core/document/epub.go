@@ -0,0 +1,245 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── EPUB package details ────────────────────────────────────────────────────
+// EPUB 3 packages carry a version attribute, a unique-identifier pointing at
+// one dc:identifier, a mandatory <meta property="dcterms:modified"> that
+// reading systems use to tell releases apart, and schema.org accessibility
+// properties (schema:accessMode, schema:accessibilityFeature, …). EPUB 2
+// writes the latter as <meta name="schema:…" content="…"/>.
+
+type epubPackage struct {
+	Version          string `xml:"version,attr"`
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Identifiers []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"identifier"`
+		Meta []struct {
+			Property string `xml:"property,attr"`
+			Name     string `xml:"name,attr"`
+			Content  string `xml:"content,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+}
+
+func addEPUBPackageInfo(data []byte, m *core.Metadata) {
+	var pkg epubPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return
+	}
+	add := func(k, v, cat string, editable bool) {
+		if v = strings.TrimSpace(v); v != "" {
+			m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: cat, Editable: editable})
+		}
+	}
+	add("EPUBVersion", pkg.Version, "EPUB Package", false)
+	for _, id := range pkg.Metadata.Identifiers {
+		if id.ID != "" && id.ID == pkg.UniqueIdentifier {
+			add("UniqueIdentifier", id.Value, "EPUB Package", false)
+		}
+	}
+	for _, meta := range pkg.Metadata.Meta {
+		prop, val := meta.Property, meta.Value
+		if prop == "" {
+			prop, val = meta.Name, meta.Content
+		}
+		switch {
+		case prop == "dcterms:modified":
+			add("Modified", val, "EPUB Package", true)
+		case strings.HasPrefix(prop, "schema:access"):
+			add(strings.TrimPrefix(prop, "schema:"), val, "Accessibility", false)
+		}
+	}
+}
+
+// epubOPFPath returns the package document named by META-INF/container.xml.
+func epubOPFPath(files []*zip.File) string {
+	for _, f := range files {
+		if f.Name != "META-INF/container.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return ""
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		re := regexp.MustCompile(`full-path="([^"]+\.opf)"`)
+		if match := re.FindSubmatch(data); match != nil {
+			return string(match[1])
+		}
+	}
+	return ""
+}
+
+// ─── EPUB Edit ───────────────────────────────────────────────────────────────
+
+var reEPUBModified = regexp.MustCompile(`(<(?:\w+:)?meta\b[^>]*property\s*=\s*["']dcterms:modified["'][^>]*>)[^<]*(</(?:\w+:)?meta>)`)
+
+// editEPUB updates dcterms:modified, the one field EPUB 3 requires to change
+// whenever the publication does.
+func editEPUB(path, outPath string, opts core.EditOptions) error {
+	value := ""
+	for k, v := range opts.Set {
+		if !strings.EqualFold(k, "Modified") && !strings.EqualFold(k, "dcterms:modified") {
+			return fmt.Errorf("EPUB editing supports only Modified (dcterms:modified), not %q", k)
+		}
+		value = v
+	}
+	if len(opts.Delete) > 0 {
+		return fmt.Errorf("EPUB editing cannot delete fields: dcterms:modified is required by EPUB 3")
+	}
+	if value == "" && opts.TouchModified {
+		value = "now"
+	}
+	if value == "" {
+		return fmt.Errorf("no recognised fields to set")
+	}
+	t, err := parseHumanDate(value)
+	if err != nil {
+		return fmt.Errorf("Modified: %w", err)
+	}
+	// EPUB 3 requires exactly CCYY-MM-DDThh:mm:ssZ
+	stamp := t.UTC().Format("2006-01-02T15:04:05Z")
+
+	if opts.DryRun {
+		fmt.Println("Dry-run: EPUB dcterms:modified would be set:")
+		fmt.Printf("  Modified = %s\n", stamp)
+		return nil
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("cannot open as ZIP: %w", err)
+	}
+	defer r.Close()
+
+	opfPath := epubOPFPath(r.File)
+	if opfPath == "" {
+		return fmt.Errorf("EPUB has no package document in META-INF/container.xml")
+	}
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	// OCF requires the mimetype entry first, stored and with no extra
+	// field, and it is written that way whatever the source did. The
+	// package document is rewritten; every other member is copied still
+	// compressed.
+	w := zip.NewWriter(outFile)
+	for _, f := range r.File {
+		if f.Name == "mimetype" {
+			if err := writeEPUBMimetype(w, f); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range r.File {
+		switch f.Name {
+		case "mimetype":
+		case opfPath:
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if content, err = setEPUBModified(content, stamp); err != nil {
+				return err
+			}
+			fw, err := w.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(content); err != nil {
+				return err
+			}
+		default:
+			if err := copyZipMember(w, f); err != nil {
+				return err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return outFile.Close()
+}
+
+// writeEPUBMimetype copies the mimetype entry f to w stored, with no extra
+// field and no data descriptor, keeping its DOS timestamp.
+func writeEPUBMimetype(w *zip.Writer, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	fw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               f.Name,
+		Method:             zip.Store,
+		ModifiedTime:       f.ModifiedTime,
+		ModifiedDate:       f.ModifiedDate,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: uint64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(content)
+	return err
+}
+
+func setEPUBModified(opf []byte, stamp string) ([]byte, error) {
+	if reEPUBModified.Match(opf) {
+		return reEPUBModified.ReplaceAll(opf, []byte("${1}"+stamp+"${2}")), nil
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return nil, fmt.Errorf("cannot parse EPUB package document: %w", err)
+	}
+	if !strings.HasPrefix(pkg.Version, "3") {
+		return nil, fmt.Errorf("dcterms:modified is an EPUB 3 property; this package is version %q", pkg.Version)
+	}
+	closing := regexp.MustCompile(`</((?:\w+:)?)metadata>`)
+	loc := closing.FindSubmatchIndex(opf)
+	if loc == nil {
+		return nil, fmt.Errorf("EPUB package document has no metadata element")
+	}
+	prefix := opf[loc[2]:loc[3]]
+	// Insert after the last child, on its own line with the same indent.
+	at := len(bytes.TrimRight(opf[:loc[0]], " \t\r\n"))
+	lineStart := bytes.LastIndexByte(opf[:at], '\n') + 1
+	indent := opf[lineStart : lineStart+len(opf[lineStart:at])-len(bytes.TrimLeft(opf[lineStart:at], " \t"))]
+	var buf bytes.Buffer
+	buf.Write(opf[:at])
+	fmt.Fprintf(&buf, "\n%s<%smeta property=\"dcterms:modified\">%s</%smeta>", indent, prefix, stamp, prefix)
+	buf.Write(opf[at:])
+	return buf.Bytes(), nil
+}
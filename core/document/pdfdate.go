@@ -0,0 +1,148 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── PDF dates ───────────────────────────────────────────────────────────────
+// PDF date strings have the form D:YYYYMMDDHHmmSSOHH'mm' where everything
+// after the year is optional and O is +, - or Z (PDF 32000-1 §7.9.4).
+// View shows them as ISO 8601; edit accepts human dates and encodes them.
+
+// pdfDateFields are the Info keys that hold dates.
+var pdfDateFields = map[string]bool{"CreationDate": true, "ModDate": true}
+
+// pdfDateField returns the Info key of the date field key names, without
+// regard to case, as edit matches the keys of the Info dictionary.
+func pdfDateField(key string) (string, bool) {
+	for name := range pdfDateFields {
+		if strings.EqualFold(name, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// parsePDFDate decodes a PDF date string.
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	digits, zone := s[:n], s[n:]
+	if len(digits) < 4 {
+		return time.Time{}, false
+	}
+	parts := [6]int{0, 1, 1, 0, 0, 0} // year, month, day, hour, minute, second
+	for i, off := 0, 0; i < len(parts); i++ {
+		w := 2
+		if i == 0 {
+			w = 4
+		}
+		if off+w > len(digits) {
+			break
+		}
+		parts[i], _ = strconv.Atoi(digits[off : off+w])
+		off += w
+	}
+
+	loc := time.UTC
+	if zone != "" && (zone[0] == '+' || zone[0] == '-') {
+		digits := strings.NewReplacer("'", "", ":", "").Replace(zone[1:])
+		h, m := 0, 0
+		if len(digits) >= 2 {
+			h, _ = strconv.Atoi(digits[:2])
+		}
+		if len(digits) >= 4 {
+			m, _ = strconv.Atoi(digits[2:4])
+		}
+		offset := h*3600 + m*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	t := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, loc)
+	return t, true
+}
+
+// formatPDFDateISO renders a PDF date for display, or returns it unchanged
+// if it cannot be parsed.
+func formatPDFDateISO(s string) string {
+	t, ok := parsePDFDate(s)
+	if !ok {
+		return s
+	}
+	return t.Format(time.RFC3339)
+}
+
+// pdfDateInputLayouts are the human date formats accepted on edit.
+var pdfDateInputLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006:01:02 15:04:05", // EXIF style
+	"02 Jan 2006 15:04",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// parseHumanDate reads a user-supplied date: "now", ISO 8601 / RFC 3339,
+// EXIF style, a few spelled-out forms, or an existing PDF D: string.
+// Dates without a zone are taken as local time.
+func parseHumanDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if strings.EqualFold(v, "now") {
+		return time.Now(), nil
+	}
+	if strings.HasPrefix(v, "D:") {
+		if t, ok := parsePDFDate(v); ok {
+			return t, nil
+		}
+	}
+	for _, layout := range pdfDateInputLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date %q (use e.g. 2024-01-02, 2024-01-02T15:04:05+01:00 or now)", v)
+}
+
+// encodePDFDate converts a user-supplied date into a PDF date string.
+func encodePDFDate(v string) (string, error) {
+	t, err := parseHumanDate(v)
+	if err != nil {
+		return "", err
+	}
+	return formatPDFDate(t), nil
+}
+
+// encodeW3CDTF converts a user-supplied date into the W3CDTF form OPC
+// core properties require for dcterms:created / dcterms:modified.
+func encodeW3CDTF(v string) (string, error) {
+	t, err := parseHumanDate(v)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+}
+
+// formatPDFDate encodes t as D:YYYYMMDDHHmmSS with its zone offset.
+func formatPDFDate(t time.Time) string {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return t.Format("D:20060102150405") + "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%c%02d'%02d'", t.Format("D:20060102150405"), sign, offset/3600, offset%3600/60)
+}
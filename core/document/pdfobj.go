@@ -0,0 +1,481 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── PDF objects, object streams and xref streams ───────────────────────────
+// PDF 1.5+ writers pack most non-stream objects — including the Info
+// dictionary — into Flate-compressed object streams (/Type /ObjStm) and
+// replace the classic "xref … trailer" table with an xref stream
+// (/Type /XRef) whose dictionary doubles as the trailer. None of that is
+// visible to a byte-level regex, so this file builds a small object index:
+// direct "N G obj … endobj" bodies, plus objects unpacked from object streams
+// as located by the xref stream.
+
+var (
+	rePDFObj      = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	rePDFInfoRef  = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+	rePDFTypeObjS = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	rePDFTypeXRef = regexp.MustCompile(`/Type\s*/XRef\b`)
+	rePDFTypeMeta = regexp.MustCompile(`/Type\s*/Metadata\b`)
+	rePDFFlate    = regexp.MustCompile(`/Filter\s*(?:\[\s*)?/(?:FlateDecode|Fl)\b`)
+	rePDFFilter   = regexp.MustCompile(`/Filter\b`)
+)
+
+// maxPDFStream caps what one FlateDecode stream may inflate to. Object,
+// xref and metadata streams are far smaller; a stream that inflates past
+// it is treated as unreadable rather than filling memory.
+const maxPDFStream = 64 << 20
+
+// pdfCompressed locates an object inside an object stream.
+type pdfCompressed struct {
+	stream int // object number of the ObjStm
+	index  int // position inside it
+}
+
+// pdfDoc is a lightweight index of the objects in a PDF file.
+type pdfDoc struct {
+	data       []byte
+	direct     map[int][]byte // object number → body between "obj" and "endobj"
+	compressed map[int]pdfCompressed
+	unpacked   map[int]map[int][]byte // ObjStm number → its objects
+	trailer    []byte
+	xrefStream bool
+	at         map[int]int64 // object number → offset of its "N G obj"
+
+	// Set when the index is built from the cross-reference chain rather
+	// than the whole file (see loadPDFSparse): type-1 entries, and the file
+	// objects are read from on demand.
+	offsets map[int]int64
+	src     io.ReaderAt
+	size    int64
+}
+
+func loadPDF(data []byte) *pdfDoc {
+	d := &pdfDoc{
+		data:       data,
+		direct:     map[int][]byte{},
+		compressed: map[int]pdfCompressed{},
+		unpacked:   map[int]map[int][]byte{},
+		at:         map[int]int64{},
+	}
+	// Later definitions win, matching incremental-update semantics.
+	for _, loc := range rePDFObj.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		body := data[loc[1]:]
+		if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+			body = body[:end]
+		}
+		d.direct[num] = body
+		d.at[num] = int64(loc[0])
+		if rePDFTypeXRef.Match(pdfDict(body)) {
+			d.trailer = pdfDict(body)
+			d.xrefStream = true
+			d.readXRefStream(body)
+		}
+	}
+	// A classic trailer, when present and later in the file, takes over.
+	if i := bytes.LastIndex(data, []byte("trailer")); i >= 0 {
+		if dict := pdfDict(data[i:]); dict != nil && rePDFInfoRef.Match(dict) {
+			d.trailer = dict
+		}
+	}
+	return d
+}
+
+// readXRefStream records the type-2 (compressed) entries of an xref stream.
+func (d *pdfDoc) readXRefStream(body []byte) {
+	dict := pdfDict(body)
+	raw, ok := pdfStreamData(body)
+	if !ok {
+		return
+	}
+	w := pdfIntArray(dict, "W")
+	if len(w) != 3 {
+		return
+	}
+	index := pdfIntArray(dict, "Index")
+	if len(index) == 0 {
+		index = []int{0, pdfInt(dict, "Size")}
+	}
+	row := w[0] + w[1] + w[2]
+	if row == 0 {
+		return
+	}
+	field := func(b []byte) int {
+		v := 0
+		for _, c := range b {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		for num := index[i]; num < index[i]+index[i+1]; num++ {
+			if pos+row > len(raw) {
+				return
+			}
+			typ := 1 // default when the type field is omitted
+			if w[0] > 0 {
+				typ = field(raw[pos : pos+w[0]])
+			}
+			f2 := field(raw[pos+w[0] : pos+w[0]+w[1]])
+			f3 := field(raw[pos+w[0]+w[1] : pos+row])
+			if d.offsets != nil {
+				// Sections are read newest first, so the first entry wins.
+				_, c := d.compressed[num]
+				_, o := d.offsets[num]
+				if !c && !o {
+					switch typ {
+					case 1:
+						d.offsets[num] = int64(f2)
+					case 2:
+						d.compressed[num] = pdfCompressed{stream: f2, index: f3}
+					}
+				}
+			} else if typ == 2 {
+				d.compressed[num] = pdfCompressed{stream: f2, index: f3}
+			}
+			pos += row
+		}
+	}
+}
+
+// object returns the body of object num, looking in object streams when the
+// xref stream says it is compressed or no direct definition exists.
+func (d *pdfDoc) object(num int) []byte {
+	if c, ok := d.compressed[num]; ok {
+		if body := d.objStm(c.stream)[num]; body != nil {
+			return body
+		}
+	}
+	if body := d.body(num); body != nil {
+		return body
+	}
+	for n, body := range d.direct {
+		if rePDFTypeObjS.Match(pdfDict(body)) {
+			if obj := d.objStm(n)[num]; obj != nil {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// objStm unpacks (and caches) the objects held in object stream num.
+func (d *pdfDoc) objStm(num int) map[int][]byte {
+	if objs, ok := d.unpacked[num]; ok {
+		return objs
+	}
+	objs := map[int][]byte{}
+	d.unpacked[num] = objs
+	body := d.body(num)
+	dict := pdfDict(body)
+	raw, ok := pdfStreamData(body)
+	if !ok {
+		return objs
+	}
+	n, first := pdfInt(dict, "N"), pdfInt(dict, "First")
+	if first <= 0 || first > len(raw) {
+		return objs
+	}
+	// Header: N pairs of "objnum offset", offsets relative to /First.
+	hdr := bytes.Fields(raw[:first])
+	var nums, offs []int
+	for i := 0; i+1 < len(hdr) && len(nums) < n; i += 2 {
+		a, err1 := strconv.Atoi(string(hdr[i]))
+		b, err2 := strconv.Atoi(string(hdr[i+1]))
+		if err1 != nil || err2 != nil {
+			break
+		}
+		nums = append(nums, a)
+		offs = append(offs, first+b)
+	}
+	for i, objNum := range nums {
+		start, end := offs[i], len(raw)
+		if i+1 < len(offs) {
+			end = offs[i+1]
+		}
+		if start <= end && end <= len(raw) {
+			objs[objNum] = raw[start:end]
+		}
+	}
+	return objs
+}
+
+// infoDict returns the document Info dictionary referenced by the trailer.
+func (d *pdfDoc) infoDict() []byte {
+	m := rePDFInfoRef.FindSubmatch(d.trailer)
+	if m == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	return pdfDict(d.object(num))
+}
+
+// infoSource returns where the Info dictionary referenced by the trailer
+// is defined, or nil.
+func (d *pdfDoc) infoSource() *core.FieldSource {
+	m := rePDFInfoRef.FindSubmatch(d.trailer)
+	if m == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	return d.source("PDF Info", num)
+}
+
+// source returns where object num is defined for a field read from it:
+// the object itself, or the object stream holding it. The index is the
+// object number.
+func (d *pdfDoc) source(store string, num int) *core.FieldSource {
+	structure := "object"
+	if c, ok := d.compressed[num]; ok {
+		structure, num = "object stream", c.stream
+	}
+	off, ok := d.offsets[num]
+	if !ok {
+		if off, ok = d.at[num]; !ok {
+			return nil
+		}
+	}
+	return &core.FieldSource{Store: store, Structure: structure, Index: num, Offset: off}
+}
+
+// infoCompressed reports whether the Info dictionary lives in an object
+// stream, where byte-level edits cannot reach it.
+func (d *pdfDoc) infoCompressed() bool {
+	m := rePDFInfoRef.FindSubmatch(d.trailer)
+	if m == nil {
+		return false
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	if _, ok := d.compressed[num]; ok {
+		return true
+	}
+	return d.body(num) == nil && d.object(num) != nil
+}
+
+// metadataStreams returns the decoded contents of every /Type /Metadata
+// stream (XMP packets), compressed or not, by object number.
+func (d *pdfDoc) metadataStreams() map[int][]byte {
+	out := map[int][]byte{}
+	for num, body := range d.direct {
+		if !rePDFTypeMeta.Match(pdfDict(body)) {
+			continue
+		}
+		if raw, ok := pdfStreamData(body); ok {
+			out[num] = raw
+		}
+	}
+	return out
+}
+
+// objectStreams counts the ObjStm objects in the file.
+func (d *pdfDoc) objectStreams() int {
+	if d.offsets != nil {
+		// Only what view needed was read; count the streams the
+		// cross-reference places objects in.
+		streams := map[int]bool{}
+		for _, c := range d.compressed {
+			streams[c.stream] = true
+		}
+		return len(streams)
+	}
+	n := 0
+	for _, body := range d.direct {
+		if rePDFTypeObjS.Match(pdfDict(body)) {
+			n++
+		}
+	}
+	return n
+}
+
+// ─── Low-level helpers ───────────────────────────────────────────────────────
+
+// pdfDict returns the first balanced << … >> dictionary in b, skipping
+// string literals so that parentheses and brackets inside them are ignored.
+func pdfDict(b []byte) []byte {
+	start := bytes.Index(b, []byte("<<"))
+	if start < 0 {
+		return nil
+	}
+	depth := 0
+	for i := start; i+1 < len(b); i++ {
+		switch {
+		case b[i] == '(':
+			// skip literal string, honouring escapes and nesting
+			nest := 0
+			for ; i < len(b); i++ {
+				if b[i] == '\\' {
+					i++
+				} else if b[i] == '(' {
+					nest++
+				} else if b[i] == ')' {
+					nest--
+					if nest == 0 {
+						break
+					}
+				}
+			}
+		case b[i] == '<' && b[i+1] == '<':
+			depth++
+			i++
+		case b[i] == '>' && b[i+1] == '>':
+			depth--
+			i++
+			if depth == 0 {
+				return b[start : i+1]
+			}
+		}
+	}
+	return nil
+}
+
+// pdfStreamData extracts and decodes the stream of an object body.
+// Only unfiltered and FlateDecode streams (with optional PNG predictors)
+// are supported.
+func pdfStreamData(body []byte) ([]byte, bool) {
+	dict := pdfDict(body)
+	if dict == nil {
+		return nil, false
+	}
+	after := bytes.Index(body, dict) + len(dict)
+	i := bytes.Index(body[after:], []byte("stream"))
+	if i < 0 {
+		return nil, false
+	}
+	start := after + i + len("stream")
+	if start < len(body) && body[start] == '\r' {
+		start++
+	}
+	if start < len(body) && body[start] == '\n' {
+		start++
+	}
+	end := bytes.LastIndex(body, []byte("endstream"))
+	if end < start {
+		return nil, false
+	}
+	raw := body[start:end]
+	if n := pdfInt(dict, "Length"); n > 0 && n <= len(raw) {
+		raw = raw[:n]
+	} else {
+		raw = bytes.TrimRight(raw, "\r\n")
+	}
+
+	if !rePDFFilter.Match(dict) {
+		return raw, true
+	}
+	if !rePDFFlate.Match(dict) {
+		return nil, false
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	out, err := io.ReadAll(io.LimitReader(zr, maxPDFStream+1))
+	if err != nil && len(out) == 0 || len(out) > maxPDFStream {
+		return nil, false
+	}
+	if pdfInt(dict, "Predictor") >= 10 {
+		cols := pdfInt(dict, "Columns")
+		if cols <= 0 {
+			cols = 1
+		}
+		out = pngUnpredict(out, cols)
+	}
+	return out, true
+}
+
+// pngUnpredict reverses the PNG row filters used by xref and object streams.
+func pngUnpredict(b []byte, columns int) []byte {
+	row := columns + 1
+	prev := make([]byte, columns)
+	var out []byte
+	for pos := 0; pos+row <= len(b); pos += row {
+		ft, cur := b[pos], append([]byte(nil), b[pos+1:pos+row]...)
+		for i := range cur {
+			var left, upLeft byte
+			if i > 0 {
+				left, upLeft = cur[i-1], prev[i-1]
+			}
+			up := prev[i]
+			switch ft {
+			case 1:
+				cur[i] += left
+			case 2:
+				cur[i] += up
+			case 3:
+				cur[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				cur[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pdfKeyRes caches the patterns of pdfInt and pdfIntArray, which are
+// looked up with the same few keys for every object of a file.
+var pdfKeyRes sync.Map // pattern → *regexp.Regexp
+
+func pdfKeyRegexp(pattern string) *regexp.Regexp {
+	if re, ok := pdfKeyRes.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := pdfKeyRes.LoadOrStore(pattern, regexp.MustCompile(pattern))
+	return re.(*regexp.Regexp)
+}
+
+// pdfInt reads a direct integer value /Key N from a dictionary; indirect
+// references (/Key N 0 R) yield 0.
+func pdfInt(dict []byte, key string) int {
+	m := pdfKeyRegexp(`/` + key + `\s+(\d+)(\s+\d+\s+R)?`).FindSubmatch(dict)
+	if m == nil || len(m[2]) > 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+// pdfIntArray reads an integer array value /Key [a b c] from a dictionary.
+func pdfIntArray(dict []byte, key string) []int {
+	m := pdfKeyRegexp(`/` + key + `\s*\[([\d\s]*)\]`).FindSubmatch(dict)
+	if m == nil {
+		return nil
+	}
+	var out []int
+	for _, f := range bytes.Fields(m[1]) {
+		n, _ := strconv.Atoi(string(f))
+		out = append(out, n)
+	}
+	return out
+}
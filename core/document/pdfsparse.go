@@ -0,0 +1,220 @@
+package document
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Large PDFs ──────────────────────────────────────────────────────────────
+// Scanning every "N G obj" of a 2 GB scan-to-PDF to find an Info dictionary
+// of a few hundred bytes is wasteful. Above pdfSparseThreshold, view reads
+// the first and last pdfEndWindow bytes, follows startxref through the
+// cross-reference sections (tables or streams, /Prev and /XRefStm) and
+// fetches only the objects it shows: the Info dictionary, the catalog and
+// its /Metadata stream, and the object streams holding them.
+
+var (
+	rePDFStartXRef = regexp.MustCompile(`startxref\s+(\d+)`)
+	rePDFRootRef   = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	rePDFMetaRef   = regexp.MustCompile(`/Metadata\s+(\d+)\s+\d+\s+R`)
+)
+
+// pdfSparseThreshold is the size above which a PDF is not read whole.
+const pdfSparseThreshold = 64 << 20
+
+const pdfEndWindow = 1 << 20
+
+// readPDF returns the bytes of the PDF at path that view scans, and its
+// object index. For a large file the bytes are its first and last
+// pdfEndWindow; one whose cross-reference cannot be followed is read whole
+// after all.
+func readPDF(path string) ([]byte, *pdfDoc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if st.Size() > pdfSparseThreshold {
+		if data, doc, ok := loadPDFSparse(f, st.Size()); ok {
+			return data, doc, nil
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, loadPDF(data), nil
+}
+
+// loadPDFSparse builds the index of a PDF from its cross-reference chain and
+// loads the objects view needs.
+func loadPDFSparse(r io.ReaderAt, size int64) ([]byte, *pdfDoc, bool) {
+	head, err := core.ReadRange(r, 0, pdfEndWindow)
+	if err != nil {
+		return nil, nil, false
+	}
+	tailOff := size - pdfEndWindow
+	if tailOff < 0 {
+		tailOff = 0
+	}
+	tail, err := core.ReadRange(r, tailOff, pdfEndWindow)
+	if err != nil {
+		return nil, nil, false
+	}
+	locs := rePDFStartXRef.FindAllSubmatch(tail, -1)
+	if locs == nil {
+		return nil, nil, false
+	}
+	start, _ := strconv.ParseInt(string(locs[len(locs)-1][1]), 10, 64)
+
+	d := &pdfDoc{
+		direct:     map[int][]byte{},
+		compressed: map[int]pdfCompressed{},
+		unpacked:   map[int]map[int][]byte{},
+		offsets:    map[int]int64{},
+		src:        r,
+		size:       size,
+	}
+	seen := map[int64]bool{}
+	queue := []int64{start}
+	for len(queue) > 0 {
+		off := queue[0]
+		queue = queue[1:]
+		if off <= 0 || off >= size || seen[off] {
+			continue
+		}
+		seen[off] = true
+
+		var dict []byte
+		if peek, _ := core.ReadRange(r, off, 4); string(peek) == "xref" {
+			dict = d.readXRefTable(d.window(off, func(b []byte) bool { return pdfDict(afterTrailer(b)) != nil }))
+		} else if _, body := d.readObjectAt(off); rePDFTypeXRef.Match(pdfDict(body)) {
+			dict = pdfDict(body)
+			d.xrefStream = true
+			d.readXRefStream(body)
+		}
+		if dict == nil {
+			continue
+		}
+		if d.trailer == nil || (!rePDFInfoRef.Match(d.trailer) && rePDFInfoRef.Match(dict)) {
+			d.trailer = dict
+		}
+		// A hybrid file's xref stream is read before the table's /Prev.
+		if n := pdfInt(dict, "XRefStm"); n > 0 {
+			queue = append(queue, int64(n))
+		}
+		if n := pdfInt(dict, "Prev"); n > 0 {
+			queue = append(queue, int64(n))
+		}
+	}
+	if d.trailer == nil {
+		return nil, nil, false
+	}
+
+	d.infoDict()
+	if m := rePDFRootRef.FindSubmatch(d.trailer); m != nil {
+		root, _ := strconv.Atoi(string(m[1]))
+		if mm := rePDFMetaRef.FindSubmatch(pdfDict(d.object(root))); mm != nil {
+			meta, _ := strconv.Atoi(string(mm[1]))
+			d.body(meta)
+		}
+	}
+	d.src = nil // everything view asks for is loaded
+	return append(head, tail...), d, true
+}
+
+// afterTrailer returns b from its trailer keyword on, or nil.
+func afterTrailer(b []byte) []byte {
+	if i := bytes.Index(b, []byte("trailer")); i >= 0 {
+		return b[i:]
+	}
+	return nil
+}
+
+// window reads from off, doubling the read until done accepts it or the
+// file ends.
+func (d *pdfDoc) window(off int64, done func([]byte) bool) []byte {
+	for n := int64(64 << 10); off < d.size; n *= 2 {
+		if off+n > d.size {
+			n = d.size - off
+		}
+		b, err := core.ReadRange(d.src, off, int(n))
+		if err != nil {
+			return nil
+		}
+		if done(b) || off+n >= d.size {
+			return b
+		}
+	}
+	return nil
+}
+
+// readXRefTable records the in-use entries of the classic xref section at
+// the start of b and returns its trailer dictionary.
+func (d *pdfDoc) readXRefTable(b []byte) []byte {
+	i := bytes.Index(b, []byte("trailer"))
+	if i < 0 {
+		return nil
+	}
+	tok := bytes.Fields(b[len("xref"):i])
+	for pos := 0; pos+1 < len(tok); {
+		first, err1 := strconv.Atoi(string(tok[pos]))
+		count, err2 := strconv.Atoi(string(tok[pos+1]))
+		if err1 != nil || err2 != nil {
+			break
+		}
+		pos += 2
+		for num := first; num < first+count && pos+2 < len(tok); num, pos = num+1, pos+3 {
+			off, _ := strconv.ParseInt(string(tok[pos]), 10, 64)
+			_, c := d.compressed[num]
+			_, o := d.offsets[num]
+			if string(tok[pos+2]) == "n" && !c && !o {
+				d.offsets[num] = off
+			}
+		}
+	}
+	return pdfDict(b[i:])
+}
+
+// readObjectAt returns the number and body of the object defined at off.
+func (d *pdfDoc) readObjectAt(off int64) (int, []byte) {
+	b := d.window(off, func(b []byte) bool { return bytes.Contains(b, []byte("endobj")) })
+	loc := rePDFObj.FindSubmatchIndex(b)
+	if loc == nil || len(bytes.TrimSpace(b[:loc[0]])) > 0 {
+		return 0, nil
+	}
+	num, _ := strconv.Atoi(string(b[loc[2]:loc[3]]))
+	body := b[loc[1]:]
+	if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+		body = body[:end]
+	}
+	return num, body
+}
+
+// body returns the direct definition of object num, reading it from the
+// file when the index was built sparsely.
+func (d *pdfDoc) body(num int) []byte {
+	if b, ok := d.direct[num]; ok || d.src == nil {
+		return b
+	}
+	off, ok := d.offsets[num]
+	if !ok {
+		return nil
+	}
+	n, b := d.readObjectAt(off)
+	if b == nil || n != num {
+		delete(d.offsets, num)
+		return nil
+	}
+	d.direct[num] = b
+	return b
+}
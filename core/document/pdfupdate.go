@@ -0,0 +1,275 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── PDF incremental update ──────────────────────────────────────────────────
+// A PDF can be changed without touching a byte of it: an incremental
+// update appends the new version of the changed objects, a cross-reference
+// section for them and a trailer pointing back at the previous one. It is
+// how signed PDFs stay signed and how archives keep every revision, and it
+// reaches an Info dictionary inside a compressed object stream, which the
+// in-place edit cannot. The update follows the file's own kind of
+// cross-reference: a classic xref table, or an xref stream for PDF 1.5+
+// files that use them.
+
+var optPDFIncremental = core.FormatOption{
+	Key:    "pdf.incremental",
+	Values: []string{"true", "false"},
+	Doc:    "append the Info changes as an incremental update, leaving the original bytes intact",
+}
+
+var (
+	rePDFInfoRefGen = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	rePDFRootFull   = regexp.MustCompile(`/Root\s+\d+\s+\d+\s+R`)
+	rePDFIDArray    = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	rePDFRefAt      = regexp.MustCompile(`^\d+\s+\d+\s+R\b`)
+)
+
+// appendPDFInfoUpdate returns data with an incremental update that sets
+// and deletes the given Info dictionary entries. Set values are text, or
+// PDF date strings for the date fields.
+func appendPDFInfoUpdate(data []byte, set map[string]string, del []string) ([]byte, error) {
+	doc := loadPDF(data)
+	if bytes.Contains(doc.trailer, []byte("/Encrypt")) {
+		return nil, fmt.Errorf("PDF is encrypted: an incremental update cannot write its strings")
+	}
+	starts := rePDFStartXRef.FindAllSubmatch(data, -1)
+	if len(starts) == 0 {
+		return nil, fmt.Errorf("PDF has no startxref: cannot append an incremental update")
+	}
+	prev, _ := strconv.Atoi(string(starts[len(starts)-1][1]))
+	root := rePDFRootFull.Find(doc.trailer)
+	size := pdfInt(doc.trailer, "Size")
+	if root == nil || size == 0 || prev >= len(data) {
+		return nil, fmt.Errorf("PDF trailer not found: cannot append an incremental update")
+	}
+
+	// A direct Info object is redefined under its own number; one packed
+	// in an object stream is replaced by a new object.
+	num, gen := size, 0
+	var old []byte
+	if m := rePDFInfoRefGen.FindSubmatch(doc.trailer); m != nil {
+		n, _ := strconv.Atoi(string(m[1]))
+		old = pdfDict(doc.object(n))
+		if _, packed := doc.compressed[n]; !packed && doc.body(n) != nil {
+			num = n
+			gen, _ = strconv.Atoi(string(m[2]))
+		}
+	}
+	newSize := size
+	if num == size {
+		newSize++
+	}
+
+	keys, vals := pdfDictEntries(old)
+	for _, k := range del {
+		delete(vals, k)
+	}
+	for _, k := range core.SortedKeys(set) {
+		if _, ok := vals[k]; !ok {
+			keys = append(keys, k)
+		}
+		vals[k] = []byte(pdfTextString(set[k]))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	infoOff := buf.Len()
+	fmt.Fprintf(&buf, "%d %d obj\n<<", num, gen)
+	for _, k := range keys {
+		if v, ok := vals[k]; ok {
+			fmt.Fprintf(&buf, "\n/%s %s", k, v)
+		}
+	}
+	buf.WriteString("\n>>\nendobj\n")
+
+	trailer := fmt.Sprintf("%s /Info %d %d R /Prev %d", root, num, gen, prev)
+	if id := rePDFIDArray.Find(doc.trailer); id != nil {
+		trailer += " " + string(id)
+	}
+	xrefOff := buf.Len()
+	if bytes.HasPrefix(bytes.TrimLeft(data[prev:], " \t\r\n"), []byte("xref")) {
+		fmt.Fprintf(&buf, "xref\n%d 1\n%010d %05d n \ntrailer\n<< /Size %d %s >>\n", num, infoOff, gen, newSize, trailer)
+	} else {
+		xrefNum := newSize
+		newSize++
+		buf.Write(pdfXRefStream(xrefNum, newSize, trailer, map[int][2]int{
+			num:     {infoOff, gen},
+			xrefNum: {xrefOff, 0},
+		}))
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOff)
+	return buf.Bytes(), nil
+}
+
+// pdfXRefStream returns object num, an uncompressed xref stream listing
+// the objects of entries (number → offset, generation) with the trailer
+// entries given.
+func pdfXRefStream(num, size int, trailer string, entries map[int][2]int) []byte {
+	nums := make([]int, 0, len(entries))
+	width := 4
+	for n, e := range entries {
+		nums = append(nums, n)
+		if e[0] > 0xFFFFFFFF {
+			width = 8
+		}
+	}
+	sort.Ints(nums)
+	var index []int // first number, count
+	var rows []byte
+	for i, n := range nums {
+		if i == 0 || n != nums[i-1]+1 {
+			index = append(index, n, 0)
+		}
+		index[len(index)-1]++
+		var off [8]byte
+		binary.BigEndian.PutUint64(off[:], uint64(entries[n][0]))
+		rows = append(rows, 1)
+		rows = append(rows, off[8-width:]...)
+		rows = append(rows, byte(entries[n][1]>>8), byte(entries[n][1]))
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d /W [1 %d 2] /Index [%s] %s /Length %d >>\nstream\n",
+		num, size, width, strings.Trim(fmt.Sprint(index), "[]"), trailer, len(rows))
+	buf.Write(rows)
+	buf.WriteString("\nendstream\nendobj\n")
+	return buf.Bytes()
+}
+
+// pdfTextString writes s as a PDF text string: a literal when it is
+// printable ASCII, otherwise UTF-16BE hex with a byte order mark.
+func pdfTextString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfDictEntries splits a dictionary into its keys, in order, and their
+// values as written.
+func pdfDictEntries(dict []byte) ([]string, map[string][]byte) {
+	vals := map[string][]byte{}
+	var keys []string
+	if len(dict) < 4 {
+		return keys, vals
+	}
+	b := dict[2 : len(dict)-2]
+	i := 0
+	for {
+		i = pdfSkipSpace(b, i)
+		if i >= len(b) || b[i] != '/' {
+			break
+		}
+		j := pdfNameEnd(b, i+1)
+		key := string(b[i+1 : j])
+		i = pdfSkipSpace(b, j)
+		end := pdfValueEnd(b, i)
+		if end <= i {
+			break
+		}
+		if _, dup := vals[key]; !dup {
+			keys = append(keys, key)
+		}
+		vals[key] = b[i:end]
+		i = end
+	}
+	return keys, vals
+}
+
+// pdfValueEnd returns the end of the value that starts at b[i].
+func pdfValueEnd(b []byte, i int) int {
+	if i >= len(b) {
+		return i
+	}
+	switch {
+	case b[i] == '(':
+		nest := 0
+		for ; i < len(b); i++ {
+			switch b[i] {
+			case '\\':
+				i++
+			case '(':
+				nest++
+			case ')':
+				if nest--; nest == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(b)
+	case bytes.HasPrefix(b[i:], []byte("<<")):
+		if d := pdfDict(b[i:]); d != nil {
+			return i + len(d)
+		}
+		return len(b)
+	case b[i] == '<':
+		if n := bytes.IndexByte(b[i:], '>'); n >= 0 {
+			return i + n + 1
+		}
+		return len(b)
+	case b[i] == '[':
+		depth := 0
+		for ; i < len(b); i++ {
+			switch b[i] {
+			case '(':
+				i = pdfValueEnd(b, i) - 1
+			case '[':
+				depth++
+			case ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(b)
+	case b[i] == '/':
+		return pdfNameEnd(b, i+1)
+	}
+	// A number, boolean, null or indirect reference.
+	if m := rePDFRefAt.Find(b[i:]); m != nil {
+		return i + len(m)
+	}
+	return pdfNameEnd(b, i)
+}
+
+// pdfNameEnd returns the end of the name or bare token starting at b[i].
+func pdfNameEnd(b []byte, i int) int {
+	for i < len(b) && !strings.ContainsRune(" \t\r\n\f\x00()<>[]{}/%", rune(b[i])) {
+		i++
+	}
+	return i
+}
+
+func pdfSkipSpace(b []byte, i int) int {
+	for i < len(b) && strings.ContainsRune(" \t\r\n\f\x00", rune(b[i])) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,207 @@
+package document
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── PPTX presentation audit ─────────────────────────────────────────────────
+// Before a deck goes out, the slide titles, speaker notes and the names of
+// everyone who left a comment are usually more sensitive than core.xml.
+//   ppt/slides/slideN.xml          title placeholder (type title / ctrTitle)
+//   ppt/notesSlides/notesSlideN.xml speaker notes (body placeholder)
+//   ppt/comments/commentN.xml       legacy comments → ppt/commentAuthors.xml
+//   ppt/comments/modernComment_*.xml threaded comments → ppt/authors.xml
+
+var reSlideNum = regexp.MustCompile(`(\d+)\.xml$`)
+
+// addPPTXInfo reports slides, notes and comment authors from the ppt/ parts.
+func addPPTXInfo(parts map[string][]byte, m *core.Metadata) {
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "Presentation", Editable: false})
+	}
+
+	var slides, notes, comments []string
+	for name := range parts {
+		switch {
+		case strings.HasPrefix(name, "ppt/slides/slide") && strings.HasSuffix(name, ".xml"):
+			slides = append(slides, name)
+		case strings.HasPrefix(name, "ppt/notesSlides/notesSlide") && strings.HasSuffix(name, ".xml"):
+			notes = append(notes, name)
+		case strings.HasPrefix(name, "ppt/comments/") && strings.HasSuffix(name, ".xml"):
+			comments = append(comments, name)
+		}
+	}
+	if len(slides) == 0 {
+		return
+	}
+	sort.Slice(slides, func(i, j int) bool { return partNumber(slides[i]) < partNumber(slides[j]) })
+
+	add("SlideCount", fmt.Sprintf("%d", len(slides)))
+	for i, name := range slides {
+		if title := placeholderText(parts[name], "title", "ctrTitle"); title != "" {
+			add(fmt.Sprintf("Slide%d", i+1), title)
+		}
+	}
+
+	withNotes := 0
+	for _, name := range notes {
+		if placeholderText(parts[name], "body") != "" {
+			withNotes++
+		}
+	}
+	if withNotes > 0 {
+		add("SlidesWithNotes", fmt.Sprintf("%d", withNotes))
+	}
+
+	count := 0
+	for _, name := range comments {
+		count += bytes.Count(parts[name], []byte("<p:cm ")) + bytes.Count(parts[name], []byte("<p188:cm "))
+	}
+	if count > 0 {
+		add("Comments", fmt.Sprintf("%d", count))
+	}
+	if authors := commentAuthors(parts); len(authors) > 0 {
+		add("CommentAuthors", strings.Join(authors, ", "))
+	}
+}
+
+func partNumber(name string) int {
+	m := reSlideNum.FindStringSubmatch(name)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// placeholderText returns the text of the first shape whose placeholder type
+// is one of types.
+func placeholderText(data []byte, types ...string) string {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var text strings.Builder
+	inShape, match := false, false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				inShape, match = true, false
+				text.Reset()
+			case "ph":
+				for _, a := range t.Attr {
+					if a.Name.Local == "type" {
+						for _, want := range types {
+							match = match || a.Value == want
+						}
+					}
+				}
+			case "t":
+				if inShape {
+					var s string
+					dec.DecodeElement(&s, &t)
+					text.WriteString(s)
+				}
+			case "p":
+				if inShape && text.Len() > 0 {
+					text.WriteString(" ")
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "sp" && inShape {
+				if match {
+					return strings.TrimSpace(text.String())
+				}
+				inShape = false
+			}
+		}
+	}
+}
+
+// commentAuthors lists the names in commentAuthors.xml and authors.xml.
+func commentAuthors(parts map[string][]byte) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range []string{"ppt/commentAuthors.xml", "ppt/authors.xml"} {
+		dec := xml.NewDecoder(bytes.NewReader(parts[part]))
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			el, ok := tok.(xml.StartElement)
+			if !ok || (el.Name.Local != "cmAuthor" && el.Name.Local != "author") {
+				continue
+			}
+			for _, a := range el.Attr {
+				if a.Name.Local == "name" && a.Value != "" && !seen[a.Value] {
+					seen[a.Value] = true
+					names = append(names, a.Value)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ─── Dropping parts ──────────────────────────────────────────────────────────
+
+// pptxDropPart reports whether a zip entry belongs to a section named in
+// remove ("notes", "comments").
+func pptxDropPart(name string, remove map[string]bool) bool {
+	switch {
+	case remove["notes"] && strings.HasPrefix(name, "ppt/notesSlides/"):
+		return true
+	case remove["comments"] && (strings.HasPrefix(name, "ppt/comments/") ||
+		name == "ppt/commentAuthors.xml" || name == "ppt/authors.xml"):
+		return true
+	}
+	return false
+}
+
+// dropRelationships removes relationships in a .rels part whose target is
+// a dropped part, so the package has no dangling references.
+func dropRelationships(relsName string, data []byte, drop func(string) bool) []byte {
+	// ppt/slides/_rels/slide1.xml.rels → targets are relative to ppt/slides/
+	base := path.Dir(path.Dir(relsName))
+	return reOPCRel.ReplaceAllFunc(data, func(rel []byte) []byte {
+		if xmlAttr(rel, "TargetMode") == "External" {
+			return rel
+		}
+		target := xmlAttr(rel, "Target")
+		var resolved string
+		if strings.HasPrefix(target, "/") {
+			resolved = strings.TrimPrefix(target, "/")
+		} else {
+			resolved = path.Clean(path.Join(base, target))
+		}
+		if drop(resolved) {
+			return nil
+		}
+		return rel
+	})
+}
+
+var reOPCOverride = regexp.MustCompile(`<Override\b[^>]*>`)
+
+// dropOverrides removes [Content_Types].xml overrides for dropped parts.
+func dropOverrides(data []byte, drop func(string) bool) []byte {
+	return reOPCOverride.ReplaceAllFunc(data, func(el []byte) []byte {
+		if drop(strings.TrimPrefix(xmlAttr(el, "PartName"), "/")) {
+			return nil
+		}
+		return el
+	})
+}
@@ -0,0 +1,144 @@
+package document
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── XLSX workbook audit ─────────────────────────────────────────────────────
+// Spreadsheets leak more than core.xml: sheet names, hidden and "very
+// hidden" sheets (only reachable from VBA), defined names pointing at other
+// files, and external workbook links. View reports them; strip can set the
+// "remove personal information on save" flag.
+
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		FilterPrivacy string `xml:"filterPrivacy,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		State string `xml:"state,attr"`
+	} `xml:"sheets>sheet"`
+	DefinedNames []struct {
+		Name   string `xml:"name,attr"`
+		Hidden string `xml:"hidden,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"definedNames>definedName"`
+}
+
+func addXLSXWorkbook(data []byte, m *core.Metadata) {
+	var wb xlsxWorkbook
+	if err := xml.Unmarshal(data, &wb); err != nil {
+		return
+	}
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "Workbook", Editable: false})
+	}
+
+	var names, hidden, veryHidden []string
+	for _, s := range wb.Sheets {
+		names = append(names, s.Name)
+		switch s.State {
+		case "hidden":
+			hidden = append(hidden, s.Name)
+		case "veryHidden":
+			veryHidden = append(veryHidden, s.Name)
+		}
+	}
+	add("SheetCount", fmt.Sprintf("%d", len(wb.Sheets)))
+	if len(names) > 0 {
+		add("Sheets", strings.Join(names, ", "))
+	}
+	if len(hidden) > 0 {
+		add("HiddenSheets", strings.Join(hidden, ", "))
+	}
+	if len(veryHidden) > 0 {
+		add("VeryHiddenSheets", strings.Join(veryHidden, ", "))
+	}
+
+	for _, dn := range wb.DefinedNames {
+		key := "DefinedName"
+		if dn.Hidden == "1" || dn.Hidden == "true" {
+			key = "HiddenDefinedName"
+		}
+		add(key, dn.Name+" = "+strings.TrimSpace(dn.Value))
+	}
+
+	if p := wb.WorkbookPr.FilterPrivacy; p == "1" || p == "true" {
+		add("RemovePersonalInfo", "on")
+	}
+}
+
+// addXLSXExternalLink reports the target of an external workbook link from
+// its xl/externalLinks/_rels/externalLinkN.xml.rels part.
+func addXLSXExternalLink(rels []byte, m *core.Metadata) {
+	for _, rel := range reOPCRel.FindAll(rels, -1) {
+		if xmlAttr(rel, "TargetMode") != "External" {
+			continue
+		}
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "ExternalLink",
+			Value:    xmlAttr(rel, "Target"),
+			Category: "Workbook",
+			Editable: false,
+		})
+	}
+}
+
+// ─── Personal-information flag ───────────────────────────────────────────────
+
+var (
+	reXLSXWorkbookPr = regexp.MustCompile(`<((?:\w+:)?)workbookPr\b([^>]*?)(/?)>`)
+	reXLSXFilterPriv = regexp.MustCompile(`\sfilterPrivacy\s*=\s*["'][^"']*["']`)
+	reXLSXAfterPr    = regexp.MustCompile(`<((?:\w+:)?)(?:fileSharing|fileVersion)\b[^>]*?/>|<((?:\w+:)?)workbook\b[^>]*>`)
+)
+
+// setXLSXFilterPrivacy sets workbookPr/@filterPrivacy="1" in xl/workbook.xml.
+func setXLSXFilterPrivacy(data []byte) []byte {
+	if loc := reXLSXWorkbookPr.FindSubmatchIndex(data); loc != nil {
+		attrs := reXLSXFilterPriv.ReplaceAll(data[loc[4]:loc[5]], nil)
+		newTag := fmt.Sprintf(`<%sworkbookPr filterPrivacy="1"%s%s>`, data[loc[2]:loc[3]], attrs, data[loc[6]:loc[7]])
+		return append(append(append([]byte{}, data[:loc[0]]...), newTag...), data[loc[1]:]...)
+	}
+	// No workbookPr: it goes after fileVersion/fileSharing, or first.
+	all := reXLSXAfterPr.FindAllSubmatchIndex(data, -1)
+	if all == nil {
+		return data
+	}
+	last := all[len(all)-1]
+	prefix := ""
+	if last[2] >= 0 {
+		prefix = string(data[last[2]:last[3]])
+	} else if last[4] >= 0 {
+		prefix = string(data[last[4]:last[5]])
+	}
+	el := fmt.Sprintf(`<%sworkbookPr filterPrivacy="1"/>`, prefix)
+	return append(append(append([]byte{}, data[:last[1]]...), el...), data[last[1]:]...)
+}
+
+var reDOCXSettingsAnchor = regexp.MustCompile(`<w:(?:zoom|view|writeProtection)\b[^>]*?/>|<w:settings\b[^>]*>`)
+
+// setDOCXRemovePersonalInfo adds <w:removePersonalInformation/> to
+// word/settings.xml, after zoom/view/writeProtection as the schema orders it.
+func setDOCXRemovePersonalInfo(data []byte) []byte {
+	if strings.Contains(string(data), "<w:removePersonalInformation") {
+		return data
+	}
+	all := reDOCXSettingsAnchor.FindAllIndex(data, -1)
+	if all == nil {
+		return data
+	}
+	// Prefer the latest of zoom/view/writeProtection; fall back to the root.
+	at := all[0][1]
+	for _, loc := range all {
+		if !strings.HasPrefix(string(data[loc[0]:loc[1]]), "<w:settings") {
+			at = loc[1]
+		}
+	}
+	el := "<w:removePersonalInformation/>"
+	return append(append(append([]byte{}, data[:at]...), el...), data[at:]...)
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HasConditional reports whether opts holds operations that must be
+// resolved against the file's current metadata.
+func (o EditOptions) HasConditional() bool {
+	return len(o.SetIfMissing) > 0 || len(o.Append) > 0 || len(o.Prefix) > 0 ||
+		len(o.Suffix) > 0 || len(o.ClearIfEquals) > 0 || len(o.Replace) > 0 ||
+		len(o.Transforms) > 0 || o.NumberPad > 0
+}
+
+// HasChanges reports whether opts would change anything.
+func (o EditOptions) HasChanges() bool {
+	return len(o.Set) > 0 || len(o.Add) > 0 || len(o.Delete) > 0 || o.TouchModified || o.HasConditional()
+}
+
+// CheckAdd returns an error when opts adds values (KEY+=VALUE) to a file
+// whose fields hold one value each. Only Vorbis comments, in FLAC, Ogg
+// Vorbis and Opus, repeat keys.
+func CheckAdd(path string, opts EditOptions) error {
+	if len(opts.Add) == 0 {
+		return nil
+	}
+	switch f, _ := DetectFormat(path); f {
+	case FmtFLAC, FmtOGG, FmtOpus:
+		return nil
+	default:
+		return fmt.Errorf("%s+=%s: only FLAC, Ogg Vorbis and Opus fields take more than one value", opts.Add[0].Key, opts.Add[0].Value)
+	}
+}
+
+// SortedKeys returns the keys of m in order. Writers iterate fields this
+// way so that the same edit always produces the same bytes.
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResolveEditOps turns the conditional operations in opts into plain Set
+// and Delete entries, using current (as returned by View) for the existing
+// values. Keys match case-insensitively; editable fields win over
+// read-only ones of the same name. Explicit Set entries are left alone.
+func ResolveEditOps(opts EditOptions, current *Metadata) EditOptions {
+	lookup := func(key string) (string, bool) {
+		found, val := false, ""
+		for _, f := range current.Fields {
+			if !strings.EqualFold(f.Key, key) {
+				continue
+			}
+			if f.Editable {
+				return f.Value, true
+			}
+			if !found {
+				found, val = true, f.Value
+			}
+		}
+		return val, found
+	}
+
+	out := opts
+	out.Set = make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		out.Set[k] = v
+	}
+	out.Delete = append([]string(nil), opts.Delete...)
+	out.SetIfMissing, out.Append, out.Prefix, out.Suffix, out.ClearIfEquals = nil, nil, nil, nil, nil
+	out.Replace, out.Transforms = nil, nil
+
+	set := func(k, v string) {
+		if _, explicit := opts.Set[k]; !explicit {
+			out.Set[k] = v
+		}
+	}
+	for k, v := range opts.SetIfMissing {
+		if cur, ok := lookup(k); !ok || strings.TrimSpace(cur) == "" {
+			set(k, v)
+		}
+	}
+	for k, v := range opts.Append {
+		if cur, ok := lookup(k); ok && cur != "" {
+			set(k, cur+" "+v)
+		} else {
+			set(k, v)
+		}
+	}
+	for k, v := range opts.Prefix {
+		if cur, ok := lookup(k); ok && cur != "" {
+			set(k, v+cur)
+		}
+	}
+	for k, v := range opts.Suffix {
+		if cur, ok := lookup(k); ok && cur != "" {
+			if pre, ok := out.Set[k]; ok && opts.Prefix[k] != "" {
+				cur = pre // prefix and suffix on the same field combine
+			}
+			set(k, cur+v)
+		}
+	}
+	for k, v := range opts.ClearIfEquals {
+		if cur, ok := lookup(k); ok && cur == v {
+			out.Delete = append(out.Delete, k)
+		}
+	}
+
+	// Substitutions and transforms run last and in order, each seeing the
+	// result of the previous one, so they chain with the operations above.
+	rewrite := func(key string, fn func(string) string) {
+		keys := []string{key}
+		if key == "*" {
+			keys = keys[:0]
+			for _, f := range current.Fields {
+				if f.Editable {
+					keys = append(keys, f.Key)
+				}
+			}
+		}
+		for _, k := range keys {
+			cur, ok := out.Set[k]
+			if !ok {
+				if cur, ok = lookup(k); !ok {
+					continue
+				}
+			}
+			if v := fn(cur); v != cur {
+				out.Set[k] = v
+			}
+		}
+	}
+	for _, r := range opts.Replace {
+		rewrite(r.Key, r.apply)
+	}
+	for _, t := range opts.Transforms {
+		rewrite(t.Key, t.apply)
+	}
+
+	// Padding applies to the existing numbers too, so a batch run can
+	// re-pad a whole library; the handler pads explicit values itself.
+	if opts.NumberPad > 0 {
+		for _, k := range []string{"TrackNumber", "DiscNumber"} {
+			explicit := false
+			for sk := range out.Set {
+				explicit = explicit || strings.EqualFold(sk, k)
+			}
+			if cur, ok := lookup(k); ok && !explicit {
+				if v := PadNumber(cur, opts.NumberPad); v != cur {
+					out.Set[k] = v
+				}
+			}
+		}
+	}
+	return out
+}
+
+// PadNumber zero-pads each numeric part of a track or disc number ("3" or
+// "3/12") to width digits. Non-numeric parts are returned unchanged.
+func PadNumber(v string, width int) string {
+	if width <= 0 {
+		return v
+	}
+	parts := strings.Split(v, "/")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			continue
+		}
+		if len(p) < width {
+			p = strings.Repeat("0", width-len(p)) + p
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, "/")
+}
+
+// ─── Regex replacement ───────────────────────────────────────────────────────
+
+// ValueReplace is a sed-style substitution on one field ("*" for every
+// editable field).
+type ValueReplace struct {
+	Key         string
+	Pattern     *regexp.Regexp
+	Replacement string // Go template syntax: $1, ${name}
+	Global      bool
+}
+
+// ParseReplace parses "Key:s/pattern/replacement/flags". Any character
+// may follow "s" as the delimiter and can be escaped with a backslash.
+// Flags: g (every match), i (ignore case). \1 … \9 in the replacement
+// refer to capture groups, as in sed.
+func ParseReplace(spec string) (ValueReplace, error) {
+	var r ValueReplace
+	colon := strings.Index(spec, ":")
+	if colon < 1 || len(spec) < colon+3 || spec[colon+1] != 's' {
+		return r, fmt.Errorf("invalid replace %q — expected Key:s/pattern/replacement/", spec)
+	}
+	r.Key = strings.TrimSpace(spec[:colon])
+	delim := spec[colon+2]
+	parts := splitUnescaped(spec[colon+3:], delim)
+	if len(parts) == 2 {
+		parts = append(parts, "") // closing delimiter omitted
+	}
+	if len(parts) != 3 {
+		return r, fmt.Errorf("invalid replace %q — expected Key:s/pattern/replacement/", spec)
+	}
+	pattern, repl, flags := parts[0], parts[1], parts[2]
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			r.Global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return r, fmt.Errorf("invalid replace flag %q in %q", f, spec)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return r, fmt.Errorf("invalid replace pattern in %q: %w", spec, err)
+	}
+	r.Pattern = re
+	r.Replacement = regexp.MustCompile(`\\([0-9])`).ReplaceAllString(repl, "$${$1}")
+	return r, nil
+}
+
+func (r ValueReplace) apply(s string) string {
+	if r.Global {
+		return r.Pattern.ReplaceAllString(s, r.Replacement)
+	}
+	loc := r.Pattern.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s
+	}
+	dst := r.Pattern.ExpandString(nil, r.Replacement, s, loc)
+	return s[:loc[0]] + string(dst) + s[loc[1]:]
+}
+
+// splitUnescaped splits s on delim, turning "\<delim>" into a literal
+// delimiter and leaving other escapes for the regexp engine.
+func splitUnescaped(s string, delim byte) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			cur.WriteByte(delim)
+			i++
+		case s[i] == '\\' && i+1 < len(s):
+			cur.WriteByte(s[i])
+			cur.WriteByte(s[i+1])
+			i++
+		case s[i] == delim:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(parts, cur.String())
+}
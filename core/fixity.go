@@ -0,0 +1,90 @@
+package core
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ─── Payload checksums ───────────────────────────────────────────────────────
+// A checksum of the whole file changes with every tag edit, which makes it
+// useless for checking an archive whose tags are still being curated. The
+// payload — the audio frames or image data that metadata edits never
+// touch — is hashed instead and the digest stored in the file's own
+// metadata, where verify can find it later.
+
+// PayloadReader is implemented by handlers that can return the payload of
+// a file: the bytes that editing or stripping metadata leaves unchanged.
+type PayloadReader interface {
+	Payload(path string) ([]byte, error)
+}
+
+// checksumAlgos maps an algorithm name to its field name and hash.
+var checksumAlgos = map[string]struct {
+	field string
+	new   func() hash.Hash
+}{
+	"sha256": {"PayloadSHA256", sha256.New},
+	"md5":    {"PayloadMD5", md5.New},
+}
+
+// PayloadChecksum returns the field name and hex digest of the payload of
+// path for algo ("sha256" or "md5").
+func PayloadChecksum(h Handler, path, algo string) (field, sum string, err error) {
+	a, ok := checksumAlgos[strings.ToLower(algo)]
+	if !ok {
+		return "", "", fmt.Errorf("unknown checksum %q (want sha256 or md5)", algo)
+	}
+	pr, ok := h.(PayloadReader)
+	if !ok {
+		return "", "", fmt.Errorf("%s does not support payload checksums", h.Info().Name)
+	}
+	payload, err := pr.Payload(path)
+	if err != nil {
+		return "", "", err
+	}
+	if payload == nil {
+		return "", "", fmt.Errorf("%s does not support payload checksums", h.Info().Name)
+	}
+	d := a.new()
+	d.Write(payload)
+	return a.field, hex.EncodeToString(d.Sum(nil)), nil
+}
+
+// ChecksumResult is the outcome of checking one stored checksum.
+type ChecksumResult struct {
+	Field    string
+	Stored   string
+	Computed string
+}
+
+// OK reports whether the stored and computed digests match.
+func (r ChecksumResult) OK() bool { return strings.EqualFold(r.Stored, r.Computed) }
+
+// VerifyPayload recomputes every payload checksum stored in m. It returns
+// no results when the file has none.
+func VerifyPayload(h Handler, path string, m *Metadata) ([]ChecksumResult, error) {
+	var results []ChecksumResult
+	for _, algo := range []string{"sha256", "md5"} {
+		field := checksumAlgos[algo].field
+		stored := ""
+		for _, f := range m.Fields {
+			if strings.EqualFold(f.Key, field) {
+				stored = strings.TrimSpace(f.Value)
+				break
+			}
+		}
+		if stored == "" {
+			continue
+		}
+		_, sum, err := PayloadChecksum(h, path, algo)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ChecksumResult{Field: field, Stored: stored, Computed: sum})
+	}
+	return results, nil
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ─── Format options ──────────────────────────────────────────────────────────
+// Some choices only make sense for one format: the ID3 version an MP3 is
+// saved as, the PNG chunk type new text goes into, whether a PDF edit is
+// appended as an incremental update. Rather than one more field in
+// EditOptions and StripOptions for each, they travel in the Options map
+// under a "format.name" key. A handler lists the keys it understands in
+// FormatInfo.Options and ignores the rest, so one set of options can be
+// passed to a batch of mixed formats; CheckOptions catches a misspelt key
+// or value before anything is written.
+
+// FormatOption describes one key a handler accepts in Options.
+type FormatOption struct {
+	Key    string   // "id3.version"
+	Values []string // accepted values, compared case-insensitively
+	Strip  bool     // the option applies to strip rather than edit
+	Doc    string   // one line for help output
+}
+
+// Value returns the value of the option in opts, "" when it is not set,
+// after checking it against the values o accepts.
+func (o FormatOption) Value(opts map[string]string) (string, error) {
+	v, ok := opts[o.Key]
+	if !ok {
+		return "", nil
+	}
+	for _, a := range o.Values {
+		if strings.EqualFold(a, v) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("option %s: %q is not one of %s", o.Key, v, strings.Join(o.Values, ", "))
+}
+
+// CheckOptions reports an error for the first key of opts that none of
+// infos accepts for the operation (strip or edit), or whose value the
+// format does not accept. Pass the FormatInfo of the one handler that will
+// run, or of every handler a batch may reach.
+func CheckOptions(opts map[string]string, strip bool, infos ...FormatInfo) error {
+	for _, k := range SortedKeys(opts) {
+		var known *FormatOption
+		for _, info := range infos {
+			for i, o := range info.Options {
+				if o.Key == k && o.Strip == strip {
+					known = &info.Options[i]
+				}
+			}
+		}
+		if known == nil {
+			return fmt.Errorf("unknown option %q%s", k, knownOptions(strip, infos))
+		}
+		if _, err := known.Value(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knownOptions lists the keys infos accept for the operation, as the end
+// of an error message.
+func knownOptions(strip bool, infos []FormatInfo) string {
+	seen := map[string]bool{}
+	for _, info := range infos {
+		for _, o := range info.Options {
+			if o.Strip == strip {
+				seen[o.Key] = true
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return " (no options apply)"
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return " (known: " + strings.Join(keys, ", ") + ")"
+}
+
+// CheckOptionValues checks the value in opts of each option fi declares
+// for the operation, ignoring the keys of other formats. Handlers call it
+// before they write when the code that reads an option cannot fail.
+func (fi FormatInfo) CheckOptionValues(opts map[string]string, strip bool) error {
+	for _, o := range fi.Options {
+		if o.Strip != strip {
+			continue
+		}
+		if _, err := o.Value(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
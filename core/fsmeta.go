@@ -0,0 +1,265 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ─── Filesystem metadata ─────────────────────────────────────────────────────
+// Some metadata lives beside a file rather than in it: macOS Finder tags
+// and the quarantine record of a download, Linux user.* extended
+// attributes such as the origin URL a browser saves, and the Windows
+// Zone.Identifier stream. A stripped file still carries these wherever it
+// is copied with its attributes. They are shown in a "Filesystem" category
+// and removed by a full strip.
+
+// fsCategory is the category of fields read from filesystem metadata.
+const fsCategory = "Filesystem"
+
+// fsReserved lists attribute namespaces that belong to the system (SELinux
+// labels, ACLs, capabilities) or cannot be removed by the file's owner.
+// They are neither shown nor stripped.
+var fsReserved = []string{"security.", "system.", "trusted.", "com.apple.provenance"}
+
+// FSAttrs returns the names of the filesystem attributes of path that
+// surgery shows and strips.
+func FSAttrs(path string) []string {
+	names, _ := listXattrs(path)
+	var out []string
+	for _, name := range names {
+		if !fsIsReserved(name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func fsIsReserved(name string) bool {
+	for _, p := range fsReserved {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// FSFields returns the filesystem attributes of path as fields in the
+// "Filesystem" category. Known attributes are decoded; other values are
+// shown as text when printable.
+func FSFields(path string) []MetaField {
+	var fields []MetaField
+	for _, name := range FSAttrs(path) {
+		v, err := getXattr(path, name)
+		if err != nil {
+			continue
+		}
+		fields = append(fields, MetaField{
+			Key:      name,
+			Value:    fsValue(name, v),
+			Category: fsCategory,
+			Raw:      fmt.Sprintf("%x", v),
+		})
+	}
+	return fields
+}
+
+// AddFSFields appends the filesystem attributes of path to m.
+func AddFSFields(m *Metadata, path string) {
+	m.Fields = append(m.Fields, FSFields(path)...)
+}
+
+// StripFSMetadata removes the filesystem attributes of path and returns the
+// names removed. It tries every attribute and returns the first error.
+func StripFSMetadata(path string) ([]string, error) {
+	var removed []string
+	var first error
+	for _, name := range FSAttrs(path) {
+		if err := removeXattr(path, name); err != nil {
+			if first == nil {
+				first = fmt.Errorf("cannot remove %s from %s: %w", name, path, err)
+			}
+			continue
+		}
+		removed = append(removed, name)
+	}
+	return removed, first
+}
+
+// fsValue decodes the value of the attribute name for display. The
+// macOS attributes copied to Linux (by Samba or netatalk) keep their names
+// under the user. namespace.
+func fsValue(name string, v []byte) string {
+	switch strings.TrimPrefix(name, "user.") {
+	case "com.apple.metadata:_kMDItemUserTags":
+		tags := bplistStrings(v)
+		for i, t := range tags {
+			// A Finder tag is stored as "name\n<colour number>".
+			if j := strings.IndexByte(t, '\n'); j >= 0 {
+				tags[i] = t[:j]
+			}
+		}
+		if tags != nil {
+			return strings.Join(tags, ", ")
+		}
+	case "com.apple.metadata:kMDItemWhereFroms":
+		if urls := bplistStrings(v); urls != nil {
+			return strings.Join(urls, ", ")
+		}
+	case "com.apple.quarantine":
+		return quarantineValue(string(v))
+	case "Zone.Identifier":
+		return zoneValue(string(v))
+	}
+	s := strings.TrimRight(string(v), "\x00")
+	if utf8.ValidString(s) && !strings.ContainsFunc(s, func(r rune) bool { return r < 0x20 && r != '\t' }) {
+		return s
+	}
+	return fmt.Sprintf("(binary, %d bytes)", len(v))
+}
+
+// quarantineValue decodes a com.apple.quarantine record,
+// "flags;hex timestamp;agent;event UUID", into the downloading application
+// and the time of the download.
+func quarantineValue(s string) string {
+	parts := strings.Split(s, ";")
+	if len(parts) < 3 {
+		return s
+	}
+	out := parts[2]
+	if out == "" {
+		out = "unknown agent"
+	}
+	if sec, err := strconv.ParseInt(parts[1], 16, 64); err == nil && sec > 0 {
+		out += ", downloaded " + time.Unix(sec, 0).UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
+// zoneValue joins the key=value lines of a Zone.Identifier stream, naming
+// the zone.
+func zoneValue(s string) string {
+	zones := map[string]string{"0": "local", "1": "intranet", "2": "trusted", "3": "internet", "4": "restricted"}
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok && k == "ZoneId" && zones[v] != "" {
+			line += " (" + zones[v] + ")"
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "; ")
+}
+
+// bplistStrings returns the strings of a binary property list whose top
+// object is a string or an array of strings, as macOS stores Finder tags
+// and download origins. It returns nil for anything else.
+func bplistStrings(b []byte) []string {
+	if len(b) < 8+32 || string(b[:8]) != "bplist00" {
+		return nil
+	}
+	trailer := b[len(b)-32:]
+	offSize, refSize := int(trailer[6]), int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	top := binary.BigEndian.Uint64(trailer[16:24])
+	table := binary.BigEndian.Uint64(trailer[24:32])
+	if offSize < 1 || offSize > 8 || refSize < 1 || refSize > 8 || top >= numObjects ||
+		table >= uint64(len(b)) || numObjects > (uint64(len(b))-table)/uint64(offSize) {
+		return nil
+	}
+	object := func(ref uint64) int {
+		if ref >= numObjects {
+			return -1
+		}
+		p := int(table) + int(ref)*offSize
+		off := int(beUint(b[p : p+offSize]))
+		if off >= len(b)-32 {
+			return -1
+		}
+		return off
+	}
+	// length reads the length of the object at off: the low nibble of its
+	// marker, or an int object after it when the nibble is 0xF.
+	length := func(off int) (n, start int, ok bool) {
+		n, start = int(b[off]&0x0F), off+1
+		if n != 0x0F {
+			return n, start, true
+		}
+		if start >= len(b) || b[start]>>4 != 0x1 {
+			return 0, 0, false
+		}
+		size := 1 << (b[start] & 0x0F)
+		if size > 8 || start+1+size > len(b) {
+			return 0, 0, false
+		}
+		return int(beUint(b[start+1 : start+1+size])), start + 1 + size, true
+	}
+	str := func(off int) (string, bool) {
+		n, start, ok := length(off)
+		if !ok {
+			return "", false
+		}
+		switch b[off] >> 4 {
+		case 0x5: // ASCII
+			if n < 0 || start+n > len(b) {
+				return "", false
+			}
+			return string(b[start : start+n]), true
+		case 0x6: // UTF-16BE
+			if n < 0 || start+2*n > len(b) {
+				return "", false
+			}
+			u := make([]uint16, n)
+			for i := range u {
+				u[i] = binary.BigEndian.Uint16(b[start+2*i:])
+			}
+			return string(utf16.Decode(u)), true
+		}
+		return "", false
+	}
+
+	off := object(top)
+	if off < 0 {
+		return nil
+	}
+	if s, ok := str(off); ok {
+		return []string{s}
+	}
+	if b[off]>>4 != 0xA {
+		return nil
+	}
+	n, start, ok := length(off)
+	if !ok || n < 0 || start+n*refSize > len(b) {
+		return nil
+	}
+	out := []string{}
+	for i := 0; i < n; i++ {
+		p := start + i*refSize
+		o := object(beUint(b[p : p+refSize]))
+		if o < 0 {
+			return nil
+		}
+		s, ok := str(o)
+		if !ok {
+			return nil
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// beUint reads a big-endian unsigned integer of up to 8 bytes.
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
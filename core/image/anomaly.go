@@ -0,0 +1,395 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Anomaly scan ────────────────────────────────────────────────────────────
+// The scanners walk the file's structure by offset rather than through the
+// parsers the other commands use, because those stop at the end of the
+// image or skip what they do not know — exactly where hidden data sits.
+// Each reports where it found something, so the bytes can be carved out.
+
+// anomalyScans holds the scanner of each format that has one.
+var anomalyScans = map[core.FormatID]func([]byte) ([]string, error){
+	core.FmtJPEG: jpegAnomalies,
+	core.FmtPNG:  pngAnomalies,
+	core.FmtGIF:  gifAnomalies,
+	core.FmtWebP: webpAnomalies,
+}
+
+// Anomalies scans a JPEG, PNG, GIF or WebP for structures used to hide
+// data: bytes after the end of the image, duplicated EXIF blocks, oversized
+// comments, unknown chunks, and text fields that look like ciphertext.
+func (h *Handler) Anomalies(path string) ([]string, error) {
+	scan, ok := anomalyScans[h.format]
+	if !ok {
+		return nil, fmt.Errorf("anomaly scan supports JPEG, PNG, GIF and WebP, not %s", strings.ToUpper(string(h.format)))
+	}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return scan(data)
+}
+
+// textAnomalies checks a comment or text field: its size and whether its
+// content looks like a payload.
+func textAnomalies(where string, text []byte) []string {
+	var out []string
+	if len(text) > core.OversizedText {
+		out = append(out, fmt.Sprintf("%s: %d bytes of text, far longer than a real comment", where, len(text)))
+	}
+	if sign := core.PayloadSign(text); sign != "" {
+		out = append(out, where+": "+sign)
+	}
+	return out
+}
+
+// payloadAnomaly checks a metadata block that should hold structured
+// values.
+func payloadAnomaly(where string, b []byte) []string {
+	if sign := core.PayloadSign(b); sign != "" {
+		return []string{where + ": " + sign}
+	}
+	return nil
+}
+
+// jpegKnownAPP lists the identifiers of the APPn segments whose content is
+// binary by design (thumbnails, profiles, second images); they are not
+// judged by entropy.
+var jpegKnownAPP = []struct {
+	marker byte
+	prefix string
+}{
+	{0xE0, "JFIF\x00"},
+	{0xE0, "JFXX\x00"},
+	{0xE1, "Exif\x00\x00"},
+	{0xE1, "http://ns.adobe.com/xmp/extension/\x00"},
+	{0xE2, jpegICCPrefix},
+	{0xE2, "MPF\x00"},
+	{0xE2, "FPXR\x00"},
+	{0xEC, "Ducky"},
+	{0xED, "Photoshop 3.0\x00"},
+	{0xEE, "Adobe"},
+}
+
+func isKnownJPEGAPP(marker byte, data []byte) bool {
+	for _, k := range jpegKnownAPP {
+		if k.marker == marker && bytes.HasPrefix(data, []byte(k.prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// jpegAnomalies walks the markers of a JPEG, through the scans, to its EOI.
+// Entropy-coded data is skipped byte by byte: there 0xFF is followed by a
+// stuffed 0x00 or a restart marker, and any other marker ends the scan.
+func jpegAnomalies(data []byte) ([]string, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG")
+	}
+	var out []string
+	firstEXIF, hasMPF, end := -1, false, 0
+	i := 2
+walk:
+	for i+1 < len(data) {
+		if data[i] != 0xFF {
+			i++
+			continue
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xD9:
+			end = i + 2
+			break walk
+		case marker == 0x00, marker == 0xFF, marker == 0x01, marker == 0xD8, marker >= 0xD0 && marker <= 0xD7:
+			i++ // stuffing, fill or a marker without a length
+			continue
+		}
+		if i+4 > len(data) {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			out = append(out, fmt.Sprintf("JPEG marker 0x%02X at offset %d: length %d runs past the end of the file", marker, i, n))
+			return out, nil
+		}
+		seg := jpegSegment{marker: marker, data: data[i+4 : i+2+n]}
+		where := fmt.Sprintf("JPEG %s segment at offset %d", jpegSegmentName(seg), i)
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")):
+			if firstEXIF >= 0 {
+				out = append(out, fmt.Sprintf("%s: another EXIF block (the first is at offset %d) — readers disagree on which one applies", where, firstEXIF))
+			} else {
+				firstEXIF = i
+			}
+		case marker == 0xE2 && bytes.HasPrefix(seg.data, []byte("MPF\x00")):
+			hasMPF = true
+		case marker == 0xFE:
+			out = append(out, textAnomalies(where, seg.data)...)
+		case marker >= 0xE0 && marker <= 0xEF && !isKnownJPEGAPP(marker, seg.data):
+			out = append(out, payloadAnomaly(where, seg.data)...)
+		}
+		i += 2 + n
+	}
+	switch {
+	case end == 0:
+		out = append(out, "JPEG ends without an EOI marker")
+	case end < len(data) && !hasMPF: // MPF images follow the first one by design
+		out = append(out, fmt.Sprintf("JPEG EOI at offset %d: %s", end-2, core.TrailerSign(data[end:])))
+	}
+	return out, nil
+}
+
+// pngKnownChunks lists the chunk types of the PNG specification and its
+// registered extensions, and a few private chunks common encoders write.
+var pngKnownChunks = map[string]bool{
+	"IHDR": true, "PLTE": true, "IDAT": true, "IEND": true, "tRNS": true,
+	"cHRM": true, "gAMA": true, "iCCP": true, "sBIT": true, "sRGB": true,
+	"cICP": true, "mDCV": true, "cLLI": true, "tEXt": true, "zTXt": true,
+	"iTXt": true, "bKGD": true, "hIST": true, "pHYs": true, "sPLT": true,
+	"eXIf": true, "tIME": true, "acTL": true, "fcTL": true, "fdAT": true,
+	"oFFs": true, "pCAL": true, "sCAL": true, "sTER": true, "gIFg": true,
+	"gIFx": true, "dSIG": true,
+	"iDOT": true, "CgBI": true, "vpAg": true, "caNv": true, "orNT": true,
+}
+
+// pngTextParts splits a tEXt, zTXt or iTXt chunk into its keyword and its
+// text, reporting whether the text is compressed.
+func pngTextParts(typ string, data []byte) (keyword string, text []byte, compressed bool) {
+	k, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return string(data), nil, false
+	}
+	switch typ {
+	case "zTXt":
+		return string(k), rest, true
+	case "iTXt":
+		if len(rest) < 2 {
+			return string(k), nil, false
+		}
+		compressed = rest[0] == 1
+		// language tag and translated keyword, both NUL-terminated
+		if _, rest, ok = bytes.Cut(rest[2:], []byte{0}); ok {
+			_, rest, _ = bytes.Cut(rest, []byte{0})
+		}
+		return string(k), rest, compressed
+	}
+	return string(k), rest, false
+}
+
+// pngAnomalies walks the chunks of a PNG to its IEND.
+func pngAnomalies(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a valid PNG")
+	}
+	var out []string
+	firstEXIF, end := -1, 0
+	off := 8
+	for off+8 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[off:]))
+		typ := string(data[off+4 : off+8])
+		if n > len(data)-off-12 {
+			out = append(out, fmt.Sprintf("PNG %q chunk at offset %d: length %d runs past the end of the file", typ, off, n))
+			return out, nil
+		}
+		body := data[off+8 : off+8+n]
+		where := fmt.Sprintf("PNG %s chunk at offset %d", typ, off)
+		isEXIF := typ == "eXIf"
+		switch {
+		case typ == "IEND":
+			end = off + 12 + n
+		case typ == "tEXt" || typ == "zTXt" || typ == "iTXt":
+			keyword, text, compressed := pngTextParts(typ, body)
+			where = fmt.Sprintf("PNG %s chunk %q at offset %d", typ, keyword, off)
+			switch {
+			case strings.HasPrefix(keyword, "Raw profile type"):
+				// ImageMagick's hex-encoded EXIF, IPTC or ICC data
+				isEXIF = keyword == "Raw profile type exif" || keyword == "Raw profile type APP1"
+			case keyword == pngXMPKeyword:
+				if !compressed {
+					out = append(out, payloadAnomaly(where, text)...)
+				}
+			case compressed:
+				if len(text) > core.OversizedText {
+					out = append(out, fmt.Sprintf("%s: %d bytes of compressed text, far longer than a real comment", where, len(text)))
+				}
+			default:
+				out = append(out, textAnomalies(where, text)...)
+			}
+		case !pngKnownChunks[typ]:
+			out = append(out, fmt.Sprintf("%s: unknown chunk (%d bytes)", where, n))
+			out = append(out, payloadAnomaly(where, body)...)
+		}
+		if isEXIF {
+			if firstEXIF >= 0 {
+				out = append(out, fmt.Sprintf("%s: another EXIF block (the first is at offset %d) — readers disagree on which one applies", where, firstEXIF))
+			} else {
+				firstEXIF = off
+			}
+		}
+		off += 12 + n
+		if end > 0 {
+			break
+		}
+	}
+	switch {
+	case end == 0:
+		out = append(out, "PNG ends without an IEND chunk")
+	case end < len(data):
+		out = append(out, fmt.Sprintf("PNG IEND at offset %d: %s", end-12, core.TrailerSign(data[end:])))
+	}
+	return out, nil
+}
+
+// gifKnownApps lists the application extensions GIF encoders write.
+var gifKnownApps = map[string]bool{
+	"NETSCAPE2.0": true, "ANIMEXTS1.0": true, "XMP DataXMP": true, "ICCRGBG1012": true,
+}
+
+// gifSubBlocks reads the data sub-blocks starting at i, returning their
+// joined content and the offset after the terminator.
+func gifSubBlocks(data []byte, i int) ([]byte, int, bool) {
+	var b []byte
+	for i < len(data) {
+		n := int(data[i])
+		i++
+		if n == 0 {
+			return b, i, true
+		}
+		if i+n > len(data) {
+			return b, len(data), false
+		}
+		b = append(b, data[i:i+n]...)
+		i += n
+	}
+	return b, i, false
+}
+
+// gifAnomalies walks the blocks of a GIF to its trailer.
+func gifAnomalies(data []byte) ([]string, error) {
+	if len(data) < 13 || !bytes.HasPrefix(data, []byte("GIF")) {
+		return nil, fmt.Errorf("not a valid GIF")
+	}
+	var out []string
+	truncated := func(i int) ([]string, error) {
+		return append(out, fmt.Sprintf("GIF block at offset %d runs past the end of the file", i)), nil
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 * (1 << (int(data[10]&0x07) + 1))
+	}
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x3B: // trailer
+			if i+1 < len(data) {
+				out = append(out, fmt.Sprintf("GIF trailer at offset %d: %s", i, core.TrailerSign(data[i+1:])))
+			}
+			return out, nil
+		case 0x21: // extension
+			if i+2 >= len(data) {
+				return truncated(start)
+			}
+			label := data[i+1]
+			i += 2
+			app := ""
+			if label == 0xFF && data[i] == 11 && i+12 <= len(data) {
+				app = string(data[i+1 : i+12])
+				i += 12
+			}
+			body, next, ok := gifSubBlocks(data, i)
+			if !ok {
+				return truncated(start)
+			}
+			i = next
+			switch {
+			case label == 0xFE:
+				out = append(out, textAnomalies(fmt.Sprintf("GIF comment at offset %d", start), body)...)
+			case label == 0xFF && !gifKnownApps[app]:
+				where := fmt.Sprintf("GIF application extension %q at offset %d", app, start)
+				out = append(out, fmt.Sprintf("%s: unknown application (%d bytes)", where, len(body)))
+				out = append(out, payloadAnomaly(where, body)...)
+			}
+		case 0x2C: // image descriptor, local colour table, LZW code size, data
+			if i+10 > len(data) {
+				return truncated(start)
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 * (1 << (int(flags&0x07) + 1))
+			}
+			if i >= len(data) {
+				return truncated(start)
+			}
+			_, next, ok := gifSubBlocks(data, i+1)
+			if !ok {
+				return truncated(start)
+			}
+			i = next
+		default:
+			return append(out, fmt.Sprintf("GIF byte 0x%02X at offset %d does not start a block: %s", data[i], i, core.TrailerSign(data[i:]))), nil
+		}
+	}
+	return append(out, "GIF ends without a trailer"), nil
+}
+
+// webpKnownChunks lists the chunk types of the WebP container.
+var webpKnownChunks = map[string]bool{
+	"VP8 ": true, "VP8L": true, "VP8X": true, "ALPH": true,
+	"ANIM": true, "ANMF": true, "ICCP": true, "EXIF": true, "XMP ": true,
+}
+
+// webpAnomalies walks the chunks of a WebP to the end of its RIFF size.
+func webpAnomalies(data []byte) ([]string, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("not a valid WebP")
+	}
+	var out []string
+	end := 8 + int(binary.LittleEndian.Uint32(data[4:8]))
+	if end > len(data) {
+		out = append(out, fmt.Sprintf("WebP RIFF size %d runs past the end of the file", end-8))
+		end = len(data)
+	}
+	firstEXIF := -1
+	off := 12
+	for off+8 <= end {
+		id := string(data[off : off+4])
+		n := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		if n > end-off-8 {
+			return append(out, fmt.Sprintf("WebP %q chunk at offset %d: size %d runs past the end of the file", id, off, n)), nil
+		}
+		body := data[off+8 : off+8+n]
+		where := fmt.Sprintf("WebP %q chunk at offset %d", id, off)
+		switch {
+		case id == "EXIF":
+			if firstEXIF >= 0 {
+				out = append(out, fmt.Sprintf("%s: another EXIF block (the first is at offset %d) — readers disagree on which one applies", where, firstEXIF))
+			} else {
+				firstEXIF = off
+			}
+		case id == "XMP ":
+			out = append(out, payloadAnomaly(where, body)...)
+		case !webpKnownChunks[id]:
+			out = append(out, fmt.Sprintf("%s: unknown chunk (%d bytes)", where, n))
+			out = append(out, payloadAnomaly(where, body)...)
+		}
+		off += 8 + n + n%2
+	}
+	if end < len(data) {
+		out = append(out, fmt.Sprintf("WebP RIFF data ends at offset %d: %s", end, core.TrailerSign(data[end:])))
+	}
+	return out, nil
+}
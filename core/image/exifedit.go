@@ -0,0 +1,467 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── EXIF editing ────────────────────────────────────────────────────────────
+// A camera writes far more EXIF than edit can set: orientation, exposure,
+// lens, a thumbnail in IFD1, and a MakerNote whose private structures hold
+// offsets into the TIFF block that no generic writer can fix up. An edit
+// therefore changes the block where it stands instead of rebuilding it, and
+// nothing it keeps moves. A value that no longer fits where it was, and an
+// IFD that gains entries, are written at the end of the block and their
+// offsets repointed; the bytes a deleted or replaced value occupied are
+// zeroed so that removed data does not linger in the file.
+
+// exifIFDPointer is the IFD0 tag pointing at the Exif IFD.
+const exifIFDPointer = 0x8769
+
+// exifIFDTags are the editable tags that belong in the Exif IFD rather
+// than IFD0.
+var exifIFDTags = map[uint16]bool{
+	0x9003: true, // DateTimeOriginal
+	0x9004: true, // DateTimeDigitized
+	0x9286: true, // UserComment
+}
+
+// tiffTypeSizes gives the size of one value of each TIFF field type.
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 13: 4,
+}
+
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	field    [4]byte // the value, or its offset when longer than 4 bytes
+}
+
+type tiffIFD struct {
+	off     uint32 // where the IFD is in the block; 0 when it is new
+	room    int    // entries the space at off holds
+	entries []tiffEntry
+	next    uint32
+	changed bool
+}
+
+// exifBlock is the TIFF block of an EXIF segment being edited.
+type exifBlock struct {
+	b  []byte
+	bo binary.ByteOrder
+}
+
+// editEXIF returns EXIF APP1 data, "Exif\0\0" header included, with set
+// and del applied and every other tag and IFD kept. With data nil it
+// builds a new segment, which must end up with at least one field.
+func editEXIF(data []byte, set map[string]string, del []string) ([]byte, error) {
+	set = copyFields(set)
+	gps, err := takeGPS(set)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &exifBlock{b: []byte("II*\x00\x00\x00\x00\x00"), bo: binary.LittleEndian}
+	ifd0 := &tiffIFD{}
+	if data != nil {
+		if x, err = parseEXIFBlock(data); err != nil {
+			return nil, err
+		}
+		if ifd0, err = x.readIFD(x.bo.Uint32(x.b[4:8])); err != nil {
+			return nil, err
+		}
+	}
+	exifIFD, err := x.subIFD(ifd0, exifIFDPointer)
+	if err != nil {
+		return nil, err
+	}
+	gpsIFD, err := x.subIFD(ifd0, exifGPSInfoTag)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range del {
+		switch k {
+		case "GPSLatitude":
+			x.remove(gpsIFD, 0x0001, 0x0002)
+		case "GPSLongitude":
+			x.remove(gpsIFD, 0x0003, 0x0004)
+		case "GPSAltitude":
+			x.remove(gpsIFD, 0x0005, 0x0006)
+		default:
+			if tag, ok := exifTagIDs[k]; ok {
+				x.remove(ifd0, tag)
+				x.remove(exifIFD, tag)
+			}
+		}
+	}
+
+	for _, k := range core.SortedKeys(set) {
+		tag, ok := exifTagIDs[k]
+		if !ok {
+			continue
+		}
+		// A tag stays in the IFD it was found in, even the wrong one.
+		ifd := ifd0
+		switch {
+		case ifd0.find(tag) >= 0:
+		case exifIFD != nil && exifIFD.find(tag) >= 0, exifIFDTags[tag]:
+			if exifIFD == nil {
+				exifIFD = &tiffIFD{}
+			}
+			ifd = exifIFD
+		}
+		typ, val := x.encodeText(tag, set[k])
+		x.set(ifd, tag, typ, val)
+	}
+
+	if gps != nil {
+		if gpsIFD == nil {
+			gpsIFD = &tiffIFD{}
+		}
+		for _, v := range gpsValues(gps, x.bo) {
+			x.set(gpsIFD, v.tag, v.typ, v.data)
+		}
+	}
+
+	if data == nil && len(ifd0.entries) == 0 && exifIFD == nil && gpsIFD == nil {
+		return nil, fmt.Errorf("no recognised EXIF fields to write; supported: %v", supportedEditFields())
+	}
+	x.writeChild(ifd0, exifIFDPointer, exifIFD)
+	x.writeChild(ifd0, exifGPSInfoTag, gpsIFD)
+	if ifd0.changed {
+		// writeIFD may grow x.b, so the offset is stored after it returns.
+		off := x.writeIFD(ifd0)
+		x.bo.PutUint32(x.b[4:8], off)
+	}
+	return x.segment()
+}
+
+// stripEXIFGPS returns EXIF APP1 data without its GPS IFD, every other tag
+// kept. Data that is not an EXIF segment is returned as it is.
+func stripEXIFGPS(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+		return data, nil
+	}
+	x, err := parseEXIFBlock(data)
+	if err != nil {
+		return nil, err
+	}
+	if dropped, err := x.dropGPS(); err != nil || !dropped {
+		return data, err
+	}
+	return x.segment()
+}
+
+// stripTIFFGPS is stripEXIFGPS for a bare TIFF block, as PNG eXIf and
+// WebP EXIF chunks and TIFF files hold it. The block is edited in place
+// and never grows. An EXIF segment prefix is accepted too.
+func stripTIFFGPS(b []byte) ([]byte, error) {
+	if bytes.HasPrefix(b, []byte("Exif\x00\x00")) {
+		return stripEXIFGPS(b)
+	}
+	x, err := newEXIFBlock(append([]byte{}, b...))
+	if err != nil {
+		return nil, err
+	}
+	if dropped, err := x.dropGPS(); err != nil || !dropped {
+		return b, err
+	}
+	return x.b, nil
+}
+
+// dropGPS removes the GPS IFD, zeroing it and its values, and its pointer
+// from IFD0, which is rewritten where it was. It reports whether there was
+// a GPS IFD.
+func (x *exifBlock) dropGPS() (bool, error) {
+	ifd0, err := x.readIFD(x.bo.Uint32(x.b[4:8]))
+	if err != nil {
+		return false, err
+	}
+	gpsIFD, err := x.subIFD(ifd0, exifGPSInfoTag)
+	if err != nil || gpsIFD == nil {
+		return false, err
+	}
+	for _, e := range gpsIFD.entries {
+		x.release(e)
+	}
+	x.zero(gpsIFD.off, ifdLen(gpsIFD.room))
+	x.remove(ifd0, exifGPSInfoTag)
+	x.bo.PutUint32(x.b[4:8], x.writeIFD(ifd0))
+	return true, nil
+}
+
+// parseEXIFBlock copies the TIFF block of an EXIF segment for editing.
+func parseEXIFBlock(data []byte) (*exifBlock, error) {
+	if !bytes.HasPrefix(data, []byte("Exif\x00\x00")) || len(data) < 14 {
+		return nil, fmt.Errorf("not an EXIF segment")
+	}
+	return newEXIFBlock(append([]byte{}, data[6:]...))
+}
+
+// newEXIFBlock reads the byte order of the TIFF block b, which it edits in
+// place.
+func newEXIFBlock(b []byte) (*exifBlock, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("EXIF: not a TIFF header")
+	}
+	x := &exifBlock{b: b}
+	switch string(x.b[0:2]) {
+	case "II":
+		x.bo = binary.LittleEndian
+	case "MM":
+		x.bo = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("EXIF: unknown byte order %q", x.b[0:2])
+	}
+	if x.bo.Uint16(x.b[2:4]) != 42 {
+		return nil, fmt.Errorf("EXIF: not a TIFF header")
+	}
+	return x, nil
+}
+
+// segment returns the block as APP1 data.
+func (x *exifBlock) segment() ([]byte, error) {
+	out := append([]byte("Exif\x00\x00"), x.b...)
+	if len(out) > 65533 {
+		return nil, fmt.Errorf("EXIF would grow to %d bytes, over the 64 KB a JPEG segment holds", len(out))
+	}
+	return out, nil
+}
+
+func ifdLen(entries int) int { return 2 + 12*entries + 4 }
+
+func (x *exifBlock) readIFD(off uint32) (*tiffIFD, error) {
+	if off < 8 || int(off)+2 > len(x.b) {
+		return nil, fmt.Errorf("EXIF: IFD offset %d outside the block", off)
+	}
+	n := int(x.bo.Uint16(x.b[off:]))
+	if int(off)+ifdLen(n) > len(x.b) {
+		return nil, fmt.Errorf("EXIF: IFD at %d runs past the block", off)
+	}
+	ifd := &tiffIFD{off: off, room: n}
+	p := int(off) + 2
+	for i := 0; i < n; i++ {
+		e := tiffEntry{
+			tag:   x.bo.Uint16(x.b[p:]),
+			typ:   x.bo.Uint16(x.b[p+2:]),
+			count: x.bo.Uint32(x.b[p+4:]),
+		}
+		copy(e.field[:], x.b[p+8:p+12])
+		ifd.entries = append(ifd.entries, e)
+		p += 12
+	}
+	ifd.next = x.bo.Uint32(x.b[p:])
+	return ifd, nil
+}
+
+// subIFD reads the IFD that tag in ifd points at, or returns nil when ifd
+// has no such tag.
+func (x *exifBlock) subIFD(ifd *tiffIFD, tag uint16) (*tiffIFD, error) {
+	if ifd == nil {
+		return nil, nil
+	}
+	i := ifd.find(tag)
+	if i < 0 {
+		return nil, nil
+	}
+	return x.readIFD(x.bo.Uint32(ifd.entries[i].field[:]))
+}
+
+func (ifd *tiffIFD) find(tag uint16) int {
+	for i, e := range ifd.entries {
+		if e.tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// outOfLine returns where the value of e is stored when it does not fit
+// in the entry, and false when it does or lies outside the block.
+func (x *exifBlock) outOfLine(e tiffEntry) (off, n int, ok bool) {
+	n = tiffTypeSizes[e.typ] * int(e.count)
+	if n <= 4 {
+		return 0, 0, false
+	}
+	off = int(x.bo.Uint32(e.field[:]))
+	if off < 8 || off+n > len(x.b) {
+		return 0, 0, false
+	}
+	return off, n, true
+}
+
+// set gives tag in ifd the value data of type typ, replacing or adding
+// the entry.
+func (x *exifBlock) set(ifd *tiffIFD, tag, typ uint16, data []byte) {
+	ifd.changed = true
+	e := tiffEntry{tag: tag, typ: typ, count: uint32(len(data) / tiffTypeSizes[typ])}
+	i := ifd.find(tag)
+	if i < 0 {
+		e.field = x.place(data)
+		ifd.entries = append(ifd.entries, e)
+		return
+	}
+	old := ifd.entries[i]
+	if off, n, ok := x.outOfLine(old); ok && len(data) > 4 && len(data) <= n {
+		copy(x.b[off:], data)
+		x.zero(uint32(off+len(data)), n-len(data))
+		e.field = old.field
+	} else {
+		x.release(old)
+		e.field = x.place(data)
+	}
+	ifd.entries[i] = e
+}
+
+// remove deletes the entries for tags from ifd, zeroing their values.
+func (x *exifBlock) remove(ifd *tiffIFD, tags ...uint16) {
+	if ifd == nil {
+		return
+	}
+	for _, tag := range tags {
+		if i := ifd.find(tag); i >= 0 {
+			x.release(ifd.entries[i])
+			ifd.entries = append(ifd.entries[:i], ifd.entries[i+1:]...)
+			ifd.changed = true
+		}
+	}
+}
+
+// release zeroes the value of e when it is stored outside the entry.
+func (x *exifBlock) release(e tiffEntry) {
+	if off, n, ok := x.outOfLine(e); ok {
+		x.zero(uint32(off), n)
+	}
+}
+
+func (x *exifBlock) zero(off uint32, n int) {
+	if off == 0 {
+		return
+	}
+	for i := int(off); i < int(off)+n && i < len(x.b); i++ {
+		x.b[i] = 0
+	}
+}
+
+// place returns the entry field for data: data itself when it fits in
+// four bytes, otherwise the offset it is appended at.
+func (x *exifBlock) place(data []byte) [4]byte {
+	var f [4]byte
+	if len(data) <= 4 {
+		copy(f[:], data)
+		return f
+	}
+	x.bo.PutUint32(f[:], x.append(data))
+	return f
+}
+
+// append adds data at the end of the block, on a word boundary as TIFF
+// requires, and returns its offset.
+func (x *exifBlock) append(data []byte) uint32 {
+	if len(x.b)%2 == 1 {
+		x.b = append(x.b, 0)
+	}
+	off := uint32(len(x.b))
+	x.b = append(x.b, data...)
+	return off
+}
+
+// writeIFD stores ifd, in place when its entries fit where it was and at
+// the end of the block otherwise, and returns its offset.
+func (x *exifBlock) writeIFD(ifd *tiffIFD) uint32 {
+	sort.Slice(ifd.entries, func(i, j int) bool { return ifd.entries[i].tag < ifd.entries[j].tag })
+	buf := make([]byte, ifdLen(len(ifd.entries)))
+	x.bo.PutUint16(buf, uint16(len(ifd.entries)))
+	p := 2
+	for _, e := range ifd.entries {
+		x.bo.PutUint16(buf[p:], e.tag)
+		x.bo.PutUint16(buf[p+2:], e.typ)
+		x.bo.PutUint32(buf[p+4:], e.count)
+		copy(buf[p+8:p+12], e.field[:])
+		p += 12
+	}
+	x.bo.PutUint32(buf[p:], ifd.next)
+
+	if ifd.off != 0 && len(ifd.entries) <= ifd.room {
+		copy(x.b[ifd.off:], buf)
+		x.zero(ifd.off+uint32(len(buf)), ifdLen(ifd.room)-len(buf))
+	} else {
+		x.zero(ifd.off, ifdLen(ifd.room))
+		ifd.off, ifd.room = x.append(buf), len(ifd.entries)
+	}
+	ifd.changed = false
+	return ifd.off
+}
+
+// writeChild stores child when it changed and points tag in parent at it.
+// A child left with no entries is dropped with its pointer.
+func (x *exifBlock) writeChild(parent *tiffIFD, tag uint16, child *tiffIFD) {
+	if child == nil || !child.changed {
+		return
+	}
+	if len(child.entries) == 0 {
+		x.zero(child.off, ifdLen(child.room))
+		x.remove(parent, tag)
+		return
+	}
+	off := make([]byte, 4)
+	x.bo.PutUint32(off, x.writeIFD(child))
+	x.set(parent, tag, 4, off) // LONG
+}
+
+// encodeText returns the type and bytes of a text value for tag:
+// UserComment is UNDEFINED with a character code prefix, the rest ASCII.
+func (x *exifBlock) encodeText(tag uint16, s string) (uint16, []byte) {
+	if tag != 0x9286 {
+		return 2, append([]byte(s), 0)
+	}
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return 7, append([]byte("ASCII\x00\x00\x00"), s...)
+	}
+	out := []byte("UNICODE\x00")
+	u16 := make([]byte, 2)
+	for _, u := range utf16.Encode([]rune(s)) {
+		x.bo.PutUint16(u16, u)
+		out = append(out, u16...)
+	}
+	return 7, out
+}
+
+// decodeUserComment returns the text of a UserComment value, which
+// starts with an 8-byte character code. The byte order of UTF-16 text is
+// guessed from where the zero bytes of the first character fall.
+func decodeUserComment(v []byte) string {
+	if len(v) < 8 {
+		return strings.TrimRight(string(v), "\x00 ")
+	}
+	text := v[8:]
+	switch string(v[:8]) {
+	case "UNICODE\x00":
+		var bo binary.ByteOrder = binary.LittleEndian
+		if len(text) >= 2 && text[0] == 0 && text[1] != 0 {
+			bo = binary.BigEndian
+		}
+		u := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			u = append(u, bo.Uint16(text[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00 ")
+	case "ASCII\x00\x00\x00", "\x00\x00\x00\x00\x00\x00\x00\x00":
+		return strings.TrimRight(string(text), "\x00 ")
+	}
+	return strings.TrimRight(string(v), "\x00 ")
+}
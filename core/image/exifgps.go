@@ -0,0 +1,111 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ─── EXIF GPS writing ────────────────────────────────────────────────────────
+// edit takes GPSLatitude and GPSLongitude as signed decimal degrees and
+// GPSAltitude as signed metres, and writes them as a GPS IFD of the
+// degree/minute/second rationals and N/S, E/W, above/below references
+// that cameras use.
+
+const exifGPSInfoTag = 0x8825
+
+// exifGPS is a position to be written to the GPS IFD.
+type exifGPS struct {
+	lat, long   float64
+	alt         float64
+	hasAltitude bool
+}
+
+// takeGPS removes the GPS keys from fields and parses them. Latitude and
+// longitude must be given together.
+func takeGPS(fields map[string]string) (*exifGPS, error) {
+	latS, hasLat := fields["GPSLatitude"]
+	longS, hasLong := fields["GPSLongitude"]
+	altS, hasAlt := fields["GPSAltitude"]
+	delete(fields, "GPSLatitude")
+	delete(fields, "GPSLongitude")
+	delete(fields, "GPSAltitude")
+	if !hasLat && !hasLong {
+		return nil, nil
+	}
+	if !hasLat || !hasLong {
+		return nil, fmt.Errorf("GPSLatitude and GPSLongitude must be set together")
+	}
+	g := &exifGPS{}
+	var err error
+	if g.lat, err = strconv.ParseFloat(strings.TrimSpace(latS), 64); err != nil || math.Abs(g.lat) > 90 {
+		return nil, fmt.Errorf("GPSLatitude: %q is not a latitude in decimal degrees", latS)
+	}
+	if g.long, err = strconv.ParseFloat(strings.TrimSpace(longS), 64); err != nil || math.Abs(g.long) > 180 {
+		return nil, fmt.Errorf("GPSLongitude: %q is not a longitude in decimal degrees", longS)
+	}
+	if hasAlt {
+		if g.alt, err = strconv.ParseFloat(strings.TrimSpace(altS), 64); err != nil {
+			return nil, fmt.Errorf("GPSAltitude: %q is not a number of metres", altS)
+		}
+		g.hasAltitude = true
+	}
+	return g, nil
+}
+
+// gpsValue is one GPS IFD entry, its data in the block's byte order.
+type gpsValue struct {
+	tag, typ uint16
+	data     []byte
+}
+
+// gpsValues returns the GPS IFD entries that record g: the version,
+// latitude and longitude with their references, and the altitude when g
+// has one.
+func gpsValues(g *exifGPS, bo binary.ByteOrder) []gpsValue {
+	ref := func(v float64, pos, neg string) []byte {
+		if v < 0 {
+			return []byte(neg + "\x00")
+		}
+		return []byte(pos + "\x00")
+	}
+	values := []gpsValue{
+		{tag: 0x0000, typ: 1, data: []byte{2, 3, 0, 0}},
+		{tag: 0x0001, typ: 2, data: ref(g.lat, "N", "S")},
+		{tag: 0x0002, typ: 5, data: dmsRationals(g.lat, bo)},
+		{tag: 0x0003, typ: 2, data: ref(g.long, "E", "W")},
+		{tag: 0x0004, typ: 5, data: dmsRationals(g.long, bo)},
+	}
+	if g.hasAltitude {
+		below := byte(0)
+		if g.alt < 0 {
+			below = 1
+		}
+		values = append(values,
+			gpsValue{tag: 0x0005, typ: 1, data: []byte{below}},
+			gpsValue{tag: 0x0006, typ: 5, data: rational(math.Abs(g.alt), 100, bo)})
+	}
+	return values
+}
+
+// dmsRationals encodes |deg| as three RATIONALs: degrees, minutes and
+// seconds to 1/10000.
+func dmsRationals(deg float64, bo binary.ByteOrder) []byte {
+	deg = math.Abs(deg)
+	d := math.Floor(deg)
+	m := math.Floor((deg - d) * 60)
+	s := (deg - d - m/60) * 3600
+	out := rational(d, 1, bo)
+	out = append(out, rational(m, 1, bo)...)
+	return append(out, rational(s, 10000, bo)...)
+}
+
+// rational encodes v as a RATIONAL with the given denominator.
+func rational(v float64, den uint32, bo binary.ByteOrder) []byte {
+	b := make([]byte, 8)
+	bo.PutUint32(b[0:4], uint32(math.Round(v*float64(den))))
+	bo.PutUint32(b[4:8], den)
+	return b
+}
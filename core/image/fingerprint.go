@@ -0,0 +1,145 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ─── JPEG encoder fingerprint ────────────────────────────────────────────────
+// The DQT and SOF segments say how a JPEG was encoded — quality, chroma
+// subsampling, progressive or baseline — and together with the markers an
+// encoder leaves (an Adobe APP14, a comment naming a library, the camera
+// maker in EXIF) usually which program saved it. view --verbose shows them
+// in the "JPEG Encoding" category.
+
+// jpegEncodingCategory is the category of the fields of JPEGEncodingFields.
+const jpegEncodingCategory = "JPEG Encoding"
+
+// sofNames names the frame types by SOF marker.
+var sofNames = map[byte]string{
+	0xC0: "baseline DCT, Huffman",
+	0xC1: "extended sequential DCT, Huffman",
+	0xC2: "progressive DCT, Huffman",
+	0xC3: "lossless, Huffman",
+	0xC5: "differential sequential DCT, Huffman",
+	0xC6: "differential progressive DCT, Huffman",
+	0xC7: "differential lossless, Huffman",
+	0xC9: "extended sequential DCT, arithmetic",
+	0xCA: "progressive DCT, arithmetic",
+	0xCB: "lossless, arithmetic",
+	0xCD: "differential sequential DCT, arithmetic",
+	0xCE: "differential progressive DCT, arithmetic",
+	0xCF: "differential lossless, arithmetic",
+}
+
+// jpegCommentEncoders maps text that encoders write to COM segments to the
+// encoder's name.
+var jpegCommentEncoders = []struct{ text, name string }{
+	{"gd-jpeg", "GD library"},
+	{"Created with GIMP", "GIMP"},
+	{"LEAD Technologies", "LEADTOOLS"},
+	{"Lavc", "FFmpeg"},
+	{"Intel(R) JPEG Library", "Intel JPEG Library"},
+	{"JPEGmini", "JPEGmini"},
+	{"Optimized by JPEGmini", "JPEGmini"},
+	{"File written by Adobe Photoshop", "Adobe Photoshop"},
+	{"ImageMagick", "ImageMagick"},
+}
+
+// JPEGEncodingFields returns the encoding details of the JPEG at path, or
+// nil when it cannot be read.
+func JPEGEncodingFields(path string) []core.MetaField {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	segments, err := parseJPEGSegments(data)
+	if err != nil {
+		return nil
+	}
+	frame, ok := readJPEGFrame(segments)
+	if !ok {
+		return nil
+	}
+	field := func(k, v string) core.MetaField {
+		return core.MetaField{Key: k, Value: v, Category: jpegEncodingCategory}
+	}
+
+	fields := []core.MetaField{
+		field("Process", sofNames[frame.sofMarker]),
+		field("FrameSize", fmt.Sprintf("%dx%d", frame.width, frame.height)),
+		field("Subsampling", frame.subsampling()),
+	}
+	q, exact := frame.quality()
+	switch {
+	case exact:
+		fields = append(fields, field("Quality", fmt.Sprintf("%d (standard libjpeg tables)", q)))
+	case q > 0:
+		fields = append(fields, field("Quality", fmt.Sprintf("about %d (custom tables)", q)))
+	}
+	for id := 0; id < 4; id++ {
+		if t, ok := frame.tables[id]; ok {
+			fields = append(fields, field(fmt.Sprintf("QuantTable%d", id), formatQuantTable(t)))
+		}
+	}
+	encoder, evidence := jpegEncoder(segments, exact)
+	return append(fields, field("Encoder", encoder+" — "+evidence))
+}
+
+// jpegEncoder names the program that most likely saved the JPEG, and the
+// evidence for it. ijg reports whether its tables are scaled Annex K ones.
+func jpegEncoder(segments []jpegSegment, ijg bool) (name, evidence string) {
+	var maker, software string
+	adobe, photoshop := false, false
+	for _, seg := range segments {
+		switch {
+		case seg.marker == 0xFE:
+			for _, e := range jpegCommentEncoders {
+				if bytes.Contains(seg.data, []byte(e.text)) {
+					return e.name, fmt.Sprintf("comment %q", strings.TrimSpace(string(seg.data)))
+				}
+			}
+		case seg.marker == 0xEE && bytes.HasPrefix(seg.data, []byte("Adobe")):
+			adobe = true
+		case seg.marker == 0xED && bytes.HasPrefix(seg.data, []byte("Photoshop 3.0")):
+			photoshop = true
+		case seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")):
+			if x, err := exif.Decode(bytes.NewReader(seg.data[6:])); err == nil {
+				fields := map[string]string{}
+				x.Walk(exifStringWalker{fields: fields})
+				maker, software = strings.TrimSpace(fields["Make"]), strings.TrimSpace(fields["Software"])
+			}
+		}
+	}
+	switch {
+	case strings.Contains(software, "Photoshop") || (adobe && photoshop && !ijg):
+		return "Adobe Photoshop", "Adobe APP14 and Photoshop APP13 segments"
+	case maker != "" && !ijg:
+		return maker + " camera pipeline", fmt.Sprintf("EXIF Make %q with the maker's own quantization tables", maker)
+	case ijg && maker != "":
+		return "libjpeg-compatible encoder", fmt.Sprintf("standard tables, so re-saved after leaving the %s camera", maker)
+	case ijg:
+		return "libjpeg-compatible encoder", "standard libjpeg tables (libjpeg, libjpeg-turbo, browsers, most apps)"
+	case adobe:
+		return "Adobe software", "Adobe APP14 segment"
+	}
+	return "unknown", "custom quantization tables and no identifying markers"
+}
+
+// formatQuantTable writes a table in natural order, one row per group.
+func formatQuantTable(t [64]int) string {
+	rows := make([]string, 8)
+	for r := range rows {
+		vals := make([]string, 8)
+		for c := range vals {
+			vals[c] = fmt.Sprint(t[r*8+c])
+		}
+		rows[r] = strings.Join(vals, " ")
+	}
+	return strings.Join(rows, " / ")
+}
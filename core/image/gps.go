@@ -0,0 +1,146 @@
+package image
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── EXIF GPS reading ────────────────────────────────────────────────────────
+// EXIF stores a position as rationals: latitude and longitude as degrees,
+// minutes and seconds with the hemisphere in a separate Ref tag, altitude
+// as metres with an above/below sea level flag. decodeGPS rewrites them in
+// the signed decimal form edit takes, so a position read from one photo
+// can be set on another as it is, keeps the rationals as the field's Raw
+// value, and adds GPSPosition, latitude and longitude together.
+
+// decodeGPS rewrites the GPS fields of m.Fields[from:] in decimal.
+func decodeGPS(m *core.Metadata, from int) {
+	fields := m.Fields[from:]
+	ref := func(key string) string {
+		for _, f := range fields {
+			if f.Key == key {
+				return strings.Trim(strings.TrimSpace(f.Value), "[]")
+			}
+		}
+		return ""
+	}
+	var lat, long string
+	last := -1
+	for i := range fields {
+		f := &fields[i]
+		var v float64
+		var ok bool
+		switch f.Key {
+		case "GPSLatitude":
+			if v, ok = dmsDegrees(f.Value); ok && ref("GPSLatitudeRef") == "S" {
+				v = -v
+			}
+		case "GPSLongitude":
+			if v, ok = dmsDegrees(f.Value); ok && ref("GPSLongitudeRef") == "W" {
+				v = -v
+			}
+		case "GPSAltitude":
+			var r []float64
+			if r, ok = parseRationals(f.Value); ok && len(r) == 1 {
+				v = r[0]
+				if ref("GPSAltitudeRef") == "1" {
+					v = -v
+				}
+			} else {
+				ok = false
+			}
+		default:
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if f.Raw == "" {
+			f.Raw = f.Value
+		}
+		if f.Key == "GPSAltitude" {
+			f.Value = decimal(v, 2)
+			continue
+		}
+		f.Value = decimal(v, 7)
+		last = i
+		if f.Key == "GPSLatitude" {
+			lat = f.Value
+		} else {
+			long = f.Value
+		}
+	}
+	if lat == "" || long == "" {
+		return
+	}
+	pos := core.MetaField{
+		Key:      "GPSPosition",
+		Value:    lat + ", " + long,
+		Category: fields[last].Category,
+	}
+	at := from + last + 1
+	m.Fields = append(m.Fields[:at], append([]core.MetaField{pos}, m.Fields[at:]...)...)
+}
+
+// dmsDegrees converts degrees, minutes and seconds rationals to degrees.
+func dmsDegrees(v string) (float64, bool) {
+	r, ok := parseRationals(v)
+	if !ok || len(r) == 0 || len(r) > 3 {
+		return 0, false
+	}
+	deg := 0.0
+	for i, scale := range []float64{1, 60, 3600}[:len(r)] {
+		deg += r[i] / scale
+	}
+	return deg, true
+}
+
+// parseRationals reads a value as goexif renders rationals: "35/1", or
+// ["48/1","51/1","2997/100"].
+func parseRationals(v string) ([]float64, bool) {
+	var out []float64
+	for _, s := range strings.Split(strings.Trim(strings.TrimSpace(v), "[]"), ",") {
+		s = strings.Trim(strings.TrimSpace(s), `"`)
+		num, den, found := strings.Cut(s, "/")
+		if !found {
+			den = "1"
+		}
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return nil, false
+		}
+		out = append(out, n/d)
+	}
+	return out, true
+}
+
+// decimal formats v with at most prec decimals.
+func decimal(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// AddGPSMapLink adds GPSMapLink, a Google Maps link to the GPSPosition of
+// m, after that field. It does nothing when m has no position.
+func AddGPSMapLink(m *core.Metadata) {
+	for i, f := range m.Fields {
+		if f.Key != "GPSPosition" {
+			continue
+		}
+		link := f
+		link.Key = "GPSMapLink"
+		link.Value = "https://www.google.com/maps?q=" + strings.ReplaceAll(f.Value, " ", "")
+		link.Raw = ""
+		m.Fields = append(m.Fields[:i+1], append([]core.MetaField{link}, m.Fields[i+1:]...)...)
+		return
+	}
+}
@@ -0,0 +1,1252 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ─── HEIC/HEIF items ─────────────────────────────────────────────────────────
+// HEIF does not keep Exif and XMP in boxes of their own. They are items of
+// the top-level meta box, like the image tiles: iinf names each item (type
+// Exif, or mime with the content type application/rdf+xml), iloc gives the
+// extents its bytes occupy — in the file, usually inside mdat, or in meta's
+// own idat — and an iref cdsc reference ties it to the image it describes.
+//
+// An edit therefore rewrites the meta box: iinf, iloc, iref and idat are
+// rebuilt, and every other child is copied as it is. An item whose data
+// changes is rewritten where it was when it occupies one extent of the
+// file, and moved into idat otherwise; a new item goes into idat. The
+// bytes of a removed item are cut out of the file, not left behind. Every
+// iloc offset into the file is then moved by what was inserted or cut
+// before it, and the size of each box that was cut into is fixed up.
+
+// heifBox locates one box inside an in-memory meta box or file.
+type heifBox struct {
+	typ   string
+	start int // offset of the size field
+	body  int // first payload byte
+	end   int
+}
+
+// heifBoxes lists the boxes laid out back to back in data[start:end].
+func heifBoxes(data []byte, start, end int) ([]heifBox, error) {
+	var out []heifBox
+	for pos := start; pos < end; {
+		if pos+8 > end {
+			return nil, fmt.Errorf("HEIF box at offset %d is truncated", pos)
+		}
+		size := uint64(binary.BigEndian.Uint32(data[pos:]))
+		hdr := 8
+		switch size {
+		case 0:
+			size = uint64(end - pos)
+		case 1:
+			if pos+16 > end {
+				return nil, fmt.Errorf("HEIF box at offset %d is truncated", pos)
+			}
+			size, hdr = binary.BigEndian.Uint64(data[pos+8:]), 16
+		}
+		if size < uint64(hdr) || size > uint64(end-pos) {
+			return nil, fmt.Errorf("HEIF %q box at offset %d has a bad size", data[pos+4:pos+8], pos)
+		}
+		out = append(out, heifBox{typ: string(data[pos+4 : pos+8]), start: pos, body: pos + hdr, end: pos + int(size)})
+		pos += int(size)
+	}
+	return out, nil
+}
+
+// heifItem is one entry of iinf.
+type heifItem struct {
+	id          uint32
+	typ         string // hvc1, grid, Exif, mime, …
+	contentType string // of a mime item
+	infe        []byte // the infe box as the file has it; nil for a new item
+}
+
+// heifExtent is one extent of an item's data.
+type heifExtent struct {
+	index, offset, length uint64
+}
+
+// heifLoc is one entry of iloc.
+type heifLoc struct {
+	id      uint32
+	method  uint8 // 0 file offset, 1 idat offset, 2 item offset
+	dataRef uint16
+	base    uint64
+	extents []heifExtent
+}
+
+// heifRef is one reference of iref: from points at each of to.
+type heifRef struct {
+	typ  string
+	from uint32
+	to   []uint32
+}
+
+// heifFile is the item structure of a HEIF file.
+type heifFile struct {
+	r      io.ReaderAt
+	size   int64
+	top    []heifBox // top-level boxes
+	meta   []byte    // the meta box
+	metaAt int64     // its offset in the file
+
+	children []heifBox // of meta, offsets into meta
+	primary  uint32
+	items    []heifItem
+	locs     []heifLoc
+	refs     []heifRef
+
+	ilocVersion byte
+	indexSize   int
+	irefVersion byte
+	idat        *heifBox
+}
+
+// mimeXMP is the content type of an XMP item.
+const mimeXMP = "application/rdf+xml"
+
+// heifMaxMeta bounds the meta box read into memory; it holds only item
+// tables and the small items kept in idat.
+const heifMaxMeta = 16 << 20
+
+// readHEIF reads the item structure of the HEIF file r of size bytes,
+// without reading its image data.
+func readHEIF(r io.ReaderAt, size int64) (*heifFile, error) {
+	f := &heifFile{r: r, size: size}
+	var hdr [16]byte
+	for pos := int64(0); pos < size; {
+		if _, err := r.ReadAt(hdr[:8], pos); err != nil {
+			return nil, fmt.Errorf("HEIF box at offset %d is truncated", pos)
+		}
+		boxSize := int64(binary.BigEndian.Uint32(hdr[:4]))
+		h := int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - pos
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], pos+8); err != nil {
+				return nil, fmt.Errorf("HEIF box at offset %d is truncated", pos)
+			}
+			boxSize, h = int64(binary.BigEndian.Uint64(hdr[8:16])), 16
+		}
+		if boxSize < h || boxSize > size-pos {
+			return nil, fmt.Errorf("HEIF %q box at offset %d has a bad size", hdr[4:8], pos)
+		}
+		b := heifBox{typ: string(hdr[4:8]), start: int(pos), body: int(pos + h), end: int(pos + boxSize)}
+		f.top = append(f.top, b)
+		if b.typ == "meta" && f.meta == nil {
+			if boxSize > heifMaxMeta {
+				return nil, fmt.Errorf("HEIF meta box is too large (%d bytes)", boxSize)
+			}
+			f.meta = make([]byte, boxSize)
+			if _, err := r.ReadAt(f.meta, pos); err != nil {
+				return nil, err
+			}
+			f.metaAt = pos
+		}
+		pos += boxSize
+	}
+	if f.meta == nil {
+		return nil, fmt.Errorf("HEIF file has no meta box")
+	}
+	return f, f.parseMeta()
+}
+
+// parseMeta reads pitm, iinf, iloc, iref and idat out of the meta box.
+func (f *heifFile) parseMeta() error {
+	top, err := heifBoxes(f.meta, 0, len(f.meta))
+	if err != nil || len(top) != 1 || top[0].body+4 > top[0].end {
+		return fmt.Errorf("HEIF meta box is malformed")
+	}
+	if f.children, err = heifBoxes(f.meta, top[0].body+4, top[0].end); err != nil {
+		return err
+	}
+	for i := range f.children {
+		b := f.children[i]
+		p := f.meta[b.body:b.end]
+		switch b.typ {
+		case "pitm":
+			if v, ok := fullBox(p); ok {
+				f.primary, _ = v.id16or32(v.version > 0)
+			}
+		case "iinf":
+			err = f.parseIINF(p)
+		case "iloc":
+			err = f.parseILOC(p)
+		case "iref":
+			err = f.parseIREF(p)
+		case "idat":
+			f.idat = &f.children[i]
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *heifFile) parseIINF(p []byte) error {
+	r, ok := fullBox(p)
+	if !ok {
+		return fmt.Errorf("HEIF iinf box is truncated")
+	}
+	if _, ok := r.id16or32(r.version > 0); !ok {
+		return fmt.Errorf("HEIF iinf box is truncated")
+	}
+	entries, err := heifBoxes(p, len(p)-len(r.b), len(p))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.typ != "infe" {
+			continue
+		}
+		it := heifItem{infe: p[e.start:e.end]}
+		r, ok := fullBox(p[e.body:e.end])
+		if !ok {
+			return fmt.Errorf("HEIF infe box is truncated")
+		}
+		if it.id, ok = r.id16or32(r.version >= 3); !ok {
+			return fmt.Errorf("HEIF infe box is truncated")
+		}
+		r.skip(2) // item_protection_index
+		if r.version >= 2 {
+			it.typ = string(r.take(4))
+			r.cstring() // item_name
+			if it.typ == "mime" {
+				it.contentType = r.cstring()
+			}
+		} else {
+			r.cstring()
+			it.typ, it.contentType = "mime", r.cstring()
+		}
+		f.items = append(f.items, it)
+	}
+	return nil
+}
+
+func (f *heifFile) parseILOC(p []byte) error {
+	r, ok := fullBox(p)
+	if !ok || len(r.b) < 2 {
+		return fmt.Errorf("HEIF iloc box is truncated")
+	}
+	f.ilocVersion = r.version
+	sizes := r.take(2)
+	offSize, lenSize, baseSize := int(sizes[0]>>4), int(sizes[0]&15), int(sizes[1]>>4)
+	if r.version == 1 || r.version == 2 {
+		f.indexSize = int(sizes[1] & 15)
+	}
+	count, ok := r.id16or32(r.version >= 2)
+	for i := uint32(0); ok && i < count; i++ {
+		var l heifLoc
+		if l.id, ok = r.id16or32(r.version >= 2); !ok {
+			break
+		}
+		if r.version == 1 || r.version == 2 {
+			l.method = byte(r.uint(2) & 15)
+		}
+		l.dataRef = uint16(r.uint(2))
+		l.base = r.uint(baseSize)
+		n := int(r.uint(2))
+		for j := 0; j < n; j++ {
+			var e heifExtent
+			if f.indexSize > 0 {
+				e.index = r.uint(f.indexSize)
+			}
+			e.offset = r.uint(offSize)
+			e.length = r.uint(lenSize)
+			l.extents = append(l.extents, e)
+		}
+		if r.short {
+			ok = false
+			break
+		}
+		f.locs = append(f.locs, l)
+	}
+	if !ok {
+		return fmt.Errorf("HEIF iloc box is truncated")
+	}
+	return nil
+}
+
+func (f *heifFile) parseIREF(p []byte) error {
+	r, ok := fullBox(p)
+	if !ok {
+		return fmt.Errorf("HEIF iref box is truncated")
+	}
+	f.irefVersion = r.version
+	refs, err := heifBoxes(p, 4, len(p))
+	if err != nil {
+		return err
+	}
+	for _, b := range refs {
+		rr := &boxReader{b: p[b.body:b.end]}
+		ref := heifRef{typ: b.typ}
+		ref.from, _ = rr.id16or32(r.version > 0)
+		n := int(rr.uint(2))
+		for i := 0; i < n; i++ {
+			id, _ := rr.id16or32(r.version > 0)
+			ref.to = append(ref.to, id)
+		}
+		if rr.short {
+			return fmt.Errorf("HEIF iref box is truncated")
+		}
+		f.refs = append(f.refs, ref)
+	}
+	return nil
+}
+
+// boxReader reads the fields of a box payload; a read past the end yields
+// zeros and sets short.
+type boxReader struct {
+	b       []byte
+	version byte
+	short   bool
+}
+
+// fullBox reads the version and flags of a full box payload.
+func fullBox(p []byte) (*boxReader, bool) {
+	if len(p) < 4 {
+		return nil, false
+	}
+	return &boxReader{b: p[4:], version: p[0]}, true
+}
+
+func (r *boxReader) take(n int) []byte {
+	if n > len(r.b) {
+		r.short = true
+		r.b = nil
+		return make([]byte, n)
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *boxReader) skip(n int) { r.take(n) }
+
+func (r *boxReader) uint(n int) uint64 {
+	var v uint64
+	for _, c := range r.take(n) {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func (r *boxReader) id16or32(wide bool) (uint32, bool) {
+	n := 2
+	if wide {
+		n = 4
+	}
+	v := uint32(r.uint(n))
+	return v, !r.short
+}
+
+func (r *boxReader) cstring() string {
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		s := string(r.b)
+		r.b = nil
+		return s
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
+
+// ─── Item lookup ─────────────────────────────────────────────────────────────
+
+// metadataItem returns the first item holding Exif (typ "Exif") or XMP
+// (typ "mime"), or nil.
+func (f *heifFile) metadataItem(typ string) *heifItem {
+	for i := range f.items {
+		if f.items[i].isMetadata(typ) {
+			return &f.items[i]
+		}
+	}
+	return nil
+}
+
+func (it heifItem) isMetadata(typ string) bool {
+	if typ == "mime" {
+		return it.typ == "mime" && strings.EqualFold(it.contentType, mimeXMP)
+	}
+	return it.typ == typ
+}
+
+func (f *heifFile) loc(id uint32) *heifLoc {
+	for i := range f.locs {
+		if f.locs[i].id == id {
+			return &f.locs[i]
+		}
+	}
+	return nil
+}
+
+// itemData reads the data of item id and returns it with the file offset
+// of its first byte.
+func (f *heifFile) itemData(id uint32) ([]byte, int64, error) {
+	l := f.loc(id)
+	if l == nil {
+		return nil, 0, fmt.Errorf("HEIF item %d has no location", id)
+	}
+	if l.dataRef != 0 {
+		return nil, 0, fmt.Errorf("HEIF item %d is stored in another file", id)
+	}
+	var out []byte
+	var at int64 = -1
+	for _, e := range l.extents {
+		off := int64(l.base + e.offset)
+		var src io.ReaderAt
+		var limit int64
+		switch l.method {
+		case 0:
+			src, limit = f.r, f.size
+		case 1:
+			if f.idat == nil {
+				return nil, 0, fmt.Errorf("HEIF item %d is in an idat box the file does not have", id)
+			}
+			src = bytes.NewReader(f.meta[f.idat.body:f.idat.end])
+			limit = int64(f.idat.end - f.idat.body)
+			if at < 0 {
+				at = f.metaAt + int64(f.idat.body) + off
+			}
+		default:
+			return nil, 0, fmt.Errorf("HEIF item %d is built from other items", id)
+		}
+		n := int64(e.length)
+		if n == 0 {
+			n = limit - off
+		}
+		if off < 0 || n < 0 || off+n > limit || len(out)+int(n) > heifMaxMeta {
+			return nil, 0, fmt.Errorf("HEIF item %d lies outside the file", id)
+		}
+		if at < 0 {
+			at = off
+		}
+		buf := make([]byte, n)
+		if _, err := src.ReadAt(buf, off); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, buf...)
+	}
+	return out, at, nil
+}
+
+// heifExifBlock turns the data of an Exif item — a 4-byte offset to the
+// TIFF header, then usually "Exif\0\0" — into the APP1 form editEXIF
+// takes.
+func heifExifBlock(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("HEIF Exif item is truncated")
+	}
+	off := int(binary.BigEndian.Uint32(data))
+	if 4+off > len(data) {
+		return nil, fmt.Errorf("HEIF Exif item has a bad TIFF header offset")
+	}
+	return append([]byte("Exif\x00\x00"), data[4+off:]...), nil
+}
+
+// heifExifItem is the inverse of heifExifBlock.
+func heifExifItem(block []byte) []byte {
+	out := []byte{0, 0, 0, 6}
+	return append(out, block...)
+}
+
+// ─── Rewriting ───────────────────────────────────────────────────────────────
+
+// heifChange is what an edit does to the items of a file: new data for
+// existing items, nil to remove one, and items to add.
+type heifChange struct {
+	replace map[uint32][]byte
+	add     []heifNewItem
+}
+
+type heifNewItem struct {
+	typ, contentType string
+	data             []byte
+}
+
+func (ch heifChange) empty() bool { return len(ch.replace) == 0 && len(ch.add) == 0 }
+
+// heifSplice replaces old bytes of the file at offset at with repl.
+type heifSplice struct {
+	at, old int
+	repl    []byte
+}
+
+// shiftHEIF returns where offset off of the old file is in the new one:
+// moved by every splice that ends at or before it.
+func shiftHEIF(splices []heifSplice, off int) int {
+	d := 0
+	for _, s := range splices {
+		if s.at+s.old <= off {
+			d += len(s.repl) - s.old
+		}
+	}
+	return off + d
+}
+
+// heifCuts are ranges removed from idat, in idat offsets.
+type heifCuts [][2]int
+
+func (c heifCuts) shift(off int) int {
+	d := 0
+	for _, r := range c {
+		if r[1] <= off {
+			d += r[1] - r[0]
+		}
+	}
+	return off - d
+}
+
+func (c heifCuts) overlaps(from, to int) bool {
+	for _, r := range c {
+		if from < r[1] && r[0] < to {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite returns data, the file f was read from, with ch applied.
+func (f *heifFile) rewrite(data []byte, ch heifChange) ([]byte, error) {
+	var metaBox heifBox
+	for _, b := range f.top {
+		if b.typ == "moov" {
+			return nil, fmt.Errorf("HEIF image sequences (moov) cannot be rewritten yet")
+		}
+		if b.start == int(f.metaAt) {
+			metaBox = b
+		}
+	}
+	inMeta := func(off, n int) bool { return off < metaBox.end && metaBox.start < off+n }
+
+	var idat []byte
+	if f.idat != nil {
+		idat = f.meta[f.idat.body:f.idat.end]
+	}
+	var splices []heifSplice
+	var cuts heifCuts
+	moved := map[uint32][]byte{}
+	removed := map[uint32]bool{}
+	inPlace := map[uint32]int{} // item → its new length
+
+	ids := make([]uint32, 0, len(ch.replace))
+	for id := range ch.replace {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		repl := ch.replace[id]
+		l := f.loc(id)
+		if l == nil {
+			if repl == nil {
+				removed[id] = true
+			} else {
+				moved[id] = repl
+			}
+			continue
+		}
+		if l.dataRef != 0 {
+			return nil, fmt.Errorf("HEIF item %d is stored in another file", id)
+		}
+		if repl != nil && l.method == 0 && len(l.extents) == 1 && l.extents[0].length > 0 {
+			at, n := int(l.base+l.extents[0].offset), int(l.extents[0].length)
+			if at+n <= len(data) && !inMeta(at, n) {
+				splices = append(splices, heifSplice{at: at, old: n, repl: repl})
+				inPlace[id] = len(repl)
+				continue
+			}
+		}
+		for _, e := range l.extents {
+			at, n := int(l.base+e.offset), int(e.length)
+			switch l.method {
+			case 0:
+				if n == 0 {
+					n = len(data) - at
+				}
+				if at < 0 || at+n > len(data) || inMeta(at, n) {
+					return nil, fmt.Errorf("HEIF item %d lies outside the media data", id)
+				}
+				splices = append(splices, heifSplice{at: at, old: n})
+			case 1:
+				if n == 0 {
+					n = len(idat) - at
+				}
+				if at < 0 || at+n > len(idat) {
+					return nil, fmt.Errorf("HEIF item %d lies outside idat", id)
+				}
+				cuts = append(cuts, [2]int{at, at + n})
+			}
+		}
+		if repl == nil {
+			removed[id] = true
+		} else {
+			moved[id] = repl
+		}
+	}
+	sort.Slice(splices, func(i, j int) bool { return splices[i].at < splices[j].at })
+	for i := 1; i < len(splices); i++ {
+		if splices[i].at < splices[i-1].at+splices[i-1].old {
+			return nil, fmt.Errorf("HEIF metadata items share their data")
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i][0] < cuts[j][0] })
+
+	// The items that stay where they are must not share bytes with those
+	// that change.
+	for _, l := range f.locs {
+		if removed[l.id] || moved[l.id] != nil || inPlace[l.id] > 0 || l.dataRef != 0 {
+			continue
+		}
+		for _, e := range l.extents {
+			at, n := int(l.base+e.offset), int(e.length)
+			switch l.method {
+			case 0:
+				if n > 0 && inMeta(at, n) {
+					return nil, fmt.Errorf("HEIF item %d is stored inside the meta box, which cannot be rewritten", l.id)
+				}
+				for _, s := range splices {
+					if at < s.at+s.old && s.at < at+max(n, 1) {
+						return nil, fmt.Errorf("HEIF item %d shares its data with a metadata item", l.id)
+					}
+				}
+			case 1:
+				if cuts.overlaps(at, at+max(n, 1)) {
+					return nil, fmt.Errorf("HEIF item %d shares its data with a metadata item", l.id)
+				}
+			}
+		}
+	}
+
+	// The new idat: the old one less the cuts, then the moved and new
+	// items.
+	var newIdat []byte
+	prev := 0
+	for _, c := range cuts {
+		if c[0] > prev {
+			newIdat = append(newIdat, idat[prev:c[0]]...)
+		}
+		if c[1] > prev {
+			prev = c[1]
+		}
+	}
+	newIdat = append(newIdat, idat[prev:]...)
+	idatAt := map[uint32]int{}
+	for _, id := range ids {
+		if d := moved[id]; d != nil {
+			idatAt[id] = len(newIdat)
+			newIdat = append(newIdat, d...)
+		}
+	}
+	next := uint32(0)
+	for _, it := range f.items {
+		if it.id > next {
+			next = it.id
+		}
+	}
+	for _, l := range f.locs {
+		if l.id > next {
+			next = l.id
+		}
+	}
+	items := make([]heifItem, 0, len(f.items)+len(ch.add))
+	for _, it := range f.items {
+		if !removed[it.id] {
+			items = append(items, it)
+		}
+	}
+	for _, a := range ch.add {
+		next++
+		items = append(items, heifItem{id: next, typ: a.typ, contentType: a.contentType})
+		moved[next] = a.data
+		idatAt[next] = len(newIdat)
+		newIdat = append(newIdat, a.data...)
+	}
+
+	// meta is built twice: once to learn its length, which moves every
+	// offset after it, and once more with the offsets final.
+	metaSplice := heifSplice{at: metaBox.start, old: metaBox.end - metaBox.start}
+	all := func() []heifSplice {
+		out := append([]heifSplice{metaSplice}, splices...)
+		sort.Slice(out, func(i, j int) bool { return out[i].at < out[j].at })
+		return out
+	}
+	build := func() ([]byte, error) {
+		locs, version, err := f.newLocs(all(), cuts, removed, moved, idatAt, inPlace)
+		if err != nil {
+			return nil, err
+		}
+		return f.buildMeta(items, locs, version, removed, newIdat)
+	}
+	meta, err := build()
+	if err != nil {
+		return nil, err
+	}
+	metaSplice.repl = meta
+	if meta, err = build(); err != nil {
+		return nil, err
+	}
+	if len(meta) != len(metaSplice.repl) {
+		return nil, fmt.Errorf("HEIF meta box changed size while being rewritten")
+	}
+	metaSplice.repl = meta
+	splices = all()
+
+	// Splice the file, then fix the size of each top-level box cut into.
+	var out []byte
+	pos := 0
+	for _, s := range splices {
+		out = append(out, data[pos:s.at]...)
+		out = append(out, s.repl...)
+		pos = s.at + s.old
+	}
+	out = append(out, data[pos:]...)
+	for _, b := range f.top {
+		if b.start == metaBox.start {
+			continue
+		}
+		delta := 0
+		for _, s := range splices {
+			if s.at >= b.body && s.at+s.old <= b.end {
+				delta += len(s.repl) - s.old
+			}
+		}
+		if delta == 0 {
+			continue
+		}
+		at := shiftHEIF(splices, b.start)
+		switch size := binary.BigEndian.Uint32(data[b.start:]); size {
+		case 0:
+		case 1:
+			binary.BigEndian.PutUint64(out[at+8:], uint64(b.end-b.start+delta))
+		default:
+			if int64(size)+int64(delta) > 1<<32-1 {
+				return nil, fmt.Errorf("HEIF %s box would exceed 4 GiB", b.typ)
+			}
+			binary.BigEndian.PutUint32(out[at:], uint32(int(size)+delta))
+		}
+	}
+	return out, nil
+}
+
+// newLocs returns the iloc entries after an edit, and the iloc version
+// they need.
+func (f *heifFile) newLocs(splices []heifSplice, cuts heifCuts, removed map[uint32]bool, moved map[uint32][]byte, idatAt, inPlace map[uint32]int) ([]heifLoc, byte, error) {
+	version := f.ilocVersion
+	var out []heifLoc
+	for _, l := range f.locs {
+		if removed[l.id] || moved[l.id] != nil {
+			continue
+		}
+		nl := heifLoc{id: l.id, method: l.method, dataRef: l.dataRef, base: l.base}
+		for _, e := range l.extents {
+			ne := e
+			if l.dataRef == 0 && l.method < 2 {
+				// Offsets are written whole, with no base.
+				abs := int(l.base + e.offset)
+				if l.method == 0 {
+					abs = shiftHEIF(splices, abs)
+				} else {
+					abs = cuts.shift(abs)
+				}
+				ne.offset, nl.base = uint64(abs), 0
+				if n, ok := inPlace[l.id]; ok {
+					ne.length = uint64(n)
+				}
+			}
+			nl.extents = append(nl.extents, ne)
+		}
+		out = append(out, nl)
+	}
+	ids := make([]uint32, 0, len(moved))
+	for id := range moved {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		out = append(out, heifLoc{id: id, method: 1, extents: []heifExtent{{offset: uint64(idatAt[id]), length: uint64(len(moved[id]))}}})
+		if version == 0 {
+			version = 1
+		}
+	}
+	for _, l := range out {
+		if l.id > 0xFFFF {
+			version = 2
+		}
+	}
+	return out, version, nil
+}
+
+// buildMeta returns the new meta box: the old one with iinf, iloc, iref,
+// idat and the ipma of iprp rebuilt.
+func (f *heifFile) buildMeta(items []heifItem, locs []heifLoc, ilocVersion byte, removed map[uint32]bool, idat []byte) ([]byte, error) {
+	var added []heifItem
+	for _, it := range items {
+		if it.infe == nil {
+			added = append(added, it)
+		}
+	}
+	iref := f.buildIREF(removed, added)
+	body := append([]byte(nil), f.meta[8:12]...) // version and flags
+	hasIREF, hasIDAT := false, false
+	for _, c := range f.children {
+		raw := f.meta[c.start:c.end]
+		switch c.typ {
+		case "iinf":
+			body = append(body, buildIINF(f.meta[c.body], items)...)
+			if !hasIREF && !f.hasChild("iref") {
+				body = append(body, iref...)
+				hasIREF = true
+			}
+		case "iloc":
+			body = append(body, f.buildILOC(locs, ilocVersion)...)
+		case "iref":
+			body = append(body, iref...)
+			hasIREF = true
+		case "idat":
+			if len(idat) > 0 {
+				body = append(body, heifPack("idat", idat)...)
+			}
+			hasIDAT = true
+		case "iprp":
+			body = append(body, dropIPMA(raw, removed)...)
+		default:
+			body = append(body, raw...)
+		}
+	}
+	if !hasIDAT && len(idat) > 0 {
+		body = append(body, heifPack("idat", idat)...)
+	}
+	if len(body)+8 > 1<<32-1 {
+		return nil, fmt.Errorf("HEIF meta box would exceed 4 GiB")
+	}
+	return heifPack("meta", body), nil
+}
+
+func (f *heifFile) hasChild(typ string) bool {
+	for _, c := range f.children {
+		if c.typ == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func heifPack(typ string, payload []byte) []byte {
+	out := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(out, uint32(8+len(payload)))
+	copy(out[4:], typ)
+	return append(out, payload...)
+}
+
+func putUint(b []byte, v uint64, n int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// buildIINF writes the kept infe boxes as they were and new ones as
+// version 2, or 3 for an ID past 16 bits.
+func buildIINF(version byte, items []heifItem) []byte {
+	if len(items) > 0xFFFF {
+		version = 1
+	}
+	p := []byte{version, 0, 0, 0}
+	if version == 0 {
+		p = putUint(p, uint64(len(items)), 2)
+	} else {
+		p = putUint(p, uint64(len(items)), 4)
+	}
+	for _, it := range items {
+		if it.infe != nil {
+			p = append(p, it.infe...)
+			continue
+		}
+		e := []byte{2, 0, 0, 0}
+		if it.id > 0xFFFF {
+			e[0] = 3
+			e = putUint(e, uint64(it.id), 4)
+		} else {
+			e = putUint(e, uint64(it.id), 2)
+		}
+		e = append(e, 0, 0) // item_protection_index
+		e = append(e, it.typ...)
+		e = append(e, 0) // item_name
+		if it.typ == "mime" {
+			e = append(append(e, it.contentType...), 0)
+		}
+		p = append(p, heifPack("infe", e)...)
+	}
+	return heifPack("iinf", p)
+}
+
+// buildILOC writes locs with 32-bit offsets and lengths, or 64-bit ones
+// when a value needs them. A base offset is kept only for items built from
+// other items, the others having theirs folded into the extent offsets.
+func (f *heifFile) buildILOC(locs []heifLoc, version byte) []byte {
+	offSize, lenSize, baseSize := 4, 4, 0
+	for _, l := range locs {
+		if l.base > 0 {
+			baseSize = 4
+		}
+		if l.base > 1<<32-1 {
+			baseSize = 8
+		}
+		for _, e := range l.extents {
+			if e.offset > 1<<32-1 {
+				offSize = 8
+			}
+			if e.length > 1<<32-1 {
+				lenSize = 8
+			}
+		}
+	}
+	indexSize := 0
+	if version > 0 {
+		indexSize = f.indexSize
+	}
+	p := []byte{version, 0, 0, 0, byte(offSize<<4 | lenSize), byte(baseSize<<4 | indexSize)}
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+	p = putUint(p, uint64(len(locs)), idSize)
+	for _, l := range locs {
+		p = putUint(p, uint64(l.id), idSize)
+		if version > 0 {
+			p = putUint(p, uint64(l.method), 2)
+		}
+		p = putUint(p, uint64(l.dataRef), 2)
+		p = putUint(p, l.base, baseSize)
+		p = putUint(p, uint64(len(l.extents)), 2)
+		for _, e := range l.extents {
+			p = putUint(p, e.index, indexSize)
+			p = putUint(p, e.offset, offSize)
+			p = putUint(p, e.length, lenSize)
+		}
+	}
+	return heifPack("iloc", p)
+}
+
+// buildIREF drops the references from and to removed items and adds a
+// cdsc reference from each added item to the primary image. It returns
+// nil when no reference is left.
+func (f *heifFile) buildIREF(removed map[uint32]bool, added []heifItem) []byte {
+	var refs []heifRef
+	for _, r := range f.refs {
+		if removed[r.from] {
+			continue
+		}
+		nr := heifRef{typ: r.typ, from: r.from}
+		for _, id := range r.to {
+			if !removed[id] {
+				nr.to = append(nr.to, id)
+			}
+		}
+		if len(nr.to) > 0 {
+			refs = append(refs, nr)
+		}
+	}
+	if f.primary != 0 {
+		for _, it := range added {
+			refs = append(refs, heifRef{typ: "cdsc", from: it.id, to: []uint32{f.primary}})
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	version := f.irefVersion
+	for _, r := range refs {
+		for _, id := range append([]uint32{r.from}, r.to...) {
+			if id > 0xFFFF {
+				version = 1
+			}
+		}
+	}
+	idSize := 2
+	if version > 0 {
+		idSize = 4
+	}
+	p := []byte{version, 0, 0, 0}
+	for _, r := range refs {
+		b := putUint(nil, uint64(r.from), idSize)
+		b = putUint(b, uint64(len(r.to)), 2)
+		for _, id := range r.to {
+			b = putUint(b, uint64(id), idSize)
+		}
+		p = append(p, heifPack(r.typ, b)...)
+	}
+	return heifPack("iref", p)
+}
+
+// dropIPMA returns the iprp box raw without the property associations of
+// removed items.
+func dropIPMA(raw []byte, removed map[uint32]bool) []byte {
+	if len(removed) == 0 {
+		return raw
+	}
+	children, err := heifBoxes(raw, 8, len(raw))
+	if err != nil || len(raw) < 8 || binary.BigEndian.Uint32(raw) == 1 {
+		return raw
+	}
+	body := []byte{}
+	changed := false
+	for _, c := range children {
+		box := raw[c.start:c.end]
+		if c.typ == "ipma" {
+			if nb, ok := filterIPMA(raw[c.body:c.end], removed); ok {
+				box, changed = heifPack("ipma", nb), true
+			}
+		}
+		body = append(body, box...)
+	}
+	if !changed {
+		return raw
+	}
+	return heifPack("iprp", body)
+}
+
+func filterIPMA(p []byte, removed map[uint32]bool) ([]byte, bool) {
+	r, ok := fullBox(p)
+	if !ok {
+		return nil, false
+	}
+	flags := p[3]
+	count := int(r.uint(4))
+	out := append([]byte(nil), p[:4]...)
+	var entries []byte
+	kept, dropped := 0, false
+	for i := 0; i < count; i++ {
+		start := len(p) - len(r.b)
+		id, _ := r.id16or32(r.version >= 1)
+		n := int(r.uint(1))
+		size := 1
+		if flags&1 != 0 {
+			size = 2
+		}
+		r.skip(n * size)
+		if r.short {
+			return nil, false
+		}
+		if removed[id] {
+			dropped = true
+			continue
+		}
+		entries = append(entries, p[start:len(p)-len(r.b)]...)
+		kept++
+	}
+	if !dropped {
+		return nil, false
+	}
+	out = putUint(out, uint64(kept), 4)
+	return append(out, entries...), true
+}
+
+// ─── View, edit and strip ────────────────────────────────────────────────────
+
+func viewHEIC(path string, m *core.Metadata) (*core.Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return m, err
+	}
+	return viewHEIF(f, st.Size(), m)
+}
+
+// viewHEIF reads the brand and the Exif and XMP items of the HEIF file r.
+func viewHEIF(r io.ReaderAt, size int64, m *core.Metadata) (*core.Metadata, error) {
+	if brand, err := core.ReadRange(r, 8, 4); err == nil && len(brand) == 4 {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "Brand",
+			Value:    strings.TrimSpace(string(brand)),
+			Category: "HEIC",
+			Editable: false,
+		})
+	}
+	f, err := readHEIF(r, size)
+	if err != nil {
+		return m, err
+	}
+	if it := f.metadataItem("Exif"); it != nil {
+		from := len(m.Fields)
+		data, at, err := f.itemData(it.id)
+		if err != nil {
+			return m, err
+		}
+		if block, err := heifExifBlock(data); err == nil {
+			if x, err := exif.Decode(bytes.NewReader(block[6:])); err == nil {
+				x.Walk(exifWalker{m: m, editableSet: exifEditable})
+				decodeGPS(m, from)
+			}
+		}
+		m.SetSource(from, core.FieldSource{Store: "EXIF", Structure: "Exif item", Offset: at})
+	}
+	if it := f.metadataItem("mime"); it != nil {
+		from := len(m.Fields)
+		data, at, err := f.itemData(it.id)
+		if err != nil {
+			return m, err
+		}
+		parseXMPInto(data, m)
+		m.SetSource(from, core.FieldSource{Store: "XMP", Structure: "mime item", Offset: at})
+	}
+	return m, nil
+}
+
+func editHEIC(path, outPath string, opts core.EditOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := applyHEICEdits(data, opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Println("Dry-run: HEIC Exif and XMP items would be updated with:")
+		for _, k := range core.SortedKeys(opts.Set) {
+			fmt.Printf("  %s = %s\n", k, opts.Set[k])
+		}
+		return nil
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
+
+// applyHEICEdits returns data with the EXIF and XMP changes of opts.
+func applyHEICEdits(data []byte, opts core.EditOptions) ([]byte, error) {
+	f, err := readHEIF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	opts, kw, err := takeXMPEdits(opts)
+	if err != nil {
+		return nil, err
+	}
+	ch := heifChange{replace: map[uint32][]byte{}}
+	if len(opts.Set) > 0 || len(opts.Delete) > 0 {
+		if it := f.metadataItem("Exif"); it != nil {
+			old, _, err := f.itemData(it.id)
+			if err != nil {
+				return nil, err
+			}
+			block, err := heifExifBlock(old)
+			if err != nil {
+				return nil, err
+			}
+			if block, err = editEXIF(block, opts.Set, opts.Delete); err != nil {
+				return nil, err
+			}
+			ch.replace[it.id] = heifExifItem(block)
+		} else if len(opts.Set) > 0 {
+			block, err := editEXIF(nil, opts.Set, nil)
+			if err != nil {
+				return nil, err
+			}
+			ch.add = append(ch.add, heifNewItem{typ: "Exif", data: heifExifItem(block)})
+		}
+	}
+	if len(kw) > 0 {
+		if it := f.metadataItem("mime"); it != nil {
+			old, _, err := f.itemData(it.id)
+			if err != nil {
+				return nil, err
+			}
+			ch.replace[it.id] = xmp.Repad(applyXMPEdit(old, kw), old)
+		} else if !kw.removesOnly() {
+			ch.add = append(ch.add, heifNewItem{typ: "mime", contentType: mimeXMP, data: xmp.Repad(applyXMPEdit(nil, kw), nil)})
+		}
+	}
+	if ch.empty() {
+		return data, nil
+	}
+	return f.rewrite(data, ch)
+}
+
+func stripHEIC(path, outPath string, opts core.StripOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := stripHEICData(data, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
+
+// stripHEICData removes the Exif and XMP items of a HEIC, or with
+// --gps-only the GPS IFD of its Exif. The colour profile, a property of
+// the image rather than an item, is kept.
+func stripHEICData(data []byte, opts core.StripOptions) ([]byte, error) {
+	f, err := readHEIF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	keepSet := make(map[string]bool)
+	for _, k := range opts.KeepFields {
+		keepSet[strings.ToLower(k)] = true
+	}
+	ch := heifChange{replace: map[uint32][]byte{}}
+	if opts.StripRegions {
+		removed := false
+		if it := f.metadataItem("mime"); it != nil {
+			old, _, err := f.itemData(it.id)
+			if err != nil {
+				return nil, err
+			}
+			if packet, ok := removeFaceRegions(old); ok {
+				ch.replace[it.id], removed = packet, true
+			}
+		}
+		if !removed {
+			fmt.Println("  Note: no face regions found")
+		}
+	}
+	regionsOnly := opts.StripRegions && !opts.StripAll && !opts.StripGPS && len(keepSet) == 0
+	for _, it := range f.items {
+		if regionsOnly {
+			break
+		}
+		switch {
+		case it.isMetadata("Exif") && opts.StripGPS:
+			old, _, err := f.itemData(it.id)
+			if err != nil {
+				return nil, err
+			}
+			block, err := heifExifBlock(old)
+			if err != nil {
+				return nil, err
+			}
+			if block, err = stripEXIFGPS(block); err != nil {
+				return nil, err
+			}
+			ch.replace[it.id] = heifExifItem(block)
+		case opts.StripGPS:
+		case it.isMetadata("Exif") && (opts.StripAll || !keepSet["exif"]),
+			it.isMetadata("mime") && (opts.StripAll || !keepSet["xmp"]):
+			ch.replace[it.id] = nil
+		}
+	}
+	if ch.empty() {
+		return data, nil
+	}
+	return f.rewrite(data, ch)
+}
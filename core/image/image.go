@@ -7,14 +7,17 @@ import (
 	"encoding/binary"
 	"encoding/xml"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
 	"github.com/rwcarlsen/goexif/exif"
 	"github.com/rwcarlsen/goexif/tiff"
 )
@@ -45,12 +48,15 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "EXIF, XMP, IPTC metadata. Edit supports common EXIF text fields.",
+		StripsGPS:   true,
+		Notes:       "EXIF, XMP, IPTC metadata. Edit supports common EXIF text fields, XMP properties and IPTC datasets.",
 		EditableFields: []string{
 			"Make", "Model", "Software", "Artist", "Copyright",
 			"ImageDescription", "UserComment", "DateTime",
 			"DateTimeOriginal", "DateTimeDigitized",
+			"Caption", "Headline", "Byline", "City", "Country", "CopyrightNotice",
 		},
+		Options: []core.FormatOption{optJPEGKeepICC},
 	},
 	core.FmtPNG: {
 		Name:        "PNG",
@@ -60,12 +66,14 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
+		StripsGPS:   true,
 		Notes:       "tEXt, iTXt, zTXt, eXIf chunks.",
 		EditableFields: []string{
 			"Title", "Author", "Description", "Copyright",
 			"Comment", "Creation Time", "Source", "Software",
 			"Disclaimer", "Warning",
 		},
+		Options: []core.FormatOption{optPNGTextChunk},
 	},
 	core.FmtGIF: {
 		Name:       "GIF",
@@ -85,17 +93,20 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:    true,
 		CanEdit:    false,
 		CanStrip:   true,
+		StripsGPS:  true,
 		Notes:      "EXIF and XMP chunks in RIFF container.",
 	},
 	core.FmtTIFF: {
 		Name:       "TIFF",
-		Extensions: []string{".tiff", ".tif"},
+		Extensions: []string{".tiff", ".tif", ".dng"},
 		MediaType:  "image",
-		MIMETypes:  []string{"image/tiff"},
+		MIMETypes:  []string{"image/tiff", "image/x-adobe-dng"},
 		CanView:    true,
-		CanEdit:    false,
+		CanEdit:    true,
 		CanStrip:   false,
-		Notes:      "IFD-based metadata. View only in v0.1.2.",
+		StripsGPS:  true,
+		Notes:      "IFD-based metadata, DNG included. Edit overwrites Artist, Copyright, ImageDescription and the XMP packet in place, and refuses a value that does not fit; strip --gps-only removes the GPS IFD. Image data never moves.",
+		EditableFields: []string{"Artist", "Copyright", "ImageDescription"},
 	},
 	core.FmtBMP: {
 		Name:       "BMP",
@@ -113,9 +124,15 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:  "image",
 		MIMETypes:  []string{"image/heic", "image/heif"},
 		CanView:    true,
-		CanEdit:    false,
-		CanStrip:   false,
-		Notes:      "EXIF embedded in ISOBMFF container. View only in v0.1.2.",
+		CanEdit:    true,
+		CanStrip:   true,
+		StripsGPS:  true,
+		Notes:      "Exif and XMP items of the ISOBMFF meta box. Edit and strip rewrite the items and fix up iloc offsets; the colour profile is kept.",
+		EditableFields: []string{
+			"Make", "Model", "Software", "Artist", "Copyright",
+			"ImageDescription", "UserComment", "DateTime",
+			"DateTimeOriginal", "DateTimeDigitized",
+		},
 	},
 }
 
@@ -125,6 +142,9 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 
 func (h *Handler) View(path string) (*core.Metadata, error) {
 	m := &core.Metadata{FilePath: path}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return m, err
+	}
 	ext := strings.ToLower(filepath.Ext(path))
 
 	switch h.format {
@@ -142,6 +162,9 @@ func (h *Handler) View(path string) (*core.Metadata, error) {
 		return viewWebP(path, m)
 	case core.FmtTIFF:
 		m.Format = "TIFF"
+		if ext == ".dng" {
+			m.Format = "DNG"
+		}
 		return viewTIFF(path, m)
 	case core.FmtBMP:
 		m.Format = "BMP"
@@ -166,35 +189,51 @@ func viewJPEG(path string, m *core.Metadata) (*core.Metadata, error) {
 		return m, err
 	}
 	defer f.Close()
+	return viewJPEGFrom(f, m)
+}
 
+func viewJPEGFrom(f io.ReadSeeker, m *core.Metadata) (*core.Metadata, error) {
 	// EXIF via goexif
+	from := len(m.Fields)
 	x, err := exif.Decode(f)
 	if err == nil {
-		editableSet := map[string]bool{
-			"Make": true, "Model": true, "Software": true, "Artist": true,
-			"Copyright": true, "ImageDescription": true, "UserComment": true,
-			"DateTime": true, "DateTimeOriginal": true, "DateTimeDigitized": true,
+		x.Walk(exifWalker{m: m, editableSet: exifEditable})
+		decodeGPS(m, from)
+		f.Seek(0, io.SeekStart)
+		if _, idx, off := extractJPEGSegment(f, 0xE1, []byte("Exif\x00\x00")); off >= 0 {
+			m.SetSource(from, core.FieldSource{Store: "EXIF", Structure: "APP1", Index: idx, Offset: off})
 		}
-		x.Walk(exifWalker{m: m, editableSet: editableSet})
 	}
 
 	// XMP — scan for APP1 with XMP namespace
 	f.Seek(0, io.SeekStart)
-	xmpData := extractJPEGSegment(f, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))
+	xmpData, idx, off := extractJPEGSegment(f, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))
 	if len(xmpData) > 0 {
+		from = len(m.Fields)
 		parseXMPInto(xmpData, m)
+		m.SetSource(from, core.FieldSource{Store: "XMP", Structure: "APP1", Index: idx, Offset: off})
 	}
 
 	// IPTC — scan APP13
 	f.Seek(0, io.SeekStart)
-	iptcData := extractJPEGSegment(f, 0xED, []byte("Photoshop 3.0\x00"))
+	iptcData, idx, off := extractJPEGSegment(f, 0xED, []byte("Photoshop 3.0\x00"))
 	if len(iptcData) > 0 {
+		from = len(m.Fields)
 		parseIPTCInto(iptcData, m)
+		m.SetSource(from, core.FieldSource{Store: "IPTC", Structure: "APP13", Index: idx, Offset: off})
 	}
 
 	return m, nil
 }
 
+// exifEditable lists the EXIF tags edit can write, in JPEG and HEIC alike.
+var exifEditable = map[string]bool{
+	"Make": true, "Model": true, "Software": true, "Artist": true,
+	"Copyright": true, "ImageDescription": true, "UserComment": true,
+	"DateTime": true, "DateTimeOriginal": true, "DateTimeDigitized": true,
+	"GPSLatitude": true, "GPSLongitude": true, "GPSAltitude": true,
+}
+
 type exifWalker struct {
 	m          *core.Metadata
 	editableSet map[string]bool
@@ -206,6 +245,9 @@ func (w exifWalker) Walk(name exif.FieldName, tag *tiff.Tag) error {
 	if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
 		val = val[1 : len(val)-1]
 	}
+	if name == exif.UserComment && tag.Type == tiff.DTUndefined {
+		val = decodeUserComment(tag.Val)
+	}
 	w.m.Fields = append(w.m.Fields, core.MetaField{
 		Key:      string(name),
 		Value:    val,
@@ -216,57 +258,91 @@ func (w exifWalker) Walk(name exif.FieldName, tag *tiff.Tag) error {
 }
 
 // extractJPEGSegment finds a JPEG APP segment by marker byte and optional prefix.
-// Returns the segment data (after the prefix), or nil.
-func extractJPEGSegment(r io.ReadSeeker, marker byte, prefix []byte) []byte {
-	buf := make([]byte, 2)
+// Returns the segment data (after the prefix), or nil, with the position of
+// the segment among those with its marker and the offset of its marker in
+// the file (-1 when not found). Only the matching segment is read; the
+// others are skipped with a seek.
+func extractJPEGSegment(r io.ReadSeeker, marker byte, prefix []byte) ([]byte, int, int64) {
+	buf := make([]byte, 4)
 	// Read SOI
-	if _, err := io.ReadFull(r, buf); err != nil {
-		return nil
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return nil, 0, -1
 	}
 	if buf[0] != 0xFF || buf[1] != 0xD8 {
-		return nil
+		return nil, 0, -1
 	}
+	off, idx := int64(2), 0
 	for {
 		if _, err := io.ReadFull(r, buf); err != nil {
-			return nil
+			return nil, 0, -1
 		}
 		if buf[0] != 0xFF {
-			return nil
+			return nil, 0, -1
 		}
 		segMarker := buf[1]
-		lenBuf := make([]byte, 2)
-		if _, err := io.ReadFull(r, lenBuf); err != nil {
-			return nil
-		}
-		segLen := int(binary.BigEndian.Uint16(lenBuf)) - 2
+		segLen := int(binary.BigEndian.Uint16(buf[2:])) - 2
 		if segLen < 0 {
-			return nil
-		}
-		data := make([]byte, segLen)
-		if _, err := io.ReadFull(r, data); err != nil {
-			return nil
-		}
-		if segMarker == marker {
-			if len(prefix) == 0 || bytes.HasPrefix(data, prefix) {
-				if len(prefix) <= len(data) {
-					return data[len(prefix):]
-				}
-			}
+			return nil, 0, -1
 		}
 		// Stop at SOS (start of scan)
 		if segMarker == 0xDA {
 			break
 		}
+		if segMarker != marker || segLen < len(prefix) {
+			if _, err := r.Seek(int64(segLen), io.SeekCurrent); err != nil {
+				return nil, 0, -1
+			}
+			if segMarker == marker {
+				idx++
+			}
+			off += 4 + int64(segLen)
+			continue
+		}
+		data := make([]byte, segLen)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, 0, -1
+		}
+		if bytes.HasPrefix(data, prefix) {
+			return data[len(prefix):], idx, off
+		}
+		idx++
+		off += 4 + int64(segLen)
 	}
-	return nil
+	return nil, 0, -1
 }
 
 // ─── XMP ─────────────────────────────────────────────────────────────────────
 
 func parseXMPInto(data []byte, m *core.Metadata) {
-	// Simple XMP key extraction using a generic approach
+	// Each property — a child element or an attribute of a top-level
+	// rdf:Description — becomes one field, an array as a "; "-separated
+	// list. Properties of the namespaces core/xmp knows are named
+	// xmp:prefix:name, as edit takes them; others by their local name.
+	// Keyword bags and this tool's own properties are gathered by name
+	// (see xmp.go) and face regions are shown one per region (see
+	// regions.go).
 	dec := xml.NewDecoder(bytes.NewReader(data))
-	var current string
+	var prop *xml.Name // property element being read
+	var items []string
+	depth := 0 // elements open inside prop
+	var current xml.Name
+	var bag *xmpProp
+	bags := make(map[string][]string)
+	inRegions := 0
+	add := func(name xml.Name, val string, editable bool) {
+		key := "xmp:" + name.Local
+		if prefix := xmp.Prefix(name.Space); prefix != "" {
+			key = "xmp:" + prefix + ":" + name.Local
+		} else {
+			editable = false
+		}
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      key,
+			Value:    val,
+			Category: "XMP",
+			Editable: editable,
+		})
+	}
 	for {
 		tok, err := dec.Token()
 		if err != nil {
@@ -274,116 +350,87 @@ func parseXMPInto(data []byte, m *core.Metadata) {
 		}
 		switch t := tok.(type) {
 		case xml.StartElement:
-			current = t.Name.Local
+			if inRegions > 0 || (t.Name.Space == nsMWGRegions && t.Name.Local == "Regions") {
+				inRegions++
+				continue
+			}
+			current = t.Name
+			top := prop == nil
+			switch {
+			case !top:
+				depth++
+			case t.Name.Space != xmp.NSRDF && t.Name.Space != "adobe:ns:meta/":
+				name := t.Name
+				prop, items, depth = &name, nil, 0
+				bag = nil
+				for i, b := range xmpProps {
+					if t.Name.Space == b.NS && t.Name.Local == b.Local {
+						bag = &xmpProps[i]
+					}
+				}
+			}
 			// Also capture attributes as fields
+			isDesc := t.Name.Space == xmp.NSRDF && t.Name.Local == "Description"
 			for _, attr := range t.Attr {
-				if attr.Name.Local == "xmlns" || strings.HasPrefix(attr.Name.Local, "xmlns") {
+				switch attr.Name.Space {
+				case "xmlns", xmp.NSRDF, "http://www.w3.org/XML/1998/namespace":
 					continue
 				}
-				if attr.Value != "" {
-					m.Fields = append(m.Fields, core.MetaField{
-						Key:      "xmp:" + attr.Name.Local,
-						Value:    attr.Value,
-						Category: "XMP",
-						Editable: false,
-					})
+				if attr.Name.Local == "xmlns" || attr.Value == "" {
+					continue
 				}
+				if top && isDesc {
+					if i := xmpPropIndex(attr.Name); i >= 0 {
+						bags[xmpProps[i].field] = append(bags[xmpProps[i].field], attr.Value)
+						continue
+					}
+				}
+				add(attr.Name, attr.Value, top && isDesc)
+			}
+		case xml.EndElement:
+			if inRegions > 0 {
+				inRegions--
+				continue
 			}
+			if prop == nil {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			switch {
+			case bag != nil:
+				bags[bag.field] = append(bags[bag.field], items...)
+			case len(items) > 0:
+				add(*prop, strings.Join(items, "; "), true)
+			}
+			prop, bag = nil, nil
 		case xml.CharData:
 			val := strings.TrimSpace(string(t))
-			if val != "" && current != "" && current != "xmpmeta" && current != "RDF" {
-				m.Fields = append(m.Fields, core.MetaField{
-					Key:      "xmp:" + current,
-					Value:    val,
-					Category: "XMP",
-					Editable: false,
-				})
+			if inRegions > 0 || val == "" || prop == nil {
+				continue
+			}
+			if depth == 0 || depth == 2 && current.Space == xmp.NSRDF && current.Local == "li" {
+				// The property itself, or an item of its array.
+				items = append(items, val)
+			} else {
+				// A field of a structure.
+				add(current, val, false)
 			}
 		}
 	}
-
-}
-
-// ─── IPTC ─────────────────────────────────────────────────────────────────────
-
-var iptcFieldNames = map[byte]string{
-	0x05: "ObjectName",
-	0x0F: "Category",
-	0x14: "SupplementalCategory",
-	0x19: "Keywords",
-	0x1E: "DateCreated",
-	0x1F: "TimeCreated",
-	0x28: "SpecialInstructions",
-	0x37: "DigitalCreationDate",
-	0x3C: "Byline",
-	0x3E: "BylineTitle",
-	0x46: "City",
-	0x4E: "Province",
-	0x55: "Country",
-	0x67: "OriginalTransmissionReference",
-	0x69: "Headline",
-	0x6E: "Credit",
-	0x73: "Source",
-	0x74: "CopyrightNotice",
-	0x76: "Contact",
-	0x78: "Caption",
-	0x7A: "CaptionWriter",
-}
-
-func parseIPTCInto(data []byte, m *core.Metadata) {
-	// Skip "8BIM" Photoshop resource blocks to find IPTC resource (0x0404)
-	i := 0
-	for i+8 < len(data) {
-		if !bytes.Equal(data[i:i+4], []byte("8BIM")) {
-			i++
-			continue
-		}
-		resType := binary.BigEndian.Uint16(data[i+4 : i+6])
-		nameLen := int(data[i+6])
-		if nameLen%2 == 0 {
-			nameLen++
-		}
-		i += 7 + nameLen
-		if i+4 > len(data) {
-			break
-		}
-		blockLen := int(binary.BigEndian.Uint32(data[i : i+4]))
-		i += 4
-		if resType == 0x0404 && i+blockLen <= len(data) {
-			parseIPTCBlock(data[i:i+blockLen], m)
-		}
-		i += blockLen
-		if blockLen%2 != 0 {
-			i++
-		}
-	}
-}
-
-func parseIPTCBlock(data []byte, m *core.Metadata) {
-	i := 0
-	for i+5 < len(data) {
-		if data[i] != 0x1C {
-			i++
-			continue
-		}
-		// record := data[i+1]
-		dataset := data[i+2]
-		length := int(binary.BigEndian.Uint16(data[i+3 : i+5]))
-		i += 5
-		if i+length > len(data) {
-			break
-		}
-		val := string(data[i : i+length])
-		if name, ok := iptcFieldNames[dataset]; ok {
+	for _, b := range xmpProps {
+		if items := bags[b.field]; len(items) > 0 {
 			m.Fields = append(m.Fields, core.MetaField{
-				Key:      name,
-				Value:    val,
-				Category: "IPTC",
-				Editable: false,
+				Key:      b.field,
+				Value:    strings.Join(items, "; "),
+				Category: "XMP",
+				Editable: true,
 			})
 		}
-		i += length
 	}
+	appendRegionFields(data, m)
 }
 
 // ─── PNG ─────────────────────────────────────────────────────────────────────
@@ -395,12 +442,34 @@ func viewPNG(path string, m *core.Metadata) (*core.Metadata, error) {
 	}
 	defer f.Close()
 
-	chunks, err := readPNGChunks(f)
+	chunks, err := indexPNGChunks(f)
 	if err != nil {
 		return m, err
 	}
+	return viewPNGChunks(chunks, f, m)
+}
 
+// viewPNGChunks reads the metadata chunks of chunks, loading their data
+// from r when they were only indexed.
+func viewPNGChunks(chunks []pngChunk, r io.ReaderAt, m *core.Metadata) (*core.Metadata, error) {
+	off := int64(len(pngSignature))
+	count := map[string]int{}
 	for _, c := range chunks {
+		chunkOff, idx := off, count[c.typ]
+		off += 12 + int64(c.size)
+		count[c.typ]++
+		switch c.typ {
+		case "tEXt", "iTXt", "eXIf", "tIME":
+			if err := c.load(r); err != nil {
+				return m, err
+			}
+		default:
+			continue // image data and the like are never read
+		}
+		from := len(m.Fields)
+		source := func(store string) {
+			m.SetSource(from, core.FieldSource{Store: store, Structure: c.typ, Index: idx, Offset: chunkOff})
+		}
 		switch c.typ {
 		case "tEXt":
 			// Format: keyword\0value
@@ -419,6 +488,11 @@ func viewPNG(path string, m *core.Metadata) (*core.Metadata, error) {
 				})
 			}
 		case "iTXt":
+			if key, text, ok := pngITXtText(c.data); ok && key == pngXMPKeyword {
+				parseXMPInto(text, m)
+				source("XMP")
+				continue
+			}
 			// Format: keyword\0compression_flag\0compression_method\0language\0translated_keyword\0text
 			null := bytes.IndexByte(c.data, 0)
 			if null > 0 {
@@ -449,7 +523,9 @@ func viewPNG(path string, m *core.Metadata) (*core.Metadata, error) {
 			x, err := exif.Decode(bytes.NewReader(c.data))
 			if err == nil {
 				x.Walk(exifWalker{m: m})
+				decodeGPS(m, from)
 			}
+			source("EXIF")
 		case "tIME":
 			if len(c.data) == 7 {
 				year := binary.BigEndian.Uint16(c.data[0:2])
@@ -461,6 +537,7 @@ func viewPNG(path string, m *core.Metadata) (*core.Metadata, error) {
 				})
 			}
 		}
+		source("PNG " + c.typ)
 	}
 	return m, nil
 }
@@ -468,38 +545,84 @@ func viewPNG(path string, m *core.Metadata) (*core.Metadata, error) {
 type pngChunk struct {
 	typ  string
 	data []byte
+	// off and size locate the data in the file for chunks listed by
+	// indexPNGChunks; data stays nil until load reads it.
+	off  int64
+	size uint32
 }
 
+var pngSignature = []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+// readPNGChunks reads every chunk, data included. Writers need that;
+// readers that only want a few chunks use indexPNGChunks.
 func readPNGChunks(r io.Reader) ([]pngChunk, error) {
 	sig := make([]byte, 8)
 	if _, err := io.ReadFull(r, sig); err != nil {
 		return nil, err
 	}
-	expected := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
-	if !bytes.Equal(sig, expected) {
+	if !bytes.Equal(sig, pngSignature) {
 		return nil, fmt.Errorf("not a valid PNG")
 	}
 
 	var chunks []pngChunk
+	hdr := make([]byte, 8)
 	for {
-		lenBuf := make([]byte, 4)
-		if _, err := io.ReadFull(r, lenBuf); err != nil {
-			break
-		}
-		length := binary.BigEndian.Uint32(lenBuf)
-		typBuf := make([]byte, 4)
-		if _, err := io.ReadFull(r, typBuf); err != nil {
+		if _, err := io.ReadFull(r, hdr); err != nil {
 			break
 		}
+		length := binary.BigEndian.Uint32(hdr[:4])
 		data := make([]byte, length)
 		if _, err := io.ReadFull(r, data); err != nil {
 			break
 		}
-		crcBuf := make([]byte, 4)
-		io.ReadFull(r, crcBuf)
+		io.ReadFull(r, hdr[:4]) // CRC
+
+		typ := string(hdr[4:8])
+		chunks = append(chunks, pngChunk{typ: typ, data: data, size: length})
+		if typ == "IEND" {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// indexPNGChunks lists the chunks of a PNG with their offsets and sizes,
+// seeking past the data instead of reading it.
+func indexPNGChunks(r io.ReadSeeker) ([]pngChunk, error) {
+	sig := make([]byte, 8)
+	if _, err := io.ReadFull(r, sig); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sig, pngSignature) {
+		return nil, fmt.Errorf("not a valid PNG")
+	}
+
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(8, io.SeekStart); err != nil {
+		return nil, err
+	}
 
-		typ := string(typBuf)
-		chunks = append(chunks, pngChunk{typ: typ, data: data})
+	var chunks []pngChunk
+	hdr := make([]byte, 8)
+	off := int64(8)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(hdr[:4])
+		typ := string(hdr[4:8])
+		if off+8+int64(length) > fileSize {
+			break // truncated, as readPNGChunks drops it
+		}
+		next := off + 12 + int64(length)
+		if _, err := r.Seek(next, io.SeekStart); err != nil {
+			break
+		}
+		chunks = append(chunks, pngChunk{typ: typ, off: off + 8, size: length})
+		off = next
 		if typ == "IEND" {
 			break
 		}
@@ -507,6 +630,20 @@ func readPNGChunks(r io.Reader) ([]pngChunk, error) {
 	return chunks, nil
 }
 
+// load reads the data of an indexed chunk. A chunk cut short by the end
+// of the file is an error.
+func (c *pngChunk) load(r io.ReaderAt) error {
+	if c.data != nil {
+		return nil
+	}
+	data := make([]byte, c.size)
+	if _, err := r.ReadAt(data, c.off); err != nil {
+		return fmt.Errorf("PNG %s chunk: %w", c.typ, err)
+	}
+	c.data = data
+	return nil
+}
+
 // ─── GIF ─────────────────────────────────────────────────────────────────────
 
 func viewGIF(path string, m *core.Metadata) (*core.Metadata, error) {
@@ -514,6 +651,10 @@ func viewGIF(path string, m *core.Metadata) (*core.Metadata, error) {
 	if err != nil {
 		return m, err
 	}
+	return viewGIFData(data, m)
+}
+
+func viewGIFData(data []byte, m *core.Metadata) (*core.Metadata, error) {
 
 	if len(data) < 6 {
 		return m, fmt.Errorf("file too short")
@@ -544,13 +685,15 @@ func viewGIF(path string, m *core.Metadata) (*core.Metadata, error) {
 		i += colorTableSize * 3
 	}
 
-	commentCount := 0
+	commentCount, extCount := 0, 0
 	for i < len(data)-1 {
 		if data[i] == 0x3B { // trailer
 			break
 		}
 		if data[i] == 0x21 && i+1 < len(data) && data[i+1] == 0xFE {
 			// Comment extension
+			src := core.FieldSource{Store: "GIF Comment", Structure: "Comment Extension", Index: extCount, Offset: int64(i)}
+			extCount++
 			i += 2
 			var comment []byte
 			for i < len(data) {
@@ -572,6 +715,7 @@ func viewGIF(path string, m *core.Metadata) (*core.Metadata, error) {
 					Value:    string(comment),
 					Category: "GIF Comment",
 					Editable: false,
+					Source:   &src,
 				})
 			}
 			continue
@@ -588,46 +732,267 @@ func viewWebP(path string, m *core.Metadata) (*core.Metadata, error) {
 	if err != nil {
 		return m, err
 	}
-	if len(data) < 12 {
-		return m, fmt.Errorf("file too short")
-	}
+	return viewWebPData(data, m)
+}
 
-	// Parse RIFF chunks
-	offset := 12 // skip RIFF header
-	for offset+8 <= len(data) {
-		chunkID := string(data[offset : offset+4])
-		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
-		offset += 8
-		if offset+chunkSize > len(data) {
-			break
-		}
-		chunkData := data[offset : offset+chunkSize]
+func viewWebPData(data []byte, m *core.Metadata) (*core.Metadata, error) {
+	// A truncated trailing chunk still leaves everything before it readable.
+	chunks, err := readWebPChunks(data)
+	if err != nil && len(chunks) == 0 {
+		return m, err
+	}
 
-		switch chunkID {
+	frames, totalMS := 0, 0
+	off := int64(12) // RIFF header
+	count := map[string]int{}
+	for _, c := range chunks {
+		from := len(m.Fields)
+		src := core.FieldSource{Store: "WebP", Structure: c.id, Index: count[c.id], Offset: off}
+		off += 8 + int64(len(c.data)+len(c.data)%2)
+		count[c.id]++
+		switch c.id {
 		case "EXIF":
-			x, err := exif.Decode(bytes.NewReader(chunkData))
+			x, err := exif.Decode(bytes.NewReader(c.data))
 			if err == nil {
 				x.Walk(exifWalker{m: m})
+				decodeGPS(m, from)
 			}
+			src.Store = "EXIF"
 		case "XMP ":
-			if utf8.Valid(chunkData) {
-				parseXMPInto(chunkData, m)
+			if utf8.Valid(c.data) {
+				parseXMPInto(c.data, m)
 			}
+			src.Store = "XMP"
 		case "VP8 ", "VP8L", "VP8X":
 			m.Fields = append(m.Fields, core.MetaField{
 				Key:      "Encoding",
-				Value:    strings.TrimSpace(chunkID),
+				Value:    strings.TrimSpace(c.id),
 				Category: "WebP",
 				Editable: false,
 			})
+			if c.id == "VP8X" && len(c.data) >= 10 {
+				w, h := vp8xCanvas(c.data)
+				m.Fields = append(m.Fields,
+					core.MetaField{Key: "Canvas", Value: fmt.Sprintf("%d x %d", w, h), Category: "WebP", Editable: false},
+					core.MetaField{Key: "Features", Value: vp8xFlagNames(c.data[0]), Category: "WebP", Editable: false},
+				)
+			}
+		case "ANIM":
+			// 4 bytes background colour (BGRA), 2 bytes loop count (0 = infinite)
+			if len(c.data) >= 6 {
+				loops := "infinite"
+				if n := binary.LittleEndian.Uint16(c.data[4:6]); n != 0 {
+					loops = fmt.Sprintf("%d", n)
+				}
+				m.Fields = append(m.Fields, core.MetaField{
+					Key:      "LoopCount",
+					Value:    loops,
+					Category: "WebP Animation",
+					Editable: false,
+				})
+			}
+		case "ANMF":
+			// 3 bytes X, Y, width-1, height-1, duration (ms), then 1 byte flags
+			frames++
+			if len(c.data) >= 15 {
+				totalMS += int(c.data[12]) | int(c.data[13])<<8 | int(c.data[14])<<16
+			}
 		}
+		m.SetSource(from, src)
+	}
+	if frames > 0 {
+		m.Fields = append(m.Fields,
+			core.MetaField{Key: "FrameCount", Value: fmt.Sprintf("%d", frames), Category: "WebP Animation", Editable: false},
+			core.MetaField{Key: "Duration", Value: fmt.Sprintf("%d ms", totalMS), Category: "WebP Animation", Editable: false},
+		)
+	}
+	return m, nil
+}
+
+// webpChunk is a single RIFF chunk inside a WebP container.
+type webpChunk struct {
+	id   string
+	data []byte
+}
 
+// readWebPChunks splits a WebP file into its RIFF chunks, in file order.
+// Chunk order is significant (VP8X, ICCP, ANIM, ANMF frames, EXIF, XMP) and
+// callers that rewrite the file must keep it. A truncated chunk is reported
+// as an error together with the chunks read so far, so that writers never
+// silently drop the tail of an animation.
+func readWebPChunks(data []byte) ([]webpChunk, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("WebP too short")
+	}
+	var chunks []webpChunk
+	offset := 12 // skip RIFF header
+	for offset+8 <= len(data) {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		offset += 8
+		if offset+chunkSize > len(data) {
+			return chunks, fmt.Errorf("WebP chunk %q truncated", chunkID)
+		}
+		chunks = append(chunks, webpChunk{id: chunkID, data: data[offset : offset+chunkSize]})
 		offset += chunkSize
 		if chunkSize%2 != 0 {
 			offset++ // padding
 		}
 	}
-	return m, nil
+	return chunks, nil
+}
+
+// buildWebP serialises chunks into a complete RIFF/WEBP file.
+func buildWebP(chunks []webpChunk) []byte {
+	var body bytes.Buffer
+	for _, c := range chunks {
+		body.WriteString(c.id)
+		sizeBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sizeBuf, uint32(len(c.data)))
+		body.Write(sizeBuf)
+		body.Write(c.data)
+		if len(c.data)%2 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	totalSize := make([]byte, 4)
+	binary.LittleEndian.PutUint32(totalSize, uint32(body.Len()+4))
+	out.Write(totalSize)
+	out.WriteString("WEBP")
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// ─── WebP VP8X ────────────────────────────────────────────────────────────────
+// The VP8X chunk carries feature flags that must agree with the chunks that
+// are actually present, otherwise decoders may reject the file or look for
+// metadata that is no longer there.
+//
+// Layout: 1 byte flags, 3 bytes reserved, 3 bytes canvas width-1 (LE),
+// 3 bytes canvas height-1 (LE).
+
+const (
+	vp8xFlagAnimation = 0x02
+	vp8xFlagXMP       = 0x04
+	vp8xFlagEXIF      = 0x08
+	vp8xFlagAlpha     = 0x10
+	vp8xFlagICC       = 0x20
+)
+
+func vp8xCanvas(data []byte) (w, h int) {
+	w = int(data[4]) | int(data[5])<<8 | int(data[6])<<16
+	h = int(data[7]) | int(data[8])<<8 | int(data[9])<<16
+	return w + 1, h + 1
+}
+
+func vp8xFlagNames(flags byte) string {
+	var names []string
+	for _, f := range []struct {
+		bit  byte
+		name string
+	}{
+		{vp8xFlagICC, "ICC"}, {vp8xFlagAlpha, "Alpha"}, {vp8xFlagEXIF, "EXIF"},
+		{vp8xFlagXMP, "XMP"}, {vp8xFlagAnimation, "Animation"},
+	} {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// webpBitstreamSize returns the frame dimensions encoded in a VP8 or VP8L
+// bitstream chunk, and whether the VP8L header advertises alpha.
+func webpBitstreamSize(c webpChunk) (w, h int, alpha bool, ok bool) {
+	switch c.id {
+	case "VP8 ":
+		// 3-byte frame tag, 3-byte start code 9D 01 2A, then 14-bit sizes.
+		if len(c.data) < 10 || c.data[3] != 0x9D || c.data[4] != 0x01 || c.data[5] != 0x2A {
+			return 0, 0, false, false
+		}
+		w = int(binary.LittleEndian.Uint16(c.data[6:8]) & 0x3FFF)
+		h = int(binary.LittleEndian.Uint16(c.data[8:10]) & 0x3FFF)
+		return w, h, false, true
+	case "VP8L":
+		// Signature 0x2F, then 14-bit width-1, 14-bit height-1, 1-bit alpha.
+		if len(c.data) < 5 || c.data[0] != 0x2F {
+			return 0, 0, false, false
+		}
+		bits := binary.LittleEndian.Uint32(c.data[1:5])
+		w = int(bits&0x3FFF) + 1
+		h = int((bits>>14)&0x3FFF) + 1
+		alpha = (bits>>28)&1 != 0
+		return w, h, alpha, true
+	}
+	return 0, 0, false, false
+}
+
+// syncVP8X brings the VP8X chunk in line with the chunks present. Flags for
+// ICC, EXIF, XMP and animation are recomputed; the alpha flag and canvas size
+// are left as-is. If extended features are present but there is no VP8X
+// chunk (a simple lossy/lossless file gaining metadata), one is created with
+// the canvas size taken from the image bitstream.
+func syncVP8X(chunks []webpChunk) []webpChunk {
+	var flags byte
+	vp8xIdx := -1
+	for i, c := range chunks {
+		switch c.id {
+		case "VP8X":
+			vp8xIdx = i
+		case "ICCP":
+			flags |= vp8xFlagICC
+		case "EXIF":
+			flags |= vp8xFlagEXIF
+		case "XMP ":
+			flags |= vp8xFlagXMP
+		case "ANIM":
+			flags |= vp8xFlagAnimation
+		case "ALPH":
+			flags |= vp8xFlagAlpha
+		}
+	}
+
+	if vp8xIdx >= 0 {
+		old := chunks[vp8xIdx].data
+		if len(old) < 10 {
+			return chunks
+		}
+		data := append([]byte{}, old...)
+		data[0] = flags | (old[0] & vp8xFlagAlpha)
+		chunks[vp8xIdx].data = data
+		return chunks
+	}
+
+	if flags == 0 {
+		return chunks // simple format is still valid
+	}
+
+	var w, h int
+	found := false
+	for _, c := range chunks {
+		var alpha bool
+		if w, h, alpha, found = webpBitstreamSize(c); found {
+			if alpha {
+				flags |= vp8xFlagAlpha
+			}
+			break
+		}
+	}
+	if !found {
+		return chunks
+	}
+
+	data := make([]byte, 10)
+	data[0] = flags
+	data[4], data[5], data[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+	data[7], data[8], data[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+	return append([]webpChunk{{id: "VP8X", data: data}}, chunks...)
 }
 
 // ─── TIFF ────────────────────────────────────────────────────────────────────
@@ -643,7 +1008,14 @@ func viewTIFF(path string, m *core.Metadata) (*core.Metadata, error) {
 	if err != nil {
 		return m, fmt.Errorf("could not parse TIFF IFDs: %w", err)
 	}
-	x.Walk(exifWalker{m: m})
+	from := len(m.Fields)
+	x.Walk(exifWalker{m: m, editableSet: tiffEditableNames()})
+	decodeGPS(m, from)
+	if packet, off := readTIFFXMP(f); packet != nil {
+		from = len(m.Fields)
+		parseXMPInto(packet, m)
+		m.SetSource(from, core.FieldSource{Store: "XMP", Structure: "IFD0 tag 700", Offset: off})
+	}
 	return m, nil
 }
 
@@ -671,78 +1043,27 @@ func viewBMP(path string, m *core.Metadata) (*core.Metadata, error) {
 	return m, nil
 }
 
-// ─── HEIC ─────────────────────────────────────────────────────────────────────
-
-func viewHEIC(path string, m *core.Metadata) (*core.Metadata, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return m, err
-	}
-	defer f.Close()
-
-	// HEIC is ISOBMFF — walk boxes looking for 'Exif' or 'uuid' boxes
-	parseISOBMFF(f, m)
-	return m, nil
-}
-
-func parseISOBMFF(r io.ReadSeeker, m *core.Metadata) {
-	for {
-		hdr := make([]byte, 8)
-		if _, err := io.ReadFull(r, hdr); err != nil {
-			break
-		}
-		size := int64(binary.BigEndian.Uint32(hdr[0:4]))
-		boxType := string(hdr[4:8])
-		dataSize := size - 8
-		if size == 1 {
-			// 64-bit size
-			ext := make([]byte, 8)
-			io.ReadFull(r, ext)
-			size = int64(binary.BigEndian.Uint64(ext))
-			dataSize = size - 16
-		}
-		if size == 0 {
-			break
-		}
-
-		switch boxType {
-		case "ftyp":
-			brand := make([]byte, 4)
-			io.ReadFull(r, brand)
-			m.Fields = append(m.Fields, core.MetaField{
-				Key:      "Brand",
-				Value:    strings.TrimSpace(string(brand)),
-				Category: "HEIC",
-				Editable: false,
-			})
-			r.Seek(dataSize-4, io.SeekCurrent)
-		case "Exif":
-			// Skip 4-byte offset prefix
-			skip := make([]byte, 4)
-			io.ReadFull(r, skip)
-			exifData := make([]byte, dataSize-4)
-			io.ReadFull(r, exifData)
-			x, err := exif.Decode(bytes.NewReader(exifData))
-			if err == nil {
-				x.Walk(exifWalker{m: m})
-			}
-		default:
-			r.Seek(dataSize, io.SeekCurrent)
-		}
-	}
-}
-
 // ──────────────────────────────────────────────────────────────────────────────
 // Edit
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	if err := h.Info().CheckOptionValues(opts.Options, false); err != nil {
+		return err
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtJPEG:
 		return editJPEG(path, out, opts)
 	case core.FmtPNG:
 		return editPNG(path, out, opts)
+	case core.FmtTIFF:
+		return editTIFF(path, out, opts)
+	case core.FmtHEIC:
+		return editHEIC(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanEdit {
@@ -753,9 +1074,8 @@ func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error
 }
 
 // ─── JPEG Edit ───────────────────────────────────────────────────────────────
-// Approach: Read all JPEG segments, rebuild APP1/EXIF segment with updated IFD
-// entries using dsoprea/go-exif.  For simplicity in v0.1.2 we implement a
-// targeted string-field replacement by scanning the raw EXIF IFD bytes.
+// Approach: Read all JPEG segments and patch the APP1/EXIF segment in place,
+// changing only the IFD entries being edited (see exifedit.go).
 
 func editJPEG(path, outPath string, opts core.EditOptions) error {
 	data, err := os.ReadFile(path)
@@ -767,6 +1087,36 @@ func editJPEG(path, outPath string, opts core.EditOptions) error {
 	if err != nil {
 		return err
 	}
+	return editJPEGSegments(segments, outPath, opts)
+}
+
+func editJPEGSegments(segments []jpegSegment, outPath string, opts core.EditOptions) error {
+	segments, err := applyJPEGEdits(segments, opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Println("Dry-run: JPEG EXIF would be updated with:")
+		for k, v := range opts.Set {
+			fmt.Printf("  %s = %s\n", k, v)
+		}
+		return nil
+	}
+
+	return writeJPEGSegments(outPath, segments)
+}
+
+// applyJPEGEdits returns segments with the EXIF and XMP changes of opts.
+func applyJPEGEdits(segments []jpegSegment, opts core.EditOptions) ([]jpegSegment, error) {
+	opts, iptc, err := takeIPTCEdits(opts, jpegHasIPTC(segments))
+	if err != nil {
+		return nil, err
+	}
+	opts, keywords, err := takeXMPEdits(opts)
+	if err != nil {
+		return nil, err
+	}
+	exifEdit := len(opts.Set) > 0 || len(opts.Delete) > 0
 
 	// Find APP1 EXIF segment
 	exifSegIdx := -1
@@ -777,11 +1127,13 @@ func editJPEG(path, outPath string, opts core.EditOptions) error {
 		}
 	}
 
-	if exifSegIdx < 0 && len(opts.Set) > 0 {
+	if !exifEdit {
+		// Keywords only — leave EXIF as it is.
+	} else if exifSegIdx < 0 && len(opts.Set) > 0 {
 		// No EXIF yet — create a minimal one
 		newExifData, err := buildMinimalEXIF(opts.Set)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// Insert APP1 after SOI
 		newSeg := jpegSegment{marker: 0xE1, data: newExifData}
@@ -789,20 +1141,19 @@ func editJPEG(path, outPath string, opts core.EditOptions) error {
 	} else if exifSegIdx >= 0 {
 		updated, err := patchEXIFSegment(segments[exifSegIdx].data, opts.Set, opts.Delete)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		segments[exifSegIdx].data = updated
 	}
-
-	if opts.DryRun {
-		fmt.Println("Dry-run: JPEG EXIF would be updated with:")
-		for k, v := range opts.Set {
-			fmt.Printf("  %s = %s\n", k, v)
+	if len(iptc) > 0 {
+		if segments, err = setJPEGIPTC(segments, iptc); err != nil {
+			return nil, err
 		}
-		return nil
 	}
-
-	return writeJPEGSegments(outPath, segments)
+	if len(keywords) > 0 {
+		return setJPEGXMP(segments, keywords)
+	}
+	return segments, nil
 }
 
 type jpegSegment struct {
@@ -810,6 +1161,10 @@ type jpegSegment struct {
 	data   []byte
 }
 
+// parseJPEGSegments splits data into segments without copying: each
+// segment's data is a capped slice of data, so the caller must not change
+// data while the segments are in use. Writers replace a segment's data
+// rather than modifying it.
 func parseJPEGSegments(data []byte) ([]jpegSegment, error) {
 	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
 		return nil, fmt.Errorf("not a JPEG")
@@ -847,13 +1202,18 @@ func parseJPEGSegments(data []byte) ([]jpegSegment, error) {
 		if segLen < 0 || i+segLen > len(data) {
 			break
 		}
-		segs = append(segs, jpegSegment{marker: marker, data: append([]byte{}, data[i:i+segLen]...)})
+		segs = append(segs, jpegSegment{marker: marker, data: data[i : i+segLen : i+segLen]})
 		i += segLen
 	}
 	return segs, nil
 }
 
 func writeJPEGSegments(path string, segs []jpegSegment) error {
+	return os.WriteFile(path, jpegBytes(segs), 0644)
+}
+
+// jpegBytes joins segments back into a JPEG file.
+func jpegBytes(segs []jpegSegment) []byte {
 	var buf bytes.Buffer
 	for _, seg := range segs {
 		switch seg.marker {
@@ -872,7 +1232,7 @@ func writeJPEGSegments(path string, segs []jpegSegment) error {
 			buf.Write(seg.data)
 		}
 	}
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	return buf.Bytes()
 }
 
 // EXIF tag IDs for common editable string fields.
@@ -891,89 +1251,25 @@ var exifTagIDs = map[string]uint16{
 
 // buildMinimalEXIF creates a bare-bones EXIF APP1 segment data with the given fields.
 func buildMinimalEXIF(fields map[string]string) ([]byte, error) {
-	var buf bytes.Buffer
-	// EXIF header
-	buf.WriteString("Exif\x00\x00")
-	// TIFF header (little-endian)
-	buf.WriteString("II")                   // byte order
-	buf.Write([]byte{0x2A, 0x00})           // magic
-	buf.Write([]byte{0x08, 0x00, 0x00, 0x00}) // offset to IFD0
-
-	// Collect valid fields
-	type ifdEntry struct {
-		tag   uint16
-		value string
-	}
-	var entries []ifdEntry
+	return editEXIF(nil, fields, nil)
+}
+
+func copyFields(fields map[string]string) map[string]string {
+	out := make(map[string]string, len(fields))
 	for k, v := range fields {
-		if tid, ok := exifTagIDs[k]; ok {
-			entries = append(entries, ifdEntry{tag: tid, value: v})
-		}
-	}
-
-	if len(entries) == 0 {
-		return nil, fmt.Errorf("no recognised EXIF fields to write; supported: %v", supportedEditFields())
-	}
-
-	// IFD structure (little-endian)
-	// Each entry: 2 tag + 2 type + 4 count + 4 value/offset = 12 bytes
-	numEntries := uint16(len(entries))
-	ifdBase := 8 // offset in TIFF block where IFD starts
-	ifdSize := 2 + int(numEntries)*12 + 4
-	valOffset := ifdBase + ifdSize
-
-	var ifdBuf bytes.Buffer
-	le16 := func(v uint16) { binary.Write(&ifdBuf, binary.LittleEndian, v) }
-	le32 := func(v uint32) { binary.Write(&ifdBuf, binary.LittleEndian, v) }
-
-	le16(numEntries)
-	var valueBuf bytes.Buffer
-	for _, e := range entries {
-		val := e.value + "\x00"
-		le16(e.tag)
-		le16(2) // ASCII type
-		le32(uint32(len(val)))
-		if len(val) <= 4 {
-			// Value fits inline
-			padded := make([]byte, 4)
-			copy(padded, val)
-			ifdBuf.Write(padded)
-		} else {
-			offset := uint32(valOffset + valueBuf.Len())
-			le32(offset)
-			valueBuf.WriteString(val)
-		}
+		out[k] = v
 	}
-	le32(0) // next IFD offset = 0
-
-	buf.Write(ifdBuf.Bytes())
-	buf.Write(valueBuf.Bytes())
-	return buf.Bytes(), nil
+	return out
 }
 
-// patchEXIFSegment updates string IFD entries in an existing EXIF APP1 block.
-// It uses a conservative approach: locate string values by tag and overwrite
-// them in-place if the new value fits, or add new IFD entries otherwise.
+// patchEXIFSegment applies set and del to an existing EXIF APP1 block,
+// keeping every other tag, sub-IFD, thumbnail and MakerNote (see
+// editEXIF).
 func patchEXIFSegment(data []byte, set map[string]string, del []string) ([]byte, error) {
 	if len(data) < 8 {
 		return data, nil
 	}
-	// For v0.1.2, if the segment is present we rebuild with merged fields.
-	// Read existing fields, apply changes, rebuild.
-	existing := map[string]string{}
-	x, err := exif.Decode(bytes.NewReader(data[6:])) // skip "Exif\x00\x00"
-	if err == nil {
-		x.Walk(exifStringWalker{fields: existing})
-	}
-	// Apply set
-	for k, v := range set {
-		existing[k] = v
-	}
-	// Apply delete
-	for _, k := range del {
-		delete(existing, k)
-	}
-	return buildMinimalEXIF(existing)
+	return editEXIF(data, set, del)
 }
 
 type exifStringWalker struct {
@@ -986,6 +1282,9 @@ func (w exifStringWalker) Walk(name exif.FieldName, tag *tiff.Tag) error {
 		if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
 			val = val[1 : len(val)-1]
 		}
+		if name == exif.UserComment && tag.Type == tiff.DTUndefined {
+			val = decodeUserComment(tag.Val)
+		}
 		w.fields[string(name)] = val
 	}
 	return nil
@@ -996,7 +1295,8 @@ func supportedEditFields() []string {
 	for k := range exifTagIDs {
 		fields = append(fields, k)
 	}
-	return fields
+	sort.Strings(fields)
+	return append(fields, "GPSLatitude", "GPSLongitude", "GPSAltitude")
 }
 
 // ─── PNG Edit ────────────────────────────────────────────────────────────────
@@ -1012,28 +1312,81 @@ func editPNG(path, outPath string, opts core.EditOptions) error {
 	if err != nil {
 		return err
 	}
+	return editPNGChunks(chunks, outPath, opts)
+}
+
+func editPNGChunks(chunks []pngChunk, outPath string, opts core.EditOptions) error {
+	final, err := applyPNGEdits(chunks, opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Printf("Dry-run: PNG tEXt chunks would be updated:\n")
+		for k, v := range opts.Set {
+			fmt.Printf("  %s = %s\n", k, v)
+		}
+		return nil
+	}
+
+	return writePNGChunks(outPath, final)
+}
+
+// optPNGTextChunk picks the chunk edited text is written to. tEXt is
+// Latin-1 only; iTXt holds UTF-8, so names and captions outside Latin-1
+// survive.
+var optPNGTextChunk = core.FormatOption{
+	Key:    "png.text-chunk",
+	Values: []string{"tEXt", "iTXt"},
+	Doc:    "write edited text as tEXt (Latin-1, the default) or iTXt (UTF-8)",
+}
+
+// applyPNGEdits returns chunks with the tEXt and XMP changes of opts. With
+// png.text-chunk=iTXt, text goes to iTXt chunks instead, and existing
+// iTXt fields are updated and deleted as tEXt ones are.
+func applyPNGEdits(chunks []pngChunk, opts core.EditOptions) ([]pngChunk, error) {
+	opts, keywords, err := takeXMPEdits(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(keywords) > 0 {
+		chunks = setPNGXMP(chunks, keywords)
+	}
+	textType, _ := optPNGTextChunk.Value(opts.Options) // checked by Edit
+	textChunk := func(key, v string) pngChunk {
+		if textType == "iTXt" {
+			return pngChunk{typ: "iTXt", data: buildPNGITXt(key, []byte(v))}
+		}
+		return pngChunk{typ: "tEXt", data: append([]byte(key+"\x00"), []byte(v)...)}
+	}
 
 	delSet := make(map[string]bool)
 	for _, k := range opts.Delete {
 		delSet[k] = true
 	}
 
-	// Update or remove existing tEXt chunks, then add new ones
+	// Update or remove existing text chunks, then add new ones
 	setDone := make(map[string]bool)
 	var newChunks []pngChunk
 	for _, c := range chunks {
-		if c.typ == "tEXt" {
-			null := bytes.IndexByte(c.data, 0)
-			if null > 0 {
-				key := string(c.data[:null])
-				if delSet[key] {
-					continue // delete
-				}
-				if v, ok := opts.Set[key]; ok {
-					// Update
-					c.data = append([]byte(key+"\x00"), []byte(v)...)
-					setDone[key] = true
-				}
+		key := ""
+		switch {
+		case c.typ == "tEXt":
+			if null := bytes.IndexByte(c.data, 0); null > 0 {
+				key = string(c.data[:null])
+			}
+		case c.typ == "iTXt" && textType == "iTXt":
+			if k, _, ok := pngITXtText(c.data); ok && k != pngXMPKeyword {
+				key = k
+			}
+		}
+		if key != "" {
+			if delSet[key] {
+				continue // delete
+			}
+			if v, ok := opts.Set[key]; ok {
+				// Update
+				c = textChunk(key, v)
+				setDone[key] = true
 			}
 		}
 		newChunks = append(newChunks, c)
@@ -1041,10 +1394,9 @@ func editPNG(path, outPath string, opts core.EditOptions) error {
 
 	// Add new fields not yet present
 	var addChunks []pngChunk
-	for k, v := range opts.Set {
-		if !setDone[k] {
-			d := append([]byte(k+"\x00"), []byte(v)...)
-			addChunks = append(addChunks, pngChunk{typ: "tEXt", data: d})
+	for _, k := range core.SortedKeys(opts.Set) {
+		if v := opts.Set[k]; !setDone[k] {
+			addChunks = append(addChunks, textChunk(k, v))
 		}
 	}
 
@@ -1061,26 +1413,22 @@ func editPNG(path, outPath string, opts core.EditOptions) error {
 	if !inserted {
 		final = append(final, addChunks...)
 	}
-
-	if opts.DryRun {
-		fmt.Printf("Dry-run: PNG tEXt chunks would be updated:\n")
-		for k, v := range opts.Set {
-			fmt.Printf("  %s = %s\n", k, v)
-		}
-		return nil
-	}
-
-	return writePNGChunks(outPath, final)
+	return final, nil
 }
 
 func writePNGChunks(path string, chunks []pngChunk) error {
+	return os.WriteFile(path, pngBytes(chunks), 0644)
+}
+
+// pngBytes joins chunks, after the signature, into a PNG file.
+func pngBytes(chunks []pngChunk) []byte {
 	var buf bytes.Buffer
 	// PNG signature
 	buf.Write([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A})
 	for _, c := range chunks {
 		writePNGChunk(&buf, c.typ, c.data)
 	}
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	return buf.Bytes()
 }
 
 func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
@@ -1096,30 +1444,10 @@ func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
 	w.Write(crcBuf)
 }
 
-// crc32PNG computes CRC32 for PNG chunk (type + data).
+// crc32PNG computes CRC32 for PNG chunk (type + data). PNG uses the IEEE
+// polynomial, as hash/crc32 does.
 func crc32PNG(typ, data []byte) uint32 {
-	// PNG uses CRC32 with polynomial 0xEDB88320 (reflected)
-	const poly = 0xEDB88320
-	var table [256]uint32
-	for i := range table {
-		c := uint32(i)
-		for j := 0; j < 8; j++ {
-			if c&1 != 0 {
-				c = poly ^ (c >> 1)
-			} else {
-				c >>= 1
-			}
-		}
-		table[i] = c
-	}
-	crc := uint32(0xFFFFFFFF)
-	for _, b := range typ {
-		crc = table[(crc^uint32(b))&0xFF] ^ (crc >> 8)
-	}
-	for _, b := range data {
-		crc = table[(crc^uint32(b))&0xFF] ^ (crc >> 8)
-	}
-	return crc ^ 0xFFFFFFFF
+	return crc32.Update(crc32.ChecksumIEEE(typ), crc32.IEEETable, data)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -1127,6 +1455,15 @@ func crc32PNG(typ, data []byte) uint32 {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	if err := h.Info().CheckOptionValues(opts.Options, true); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return core.PreviewStrip(h, path, opts)
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtJPEG:
@@ -1137,6 +1474,10 @@ func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) err
 		return stripGIF(path, out, opts)
 	case core.FmtWebP:
 		return stripWebP(path, out, opts)
+	case core.FmtHEIC:
+		return stripHEIC(path, out, opts)
+	case core.FmtTIFF:
+		return stripTIFF(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanStrip {
@@ -1174,12 +1515,36 @@ func stripJPEG(path, outPath string, opts core.StripOptions) error {
 	if err != nil {
 		return err
 	}
+	return writeJPEGSegments(outPath, stripJPEGSegments(segments, opts))
+}
+
+// optJPEGKeepICC keeps the colour profile through a strip: without it a
+// wide-gamut photo is shown as sRGB, with visibly wrong colours.
+var optJPEGKeepICC = core.FormatOption{
+	Key:    "jpeg.keep-icc",
+	Values: []string{"true", "false"},
+	Strip:  true,
+	Doc:    "keep the APP2 ICC colour profile when stripping",
+}
+
+func stripJPEGSegments(segments []jpegSegment, opts core.StripOptions) []jpegSegment {
 
 	keepSet := make(map[string]bool)
 	for _, k := range opts.KeepFields {
 		keepSet[strings.ToLower(k)] = true
 	}
 
+	if opts.StripRegions {
+		if !stripJPEGRegions(segments) {
+			fmt.Println("  Note: no face regions found")
+		}
+		if !opts.StripAll && !opts.StripGPS && len(keepSet) == 0 {
+			return segments
+		}
+	}
+
+	icc, _ := optJPEGKeepICC.Value(opts.Options) // checked by Strip
+	keepICC := icc == "true"
 	var out []jpegSegment
 	for _, seg := range segments {
 		if opts.StripGPS && seg.marker == 0xE1 {
@@ -1191,6 +1556,10 @@ func stripJPEG(path, outPath string, opts core.StripOptions) error {
 			out = append(out, seg)
 			continue
 		}
+		if keepICC && seg.marker == 0xE2 && bytes.HasPrefix(seg.data, []byte(jpegICCPrefix)) {
+			out = append(out, seg)
+			continue
+		}
 		if jpegMetaMarkers[seg.marker] {
 			if opts.StripAll {
 				continue // drop segment
@@ -1206,7 +1575,12 @@ func stripJPEG(path, outPath string, opts core.StripOptions) error {
 					continue
 				}
 				if keepSet["iptc"] && seg.marker == 0xED {
-					out = append(out, seg)
+					// The IPTC datasets, not the rest of the Photoshop
+					// resources (thumbnail, paths, print settings).
+					if data := keepIPTCResources(seg.data); data != nil {
+						seg.data = data
+						out = append(out, seg)
+					}
 					continue
 				}
 			}
@@ -1214,22 +1588,12 @@ func stripJPEG(path, outPath string, opts core.StripOptions) error {
 		}
 		out = append(out, seg)
 	}
-
-	return writeJPEGSegments(outPath, out)
+	return out
 }
 
 func stripGPSFromEXIF(data []byte) ([]byte, error) {
-	// Simply rebuild EXIF without GPS tags
-	existing := map[string]string{}
-	if len(data) < 6 {
-		return data, nil
-	}
-	x, err := exif.Decode(bytes.NewReader(data[6:]))
-	if err != nil {
-		return data, err
-	}
-	x.Walk(exifStringWalker{fields: existing})
-	return buildMinimalEXIF(existing)
+	// Drop the GPS IFD, keep the rest
+	return stripEXIFGPS(data)
 }
 
 // ─── PNG Strip ───────────────────────────────────────────────────────────────
@@ -1262,14 +1626,50 @@ func stripPNG(path, outPath string, opts core.StripOptions) error {
 	if err != nil {
 		return err
 	}
+	chunks, err = stripPNGChunks(chunks, opts)
+	if err != nil {
+		return err
+	}
+	return writePNGChunks(outPath, chunks)
+}
 
+func stripPNGChunks(chunks []pngChunk, opts core.StripOptions) ([]pngChunk, error) {
 	keepSet := make(map[string]bool)
 	for _, k := range opts.KeepFields {
 		keepSet[strings.ToLower(k)] = true
 	}
 
+	if opts.StripRegions {
+		if !stripPNGRegions(chunks) {
+			fmt.Println("  Note: no face regions found")
+		}
+		if !opts.StripAll && !opts.StripGPS && len(keepSet) == 0 {
+			return chunks, nil
+		}
+	}
+
 	var final []pngChunk
 	for _, c := range chunks {
+		if opts.StripGPS {
+			// Strip only the GPS IFD of eXIf and the exif:GPS* XMP
+			// properties, keep every other chunk
+			switch c.typ {
+			case "eXIf":
+				stripped, err := stripTIFFGPS(c.data)
+				if err != nil {
+					return nil, fmt.Errorf("PNG eXIf: %w", err)
+				}
+				c.data = stripped
+			case "iTXt":
+				if key, text, ok := pngITXtText(c.data); ok && key == pngXMPKeyword {
+					if packet, ok := removeXMPGPS(text); ok {
+						c.data = buildPNGITXt(pngXMPKeyword, packet)
+					}
+				}
+			}
+			final = append(final, c)
+			continue
+		}
 		if pngMetaChunks[c.typ] {
 			if keepSet[strings.ToLower(c.typ)] || keepSet["all"] {
 				final = append(final, c)
@@ -1279,8 +1679,7 @@ func stripPNG(path, outPath string, opts core.StripOptions) error {
 		}
 		final = append(final, c)
 	}
-
-	return writePNGChunks(outPath, final)
+	return final, nil
 }
 
 // ─── GIF Strip ───────────────────────────────────────────────────────────────
@@ -1290,14 +1689,21 @@ func stripGIF(path, outPath string, opts core.StripOptions) error {
 	if err != nil {
 		return err
 	}
+	out, err := stripGIFData(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
 
+func stripGIFData(data []byte) ([]byte, error) {
 	// Walk through GIF blocks, skip comment extensions
 	var out bytes.Buffer
 	i := 0
 
 	// Header (6 bytes) + Logical Screen Descriptor (7 bytes)
 	if len(data) < 13 {
-		return fmt.Errorf("GIF too short")
+		return nil, fmt.Errorf("GIF too short")
 	}
 	out.Write(data[:13])
 	i = 13
@@ -1306,7 +1712,7 @@ func stripGIF(path, outPath string, opts core.StripOptions) error {
 	if data[10]&0x80 != 0 {
 		ctSize := 3 * (1 << (int(data[10]&0x07) + 1))
 		if i+ctSize > len(data) {
-			return fmt.Errorf("GIF truncated")
+			return nil, fmt.Errorf("GIF truncated")
 		}
 		out.Write(data[i : i+ctSize])
 		i += ctSize
@@ -1334,8 +1740,7 @@ func stripGIF(path, outPath string, opts core.StripOptions) error {
 		out.WriteByte(data[i])
 		i++
 	}
-
-	return os.WriteFile(outPath, out.Bytes(), 0644)
+	return out.Bytes(), nil
 }
 
 // ─── WebP Strip ───────────────────────────────────────────────────────────────
@@ -1345,57 +1750,55 @@ func stripWebP(path, outPath string, opts core.StripOptions) error {
 	if err != nil {
 		return err
 	}
-	if len(data) < 12 {
-		return fmt.Errorf("WebP too short")
+	out, err := stripWebPData(data, opts)
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(outPath, out, 0644)
+}
 
-	// Rebuild RIFF without EXIF and XMP chunks
-	var body bytes.Buffer
-	offset := 12
-	for offset+8 <= len(data) {
-		chunkID := string(data[offset : offset+4])
-		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
-		offset += 8
-		if offset+chunkSize > len(data) {
-			break
-		}
-		chunkData := data[offset : offset+chunkSize]
+func stripWebPData(data []byte, opts core.StripOptions) ([]byte, error) {
+	chunks, err := readWebPChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keepSet := make(map[string]bool)
+	for _, k := range opts.KeepFields {
+		keepSet[strings.ToLower(k)] = true
+	}
 
-		skip := false
-		if opts.StripAll || len(opts.KeepFields) == 0 {
-			if chunkID == "EXIF" || chunkID == "XMP " {
-				skip = true
+	// Rebuild RIFF without EXIF and XMP chunks
+	var final []webpChunk
+	for _, c := range chunks {
+		if opts.StripGPS {
+			// Strip only the GPS IFD of EXIF and the exif:GPS* XMP
+			// properties, keep the rest
+			switch c.id {
+			case "EXIF":
+				stripped, err := stripTIFFGPS(c.data)
+				if err != nil {
+					return nil, fmt.Errorf("WebP EXIF: %w", err)
+				}
+				c.data = stripped
+			case "XMP ":
+				c.data, _ = removeXMPGPS(c.data)
 			}
+			final = append(final, c)
+			continue
 		}
-		if !skip {
-			body.WriteString(chunkID)
-			sizeBuf := make([]byte, 4)
-			binary.LittleEndian.PutUint32(sizeBuf, uint32(chunkSize))
-			body.Write(sizeBuf)
-			body.Write(chunkData)
-			if chunkSize%2 != 0 {
-				body.WriteByte(0)
-			}
+		if c.id == "EXIF" && !keepSet["exif"] {
+			continue
 		}
-
-		offset += chunkSize
-		if chunkSize%2 != 0 {
-			offset++
+		if c.id == "XMP " && !keepSet["xmp"] {
+			continue
 		}
+		final = append(final, c)
 	}
 
-	var out bytes.Buffer
-	out.WriteString("RIFF")
-	totalSize := make([]byte, 4)
-	binary.LittleEndian.PutUint32(totalSize, uint32(body.Len()+4))
-	out.Write(totalSize)
-	out.WriteString("WEBP")
-	out.Write(body.Bytes())
-
-	return os.WriteFile(outPath, out.Bytes(), 0644)
+	return buildWebP(syncVP8X(final)), nil
 }
 
-
 // ─── SVG ─────────────────────────────────────────────────────────────────────
 
 func init() {
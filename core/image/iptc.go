@@ -0,0 +1,482 @@
+package image
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── IPTC ─────────────────────────────────────────────────────────────────────
+// Newsroom systems read captions, bylines and keywords from IPTC IIM
+// datasets, which a JPEG keeps in resource 0x0404 of the Photoshop image
+// resources in APP13. An edit rebuilds that resource alone and leaves the
+// others — paths, slices, print settings — as they were; the IPTC digest
+// resource, when there is one, is recomputed so that Photoshop does not
+// take the new datasets for a stale copy of the XMP.
+//
+// Fields are named as view shows them (Caption, Byline, City), or with an
+// "iptc:" prefix. Keywords is shared with XMP: --set and --delete Keywords
+// change the XMP bag and, when the photo has IPTC datasets, IPTC Keywords
+// too, so the two do not disagree; iptc:Keywords changes IPTC alone.
+// Repeatable datasets take a "; "-separated list, as keyword bags do.
+
+const (
+	jpegPhotoshopPrefix = "Photoshop 3.0\x00"
+
+	psResIPTC       = 0x0404
+	psResIPTCDigest = 0x0425
+)
+
+// iptcField is one record 2 (application) dataset.
+type iptcField struct {
+	name   string
+	num    byte
+	repeat bool
+}
+
+var iptcFields = []iptcField{
+	{"ObjectName", 5, false},
+	{"Urgency", 10, false},
+	{"Category", 15, false},
+	{"SupplementalCategory", 20, true},
+	{"Keywords", 25, true},
+	{"SpecialInstructions", 40, false},
+	{"DateCreated", 55, false},
+	{"TimeCreated", 60, false},
+	{"DigitalCreationDate", 62, false},
+	{"DigitalCreationTime", 63, false},
+	{"OriginatingProgram", 65, false},
+	{"ProgramVersion", 70, false},
+	{"Byline", 80, true},
+	{"BylineTitle", 85, true},
+	{"City", 90, false},
+	{"Sublocation", 92, false},
+	{"Province", 95, false},
+	{"CountryCode", 100, false},
+	{"Country", 101, false},
+	{"OriginalTransmissionReference", 103, false},
+	{"Headline", 105, false},
+	{"Credit", 110, false},
+	{"Source", 115, false},
+	{"CopyrightNotice", 116, false},
+	{"Contact", 118, true},
+	{"Caption", 120, false},
+	{"CaptionWriter", 122, true},
+}
+
+func iptcFieldByNum(num byte) (iptcField, bool) {
+	for _, f := range iptcFields {
+		if f.num == num {
+			return f, true
+		}
+	}
+	return iptcField{}, false
+}
+
+// iptcDataset is one dataset of an IIM block.
+type iptcDataset struct {
+	record, num byte
+	value       []byte
+}
+
+// parseIIM splits an IIM block into its datasets, stopping at the first
+// byte that does not start one.
+func parseIIM(data []byte) []iptcDataset {
+	var out []iptcDataset
+	i := 0
+	for i+5 <= len(data) && data[i] == 0x1C {
+		d := iptcDataset{record: data[i+1], num: data[i+2]}
+		n := int(binary.BigEndian.Uint16(data[i+3 : i+5]))
+		i += 5
+		if n&0x8000 != 0 {
+			// Extended dataset: the low bits give the size of the length.
+			w := n & 0x7FFF
+			if w > 4 || i+w > len(data) {
+				break
+			}
+			n = 0
+			for _, c := range data[i : i+w] {
+				n = n<<8 | int(c)
+			}
+			i += w
+		}
+		if n < 0 || i+n > len(data) {
+			break
+		}
+		d.value = data[i : i+n]
+		out = append(out, d)
+		i += n
+	}
+	return out
+}
+
+func buildIIM(ds []iptcDataset) []byte {
+	var b bytes.Buffer
+	for _, d := range ds {
+		b.Write([]byte{0x1C, d.record, d.num})
+		if len(d.value) > 0x7FFF {
+			b.Write([]byte{0x80, 4})
+			binary.Write(&b, binary.BigEndian, uint32(len(d.value)))
+		} else {
+			binary.Write(&b, binary.BigEndian, uint16(len(d.value)))
+		}
+		b.Write(d.value)
+	}
+	return b.Bytes()
+}
+
+// psResource is one Photoshop image resource.
+type psResource struct {
+	sig  string // "8BIM", or an older signature
+	id   uint16
+	name []byte // Pascal string, padded to even length
+	data []byte
+}
+
+// parsePSResources splits the image resources of an APP13 segment (after
+// its "Photoshop 3.0" prefix). ok is false when the block does not parse
+// to its end.
+func parsePSResources(data []byte) (rs []psResource, ok bool) {
+	i := 0
+	for i < len(data) {
+		if i+7 > len(data) {
+			return rs, false
+		}
+		sig := string(data[i : i+4])
+		switch sig {
+		case "8BIM", "MeSa", "PHUT", "AgHg", "DCSR":
+		default:
+			return rs, false
+		}
+		r := psResource{sig: sig, id: binary.BigEndian.Uint16(data[i+4 : i+6])}
+		nameLen := 1 + int(data[i+6])
+		if nameLen%2 != 0 {
+			nameLen++
+		}
+		i += 6
+		if i+nameLen+4 > len(data) {
+			return rs, false
+		}
+		r.name = data[i : i+nameLen]
+		i += nameLen
+		n := int(binary.BigEndian.Uint32(data[i : i+4]))
+		i += 4
+		if n < 0 || i+n > len(data) {
+			return rs, false
+		}
+		r.data = data[i : i+n]
+		i += n + n%2
+		rs = append(rs, r)
+	}
+	return rs, true
+}
+
+func buildPSResources(rs []psResource) []byte {
+	var b bytes.Buffer
+	for _, r := range rs {
+		b.WriteString(r.sig)
+		binary.Write(&b, binary.BigEndian, r.id)
+		b.Write(r.name)
+		binary.Write(&b, binary.BigEndian, uint32(len(r.data)))
+		b.Write(r.data)
+		if len(r.data)%2 != 0 {
+			b.WriteByte(0)
+		}
+	}
+	return b.Bytes()
+}
+
+// ─── IPTC view ───────────────────────────────────────────────────────────────
+
+func parseIPTCInto(data []byte, m *core.Metadata) {
+	rs, _ := parsePSResources(data)
+	for _, r := range rs {
+		if r.id == psResIPTC {
+			parseIPTCBlock(r.data, m)
+		}
+	}
+}
+
+func parseIPTCBlock(data []byte, m *core.Metadata) {
+	vals := map[byte][]string{}
+	var order []byte
+	for _, d := range parseIIM(data) {
+		if d.record != 2 {
+			continue
+		}
+		if _, ok := iptcFieldByNum(d.num); !ok {
+			continue
+		}
+		if _, seen := vals[d.num]; !seen {
+			order = append(order, d.num)
+		}
+		vals[d.num] = append(vals[d.num], string(d.value))
+	}
+	for _, num := range order {
+		f, _ := iptcFieldByNum(num)
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      f.name,
+			Value:    strings.Join(vals[num], "; "),
+			Category: "IPTC",
+			Editable: true,
+		})
+	}
+}
+
+// ─── IPTC edit ───────────────────────────────────────────────────────────────
+
+// iptcEdit holds the datasets an edit replaces, by number, with their
+// values; an empty entry removes the dataset.
+type iptcEdit map[byte][]string
+
+// takeIPTCEdits moves the IPTC fields out of opts. Keywords stays for the
+// XMP edit and, when hasIPTC, is applied to IPTC as well.
+func takeIPTCEdits(opts core.EditOptions, hasIPTC bool) (core.EditOptions, iptcEdit, error) {
+	ed := iptcEdit{}
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		f, ok, shared := iptcFieldFor(k)
+		if !ok && len(k) > 5 && strings.EqualFold(k[:5], "iptc:") {
+			return opts, nil, fmt.Errorf("unknown IPTC field %q", k[5:])
+		}
+		if ok && (!shared || hasIPTC) {
+			ed[f.num] = splitIPTCValue(f, v)
+		}
+		if !ok || shared {
+			set[k] = v
+		}
+	}
+	var del []string
+	for _, k := range opts.Delete {
+		f, ok, shared := iptcFieldFor(k)
+		if ok && (!shared || hasIPTC) {
+			ed[f.num] = []string{}
+		}
+		if !ok || shared {
+			del = append(del, k)
+		}
+	}
+	opts.Set, opts.Delete = set, del
+	return opts, ed, nil
+}
+
+// iptcFieldFor returns the IPTC field an edit key names; shared is true
+// for a bare Keywords, which belongs to XMP as well.
+func iptcFieldFor(key string) (f iptcField, ok, shared bool) {
+	name, prefixed := key, false
+	if len(key) > 5 && strings.EqualFold(key[:5], "iptc:") {
+		name, prefixed = key[5:], true
+	}
+	for _, f := range iptcFields {
+		if strings.EqualFold(f.name, name) {
+			return f, true, !prefixed && f.num == 25
+		}
+	}
+	return iptcField{}, false, false
+}
+
+func splitIPTCValue(f iptcField, v string) []string {
+	if !f.repeat {
+		if v = strings.TrimSpace(v); v == "" {
+			return []string{}
+		}
+		return []string{v}
+	}
+	return splitKeywords(v)
+}
+
+// splitKeywords splits a "; "-separated list.
+func splitKeywords(v string) []string {
+	out := []string{}
+	for _, k := range strings.Split(v, ";") {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// setJPEGIPTC applies ed to the IPTC datasets of the first Photoshop APP13
+// segment, adding one when the photo has none.
+func setJPEGIPTC(segments []jpegSegment, ed iptcEdit) ([]jpegSegment, error) {
+	for i, seg := range segments {
+		if seg.marker == 0xED && bytes.HasPrefix(seg.data, []byte(jpegPhotoshopPrefix)) {
+			data, err := editPSResources(seg.data[len(jpegPhotoshopPrefix):], ed)
+			if err != nil {
+				return nil, err
+			}
+			segments[i].data = append([]byte(jpegPhotoshopPrefix), data...)
+			return segments, nil
+		}
+	}
+	removesOnly := true
+	for _, v := range ed {
+		if len(v) > 0 {
+			removesOnly = false
+		}
+	}
+	if removesOnly {
+		return segments, nil
+	}
+	data, err := editPSResources(nil, ed)
+	if err != nil {
+		return nil, err
+	}
+	seg := jpegSegment{marker: 0xED, data: append([]byte(jpegPhotoshopPrefix), data...)}
+	// After SOI and the other APPn segments, where Photoshop writes it.
+	at := 1
+	for at < len(segments) && segments[at].marker >= 0xE0 && segments[at].marker <= 0xEC {
+		at++
+	}
+	return append(segments[:at], append([]jpegSegment{seg}, segments[at:]...)...), nil
+}
+
+// editPSResources returns the image resources in data with ed applied to
+// the IPTC resource, which is added when missing.
+func editPSResources(data []byte, ed iptcEdit) ([]byte, error) {
+	rs, ok := parsePSResources(data)
+	if !ok {
+		return nil, fmt.Errorf("APP13 Photoshop resources are malformed: refusing to rewrite IPTC")
+	}
+	at := -1
+	for i, r := range rs {
+		if r.id == psResIPTC {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		rs = append(rs, psResource{sig: "8BIM", id: psResIPTC, name: []byte{0, 0}})
+		at = len(rs) - 1
+	}
+	iim, err := editIIM(rs[at].data, ed)
+	if err != nil {
+		return nil, err
+	}
+	rs[at].data = iim
+	for i, r := range rs {
+		if r.id == psResIPTCDigest {
+			sum := md5.Sum(iim)
+			rs[i].data = sum[:]
+		}
+	}
+	out := buildPSResources(rs)
+	if len(jpegPhotoshopPrefix)+len(out) > 65533 {
+		return nil, fmt.Errorf("IPTC would grow to %d bytes, over the 64 KB a JPEG segment holds", len(out))
+	}
+	return out, nil
+}
+
+// editIIM returns the IIM block data with the record 2 datasets of ed
+// replaced. New datasets go in dataset-number order among the others.
+func editIIM(data []byte, ed iptcEdit) ([]byte, error) {
+	ds := parseIIM(data)
+	utf8Declared, charset := false, false
+	for _, d := range ds {
+		if d.record == 1 && d.num == 90 {
+			charset = true
+			utf8Declared = bytes.Equal(d.value, []byte("\x1b%G"))
+		}
+	}
+	nums := make([]int, 0, len(ed))
+	for num, vals := range ed {
+		nums = append(nums, int(num))
+		for _, v := range vals {
+			if !utf8.ValidString(v) {
+				return nil, fmt.Errorf("IPTC value %q is not valid UTF-8", v)
+			}
+			if len(v) > 0x7FFF {
+				return nil, fmt.Errorf("IPTC value of %d bytes is too long for a dataset", len(v))
+			}
+			if strings.IndexFunc(v, func(r rune) bool { return r > 0x7E }) >= 0 && !utf8Declared {
+				if charset {
+					return nil, fmt.Errorf("IPTC datasets declare a character set other than UTF-8: cannot write %q", v)
+				}
+				ds = append([]iptcDataset{{record: 1, num: 90, value: []byte("\x1b%G")}}, ds...)
+				utf8Declared, charset = true, true
+			}
+		}
+	}
+	sort.Ints(nums)
+
+	var kept []iptcDataset
+	hasRecord2 := false
+	for _, d := range ds {
+		if d.record == 2 {
+			if _, replaced := ed[d.num]; replaced {
+				continue
+			}
+			hasRecord2 = true
+		}
+		kept = append(kept, d)
+	}
+	for _, n := range nums {
+		num := byte(n)
+		for i := len(ed[num]) - 1; i >= 0; i-- {
+			if !hasRecord2 {
+				// A record starts with its version, 2:00.
+				kept = insertIPTCDataset(kept, iptcDataset{record: 2, num: 0, value: []byte{0, 4}})
+				hasRecord2 = true
+			}
+			kept = insertIPTCDataset(kept, iptcDataset{record: 2, num: num, value: []byte(ed[num][i])})
+		}
+	}
+	return buildIIM(kept), nil
+}
+
+// insertIPTCDataset puts d before the first dataset that sorts after it,
+// so that repeated inserts of one dataset number, last value first, keep
+// the values in order.
+func insertIPTCDataset(ds []iptcDataset, d iptcDataset) []iptcDataset {
+	at := len(ds)
+	for i, e := range ds {
+		if e.record > d.record || e.record == d.record && e.num >= d.num {
+			at = i
+			break
+		}
+	}
+	return append(ds[:at], append([]iptcDataset{d}, ds[at:]...)...)
+}
+
+// keepIPTCResources returns the APP13 segment data with only the IPTC
+// datasets and their digest, or nil when it has no IPTC.
+func keepIPTCResources(seg []byte) []byte {
+	if !bytes.HasPrefix(seg, []byte(jpegPhotoshopPrefix)) {
+		return nil
+	}
+	rs, ok := parsePSResources(seg[len(jpegPhotoshopPrefix):])
+	if !ok {
+		return seg
+	}
+	var keep []psResource
+	for _, r := range rs {
+		if r.id == psResIPTC || r.id == psResIPTCDigest {
+			keep = append(keep, r)
+		}
+	}
+	if len(keep) == 0 {
+		return nil
+	}
+	return append([]byte(jpegPhotoshopPrefix), buildPSResources(keep)...)
+}
+
+// jpegHasIPTC reports whether segments hold IPTC datasets.
+func jpegHasIPTC(segments []jpegSegment) bool {
+	for _, seg := range segments {
+		if seg.marker == 0xED && bytes.HasPrefix(seg.data, []byte(jpegPhotoshopPrefix)) {
+			rs, _ := parsePSResources(seg.data[len(jpegPhotoshopPrefix):])
+			for _, r := range rs {
+				if r.id == psResIPTC {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ─── JPEG frame ──────────────────────────────────────────────────────────────
+// How a JPEG was encoded is recorded outside EXIF, in its quantization
+// (DQT) and frame (SOF) segments, and survives a strip. The tables of most
+// encoders are the example tables of the JPEG standard (Annex K) scaled by
+// the libjpeg quality formula, so the quality a file was saved at can be
+// recovered from them exactly; cameras and some editors use their own.
+
+// jpegZigzag maps the zigzag position of a DQT entry to its natural
+// (row-major) position in the 8×8 block.
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// Annex K tables, in natural order: luminance and chrominance.
+var jpegStdTables = [2][64]int{{
+	16, 11, 10, 16, 24, 40, 51, 61,
+	12, 12, 14, 19, 26, 58, 60, 55,
+	14, 13, 16, 24, 40, 57, 69, 56,
+	14, 17, 22, 29, 51, 87, 80, 62,
+	18, 22, 37, 56, 68, 109, 103, 77,
+	24, 35, 55, 64, 81, 104, 113, 92,
+	49, 64, 78, 87, 103, 121, 120, 101,
+	72, 92, 95, 98, 112, 100, 103, 99,
+}, {
+	17, 18, 24, 47, 99, 99, 99, 99,
+	18, 21, 26, 66, 99, 99, 99, 99,
+	24, 26, 56, 99, 99, 99, 99, 99,
+	47, 66, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+	99, 99, 99, 99, 99, 99, 99, 99,
+}}
+
+// jpegFrame is what the DQT and SOF segments of a JPEG record.
+type jpegFrame struct {
+	width, height int
+	sofMarker     byte // 0xC0 baseline, 0xC2 progressive, ...
+	components    []jpegComponent
+	tables        map[int][64]int // quantization tables by id, natural order
+}
+
+type jpegComponent struct {
+	id, h, v, table int
+}
+
+// readJPEGFrame collects the quantization tables and the frame header of
+// segments. ok is false when there is no frame header.
+func readJPEGFrame(segments []jpegSegment) (f jpegFrame, ok bool) {
+	f.tables = map[int][64]int{}
+	for _, seg := range segments {
+		switch {
+		case seg.marker == 0xDB:
+			for d := seg.data; len(d) > 0; {
+				precision, id := d[0]>>4, int(d[0]&0x0F)
+				size := 64
+				if precision != 0 {
+					size = 128
+				}
+				if len(d) < 1+size {
+					break
+				}
+				var t [64]int
+				for i := 0; i < 64; i++ {
+					if precision != 0 {
+						t[jpegZigzag[i]] = int(binary.BigEndian.Uint16(d[1+2*i:]))
+					} else {
+						t[jpegZigzag[i]] = int(d[1+i])
+					}
+				}
+				f.tables[id] = t
+				d = d[1+size:]
+			}
+		case isSOF(seg.marker) && !ok && len(seg.data) >= 6:
+			f.sofMarker = seg.marker
+			f.height = int(binary.BigEndian.Uint16(seg.data[1:3]))
+			f.width = int(binary.BigEndian.Uint16(seg.data[3:5]))
+			n := int(seg.data[5])
+			for i := 0; i < n && 6+3*i+3 <= len(seg.data); i++ {
+				c := seg.data[6+3*i:]
+				f.components = append(f.components, jpegComponent{
+					id: int(c[0]), h: int(c[1] >> 4), v: int(c[1] & 0x0F), table: int(c[2]),
+				})
+			}
+			ok = true
+		}
+	}
+	return f, ok
+}
+
+// isSOF reports whether marker starts a frame: C0–CF except DHT (C4), JPG
+// (C8) and DAC (CC).
+func isSOF(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
+
+// subsampling names the chroma subsampling of a YCbCr frame.
+func (f jpegFrame) subsampling() string {
+	switch len(f.components) {
+	case 1:
+		return "grayscale"
+	case 3:
+	default:
+		return fmt.Sprintf("%d components", len(f.components))
+	}
+	y, cb, cr := f.components[0], f.components[1], f.components[2]
+	if cb.h != cr.h || cb.v != cr.v || cb.h == 0 || cb.v == 0 {
+		return "unusual"
+	}
+	switch h, v := y.h/cb.h, y.v/cb.v; {
+	case h == 1 && v == 1:
+		return "4:4:4"
+	case h == 2 && v == 1:
+		return "4:2:2"
+	case h == 2 && v == 2:
+		return "4:2:0"
+	case h == 1 && v == 2:
+		return "4:4:0"
+	case h == 4 && v == 1:
+		return "4:1:1"
+	}
+	return fmt.Sprintf("%dx%d", y.h, y.v)
+}
+
+// ijgTable scales an Annex K table to quality q as libjpeg does.
+func ijgTable(base [64]int, q int) [64]int {
+	scale := 200 - 2*q
+	if q < 50 {
+		scale = 5000 / q
+	}
+	var t [64]int
+	for i, b := range base {
+		v := (b*scale + 50) / 100
+		if v < 1 {
+			v = 1
+		}
+		if v > 255 {
+			v = 255
+		}
+		t[i] = v
+	}
+	return t
+}
+
+// quality estimates the libjpeg quality of the frame's tables (1–100),
+// and reports whether the tables are exactly the scaled Annex K ones. q is
+// 0 when there is no luminance table.
+func (f jpegFrame) quality() (q int, exact bool) {
+	luma, ok := f.tables[0]
+	if !ok {
+		return 0, false
+	}
+	chroma, hasChroma := f.tables[1]
+	best := -1
+	for try := 1; try <= 100; try++ {
+		diff := tableDiff(luma, ijgTable(jpegStdTables[0], try))
+		if hasChroma {
+			diff += tableDiff(chroma, ijgTable(jpegStdTables[1], try))
+		}
+		if best < 0 || diff < best {
+			best, q = diff, try
+		}
+	}
+	return q, best == 0
+}
+
+func tableDiff(a, b [64]int) int {
+	d := 0
+	for i := range a {
+		if a[i] > b[i] {
+			d += a[i] - b[i]
+		} else {
+			d += b[i] - a[i]
+		}
+	}
+	return d
+}
@@ -0,0 +1,115 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── In memory ───────────────────────────────────────────────────────────────
+// core.MemoryHandler: the same segment and chunk code as the file paths,
+// fed from and returned to a byte slice.
+
+// ViewBytes returns the metadata of a JPEG, PNG, GIF, WebP or HEIC held in
+// data.
+func (h *Handler) ViewBytes(data []byte) (*core.Metadata, error) {
+	m := &core.Metadata{Format: formatInfo[h.format].Name}
+	if err := core.CheckLen(h.format, int64(len(data))); err != nil {
+		return m, err
+	}
+	switch h.format {
+	case core.FmtJPEG:
+		m.Format = "JPEG"
+		return viewJPEGFrom(bytes.NewReader(data), m)
+	case core.FmtPNG:
+		m.Format = "PNG"
+		chunks, err := readPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return m, err
+		}
+		return viewPNGChunks(chunks, nil, m)
+	case core.FmtGIF:
+		m.Format = "GIF"
+		return viewGIFData(data, m)
+	case core.FmtWebP:
+		m.Format = "WebP"
+		return viewWebPData(data, m)
+	case core.FmtHEIC:
+		m.Format = "HEIC/HEIF"
+		return viewHEIF(bytes.NewReader(data), int64(len(data)), m)
+	}
+	return m, notInMemory(h.format)
+}
+
+// EditBytes applies opts to a JPEG, PNG or HEIC held in data.
+func (h *Handler) EditBytes(data []byte, opts core.EditOptions) ([]byte, error) {
+	if err := core.CheckLen(h.format, int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err := h.Info().CheckOptionValues(opts.Options, false); err != nil {
+		return nil, err
+	}
+	switch h.format {
+	case core.FmtJPEG:
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		if segments, err = applyJPEGEdits(segments, opts); err != nil {
+			return nil, err
+		}
+		return jpegBytes(segments), nil
+	case core.FmtPNG:
+		chunks, err := readPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if chunks, err = applyPNGEdits(chunks, opts); err != nil {
+			return nil, err
+		}
+		return pngBytes(chunks), nil
+	case core.FmtHEIC:
+		return applyHEICEdits(data, opts)
+	}
+	return nil, notInMemory(h.format)
+}
+
+// StripBytes removes metadata from a JPEG, PNG, GIF, WebP or HEIC held in
+// data.
+func (h *Handler) StripBytes(data []byte, opts core.StripOptions) ([]byte, error) {
+	if err := core.CheckLen(h.format, int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err := h.Info().CheckOptionValues(opts.Options, true); err != nil {
+		return nil, err
+	}
+	switch h.format {
+	case core.FmtJPEG:
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		return jpegBytes(stripJPEGSegments(segments, opts)), nil
+	case core.FmtPNG:
+		chunks, err := readPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if chunks, err = stripPNGChunks(chunks, opts); err != nil {
+			return nil, err
+		}
+		return pngBytes(chunks), nil
+	case core.FmtGIF:
+		return stripGIFData(data)
+	case core.FmtWebP:
+		return stripWebPData(data, opts)
+	case core.FmtHEIC:
+		return stripHEICData(data, opts)
+	}
+	return nil, notInMemory(h.format)
+}
+
+func notInMemory(id core.FormatID) error {
+	return fmt.Errorf("%s files cannot be processed in memory; use a file", formatInfo[id].Name)
+}
@@ -0,0 +1,63 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
+)
+
+// Optimize removes the padding of the XMP packet in a JPEG or PNG.
+func (h *Handler) Optimize(path, outPath string, dryRun bool) (int64, error) {
+	switch h.format {
+	case core.FmtJPEG:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return 0, err
+		}
+		var saved int64
+		for i, seg := range segments {
+			if seg.marker != 0xE1 || !bytes.HasPrefix(seg.data, []byte(jpegXMPPrefix)) {
+				continue
+			}
+			packet := xmp.TrimPadding(seg.data[len(jpegXMPPrefix):])
+			saved += int64(len(seg.data) - len(jpegXMPPrefix) - len(packet))
+			segments[i].data = append([]byte(jpegXMPPrefix), packet...)
+		}
+		if dryRun || saved == 0 {
+			return saved, nil
+		}
+		return saved, writeJPEGSegments(outPath, segments)
+	case core.FmtPNG:
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		chunks, err := readPNGChunks(f)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		var saved int64
+		for i, c := range chunks {
+			if c.typ != "iTXt" {
+				continue
+			}
+			if key, text, ok := pngITXtText(c.data); ok && key == pngXMPKeyword {
+				chunks[i].data = buildPNGITXt(pngXMPKeyword, xmp.TrimPadding(text))
+				saved += int64(len(c.data) - len(chunks[i].data))
+			}
+		}
+		if dryRun || saved <= 0 {
+			return saved, nil
+		}
+		return saved, writePNGChunks(outPath, chunks)
+	}
+	return 0, fmt.Errorf("%s does not support optimize", formatInfo[h.format].Name)
+}
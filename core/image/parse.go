@@ -0,0 +1,58 @@
+package image
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Parsed JPEG and PNG ─────────────────────────────────────────────────────
+// For core.OpenDocument: the file is read once, split into segments or
+// chunks, and both the fields and a later edit come from those.
+
+// Parse reads a JPEG or PNG for core.OpenDocument; other formats return nil.
+func (h *Handler) Parse(path string) (core.Parsed, error) {
+	if h.format != core.FmtJPEG && h.format != core.FmtPNG {
+		return nil, nil
+	}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &parsedImage{m: &core.Metadata{FilePath: path}}
+	if h.format == core.FmtJPEG {
+		p.m.Format = "JPEG"
+		if p.segments, err = parseJPEGSegments(data); err != nil {
+			return nil, err
+		}
+		_, err = viewJPEGFrom(bytes.NewReader(data), p.m)
+	} else {
+		p.m.Format = "PNG"
+		if p.chunks, err = readPNGChunks(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		_, err = viewPNGChunks(p.chunks, nil, p.m)
+	}
+	return p, err
+}
+
+type parsedImage struct {
+	m        *core.Metadata
+	segments []jpegSegment // JPEG
+	chunks   []pngChunk    // PNG
+}
+
+func (p *parsedImage) Metadata() *core.Metadata { return p.m }
+
+// Edit works on copies of the segment and chunk lists, whose entries the
+// writers replace, so p can be edited again.
+func (p *parsedImage) Edit(outPath string, opts core.EditOptions) error {
+	if p.segments != nil {
+		return editJPEGSegments(append([]jpegSegment(nil), p.segments...), outPath, opts)
+	}
+	return editPNGChunks(append([]pngChunk(nil), p.chunks...), outPath, opts)
+}
@@ -0,0 +1,53 @@
+package image
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Payload returns the image data of a JPEG (every segment but APPn and
+// COM, with the entropy-coded scans) or a PNG (the critical chunks: IHDR,
+// PLTE, IDAT, IEND). Other formats return nil.
+func (h *Handler) Payload(path string) ([]byte, error) {
+	switch h.format {
+	case core.FmtJPEG:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, seg := range segments {
+			if (seg.marker >= 0xE0 && seg.marker <= 0xEF) || seg.marker == 0xFE {
+				continue
+			}
+			buf.WriteByte(seg.marker)
+			buf.Write(seg.data)
+		}
+		return buf.Bytes(), nil
+	case core.FmtPNG:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		chunks, err := readPNGChunks(f)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, c := range chunks {
+			if c.typ[0] >= 'A' && c.typ[0] <= 'Z' { // critical chunk
+				buf.WriteString(c.typ)
+				buf.Write(c.data)
+			}
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,248 @@
+package image
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ─── Pipelines ───────────────────────────────────────────────────────────────
+// core.MetadataPreserver for JPEG and PNG. A JPEG keeps its fields in the
+// EXIF segment and its profile in APP2 ICC_PROFILE segments (split at 64
+// KB); a PNG in tEXt chunks under the PNG keywords and a zlib-compressed
+// iCCP chunk.
+
+// pngTextKeys maps EXIF field names to the PNG keywords for the same thing.
+var pngTextKeys = map[string]string{
+	"Artist":           "Author",
+	"ImageDescription": "Description",
+	"DateTime":         "Creation Time",
+}
+
+func pngTextKey(field string) string {
+	if k, ok := pngTextKeys[field]; ok {
+		return k
+	}
+	return field
+}
+
+// jpegICCPrefix starts each APP2 segment of an ICC profile, followed by
+// the segment's sequence number and the segment count.
+const jpegICCPrefix = "ICC_PROFILE\x00"
+
+// jpegICCChunk is the most profile data one APP2 segment holds.
+const jpegICCChunk = 65535 - 2 - len(jpegICCPrefix) - 2
+
+// PreservableMetadata returns the fields and profile of a JPEG or PNG in
+// data that opts keeps.
+func (h *Handler) PreservableMetadata(data []byte, opts core.PreserveOptions) (*core.PreservedMetadata, error) {
+	for _, f := range opts.Fields {
+		if _, ok := exifTagIDs[f]; !ok {
+			fields := supportedEditFields() // the EXIF text fields, then GPS
+			return nil, fmt.Errorf("cannot preserve %s; supported fields: %s", f, strings.Join(fields[:len(exifTagIDs)], ", "))
+		}
+	}
+	fields := map[string]string{}
+	var icc []byte
+	switch h.format {
+	case core.FmtJPEG:
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range segments {
+			if seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")) {
+				if x, err := exif.Decode(bytes.NewReader(seg.data[6:])); err == nil {
+					x.Walk(exifStringWalker{fields: fields})
+				}
+				break
+			}
+		}
+		icc = jpegICC(segments)
+	case core.FmtPNG:
+		chunks, err := readPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range chunks {
+			switch c.typ {
+			case "eXIf":
+				if x, err := exif.Decode(bytes.NewReader(c.data)); err == nil {
+					x.Walk(exifStringWalker{fields: fields})
+				}
+			case "iCCP":
+				if icc, err = pngICC(c.data); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, c := range chunks { // text chunks win over eXIf
+			if key, val, ok := bytes.Cut(c.data, []byte{0}); ok && c.typ == "tEXt" {
+				for _, f := range opts.Fields {
+					if string(key) == f || string(key) == pngTextKey(f) {
+						fields[f] = string(val)
+					}
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s does not support metadata pipelines", formatInfo[h.format].Name)
+	}
+
+	p := &core.PreservedMetadata{Fields: map[string]string{}}
+	for _, f := range opts.Fields {
+		if v := strings.TrimSpace(fields[f]); v != "" {
+			p.Fields[f] = v
+		}
+	}
+	if opts.ICC {
+		p.ICCProfile = icc
+	}
+	return p, nil
+}
+
+// ReattachMetadata writes p into the JPEG or PNG in data.
+func (h *Handler) ReattachMetadata(data []byte, p *core.PreservedMetadata) ([]byte, error) {
+	switch h.format {
+	case core.FmtJPEG:
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		var add []jpegSegment
+		if len(p.Fields) > 0 {
+			exifData, err := buildMinimalEXIF(p.Fields)
+			if err != nil {
+				return nil, err
+			}
+			add = append(add, jpegSegment{marker: 0xE1, data: exifData})
+		}
+		add = append(add, jpegICCSegments(p.ICCProfile)...)
+
+		out := []jpegSegment{segments[0]}
+		rest := segments[1:]
+		if len(rest) > 0 && rest[0].marker == 0xE0 { // JFIF stays first
+			out, rest = append(out, rest[0]), rest[1:]
+		}
+		out = append(out, add...)
+		for _, seg := range rest {
+			replaced := (seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")) && len(p.Fields) > 0) ||
+				(seg.marker == 0xE2 && bytes.HasPrefix(seg.data, []byte(jpegICCPrefix)) && p.ICCProfile != nil)
+			if !replaced {
+				out = append(out, seg)
+			}
+		}
+		return jpegBytes(out), nil
+	case core.FmtPNG:
+		chunks, err := readPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 || chunks[0].typ != "IHDR" {
+			return nil, fmt.Errorf("PNG does not start with IHDR")
+		}
+		set := map[string]bool{}
+		var add []pngChunk
+		if p.ICCProfile != nil {
+			iccp, err := pngICCChunk(p.ICCProfile)
+			if err != nil {
+				return nil, err
+			}
+			add = append(add, iccp)
+		}
+		for _, f := range core.SortedKeys(p.Fields) {
+			key := pngTextKey(f)
+			set[key] = true
+			add = append(add, pngChunk{typ: "tEXt", data: []byte(key + "\x00" + p.Fields[f])})
+		}
+
+		out := append([]pngChunk{chunks[0]}, add...)
+		for _, c := range chunks[1:] {
+			switch c.typ {
+			case "iCCP", "sRGB": // a profile replaces either
+				if p.ICCProfile != nil {
+					continue
+				}
+			case "tEXt":
+				if key, _, ok := bytes.Cut(c.data, []byte{0}); ok && set[string(key)] {
+					continue
+				}
+			}
+			out = append(out, c)
+		}
+		return pngBytes(out), nil
+	}
+	return nil, fmt.Errorf("%s does not support metadata pipelines", formatInfo[h.format].Name)
+}
+
+// jpegICC joins the ICC profile split over APP2 segments, or returns nil.
+func jpegICC(segments []jpegSegment) []byte {
+	parts := map[int][]byte{}
+	for _, seg := range segments {
+		if seg.marker == 0xE2 && len(seg.data) > len(jpegICCPrefix)+2 && bytes.HasPrefix(seg.data, []byte(jpegICCPrefix)) {
+			parts[int(seg.data[len(jpegICCPrefix)])] = seg.data[len(jpegICCPrefix)+2:]
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	seqs := make([]int, 0, len(parts))
+	for n := range parts {
+		seqs = append(seqs, n)
+	}
+	sort.Ints(seqs)
+	var icc []byte
+	for _, n := range seqs {
+		icc = append(icc, parts[n]...)
+	}
+	return icc
+}
+
+// jpegICCSegments splits an ICC profile into APP2 segments.
+func jpegICCSegments(icc []byte) []jpegSegment {
+	n := (len(icc) + jpegICCChunk - 1) / jpegICCChunk
+	var segs []jpegSegment
+	for i := 0; i < n; i++ {
+		end := (i + 1) * jpegICCChunk
+		if end > len(icc) {
+			end = len(icc)
+		}
+		d := append([]byte(jpegICCPrefix), byte(i+1), byte(n))
+		segs = append(segs, jpegSegment{marker: 0xE2, data: append(d, icc[i*jpegICCChunk:end]...)})
+	}
+	return segs
+}
+
+// pngICC decompresses the profile of an iCCP chunk: a name, a NUL, the
+// compression method (0, zlib) and the compressed profile.
+func pngICC(data []byte) ([]byte, error) {
+	_, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 1 || rest[0] != 0 {
+		return nil, fmt.Errorf("PNG iCCP chunk is malformed")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(rest[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("PNG iCCP chunk: %w", err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func pngICCChunk(icc []byte) (pngChunk, error) {
+	var buf bytes.Buffer
+	buf.WriteString("ICC Profile\x00\x00")
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(icc); err != nil {
+		return pngChunk{}, err
+	}
+	if err := w.Close(); err != nil {
+		return pngChunk{}, err
+	}
+	return pngChunk{typ: "iCCP", data: buf.Bytes()}, nil
+}
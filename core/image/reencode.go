@@ -0,0 +1,136 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Re-encode check ─────────────────────────────────────────────────────────
+// Messaging apps (WhatsApp, Telegram, Messenger) decode every photo sent
+// through them, shrink it to a fixed long edge and save it again with
+// libjpeg, dropping EXIF on the way. The result has a recognisable shape:
+// no camera metadata, the standard libjpeg tables at a middling quality,
+// and a long edge of one of the apps' sizes. A camera original has EXIF
+// and usually its maker's own tables. CheckReencode weighs these signs; it
+// is a heuristic, and a verdict is a lead to follow, not proof.
+
+// messagingEdges lists the long edges, in pixels, that messaging apps are
+// known to resize photos to.
+var messagingEdges = []struct {
+	edge int
+	app  string
+}{
+	{1600, "WhatsApp"},
+	{4096, "WhatsApp (HD)"},
+	{1280, "Telegram"},
+	{2560, "Telegram (HD)"},
+	{2048, "Messenger"},
+	{960, "Messenger"},
+}
+
+// ReencodeCheck is the result of CheckReencode.
+type ReencodeCheck struct {
+	Verdict string   // "likely", "possible" or "unlikely"
+	Apps    []string // apps whose output size the image matches
+	Reasons []string // the signs found, for and against
+}
+
+// String returns the verdict with the matching apps and the reasons.
+func (c *ReencodeCheck) String() string {
+	s := c.Verdict
+	if len(c.Apps) > 0 && c.Verdict != "unlikely" {
+		s += " (" + strings.Join(c.Apps, ", ") + ")"
+	}
+	return s + " — " + strings.Join(c.Reasons, "; ")
+}
+
+// CheckReencode reports whether the JPEG at path looks like a messaging
+// app's re-encode of a photo.
+func CheckReencode(path string) (*ReencodeCheck, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := parseJPEGSegments(data)
+	if err != nil {
+		return nil, err
+	}
+	frame, ok := readJPEGFrame(segments)
+	if !ok {
+		return nil, fmt.Errorf("JPEG has no frame header")
+	}
+
+	c := &ReencodeCheck{}
+	score := 0
+	var hasEXIF, hasMeta bool
+	for _, seg := range segments {
+		switch {
+		case seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")):
+			hasEXIF = true
+		case seg.marker >= 0xE1 && seg.marker <= 0xEF, seg.marker == 0xFE:
+			hasMeta = true
+		}
+	}
+	switch {
+	case hasEXIF:
+		c.Reasons = append(c.Reasons, "has EXIF")
+		score -= 2
+	case !hasMeta:
+		c.Reasons = append(c.Reasons, "no EXIF, XMP, IPTC or comment")
+		score++
+	default:
+		c.Reasons = append(c.Reasons, "no EXIF")
+	}
+
+	if q, exact := frame.quality(); exact {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("standard libjpeg tables at quality %d", q))
+		if q >= 60 && q <= 90 {
+			score++
+		}
+	} else if q > 0 {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("custom quantization tables (about quality %d)", q))
+		score--
+	}
+
+	long := frame.width
+	if frame.height > long {
+		long = frame.height
+	}
+	for _, m := range messagingEdges {
+		if long == m.edge {
+			c.Apps = append(c.Apps, m.app)
+		}
+	}
+	if len(c.Apps) > 0 {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("long edge %d px, a size %s resizes to", long, strings.Join(c.Apps, " and ")))
+		score++
+	}
+
+	switch {
+	case score >= 3:
+		c.Verdict = "likely"
+	case score == 2:
+		c.Verdict = "possible"
+	default:
+		c.Verdict = "unlikely"
+	}
+	return c, nil
+}
+
+// ReencodeFields returns the result of CheckReencode for path as fields,
+// or nil when path is not a JPEG that can be checked.
+func ReencodeFields(path string) []core.MetaField {
+	c, err := CheckReencode(path)
+	if err != nil {
+		return nil
+	}
+	fields := []core.MetaField{{Key: "Reencoded", Value: c.Verdict, Category: "Re-encode check"}}
+	if len(c.Apps) > 0 {
+		fields = append(fields, core.MetaField{Key: "MatchesApp", Value: strings.Join(c.Apps, ", "), Category: "Re-encode check"})
+	}
+	return append(fields, core.MetaField{Key: "Signs", Value: strings.Join(c.Reasons, "; "), Category: "Re-encode check"})
+}
@@ -0,0 +1,212 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── XMP face regions ────────────────────────────────────────────────────────
+// Picasa, Lightroom and most phone galleries store the faces they have
+// recognised as Metadata Working Group regions: an mwg-rs:Regions struct
+// whose RegionList holds one rectangle per face, with the person's name.
+// Areas are normalised to the image size, x/y being the centre.
+
+const (
+	nsMWGRegions = "http://www.metadataworkinggroup.com/schemas/regions/"
+	nsStArea     = "http://ns.adobe.com/xmp/sType/Area#"
+)
+
+// faceRegion is one entry of an mwg-rs RegionList.
+type faceRegion struct {
+	Name, Type string
+	X, Y, W, H string
+	Unit       string
+}
+
+func (r faceRegion) String() string {
+	name := r.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	s := name
+	if r.Type != "" {
+		s += " — " + r.Type
+	}
+	if r.X != "" && r.Y != "" {
+		s += fmt.Sprintf(", centre %s,%s", trimFloat(r.X), trimFloat(r.Y))
+	}
+	if r.W != "" && r.H != "" {
+		s += fmt.Sprintf(", size %s×%s", trimFloat(r.W), trimFloat(r.H))
+	}
+	if r.Unit != "" && r.Unit != "normalized" {
+		s += " " + r.Unit
+	}
+	return s
+}
+
+// trimFloat shortens a decimal to three places for display.
+func trimFloat(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+// readFaceRegions returns the regions in an XMP packet. Region properties
+// may be written as attributes of the rdf:Description or as elements.
+func readFaceRegions(packet []byte) []faceRegion {
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	var regions []faceRegion
+	var cur *faceRegion
+	depth, listDepth, itemDepth := 0, 0, 0
+	var field string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return regions
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Space == nsMWGRegions && t.Name.Local == "RegionList":
+				listDepth = depth
+			case listDepth > 0 && cur == nil && t.Name.Local == "li":
+				regions = append(regions, faceRegion{})
+				cur, itemDepth = &regions[len(regions)-1], depth
+			}
+			if cur == nil {
+				continue
+			}
+			for _, a := range t.Attr {
+				setRegionProp(cur, a.Name.Space, a.Name.Local, a.Value)
+			}
+			field = ""
+			if t.Name.Space == nsMWGRegions {
+				field = t.Name.Local
+			}
+		case xml.EndElement:
+			if cur != nil && depth == itemDepth {
+				cur = nil
+			}
+			if depth == listDepth {
+				listDepth = 0
+			}
+			depth--
+			field = ""
+		case xml.CharData:
+			if cur != nil && field != "" {
+				if v := strings.TrimSpace(string(t)); v != "" {
+					setRegionProp(cur, nsMWGRegions, field, v)
+				}
+			}
+		}
+	}
+}
+
+func setRegionProp(r *faceRegion, ns, local, val string) {
+	switch ns {
+	case nsMWGRegions:
+		switch local {
+		case "Name":
+			r.Name = val
+		case "Type":
+			r.Type = val
+		}
+	case nsStArea:
+		switch local {
+		case "x":
+			r.X = val
+		case "y":
+			r.Y = val
+		case "w":
+			r.W = val
+		case "h":
+			r.H = val
+		case "unit":
+			r.Unit = val
+		}
+	}
+}
+
+// appendRegionFields adds one field per region and a RegionPersons list of
+// the names, which the privacy audit reports.
+func appendRegionFields(packet []byte, m *core.Metadata) {
+	regions := readFaceRegions(packet)
+	var names []string
+	for i, r := range regions {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      fmt.Sprintf("Region%d", i+1),
+			Value:    r.String(),
+			Category: "Regions",
+			Editable: false,
+		})
+		if r.Name != "" {
+			names = append(names, r.Name)
+		}
+	}
+	if len(names) > 0 {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "RegionPersons",
+			Value:    strings.Join(names, "; "),
+			Category: "Regions",
+			Editable: false,
+		})
+	}
+}
+
+var mwgPrefixRe = regexp.MustCompile(`xmlns:([A-Za-z][\w.-]*)\s*=\s*["']` + regexp.QuoteMeta(nsMWGRegions) + `["']`)
+
+// removeFaceRegions drops the mwg-rs:Regions struct from an XMP packet and
+// reports whether there was one.
+func removeFaceRegions(packet []byte) ([]byte, bool) {
+	m := mwgPrefixRe.FindSubmatch(packet)
+	if m == nil {
+		return packet, false
+	}
+	name := regexp.QuoteMeta(string(m[1]) + ":Regions")
+	re := regexp.MustCompile(`(?s)\s*<` + name + `\b[^>]*?(?:/>|>.*?</` + name + `>)`)
+	if !re.Match(packet) {
+		return packet, false
+	}
+	return re.ReplaceAll(packet, nil), true
+}
+
+// stripJPEGRegions removes face regions from the XMP APP1 segment.
+func stripJPEGRegions(segments []jpegSegment) (removed bool) {
+	for i, seg := range segments {
+		if seg.marker != 0xE1 || !bytes.HasPrefix(seg.data, []byte(jpegXMPPrefix)) {
+			continue
+		}
+		if packet, ok := removeFaceRegions(seg.data[len(jpegXMPPrefix):]); ok {
+			segments[i].data = append([]byte(jpegXMPPrefix), packet...)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// stripPNGRegions removes face regions from the XMP iTXt chunk.
+func stripPNGRegions(chunks []pngChunk) (removed bool) {
+	for i, c := range chunks {
+		if c.typ != "iTXt" {
+			continue
+		}
+		key, text, ok := pngITXtText(c.data)
+		if !ok || key != pngXMPKeyword {
+			continue
+		}
+		if packet, ok := removeFaceRegions(text); ok {
+			chunks[i].data = buildPNGITXt(pngXMPKeyword, packet)
+			removed = true
+		}
+	}
+	return removed
+}
@@ -0,0 +1,90 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// SizeBreakdown lists the segments of a JPEG or the chunks of a PNG.
+// Other formats return nil.
+func (h *Handler) SizeBreakdown(path string) ([]core.SizePart, error) {
+	switch h.format {
+	case core.FmtJPEG:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		var parts []core.SizePart
+		for _, seg := range segments {
+			n := int64(len(seg.data))
+			switch seg.marker {
+			case 0x00:
+			case 0xD8, 0xD9:
+				n += 2
+			default:
+				n += 4 // marker and length
+			}
+			parts = append(parts, core.SizePart{Name: jpegSegmentName(seg), Bytes: n, Metadata: jpegMetaMarkers[seg.marker]})
+		}
+		return parts, nil
+	case core.FmtPNG:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		chunks, err := indexPNGChunks(f)
+		if err != nil {
+			return nil, err
+		}
+		parts := []core.SizePart{{Name: "PNG signature", Bytes: 8}}
+		for _, c := range chunks {
+			name := c.typ
+			if c.typ == "iTXt" && c.load(f) == nil {
+				if key, _, ok := pngITXtText(c.data); ok && key == pngXMPKeyword {
+					name = "iTXt XMP"
+				}
+			}
+			parts = append(parts, core.SizePart{Name: name, Bytes: int64(c.size) + 12, Metadata: pngMetaChunks[c.typ]})
+		}
+		return parts, nil
+	}
+	return nil, nil
+}
+
+// jpegSegmentName names a segment by its marker and, for APPn, its
+// identifier.
+func jpegSegmentName(seg jpegSegment) string {
+	switch {
+	case seg.marker == 0x00 || seg.marker == 0xDA:
+		return "Scan data"
+	case seg.marker == 0xD8 || seg.marker == 0xD9:
+		return "SOI/EOI"
+	case seg.marker == 0xFE:
+		return "COM comment"
+	case seg.marker == 0xE0 && bytes.HasPrefix(seg.data, []byte("JFIF")):
+		return "APP0 JFIF"
+	case seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("Exif")):
+		return "APP1 EXIF"
+	case seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte(jpegXMPPrefix)):
+		return "APP1 XMP"
+	case seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte("http://ns.adobe.com/xmp/extension/")):
+		return "APP1 Extended XMP"
+	case seg.marker == 0xE2 && bytes.HasPrefix(seg.data, []byte("ICC_PROFILE")):
+		return "APP2 ICC profile"
+	case seg.marker == 0xED:
+		return "APP13 IPTC/Photoshop"
+	case seg.marker == 0xEE:
+		return "APP14 Adobe"
+	case seg.marker >= 0xE0 && seg.marker <= 0xEF:
+		return fmt.Sprintf("APP%d", seg.marker-0xE0)
+	}
+	return "Tables and headers"
+}
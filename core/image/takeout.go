@@ -0,0 +1,185 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Google Takeout sidecars ─────────────────────────────────────────────────
+// Google Photos exports keep edits made in the app — descriptions, the
+// "taken" time and map positions — in a JSON file beside each photo
+// rather than in the photo itself. The sidecar for IMG_1234.JPG is
+// IMG_1234.JPG.json or IMG_1234.JPG.supplemental-metadata.json, with the
+// name cut short when it would pass 51 characters, and the sidecar for
+// the duplicate IMG_1234(1).JPG is IMG_1234.JPG(1).json.
+
+// TakeoutSidecar is the part of a Takeout JSON sidecar that maps onto
+// image metadata.
+type TakeoutSidecar struct {
+	Path           string `json:"-"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	PhotoTakenTime struct {
+		Timestamp string `json:"timestamp"`
+	} `json:"photoTakenTime"`
+	GeoData     takeoutGeo `json:"geoData"`
+	GeoDataExif takeoutGeo `json:"geoDataExif"`
+}
+
+type takeoutGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+}
+
+// ReadTakeoutSidecar parses one sidecar file.
+func ReadTakeoutSidecar(path string) (*TakeoutSidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s TakeoutSidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: invalid Takeout JSON: %w", filepath.Base(path), err)
+	}
+	s.Path = path
+	return &s, nil
+}
+
+// TakenTime returns the photo's capture time, if the sidecar has one.
+func (s *TakeoutSidecar) TakenTime() (time.Time, bool) {
+	sec, err := strconv.ParseInt(s.PhotoTakenTime.Timestamp, 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// position returns the location set in Google Photos, falling back to the
+// camera's own. Takeout writes 0,0 when there is none.
+func (s *TakeoutSidecar) position() (takeoutGeo, bool) {
+	for _, g := range []takeoutGeo{s.GeoData, s.GeoDataExif} {
+		if g.Latitude != 0 || g.Longitude != 0 {
+			return g, true
+		}
+	}
+	return takeoutGeo{}, false
+}
+
+// Fields returns the edit fields the sidecar provides for an image of the
+// given format, keyed as that format's edit accepts them. Capture times
+// are written in the local time zone, as cameras do.
+func (s *TakeoutSidecar) Fields(format core.FormatID) map[string]string {
+	set := make(map[string]string)
+	desc := strings.TrimSpace(s.Description)
+	taken, hasTaken := s.TakenTime()
+	switch format {
+	case core.FmtJPEG, core.FmtHEIC:
+		if desc != "" {
+			set["ImageDescription"] = desc
+		}
+		if hasTaken {
+			set["DateTimeOriginal"] = taken.Local().Format("2006:01:02 15:04:05")
+		}
+		if g, ok := s.position(); ok {
+			set["GPSLatitude"] = strconv.FormatFloat(g.Latitude, 'f', 7, 64)
+			set["GPSLongitude"] = strconv.FormatFloat(g.Longitude, 'f', 7, 64)
+			if g.Altitude != 0 {
+				set["GPSAltitude"] = strconv.FormatFloat(g.Altitude, 'f', -1, 64)
+			}
+		}
+	case core.FmtPNG:
+		if desc != "" {
+			set["Description"] = desc
+		}
+		if hasTaken {
+			set["Creation Time"] = taken.Local().Format(time.RFC1123Z)
+		}
+	}
+	return set
+}
+
+// ─── Sidecar matching ────────────────────────────────────────────────────────
+
+// TakeoutIndex finds the sidecar for each photo in an export.
+type TakeoutIndex struct {
+	byDir   map[string]map[string]string // dir → sidecar name without ".json" → path
+	byTitle map[string][]string
+}
+
+// takeoutNameLimit is the longest sidecar file name Takeout writes.
+const takeoutNameLimit = 51
+
+var takeoutDupRe = regexp.MustCompile(`^(.*)\((\d+)\)(\.[^.]*)$`)
+
+// NewTakeoutIndex indexes the .json files among files.
+func NewTakeoutIndex(files []string) *TakeoutIndex {
+	ix := &TakeoutIndex{byDir: make(map[string]map[string]string), byTitle: make(map[string][]string)}
+	for _, f := range files {
+		if !strings.EqualFold(filepath.Ext(f), ".json") {
+			continue
+		}
+		dir, name := filepath.Split(f)
+		if ix.byDir[dir] == nil {
+			ix.byDir[dir] = make(map[string]string)
+		}
+		ix.byDir[dir][strings.TrimSuffix(name, filepath.Ext(name))] = f
+		if s, err := ReadTakeoutSidecar(f); err == nil && s.Title != "" {
+			key := filepath.Join(dir, s.Title)
+			ix.byTitle[key] = append(ix.byTitle[key], f)
+		}
+	}
+	return ix
+}
+
+// Lookup returns the sidecar path for photo, or "" if there is none.
+func (ix *TakeoutIndex) Lookup(photo string) string {
+	dir, name := filepath.Split(photo)
+	sidecars := ix.byDir[dir]
+	var candidates []string
+	add := func(n string) {
+		candidates = append(candidates, n, n+".supplemental-metadata")
+	}
+	add(name)
+	if m := takeoutDupRe.FindStringSubmatch(name); m != nil {
+		// IMG_1234(1).JPG → IMG_1234.JPG(1).json
+		candidates = append(candidates, m[1]+m[3]+"("+m[2]+")", m[1]+m[3]+".supplemental-metadata("+m[2]+")")
+	}
+	if ext := filepath.Ext(name); strings.HasSuffix(strings.TrimSuffix(name, ext), "-edited") {
+		add(strings.TrimSuffix(strings.TrimSuffix(name, ext), "-edited") + ext)
+	}
+	for _, c := range candidates {
+		if p, ok := sidecars[c]; ok {
+			return p
+		}
+	}
+
+	// Truncated names: the longest sidecar name that is a prefix of the
+	// full supplemental name and still covers the photo's base name.
+	full := name + ".supplemental-metadata"
+	if len(full)+len(".json") > takeoutNameLimit {
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+		best, bestLen := "", 0
+		for n, p := range sidecars {
+			if len(n) >= len(stem) && len(n) > bestLen && strings.HasPrefix(full, n) {
+				best, bestLen = p, len(n)
+			}
+		}
+		if best != "" {
+			return best
+		}
+	}
+
+	if ps := ix.byTitle[photo]; len(ps) == 1 {
+		return ps[0]
+	}
+	return ""
+}
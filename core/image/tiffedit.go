@@ -0,0 +1,216 @@
+package image
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
+)
+
+// ─── TIFF and DNG editing ────────────────────────────────────────────────────
+// A DNG is a TIFF whose IFDs point at raw sensor data, tile tables,
+// previews and maker structures, with offsets anywhere in the file.
+// Rebuilding any of that risks the raw data photographers care most
+// about, so edit never moves a byte: it overwrites the values of a few
+// IFD0 tags where they stand — Artist, Copyright and ImageDescription —
+// and the XMP packet of tag 700. A value that fits the space the file
+// gives it is written there, the rest of the space zeroed or, for XMP,
+// filled with packet padding. One that does not fit, or a tag the file
+// does not have, is refused rather than written elsewhere.
+
+// tiffTextTags are the IFD0 tags edit patches in TIFF and DNG files.
+var tiffTextTags = map[string]uint16{
+	"Artist":           0x013B,
+	"Copyright":        0x8298,
+	"ImageDescription": 0x010E,
+}
+
+// tiffXMPTag holds the XMP packet of a TIFF, as BYTE or UNDEFINED.
+const tiffXMPTag = 700
+
+func editTIFF(path, outPath string, opts core.EditOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := applyTIFFEdits(data, opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Println("Dry-run: TIFF tags would be overwritten in place with:")
+		for _, k := range core.SortedKeys(opts.Set) {
+			fmt.Printf("  %s = %s\n", k, opts.Set[k])
+		}
+		return nil
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
+
+// stripTIFF removes the GPS IFD of a TIFF or DNG, which it does in place
+// like edit; the rest of a DNG's tags describe the raw data and stay, so
+// --gps-only is the only strip there is.
+func stripTIFF(path, outPath string, opts core.StripOptions) error {
+	if !opts.StripGPS {
+		return fmt.Errorf("TIFF and DNG support only strip --gps-only; delete other tags with edit --delete")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := stripTIFFGPS(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out, 0644)
+}
+
+// applyTIFFEdits returns a copy of the TIFF data with the tags of opts
+// overwritten in place.
+func applyTIFFEdits(data []byte, opts core.EditOptions) ([]byte, error) {
+	x, err := newEXIFBlock(append([]byte(nil), data...))
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := x.readIFD(x.bo.Uint32(x.b[4:8]))
+	if err != nil {
+		return nil, err
+	}
+	opts, kw, err := takeXMPEdits(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range core.SortedKeys(opts.Set) {
+		if err := x.patchText(ifd0, k, opts.Set[k]); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range opts.Delete {
+		if err := x.patchText(ifd0, k, ""); err != nil {
+			return nil, err
+		}
+	}
+	if len(kw) > 0 {
+		if err := x.patchXMP(ifd0, kw); err != nil {
+			return nil, err
+		}
+	}
+	return x.b, nil
+}
+
+// entryAt returns where the value of entry i of ifd is stored and how many
+// bytes it has.
+func (x *exifBlock) entryAt(ifd *tiffIFD, i int) (off, n int, err error) {
+	e := ifd.entries[i]
+	if off, n, ok := x.outOfLine(e); ok {
+		return off, n, nil
+	}
+	n = tiffTypeSizes[e.typ] * int(e.count)
+	if n > 4 {
+		return 0, 0, fmt.Errorf("TIFF: value of tag %d lies outside the file", e.tag)
+	}
+	return int(ifd.off) + 2 + 12*i + 8, n, nil
+}
+
+// patchText overwrites the ASCII value of the IFD0 tag called name with
+// val, an empty val clearing it.
+func (x *exifBlock) patchText(ifd0 *tiffIFD, name, val string) error {
+	tag, ok := tiffTextTags[name]
+	if !ok {
+		return fmt.Errorf("%s cannot be edited in TIFF or DNG; editable: Artist, Copyright, ImageDescription and xmp:prefix:name", name)
+	}
+	i := ifd0.find(tag)
+	if i < 0 {
+		return fmt.Errorf("%s: the file has no %s tag, and adding one would move IFD0; not written", name, name)
+	}
+	if ifd0.entries[i].typ != 2 {
+		return fmt.Errorf("%s: the file stores it as type %d, not ASCII", name, ifd0.entries[i].typ)
+	}
+	off, n, err := x.entryAt(ifd0, i)
+	if err != nil {
+		return err
+	}
+	value := append([]byte(val), 0)
+	if len(value) > n {
+		return fmt.Errorf("%s: %d bytes do not fit the %d the file reserves for it; not written", name, len(value), n)
+	}
+	copy(x.b[off:], value)
+	x.zero(uint32(off+len(value)), n-len(value))
+	// A value stored out of line keeps its count, NUL padded, since a count
+	// of 4 or less would mean the value is in the entry.
+	if n <= 4 {
+		x.bo.PutUint32(x.b[int(ifd0.off)+2+12*i+4:], uint32(len(value)))
+	}
+	return nil
+}
+
+// patchXMP applies kw to the XMP packet of IFD0, padded to the length it
+// had.
+func (x *exifBlock) patchXMP(ifd0 *tiffIFD, kw xmpEdit) error {
+	i := ifd0.find(tiffXMPTag)
+	if i < 0 {
+		return fmt.Errorf("XMP: the file has no XMP packet, and adding one would move IFD0; not written")
+	}
+	off, n, err := x.entryAt(ifd0, i)
+	if err != nil {
+		return err
+	}
+	old := x.b[off : off+n]
+	packet := xmp.Pad(applyXMPEdit(old, kw), n)
+	if len(packet) != n {
+		return fmt.Errorf("XMP: the edited packet does not fit the %d bytes the file reserves for it; not written", n)
+	}
+	copy(x.b[off:], packet)
+	return nil
+}
+
+// readTIFFXMP returns the XMP packet of IFD0 of the TIFF r and its offset,
+// reading only IFD0 and the packet.
+func readTIFFXMP(r io.ReaderAt) ([]byte, int64) {
+	hdr, err := core.ReadRange(r, 0, 8)
+	if err != nil || len(hdr) < 8 {
+		return nil, 0
+	}
+	var bo binary.ByteOrder = binary.LittleEndian
+	if string(hdr[:2]) == "MM" {
+		bo = binary.BigEndian
+	}
+	ifd := int64(bo.Uint32(hdr[4:8]))
+	cnt, err := core.ReadRange(r, ifd, 2)
+	if err != nil || len(cnt) < 2 {
+		return nil, 0
+	}
+	n := int(bo.Uint16(cnt))
+	entries, err := core.ReadRange(r, ifd+2, 12*n)
+	if err != nil || len(entries) < 12*n {
+		return nil, 0
+	}
+	for i := 0; i < n; i++ {
+		e := entries[12*i:]
+		if bo.Uint16(e) != tiffXMPTag || tiffTypeSizes[bo.Uint16(e[2:])] != 1 {
+			continue
+		}
+		size, at := int(bo.Uint32(e[4:])), int64(bo.Uint32(e[8:]))
+		if size <= 4 {
+			return nil, 0
+		}
+		packet, err := core.ReadRange(r, at, size)
+		if err != nil || len(packet) < size {
+			return nil, 0
+		}
+		return packet, at
+	}
+	return nil, 0
+}
+
+// tiffEditableNames is tiffTextTags as the set view marks editable.
+func tiffEditableNames() map[string]bool {
+	out := make(map[string]bool, len(tiffTextTags))
+	for k := range tiffTextTags {
+		out[k] = true
+	}
+	return out
+}
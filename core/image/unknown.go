@@ -0,0 +1,83 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// UnknownStructures lists the APP segments of a JPEG that view does not
+// recognise, named by their identifier, and the chunks of a PNG or WebP
+// outside the specification.
+func (h *Handler) UnknownStructures(path string) ([]core.UnknownStructure, error) {
+	switch h.format {
+	case core.FmtJPEG, core.FmtPNG, core.FmtWebP:
+	default:
+		return nil, fmt.Errorf("unknown-structure report supports JPEG, PNG and WebP images, not %s", strings.ToUpper(string(h.format)))
+	}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []core.UnknownStructure
+	switch h.format {
+	case core.FmtJPEG:
+		segments, err := parseJPEGSegments(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range segments {
+			if seg.marker < 0xE0 || seg.marker > 0xEF || jpegSegmentName(seg) != fmt.Sprintf("APP%d", seg.marker-0xE0) {
+				continue
+			}
+			id := fmt.Sprintf("APP%d", seg.marker-0xE0)
+			if ident := jpegAppIdentifier(seg.data); ident != "" {
+				id += " " + ident
+			}
+			out = append(out, core.UnknownStructure{Kind: "APP segment", ID: id, Bytes: int64(len(seg.data)) + 4})
+		}
+	case core.FmtPNG:
+		chunks, err := indexPNGChunks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range chunks {
+			if !pngKnownChunks[c.typ] {
+				out = append(out, core.UnknownStructure{Kind: "PNG chunk", ID: c.typ, Bytes: int64(c.size) + 12})
+			}
+		}
+	case core.FmtWebP:
+		chunks, err := readWebPChunks(data)
+		if err != nil && len(chunks) == 0 {
+			return nil, err
+		}
+		for _, c := range chunks {
+			if !webpKnownChunks[c.id] {
+				out = append(out, core.UnknownStructure{Kind: "WebP chunk", ID: c.id, Bytes: int64(len(c.data)) + 8})
+			}
+		}
+	}
+	return out, nil
+}
+
+// jpegAppIdentifier returns the NUL-terminated name most APP segments
+// start with ("MPF", "Ducky", "FPXR"), or "" when the segment does not
+// start with printable text.
+func jpegAppIdentifier(data []byte) string {
+	n := bytes.IndexByte(data, 0)
+	if n <= 0 || n > 32 {
+		return ""
+	}
+	for _, c := range data[:n] {
+		if c < 0x20 || c > 0x7E {
+			return ""
+		}
+	}
+	return string(data[:n])
+}
@@ -0,0 +1,277 @@
+package image
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
+)
+
+// ─── XMP keywords ────────────────────────────────────────────────────────────
+// Flat keywords live in the dc:subject bag. Lightroom and darktable keep
+// the tree they came from in lr:hierarchicalSubject as "Animals|Birds|Owl"
+// paths, and export every level of each path as a flat keyword too.
+// view shows both bags as "; "-separated lists under Keywords and
+// HierarchicalKeywords, and edit writes them back into the XMP packet of a
+// JPEG or PNG without touching the rest of it. Payload checksums are kept
+// in the same packet, as simple properties in this tool's namespace. Any
+// other property is set by its XMP name, as "xmp:dc:creator=Jane"; see
+// core/xmp for how the packet is written.
+
+const (
+	jpegXMPPrefix = "http://ns.adobe.com/xap/1.0/\x00"
+	pngXMPKeyword = "XML:com.adobe.xmp"
+
+	// jpegXMPMax is the largest packet one APP1 segment holds.
+	jpegXMPMax = 65533 - len(jpegXMPPrefix)
+)
+
+// xmpProp is an XMP property that view and edit also handle under a
+// field name of their own.
+type xmpProp struct {
+	field string // edit/view name
+	xmp.Property
+}
+
+func namedXMPProp(field, name string) xmpProp {
+	p, err := xmp.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+	return xmpProp{field, p}
+}
+
+var (
+	bagKeywords     = namedXMPProp("Keywords", "dc:subject")
+	bagHierarchical = namedXMPProp("HierarchicalKeywords", "lr:hierarchicalSubject")
+	keywordBags     = []xmpProp{bagKeywords, bagHierarchical}
+
+	xmpProps = append(keywordBags,
+		namedXMPProp("PayloadSHA256", "surgery:PayloadSHA256"),
+		namedXMPProp("PayloadMD5", "surgery:PayloadMD5"))
+)
+
+// xmpPropIndex returns the index in xmpProps of the property called name,
+// or -1.
+func xmpPropIndex(name xml.Name) int {
+	for i, p := range xmpProps {
+		if name.Space == p.NS && name.Local == p.Local {
+			return i
+		}
+	}
+	return -1
+}
+
+// xmpEdit holds the properties an edit replaces, by XMP name ("dc:subject"),
+// with their items; a missing entry is left alone and an empty one is
+// removed.
+type xmpEdit map[string][]string
+
+// removesOnly reports whether kw removes properties and sets none.
+func (kw xmpEdit) removesOnly() bool {
+	for _, items := range kw {
+		if len(items) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// takeXMPEdits moves the XMP properties — the named ones of xmpProps and
+// any "xmp:prefix:name" — out of opts.
+func takeXMPEdits(opts core.EditOptions) (core.EditOptions, xmpEdit, error) {
+	kw := xmpEdit{}
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		p, ok, err := xmpPropFor(k)
+		if err != nil {
+			return opts, nil, err
+		}
+		if ok {
+			kw[p.Name()] = p.Split(v)
+			continue
+		}
+		set[k] = v
+	}
+	var del []string
+	for _, k := range opts.Delete {
+		p, ok, err := xmpPropFor(k)
+		if err != nil {
+			return opts, nil, err
+		}
+		if ok {
+			kw[p.Name()] = []string{}
+			continue
+		}
+		del = append(del, k)
+	}
+	opts.Set, opts.Delete = set, del
+	return opts, kw, nil
+}
+
+// xmpPropFor returns the XMP property an edit key names, if it names one.
+func xmpPropFor(key string) (xmp.Property, bool, error) {
+	for _, p := range xmpProps {
+		if strings.EqualFold(key, p.field) || strings.EqualFold(key, p.Name()) {
+			return p.Property, true, nil
+		}
+	}
+	if len(key) > 4 && strings.EqualFold(key[:4], "xmp:") {
+		p, err := xmp.Lookup(key[4:])
+		return p, err == nil, err
+	}
+	return xmp.Property{}, false, nil
+}
+
+// applyXMPEdit rewrites the properties named in kw inside packet (a new
+// packet when nil). Setting hierarchical keywords alone also adds every
+// level of each path to the flat keywords, as Lightroom does.
+func applyXMPEdit(packet []byte, kw xmpEdit) []byte {
+	if len(packet) == 0 {
+		packet = xmp.New()
+	}
+	hier, setHier := kw[bagHierarchical.Name()]
+	if _, setFlat := kw[bagKeywords.Name()]; setHier && !setFlat && len(hier) > 0 {
+		flat := xmp.Values(packet, bagKeywords.Property)
+		seen := make(map[string]bool)
+		for _, k := range flat {
+			seen[k] = true
+		}
+		for _, path := range hier {
+			for _, level := range strings.Split(path, "|") {
+				if level = strings.TrimSpace(level); level != "" && !seen[level] {
+					flat = append(flat, level)
+					seen[level] = true
+				}
+			}
+		}
+		kw[bagKeywords.Name()] = flat
+	}
+	names := make([]string, 0, len(kw))
+	for name := range kw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if p, err := xmp.Lookup(name); err == nil {
+			packet = xmp.Set(packet, p, kw[name])
+		}
+	}
+	return packet
+}
+
+// ─── Container plumbing ──────────────────────────────────────────────────────
+
+// setJPEGXMP updates (or adds) the XMP APP1 segment.
+func setJPEGXMP(segments []jpegSegment, kw xmpEdit) ([]jpegSegment, error) {
+	for i, seg := range segments {
+		if seg.marker == 0xE1 && bytes.HasPrefix(seg.data, []byte(jpegXMPPrefix)) {
+			packet, err := jpegXMPPacket(seg.data[len(jpegXMPPrefix):], kw)
+			if err != nil {
+				return nil, err
+			}
+			segments[i].data = append([]byte(jpegXMPPrefix), packet...)
+			return segments, nil
+		}
+	}
+	if kw.removesOnly() {
+		return segments, nil
+	}
+	packet, err := jpegXMPPacket(nil, kw)
+	if err != nil {
+		return nil, err
+	}
+	seg := jpegSegment{marker: 0xE1, data: append([]byte(jpegXMPPrefix), packet...)}
+	// After SOI and any APP0/APP1 (JFIF, EXIF) segments.
+	at := 1
+	for at < len(segments) && (segments[at].marker == 0xE0 || segments[at].marker == 0xE1) {
+		at++
+	}
+	return append(segments[:at], append([]jpegSegment{seg}, segments[at:]...)...), nil
+}
+
+// jpegXMPPacket returns old with the edits of kw, padded (see xmp.Repad)
+// and short enough for one APP1 segment.
+func jpegXMPPacket(old []byte, kw xmpEdit) ([]byte, error) {
+	packet := xmp.Repad(applyXMPEdit(old, kw), old)
+	if len(packet) > jpegXMPMax {
+		packet = xmp.Pad(packet, jpegXMPMax)
+	}
+	if len(packet) > jpegXMPMax {
+		return nil, fmt.Errorf("XMP packet would grow to %d bytes, over the 64 KB a JPEG segment holds", len(xmp.TrimPadding(packet)))
+	}
+	return packet, nil
+}
+
+// removeXMPGPS drops the exif:GPS* properties from an XMP packet, keeping
+// its length where the padding allows, and reports whether it had any.
+func removeXMPGPS(packet []byte) ([]byte, bool) {
+	out, n := xmp.RemoveAll(packet, xmp.Namespaces["exif"], "GPS")
+	if n == 0 {
+		return packet, false
+	}
+	return xmp.Repad(out, packet), true
+}
+
+// pngITXtText returns the keyword and text of an iTXt chunk.
+func pngITXtText(data []byte) (key string, text []byte, ok bool) {
+	null := bytes.IndexByte(data, 0)
+	if null <= 0 || null+3 > len(data) {
+		return "", nil, false
+	}
+	compressed := data[null+1] == 1
+	rest := data[null+3:]
+	for i := 0; i < 2; i++ { // language tag, translated keyword
+		n := bytes.IndexByte(rest, 0)
+		if n < 0 {
+			return "", nil, false
+		}
+		rest = rest[n+1:]
+	}
+	if compressed {
+		r, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			return "", nil, false
+		}
+		defer r.Close()
+		if rest, err = io.ReadAll(r); err != nil {
+			return "", nil, false
+		}
+	}
+	return string(data[:null]), rest, true
+}
+
+func buildPNGITXt(key string, text []byte) []byte {
+	d := append([]byte(key), 0, 0, 0, 0, 0) // key, no compression, method, empty language and translation
+	return append(d, text...)
+}
+
+// setPNGXMP updates (or adds, before IDAT) the XMP iTXt chunk.
+func setPNGXMP(chunks []pngChunk, kw xmpEdit) []pngChunk {
+	for i, c := range chunks {
+		if c.typ != "iTXt" {
+			continue
+		}
+		if key, text, ok := pngITXtText(c.data); ok && key == pngXMPKeyword {
+			packet := xmp.Repad(applyXMPEdit(text, kw), text)
+			chunks[i].data = buildPNGITXt(pngXMPKeyword, packet)
+			return chunks
+		}
+	}
+	if kw.removesOnly() {
+		return chunks
+	}
+	c := pngChunk{typ: "iTXt", data: buildPNGITXt(pngXMPKeyword, xmp.Repad(applyXMPEdit(nil, kw), nil))}
+	for i, ch := range chunks {
+		if ch.typ == "IDAT" {
+			return append(chunks[:i], append([]pngChunk{c}, chunks[i:]...)...)
+		}
+	}
+	return append(chunks, c)
+}
@@ -0,0 +1,17 @@
+package core
+
+// ─── In-memory files ─────────────────────────────────────────────────────────
+// A browser running the WASM build, or a server holding an upload, has the
+// bytes of a file and no path to open. A MemoryHandler views, edits and
+// strips those bytes without touching the file system. The image handler
+// implements it for JPEG, PNG, GIF and WebP (edit: JPEG and PNG); other
+// formats still need a file.
+
+// MemoryHandler is implemented by handlers that work on file contents in
+// memory. The input slice is not modified; edits and strips return a new
+// one. DryRun has no effect: nothing is written either way.
+type MemoryHandler interface {
+	ViewBytes(data []byte) (*Metadata, error)
+	EditBytes(data []byte, opts EditOptions) ([]byte, error)
+	StripBytes(data []byte, opts StripOptions) ([]byte, error)
+}
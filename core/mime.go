@@ -0,0 +1,163 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─── MIME types ──────────────────────────────────────────────────────────────
+// A server that stores uploads needs the content type, not the format ID.
+// Most formats have one MIME type. ZIP and ISOBMFF files do not: a ZIP is
+// a DOCX, an ODS or an EPUB depending on its parts, and an ftyp box can
+// hold an MP4, an M4A or a HEIC. DetectMIME looks inside those instead of
+// trusting the extension.
+
+// mimeTypes maps each format to its primary MIME type, as the handlers list
+// it first in FormatInfo.MIMETypes.
+var mimeTypes = map[FormatID]string{
+	FmtJPEG: "image/jpeg",
+	FmtPNG:  "image/png",
+	FmtGIF:  "image/gif",
+	FmtWebP: "image/webp",
+	FmtTIFF: "image/tiff",
+	FmtBMP:  "image/bmp",
+	FmtHEIC: "image/heic",
+	FmtSVG:  "image/svg+xml",
+
+	FmtMP3:  "audio/mpeg",
+	FmtFLAC: "audio/flac",
+	FmtOGG:  "audio/ogg",
+	FmtOpus: "audio/opus",
+	FmtM4A:  "audio/mp4",
+	FmtWAV:  "audio/wav",
+	FmtAIFF: "audio/aiff",
+
+	FmtMP4:  "video/mp4",
+	FmtMOV:  "video/quicktime",
+	FmtMKV:  "video/x-matroska",
+	FmtWebM: "video/webm",
+	FmtAVI:  "video/x-msvideo",
+	FmtWMV:  "video/x-ms-wmv",
+	FmtFLV:  "video/x-flv",
+
+	FmtPDF:  "application/pdf",
+	FmtDOCX: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	FmtXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FmtPPTX: "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	FmtODT:  "application/vnd.oasis.opendocument.text",
+	FmtEPUB: "application/epub+zip",
+	FmtCBZ:  "application/vnd.comicbook+zip",
+
+	FmtSRT: "application/x-subrip",
+	FmtASS: "text/x-ssa",
+	FmtVTT: "text/vtt",
+}
+
+// MIMEOctetStream is returned for files whose format is not recognised.
+const MIMEOctetStream = "application/octet-stream"
+
+// MIMEFor returns the primary MIME type of a format, or MIMEOctetStream.
+func MIMEFor(id FormatID) string {
+	if m, ok := mimeTypes[id]; ok {
+		return m
+	}
+	return MIMEOctetStream
+}
+
+// DetectMIME returns the MIME type of the file at path. ZIP containers are
+// told apart by their parts and ISOBMFF files by their ftyp brands; other
+// formats take the type of the format DetectFormat finds.
+func DetectMIME(path string) (string, error) {
+	id, err := DetectFormat(path)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case MediaTypeFor(id) == "document" && id != FmtPDF:
+		if m := zipMIME(path); m != "" {
+			return m, nil
+		}
+	case id == FmtMP4 || id == FmtM4A || id == FmtMOV || id == FmtHEIC:
+		if m := ftypMIME(path); m != "" {
+			return m, nil
+		}
+	}
+	return MIMEFor(id), nil
+}
+
+// zipMIME returns the MIME type of a ZIP container, or "" when path cannot
+// be read as a ZIP. A ZIP that is none of the supported containers is
+// application/zip, unless its name says it is a comic book.
+func zipMIME(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	zr, err := zip.NewReader(f, st.Size())
+	if err != nil {
+		return ""
+	}
+	if _, m := zipKind(zr); m != "" {
+		return m
+	}
+	if strings.EqualFold(filepath.Ext(path), ".cbz") {
+		return MIMEFor(FmtCBZ)
+	}
+	return "application/zip"
+}
+
+// ftypMIME returns the MIME type named by the brands of an ISOBMFF ftyp
+// box, or "" when the box cannot be read. A generic brand (isom, mp42)
+// says nothing about the content, so the extension decides between
+// audio/mp4 and video/mp4.
+func ftypMIME(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	hdr, err := ReadRange(f, 0, 8)
+	if err != nil || len(hdr) < 8 || string(hdr[4:8]) != "ftyp" {
+		return ""
+	}
+	size := int(binary.BigEndian.Uint32(hdr[:4]))
+	if size < 16 || size > 4096 {
+		return ""
+	}
+	box, err := ReadRange(f, 8, size-8)
+	if err != nil || len(box) < 8 {
+		return ""
+	}
+	brands := []string{string(box[:4])} // major brand, then compatible ones
+	for i := 8; i+4 <= len(box); i += 4 {
+		brands = append(brands, string(box[i:i+4]))
+	}
+	for _, b := range brands {
+		switch b {
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		case "mif1", "msf1":
+			return "image/heif"
+		case "qt  ":
+			return "video/quicktime"
+		case "M4A ", "M4B ", "M4P ":
+			return "audio/mp4"
+		case "3gp4", "3gp5", "3gp6", "3gg6":
+			return "video/3gpp"
+		}
+	}
+	if id := extMap[strings.ToLower(filepath.Ext(path))]; id == FmtM4A {
+		return "audio/mp4"
+	}
+	return "video/mp4"
+}
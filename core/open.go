@@ -0,0 +1,182 @@
+package core
+
+import "strings"
+
+// ─── Open documents ──────────────────────────────────────────────────────────
+// A typical session views a file and then edits it, and each step parses
+// it again. A Document parses once: it holds the fields, collects Set and
+// Delete calls, and Save writes them all as one edit. Handlers that
+// implement Parser let Save edit the structure View already parsed; for
+// the rest Save falls back to Handler.Edit.
+
+// Parser is implemented by handlers that can keep what they parse for
+// View and edit from it. Parse returns nil for formats it does not cover.
+type Parser interface {
+	Parse(path string) (Parsed, error)
+}
+
+// Parsed is one file as a handler parsed it.
+type Parsed interface {
+	Metadata() *Metadata
+	// Edit applies opts to the parsed file and writes the result to
+	// outPath. The parsed structure itself is left unchanged.
+	Edit(outPath string, opts EditOptions) error
+}
+
+// Document is a file opened for several reads and edits.
+type Document struct {
+	h      Handler
+	path   string
+	parsed Parsed // nil when h is not a Parser for this format
+	meta   *Metadata
+	state  FileState // path as it was read, for Save's conflict check
+	set    map[string]string
+	del    []string
+}
+
+// OpenDocument parses path with h.
+func OpenDocument(h Handler, path string) (*Document, error) {
+	d := &Document{h: h, path: path}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Document) load() error {
+	state, err := Snapshot(d.path)
+	if err != nil {
+		return err
+	}
+	d.state = state
+	if p, ok := d.h.(Parser); ok {
+		parsed, err := p.Parse(d.path)
+		if err != nil {
+			return err
+		}
+		if parsed != nil {
+			d.parsed, d.meta = parsed, parsed.Metadata()
+			return nil
+		}
+	}
+	// A file with no tags yet may fail to view and still be editable, so
+	// whatever View found is kept.
+	m, err := d.h.View(d.path)
+	if m == nil {
+		return err
+	}
+	d.parsed, d.meta = nil, m
+	return nil
+}
+
+// Path returns the file the document was read from.
+func (d *Document) Path() string { return d.path }
+
+// Handler returns the handler the document was opened with.
+func (d *Document) Handler() Handler { return d.h }
+
+// Metadata returns the fields as read, without pending changes.
+func (d *Document) Metadata() *Metadata { return d.meta }
+
+// Fields returns the fields with pending changes applied: a set field
+// shows its new value (new ones last, under "Pending") and a deleted one
+// is left out. Keys match case-insensitively, as in edit.
+func (d *Document) Fields() []MetaField {
+	var out []MetaField
+	done := make(map[string]bool)
+	for _, f := range d.meta.Fields {
+		key := strings.ToLower(f.Key)
+		if d.deleted(key) || done[key] {
+			continue
+		}
+		if v, ok := d.pending(key); ok {
+			f.Value, f.Raw = v, ""
+			done[key] = true
+		}
+		out = append(out, f)
+	}
+	for _, k := range SortedKeys(d.set) {
+		if !done[strings.ToLower(k)] {
+			out = append(out, MetaField{Key: k, Value: d.set[k], Category: "Pending", Editable: true})
+		}
+	}
+	return out
+}
+
+// Set records a new value for key.
+func (d *Document) Set(key, value string) {
+	d.forget(key)
+	if d.set == nil {
+		d.set = make(map[string]string)
+	}
+	d.set[key] = value
+}
+
+// Delete records the removal of key.
+func (d *Document) Delete(key string) {
+	d.forget(key)
+	d.del = append(d.del, key)
+}
+
+// forget drops any pending change to key.
+func (d *Document) forget(key string) {
+	for k := range d.set {
+		if strings.EqualFold(k, key) {
+			delete(d.set, k)
+		}
+	}
+	kept := d.del[:0]
+	for _, k := range d.del {
+		if !strings.EqualFold(k, key) {
+			kept = append(kept, k)
+		}
+	}
+	d.del = kept
+}
+
+// Modified reports whether there are changes Save has not written.
+func (d *Document) Modified() bool { return len(d.set) > 0 || len(d.del) > 0 }
+
+// Save writes the pending changes to outPath ("" for in place) in one
+// edit, then reads the written file back: later calls see it as saved and
+// a further Save edits it. If the file changed since it was read, nothing
+// is written and the error wraps ErrConflict; open it again to pick up
+// the new content.
+func (d *Document) Save(outPath string) error {
+	if !d.Modified() {
+		return nil
+	}
+	opts := NormalizeValues(EditOptions{Set: d.set, Delete: d.del})
+	err := AuditWrite("edit", d.h, d.path, outPath, func() error {
+		return GuardedWrite(d.path, outPath, d.state, func(tmp string) error {
+			if d.parsed != nil {
+				return d.parsed.Edit(tmp, opts)
+			}
+			return d.h.Edit(d.path, tmp, opts)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	d.path = ResolveOutPath(d.path, outPath)
+	d.set, d.del = nil, nil
+	return d.load()
+}
+
+func (d *Document) pending(key string) (string, bool) {
+	for k, v := range d.set {
+		if strings.ToLower(k) == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (d *Document) deleted(key string) bool {
+	for _, k := range d.del {
+		if strings.ToLower(k) == key {
+			return true
+		}
+	}
+	return false
+}
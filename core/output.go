@@ -57,17 +57,26 @@ func (p *Printer) printText(m *Metadata) {
 				edit = " [editable]"
 			}
 			fmt.Fprintf(p.Writer, "  %-30s %s%s\n", f.Key+":", f.Value, edit)
+			if p.Verbose && f.Source != nil {
+				fmt.Fprintf(p.Writer, "  %-30s (%s)\n", "", f.Source)
+			}
 		}
 		fmt.Fprintln(p.Writer)
 	}
 }
 
 func (p *Printer) printJSON(m *Metadata) {
+	fmt.Fprintln(p.Writer, string(MetadataJSON(m)))
+}
+
+// MetadataJSON returns m as the JSON document view --json prints.
+func MetadataJSON(m *Metadata) []byte {
 	type jsonField struct {
-		Key      string `json:"key"`
-		Value    string `json:"value"`
-		Category string `json:"category"`
-		Editable bool   `json:"editable"`
+		Key      string       `json:"key"`
+		Value    string       `json:"value"`
+		Category string       `json:"category"`
+		Editable bool         `json:"editable"`
+		Source   *FieldSource `json:"source,omitempty"`
 	}
 	type jsonOutput struct {
 		FilePath string      `json:"file"`
@@ -85,11 +94,12 @@ func (p *Printer) printJSON(m *Metadata) {
 			Value:    f.Value,
 			Category: f.Category,
 			Editable: f.Editable,
+			Source:   f.Source,
 		})
 	}
 
 	b, _ := json.MarshalIndent(out, "", "  ")
-	fmt.Fprintln(p.Writer, string(b))
+	return b
 }
 
 // PrintSuccess prints a success message.
@@ -0,0 +1,41 @@
+package core
+
+// ─── Image pipelines ─────────────────────────────────────────────────────────
+// A service that resizes uploads decodes the original and encodes a new
+// file, which loses every piece of metadata, wanted or not. The usual
+// requirement is in between: thumbnails keep the photographer's credit and
+// the colour profile but not the location. A pipeline takes the
+// preservable metadata from the original before decoding, and reattaches
+// it to each derivative after encoding. Only the fields asked for are
+// carried over, so GPS and camera details never reach the derivative.
+
+// PreserveOptions selects what PreservableMetadata keeps.
+type PreserveOptions struct {
+	// Fields lists the EXIF text fields to keep (Artist, Copyright,
+	// ImageDescription, ...).
+	Fields []string
+	// ICC keeps the embedded colour profile.
+	ICC bool
+}
+
+// DefaultPreserve keeps the credit, the copyright notice and the colour
+// profile.
+var DefaultPreserve = PreserveOptions{Fields: []string{"Artist", "Copyright"}, ICC: true}
+
+// PreservedMetadata is the metadata taken from an original image for its
+// derivatives. It does not depend on the format of either: a JPEG's
+// profile and credit can be reattached to a PNG thumbnail.
+type PreservedMetadata struct {
+	Fields     map[string]string // EXIF field name → value
+	ICCProfile []byte            // raw ICC profile, nil when none
+}
+
+// MetadataPreserver is implemented by handlers that can carry metadata
+// from an original image to a re-encoded one, in memory.
+type MetadataPreserver interface {
+	// PreservableMetadata returns the metadata of data that opts keeps.
+	PreservableMetadata(data []byte, opts PreserveOptions) (*PreservedMetadata, error)
+	// ReattachMetadata returns data with p written into it, replacing
+	// the fields and profile of the same kind that data already has.
+	ReattachMetadata(data []byte, p *PreservedMetadata) ([]byte, error)
+}
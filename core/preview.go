@@ -0,0 +1,51 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ─── Strip previews ──────────────────────────────────────────────────────────
+// A dry run should show what a strip removes, field by field, and must not
+// touch the file. PreviewStrip runs the real strip into a temporary file,
+// views both, prints the difference and deletes the copy, so the preview
+// is exact for every format whatever options the strip was given.
+
+// PreviewStrip prints what h.Strip(path, "", opts) would remove or change,
+// and the bytes it would save, without writing path.
+func PreviewStrip(h Handler, path string, opts StripOptions) error {
+	tmp, err := os.CreateTemp("", "surgery-dry-*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	opts.DryRun = false
+	if err := h.Strip(path, tmp.Name(), opts); err != nil {
+		return err
+	}
+	changes := diffFields(auditFields(h, path), auditFields(h, tmp.Name()))
+	if len(changes) == 0 {
+		fmt.Printf("Dry-run: no metadata fields would be removed from %s\n", path)
+	} else {
+		fmt.Printf("Dry-run: %d field(s) would be removed or changed in %s:\n", len(changes), path)
+		for _, c := range changes {
+			switch {
+			case c.After == "":
+				fmt.Printf("  - %s: %s\n", c.Field, c.Before)
+			case c.Before == "":
+				fmt.Printf("  + %s: %s\n", c.Field, c.After)
+			default:
+				fmt.Printf("  ~ %s: %s → %s\n", c.Field, c.Before, c.After)
+			}
+		}
+	}
+	before, err1 := os.Stat(path)
+	after, err2 := os.Stat(tmp.Name())
+	if err1 == nil && err2 == nil {
+		fmt.Printf("Dry-run: size %s → %s\n", FormatSize(before.Size()), FormatSize(after.Size()))
+	}
+	return nil
+}
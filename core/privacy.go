@@ -0,0 +1,35 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrivacyAudit returns one message per piece of metadata in m that can
+// identify where a file was made or who is in it: GPS positions, the names
+// attached to face regions, and filesystem metadata such as the download
+// origin in a Zone.Identifier stream (when m has its Filesystem fields).
+func PrivacyAudit(m *Metadata) []string {
+	var findings, gps, fsAttrs []string
+	for _, f := range m.Fields {
+		switch {
+		case f.Category == fsCategory:
+			fsAttrs = append(fsAttrs, f.Key)
+		case strings.HasPrefix(f.Key, "GPS") && strings.TrimSpace(f.Value) != "":
+			gps = append(gps, f.Key)
+		case f.Key == "RegionPersons":
+			for _, name := range strings.Split(f.Value, ";") {
+				if name = strings.TrimSpace(name); name != "" {
+					findings = append(findings, fmt.Sprintf("privacy: face region names %q (strip with --regions-only)", name))
+				}
+			}
+		}
+	}
+	if len(gps) > 0 {
+		findings = append([]string{fmt.Sprintf("privacy: location in %s (strip with --gps-only)", strings.Join(gps, ", "))}, findings...)
+	}
+	if len(fsAttrs) > 0 {
+		findings = append(findings, fmt.Sprintf("privacy: filesystem metadata in %s (strip with --remove filesystem)", strings.Join(fsAttrs, ", ")))
+	}
+	return findings
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"io"
+	"os"
+)
+
+// ─── Progress ────────────────────────────────────────────────────────────────
+// Rewriting a 4 GB MP4 or a large PDF takes long enough that a GUI or a
+// server wants to show a progress bar. Handlers that rewrite whole files
+// read and write them through ReadFileProgress and WriteFileProgress,
+// which report each chunk to the Progress callback of the options. The
+// callback runs on the caller's goroutine and should return quickly.
+
+// ProgressFunc is called as an operation reads or writes a file, with the
+// stage (ProgressRead or ProgressWrite) and the bytes done out of total in
+// that stage. It is called with done == total when a stage ends.
+type ProgressFunc func(stage string, done, total int64)
+
+// Progress stages.
+const (
+	ProgressRead  = "read"
+	ProgressWrite = "write"
+)
+
+// progressChunk is how many bytes are read or written between calls.
+const progressChunk = 1 << 20
+
+// ReadFileProgress is os.ReadFile reporting progress to fn, which may be
+// nil.
+func ReadFileProgress(path string, fn ProgressFunc) ([]byte, error) {
+	if fn == nil {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	total := st.Size()
+	data := make([]byte, 0, total)
+	fn(ProgressRead, 0, total)
+	for {
+		if len(data) == cap(data) {
+			data = append(data, 0)[:len(data)] // the file grew
+		}
+		end := len(data) + progressChunk
+		if end > cap(data) {
+			end = cap(data)
+		}
+		n, err := f.Read(data[len(data):end])
+		data = data[:len(data)+n]
+		if err == io.EOF {
+			if n := int64(len(data)); n != total {
+				fn(ProgressRead, n, n)
+			}
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fn(ProgressRead, int64(len(data)), total)
+	}
+}
+
+// WriteFileProgress writes data to path with permissions 0644, as the
+// handlers do with os.WriteFile, reporting progress to fn, which may be
+// nil.
+func WriteFileProgress(path string, data []byte, fn ProgressFunc) error {
+	if fn == nil {
+		return os.WriteFile(path, data, 0644)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	total := int64(len(data))
+	fn(ProgressWrite, 0, total)
+	for done := 0; done < len(data); {
+		end := done + progressChunk
+		if end > len(data) {
+			end = len(data)
+		}
+		n, err := f.Write(data[done:end])
+		done += n
+		if err != nil {
+			f.Close()
+			return err
+		}
+		fn(ProgressWrite, int64(done), total)
+	}
+	if total == 0 {
+		fn(ProgressWrite, 0, 0)
+	}
+	return f.Close()
+}
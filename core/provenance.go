@@ -0,0 +1,35 @@
+package core
+
+import "fmt"
+
+// ─── Field provenance ────────────────────────────────────────────────────────
+// One file can say the same thing twice: EXIF and XMP both carry an author,
+// an ID3v2 tag and an ID3v1 trailer both carry a title, and nothing makes
+// them agree. A handler records with each field the physical structure it
+// was read from — a JPEG APP1 segment, a PNG chunk, a PDF object — so that
+// view --verbose and --json can show which copy holds which value.
+
+// FieldSource says where in a file a field was read from.
+type FieldSource struct {
+	Store     string `json:"store"`     // metadata store: "EXIF", "XMP", "ID3v2.4"
+	Structure string `json:"structure"` // container: "APP1", "iTXt", "obj 12"
+	Index     int    `json:"index"`     // position among the file's structures of that kind, from 0
+	Offset    int64  `json:"offset"`    // byte offset of the structure in the file
+}
+
+// String returns the source as "XMP in APP1 #1 at offset 1234".
+func (s FieldSource) String() string {
+	return fmt.Sprintf("%s in %s #%d at offset %d", s.Store, s.Structure, s.Index, s.Offset)
+}
+
+// SetSource records src as the source of the fields from index from on
+// that have none yet. Handlers note len(m.Fields) before parsing a
+// structure and call it afterwards.
+func (m *Metadata) SetSource(from int, src FieldSource) {
+	for i := from; i < len(m.Fields); i++ {
+		if m.Fields[i].Source == nil {
+			s := src
+			m.Fields[i].Source = &s
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ─── Read-only mode ──────────────────────────────────────────────────────────
+// Evidence handling needs more than "view does not write": it needs a
+// switch that makes writing impossible and a way to show afterwards that
+// the file is unchanged. Read-only mode is turned on with
+// SURGERY_READ_ONLY=1 or "read_only": true in the config file; the CLI
+// then refuses every command that can write. Every reader already opens
+// files O_RDONLY and none creates temporary files.
+
+// ReadOnlyEnabled reports whether read-only mode is on. The environment
+// variable takes precedence over the config file.
+func ReadOnlyEnabled() bool {
+	if v := strings.TrimSpace(os.Getenv("SURGERY_READ_ONLY")); v != "" {
+		switch strings.ToLower(v) {
+		case "1", "true", "yes", "on":
+			return true
+		}
+		return false
+	}
+	cfg, err := LoadConfig()
+	return err == nil && cfg.ReadOnly
+}
+
+// FileState records what --verify-untouched compares: size, modification
+// time and a SHA-256 of the contents.
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+	SHA256  string
+}
+
+// Snapshot reads path and returns its state.
+func Snapshot(path string) (FileState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return FileState{}, err
+	}
+	d := sha256.New()
+	if _, err := io.Copy(d, f); err != nil {
+		return FileState{}, err
+	}
+	return FileState{Size: st.Size(), ModTime: st.ModTime(), SHA256: hex.EncodeToString(d.Sum(nil))}, nil
+}
+
+// Equal reports whether two snapshots describe the same file contents and
+// modification time.
+func (s FileState) Equal(o FileState) bool {
+	return s.Size == o.Size && s.ModTime.Equal(o.ModTime) && s.SHA256 == o.SHA256
+}
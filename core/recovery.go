@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ─── Failed writes ───────────────────────────────────────────────────────────
+// A write can die part-way: the disk fills up, the output directory is
+// read-only, a network share goes away. Writes through a temporary file
+// leave the destination as it was, but a handler patching a file in place,
+// or a --force write straight to the output, may leave it half-written.
+// RecoverWrite compares the destination with its state before the write
+// and puts back what it can: the backup copy made before the write restores
+// the original, and an output file the write created is removed. The
+// *WriteError it returns says what state the destination was left in.
+
+// OutputState is what a failed write left at its destination.
+type OutputState int
+
+const (
+	// OutputUnchanged: the destination is as it was before the write.
+	OutputUnchanged OutputState = iota
+	// OutputRestored: the destination was changed and has been restored
+	// from the backup copy.
+	OutputRestored
+	// OutputRemoved: the write created the destination, and the partial
+	// file has been removed.
+	OutputRemoved
+	// OutputPartial: the destination was changed and could not be
+	// restored; it may be half-written.
+	OutputPartial
+	// OutputMissing: the destination existed and is gone, with no backup
+	// to restore it from.
+	OutputMissing
+)
+
+func (s OutputState) String() string {
+	switch s {
+	case OutputUnchanged:
+		return "unchanged"
+	case OutputRestored:
+		return "restored"
+	case OutputRemoved:
+		return "removed"
+	case OutputPartial:
+		return "partial"
+	case OutputMissing:
+		return "missing"
+	}
+	return fmt.Sprintf("OutputState(%d)", int(s))
+}
+
+// WriteError is the error of an edit or strip that failed, with the state
+// it left the destination in. It unwraps to the error of the write.
+type WriteError struct {
+	Op  string // "edit", "strip", …
+	Out string // the destination
+	// Backup is the copy of the destination made before the write, "" when
+	// none was made.
+	Backup string
+	State  OutputState
+	Err    error
+	// RestoreErr is why the destination could not be restored from Backup
+	// or, for OutputPartial of a new file, removed.
+	RestoreErr error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("%s failed: %v; %s", e.Op, e.Err, e.Recovery())
+}
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// Recovery describes the state of the destination in a sentence.
+func (e *WriteError) Recovery() string {
+	switch e.State {
+	case OutputUnchanged:
+		return fmt.Sprintf("%s was not changed", e.Out)
+	case OutputRestored:
+		return fmt.Sprintf("%s was restored from the backup %s", e.Out, e.Backup)
+	case OutputRemoved:
+		return fmt.Sprintf("the partly written %s was removed", e.Out)
+	case OutputMissing:
+		return fmt.Sprintf("%s is missing and there is no backup to restore it from", e.Out)
+	}
+	s := fmt.Sprintf("%s may be partly written", e.Out)
+	switch {
+	case e.Backup != "" && e.RestoreErr != nil:
+		s += fmt.Sprintf(" (restoring it failed: %v); the original is in %s", e.RestoreErr, e.Backup)
+	case e.RestoreErr != nil:
+		s += fmt.Sprintf(" (removing it failed: %v)", e.RestoreErr)
+	default:
+		s += "; write with a backup to have it restored"
+	}
+	return s
+}
+
+// RecoverWrite runs write, which saves path to out ("" for in place) as
+// op. When it fails, the destination is checked against its state before,
+// restored from backup (the copy BackupBefore made, "" for none) or removed
+// if the write created it, and the error is returned as a *WriteError.
+func RecoverWrite(op, path, out, backup string, write func() error) error {
+	dest := ResolveOutPath(path, out)
+	before, statErr := os.Stat(dest)
+	existed := statErr == nil
+
+	err := write()
+	if err == nil {
+		return nil
+	}
+	e := &WriteError{Op: op, Out: dest, Backup: backup, Err: err}
+	now, statErr := os.Stat(dest)
+	switch {
+	case !existed && statErr != nil:
+		e.State = OutputUnchanged
+	case !existed:
+		if e.RestoreErr = os.Remove(dest); e.RestoreErr == nil {
+			e.State = OutputRemoved
+		} else {
+			e.State = OutputPartial
+		}
+	case statErr == nil && os.SameFile(before, now) &&
+		before.Size() == now.Size() && before.ModTime().Equal(now.ModTime()):
+		e.State = OutputUnchanged
+	case backup != "":
+		if e.RestoreErr = restoreBackup(backup, dest); e.RestoreErr == nil {
+			e.State = OutputRestored
+		} else {
+			e.State = OutputPartial
+		}
+	case statErr != nil:
+		e.State = OutputMissing
+	default:
+		e.State = OutputPartial
+	}
+	return e
+}
+
+// restoreBackup puts the backup copy back at dest, keeping the copy.
+func restoreBackup(backup, dest string) error {
+	return replaceVia(dest, backup, func(tmp string) error {
+		in, err := os.Open(backup)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, in); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ─── Replacing files ─────────────────────────────────────────────────────────
+// Writers that mutate a file where it lies leave it half-written when they
+// fail part-way. ReplaceVia gives them a temporary file in the destination
+// directory instead and renames it over the destination only on success,
+// so the destination holds either its old content or the complete new one.
+
+// ReplaceVia writes out through a temporary file next to it: fill writes
+// the new content to tmp, and tmp replaces out only when fill succeeds.
+// out keeps its permissions, owner and extended attributes when it
+// exists; a new file gets 0644.
+func ReplaceVia(out string, fill func(tmp string) error) error {
+	return replaceVia(out, out, fill)
+}
+
+// replaceVia is ReplaceVia with the attributes taken from the file from.
+func replaceVia(out, from string, fill func(tmp string) error) error {
+	f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".surgery-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	if err := fill(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if _, err = os.Stat(from); err == nil {
+		err = CopyAttrs(from, tmp)
+	} else {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package samplegen
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Audio ───────────────────────────────────────────────────────────────────
+// A few frames of silence, enough for a player to accept the file, under
+// each format's own tags: ID3v2.3, Vorbis comments (FLAC, Ogg Vorbis,
+// Opus), iTunes ilst atoms, RIFF INFO and AIFF text chunks.
+
+func init() {
+	register(core.FmtMP3, sampleMP3)
+	register(core.FmtFLAC, sampleFLAC)
+	register(core.FmtOGG, sampleOGG)
+	register(core.FmtOpus, sampleOpus)
+	register(core.FmtM4A, sampleM4A)
+	register(core.FmtWAV, sampleWAV)
+	register(core.FmtAIFF, sampleAIFF)
+}
+
+var le = binary.LittleEndian
+
+// songFields are the view keys of the tags every tagged audio sample has.
+func songFields() map[string]string {
+	return map[string]string{"Title": Title, "Artist": Artist, "Album": Album, "Year": Year}
+}
+
+// sampleMP3 is an ID3v2.3 tag followed by four silent MPEG-1 Layer III
+// frames.
+func sampleMP3() Sample {
+	text := func(id, s string) []byte {
+		body := cat([]byte{0}, []byte(s)) // ISO-8859-1
+		return cat([]byte(id), u32(uint32(len(body))), []byte{0, 0}, body)
+	}
+	frames := cat(
+		text("TIT2", Title),
+		text("TPE1", Artist),
+		text("TALB", Album),
+		text("TYER", Year),
+	)
+	n := len(frames)
+	size := []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)} // synchsafe
+	tag := cat([]byte("ID3\x03\x00\x00"), size, frames)
+
+	// 128 kbit/s, 44.1 kHz, no padding: 144 * 128000 / 44100 = 417 bytes
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return Sample{
+		Name:   "sample.mp3",
+		Data:   cat(tag, bytes.Repeat(frame, 4)),
+		Fields: songFields(),
+	}
+}
+
+// vorbisComment returns a Vorbis comment block: vendor, then NAME=value
+// pairs, all lengths little-endian.
+func vorbisComment() []byte {
+	vendor := "samplegen"
+	comments := []string{"TITLE=" + Title, "ARTIST=" + Artist, "ALBUM=" + Album, "DATE=" + Year}
+	out := cat(le.AppendUint32(nil, uint32(len(vendor))), []byte(vendor), le.AppendUint32(nil, uint32(len(comments))))
+	for _, c := range comments {
+		out = cat(out, le.AppendUint32(nil, uint32(len(c))), []byte(c))
+	}
+	return out
+}
+
+// sampleFLAC is STREAMINFO, a Vorbis comment and one frame of silence.
+func sampleFLAC() Sample {
+	block := func(typ byte, last bool, body []byte) []byte {
+		if last {
+			typ |= 0x80
+		}
+		return cat([]byte{typ, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body)
+	}
+	// min/max block size, min/max frame size (unknown), then 44100 Hz,
+	// 1 channel, 16 bits and 192 samples packed in 64 bits, then the MD5
+	info := cat(u16(192), u16(192), make([]byte, 6),
+		be.AppendUint64(nil, 44100<<44|0<<41|15<<36|192), make([]byte, 16))
+
+	// Frame header: sync, block size 192, rates and depth from STREAMINFO,
+	// mono, frame 0; a CONSTANT subframe of 0.
+	frame := []byte{0xFF, 0xF8, 0x10, 0x00, 0x00}
+	frame = append(frame, crc8(frame))
+	frame = append(frame, 0x00, 0x00, 0x00)
+	frame = append(frame, u16(crc16(frame))...)
+
+	return Sample{
+		Name:   "sample.flac",
+		Data:   cat([]byte("fLaC"), block(0, false, info), block(4, true, vorbisComment()), frame),
+		Fields: songFields(),
+	}
+}
+
+// crc8 is the FLAC frame header checksum (polynomial x^8+x^2+x+1).
+func crc8(b []byte) byte {
+	var c byte
+	for _, v := range b {
+		c ^= v
+		for i := 0; i < 8; i++ {
+			if c&0x80 != 0 {
+				c = c<<1 ^ 0x07
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// crc16 is the FLAC frame checksum (polynomial x^16+x^15+x^2+1).
+func crc16(b []byte) uint16 {
+	var c uint16
+	for _, v := range b {
+		c ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// oggPage returns one Ogg page holding a whole packet.
+func oggPage(headerType byte, seq uint32, granule uint64, packet []byte) []byte {
+	var lacing []byte
+	n := len(packet)
+	for ; n >= 255; n -= 255 {
+		lacing = append(lacing, 255)
+	}
+	lacing = append(lacing, byte(n))
+	page := cat([]byte("OggS"), []byte{0, headerType}, le.AppendUint64(nil, granule),
+		le.AppendUint32(nil, 0x5A4D504C), // stream serial number
+		le.AppendUint32(nil, seq), make([]byte, 4), []byte{byte(len(lacing))}, lacing, packet)
+	le.PutUint32(page[22:], oggCRC(page))
+	return page
+}
+
+// oggCRC is the Ogg page checksum: CRC-32, polynomial 0x04C11DB7, not
+// reflected, computed with the checksum field zero.
+func oggCRC(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c ^= uint32(v) << 24
+		for i := 0; i < 8; i++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// sampleOGG is an Ogg Vorbis stream's identification, comment and setup
+// headers, then one audio page.
+func sampleOGG() Sample {
+	ident := cat([]byte("\x01vorbis"), make([]byte, 4), []byte{1}, le.AppendUint32(nil, 44100),
+		make([]byte, 4), le.AppendUint32(nil, 64000), make([]byte, 4),
+		[]byte{0xB8, 1}) // block sizes 256 and 2048, framing bit
+	comment := cat([]byte("\x03vorbis"), vorbisComment(), []byte{1})
+	setup := cat([]byte("\x05vorbis"), make([]byte, 8), []byte{1}) // stands in for the codebooks
+	return Sample{
+		Name: "sample.ogg",
+		Data: cat(oggPage(2, 0, 0, ident), oggPage(0, 1, 0, comment), oggPage(0, 2, 0, setup),
+			oggPage(4, 3, 256, make([]byte, 16))), // end of stream
+		Fields: songFields(),
+	}
+}
+
+// sampleOpus is an Ogg Opus stream's OpusHead and OpusTags headers, then
+// one audio page.
+func sampleOpus() Sample {
+	head := cat([]byte("OpusHead"), []byte{1, 1}, le.AppendUint16(nil, 312),
+		le.AppendUint32(nil, 48000), make([]byte, 2), []byte{0})
+	tags := cat([]byte("OpusTags"), vorbisComment())
+	return Sample{
+		Name:   "sample.opus",
+		Data:   cat(oggPage(2, 0, 0, head), oggPage(0, 1, 0, tags), oggPage(4, 2, 960, []byte{0xF8, 0xFF, 0xFE})), // a silent 20 ms frame
+		Fields: songFields(),
+	}
+}
+
+// ilstText returns an iTunes ilst item holding UTF-8 text.
+func ilstText(atom, s string) []byte {
+	return box(atom, box("data", u32(1), u32(0), []byte(s)))
+}
+
+// sampleM4A is an MPEG-4 audio file with iTunes tags in moov/udta/meta.
+func sampleM4A() Sample {
+	ilst := box("ilst",
+		ilstText("\xa9nam", Title),
+		ilstText("\xa9ART", Artist),
+		ilstText("\xa9alb", Album),
+		ilstText("\xa9day", Year),
+	)
+	meta := fullBox("meta", 0, 0,
+		fullBox("hdlr", 0, 0, make([]byte, 4), []byte("mdirappl"), make([]byte, 9)),
+		ilst)
+	return Sample{
+		Name: "sample.m4a",
+		Data: cat(
+			box("ftyp", []byte("M4A "), u32(0), []byte("M4A mp42isom")),
+			box("moov", mvhd(44100, 44100), box("udta", meta)),
+			box("mdat", make([]byte, 16)),
+		),
+		Fields: songFields(),
+	}
+}
+
+// mvhd returns a version 0 movie header.
+func mvhd(timescale, duration uint32) []byte {
+	return fullBox("mvhd", 0, 0,
+		make([]byte, 8), u32(timescale), u32(duration),
+		u32(0x00010000), u16(0x0100), make([]byte, 10), // rate, volume, reserved
+		u32(0x10000), u32(0), u32(0), u32(0), u32(0x10000), u32(0), u32(0), u32(0), u32(0x40000000), // matrix
+		make([]byte, 24), u32(2)) // pre_defined, next track ID
+}
+
+// sampleWAV is 16-bit mono PCM with a LIST INFO chunk.
+func sampleWAV() Sample {
+	info := func(id, s string) []byte { return riffChunk(id, []byte(s+"\x00")) }
+	fmtChunk := cat(le.AppendUint16(nil, 1), le.AppendUint16(nil, 1), le.AppendUint32(nil, 8000),
+		le.AppendUint32(nil, 16000), le.AppendUint16(nil, 2), le.AppendUint16(nil, 16))
+	body := cat([]byte("WAVE"),
+		riffChunk("fmt ", fmtChunk),
+		riffChunk("LIST", []byte("INFO"),
+			info("INAM", Title), info("IART", Artist), info("ICOP", Copyright), info("ICRD", Year)),
+		riffChunk("data", make([]byte, 32)),
+	)
+	return Sample{
+		Name:   "sample.wav",
+		Data:   cat([]byte("RIFF"), le.AppendUint32(nil, uint32(len(body))), body),
+		Fields: map[string]string{"Title": Title, "Artist": Artist, "Copyright": Copyright, "SampleRate": "8000 Hz"},
+	}
+}
+
+// sampleAIFF is 16-bit mono PCM with NAME, AUTH and (c) text chunks.
+func sampleAIFF() Sample {
+	chunk := func(id string, body []byte) []byte {
+		out := cat([]byte(id), u32(uint32(len(body))), body)
+		if len(body)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	// channels, sample frames, bits, and 8000 Hz as an 80-bit extended float
+	comm := cat(u16(1), u32(16), u16(16), []byte{0x40, 0x0B, 0xFA, 0, 0, 0, 0, 0, 0, 0})
+	body := cat([]byte("AIFF"),
+		chunk("COMM", comm),
+		chunk("NAME", []byte(Title)),
+		chunk("AUTH", []byte(Artist)),
+		chunk("(c) ", []byte(Copyright)),
+		chunk("SSND", make([]byte, 8+32)), // offset, block size, samples
+	)
+	return Sample{
+		Name:   "sample.aiff",
+		Data:   cat([]byte("FORM"), u32(uint32(len(body))), body),
+		Fields: map[string]string{"Title": Title, "Author": Artist, "Copyright": Copyright},
+	}
+}
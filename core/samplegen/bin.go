@@ -0,0 +1,180 @@
+package samplegen
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"time"
+)
+
+// ─── Building blocks ─────────────────────────────────────────────────────────
+// Helpers shared by the generators: ISOBMFF boxes, RIFF chunks, TIFF blocks
+// (for EXIF as well as TIFF files) and ZIP containers with fixed times.
+
+var be = binary.BigEndian
+
+func u16(v uint16) []byte { return be.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return be.AppendUint32(nil, v) }
+
+func cat(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+// box returns an ISOBMFF box (MP4 atom).
+func box(typ string, parts ...[]byte) []byte {
+	body := cat(parts...)
+	return cat(u32(uint32(8+len(body))), []byte(typ), body)
+}
+
+// fullBox returns a box with a version and flags.
+func fullBox(typ string, version byte, flags uint32, parts ...[]byte) []byte {
+	vf := u32(flags)
+	vf[0] = version
+	return box(typ, append([][]byte{vf}, parts...)...)
+}
+
+// riffChunk returns a little-endian RIFF chunk, padded to an even length.
+func riffChunk(id string, parts ...[]byte) []byte {
+	body := cat(parts...)
+	out := cat([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body))), body)
+	if len(body)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// ─── TIFF ────────────────────────────────────────────────────────────────────
+
+// TIFF field types.
+const (
+	tiffASCII    = 2
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+)
+
+var tiffTypeSize = map[uint16]int{1: 1, tiffASCII: 1, tiffShort: 2, tiffLong: 4, tiffRational: 8}
+
+type tiffTag struct {
+	id, typ uint16
+	data    []byte // big-endian value
+}
+
+func ascii(id uint16, s string) tiffTag { return tiffTag{id, tiffASCII, []byte(s + "\x00")} }
+func short(id uint16, v uint16) tiffTag { return tiffTag{id, tiffShort, u16(v)} }
+func long(id uint16, v uint32) tiffTag  { return tiffTag{id, tiffLong, u32(v)} }
+
+// rationals returns tag id as RATIONALs, each given as numerator and
+// denominator.
+func rationals(id uint16, nd ...uint32) tiffTag {
+	var b []byte
+	for _, v := range nd {
+		b = append(b, u32(v)...)
+	}
+	return tiffTag{id, tiffRational, b}
+}
+
+// cameraTags are the IFD0 tags of the image samples.
+func cameraTags() []tiffTag {
+	return []tiffTag{
+		ascii(0x010F, Make),
+		ascii(0x0110, Model),
+		ascii(0x013B, Artist),
+		ascii(0x8298, Copyright),
+	}
+}
+
+// gpsTags place the samples at 48.8584 N, 2.2945 E.
+func gpsTags() []tiffTag {
+	return []tiffTag{
+		{0x0000, 1, []byte{2, 3, 0, 0}}, // GPSVersionID, BYTE
+		ascii(0x0001, "N"),
+		rationals(0x0002, 48, 1, 51, 1, 3024, 100),
+		ascii(0x0003, "E"),
+		rationals(0x0004, 2, 1, 17, 1, 4020, 100),
+	}
+}
+
+// buildTIFF returns a big-endian TIFF block with ifd0, a GPS IFD when gps
+// is not empty, and pixels as a single strip when not nil.
+func buildTIFF(ifd0, gps []tiffTag, pixels []byte) []byte {
+	ifd0 = append([]tiffTag{}, ifd0...)
+	if len(gps) > 0 {
+		ifd0 = append(ifd0, long(0x8825, 0)) // GPSInfo, set below
+	}
+	if pixels != nil {
+		ifd0 = append(ifd0, long(0x0111, 0), long(0x0117, uint32(len(pixels)))) // StripOffsets, StripByteCounts
+	}
+	out := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	fields := writeIFD(&out, ifd0)
+	if len(gps) > 0 {
+		be.PutUint32(out[fields[0x8825]:], uint32(len(out)))
+		writeIFD(&out, gps)
+	}
+	if pixels != nil {
+		be.PutUint32(out[fields[0x0111]:], uint32(len(out)))
+		out = append(out, pixels...)
+	}
+	return out
+}
+
+// writeIFD appends an IFD of tags, sorted, followed by their out-of-line
+// values, and returns where the value field of each tag's entry is.
+func writeIFD(out *[]byte, tags []tiffTag) map[uint16]int {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].id < tags[j].id })
+	start := len(*out)
+	values := start + 2 + 12*len(tags) + 4
+	fields := make(map[uint16]int, len(tags))
+	ifd := u16(uint16(len(tags)))
+	var extra []byte
+	for i, t := range tags {
+		ifd = append(ifd, u16(t.id)...)
+		ifd = append(ifd, u16(t.typ)...)
+		ifd = append(ifd, u32(uint32(len(t.data)/tiffTypeSize[t.typ]))...)
+		fields[t.id] = start + 2 + 12*i + 8
+		if len(t.data) <= 4 {
+			ifd = append(ifd, append(t.data, make([]byte, 4-len(t.data))...)...)
+			continue
+		}
+		ifd = append(ifd, u32(uint32(values+len(extra)))...)
+		extra = append(extra, t.data...)
+		if len(extra)%2 == 1 {
+			extra = append(extra, 0)
+		}
+	}
+	ifd = append(ifd, 0, 0, 0, 0) // no next IFD
+	*out = append(append(*out, ifd...), extra...)
+	return fields
+}
+
+// ─── ZIP ─────────────────────────────────────────────────────────────────────
+
+// zipEntry is one file of a ZIP container.
+type zipEntry struct {
+	name, body string
+}
+
+// zipModified is the time stamped on every entry, so that the output does
+// not change from run to run.
+var zipModified = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// buildZip returns a ZIP of entries in order. A first entry named
+// "mimetype" is stored uncompressed, as ODF and EPUB require.
+func buildZip(entries ...zipEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: zipModified}
+		if i == 0 && e.name == "mimetype" {
+			h.Method = zip.Store
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			panic(err) // writing to memory does not fail
+		}
+		w.Write([]byte(e.body))
+	}
+	if err := zw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
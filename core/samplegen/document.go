@@ -0,0 +1,218 @@
+package samplegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Documents ───────────────────────────────────────────────────────────────
+// One page, sheet or slide with no real content: a PDF with an Info
+// dictionary and a valid cross-reference table, and the ZIP containers with
+// the parts that carry metadata (docProps/core.xml, meta.xml, the EPUB
+// package document, ComicInfo.xml).
+
+func init() {
+	register(core.FmtPDF, samplePDF)
+	register(core.FmtDOCX, func() Sample { return sampleOPC(core.FmtDOCX) })
+	register(core.FmtXLSX, func() Sample { return sampleOPC(core.FmtXLSX) })
+	register(core.FmtPPTX, func() Sample { return sampleOPC(core.FmtPPTX) })
+	register(core.FmtODT, sampleODT)
+	register(core.FmtEPUB, sampleEPUB)
+	register(core.FmtCBZ, sampleCBZ)
+}
+
+// Created is when the document samples were written.
+const Created = "2024-01-01T12:00:00Z"
+
+// samplePDF is a one-page PDF 1.4 with an Info dictionary.
+func samplePDF() Sample {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] >>",
+		"<< /Title (" + Title + ") /Author (" + Artist + ") /Subject (" + Comment + ") " +
+			"/Producer (samplegen) /CreationDate (D:20240101120000Z) >>",
+	}
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return Sample{
+		Name: "sample.pdf",
+		Data: []byte(b.String()),
+		Fields: map[string]string{
+			"Title":      Title,
+			"Author":     Artist,
+			"Subject":    Comment,
+			"Producer":   "samplegen",
+			"PDFVersion": "1.4",
+		},
+	}
+}
+
+// opcCore is docProps/core.xml of the Office samples.
+var opcCore = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+	`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+	`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+	`<dc:title>` + Title + `</dc:title><dc:creator>` + Artist + `</dc:creator>` +
+	`<cp:lastModifiedBy>` + Artist + `</cp:lastModifiedBy>` +
+	`<dcterms:created xsi:type="dcterms:W3CDTF">` + Created + `</dcterms:created>` +
+	`</cp:coreProperties>`
+
+// opcMain describes the main part of each Office format.
+var opcMain = map[core.FormatID]struct {
+	name, contentType, body string
+}{
+	core.FmtDOCX: {"sample.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p/></w:body></w:document>`},
+	core.FmtXLSX: {"sample.xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml",
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheets/></workbook>`},
+	core.FmtPPTX: {"sample.pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml",
+		`<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"/>`},
+}
+
+// opcMainPart is where each Office format keeps its main part.
+var opcMainPart = map[core.FormatID]string{
+	core.FmtDOCX: "word/document.xml",
+	core.FmtXLSX: "xl/workbook.xml",
+	core.FmtPPTX: "ppt/presentation.xml",
+}
+
+// sampleOPC is a DOCX, XLSX or PPTX package with core properties.
+func sampleOPC(id core.FormatID) Sample {
+	main, part := opcMain[id], opcMainPart[id]
+	types := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/` + part + `" ContentType="` + main.contentType + `"/>` +
+		`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		`</Types>`
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="` + part + `"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+		`</Relationships>`
+	return Sample{
+		Name: main.name,
+		Data: buildZip(
+			zipEntry{"[Content_Types].xml", types},
+			zipEntry{"_rels/.rels", rels},
+			zipEntry{"docProps/core.xml", opcCore},
+			zipEntry{part, main.body},
+		),
+		Fields: map[string]string{
+			"Title":          Title,
+			"Author":         Artist,
+			"LastModifiedBy": Artist,
+			"Created":        Created,
+		},
+	}
+}
+
+// sampleODT is an OpenDocument text with meta.xml.
+func sampleODT() Sample {
+	meta := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/" office:version="1.3">` +
+		`<office:meta><dc:title>` + Title + `</dc:title><meta:initial-creator>` + Artist + `</meta:initial-creator>` +
+		`<dc:creator>` + Artist + `</dc:creator><meta:creation-date>` + Created + `</meta:creation-date>` +
+		`<meta:generator>samplegen</meta:generator></office:meta></office:document-meta>`
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.3">` +
+		`<office:body><office:text><text:p/></office:text></office:body></office:document-content>`
+	manifest := `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.3">` +
+		`<manifest:file-entry manifest:full-path="/" manifest:media-type="application/vnd.oasis.opendocument.text"/>` +
+		`<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>` +
+		`<manifest:file-entry manifest:full-path="meta.xml" manifest:media-type="text/xml"/>` +
+		`</manifest:manifest>`
+	return Sample{
+		Name: "sample.odt",
+		Data: buildZip(
+			zipEntry{"mimetype", "application/vnd.oasis.opendocument.text"},
+			zipEntry{"META-INF/manifest.xml", manifest},
+			zipEntry{"meta.xml", meta},
+			zipEntry{"content.xml", content},
+		),
+		Fields: map[string]string{
+			"title":           Title,
+			"initial-creator": Artist,
+			"creator":         Artist,
+			"generator":       "samplegen",
+		},
+	}
+}
+
+// sampleEPUB is an EPUB 3 book of one chapter.
+func sampleEPUB() Sample {
+	container := `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+		`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`
+	opf := `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid">` +
+		`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<dc:identifier id="uid">urn:uuid:00000000-0000-4000-8000-000000000000</dc:identifier>` +
+		`<dc:title>` + Title + `</dc:title><dc:creator>` + Artist + `</dc:creator>` +
+		`<dc:language>en</dc:language><dc:rights>` + Copyright + `</dc:rights>` +
+		`<meta property="dcterms:modified">` + Created + `</meta></metadata>` +
+		`<manifest><item id="c1" href="chapter1.xhtml" media-type="application/xhtml+xml"/>` +
+		`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/></manifest>` +
+		`<spine><itemref idref="c1"/></spine></package>`
+	xhtml := func(body string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><head><title>` + Title +
+			`</title></head><body>` + body + `</body></html>`
+	}
+	return Sample{
+		Name: "sample.epub",
+		Data: buildZip(
+			zipEntry{"mimetype", "application/epub+zip"},
+			zipEntry{"META-INF/container.xml", container},
+			zipEntry{"OEBPS/content.opf", opf},
+			zipEntry{"OEBPS/nav.xhtml", xhtml(`<nav epub:type="toc"><ol><li><a href="chapter1.xhtml">One</a></li></ol></nav>`)},
+			zipEntry{"OEBPS/chapter1.xhtml", xhtml(`<p/>`)},
+		),
+		Fields: map[string]string{
+			"Title":    Title,
+			"Author":   Artist,
+			"Language": "en",
+			"Rights":   Copyright,
+		},
+	}
+}
+
+// sampleCBZ is a comic of one page with ComicInfo.xml.
+func sampleCBZ() Sample {
+	info := `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<Title>` + Title + `</Title><Series>Samples</Series><Number>1</Number><Year>` + Year + `</Year>` +
+		`<Writer>` + Artist + `</Writer><PageCount>1</PageCount></ComicInfo>`
+	page := samplePNG().Data
+	return Sample{
+		Name: "sample.cbz",
+		Data: buildZip(
+			zipEntry{"ComicInfo.xml", info},
+			zipEntry{"page001.png", string(page)},
+		),
+		Fields: map[string]string{
+			"Title":      Title,
+			"Series":     "Samples",
+			"Writer":     Artist,
+			"ImageCount": "1",
+		},
+	}
+}
@@ -0,0 +1,265 @@
+package samplegen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Images ──────────────────────────────────────────────────────────────────
+// JPEG, PNG and GIF pixels come from the standard library encoders; the
+// metadata is spliced in by hand. The other formats are built whole.
+
+func init() {
+	register(core.FmtJPEG, sampleJPEG)
+	register(core.FmtPNG, samplePNG)
+	register(core.FmtGIF, sampleGIF)
+	register(core.FmtWebP, sampleWebP)
+	register(core.FmtTIFF, sampleTIFF)
+	register(core.FmtBMP, sampleBMP)
+	register(core.FmtHEIC, sampleHEIC)
+	register(core.FmtSVG, sampleSVG)
+}
+
+// cameraFields are the view keys of cameraTags and gpsTags.
+func cameraFields() map[string]string {
+	return map[string]string{
+		"Make":         Make,
+		"Model":        Model,
+		"Artist":       Artist,
+		"Copyright":    Copyright,
+		"GPSLatitude":  "48.8584",
+		"GPSLongitude": "2.2945",
+	}
+}
+
+// pixel returns an 8×8 grey image.
+func pixel() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	return img
+}
+
+// sampleJPEG has EXIF with camera and GPS tags and an XMP title.
+func sampleJPEG() Sample {
+	var enc bytes.Buffer
+	jpeg.Encode(&enc, pixel(), &jpeg.Options{Quality: 90})
+	raw := enc.Bytes()
+	segment := func(marker byte, body []byte) []byte {
+		return cat([]byte{0xFF, marker}, u16(uint16(len(body)+2)), body)
+	}
+	exif := cat([]byte("Exif\x00\x00"), buildTIFF(cameraTags(), gpsTags(), nil))
+	xmp := cat([]byte("http://ns.adobe.com/xap/1.0/\x00"), xmpPacket())
+	fields := cameraFields()
+	fields["xmp:dc:title"] = Title
+	return Sample{
+		Name:   "sample.jpg",
+		Data:   cat(raw[:2], segment(0xE1, exif), segment(0xE1, xmp), raw[2:]),
+		Fields: fields,
+	}
+}
+
+// xmpPacket is an XMP packet with a title and creator.
+func xmpPacket() []byte {
+	return []byte("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>" +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + Title + `</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:creator><rdf:Seq><rdf:li>` + Artist + `</rdf:li></rdf:Seq></dc:creator>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta><?xpacket end="w"?>`)
+}
+
+// samplePNG has tEXt chunks and an eXIf chunk.
+func samplePNG() Sample {
+	var enc bytes.Buffer
+	png.Encode(&enc, pixel())
+	raw := enc.Bytes()
+	chunk := func(typ string, body []byte) []byte {
+		crc := crc32.ChecksumIEEE(cat([]byte(typ), body))
+		return cat(u32(uint32(len(body))), []byte(typ), body, u32(crc))
+	}
+	ihdrEnd := 8 + 12 + 13
+	meta := cat(
+		chunk("tEXt", []byte("Title\x00"+Title)),
+		chunk("tEXt", []byte("Author\x00"+Artist)),
+		chunk("tEXt", []byte("Copyright\x00"+Copyright)),
+		chunk("eXIf", buildTIFF(cameraTags()[:2], gpsTags(), nil)),
+	)
+	fields := map[string]string{
+		"Title":        Title,
+		"Author":       Artist,
+		"Copyright":    Copyright,
+		"Make":         Make,
+		"Model":        Model,
+		"GPSLatitude":  "48.8584",
+		"GPSLongitude": "2.2945",
+	}
+	return Sample{
+		Name:   "sample.png",
+		Data:   cat(raw[:ihdrEnd], meta, raw[ihdrEnd:]),
+		Fields: fields,
+	}
+}
+
+// sampleGIF has a comment extension.
+func sampleGIF() Sample {
+	img := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+	var enc bytes.Buffer
+	gif.Encode(&enc, img, nil)
+	raw := enc.Bytes()
+	comment := cat([]byte{0x21, 0xFE, byte(len(Comment))}, []byte(Comment), []byte{0})
+	end := len(raw) - 1 // the trailer
+	return Sample{
+		Name:   "sample.gif",
+		Data:   cat(raw[:end], comment, raw[end:]),
+		Fields: map[string]string{"Comment_1": Comment, "Dimensions": "8 x 8"},
+	}
+}
+
+// vp8l is a 1×1 lossless WebP bitstream.
+var vp8l = []byte{0x2f, 0x00, 0x00, 0x00, 0x10, 0x07, 0x10, 0x11, 0x11, 0x88, 0x88, 0xfe, 0x07}
+
+// sampleWebP is an extended WebP with EXIF and XMP chunks.
+func sampleWebP() Sample {
+	vp8x := make([]byte, 10) // flags, reserved, canvas width-1 and height-1 (24 bits each)
+	vp8x[0] = 0x08 | 0x04    // EXIF, XMP
+	body := cat([]byte("WEBP"),
+		riffChunk("VP8X", vp8x),
+		riffChunk("VP8L", vp8l),
+		riffChunk("EXIF", buildTIFF(cameraTags(), gpsTags(), nil)),
+		riffChunk("XMP ", xmpPacket()),
+	)
+	fields := cameraFields()
+	fields["xmp:dc:title"] = Title
+	return Sample{
+		Name:   "sample.webp",
+		Data:   cat([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body))), body),
+		Fields: fields,
+	}
+}
+
+// sampleTIFF is a 1×1 greyscale TIFF with text, camera and GPS tags.
+func sampleTIFF() Sample {
+	tags := append(cameraTags(),
+		long(0x0100, 1),  // ImageWidth
+		long(0x0101, 1),  // ImageLength
+		short(0x0102, 8), // BitsPerSample
+		short(0x0103, 1), // Compression: none
+		short(0x0106, 1), // PhotometricInterpretation: black is zero
+		long(0x0116, 1),  // RowsPerStrip
+		ascii(0x010E, Comment),
+	)
+	fields := cameraFields()
+	fields["ImageDescription"] = Comment
+	return Sample{
+		Name:   "sample.tif",
+		Data:   buildTIFF(tags, gpsTags(), []byte{0x80}),
+		Fields: fields,
+	}
+}
+
+// sampleBMP is a 1×1 24-bit BMP; the format has no metadata but its header.
+func sampleBMP() Sample {
+	le := binary.LittleEndian
+	b := make([]byte, 54+4)
+	copy(b, "BM")
+	le.PutUint32(b[2:], uint32(len(b)))
+	le.PutUint32(b[10:], 54)
+	le.PutUint32(b[14:], 40) // BITMAPINFOHEADER
+	le.PutUint32(b[18:], 1)
+	le.PutUint32(b[22:], 1)
+	le.PutUint16(b[26:], 1) // planes
+	le.PutUint16(b[28:], 24)
+	le.PutUint32(b[34:], 4) // image size: one row padded to 4 bytes
+	copy(b[54:], []byte{0x80, 0x80, 0x80, 0})
+	return Sample{
+		Name:   "sample.bmp",
+		Data:   b,
+		Fields: map[string]string{"Width": "1 px", "Height": "1 px", "BitsPerPixel": "24"},
+	}
+}
+
+// sampleHEIC has a primary image item, an Exif item and an XMP item, each
+// described by the image, all stored in mdat.
+func sampleHEIC() Sample {
+	type item struct {
+		id      uint16
+		typ     string
+		content string // content type of mime items
+		data    []byte
+	}
+	items := []item{
+		{1, "hvc1", "", bytes.Repeat([]byte("HEVCDATA"), 8)},
+		{2, "Exif", "", cat(u32(6), []byte("Exif\x00\x00"), buildTIFF(cameraTags(), gpsTags(), nil))},
+		{3, "mime", "application/rdf+xml", xmpPacket()},
+	}
+	var infes []byte
+	for _, it := range items {
+		body := cat(u16(it.id), u16(0), []byte(it.typ+"\x00"))
+		if it.content != "" {
+			body = cat(body, []byte(it.content+"\x00"))
+		}
+		infes = append(infes, fullBox("infe", 2, 0, body)...)
+	}
+	iloc := func(mdatData int) []byte {
+		// offset_size 4, length_size 4, base_offset_size 0, construction method 0
+		body := cat([]byte{0x44, 0x00}, u16(uint16(len(items))))
+		off := mdatData
+		for _, it := range items {
+			body = cat(body, u16(it.id), u16(0), u16(0), u16(1), u32(uint32(off)), u32(uint32(len(it.data))))
+			off += len(it.data)
+		}
+		return fullBox("iloc", 1, 0, body)
+	}
+	meta := func(mdatData int) []byte {
+		return fullBox("meta", 0, 0,
+			fullBox("hdlr", 0, 0, make([]byte, 4), []byte("pict"), make([]byte, 13)),
+			fullBox("pitm", 0, 0, u16(1)),
+			iloc(mdatData),
+			fullBox("iinf", 0, 0, u16(uint16(len(items))), infes),
+			fullBox("iref", 0, 0,
+				box("cdsc", u16(2), u16(1), u16(1)),
+				box("cdsc", u16(3), u16(1), u16(1))),
+			box("iprp",
+				box("ipco", fullBox("ispe", 0, 0, u32(8), u32(8))),
+				fullBox("ipma", 0, 0, u32(1), u16(1), []byte{1, 0x81})),
+		)
+	}
+	ftyp := box("ftyp", []byte("heic"), u32(0), []byte("mif1heic"))
+	base := len(ftyp) + len(meta(0)) + 8
+	var mdat []byte
+	for _, it := range items {
+		mdat = append(mdat, it.data...)
+	}
+	fields := cameraFields()
+	fields["xmp:dc:title"] = Title
+	return Sample{
+		Name:   "sample.heic",
+		Data:   cat(ftyp, meta(base), box("mdat", mdat)),
+		Fields: fields,
+	}
+}
+
+func sampleSVG() Sample {
+	svg := `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="8" height="8" viewBox="0 0 8 8">
+  <title>` + Title + `</title>
+  <desc>` + Comment + `</desc>
+  <rect width="8" height="8" fill="#808080"/>
+</svg>
+`
+	return Sample{
+		Name:   "sample.svg",
+		Data:   []byte(svg),
+		Fields: map[string]string{"Title": Title, "Description": Comment, "Width": "8"},
+	}
+}
@@ -0,0 +1,75 @@
+// Package samplegen synthesises small, valid files of every format surgery
+// supports, each carrying known metadata. The bytes are built here from
+// the format specifications, not by surgery's own writers, so a sample
+// checks the readers independently; and they are the same on every run,
+// so tests and demos need no binary fixtures.
+//
+//	for _, s := range samplegen.All() {
+//		os.WriteFile(s.Name, s.Data, 0644)
+//	}
+package samplegen
+
+import (
+	"sort"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Sample is one synthesised file.
+type Sample struct {
+	Name   string        // file name, e.g. "sample.jpg"
+	Format core.FormatID // the format surgery should detect
+	Data   []byte
+	// Fields are metadata the file carries, keyed as view reports them.
+	Fields map[string]string
+}
+
+// Known metadata written into the samples, wherever the format has a field
+// for it.
+const (
+	Title     = "Sample Title"
+	Artist    = "Jane Doe"
+	Album     = "Sample Album"
+	Year      = "2024"
+	Comment   = "Made by samplegen"
+	Copyright = "(c) 2024 Jane Doe"
+	Make      = "Surgery"
+	Model     = "Sample One"
+)
+
+// generators builds each format's sample.
+var generators = map[core.FormatID]func() Sample{}
+
+func register(id core.FormatID, fn func() Sample) { generators[id] = fn }
+
+// For returns the sample of format id, and false when there is no
+// generator for it.
+func For(id core.FormatID) (Sample, bool) {
+	fn, ok := generators[id]
+	if !ok {
+		return Sample{}, false
+	}
+	s := fn()
+	s.Format = id
+	return s, true
+}
+
+// Formats lists the formats with a generator, in name order.
+func Formats() []core.FormatID {
+	ids := make([]core.FormatID, 0, len(generators))
+	for id := range generators {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// All returns a sample of every format, in name order.
+func All() []Sample {
+	var out []Sample
+	for _, id := range Formats() {
+		s, _ := For(id)
+		out = append(out, s)
+	}
+	return out
+}
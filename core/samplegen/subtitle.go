@@ -0,0 +1,72 @@
+package samplegen
+
+import "github.com/ankit-chaubey/media-metadata-surgery/core"
+
+// ─── Subtitles ───────────────────────────────────────────────────────────────
+// Two cues each. SRT has no metadata; ASS carries [Script Info] credits and
+// WebVTT a header title, header lines and a NOTE block.
+
+func init() {
+	register(core.FmtSRT, sampleSRT)
+	register(core.FmtASS, sampleASS)
+	register(core.FmtVTT, sampleVTT)
+}
+
+func sampleSRT() Sample {
+	return Sample{
+		Name: "sample.srt",
+		Data: []byte("1\r\n00:00:01,000 --> 00:00:02,000\r\nHello.\r\n\r\n" +
+			"2\r\n00:00:03,000 --> 00:00:04,000\r\nGoodbye.\r\n"),
+		Fields: map[string]string{"CueCount": "2"},
+	}
+}
+
+func sampleASS() Sample {
+	data := "[Script Info]\n" +
+		"; " + Comment + "\n" +
+		"Title: " + Title + "\n" +
+		"Original Script: " + Artist + "\n" +
+		"ScriptType: v4.00+\n" +
+		"PlayResX: 1920\n" +
+		"PlayResY: 1080\n" +
+		"\n[V4+ Styles]\n" +
+		"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, " +
+		"Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, " +
+		"Alignment, MarginL, MarginR, MarginV, Encoding\n" +
+		"Style: Default,Arial,48,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,0,2,10,10,10,1\n" +
+		"\n[Events]\n" +
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hello.\n" +
+		"Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,Goodbye.\n"
+	return Sample{
+		Name: "sample.ass",
+		Data: []byte(data),
+		Fields: map[string]string{
+			"Title":           Title,
+			"Original Script": Artist,
+			"Comment":         Comment,
+			"PlayResX":        "1920",
+			"DialogueLines":   "2",
+		},
+	}
+}
+
+func sampleVTT() Sample {
+	data := "WEBVTT - " + Title + "\n" +
+		"Kind: captions\n" +
+		"Language: en\n" +
+		"\nNOTE " + Comment + "\n" +
+		"\n00:00:01.000 --> 00:00:02.000\nHello.\n" +
+		"\n00:00:03.000 --> 00:00:04.000\nGoodbye.\n"
+	return Sample{
+		Name: "sample.vtt",
+		Data: []byte(data),
+		Fields: map[string]string{
+			"Title":    Title,
+			"Kind":     "captions",
+			"Language": "en",
+			"Note":     Comment,
+			"CueCount": "2",
+		},
+	}
+}
@@ -0,0 +1,276 @@
+package samplegen
+
+import (
+	"math"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Video ───────────────────────────────────────────────────────────────────
+// Container structure with a video and an audio track and placeholder
+// media data: MP4 with iTunes tags and a location, MOV with QuickTime mdta
+// keys, Matroska and WebM with Info and Tags, and the RIFF, ASF and FLV
+// metadata of AVI, WMV and FLV.
+
+func init() {
+	register(core.FmtMP4, sampleMP4)
+	register(core.FmtMOV, sampleMOV)
+	register(core.FmtMKV, func() Sample { return sampleMatroska("matroska") })
+	register(core.FmtWebM, func() Sample { return sampleMatroska("webm") })
+	register(core.FmtAVI, sampleAVI)
+	register(core.FmtWMV, sampleWMV)
+	register(core.FmtFLV, sampleFLV)
+}
+
+// Location is where the video samples were shot, as ISO 6709.
+const Location = "+48.8584+002.2945/"
+
+// mediaData is the content of every video sample's mdat, one chunk per
+// track.
+var mediaData = []byte("VIDEODATAAUDIODATA")
+
+// isoTrack returns a trak whose single chunk is at offset chunk.
+func isoTrack(id uint32, handler, codec, lang string, chunk uint32) []byte {
+	var w, h uint32
+	if handler == "vide" {
+		w, h = 1920<<16, 1080<<16
+	}
+	tkhd := fullBox("tkhd", 0, 3,
+		make([]byte, 8), u32(id), make([]byte, 4), u32(1000), make([]byte, 8+8),
+		u32(0x10000), u32(0), u32(0), u32(0), u32(0x10000), u32(0), u32(0), u32(0), u32(0x40000000), // matrix
+		u32(w), u32(h))
+	l := []byte(lang)
+	mdhd := fullBox("mdhd", 0, 0, make([]byte, 8), u32(1000), u32(1000),
+		u16(uint16(l[0]-0x60)<<10|uint16(l[1]-0x60)<<5|uint16(l[2]-0x60)), u16(0))
+	hdlr := fullBox("hdlr", 0, 0, make([]byte, 4), []byte(handler), make([]byte, 12), []byte("samplegen\x00"))
+	stbl := box("stbl",
+		fullBox("stsd", 0, 0, u32(1), box(codec, make([]byte, 8))),
+		fullBox("stco", 0, 0, u32(1), u32(chunk)))
+	return box("trak", tkhd, box("mdia", mdhd, hdlr, box("minf", stbl)))
+}
+
+// isoMovie returns ftyp, moov holding the two tracks and extra, and mdat,
+// with the chunk offsets pointing into mdat.
+func isoMovie(brand string, extra ...[]byte) []byte {
+	ftyp := box("ftyp", []byte(brand), u32(0), []byte(brand+"isom"))
+	moov := func(mdat uint32) []byte {
+		return box("moov", append([][]byte{
+			mvhd(1000, 1000),
+			isoTrack(1, "vide", "avc1", "eng", mdat),
+			isoTrack(2, "soun", "mp4a", "eng", mdat+9),
+		}, extra...)...)
+	}
+	data := uint32(len(ftyp) + len(moov(0)) + 8)
+	return cat(ftyp, moov(data), box("mdat", mediaData))
+}
+
+// trackFields are the view keys of the tracks of isoMovie.
+func trackFields(fields map[string]string) map[string]string {
+	fields["Track1.Type"] = "video"
+	fields["Track1.Codec"] = "avc1"
+	fields["Track2.Type"] = "audio"
+	fields["Track2.Language"] = "eng"
+	return fields
+}
+
+// sampleMP4 has iTunes tags and a ©xyz location in moov/udta.
+func sampleMP4() Sample {
+	meta := fullBox("meta", 0, 0,
+		fullBox("hdlr", 0, 0, make([]byte, 4), []byte("mdirappl"), make([]byte, 9)),
+		box("ilst",
+			ilstText("\xa9nam", Title),
+			ilstText("\xa9ART", Artist),
+			ilstText("\xa9day", Year),
+		))
+	xyz := box("\xa9xyz", u16(uint16(len(Location))), u16(0x15C7), []byte(Location))
+	return Sample{
+		Name: "sample.mp4",
+		Data: isoMovie("isom", box("udta", xyz, meta)),
+		Fields: trackFields(map[string]string{
+			"Title":    Title,
+			"Artist":   Artist,
+			"Location": Location,
+		}),
+	}
+}
+
+// sampleMOV has QuickTime mdta keys, as iPhones write them.
+func sampleMOV() Sample {
+	keys := []struct{ key, val string }{
+		{"com.apple.quicktime.make", Make},
+		{"com.apple.quicktime.model", Model},
+		{"com.apple.quicktime.location.ISO6709", Location},
+		{"com.apple.quicktime.creationdate", Year + "-01-01T12:00:00+0000"},
+	}
+	var keysBody, ilst []byte
+	keysBody = cat(u32(0), u32(uint32(len(keys))))
+	fields := map[string]string{}
+	for i, k := range keys {
+		keysBody = cat(keysBody, u32(uint32(8+len(k.key))), []byte("mdta"), []byte(k.key))
+		ilst = cat(ilst, box(string(u32(uint32(i+1))), box("data", u32(1), u32(0), []byte(k.val))))
+		fields[k.key] = k.val
+	}
+	// QuickTime's meta is a plain box, not a full box.
+	meta := box("meta",
+		fullBox("hdlr", 0, 0, make([]byte, 4), []byte("mdta"), make([]byte, 13)),
+		box("keys", keysBody),
+		box("ilst", ilst))
+	return Sample{
+		Name:   "sample.mov",
+		Data:   isoMovie("qt  ", meta),
+		Fields: trackFields(fields),
+	}
+}
+
+// ebml returns an EBML element; the id is given with its marker bits.
+func ebml(id uint32, parts ...[]byte) []byte {
+	body := cat(parts...)
+	var idb []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(idb) > 0 {
+			idb = append(idb, b)
+		}
+	}
+	// One byte for small elements, as muxers write them (and as the WebM
+	// sniffer expects of DocType), else eight.
+	size := []byte{0x80 | byte(len(body))}
+	if len(body) >= 0x7F {
+		size = be.AppendUint64(nil, uint64(len(body)))
+		size[0] = 0x01
+	}
+	return cat(idb, size, body)
+}
+
+// ebmlUint returns an unsigned integer element of one byte, all a sample needs.
+func ebmlUint(id uint32, v uint8) []byte  { return ebml(id, []byte{v}) }
+func ebmlText(id uint32, s string) []byte { return ebml(id, []byte(s)) }
+
+// sampleMatroska is a Matroska or WebM file with a title, tags and a video
+// and an audio track.
+func sampleMatroska(docType string) Sample {
+	video, audio, name := "V_MPEG4/ISO/AVC", "A_AAC", "sample.mkv"
+	if docType == "webm" {
+		video, audio, name = "V_VP9", "A_OPUS", "sample.webm"
+	}
+	header := ebml(0x1A45DFA3,
+		ebmlUint(0x4286, 1), // EBMLVersion
+		ebmlText(0x4282, docType),
+		ebmlUint(0x4287, 4), // DocTypeVersion
+		ebmlUint(0x4285, 2)) // DocTypeReadVersion
+	info := ebml(0x1549A966,
+		ebml(0x2AD7B1, u32(1000000)), // TimestampScale
+		ebmlText(0x7BA9, Title),
+		ebmlText(0x4D80, "samplegen"),
+		ebmlText(0x5741, "samplegen"))
+	track := func(n, typ uint8, codec, lang string) []byte {
+		return ebml(0xAE, ebmlUint(0xD7, n), ebmlUint(0x73C5, n), ebmlUint(0x83, typ),
+			ebmlText(0x86, codec), ebmlText(0x22B59C, lang))
+	}
+	tracks := ebml(0x1654AE6B, track(1, 1, video, "eng"), track(2, 2, audio, "eng"))
+	simple := func(k, v string) []byte { return ebml(0x67C8, ebmlText(0x45A3, k), ebmlText(0x4487, v)) }
+	tags := ebml(0x1254C367, ebml(0x7373,
+		ebml(0x63C0, ebmlUint(0x68CA, 50)), // Targets: movie
+		simple("ARTIST", Artist),
+		simple("DATE_RELEASED", Year),
+		simple("COMMENT", Comment)))
+	cluster := ebml(0x1F43B675, ebmlUint(0xE7, 0), ebml(0xA3, mediaData)) // Timestamp, SimpleBlock
+	return Sample{
+		Name: name,
+		Data: cat(header, ebml(0x18538067, info, tracks, tags, cluster)),
+		Fields: map[string]string{
+			"DocType":         docType,
+			"Title":           Title,
+			"MuxingApp":       "samplegen",
+			"ARTIST":          Artist,
+			"DATE_RELEASED":   Year,
+			"Track1.Type":     "video",
+			"Track1.Codec":    video,
+			"Track2.Language": "eng",
+		},
+	}
+}
+
+// sampleAVI has an avih header and a LIST INFO chunk.
+func sampleAVI() Sample {
+	avih := make([]byte, 56)
+	le.PutUint32(avih[0:], 40000) // µs per frame
+	le.PutUint32(avih[16:], 1)    // total frames
+	le.PutUint32(avih[24:], 1)    // streams
+	le.PutUint32(avih[32:], 320)
+	le.PutUint32(avih[36:], 240)
+	info := func(id, s string) []byte { return riffChunk(id, []byte(s+"\x00")) }
+	body := cat([]byte("AVI "),
+		riffChunk("LIST", []byte("hdrl"), riffChunk("avih", avih)),
+		riffChunk("LIST", []byte("INFO"), info("INAM", Title), info("IART", Artist), info("ICOP", Copyright)),
+		riffChunk("LIST", []byte("movi"), riffChunk("00dc", mediaData)),
+	)
+	return Sample{
+		Name: "sample.avi",
+		Data: cat([]byte("RIFF"), le.AppendUint32(nil, uint32(len(body))), body),
+		Fields: map[string]string{
+			"Width": "320 px", "Height": "240 px",
+			"INAM": Title, "IART": Artist, "ICOP": Copyright,
+		},
+	}
+}
+
+// ASF object GUIDs, in their on-disk byte order.
+var (
+	asfHeader      = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	asfContentDesc = []byte{0x33, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	asfData        = []byte{0x36, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+)
+
+// sampleWMV is an ASF Header Object with a Content Description Object,
+// and an empty Data Object.
+func sampleWMV() Sample {
+	utf16le := func(s string) []byte {
+		var b []byte
+		for _, u := range utf16.Encode([]rune(s + "\x00")) {
+			b = le.AppendUint16(b, u)
+		}
+		return b
+	}
+	object := func(guid []byte, body []byte) []byte {
+		return cat(guid, le.AppendUint64(nil, uint64(24+len(body))), body)
+	}
+	strs := [][]byte{utf16le(Title), utf16le(Artist), utf16le(Copyright), utf16le(Comment), utf16le("")}
+	var lens, text []byte
+	for _, s := range strs {
+		lens = le.AppendUint16(lens, uint16(len(s)))
+		text = append(text, s...)
+	}
+	desc := object(asfContentDesc, cat(lens, text))
+	header := object(asfHeader, cat(le.AppendUint32(nil, 1), []byte{1, 2}, desc))
+	data := object(asfData, cat(make([]byte, 16), le.AppendUint64(nil, 0), []byte{1, 1}))
+	return Sample{
+		Name: "sample.wmv",
+		Data: cat(header, data),
+		Fields: map[string]string{
+			"Title": Title, "Author": Artist, "Copyright": Copyright, "Description": Comment,
+		},
+	}
+}
+
+// sampleFLV is an FLV header and an onMetaData script tag.
+func sampleFLV() Sample {
+	amfString := func(s string) []byte { return cat(u16(uint16(len(s))), []byte(s)) }
+	number := func(k string, v float64) []byte {
+		return cat(amfString(k), []byte{0}, be.AppendUint64(nil, math.Float64bits(v)))
+	}
+	text := func(k, v string) []byte { return cat(amfString(k), []byte{2}, amfString(v)) }
+	script := cat([]byte{2}, amfString("onMetaData"), []byte{8}, u32(4),
+		number("duration", 1), number("width", 320), number("height", 240), text("title", Title),
+		[]byte{0, 0, 9}) // object end
+	n := len(script)
+	tag := cat([]byte{18, byte(n >> 16), byte(n >> 8), byte(n)}, make([]byte, 7), script)
+	return Sample{
+		Name: "sample.flv",
+		Data: cat([]byte("FLV\x01\x05"), u32(9), u32(0), tag, u32(uint32(len(tag)))),
+		Fields: map[string]string{
+			"HasVideo": "true", "HasAudio": "true",
+			"duration": "1", "width": "320", "title": Title,
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ─── Bounded reads ───────────────────────────────────────────────────────────
+// The metadata of a recording sits in a few kilobytes near its start or
+// end, and viewing a 2 GB WAV should read about that much of it. These
+// helpers read one byte range, or walk the chunks of a RIFF list by their
+// headers, seeking past payloads instead of loading them.
+
+// ReadRange reads up to n bytes at off. Fewer are returned at end of file.
+func ReadRange(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	got, err := r.ReadAt(buf, off)
+	if err == io.EOF {
+		err = nil
+	}
+	return buf[:got], err
+}
+
+// RIFFChunk is one chunk of a RIFF list: its ID and where its payload lies.
+type RIFFChunk struct {
+	ID   string
+	Off  int64 // payload offset
+	Size int64
+}
+
+// Read returns the payload of c.
+func (c RIFFChunk) Read(r io.ReaderAt) ([]byte, error) {
+	return ReadRange(r, c.Off, int(c.Size))
+}
+
+// RIFFChunks lists the chunks between start and end of r, reading only
+// their 8-byte headers. The walk stops at a chunk that runs past end.
+func RIFFChunks(r io.ReaderAt, start, end int64) ([]RIFFChunk, error) {
+	var chunks []RIFFChunk
+	hdr := make([]byte, 8)
+	for off := start; off+8 <= end; {
+		if _, err := r.ReadAt(hdr, off); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return chunks, err
+		}
+		c := RIFFChunk{
+			ID:   string(hdr[:4]),
+			Off:  off + 8,
+			Size: int64(binary.LittleEndian.Uint32(hdr[4:8])),
+		}
+		if c.Off+c.Size > end {
+			break
+		}
+		chunks = append(chunks, c)
+		off = c.Off + c.Size + c.Size%2
+	}
+	return chunks, nil
+}
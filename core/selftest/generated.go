@@ -0,0 +1,70 @@
+package selftest
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/samplegen"
+)
+
+// ─── Generated samples ───────────────────────────────────────────────────────
+// The corpus above covers the formats with the most involved writers; the
+// samplegen files cover every format, and check that each is detected and
+// that view reports the metadata it was made with.
+
+// Generated views the sample samplegen makes of every format and checks
+// that it carries the fields it was made with.
+func Generated() ([]*Report, error) {
+	dir, err := os.MkdirTemp("", "surgery-selftest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var out []*Report
+	for _, s := range samplegen.All() {
+		path := filepath.Join(dir, s.Name)
+		if err := os.WriteFile(path, s.Data, 0644); err != nil {
+			return out, err
+		}
+		r := &Report{Path: "samplegen/" + s.Name, Format: string(s.Format)}
+		out = append(out, r)
+
+		if id, _ := core.DetectFormat(path); id != s.Format {
+			r.fail("detect", "detected as %s", id)
+			continue
+		}
+		r.pass("detect", "")
+		h, err := batch.HandlerFor(path)
+		if err != nil {
+			r.fail("view", "%v", err)
+			continue
+		}
+		r.Format = h.Info().Name
+		m, err := h.View(path)
+		if err != nil {
+			r.fail("view", "%v", err)
+			continue
+		}
+		r.pass("view", "")
+		checkFields(r, m, s.Fields)
+	}
+	return out, nil
+}
+
+// checkFields checks that m has every field of want with its value.
+func checkFields(r *Report, m *core.Metadata, want map[string]string) {
+	for _, k := range core.SortedKeys(want) {
+		switch got, ok := lookup(m, k); {
+		case !ok:
+			r.fail("sample fields", "%s missing", k)
+			return
+		case got != want[k]:
+			r.fail("sample fields", "%s is %q, want %q", k, got, want[k])
+			return
+		}
+	}
+	r.pass("sample fields", "")
+}
@@ -0,0 +1,421 @@
+// Package selftest checks that surgery round-trips files without damage:
+// a copy is viewed, edited, viewed again, stripped and viewed once more,
+// and each step is checked against what it should have done — the edited
+// fields present, no other field lost, metadata gone after strip, the
+// payload (image or audio data) unchanged throughout and the original file
+// never touched. It runs on a built-in corpus of small samples, compared
+// with golden files of their expected metadata, or on the user's own
+// files.
+package selftest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// sample is one file of the corpus and the edit made to it.
+type sample struct {
+	name string
+	set  map[string]string
+}
+
+// corpus lists the samples of testdata. Each has a golden file,
+// name.golden, holding its metadata after view, edit and strip.
+var corpus = []sample{
+	{"sample.jpg", map[string]string{"Artist": "Ann Smith", "xmp:dc:title": "Tower"}},
+	{"sample.png", map[string]string{"Title": "Edited"}},
+	{"sample.mp3", map[string]string{"Title": "Edited", "Year": "2024"}},
+	{"sample.flac", map[string]string{"TITLE": "Edited"}},
+	{"sample.mp4", map[string]string{"title": "Edited", "Track2.Language": "fra", "Track1.Rotation": "90"}},
+	{"sample.heic", map[string]string{"Artist": "Ann Smith", "GPSLatitude": "10.5", "GPSLongitude": "-3.25"}},
+	{"sample.dng", map[string]string{"Artist": "Ann Smith"}},
+	{"sample.mkv", map[string]string{"Track3.Title": "Português"}},
+	{"sample.m4a", map[string]string{"Title": "Edited", "TrackNumber": "3/12"}},
+	{"sample.ogg", map[string]string{"TITLE": "Edited"}},
+	{"sample.opus", map[string]string{"TITLE": "Edited", "ALBUM": "Opus"}},
+}
+
+// probeValue is what File sets a field to.
+const probeValue = "selftest"
+
+// Check is the outcome of one check of a run.
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string // why it failed, or a note when it passed
+}
+
+// Report is the outcome of a run on one file.
+type Report struct {
+	Path   string
+	Format string
+	Checks []Check
+}
+
+// Failed reports whether any check of r failed.
+func (r *Report) Failed() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) pass(name, note string) {
+	r.Checks = append(r.Checks, Check{Name: name, OK: true, Detail: note})
+}
+
+func (r *Report) fail(name, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Detail: fmt.Sprintf(format, args...)})
+}
+
+// steps is the metadata a run saw after view, edit and strip; nil for a
+// step the format does not support.
+type steps [3]*core.Metadata
+
+// File round-trips a copy of path, made in a temporary directory, setting
+// one editable field of its format to "selftest". The original is only
+// read, and checked afterwards to be unchanged.
+func File(path string) (*Report, error) {
+	h, err := batch.HandlerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := h.View(path)
+	if err != nil {
+		return nil, err
+	}
+	before, err := core.Snapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := roundTrip(h, path, probe(h.Info(), m))
+	if err != nil {
+		return nil, err
+	}
+	if after, err := core.Snapshot(path); err != nil || after.SHA256 != before.SHA256 {
+		r.fail("original untouched", "%s changed during the self-test", path)
+	} else {
+		r.pass("original untouched", "")
+	}
+	return r, nil
+}
+
+// probe picks the field File sets: an editable field the file already
+// has, so that formats edited in place have room for it — one the format
+// lists first, then any other but a track's — else the first the format
+// lists. The value is never longer than the one it replaces.
+func probe(info core.FormatInfo, m *core.Metadata) map[string]string {
+	fit := func(f core.MetaField) map[string]string {
+		v := probeValue
+		if len(f.Value) < len(v) {
+			v = v[:len(f.Value)]
+		}
+		return map[string]string{f.Key: v}
+	}
+	for _, name := range info.EditableFields {
+		for _, f := range m.Fields {
+			if f.Editable && strings.EqualFold(f.Key, name) && f.Value != "" {
+				return fit(f)
+			}
+		}
+	}
+	for _, f := range m.Fields {
+		if f.Editable && f.Category != "Tracks" && f.Value != "" {
+			return fit(f)
+		}
+	}
+	if len(info.EditableFields) > 0 {
+		return map[string]string{info.EditableFields[0]: probeValue}
+	}
+	return nil
+}
+
+// Corpus runs every built-in sample, comparing its metadata with the
+// sample's golden file.
+func Corpus() ([]*Report, error) {
+	var out []*Report
+	err := eachSample(func(s sample, path string, golden []byte) error {
+		h, err := batch.HandlerFor(path)
+		if err != nil {
+			return err
+		}
+		r, st, err := roundTrip(h, path, s.set)
+		if err != nil {
+			return err
+		}
+		r.Path = s.name
+		if got := render(st); !bytes.Equal(got, golden) {
+			r.fail("golden file", "%s", firstDiff(golden, got))
+		} else {
+			r.pass("golden file", "")
+		}
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+// WriteGolden writes the golden file of every built-in sample to dir, as
+// the samples come out of this build; copied over testdata, they become
+// the expected output.
+func WriteGolden(dir string) error {
+	return eachSample(func(s sample, path string, _ []byte) error {
+		h, err := batch.HandlerFor(path)
+		if err != nil {
+			return err
+		}
+		_, st, err := roundTrip(h, path, s.set)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, s.name+".golden"), render(st), 0644)
+	})
+}
+
+// eachSample extracts each sample to a temporary directory and calls fn
+// with it and its golden file.
+func eachSample(fn func(s sample, path string, golden []byte) error) error {
+	dir, err := os.MkdirTemp("", "surgery-selftest-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for _, s := range corpus {
+		data, err := testdata.ReadFile("testdata/" + s.name)
+		if err != nil {
+			return err
+		}
+		golden, _ := testdata.ReadFile("testdata/" + s.name + ".golden")
+		path := filepath.Join(dir, s.name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		if err := fn(s, path, golden); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// ─── The round trip ──────────────────────────────────────────────────────────
+
+// roundTrip copies path to a temporary directory and runs view, edit with
+// set, and strip on the copy, checking each step.
+func roundTrip(h core.Handler, path string, set map[string]string) (*Report, steps, error) {
+	info := h.Info()
+	r := &Report{Path: path, Format: info.Name}
+	var st steps
+
+	dir, err := os.MkdirTemp("", "surgery-selftest-*")
+	if err != nil {
+		return nil, st, err
+	}
+	defer os.RemoveAll(dir)
+	ext := filepath.Ext(path)
+	work := filepath.Join(dir, "original"+ext)
+	if err := copyFile(path, work); err != nil {
+		return nil, st, err
+	}
+
+	if st[0], err = h.View(work); err != nil {
+		r.fail("view", "%v", err)
+		return r, st, nil
+	}
+	r.pass("view", fmt.Sprintf("%d fields", len(st[0].Fields)))
+	payload := readPayload(h, work)
+
+	current := work
+	switch {
+	case !info.CanEdit:
+		r.pass("edit", "not supported for "+info.Name)
+	case len(set) == 0:
+		r.pass("edit", "no editable field to try")
+	default:
+		edited := filepath.Join(dir, "edited"+ext)
+		if err := h.Edit(work, edited, core.EditOptions{Set: set}); err != nil {
+			r.fail("edit", "%v", err)
+			break
+		}
+		if st[1], err = h.View(edited); err != nil {
+			r.fail("view after edit", "%v", err)
+			break
+		}
+		checkEdited(r, st[0], st[1], set)
+		checkPayload(r, "payload unchanged by edit", payload, readPayload(h, edited))
+		current = edited
+	}
+
+	if !info.CanStrip {
+		r.pass("strip", "not supported for "+info.Name)
+		return r, st, nil
+	}
+	stripped := filepath.Join(dir, "stripped"+ext)
+	if err := h.Strip(current, stripped, core.StripOptions{StripAll: true}); err != nil {
+		r.fail("strip", "%v", err)
+		return r, st, nil
+	}
+	if st[2], err = h.View(stripped); err != nil {
+		r.fail("view after strip", "%v", err)
+		return r, st, nil
+	}
+	last := st[0]
+	if st[1] != nil {
+		last = st[1]
+	}
+	checkStripped(r, last, st[2])
+	checkPayload(r, "payload unchanged by strip", payload, readPayload(h, stripped))
+	return r, st, nil
+}
+
+// checkEdited checks that after has the values of set and every field of
+// before.
+func checkEdited(r *Report, before, after *core.Metadata, set map[string]string) {
+	ok := true
+	for _, k := range core.SortedKeys(set) {
+		if v, found := lookup(after, k); !found {
+			r.fail("edited fields", "%s is missing after the edit", k)
+			ok = false
+		} else if v != set[k] {
+			r.fail("edited fields", "%s is %q after the edit, want %q", k, v, set[k])
+			ok = false
+		}
+	}
+	if ok {
+		r.pass("edited fields", "")
+	}
+	var lost []string
+	for _, f := range before.Fields {
+		if _, edited := set[f.Key]; edited {
+			continue
+		}
+		if _, found := lookup(after, f.Key); !found {
+			lost = append(lost, f.Key)
+		}
+	}
+	if len(lost) > 0 {
+		r.fail("other fields kept", "lost by the edit: %s", strings.Join(lost, ", "))
+	} else {
+		r.pass("other fields kept", "")
+	}
+}
+
+// checkStripped checks that strip removed metadata. The fields of the
+// streams themselves — tracks, codec parameters — are not metadata and
+// may stay.
+func checkStripped(r *Report, before, after *core.Metadata) {
+	var kept []string
+	for _, f := range before.Fields {
+		if !f.Editable || f.Category == "Tracks" {
+			continue
+		}
+		if _, found := lookup(after, f.Key); found {
+			kept = append(kept, f.Key)
+		}
+	}
+	if len(kept) > 0 {
+		r.fail("metadata removed", "still present after strip: %s", strings.Join(kept, ", "))
+	} else {
+		r.pass("metadata removed", fmt.Sprintf("%d → %d fields", len(before.Fields), len(after.Fields)))
+	}
+}
+
+func checkPayload(r *Report, name string, want, got []byte) {
+	switch {
+	case want == nil:
+		r.pass(name, "no payload reader for "+r.Format)
+	case !bytes.Equal(want, got):
+		r.fail(name, "payload differs: %d bytes before, %d after", len(want), len(got))
+	default:
+		r.pass(name, fmt.Sprintf("%d bytes", len(want)))
+	}
+}
+
+// readPayload returns the payload of path, nil when h cannot read one.
+func readPayload(h core.Handler, path string) []byte {
+	pr, ok := h.(core.PayloadReader)
+	if !ok {
+		return nil
+	}
+	p, err := pr.Payload(path)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// lookup finds a field by key, ignoring case, as edit matches keys.
+func lookup(m *core.Metadata, key string) (string, bool) {
+	for _, f := range m.Fields {
+		if strings.EqualFold(f.Key, key) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// ─── Golden files ────────────────────────────────────────────────────────────
+
+// render writes the metadata of each step as sorted "Category: Key = Value"
+// lines under a heading, so that the order a parser walks tags in does not
+// matter.
+func render(st steps) []byte {
+	var b bytes.Buffer
+	for i, name := range []string{"view", "edit", "strip"} {
+		fmt.Fprintf(&b, "# %s\n", name)
+		if st[i] == nil {
+			b.WriteString("(not run)\n")
+			continue
+		}
+		lines := make([]string, 0, len(st[i].Fields))
+		for _, f := range st[i].Fields {
+			lines = append(lines, fmt.Sprintf("%s: %s = %s", f.Category, f.Key, f.Value))
+		}
+		sort.Strings(lines)
+		for _, l := range lines {
+			b.WriteString(l + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// firstDiff describes the first line where got differs from want.
+func firstDiff(want, got []byte) string {
+	if want == nil {
+		return "no golden file"
+	}
+	w, g := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			return fmt.Sprintf("line %d: want %q, got %q", i+1, wl, gl)
+		}
+	}
+	return "files differ"
+}
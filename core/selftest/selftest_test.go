@@ -0,0 +1,57 @@
+package selftest
+
+import (
+	"testing"
+)
+
+// TestCorpus runs view, edit, view, strip and view on every sample of
+// testdata, checking the payload is unchanged by each write and the
+// metadata matches the sample's golden file.
+func TestCorpus(t *testing.T) {
+	reports, err := Corpus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != len(corpus) {
+		t.Fatalf("got %d reports for %d samples", len(reports), len(corpus))
+	}
+	for _, r := range reports {
+		r := r
+		t.Run(r.Path, func(t *testing.T) {
+			checkReport(t, r, "view", "golden file")
+		})
+	}
+}
+
+// TestGenerated checks that every samplegen file is detected as its
+// format and views with the fields it was made with.
+func TestGenerated(t *testing.T) {
+	reports, err := Generated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range reports {
+		r := r
+		t.Run(r.Path, func(t *testing.T) {
+			checkReport(t, r, "detect", "view", "sample fields")
+		})
+	}
+}
+
+// checkReport fails t for each failed check of r, and for each of want
+// that did not run.
+func checkReport(t *testing.T, r *Report, want ...string) {
+	t.Helper()
+	ran := make(map[string]bool)
+	for _, c := range r.Checks {
+		ran[c.Name] = true
+		if !c.OK {
+			t.Errorf("%s: %s", c.Name, c.Detail)
+		}
+	}
+	for _, name := range want {
+		if !ran[name] {
+			t.Errorf("%s: not run", name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package core
+
+// ─── Provenance signing ──────────────────────────────────────────────────────
+// surgery never writes its own name into a file. Users who want a record
+// of what processed a file pass --sign TEXT, which is written to the
+// field each format keeps for the software that produced it.
+
+// signFields names that field, as the format's edit accepts it.
+var signFields = map[FormatID]string{
+	FmtJPEG: "Software",
+	FmtPNG:  "Software",
+	FmtMP3:  "TSSE",
+	FmtFLAC: "ENCODER",
+	FmtMP4:  "EncodingTool",
+	FmtMOV:  "com.apple.quicktime.software",
+	FmtPDF:  "Producer",
+}
+
+// SignField returns the field --sign writes for format.
+func SignField(format FormatID) (string, bool) {
+	f, ok := signFields[format]
+	return f, ok
+}
+
+// Sign returns opts with text added as a Set of format's sign field. It
+// reports false, leaving opts alone, when the format has no such field.
+func Sign(opts EditOptions, format FormatID, text string) (EditOptions, bool) {
+	field, ok := SignField(format)
+	if !ok {
+		return opts, false
+	}
+	set := make(map[string]string, len(opts.Set)+1)
+	for k, v := range opts.Set {
+		set[k] = v
+	}
+	set[field] = text
+	opts.Set = set
+	return opts, true
+}
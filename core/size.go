@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+// ─── Size breakdown ──────────────────────────────────────────────────────────
+// How much of a file is metadata? A phone JPEG can carry 60 KB of EXIF and
+// maker notes; a ripped album, a 2 MB front cover in every track. Handlers
+// that understand their container list its structures with their sizes,
+// so info --size-breakdown can show where the bytes go and batch strip
+// --dry-run what stripping would save.
+
+// SizePart is one structure of a file, headers included.
+type SizePart struct {
+	Name  string
+	Bytes int64
+	// Metadata marks what a default strip removes.
+	Metadata bool
+}
+
+// SizeReporter is implemented by handlers that can break a file down.
+type SizeReporter interface {
+	SizeBreakdown(path string) ([]SizePart, error)
+}
+
+// SizeBreakdown returns the structures of path, parts of the same name
+// merged, in file order. Bytes no part accounts for are reported as
+// "Other".
+func SizeBreakdown(h Handler, path string) ([]SizePart, error) {
+	sr, ok := h.(SizeReporter)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support a size breakdown", h.Info().Name)
+	}
+	parts, err := sr.SizeBreakdown(path)
+	if err != nil {
+		return nil, err
+	}
+	if parts == nil {
+		return nil, fmt.Errorf("%s does not support a size breakdown", h.Info().Name)
+	}
+	var merged []SizePart
+	index := make(map[string]int)
+	var total int64
+	for _, p := range parts {
+		total += p.Bytes
+		if i, ok := index[p.Name]; ok {
+			merged[i].Bytes += p.Bytes
+			continue
+		}
+		index[p.Name] = len(merged)
+		merged = append(merged, p)
+	}
+	if st, err := os.Stat(path); err == nil && st.Size() > total {
+		merged = append(merged, SizePart{Name: "Other", Bytes: st.Size() - total})
+	}
+	return merged, nil
+}
+
+// MetadataBytes totals the parts a default strip removes.
+func MetadataBytes(parts []SizePart) int64 {
+	var n int64
+	for _, p := range parts {
+		if p.Metadata {
+			n += p.Bytes
+		}
+	}
+	return n
+}
+
+// FormatSize renders a byte count as "512 B", "62.0 KB" or "2.1 MB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// ─── Optimize ────────────────────────────────────────────────────────────────
+// Writers leave room for later edits: FLAC PADDING blocks, ID3v2 padding,
+// whitespace at the end of XMP packets, free and skip boxes in MP4.
+// Optimizing removes that room and nothing else — no field, picture or
+// payload byte changes — unlike strip.
+
+// Optimizer is implemented by handlers that can drop padding. saved is
+// the number of bytes removed, or that would be on a dry run.
+type Optimizer interface {
+	Optimize(path, outPath string, dryRun bool) (saved int64, err error)
+}
+
+// Optimize drops padding from path, writing to outPath ("" for in place).
+func Optimize(h Handler, path, outPath string, dryRun bool) (int64, error) {
+	o, ok := h.(Optimizer)
+	if !ok {
+		return 0, fmt.Errorf("%s does not support optimize", h.Info().Name)
+	}
+	return o.Optimize(path, ResolveOutPath(path, outPath), dryRun)
+}
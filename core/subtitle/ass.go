@@ -0,0 +1,137 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── ASS / SSA ───────────────────────────────────────────────────────────────
+// [Script Info] mixes credits (Title, Original Script, …) with fields the
+// renderer needs (PlayResX, WrapStyle, …). Aegisub also writes "; Script
+// generated by …" comments and an [Aegisub Project Garbage] section holding
+// absolute paths to the audio and video the subtitles were timed against.
+
+// assCredits are the [Script Info] keys that describe people and releases.
+var assCredits = map[string]bool{
+	"title":                true,
+	"original script":      true,
+	"original translation": true,
+	"original editing":     true,
+	"original timing":      true,
+	"synch point":          true,
+	"script updated by":    true,
+	"update details":       true,
+}
+
+// assProjectSections are written by Aegisub and never read by renderers.
+var assProjectSections = map[string]bool{
+	"aegisub project garbage": true,
+	"aegisub extradata":       true,
+}
+
+func assSection(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		return strings.ToLower(line[1 : len(line)-1]), true
+	}
+	return "", false
+}
+
+func assKV(line string) (string, string, bool) {
+	k, v, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(k), strings.TrimSpace(v), true
+}
+
+func viewASS(t *subtitleText, m *core.Metadata) {
+	add := func(k, v, cat string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: cat, Editable: false})
+	}
+	section := ""
+	styles, dialogue := 0, 0
+	for _, line := range t.lines {
+		if s, ok := assSection(line); ok {
+			section = s
+			continue
+		}
+		switch {
+		case section == "script info":
+			if strings.HasPrefix(line, ";") {
+				if c := strings.TrimSpace(line[1:]); c != "" {
+					add("Comment", c, "Script Info")
+				}
+				continue
+			}
+			k, v, ok := assKV(line)
+			if !ok || v == "" {
+				continue
+			}
+			cat := "Rendering"
+			if assCredits[strings.ToLower(k)] {
+				cat = "Script Info"
+			}
+			add(k, v, cat)
+		case assProjectSections[section]:
+			if k, v, ok := assKV(line); ok && v != "" {
+				add(k, v, "Aegisub Project")
+			}
+		case strings.HasSuffix(section, "styles"):
+			if strings.HasPrefix(line, "Style:") {
+				styles++
+			}
+		case section == "events":
+			if strings.HasPrefix(line, "Dialogue:") {
+				dialogue++
+			}
+		}
+	}
+	add("Styles", fmt.Sprintf("%d", styles), "Subtitles")
+	add("DialogueLines", fmt.Sprintf("%d", dialogue), "Subtitles")
+	add("Encoding", t.encoding(), "Subtitles")
+}
+
+// stripASS removes credits, comments and Aegisub project sections from t
+// and returns the removed lines. keep holds lower-cased keys to leave alone
+// ("title", "comment", "aegisub project garbage", …).
+func stripASS(t *subtitleText, keep map[string]bool) []string {
+	var out, removed []string
+	section := ""
+	for _, line := range t.lines {
+		if s, ok := assSection(line); ok {
+			section = s
+			if assProjectSections[s] && !keep[s] {
+				removed = append(removed, line)
+				continue
+			}
+			out = append(out, line)
+			continue
+		}
+		drop := false
+		switch {
+		case assProjectSections[section] && !keep[section]:
+			// the section's trailing blank line goes with it
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			drop = true
+		case section == "script info" && strings.HasPrefix(line, ";"):
+			drop = !keep["comment"]
+		case section == "script info":
+			if k, _, ok := assKV(line); ok {
+				lk := strings.ToLower(k)
+				drop = assCredits[lk] && !keep[lk]
+			}
+		}
+		if drop {
+			removed = append(removed, line)
+			continue
+		}
+		out = append(out, line)
+	}
+	t.lines = out
+	return removed
+}
@@ -0,0 +1,198 @@
+// Package subtitle handles metadata for subtitle formats:
+// SRT, ASS/SSA ([Script Info]), WebVTT (header and NOTE blocks)
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Handler implements core.Handler for subtitle formats.
+type Handler struct {
+	format core.FormatID
+}
+
+// New returns a subtitle Handler for the given format.
+func New(fmt core.FormatID) *Handler { return &Handler{format: fmt} }
+
+func (h *Handler) Info() core.FormatInfo {
+	return formatInfo[h.format]
+}
+
+var formatInfo = map[core.FormatID]core.FormatInfo{
+	core.FmtSRT: {
+		Name:       "SRT",
+		Extensions: []string{".srt"},
+		MediaType:  "subtitle",
+		MIMETypes:  []string{"application/x-subrip"},
+		CanView:    true,
+		CanEdit:    false,
+		CanStrip:   false,
+		Notes:      "SubRip has no metadata header; view reports cue count and encoding.",
+	},
+	core.FmtASS: {
+		Name:       "ASS/SSA",
+		Extensions: []string{".ass", ".ssa"},
+		MediaType:  "subtitle",
+		MIMETypes:  []string{"text/x-ssa"},
+		CanView:    true,
+		CanEdit:    false,
+		CanStrip:   true,
+		Notes:      "[Script Info] credits, ; comments and Aegisub project paths. Strip keeps rendering fields (PlayResX, WrapStyle, …).",
+	},
+	core.FmtVTT: {
+		Name:       "WebVTT",
+		Extensions: []string{".vtt"},
+		MediaType:  "subtitle",
+		MIMETypes:  []string{"text/vtt"},
+		CanView:    true,
+		CanEdit:    false,
+		CanStrip:   true,
+		Notes:      "WEBVTT header text, header lines and NOTE blocks. Strip keeps X-TIMESTAMP-MAP, STYLE and REGION.",
+	},
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Text handling
+// ──────────────────────────────────────────────────────────────────────────────
+
+// subtitleText is a subtitle file split into lines, remembering the BOM
+// and line ending so that strip writes the file back the way it was.
+type subtitleText struct {
+	bom     bool
+	newline string
+	lines   []string
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+func readText(path string) (*subtitleText, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &subtitleText{newline: "\n"}
+	if bytes.HasPrefix(data, utf8BOM) {
+		t.bom = true
+		data = data[len(utf8BOM):]
+	}
+	if bytes.Contains(data, []byte("\r\n")) {
+		t.newline = "\r\n"
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		t.lines = append(t.lines, strings.TrimSuffix(line, "\r"))
+	}
+	return t, nil
+}
+
+func (t *subtitleText) bytes() []byte {
+	var buf bytes.Buffer
+	if t.bom {
+		buf.Write(utf8BOM)
+	}
+	buf.WriteString(strings.Join(t.lines, t.newline))
+	return buf.Bytes()
+}
+
+func (t *subtitleText) encoding() string {
+	if t.bom {
+		return "UTF-8 with BOM"
+	}
+	return "UTF-8"
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// View
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (h *Handler) View(path string) (*core.Metadata, error) {
+	m := &core.Metadata{FilePath: path, Format: formatInfo[h.format].Name}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return m, err
+	}
+	t, err := readText(path)
+	if err != nil {
+		return m, err
+	}
+	switch h.format {
+	case core.FmtSRT:
+		viewSRT(t, m)
+	case core.FmtASS:
+		viewASS(t, m)
+	case core.FmtVTT:
+		viewVTT(t, m)
+	default:
+		ext := strings.ToLower(filepath.Ext(path))
+		return m, fmt.Errorf("unsupported subtitle format: %s", ext)
+	}
+	return m, nil
+}
+
+func viewSRT(t *subtitleText, m *core.Metadata) {
+	cues := 0
+	for _, line := range t.lines {
+		if strings.Contains(line, " --> ") {
+			cues++
+		}
+	}
+	m.Fields = append(m.Fields,
+		core.MetaField{Key: "CueCount", Value: fmt.Sprintf("%d", cues), Category: "Subtitles", Editable: false},
+		core.MetaField{Key: "Encoding", Value: t.encoding(), Category: "Subtitles", Editable: false},
+	)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Edit
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error {
+	return fmt.Errorf("%s does not support metadata editing in v0.1.2", formatInfo[h.format].Name)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Strip
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	out := core.ResolveOutPath(path, outPath)
+	if !formatInfo[h.format].CanStrip {
+		return fmt.Errorf("%s does not support strip in v0.1.2", formatInfo[h.format].Name)
+	}
+	t, err := readText(path)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	for _, k := range opts.KeepFields {
+		keep[strings.ToLower(k)] = true
+	}
+
+	var removed []string
+	switch h.format {
+	case core.FmtASS:
+		removed = stripASS(t, keep)
+	case core.FmtVTT:
+		removed = stripVTT(t, keep)
+	}
+
+	if opts.DryRun {
+		if len(removed) == 0 {
+			fmt.Println("Dry-run: no subtitle metadata to remove")
+			return nil
+		}
+		fmt.Println("Dry-run: these lines would be removed:")
+		for _, line := range removed {
+			fmt.Printf("  %s\n", line)
+		}
+		return nil
+	}
+	return os.WriteFile(out, t.bytes(), 0644)
+}
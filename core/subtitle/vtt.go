@@ -0,0 +1,121 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── WebVTT ──────────────────────────────────────────────────────────────────
+// The "WEBVTT" signature line may carry free text (often a title), and the
+// lines after it up to the first blank line are header metadata such as
+// "Kind: captions" or "Language: en". NOTE blocks are comments and may
+// appear anywhere. X-TIMESTAMP-MAP is needed for HLS sync and is never
+// stripped; STYLE and REGION blocks affect rendering and are left alone.
+
+const vttTimestampMap = "X-TIMESTAMP-MAP"
+
+// vttHeaderKey returns the key of a header line ("Kind: captions",
+// "X-TIMESTAMP-MAP=…").
+func vttHeaderKey(line string) (string, string) {
+	if i := strings.IndexAny(line, ":="); i > 0 {
+		return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	return "Header", strings.TrimSpace(line)
+}
+
+func isVTTNote(line string) bool {
+	return line == "NOTE" || strings.HasPrefix(line, "NOTE ") || strings.HasPrefix(line, "NOTE\t")
+}
+
+// vttHeaderEnd returns the index of the first blank line after the
+// signature, i.e. the end of the header block.
+func vttHeaderEnd(lines []string) int {
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+func viewVTT(t *subtitleText, m *core.Metadata) {
+	add := func(k, v, cat string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: cat, Editable: false})
+	}
+	if len(t.lines) == 0 || !strings.HasPrefix(t.lines[0], "WEBVTT") {
+		return
+	}
+	if text := strings.TrimSpace(strings.TrimPrefix(t.lines[0], "WEBVTT")); text != "" {
+		add("Title", strings.TrimLeft(text, "- "), "Header")
+	}
+	end := vttHeaderEnd(t.lines)
+	for _, line := range t.lines[1:end] {
+		k, v := vttHeaderKey(line)
+		add(k, v, "Header")
+	}
+
+	cues := 0
+	for i := end; i < len(t.lines); i++ {
+		line := t.lines[i]
+		if isVTTNote(line) {
+			note := []string{strings.TrimSpace(strings.TrimPrefix(line, "NOTE"))}
+			for i+1 < len(t.lines) && strings.TrimSpace(t.lines[i+1]) != "" {
+				i++
+				note = append(note, strings.TrimSpace(t.lines[i]))
+			}
+			add("Note", strings.TrimSpace(strings.Join(note, " ")), "Notes")
+			continue
+		}
+		if strings.Contains(line, "-->") {
+			cues++
+		}
+	}
+	add("CueCount", fmt.Sprintf("%d", cues), "Subtitles")
+	add("Encoding", t.encoding(), "Subtitles")
+}
+
+// stripVTT removes the signature text, header lines other than
+// X-TIMESTAMP-MAP, and NOTE blocks from t, returning the removed lines.
+// keep holds lower-cased keys to leave alone ("title", "kind", "note", …).
+func stripVTT(t *subtitleText, keep map[string]bool) []string {
+	if len(t.lines) == 0 || !strings.HasPrefix(t.lines[0], "WEBVTT") {
+		return nil
+	}
+	var removed []string
+	out := []string{t.lines[0]}
+	if t.lines[0] != "WEBVTT" && !keep["title"] {
+		removed = append(removed, t.lines[0])
+		out[0] = "WEBVTT"
+	}
+
+	end := vttHeaderEnd(t.lines)
+	for _, line := range t.lines[1:end] {
+		k, _ := vttHeaderKey(line)
+		if strings.EqualFold(k, vttTimestampMap) || keep[strings.ToLower(k)] {
+			out = append(out, line)
+			continue
+		}
+		removed = append(removed, line)
+	}
+
+	for i := end; i < len(t.lines); i++ {
+		line := t.lines[i]
+		if !isVTTNote(line) || keep["note"] {
+			out = append(out, line)
+			continue
+		}
+		removed = append(removed, line)
+		for i+1 < len(t.lines) && strings.TrimSpace(t.lines[i+1]) != "" {
+			i++
+			removed = append(removed, t.lines[i])
+		}
+		// the blank line after the block goes with it
+		if i+1 < len(t.lines) {
+			i++
+		}
+	}
+	t.lines = out
+	return removed
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ─── Value transforms ────────────────────────────────────────────────────────
+// Built-in clean-ups for messy libraries, applied to current values during
+// edit: --titlecase Artist, --trim all, --collapse-spaces all, …
+
+// Transform kinds.
+const (
+	TransformTitleCase      = "titlecase"
+	TransformUpper          = "uppercase"
+	TransformLower          = "lowercase"
+	TransformTrim           = "trim"
+	TransformCollapseSpaces = "collapse-spaces"
+)
+
+// ValueTransform applies one transform kind to a field ("*" for every
+// editable field).
+type ValueTransform struct {
+	Key  string
+	Kind string
+}
+
+// NewTransform validates kind and maps the key "all" to "*".
+func NewTransform(kind, key string) (ValueTransform, error) {
+	switch kind {
+	case TransformTitleCase, TransformUpper, TransformLower, TransformTrim, TransformCollapseSpaces:
+	default:
+		return ValueTransform{}, fmt.Errorf("unknown transform %q", kind)
+	}
+	if strings.EqualFold(key, "all") {
+		key = "*"
+	}
+	return ValueTransform{Key: key, Kind: kind}, nil
+}
+
+func (t ValueTransform) apply(s string) string {
+	switch t.Kind {
+	case TransformTitleCase:
+		return titleCase(s)
+	case TransformUpper:
+		return strings.ToUpper(s)
+	case TransformLower:
+		return strings.ToLower(s)
+	case TransformTrim:
+		return strings.TrimSpace(s)
+	case TransformCollapseSpaces:
+		return collapseSpaces(s)
+	}
+	return s
+}
+
+// titleSmallWords stay lower case inside a title, as most tagging style
+// guides (MusicBrainz, Chicago) ask.
+var titleSmallWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "but": true, "or": true,
+	"nor": true, "of": true, "in": true, "on": true, "at": true, "to": true,
+	"for": true, "by": true, "vs": true, "vs.": true, "feat.": true, "ft.": true,
+}
+
+// titleCase capitalises the first letter of each word. The rest of a word
+// is left alone so that "McCartney" and "AC/DC" survive; small words stay
+// lower case unless they start or end the title.
+func titleCase(s string) string {
+	words := strings.Split(s, " ")
+	last := len(words) - 1
+	for last > 0 && words[last] == "" {
+		last--
+	}
+	first := true
+	for i, w := range words {
+		if w == "" {
+			continue
+		}
+		if !first && i != last && titleSmallWords[strings.ToLower(w)] {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = upperFirst(w)
+		}
+		first = false
+	}
+	return strings.Join(words, " ")
+}
+
+// upperFirst upper-cases the first letter of w, skipping leading
+// punctuation such as "(" or a quote.
+func upperFirst(w string) string {
+	r := []rune(w)
+	for i, c := range r {
+		if unicode.IsLetter(c) {
+			r[i] = unicode.ToUpper(c)
+			break
+		}
+		if unicode.IsDigit(c) {
+			break
+		}
+	}
+	return string(r)
+}
+
+// collapseSpaces replaces every run of whitespace with a single space.
+// Leading and trailing space is collapsed too, not removed; that is --trim.
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	space := false
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
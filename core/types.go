@@ -2,13 +2,16 @@
 // for Media Metadata Surgery.
 package core
 
+import "strings"
+
 // MetaField represents a single metadata key-value pair.
 type MetaField struct {
-	Key      string // Canonical field name (e.g. "Make", "Artist", "Title")
-	Value    string // String representation of the value
-	Category string // Category label (e.g. "EXIF", "ID3", "Vorbis", "XMP")
-	Editable bool   // Whether this field can be written back by surgery
-	Raw      string // Raw / hex representation if different from Value
+	Key      string       // Canonical field name (e.g. "Make", "Artist", "Title")
+	Value    string       // String representation of the value
+	Category string       // Category label (e.g. "EXIF", "ID3", "Vorbis", "XMP")
+	Editable bool         // Whether this field can be written back by surgery
+	Raw      string       // Raw / hex representation if different from Value
+	Source   *FieldSource // Where in the file the value was read from; nil when unknown
 }
 
 // Metadata holds all metadata extracted from a single file.
@@ -35,33 +38,132 @@ type StripOptions struct {
 	KeepFields []string
 	// StripGPS removes GPS coordinates only (for privacy).
 	StripGPS bool
+	// StripRegions removes XMP face regions (names and rectangles of the
+	// people in a photo) only.
+	StripRegions bool
 	// StripAll removes every possible metadata structure.
 	StripAll bool
 	// DryRun previews what would be removed without writing.
 	DryRun bool
+	// RemoveSections lists format-specific structures that a normal strip
+	// leaves in place and that should be removed as well
+	// (e.g. "cuesheet", "application" for FLAC).
+	RemoveSections []string
+	// PrivacyFlag additionally turns on the document's own "remove personal
+	// information on save" setting (XLSX filterPrivacy, DOCX
+	// removePersonalInformation).
+	PrivacyFlag bool
+	// Vendor replaces the Vorbis comment vendor string; empty keeps the
+	// one the encoder wrote.
+	Vendor string
+	// Progress, when set, is called as the file is read and written (MP4,
+	// MOV, PDF). See ProgressFunc.
+	Progress ProgressFunc
+	// Backup, when set, copies the file about to be replaced first. It is
+	// applied by Audited, not by the handlers. See BackupBefore.
+	Backup *BackupOptions
+	// Options holds format-specific settings by "format.name" key
+	// ("jpeg.keep-icc" = "true"). See FormatOption.
+	Options map[string]string
+}
+
+// KV is a key and value of an ordered list, where a map would keep only
+// the last value of a repeated key.
+type KV struct {
+	Key, Value string
 }
 
 // EditOptions holds field changes for an edit operation.
 type EditOptions struct {
 	// Set is a map of Key → Value for fields to set or update.
 	Set map[string]string
+	// Add lists values to add to fields that may repeat (Vorbis comments),
+	// applied in order after Set: ARTIST+=a and ARTIST+=b add both.
+	Add []KV
 	// Delete is a list of field keys to remove.
 	Delete []string
 	// DryRun previews changes without writing.
 	DryRun bool
+	// TouchModified sets the document's last-modified date to now
+	// (OPC dcterms:modified, PDF ModDate) unless Set already provides one.
+	TouchModified bool
+	// UnicodeForm is the normalization form for written values: "nfc"
+	// (the default when empty), "nfd" or "none". See NormalizeValues.
+	UnicodeForm string
+	// NumberPad zero-pads track and disc numbers and totals to this many
+	// digits ("03/12"); 0 writes them as given.
+	NumberPad int
+	// Deterministic makes identical inputs produce byte-identical output:
+	// Vorbis comments and ID3 frames are written sorted by key.
+	Deterministic bool
+	// Vendor replaces the Vorbis comment vendor string; empty keeps the
+	// one the encoder wrote.
+	Vendor string
+	// Progress, when set, is called as the file is read and written (MP4,
+	// MOV, PDF). See ProgressFunc.
+	Progress ProgressFunc
+	// Backup, when set, copies the file about to be replaced first. It is
+	// applied by Audited, not by the handlers. See BackupBefore.
+	Backup *BackupOptions
+	// Options holds format-specific settings by "format.name" key
+	// ("id3.version" = "2.3"). See FormatOption.
+	Options map[string]string
+
+	// Conditional operations depend on the current value of a field and
+	// are turned into Set/Delete by ResolveEditOps before Edit runs.
+
+	// SetIfMissing sets a field only when it is absent or empty.
+	SetIfMissing map[string]string
+	// Append adds text after the current value, separated by a space.
+	Append map[string]string
+	// Prefix and Suffix add text before/after a non-empty current value.
+	Prefix map[string]string
+	Suffix map[string]string
+	// ClearIfEquals deletes a field whose current value equals the given one.
+	ClearIfEquals map[string]string
+	// Replace applies regular-expression substitutions to current values.
+	Replace []ValueReplace
+	// Transforms apply case and whitespace normalisation to current values.
+	Transforms []ValueTransform
 }
 
 // FormatInfo describes what a format handler supports.
 type FormatInfo struct {
 	Name           string   // "JPEG"
 	Extensions     []string // [".jpg", ".jpeg"]
-	MediaType      string   // "image" | "audio" | "video" | "document"
+	MediaType      string   // "image" | "audio" | "video" | "document" | "subtitle"
 	MIMETypes      []string
 	CanView        bool
 	CanEdit        bool
 	CanStrip       bool
-	EditableFields []string // Names of fields the handler can write
-	Notes          string   // Any caveats or notes
+	StripsGPS      bool           // strip --gps-only removes location, also where CanStrip is false
+	EditableFields []string       // Names of fields the handler can write
+	SetOnlyFields  []string       // EditableFields that can be set but not deleted
+	Options        []FormatOption // Keys accepted in EditOptions/StripOptions.Options
+	Notes          string         // Any caveats or notes
+}
+
+// Write support levels reported by FormatInfo.FieldSupport.
+const (
+	WriteFull    = "full"     // set and delete
+	WriteSetOnly = "set-only" // set, but not deleted
+)
+
+// FieldSupport returns how far the handler can write field: WriteFull,
+// WriteSetOnly, or "" when the field is not editable. Names match
+// case-insensitively, as in edit.
+func (fi FormatInfo) FieldSupport(field string) string {
+	for _, f := range fi.SetOnlyFields {
+		if strings.EqualFold(f, field) {
+			return WriteSetOnly
+		}
+	}
+	for _, f := range fi.EditableFields {
+		if strings.EqualFold(f, field) {
+			return WriteFull
+		}
+	}
+	return ""
 }
 
 // Handler is the interface every format must implement.
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ─── Unicode normalization ───────────────────────────────────────────────────
+// macOS writes file names, and often tags, in NFD ("e" + combining acute)
+// while nearly everything else expects NFC ("é"). Both look the same but
+// compare, sort and search differently, so written values are normalized
+// to NFC unless EditOptions.UnicodeForm says otherwise.
+
+// Unicode normalization forms accepted by EditOptions.UnicodeForm.
+const (
+	UnicodeNFC  = "nfc"
+	UnicodeNFD  = "nfd"
+	UnicodeNone = "none"
+)
+
+// ParseUnicodeForm validates a --unicode value.
+func ParseUnicodeForm(s string) (string, error) {
+	switch f := strings.ToLower(s); f {
+	case "", UnicodeNFC:
+		return UnicodeNFC, nil
+	case UnicodeNFD, UnicodeNone:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown Unicode form %q (want nfc, nfd or none)", s)
+}
+
+// NormalizeValues returns opts with every Set value converted to
+// opts.UnicodeForm.
+func NormalizeValues(opts EditOptions) EditOptions {
+	var form norm.Form
+	switch opts.UnicodeForm {
+	case "", UnicodeNFC:
+		form = norm.NFC
+	case UnicodeNFD:
+		form = norm.NFD
+	default:
+		return opts
+	}
+	if len(opts.Set) == 0 {
+		return opts
+	}
+	set := make(map[string]string, len(opts.Set))
+	for k, v := range opts.Set {
+		set[k] = form.String(v)
+	}
+	opts.Set = set
+	return opts
+}
+
+// unicodeFormOf classifies s as "NFC", "NFD", "mixed" (neither form), or
+// "" when both forms are identical, as for plain ASCII.
+func unicodeFormOf(s string) string {
+	nfc, nfd := norm.NFC.IsNormalString(s), norm.NFD.IsNormalString(s)
+	switch {
+	case nfc && nfd:
+		return ""
+	case nfc:
+		return "NFC"
+	case nfd:
+		return "NFD"
+	}
+	return "mixed"
+}
+
+// CheckUnicodeForms reports fields whose values mix NFC and NFD, either
+// within one value or across the fields of m. It returns one message per
+// problem, sorted by field.
+func CheckUnicodeForms(m *Metadata) []string {
+	var issues []string
+	byForm := make(map[string][]string)
+	for _, f := range m.Fields {
+		switch form := unicodeFormOf(f.Value); form {
+		case "":
+		case "mixed":
+			issues = append(issues, fmt.Sprintf("%s: value mixes NFC and NFD characters", f.Key))
+		default:
+			byForm[form] = append(byForm[form], f.Key)
+		}
+	}
+	if len(byForm["NFC"]) > 0 && len(byForm["NFD"]) > 0 {
+		for _, k := range byForm["NFD"] {
+			issues = append(issues, fmt.Sprintf("%s: NFD value in a file whose other fields are NFC", k))
+		}
+	} else {
+		for _, k := range byForm["NFD"] {
+			issues = append(issues, fmt.Sprintf("%s: value is NFD; most players and databases expect NFC", k))
+		}
+	}
+	sort.Strings(issues)
+	return issues
+}
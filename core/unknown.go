@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ─── Unknown structures ──────────────────────────────────────────────────────
+// Every parser passes over what it does not understand: an APP segment a
+// camera maker never documented, an atom a new phone writes into udta, an
+// EBML element from a newer Matroska spec. view --report-unknown lists
+// what was passed over and how often, so a request for support can name
+// the structure, and batch view --report-unknown totals them over a
+// library to show which are common enough to be worth supporting.
+
+// UnknownStructure is one kind of structure a parser skipped.
+type UnknownStructure struct {
+	Format string // the file format, set by UnknownStructures
+	Kind   string // "APP segment", "PNG chunk", "udta atom", "EBML element", …
+	ID     string // marker, type or element ID, with an APPn identifier
+	Count  int
+	Bytes  int64 // headers included
+	Files  int   // files it was found in, once tallies are merged
+}
+
+// UnknownReporter is implemented by handlers that can list the structures
+// their parser skips. UnknownStructures returns one entry per structure
+// found; a file without any returns none.
+type UnknownReporter interface {
+	UnknownStructures(path string) ([]UnknownStructure, error)
+}
+
+// UnknownStructures returns the skipped structures of path, entries of the
+// same kind and ID merged, in file order.
+func UnknownStructures(h Handler, path string) ([]UnknownStructure, error) {
+	ur, ok := h.(UnknownReporter)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support an unknown-structure report", h.Info().Name)
+	}
+	found, err := ur.UnknownStructures(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		found[i].Format = h.Info().Name
+	}
+	return MergeUnknown(nil, found), nil
+}
+
+// MergeUnknown adds found, the report of one file, to total and returns
+// it. Counts and bytes add up and Files counts the files of each entry.
+func MergeUnknown(total, found []UnknownStructure) []UnknownStructure {
+	index := make(map[[3]string]int, len(total))
+	for i, u := range total {
+		index[[3]string{u.Format, u.Kind, u.ID}] = i
+	}
+	seen := make(map[int]bool)
+	for _, u := range found {
+		if u.Count == 0 {
+			u.Count = 1
+		}
+		key := [3]string{u.Format, u.Kind, u.ID}
+		i, ok := index[key]
+		if !ok {
+			i = len(total)
+			index[key] = i
+			u.Files = 0
+			total = append(total, u)
+		} else {
+			total[i].Count += u.Count
+			total[i].Bytes += u.Bytes
+		}
+		if !seen[i] {
+			seen[i] = true
+			total[i].Files++
+		}
+	}
+	return total
+}
+
+// SortUnknown orders a tally by count, most common first.
+func SortUnknown(list []UnknownStructure) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Count > list[j].Count
+	})
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Validate runs every metadata check on m and returns one message per
+// problem found.
+func Validate(m *Metadata) []string {
+	issues := CheckUnicodeForms(m)
+	issues = append(issues, checkMusicBrainzIDs(m)...)
+	return append(issues, checkPodcastFields(m)...)
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// checkMusicBrainzIDs reports MusicBrainz*ID fields that are not UUIDs.
+// Artist IDs may hold several, separated by "; " or "/". The disc ID is
+// a hash of the TOC, not a UUID, and is not checked.
+func checkMusicBrainzIDs(m *Metadata) []string {
+	var issues []string
+	for _, f := range m.Fields {
+		if !strings.HasPrefix(f.Key, "MusicBrainz") || !strings.HasSuffix(f.Key, "ID") || f.Key == "MusicBrainzDiscID" {
+			continue
+		}
+		for _, id := range strings.FieldsFunc(f.Value, func(r rune) bool { return r == ';' || r == '/' }) {
+			if id = strings.TrimSpace(id); !uuidRe.MatchString(id) {
+				issues = append(issues, fmt.Sprintf("%s: %q is not a MusicBrainz ID (UUID)", f.Key, id))
+			}
+		}
+	}
+	return issues
+}
+
+// checkPodcastFields reports episode and season numbers that are not
+// positive integers, which podcast feeds reject.
+func checkPodcastFields(m *Metadata) []string {
+	var issues []string
+	for _, f := range m.Fields {
+		if f.Key != "PodcastEpisode" && f.Key != "PodcastSeason" {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(f.Value)); err != nil || n < 1 {
+			issues = append(issues, fmt.Sprintf("%s: %q is not a positive whole number", f.Key, f.Value))
+		}
+	}
+	return issues
+}
+
+// CRCChecker is implemented by handlers whose containers carry checksums
+// of their own, such as PNG chunk CRCs. CheckCRC returns one message per
+// corrupt structure; a format without checksums returns none.
+type CRCChecker interface {
+	CheckCRC(path string) ([]string, error)
+}
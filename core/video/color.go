@@ -0,0 +1,337 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ─── Colour and HDR ──────────────────────────────────────────────────────────
+// A video track may say how its samples are to be shown: the colour
+// primaries, transfer function and matrix as ITU-T H.273 code points, and
+// for HDR the SMPTE ST 2086 mastering display and the content light
+// levels. MP4 carries them in the colr, mdcv and clli boxes of the sample
+// entry; Matroska in the Colour element of a track's Video. QC teams check
+// them before delivery, so view reports them alongside the other track
+// fields, named and with their raw code points, plus a TrackN.HDR
+// summary (HDR10, HLG, PQ, Dolby Vision) when the transfer function is an HDR
+// one.
+
+// trackColor is the colour description of a video track.
+type trackColor struct {
+	primaries, transfer, matrix int // H.273 code points; -1 when not given
+	fullRange                   int // 1 full, 0 limited, -1 not given
+	icc                         int // size of an embedded ICC profile
+
+	// The mastering display: red, green, blue and white point as CIE 1931
+	// x,y pairs, and its luminance range in cd/m². A value the file does
+	// not give is NaN.
+	display        [8]float64
+	maxLum, minLum float64
+
+	maxCLL, maxFALL int // cd/m²; -1 when not given
+	dolbyVision     bool
+}
+
+func newTrackColor() *trackColor {
+	c := &trackColor{primaries: -1, transfer: -1, matrix: -1, fullRange: -1, maxCLL: -1, maxFALL: -1}
+	for i := range c.display {
+		c.display[i] = math.NaN()
+	}
+	c.maxLum, c.minLum = math.NaN(), math.NaN()
+	return c
+}
+
+// colorPrimaries, colorTransfers and colorMatrices name the H.273 code
+// points, which Matroska uses too.
+var colorPrimaries = map[int]string{
+	1: "BT.709", 2: "unspecified", 4: "BT.470 System M", 5: "BT.470 System B/G",
+	6: "SMPTE 170M", 7: "SMPTE 240M", 8: "generic film", 9: "BT.2020",
+	10: "SMPTE ST 428-1 XYZ", 11: "DCI-P3", 12: "Display P3", 22: "EBU Tech 3213-E",
+}
+
+var colorTransfers = map[int]string{
+	1: "BT.709", 2: "unspecified", 4: "BT.470 System M, gamma 2.2",
+	5: "BT.470 System B/G, gamma 2.8", 6: "SMPTE 170M", 7: "SMPTE 240M",
+	8: "linear", 9: "logarithmic 100:1", 10: "logarithmic 316:1",
+	11: "IEC 61966-2-4", 12: "BT.1361", 13: "sRGB", 14: "BT.2020 10-bit",
+	15: "BT.2020 12-bit", 16: "SMPTE ST 2084 PQ", 17: "SMPTE ST 428-1",
+	18: "ARIB STD-B67 HLG",
+}
+
+var colorMatrices = map[int]string{
+	0: "identity, RGB", 1: "BT.709", 2: "unspecified", 4: "FCC",
+	5: "BT.470 System B/G", 6: "SMPTE 170M", 7: "SMPTE 240M", 8: "YCgCo",
+	9: "BT.2020 non-constant luminance", 10: "BT.2020 constant luminance",
+	11: "SMPTE ST 2085", 12: "chromaticity-derived non-constant luminance",
+	13: "chromaticity-derived constant luminance", 14: "ICtCp",
+}
+
+// fields returns the colour fields of c as name/value pairs, in the order
+// view lists them.
+func (c *trackColor) fields() [][2]string {
+	if c == nil {
+		return nil
+	}
+	var out [][2]string
+	add := func(name, val string) { out = append(out, [2]string{name, val}) }
+	code := func(names map[int]string, v int) string {
+		if n, ok := names[v]; ok {
+			return fmt.Sprintf("%s (%d)", n, v)
+		}
+		return strconv.Itoa(v)
+	}
+
+	if hdr := c.hdr(); hdr != "" {
+		add("HDR", hdr)
+	}
+	if c.primaries >= 0 {
+		add("ColorPrimaries", code(colorPrimaries, c.primaries))
+	}
+	if c.transfer >= 0 {
+		add("TransferCharacteristics", code(colorTransfers, c.transfer))
+	}
+	if c.matrix >= 0 {
+		add("MatrixCoefficients", code(colorMatrices, c.matrix))
+	}
+	switch c.fullRange {
+	case 0:
+		add("ColorRange", "limited")
+	case 1:
+		add("ColorRange", "full")
+	}
+	if c.icc > 0 {
+		add("ICCProfile", fmt.Sprintf("embedded (%d bytes)", c.icc))
+	}
+
+	var prim []string
+	for i, label := range []string{"R", "G", "B", "WP"} {
+		x, y := c.display[2*i], c.display[2*i+1]
+		if !math.IsNaN(x) && !math.IsNaN(y) {
+			prim = append(prim, fmt.Sprintf("%s %.4f,%.4f", label, x, y))
+		}
+	}
+	if len(prim) > 0 {
+		add("MasteringDisplayPrimaries", strings.Join(prim, " "))
+	}
+	if !math.IsNaN(c.maxLum) {
+		add("MasteringDisplayMaxLuminance", luminance(c.maxLum))
+	}
+	if !math.IsNaN(c.minLum) {
+		add("MasteringDisplayMinLuminance", luminance(c.minLum))
+	}
+	if c.maxCLL >= 0 {
+		add("MaxCLL", luminance(float64(c.maxCLL)))
+	}
+	if c.maxFALL >= 0 {
+		add("MaxFALL", luminance(float64(c.maxFALL)))
+	}
+	return out
+}
+
+// hdr names the kind of HDR the track is, "" for SDR or when the file does
+// not say.
+func (c *trackColor) hdr() string {
+	var kind string
+	switch c.transfer {
+	case 16:
+		kind = "PQ"
+		if c.primaries == 9 {
+			kind = "HDR10"
+		}
+	case 18:
+		kind = "HLG"
+	}
+	if c.dolbyVision {
+		if kind == "" {
+			return "Dolby Vision"
+		}
+		return "Dolby Vision, " + kind + " compatible"
+	}
+	return kind
+}
+
+func luminance(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + " cd/m²"
+}
+
+// ─── MP4 colour boxes ────────────────────────────────────────────────────────
+
+// visualSampleEntrySize is the fixed part of a VisualSampleEntry after its
+// box header, up to the first child box.
+const visualSampleEntrySize = 78
+
+// parseMP4SampleColor reads the colour boxes of the first sample entry of
+// an stsd payload, nil when it has none.
+func parseMP4SampleColor(stsd []byte) *trackColor {
+	if len(stsd) < 16 {
+		return nil
+	}
+	entry := 8
+	size := int(binary.BigEndian.Uint32(stsd[entry : entry+4]))
+	end := entry + size
+	if size < 8 || end > len(stsd) {
+		end = len(stsd)
+	}
+	var c *trackColor
+	for _, b := range mp4ChildSpans(stsd, entry+8+visualSampleEntrySize, end) {
+		p := stsd[b.body:b.end]
+		switch b.typ {
+		case "colr":
+			if len(p) < 4 {
+				continue
+			}
+			if c == nil {
+				c = newTrackColor()
+			}
+			switch string(p[:4]) {
+			case "nclx", "nclc":
+				if len(p) < 10 {
+					continue
+				}
+				c.primaries = int(binary.BigEndian.Uint16(p[4:6]))
+				c.transfer = int(binary.BigEndian.Uint16(p[6:8]))
+				c.matrix = int(binary.BigEndian.Uint16(p[8:10]))
+				if string(p[:4]) == "nclx" && len(p) >= 11 {
+					c.fullRange = int(p[10] >> 7)
+				}
+			case "prof", "rICC":
+				c.icc = len(p) - 4
+			}
+		case "mdcv":
+			// Green, blue, red, then the white point, in units of 0.00002;
+			// luminance in units of 0.0001 cd/m².
+			if len(p) < 24 {
+				continue
+			}
+			if c == nil {
+				c = newTrackColor()
+			}
+			for i, slot := range []int{1, 2, 0, 3} {
+				c.display[2*slot] = float64(binary.BigEndian.Uint16(p[4*i:])) / 50000
+				c.display[2*slot+1] = float64(binary.BigEndian.Uint16(p[4*i+2:])) / 50000
+			}
+			c.maxLum = float64(binary.BigEndian.Uint32(p[16:20])) / 10000
+			c.minLum = float64(binary.BigEndian.Uint32(p[20:24])) / 10000
+		case "clli":
+			if len(p) < 4 {
+				continue
+			}
+			if c == nil {
+				c = newTrackColor()
+			}
+			c.maxCLL = int(binary.BigEndian.Uint16(p[0:2]))
+			c.maxFALL = int(binary.BigEndian.Uint16(p[2:4]))
+		case "dvcC", "dvvC", "dvwC":
+			if c == nil {
+				c = newTrackColor()
+			}
+			c.dolbyVision = true
+		}
+	}
+	return c
+}
+
+// ─── Matroska Colour ─────────────────────────────────────────────────────────
+
+const (
+	ebmlIDVideo             = 0xE0
+	ebmlIDColour            = 0x55B0
+	ebmlIDMatrixCoeffs      = 0x55B1
+	ebmlIDColourRange       = 0x55B9
+	ebmlIDTransferChars     = 0x55BA
+	ebmlIDPrimaries         = 0x55BB
+	ebmlIDMaxCLL            = 0x55BC
+	ebmlIDMaxFALL           = 0x55BD
+	ebmlIDMasteringMetadata = 0x55D0
+	ebmlIDLuminanceMax      = 0x55D9
+	ebmlIDLuminanceMin      = 0x55DA
+)
+
+// parseEBMLVideoColour returns the Colour of a TrackEntry's Video payload,
+// nil when it has none.
+func parseEBMLVideoColour(video []byte) *trackColor {
+	var c *trackColor
+	walkEBML(video, func(id uint32, p []byte) {
+		if id != ebmlIDColour {
+			return
+		}
+		c = newTrackColor()
+		walkEBML(p, func(id uint32, p []byte) {
+			switch id {
+			case ebmlIDMatrixCoeffs:
+				c.matrix = int(ebmlUint(p))
+			case ebmlIDTransferChars:
+				c.transfer = int(ebmlUint(p))
+			case ebmlIDPrimaries:
+				c.primaries = int(ebmlUint(p))
+			case ebmlIDColourRange:
+				// 1 broadcast, 2 full; 0 and 3 leave it to the codec.
+				switch ebmlUint(p) {
+				case 1:
+					c.fullRange = 0
+				case 2:
+					c.fullRange = 1
+				}
+			case ebmlIDMaxCLL:
+				c.maxCLL = int(ebmlUint(p))
+			case ebmlIDMaxFALL:
+				c.maxFALL = int(ebmlUint(p))
+			case ebmlIDMasteringMetadata:
+				walkEBML(p, func(id uint32, p []byte) {
+					v, ok := ebmlFloat(p)
+					switch {
+					case !ok:
+					case id >= 0x55D1 && id <= 0x55D8:
+						// PrimaryRChromaticityX … WhitePointChromaticityY
+						c.display[id-0x55D1] = v
+					case id == ebmlIDLuminanceMax:
+						c.maxLum = v
+					case id == ebmlIDLuminanceMin:
+						c.minLum = v
+					}
+				})
+			}
+		})
+	})
+	return c
+}
+
+// walkEBML calls fn for each element laid out back to back in data.
+func walkEBML(data []byte, fn func(id uint32, payload []byte)) {
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		i += idLen
+		size, sLen := readEBMLSize(data, i)
+		i += sLen
+		if size < 0 || i+int(size) > len(data) {
+			return
+		}
+		fn(id, data[i:i+int(size)])
+		i += int(size)
+	}
+}
+
+func ebmlUint(p []byte) uint64 {
+	var v uint64
+	for _, c := range p {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// ebmlFloat decodes a 4- or 8-byte EBML float.
+func ebmlFloat(p []byte) (float64, bool) {
+	switch len(p) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(p))), true
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(p)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,135 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Fragmented MP4 (fMP4 / DASH / CMAF) ─────────────────────────────────────
+// A fragmented file has an "init segment" (ftyp + moov with an mvex box and
+// empty sample tables) followed by any number of moof+mdat pairs. sidx and
+// mfra/tfra boxes hold byte offsets into the fragments, so growing or
+// shrinking moov would silently invalidate them.
+
+type mp4FragmentInfo struct {
+	hasMvex bool
+	moofs   int
+	hasSidx bool
+	hasMfra bool
+}
+
+func (fi mp4FragmentInfo) fragmented() bool {
+	return fi.hasMvex || fi.moofs > 0
+}
+
+// scanMP4Fragments walks the top-level boxes (and the direct children of
+// moov) looking for fragmentation markers.
+func scanMP4Fragments(r io.ReadSeeker) mp4FragmentInfo {
+	var fi mp4FragmentInfo
+	r.Seek(0, io.SeekStart)
+	for {
+		typ, dataSize, ok := readMP4BoxHeader(r)
+		if !ok {
+			break
+		}
+		switch typ {
+		case "moof":
+			fi.moofs++
+		case "sidx":
+			fi.hasSidx = true
+		case "mfra":
+			fi.hasMfra = true
+		case "moov":
+			end, _ := r.Seek(0, io.SeekCurrent)
+			end += dataSize
+			for {
+				pos, _ := r.Seek(0, io.SeekCurrent)
+				if pos >= end {
+					break
+				}
+				ctyp, csize, ok := readMP4BoxHeader(r)
+				if !ok {
+					break
+				}
+				if ctyp == "mvex" {
+					fi.hasMvex = true
+				}
+				r.Seek(csize, io.SeekCurrent)
+			}
+			r.Seek(end, io.SeekStart)
+			continue
+		}
+		if dataSize < 0 {
+			break // box extends to end of file
+		}
+		if _, err := r.Seek(dataSize, io.SeekCurrent); err != nil {
+			break
+		}
+	}
+	return fi
+}
+
+// readMP4BoxHeader reads a box header and returns its type and payload size.
+// A payload size of -1 means the box runs to the end of the file.
+func readMP4BoxHeader(r io.Reader) (typ string, dataSize int64, ok bool) {
+	hdr := make([]byte, 8)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return "", 0, false
+	}
+	size := int64(binary.BigEndian.Uint32(hdr[0:4]))
+	typ = string(hdr[4:8])
+	switch size {
+	case 0:
+		return typ, -1, true
+	case 1:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return "", 0, false
+		}
+		size = int64(binary.BigEndian.Uint64(ext))
+		if size < 16 {
+			return "", 0, false
+		}
+		return typ, size - 16, true
+	}
+	if size < 8 {
+		return "", 0, false
+	}
+	return typ, size - 8, true
+}
+
+func addMP4FragmentInfo(r io.ReadSeeker, m *core.Metadata) {
+	fi := scanMP4Fragments(r)
+	if !fi.fragmented() {
+		return
+	}
+	add := func(k, v string) {
+		m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "MP4 Fragments", Editable: false})
+	}
+	add("Fragmented", "true")
+	add("Fragments", fmt.Sprintf("%d", fi.moofs))
+	if fi.moofs == 0 {
+		add("Layout", "init segment only")
+	}
+	if fi.hasSidx {
+		add("SegmentIndex", "sidx")
+	}
+	if fi.hasMfra {
+		add("RandomAccess", "mfra")
+	}
+}
+
+// checkMP4Rewritable refuses to rewrite fragmented files that carry media
+// fragments: their sidx/mfra/tfra offsets would no longer match once moov
+// changes size. A bare init segment (moov with mvex and nothing after it)
+// is safe to edit.
+func checkMP4Rewritable(r io.ReadSeeker) error {
+	fi := scanMP4Fragments(r)
+	if fi.moofs > 0 || fi.hasSidx || fi.hasMfra {
+		return fmt.Errorf("fragmented MP4 (%d moof fragments): metadata rewrite is not supported because it would invalidate fragment offsets; edit the init segment instead", fi.moofs)
+	}
+	return nil
+}
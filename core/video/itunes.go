@@ -0,0 +1,263 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ─── Typed iTunes atoms ──────────────────────────────────────────────────────
+// Most ilst items hold UTF-8 text (type indicator 1), but the TV and media
+// kind atoms that Plex, Jellyfin and Apple TV sort by are big-endian
+// integers (type indicator 21) of a fixed width. Writing them as text makes
+// those players ignore the value.
+
+const (
+	itunesTypeUTF8 = 1
+	itunesTypeInt  = 21
+)
+
+// itunesIntAtoms gives the byte width of each integer-typed atom.
+var itunesIntAtoms = map[string]int{
+	"tvsn": 4, // season
+	"tves": 4, // episode
+	"stik": 1, // media kind
+	"tmpo": 2, // BPM
+	"cpil": 1, // compilation flag
+	"hdvd": 1, // 0 SD, 1 720p, 2 1080p
+	"rtng": 1, // 0 none, 1 explicit, 2 clean
+}
+
+// itunesPairAtoms gives the payload width of the track and disc number
+// atoms: two reserved bytes, the number and the total as 16-bit integers,
+// and for trkn two more reserved bytes. They carry no type indicator.
+var itunesPairAtoms = map[string]int{
+	"trkn": 8,
+	"disk": 6,
+}
+
+// mediaKinds are the stik values iTunes defines.
+var mediaKinds = map[int64]string{
+	0:  "Movie (legacy)",
+	1:  "Music",
+	2:  "Audiobook",
+	6:  "Music Video",
+	9:  "Movie",
+	10: "TV Show",
+	11: "Booklet",
+	14: "Ringtone",
+	21: "Podcast",
+	23: "iTunes U",
+}
+
+// itunesDataValue decodes the value of a data atom belonging to atom.
+// Integers with no type indicator are accepted for the atoms that are
+// always integers, since some taggers write them that way.
+func itunesDataValue(atom string, data []byte) string {
+	// data atom: 4 size + 4 "data" + 1 version + 3 type + 4 locale + value
+	if len(data) < 16 || string(data[4:8]) != "data" {
+		return ""
+	}
+	typ := binary.BigEndian.Uint32(data[8:12]) & 0xFFFFFF
+	payload := data[16:]
+	if size := int(binary.BigEndian.Uint32(data[0:4])); size >= 16 && size <= len(data) {
+		payload = data[16:size]
+	}
+	if width, ok := itunesPairAtoms[atom]; ok {
+		if len(payload) < width {
+			return ""
+		}
+		n, total := binary.BigEndian.Uint16(payload[2:4]), binary.BigEndian.Uint16(payload[4:6])
+		if total > 0 {
+			return fmt.Sprintf("%d/%d", n, total)
+		}
+		return strconv.Itoa(int(n))
+	}
+	if typ == itunesTypeInt || (typ == 0 && itunesIntAtoms[atom] > 0) {
+		n, ok := decodeBEInt(payload)
+		if !ok {
+			return ""
+		}
+		if atom == "stik" {
+			if name, ok := mediaKinds[n]; ok {
+				return name
+			}
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	return strings.TrimRight(string(payload), "\x00")
+}
+
+func decodeBEInt(b []byte) (int64, bool) {
+	switch len(b) {
+	case 1:
+		return int64(int8(b[0])), true
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(b))), true
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(b))), true
+	case 8:
+		return int64(binary.BigEndian.Uint64(b)), true
+	}
+	return 0, false
+}
+
+// buildiTunesValueAtom builds the data atom for atom, encoding integer
+// atoms at their fixed width and everything else as UTF-8 text.
+func buildiTunesValueAtom(atom, val string) ([]byte, error) {
+	if width, ok := itunesPairAtoms[atom]; ok {
+		return buildiTunesPairAtom(atom, val, width)
+	}
+	width, ok := itunesIntAtoms[atom]
+	if !ok {
+		return buildiTunesDataAtom(val), nil
+	}
+	n, err := parseiTunesInt(atom, val)
+	if err != nil {
+		return nil, err
+	}
+	if limit := int64(1) << (8*width - 1); n < -limit || n >= limit {
+		return nil, fmt.Errorf("%s: %d does not fit in %d byte(s)", itunesAtomNames[atom], n, width)
+	}
+	d := make([]byte, 16+width)
+	binary.BigEndian.PutUint32(d[0:4], uint32(len(d)))
+	copy(d[4:8], "data")
+	d[11] = itunesTypeInt
+	for i := 0; i < width; i++ {
+		d[16+i] = byte(n >> (8 * (width - 1 - i)))
+	}
+	return d, nil
+}
+
+// parseiTunesInt reads an integer value; MediaKind also takes the kind's
+// name ("TV Show", "movie", …) and Compilation takes true/false.
+func parseiTunesInt(atom, val string) (int64, error) {
+	val = strings.TrimSpace(val)
+	if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return n, nil
+	}
+	switch atom {
+	case "stik":
+		want := strings.ToLower(strings.ReplaceAll(val, " ", ""))
+		for n, name := range mediaKinds {
+			if strings.ToLower(strings.ReplaceAll(name, " ", "")) == want {
+				return n, nil
+			}
+		}
+		names := make([]string, 0, len(mediaKinds))
+		for _, name := range mediaKinds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return 0, fmt.Errorf("MediaKind: unknown kind %q (want a number or one of: %s)", val, strings.Join(names, ", "))
+	case "cpil":
+		if b, err := strconv.ParseBool(val); err == nil {
+			if b {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %q is not a whole number", itunesAtomNames[atom], val)
+}
+
+// buildIlstHdlr returns the hdlr box an iTunes metadata meta box needs
+// (handler 'mdir', manufacturer 'appl').
+func buildIlstHdlr() []byte {
+	payload := make([]byte, 4+4+4+12+1) // version/flags, pre_defined, type, reserved, empty name
+	copy(payload[8:12], "mdir")
+	copy(payload[12:16], "appl")
+	return packAtom("hdlr", payload)
+}
+
+// buildiTunesPairAtom builds the data atom of trkn or disk from "3" or
+// "3/12".
+func buildiTunesPairAtom(atom, val string, width int) ([]byte, error) {
+	var nums [2]uint64
+	parts := strings.SplitN(strings.TrimSpace(val), "/", 2)
+	for i, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number or number/total", itunesAtomNames[atom], val)
+		}
+		nums[i] = n
+	}
+	d := make([]byte, 16+width)
+	binary.BigEndian.PutUint32(d[0:4], uint32(len(d)))
+	copy(d[4:8], "data")
+	binary.BigEndian.PutUint16(d[18:20], uint16(nums[0]))
+	binary.BigEndian.PutUint16(d[20:22], uint16(nums[1]))
+	return d, nil
+}
+
+// ─── ilst items ──────────────────────────────────────────────────────────────
+// An edit key names an ilst item by its four-byte atom type, or a freeform
+// item as "----:mean:name" (for example ----:com.apple.iTunes:ISRC). Cover
+// art lives in covr, whose data atoms hold the image with type indicator
+// 13 for JPEG and 14 for PNG.
+
+const (
+	itunesTypeJPEG = 13
+	itunesTypePNG  = 14
+)
+
+// ilstType returns the atom type of the item key names.
+func ilstType(key string) string {
+	if strings.HasPrefix(key, "----:") {
+		return "----"
+	}
+	return key
+}
+
+// buildIlstAtom builds the complete ilst item for key set to val.
+func buildIlstAtom(key, val string) ([]byte, error) {
+	if strings.HasPrefix(key, "----:") {
+		full := key[len("----:"):]
+		i := strings.LastIndex(full, ":")
+		if i <= 0 || i == len(full)-1 {
+			return nil, fmt.Errorf("freeform key %q must be ----:mean:name", key)
+		}
+		return buildFreeformAtom(full[:i], full[i+1:], val), nil
+	}
+	if key == "covr" {
+		return nil, fmt.Errorf("cover art is embedded with 'surgery cover set', not edit")
+	}
+	if len(key) != 4 {
+		return nil, fmt.Errorf("%q is neither a known field nor a four-character atom name", key)
+	}
+	d, err := buildiTunesValueAtom(key, val)
+	if err != nil {
+		return nil, err
+	}
+	return packAtom(key, d), nil
+}
+
+// buildFreeformAtom builds a ---- item holding mean, name and a UTF-8 value.
+func buildFreeformAtom(mean, name, val string) []byte {
+	var b []byte
+	b = append(b, packAtom("mean", append([]byte{0, 0, 0, 0}, mean...))...)
+	b = append(b, packAtom("name", append([]byte{0, 0, 0, 0}, name...))...)
+	b = append(b, buildiTunesDataAtom(val)...)
+	return packAtom("----", b)
+}
+
+// buildCovrAtom builds a covr item holding one JPEG or PNG image.
+func buildCovrAtom(mime string, img []byte) ([]byte, error) {
+	var typ byte
+	switch mime {
+	case "image/jpeg", "image/jpg":
+		typ = itunesTypeJPEG
+	case "image/png":
+		typ = itunesTypePNG
+	default:
+		return nil, fmt.Errorf("cover art in MP4 must be JPEG or PNG, not %s", mime)
+	}
+	d := make([]byte, 16+len(img))
+	binary.BigEndian.PutUint32(d[0:4], uint32(len(d)))
+	copy(d[4:8], "data")
+	d[11] = typ
+	copy(d[16:], img)
+	return packAtom("covr", d), nil
+}
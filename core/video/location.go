@@ -0,0 +1,147 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Location ────────────────────────────────────────────────────────────────
+// Phones and cameras record where a video was shot in one of three places:
+//   udta/©xyz   QuickTime text, an ISO 6709 string such as +48.8584+002.2945/
+//   udta/loci   3GPP location: name, role, and 16.16 fixed-point longitude,
+//               latitude and altitude
+//   meta keys   com.apple.quicktime.location.ISO6709 and its siblings
+//               (accuracy, body, name, note, role) in the mdta meta box
+// ©xyz may also sit in ilst as an iTunes-style item with a data atom. strip
+// --gps-only removes all of them from moov and from each track's udta, and
+// nothing else.
+
+// mdtaLocationPrefix starts the mdta keys that describe the location.
+const mdtaLocationPrefix = "com.apple.quicktime.location."
+
+// addMP4Location appends the fields of a ©xyz or loci atom body.
+func addMP4Location(atom string, body []byte, m *core.Metadata) {
+	add := func(k, v string) {
+		if v != "" {
+			m.Fields = append(m.Fields, core.MetaField{Key: k, Value: v, Category: "MP4 Location"})
+		}
+	}
+	switch atom {
+	case "\xa9xyz":
+		add("Location", quickTimeText(body))
+	case "loci":
+		name, coords := parseLoci(body)
+		add("LocationName", name)
+		add("LocationCoordinates", coords)
+	}
+}
+
+// quickTimeText decodes a udta text atom: a 16-bit length, a 16-bit
+// language code and the text, or in ilst an iTunes data atom.
+func quickTimeText(body []byte) string {
+	if len(body) >= 16 && string(body[4:8]) == "data" {
+		return itunesDataValue("\xa9xyz", body)
+	}
+	if len(body) < 4 {
+		return ""
+	}
+	n := int(binary.BigEndian.Uint16(body[0:2]))
+	if 4+n > len(body) {
+		n = len(body) - 4
+	}
+	return strings.TrimRight(string(body[4:4+n]), "\x00")
+}
+
+// parseLoci returns the place name and "latitude, longitude[, altitude]"
+// of a 3GPP loci atom body.
+func parseLoci(body []byte) (name, coords string) {
+	// version/flags(4) language(2) name\0 role(1) long(4) lat(4) alt(4) …
+	if len(body) < 7 {
+		return "", ""
+	}
+	p := 6
+	end := p
+	for end < len(body) && body[end] != 0 {
+		end++
+	}
+	name = string(body[p:end])
+	p = end + 1 + 1 // terminator, role
+	if p+12 > len(body) {
+		return name, ""
+	}
+	fixed := func(b []byte) string {
+		// 16.16 fixed point resolves about a metre; five decimals show it
+		v := float64(int32(binary.BigEndian.Uint32(b))) / 65536
+		s := strings.TrimRight(strconv.FormatFloat(v, 'f', 5, 64), "0")
+		return strings.TrimSuffix(s, ".")
+	}
+	coords = fixed(body[p+4:p+8]) + ", " + fixed(body[p:p+4])
+	if alt := int32(binary.BigEndian.Uint32(body[p+8 : p+12])); alt != 0 {
+		coords += ", " + fixed(body[p+8:p+12])
+	}
+	return name, coords
+}
+
+// stripMP4GPS returns data without its location atoms and mdta location
+// keys; every other box is kept byte for byte.
+func stripMP4GPS(data []byte) ([]byte, error) {
+	for {
+		chain := findMP4Location(data)
+		if chain == nil {
+			break
+		}
+		box := chain[len(chain)-1]
+		data = replaceMP4Range(data, chain[:len(chain)-1], box.start, box.end, nil)
+	}
+	var del []string
+	for _, k := range mdtaKeyNames(data) {
+		if strings.HasPrefix(strings.ToLower(k), mdtaLocationPrefix) {
+			del = append(del, k)
+		}
+	}
+	if len(del) == 0 {
+		return data, nil
+	}
+	out, err := patchMP4Keys(data, nil, del)
+	if err != nil {
+		return nil, fmt.Errorf("removing location keys: %w", err)
+	}
+	return out, nil
+}
+
+// findMP4Location returns the chain of boxes, from moov down, ending at
+// the first ©xyz or loci atom in moov/udta, moov/udta/meta/ilst or a
+// track's udta, or nil when there is none.
+func findMP4Location(data []byte) []mp4Span {
+	moov := findMP4Path(data, 0, len(data), "moov")
+	if moov == nil {
+		return nil
+	}
+	var parents [][]mp4Span
+	for _, b := range mp4ChildSpans(data, moov[0].body, moov[0].end) {
+		switch b.typ {
+		case "udta":
+			parents = append(parents, []mp4Span{moov[0], b})
+			if ilst := findMP4Path(data, b.body, b.end, "meta", "ilst"); ilst != nil {
+				parents = append(parents, append([]mp4Span{moov[0], b}, ilst...))
+			}
+		case "trak":
+			if udta := findMP4Path(data, b.body, b.end, "udta"); udta != nil {
+				parents = append(parents, []mp4Span{moov[0], b, udta[0]})
+			}
+		}
+	}
+	for _, chain := range parents {
+		parent := chain[len(chain)-1]
+		for _, c := range mp4ChildSpans(data, parent.body, parent.end) {
+			if c.typ == "\xa9xyz" || c.typ == "loci" {
+				return append(append([]mp4Span{}, chain...), c)
+			}
+		}
+	}
+	return nil
+}
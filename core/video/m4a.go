@@ -0,0 +1,56 @@
+package video
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Shared with M4A ─────────────────────────────────────────────────────────
+// An M4A is an MP4 holding one audio track, and its tags are the same
+// moov/udta/meta/ilst items. The audio handler edits and strips them with
+// the functions below, so both handlers write ilst through one code path
+// and the same rewrite checks.
+
+// EditISOBMFF sets and deletes ilst items in an MP4-family file. Keys are
+// iTunes friendly names (Title, TrackNumber, …), atom types (©nam, trkn)
+// or freeform items as ----:mean:name.
+func EditISOBMFF(path, outPath string, opts core.EditOptions) error {
+	return editMP4(path, outPath, opts, false)
+}
+
+// StripISOBMFF removes the metadata of an MP4-family file as an MP4 strip
+// does; ilst items named in KeepFields are kept.
+func StripISOBMFF(path, outPath string, opts core.StripOptions) error {
+	return stripMP4(path, outPath, opts)
+}
+
+// SetISOBMFFCover replaces the covr item of an MP4-family file with one
+// JPEG or PNG image.
+func SetISOBMFFCover(path, outPath, mime string, img []byte) error {
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := checkMP4Rewritable(bytes.NewReader(orig)); err != nil {
+		return err
+	}
+	if err := checkMP4SizeLimits(orig); err != nil {
+		return err
+	}
+	covr, err := buildCovrAtom(mime, img)
+	if err != nil {
+		return err
+	}
+	data, err := rewriteMP4Ilst(orig, func(children []ilstChild) []ilstChild {
+		return setIlstChild(children, "covr", covr)
+	})
+	if err != nil {
+		return err
+	}
+	if err := verifyMP4Rewrite(orig, data); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
@@ -0,0 +1,345 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── QuickTime metadata keys (mdta) ──────────────────────────────────────────
+// iPhones and other Apple devices write metadata into a meta box with an
+// 'mdta' handler instead of udta/©xyz atoms:
+//   meta
+//     hdlr  handler type 'mdta'
+//     keys  version/flags, entry count, then [size, 'mdta', key name]…
+//     ilst  items typed by the 1-based index of their key, each holding
+//           a 'data' atom (type indicator, locale, value)
+// Key names are reverse-DNS strings such as com.apple.quicktime.make.
+
+// mdtaItem is one keyed value. body is the item's payload (its data atom and
+// anything else) kept raw so untouched items are written back exactly.
+type mdtaItem struct {
+	key  string
+	body []byte
+}
+
+// mdtaMeta is the parsed content of an mdta meta box.
+type mdtaMeta struct {
+	keys  []string
+	items []mdtaItem
+}
+
+// parseMdtaKeys reads the key names of a keys box payload.
+func parseMdtaKeys(payload []byte) []string {
+	if len(payload) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(payload[4:8]))
+	var keys []string
+	pos := 8
+	for i := 0; i < count && pos+8 <= len(payload); i++ {
+		size := int(binary.BigEndian.Uint32(payload[pos : pos+4]))
+		if size < 8 || pos+size > len(payload) {
+			break
+		}
+		keys = append(keys, string(payload[pos+8:pos+size]))
+		pos += size
+	}
+	return keys
+}
+
+// parseMdtaMeta reads the keys and ilst children of a meta box body. ok is
+// false when there is no keys box, i.e. the meta box is not mdta-keyed.
+func parseMdtaMeta(data []byte, start, end int) (mm mdtaMeta, ok bool) {
+	var ilst *mp4Span
+	for _, b := range mp4ChildSpans(data, start, end) {
+		switch b.typ {
+		case "keys":
+			mm.keys = parseMdtaKeys(data[b.body:b.end])
+			ok = true
+		case "ilst":
+			b := b
+			ilst = &b
+		}
+	}
+	if !ok || ilst == nil {
+		return mm, ok
+	}
+	for _, it := range mp4ChildSpans(data, ilst.body, ilst.end) {
+		idx := int(binary.BigEndian.Uint32(data[it.start+4 : it.start+8]))
+		if idx < 1 || idx > len(mm.keys) {
+			continue
+		}
+		mm.items = append(mm.items, mdtaItem{key: mm.keys[idx-1], body: data[it.body:it.end]})
+	}
+	return mm, true
+}
+
+// value decodes the item's first data atom according to its well-known type.
+func (it mdtaItem) value() string {
+	for _, b := range mp4ChildSpans(it.body, 0, len(it.body)) {
+		if b.typ != "data" || b.body+8 > b.end {
+			continue
+		}
+		typ := binary.BigEndian.Uint32(it.body[b.body:b.body+4]) & 0x00FFFFFF
+		return formatMdtaValue(typ, it.body[b.body+8:b.end])
+	}
+	return ""
+}
+
+func formatMdtaValue(typ uint32, v []byte) string {
+	switch typ {
+	case 1: // UTF-8
+		return strings.TrimRight(string(v), "\x00")
+	case 2: // UTF-16BE
+		u := make([]uint16, len(v)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(v[2*i:])
+		}
+		return string(utf16.Decode(u))
+	case 21: // big-endian signed integer
+		switch len(v) {
+		case 1:
+			return fmt.Sprintf("%d", int8(v[0]))
+		case 2:
+			return fmt.Sprintf("%d", int16(binary.BigEndian.Uint16(v)))
+		case 4:
+			return fmt.Sprintf("%d", int32(binary.BigEndian.Uint32(v)))
+		case 8:
+			return fmt.Sprintf("%d", int64(binary.BigEndian.Uint64(v)))
+		}
+	case 22: // big-endian unsigned integer
+		switch len(v) {
+		case 1:
+			return fmt.Sprintf("%d", v[0])
+		case 2:
+			return fmt.Sprintf("%d", binary.BigEndian.Uint16(v))
+		case 4:
+			return fmt.Sprintf("%d", binary.BigEndian.Uint32(v))
+		case 8:
+			return fmt.Sprintf("%d", binary.BigEndian.Uint64(v))
+		}
+	case 23: // float32
+		if len(v) == 4 {
+			return fmt.Sprintf("%g", math.Float32frombits(binary.BigEndian.Uint32(v)))
+		}
+	case 24: // float64
+		if len(v) == 8 {
+			return fmt.Sprintf("%g", math.Float64frombits(binary.BigEndian.Uint64(v)))
+		}
+	case 13, 14, 27:
+		return fmt.Sprintf("(image, %d bytes)", len(v))
+	}
+	return fmt.Sprintf("(binary, %d bytes)", len(v))
+}
+
+// addMdtaFields appends the keyed items of a meta box body, or reports false
+// if the box is not mdta-keyed.
+func addMdtaFields(body []byte, m *core.Metadata) bool {
+	mm, ok := parseMdtaMeta(body, 0, len(body))
+	if !ok {
+		return false
+	}
+	for _, it := range mm.items {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      it.key,
+			Value:    it.value(),
+			Category: "QuickTime Keys",
+			Editable: true,
+		})
+	}
+	return true
+}
+
+// ─── mdta writing ────────────────────────────────────────────────────────────
+
+// findMdtaMeta returns the first moov/meta box carrying a keys box.
+func findMdtaMeta(data []byte) (moov, meta mp4Span, ok bool) {
+	chain := findMP4Path(data, 0, len(data), "moov")
+	if chain == nil {
+		return mp4Span{}, mp4Span{}, false
+	}
+	moov = chain[0]
+	for _, b := range mp4ChildSpans(data, moov.body, moov.end) {
+		if b.typ != "meta" {
+			continue
+		}
+		if _, isMdta := parseMdtaMeta(data, b.body, b.end); isMdta {
+			return moov, b, true
+		}
+	}
+	return moov, mp4Span{}, false
+}
+
+// mdtaKeyNames lists the keys declared in the file's moov/meta keys box.
+func mdtaKeyNames(data []byte) []string {
+	_, meta, ok := findMdtaMeta(data)
+	if !ok {
+		return nil
+	}
+	mm, _ := parseMdtaMeta(data, meta.body, meta.end)
+	return mm.keys
+}
+
+// isMdtaKey reports whether an edit key addresses a keyed item: a key the
+// file already declares, or any reverse-DNS name (com.apple.quicktime.make).
+// Freeform iTunes names ("com.apple.iTunes:NAME") keep going to ilst.
+func isMdtaKey(key string, existing []string) bool {
+	for _, k := range existing {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return strings.Contains(key, ".") && !strings.ContainsAny(key, ": ")
+}
+
+// patchMP4Keys sets and deletes keyed items in moov/meta, creating the mdta
+// meta box when needed. Keys left without an item are dropped and the
+// remaining items renumbered.
+func patchMP4Keys(data []byte, set map[string]string, del []string) ([]byte, error) {
+	moov, meta, found := findMdtaMeta(data)
+	if moov.typ == "" {
+		return nil, fmt.Errorf("could not find moov atom")
+	}
+	var mm mdtaMeta
+	if found {
+		mm, _ = parseMdtaMeta(data, meta.body, meta.end)
+	} else if len(set) == 0 {
+		return data, nil
+	}
+
+	var items []mdtaItem
+	for _, it := range mm.items {
+		drop := false
+		for _, k := range del {
+			if strings.EqualFold(it.key, k) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			items = append(items, it)
+		}
+	}
+	names := make([]string, 0, len(set))
+	for k := range set {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		body := buildiTunesDataAtom(set[k])
+		replaced := false
+		for i := range items {
+			if strings.EqualFold(items[i].key, k) {
+				items[i].body = body
+				replaced = true
+			}
+		}
+		if !replaced {
+			items = append(items, mdtaItem{key: k, body: body})
+		}
+	}
+
+	keysBox, ilstBox := buildMdtaBoxes(items)
+	if !found {
+		content := append(buildMdtaHdlr(), keysBox...)
+		content = append(content, ilstBox...)
+		return replaceMP4Range(data, []mp4Span{moov}, moov.end, moov.end, packAtom("meta", content)), nil
+	}
+
+	// Rebuild the meta body in place, keeping hdlr and any other children.
+	var body bytes.Buffer
+	wroteIlst := false
+	for _, b := range mp4ChildSpans(data, meta.body, meta.end) {
+		switch b.typ {
+		case "keys":
+			body.Write(keysBox)
+		case "ilst":
+			body.Write(ilstBox)
+			wroteIlst = true
+		default:
+			body.Write(data[b.start:b.end])
+		}
+	}
+	if !wroteIlst {
+		body.Write(ilstBox)
+	}
+	return replaceMP4Range(data, []mp4Span{moov, meta}, meta.body, meta.end, body.Bytes()), nil
+}
+
+// buildMdtaBoxes serialises items into a keys box and a matching ilst.
+func buildMdtaBoxes(items []mdtaItem) (keysBox, ilstBox []byte) {
+	var keys []string
+	index := map[string]int{}
+	var ilst bytes.Buffer
+	for _, it := range items {
+		idx, ok := index[it.key]
+		if !ok {
+			keys = append(keys, it.key)
+			idx = len(keys)
+			index[it.key] = idx
+		}
+		var name [4]byte
+		binary.BigEndian.PutUint32(name[:], uint32(idx))
+		ilst.Write(packAtom(string(name[:]), it.body))
+	}
+
+	var kb bytes.Buffer
+	kb.Write([]byte{0, 0, 0, 0}) // version/flags
+	binary.Write(&kb, binary.BigEndian, uint32(len(keys)))
+	for _, k := range keys {
+		binary.Write(&kb, binary.BigEndian, uint32(8+len(k)))
+		kb.WriteString("mdta")
+		kb.WriteString(k)
+	}
+	return packAtom("keys", kb.Bytes()), packAtom("ilst", ilst.Bytes())
+}
+
+// buildMdtaHdlr returns a QuickTime hdlr box with handler type 'mdta'.
+func buildMdtaHdlr() []byte {
+	payload := make([]byte, 4+4+4+12+1) // version/flags, pre_defined, type, reserved, empty name
+	copy(payload[8:12], "mdta")
+	return packAtom("hdlr", payload)
+}
+
+// stripMP4Keys removes the mdta meta box from moov, or — when keep names
+// some keys — only the items not listed in keep.
+func stripMP4Keys(data []byte, keep []string) []byte {
+	moov, meta, found := findMdtaMeta(data)
+	if !found {
+		return data
+	}
+	if len(keep) == 0 {
+		return replaceMP4Range(data, []mp4Span{moov}, meta.start, meta.end, nil)
+	}
+	mm, _ := parseMdtaMeta(data, meta.body, meta.end)
+	var del []string
+	for _, it := range mm.items {
+		if !containsFold(keep, it.key) {
+			del = append(del, it.key)
+		}
+	}
+	if len(del) == len(mm.items) {
+		return replaceMP4Range(data, []mp4Span{moov}, meta.start, meta.end, nil)
+	}
+	out, err := patchMP4Keys(data, nil, del)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
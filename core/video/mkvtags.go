@@ -0,0 +1,679 @@
+package video
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Matroska Info and Tags ──────────────────────────────────────────────────
+// The Segment title and the SimpleTags are edited, and Tags and
+// Attachments stripped, without moving a Cluster: the SeekHead, the Cues
+// and every block keep their offsets. Info is rewritten in place like
+// Tracks (see patchMKVTracks), into its own length and the EBML Void after
+// it. Tags, which muxers write after the Clusters, is rewritten in place
+// when it fits and grows or shrinks freely when it ends the file; otherwise
+// it moves to the end of the Segment, a Void takes its old place and the
+// SeekHead is pointed at the new one. A removed element becomes a Void of
+// the same length, zeroed so the old values are gone, and Voids left at
+// the end of the file are cut off.
+
+const (
+	ebmlIDSeekHead        = 0x114D9B74
+	ebmlIDSeek            = 0x4DBB
+	ebmlIDSeekID          = 0x53AB
+	ebmlIDSeekPosition    = 0x53AC
+	ebmlIDCluster         = 0x1F43B675
+	ebmlIDAttachments     = 0x1941A469
+	ebmlIDTargetTypeValue = 0x68CA
+	ebmlIDTagBinary       = 0x4485
+)
+
+// mkvTargetUIDs are the Targets children that tie a Tag to a track,
+// edition, chapter or attachment; a Tag with none of them is global.
+var mkvTargetUIDs = []uint32{0x63C5, 0x63C9, 0x63C4, 0x63C6}
+
+// ebmlSegment is the first Segment of an in-memory file with its
+// top-level elements.
+type ebmlSegment struct {
+	ebmlElement
+	sizeAt, sizeLen int  // the Segment's size field
+	unknown         bool // live-written: the Segment runs to the end of the file
+	children        []ebmlElement
+}
+
+// readMKVSegment locates the first Segment of data and lists the elements
+// in it.
+func readMKVSegment(data []byte) (*ebmlSegment, error) {
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		if id == 0 {
+			break
+		}
+		size, sLen := readEBMLSize(data, i+idLen)
+		if sLen == 0 {
+			break
+		}
+		body := i + idLen + sLen
+		if id != ebmlIDSegment {
+			if size < 0 {
+				break
+			}
+			i = body + int(size)
+			continue
+		}
+		s := &ebmlSegment{sizeAt: i + idLen, sizeLen: sLen, unknown: size < 0}
+		s.ebmlElement = ebmlElement{id: id, start: i, body: body, end: len(data)}
+		if size >= 0 {
+			if int64(body)+size > int64(len(data)) {
+				return nil, fmt.Errorf("Matroska Segment is truncated")
+			}
+			s.end = body + int(size)
+		}
+		var ok bool
+		if s.children, ok = ebmlChildren(data, s.body, s.end); !ok {
+			return nil, fmt.Errorf("Matroska Segment is malformed or holds a live-written Cluster of unknown size; remux the file to edit its title or tags")
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("Matroska file has no Segment")
+}
+
+// find returns the first top-level element with id and the element after
+// it, if any.
+func (s *ebmlSegment) find(id uint32) (el ebmlElement, next *ebmlElement, ok bool) {
+	for i, c := range s.children {
+		if c.id != id {
+			continue
+		}
+		if i+1 < len(s.children) {
+			next = &s.children[i+1]
+		}
+		return c, next, true
+	}
+	return ebmlElement{}, nil, false
+}
+
+// room is the length el may take when rewritten in place: its own, and
+// that of the Void after it.
+func room(el ebmlElement, next *ebmlElement) int {
+	if next != nil && next.id == ebmlIDVoid {
+		return next.end - el.start
+	}
+	return el.end - el.start
+}
+
+// setEnd makes the Segment end at end by rewriting its size field in the
+// same width. A Segment of unknown size already runs to the end.
+func (s *ebmlSegment) setEnd(data []byte, end int) error {
+	if s.unknown {
+		return nil
+	}
+	n := int64(end - s.body)
+	if n >= 1<<(7*s.sizeLen)-1 {
+		return fmt.Errorf("Matroska Segment size does not fit its %d-byte size field; remux the file", s.sizeLen)
+	}
+	for i := s.sizeLen - 1; i >= 0; i-- {
+		data[s.sizeAt+i] = byte(n)
+		n >>= 8
+	}
+	data[s.sizeAt] |= 0x80 >> (s.sizeLen - 1)
+	return nil
+}
+
+// voidEBML returns an EBML Void of exactly n (at least 2) bytes.
+func voidEBML(n int) []byte {
+	for w := 1; w <= 8 && n-1-w >= 0; w++ {
+		if v := packEBML(ebmlIDVoid, make([]byte, n-1-w), w); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// ebmlIDBytes returns an element ID as it is written, marker bits and all.
+func ebmlIDBytes(id uint32) []byte {
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// ebmlUintBytes encodes v big-endian in the fewest bytes, at least one.
+func ebmlUintBytes(v uint64) []byte {
+	var out []byte
+	for ; v > 0; v >>= 8 {
+		out = append([]byte{byte(v)}, out...)
+	}
+	if out == nil {
+		out = []byte{0}
+	}
+	return out
+}
+
+// ebmlUintValue decodes a big-endian unsigned integer payload.
+func ebmlUintValue(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// ─── Info ────────────────────────────────────────────────────────────────────
+
+// patchMKVInfo replaces the Info children with one of ids by repl (nil
+// removes them) and writes Info back in place.
+func patchMKVInfo(data []byte, repl []byte, ids ...uint32) error {
+	s, err := readMKVSegment(data)
+	if err != nil {
+		return err
+	}
+	info, next, ok := s.find(ebmlIDInfo)
+	if !ok {
+		return fmt.Errorf("Matroska file has no Info element")
+	}
+	if _, ok := ebmlChildren(data, info.body, info.end); !ok {
+		return fmt.Errorf("Matroska Info element is malformed")
+	}
+	payload, _ := replaceEBMLChildren(data[info.body:info.end], repl, ids...)
+	fixEBMLCRC(payload)
+	out, ok := fitEBML(ebmlIDInfo, payload, room(info, next))
+	if !ok {
+		grow := len(packEBML(ebmlIDInfo, payload, 0)) - room(info, next)
+		return fmt.Errorf("Matroska Info element would grow by %d bytes and there is no padding (EBML Void) after it to take them; remux the file to change the title", grow)
+	}
+	copy(data[info.start:], out)
+	return nil
+}
+
+// ─── Tags ────────────────────────────────────────────────────────────────────
+
+// simpleTagName returns the TagName of a SimpleTag payload.
+func simpleTagName(b []byte) string {
+	children, _ := ebmlChildren(b, 0, len(b))
+	for _, c := range children {
+		if c.id == ebmlIDTagName {
+			return string(b[c.body:c.end])
+		}
+	}
+	return ""
+}
+
+// isGlobalTag reports whether a Tag payload applies to the whole file.
+func isGlobalTag(b []byte) bool {
+	children, _ := ebmlChildren(b, 0, len(b))
+	for _, c := range children {
+		if c.id != ebmlIDTargets {
+			continue
+		}
+		targets, _ := ebmlChildren(b, c.body, c.end)
+		for _, t := range targets {
+			for _, uid := range mkvTargetUIDs {
+				if t.id == uid && ebmlUintValue(b[t.body:t.end]) != 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// editMKVTag returns a Tag payload without the SimpleTags drop matches and
+// with set applied: an existing SimpleTag of the name gets the new
+// TagString, others are added. empty reports that no SimpleTag is left.
+func editMKVTag(b []byte, set map[string]string, drop func(name string) bool) (out []byte, empty bool) {
+	children, _ := ebmlChildren(b, 0, len(b))
+	done := map[string]bool{}
+	n := 0
+	for _, c := range children {
+		if c.id != ebmlIDSimpleTag {
+			out = append(out, b[c.start:c.end]...)
+			continue
+		}
+		simple := b[c.body:c.end]
+		name := simpleTagName(simple)
+		if drop != nil && drop(name) {
+			continue
+		}
+		if k := setKey(set, name); k != "" && !done[k] {
+			done[k] = true
+			value := packEBML(ebmlIDTagString, []byte(set[k]), 0)
+			simple, _ = replaceEBMLChildren(simple, value, ebmlIDTagString, ebmlIDTagBinary)
+		}
+		out = append(out, packEBML(ebmlIDSimpleTag, simple, 0)...)
+		n++
+	}
+	for _, k := range core.SortedKeys(set) {
+		if done[k] {
+			continue
+		}
+		simple := append(packEBML(ebmlIDTagName, []byte(k), 0), packEBML(ebmlIDTagString, []byte(set[k]), 0)...)
+		out = append(out, packEBML(ebmlIDSimpleTag, simple, 0)...)
+		n++
+	}
+	fixEBMLCRC(out)
+	return out, n == 0
+}
+
+// setKey returns the key of set that names the tag name, ignoring case.
+func setKey(set map[string]string, name string) string {
+	for k := range set {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return ""
+}
+
+// rewriteMKVTags sets the SimpleTags of set in the first global Tag (a
+// new one if there is none), removes those drop matches from every Tag,
+// and writes the Tags of the file back as one element. A Tag left without
+// SimpleTags is dropped, and Tags with it when none is left.
+func rewriteMKVTags(data []byte, set map[string]string, drop func(name string) bool) ([]byte, error) {
+	s, err := readMKVSegment(data)
+	if err != nil {
+		return nil, err
+	}
+	var olds []ebmlElement
+	var payload, crc []byte
+	setDone := len(set) == 0
+	for _, c := range s.children {
+		if c.id != ebmlIDTags {
+			continue
+		}
+		children, ok := ebmlChildren(data, c.body, c.end)
+		if !ok {
+			return nil, fmt.Errorf("Matroska Tags element is malformed")
+		}
+		if len(olds) == 0 && len(children) > 0 && children[0].id == ebmlIDCRC32 {
+			crc = data[children[0].start:children[0].end]
+		}
+		olds = append(olds, c)
+		for _, t := range children {
+			if t.id != ebmlIDTag {
+				continue
+			}
+			tag := data[t.body:t.end]
+			var tagSet map[string]string
+			if !setDone && isGlobalTag(tag) {
+				tagSet, setDone = set, true
+			}
+			if tag, empty := editMKVTag(tag, tagSet, drop); !empty {
+				payload = append(payload, packEBML(ebmlIDTag, tag, 0)...)
+			}
+		}
+	}
+	if !setDone {
+		targets := packEBML(ebmlIDTargets, packEBML(ebmlIDTargetTypeValue, []byte{50}, 0), 0)
+		tag, _ := editMKVTag(targets, set, nil)
+		payload = append(payload, packEBML(ebmlIDTag, tag, 0)...)
+	}
+	if len(olds) == 0 && len(payload) == 0 {
+		return data, nil
+	}
+	if len(payload) > 0 && crc != nil {
+		payload = append(append([]byte{}, crc...), payload...)
+		fixEBMLCRC(payload)
+	}
+
+	// Every Tags element but the first becomes a Void; the first is
+	// rewritten where it is if it fits or ends the Segment, and is otherwise
+	// moved to the end.
+	for i := 1; i < len(olds); i++ {
+		old := olds[i]
+		copy(data[old.start:], voidEBML(old.end-old.start))
+	}
+	pos := -1
+	switch {
+	case len(payload) == 0:
+		copy(data[olds[0].start:], voidEBML(olds[0].end-olds[0].start))
+	case len(olds) > 0 && fitsMKV(data, s, olds[0], payload):
+		pos = olds[0].start - s.body
+	default:
+		if s.end != len(data) {
+			return nil, fmt.Errorf("Matroska Tags element would grow and cannot be moved to the end of the Segment, since data follows it; remux the file to change its tags")
+		}
+		at := s.end
+		if len(olds) > 0 {
+			if lastMKV(s, olds[0]) {
+				at = olds[0].start
+			} else {
+				copy(data[olds[0].start:], voidEBML(olds[0].end-olds[0].start))
+			}
+		}
+		data = append(data[:at], packEBML(ebmlIDTags, payload, 0)...)
+		if err := s.setEnd(data, len(data)); err != nil {
+			return nil, err
+		}
+		pos = at - s.body
+	}
+	if data, err = trimMKVVoids(data); err != nil {
+		return nil, err
+	}
+	return data, seekMKV(data, ebmlIDTags, pos)
+}
+
+// fitsMKV writes Tags with payload over old when it fits in old and the
+// Void after it, and reports whether it did.
+func fitsMKV(data []byte, s *ebmlSegment, old ebmlElement, payload []byte) bool {
+	var next *ebmlElement
+	for i, c := range s.children {
+		if c.start == old.start && i+1 < len(s.children) {
+			next = &s.children[i+1]
+		}
+	}
+	out, ok := fitEBML(ebmlIDTags, payload, room(old, next))
+	if ok {
+		copy(data[old.start:], out)
+	}
+	return ok
+}
+
+// lastMKV reports whether only Voids and other Tags, which are voided,
+// follow el in the Segment.
+func lastMKV(s *ebmlSegment, el ebmlElement) bool {
+	for _, c := range s.children {
+		if c.start > el.start && c.id != ebmlIDVoid && c.id != ebmlIDTags {
+			return false
+		}
+	}
+	return true
+}
+
+// voidMKV turns every top-level element with id into a Void and drops its
+// SeekHead entries.
+func voidMKV(data []byte, id uint32) error {
+	s, err := readMKVSegment(data)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.children {
+		if c.id == id {
+			copy(data[c.start:], voidEBML(c.end-c.start))
+		}
+	}
+	return seekMKV(data, id, -1)
+}
+
+// trimMKVVoids cuts the Voids that end a Segment which ends the file.
+func trimMKVVoids(data []byte) ([]byte, error) {
+	s, err := readMKVSegment(data)
+	if err != nil || s.end != len(data) {
+		return data, err
+	}
+	end := s.end
+	for i := len(s.children) - 1; i >= 0 && s.children[i].id == ebmlIDVoid; i-- {
+		end = s.children[i].start
+	}
+	if end == s.end {
+		return data, nil
+	}
+	return data[:end], s.setEnd(data, end)
+}
+
+// ─── SeekHead ────────────────────────────────────────────────────────────────
+
+// seekMKV points the SeekHead entry of the element id at pos, relative to
+// the Segment body, or removes it when pos is negative. A file without a
+// SeekHead is left without one: readers find the elements by scanning.
+func seekMKV(data []byte, id uint32, pos int) error {
+	s, err := readMKVSegment(data)
+	if err != nil {
+		return err
+	}
+	head, next, ok := s.find(ebmlIDSeekHead)
+	if !ok {
+		return nil
+	}
+	children, ok := ebmlChildren(data, head.body, head.end)
+	if !ok {
+		return fmt.Errorf("Matroska SeekHead is malformed")
+	}
+	idBytes := ebmlIDBytes(id)
+	var payload []byte
+	for _, c := range children {
+		if c.id == ebmlIDSeek && bytes.Equal(seekID(data[c.body:c.end]), idBytes) {
+			continue
+		}
+		payload = append(payload, data[c.start:c.end]...)
+	}
+	if pos >= 0 {
+		entry := append(packEBML(ebmlIDSeekID, idBytes, 0), packEBML(ebmlIDSeekPosition, ebmlUintBytes(uint64(pos)), 0)...)
+		payload = append(payload, packEBML(ebmlIDSeek, entry, 0)...)
+	}
+	fixEBMLCRC(payload)
+	out, ok := fitEBML(ebmlIDSeekHead, payload, room(head, next))
+	if !ok {
+		return fmt.Errorf("Matroska SeekHead has no room (EBML Void) for the new position of the tags; remux the file to change its tags")
+	}
+	copy(data[head.start:], out)
+	return nil
+}
+
+// seekID returns the SeekID of a Seek payload.
+func seekID(b []byte) []byte {
+	children, _ := ebmlChildren(b, 0, len(b))
+	for _, c := range children {
+		if c.id == ebmlIDSeekID {
+			return b[c.body:c.end]
+		}
+	}
+	return nil
+}
+
+// seekPositions returns the positions, relative to the Segment body, that
+// the SeekHead entries of a SeekHead payload give for the element id.
+func seekPositions(head []byte, id uint32) []int64 {
+	idBytes := ebmlIDBytes(id)
+	var out []int64
+	children, _ := ebmlChildren(head, 0, len(head))
+	for _, c := range children {
+		if c.id != ebmlIDSeek || !bytes.Equal(seekID(head[c.body:c.end]), idBytes) {
+			continue
+		}
+		entry, _ := ebmlChildren(head, c.body, c.end)
+		for _, e := range entry {
+			if e.id == ebmlIDSeekPosition {
+				out = append(out, int64(ebmlUintValue(head[e.body:e.end])))
+			}
+		}
+	}
+	return out
+}
+
+// ─── View ────────────────────────────────────────────────────────────────────
+
+// addMKVSeekedTags adds the Tags that lie beyond data, the start of the
+// file view read, at the place the SeekHead gives.
+func addMKVSeekedTags(r io.ReaderAt, data []byte, m *core.Metadata) {
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		size, sLen := readEBMLSize(data, i+idLen)
+		if id == 0 || sLen == 0 {
+			return
+		}
+		body := i + idLen + sLen
+		if id != ebmlIDSegment {
+			if size < 0 {
+				return
+			}
+			i = body + int(size)
+			continue
+		}
+		for j := body; j < len(data); {
+			id, idLen := readEBMLID(data, j)
+			size, sLen := readEBMLSize(data, j+idLen)
+			b := j + idLen + sLen
+			if id == 0 || sLen == 0 || size < 0 || int64(b)+size > int64(len(data)) {
+				return
+			}
+			if id == ebmlIDSeekHead {
+				for _, pos := range seekPositions(data[b:b+int(size)], ebmlIDTags) {
+					readMKVTagsAt(r, int64(body)+pos, int64(len(data)), m)
+				}
+				return
+			}
+			j = b + int(size)
+		}
+		return
+	}
+}
+
+// maxMKVTags caps the Tags element view reads from the end of a file.
+const maxMKVTags = 16 << 20
+
+// readMKVTagsAt parses the Tags element at off unless it lies within the
+// first n bytes, which view has parsed already.
+func readMKVTagsAt(r io.ReaderAt, off, n int64, m *core.Metadata) {
+	hdr := make([]byte, 12)
+	k, _ := r.ReadAt(hdr, off)
+	hdr = hdr[:k]
+	id, idLen := readEBMLID(hdr, 0)
+	size, sLen := readEBMLSize(hdr, idLen)
+	if id != ebmlIDTags || sLen == 0 || size < 0 || size > maxMKVTags {
+		return
+	}
+	body := off + int64(idLen+sLen)
+	if body+size <= n {
+		return
+	}
+	payload := make([]byte, size)
+	if _, err := r.ReadAt(payload, body); err != nil {
+		return
+	}
+	parseEBMLTags(payload, m)
+}
+
+// ─── Edit and strip ──────────────────────────────────────────────────────────
+
+// isMKVTitle reports whether an edit key names the Segment title, shown
+// as Title; TITLE is the SimpleTag of that name.
+func isMKVTitle(k string) bool {
+	return strings.EqualFold(k, "title") && k != "TITLE"
+}
+
+// mkvTagName returns the SimpleTag name of an edit key: Matroska tag
+// names are written in capitals.
+func mkvTagName(k string) string {
+	return strings.ToUpper(k)
+}
+
+// editMKVInfoTags applies the Title and SimpleTag part of an edit to data.
+func editMKVInfoTags(data []byte, set map[string]string, del []string) ([]byte, error) {
+	tagSet := map[string]string{}
+	dropped := map[string]bool{}
+	for _, k := range core.SortedKeys(set) {
+		if isMKVTitle(k) {
+			if err := patchMKVInfo(data, packEBML(ebmlIDTitle, []byte(set[k]), 0), ebmlIDTitle); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		tagSet[mkvTagName(k)] = set[k]
+	}
+	for _, k := range del {
+		if isMKVTitle(k) {
+			if err := patchMKVInfo(data, nil, ebmlIDTitle); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		dropped[mkvTagName(k)] = true
+	}
+	if len(tagSet) == 0 && len(dropped) == 0 {
+		return data, nil
+	}
+	return rewriteMKVTags(data, tagSet, func(name string) bool {
+		return dropped[strings.ToUpper(name)] && setKey(tagSet, name) == ""
+	})
+}
+
+// stripMKV removes the Segment title and date, the Tags and the
+// Attachments of a Matroska or WebM file. Fields named in keep (Title, a
+// tag name) survive.
+func stripMKV(path, outPath string, opts core.StripOptions) error {
+	if opts.StripGPS {
+		return fmt.Errorf("Matroska files hold no GPS location to strip")
+	}
+	orig, err := core.ReadFileProgress(path, opts.Progress)
+	if err != nil {
+		return err
+	}
+	keep := map[string]bool{}
+	for _, k := range opts.KeepFields {
+		keep[strings.ToUpper(k)] = true
+	}
+	data := append([]byte(nil), orig...)
+	ids := []uint32{ebmlIDDateUTC}
+	if !keep["TITLE"] || opts.StripAll {
+		ids = append(ids, ebmlIDTitle)
+	}
+	if err := patchMKVInfo(data, nil, ids...); err != nil {
+		return err
+	}
+	if err := voidMKV(data, ebmlIDAttachments); err != nil {
+		return err
+	}
+	if data, err = rewriteMKVTags(data, nil, func(name string) bool {
+		return opts.StripAll || !keep[strings.ToUpper(name)]
+	}); err != nil {
+		return err
+	}
+	if data, err = trimMKVVoids(data); err != nil {
+		return err
+	}
+	if err := verifyMKVRewrite(orig, data); err != nil {
+		return err
+	}
+	return core.WriteFileProgress(outPath, data, opts.Progress)
+}
+
+// verifyMKVRewrite checks out, a rewrite of orig that changed only
+// metadata: its Segment parses to the end, every Cluster is where it was
+// with the same bytes, and every SeekHead entry points at an element of
+// the kind it names.
+func verifyMKVRewrite(orig, out []byte) error {
+	before, err := readMKVSegment(orig)
+	if err != nil {
+		return nil // nothing to compare with
+	}
+	after, err := readMKVSegment(out)
+	if err != nil {
+		return fmt.Errorf("Matroska rewrite produced an invalid layout (%v); the file was not written", err)
+	}
+	for _, c := range before.children {
+		if c.id != ebmlIDCluster {
+			continue
+		}
+		if c.end > len(out) || !bytes.Equal(orig[c.start:c.end], out[c.start:c.end]) {
+			return fmt.Errorf("Matroska rewrite moved the Cluster at offset %d; the file was not written", c.start)
+		}
+	}
+	head, _, ok := after.find(ebmlIDSeekHead)
+	if !ok {
+		return nil
+	}
+	entries, _ := ebmlChildren(out, head.body, head.end)
+	for _, e := range entries {
+		if e.id != ebmlIDSeek {
+			continue
+		}
+		id, _ := readEBMLID(seekID(out[e.body:e.end]), 0)
+		for _, pos := range seekPositions(out[e.start:e.end], id) {
+			at := after.body + int(pos)
+			if got, _ := readEBMLID(out, at); got != id {
+				return fmt.Errorf("Matroska rewrite left the SeekHead entry of element %X pointing at offset %d, where there is none; the file was not written", id, at)
+			}
+		}
+	}
+	return nil
+}
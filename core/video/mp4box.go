@@ -0,0 +1,268 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ─── Size limits ──────────────────────────────────────────────────────────────
+// Rewrites load the whole file. Boxes with "largesize" headers (size field 1
+// followed by a 64-bit size) and co64 chunk-offset tables are kept in sync
+// like their 32-bit forms, but a file past 4 GiB is refused rather than read
+// into memory.
+
+const maxUint32 = 1<<32 - 1
+
+// checkMP4SizeLimits refuses rewrites of files too large to hold in memory.
+func checkMP4SizeLimits(data []byte) error {
+	if uint64(len(data)) > maxUint32 {
+		return fmt.Errorf("MP4 is larger than 4 GiB (%d bytes): metadata rewrite reads the whole file, refusing", len(data))
+	}
+	return nil
+}
+
+// ─── Box splicing ────────────────────────────────────────────────────────────
+// Writers that replace a box in the middle of moov must keep every enclosing
+// box size and, when moov sits in front of mdat, every chunk offset in sync.
+
+// mp4Span locates one box inside an in-memory file.
+type mp4Span struct {
+	typ   string
+	start int // offset of the size field
+	body  int // first payload byte (past meta's version/flags for ISO meta)
+	end   int
+}
+
+// mp4ChildSpans lists the boxes laid out back to back in data[start:end].
+func mp4ChildSpans(data []byte, start, end int) []mp4Span {
+	var out []mp4Span
+	pos := start
+	for pos+8 <= end {
+		size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		hdr := 8
+		switch size {
+		case 0:
+			size = end - pos
+		case 1:
+			if pos+16 > end {
+				return out
+			}
+			large := binary.BigEndian.Uint64(data[pos+8 : pos+16])
+			if large > uint64(end-pos) {
+				return out
+			}
+			size, hdr = int(large), 16
+		}
+		if size < hdr || pos+size > end {
+			return out
+		}
+		b := mp4Span{typ: string(data[pos+4 : pos+8]), start: pos, body: pos + hdr, end: pos + size}
+		if b.typ == "meta" && b.body+8 <= b.end && string(data[b.body+4:b.body+8]) != "hdlr" {
+			b.body += 4
+		}
+		out = append(out, b)
+		pos += size
+	}
+	return out
+}
+
+// findMP4Path follows path from data[start:end] and returns the chain of
+// boxes, outermost first, or nil if any step is missing.
+func findMP4Path(data []byte, start, end int, path ...string) []mp4Span {
+	var chain []mp4Span
+	for _, typ := range path {
+		found := false
+		for _, b := range mp4ChildSpans(data, start, end) {
+			if b.typ == typ {
+				chain = append(chain, b)
+				start, end = b.body, b.end
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return chain
+}
+
+// replaceMP4Range replaces data[from:to] with repl and grows or shrinks the
+// boxes in chain (the ancestors of the replaced range) to match. If chain
+// starts at moov, chunk offsets pointing past the edit are shifted too.
+func replaceMP4Range(data []byte, chain []mp4Span, from, to int, repl []byte) []byte {
+	delta := len(repl) - (to - from)
+	out := make([]byte, 0, len(data)+delta)
+	out = append(out, data[:from]...)
+	out = append(out, repl...)
+	out = append(out, data[to:]...)
+	if delta == 0 {
+		return out
+	}
+	for _, b := range chain {
+		switch size := binary.BigEndian.Uint32(out[b.start : b.start+4]); size {
+		case 0:
+			// runs to end of file — nothing to update
+		case 1:
+			large := binary.BigEndian.Uint64(out[b.start+8 : b.start+16])
+			binary.BigEndian.PutUint64(out[b.start+8:b.start+16], uint64(int64(large)+int64(delta)))
+		default:
+			binary.BigEndian.PutUint32(out[b.start:b.start+4], uint32(int(size)+delta))
+		}
+	}
+	if len(chain) > 0 && chain[0].typ == "moov" {
+		moov := chain[0]
+		moov.end += delta
+		shiftMP4ChunkOffsets(out, moov, from, delta)
+	}
+	return out
+}
+
+// shiftMP4ChunkOffsets adds delta to every stco/co64 entry inside moov that
+// pointed at or beyond the original offset at.
+func shiftMP4ChunkOffsets(data []byte, moov mp4Span, at, delta int) {
+	for _, trak := range mp4ChildSpans(data, moov.body, moov.end) {
+		if trak.typ != "trak" {
+			continue
+		}
+		chain := findMP4Path(data, trak.body, trak.end, "mdia", "minf", "stbl")
+		if chain == nil {
+			continue
+		}
+		stbl := chain[len(chain)-1]
+		for _, b := range mp4ChildSpans(data, stbl.body, stbl.end) {
+			if b.typ != "stco" && b.typ != "co64" || b.body+8 > b.end {
+				continue
+			}
+			count := int(binary.BigEndian.Uint32(data[b.body+4 : b.body+8]))
+			pos := b.body + 8
+			for i := 0; i < count; i++ {
+				if b.typ == "stco" {
+					if pos+4 > b.end {
+						break
+					}
+					off := int64(binary.BigEndian.Uint32(data[pos : pos+4]))
+					if off >= int64(at) {
+						binary.BigEndian.PutUint32(data[pos:pos+4], uint32(off+int64(delta)))
+					}
+					pos += 4
+				} else {
+					if pos+8 > b.end {
+						break
+					}
+					off := int64(binary.BigEndian.Uint64(data[pos : pos+8]))
+					if off >= int64(at) {
+						binary.BigEndian.PutUint64(data[pos:pos+8], uint64(off+int64(delta)))
+					}
+					pos += 8
+				}
+			}
+		}
+	}
+}
+
+// ─── Checking a rewrite ──────────────────────────────────────────────────────
+// Every writer checks its result before it is saved: each container box
+// must be filled exactly by its children, and every chunk offset must still
+// point at the media bytes it pointed at before. A bug in a splice is then
+// an error instead of a file that no longer plays.
+
+// mp4CheckContainers are the boxes whose children verifyMP4Rewrite checks.
+var mp4CheckContainers = map[string]bool{
+	"moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+	"udta": true, "meta": true, "ilst": true, "edts": true, "dinf": true, "mvex": true,
+}
+
+// verifyMP4Rewrite checks out, a rewrite of orig that changed only
+// metadata. Faults orig already had are not held against out.
+func verifyMP4Rewrite(orig, out []byte) error {
+	// A layout that was already off is left for the player to cope with.
+	if err := checkMP4Boxes(out, 0, len(out), "", 0); err != nil && checkMP4Boxes(orig, 0, len(orig), "", 0) == nil {
+		return fmt.Errorf("MP4 rewrite produced an invalid box layout (%v); the file was not written", err)
+	}
+	before, after := mp4ChunkOffsets(orig), mp4ChunkOffsets(out)
+	if len(before) != len(after) {
+		return fmt.Errorf("MP4 rewrite changed the number of tracks with chunk offsets (%d → %d); the file was not written", len(before), len(after))
+	}
+	for t := range before {
+		if len(before[t]) != len(after[t]) {
+			return fmt.Errorf("MP4 rewrite changed the chunk count of track %d; the file was not written", t+1)
+		}
+		for i, off := range before[t] {
+			n := int64(16)
+			if rest := int64(len(orig)) - off; rest < n {
+				n = rest
+			}
+			if n <= 0 {
+				continue // pointed past the end already
+			}
+			got := after[t][i]
+			if got < 0 || got+n > int64(len(out)) ||
+				string(orig[off:off+n]) != string(out[got:got+n]) {
+				return fmt.Errorf("MP4 rewrite left chunk %d of track %d pointing at the wrong data (offset %d → %d); the file was not written", i+1, t+1, off, got)
+			}
+		}
+	}
+	return nil
+}
+
+// checkMP4Boxes checks that the boxes in data[start:end] follow each other
+// up to end, at most 7 bytes short of it (QuickTime ends some udta boxes
+// with a 4-byte terminator), recursing into containers.
+func checkMP4Boxes(data []byte, start, end int, parent string, depth int) error {
+	pos := start
+	for _, b := range mp4ChildSpans(data, start, end) {
+		if depth < 8 && mp4CheckContainers[b.typ] {
+			if err := checkMP4Boxes(data, b.body, b.end, b.typ, depth+1); err != nil {
+				return err
+			}
+		}
+		pos = b.end
+	}
+	if end-pos >= 8 {
+		where := "top level"
+		if parent != "" {
+			where = parent
+		}
+		return fmt.Errorf("%d bytes at offset %d in %s are not a box", end-pos, pos, where)
+	}
+	return nil
+}
+
+// mp4ChunkOffsets returns the stco/co64 entries of each track, in file
+// order; a track without a chunk offset table has none.
+func mp4ChunkOffsets(data []byte) [][]int64 {
+	moov := findMP4Path(data, 0, len(data), "moov")
+	if moov == nil {
+		return nil
+	}
+	var out [][]int64
+	for _, trak := range mp4ChildSpans(data, moov[0].body, moov[0].end) {
+		if trak.typ != "trak" {
+			continue
+		}
+		var offs []int64
+		if chain := findMP4Path(data, trak.body, trak.end, "mdia", "minf", "stbl"); chain != nil {
+			stbl := chain[len(chain)-1]
+			for _, b := range mp4ChildSpans(data, stbl.body, stbl.end) {
+				if b.typ != "stco" && b.typ != "co64" || b.body+8 > b.end {
+					continue
+				}
+				width := 4
+				if b.typ == "co64" {
+					width = 8
+				}
+				count := int(binary.BigEndian.Uint32(data[b.body+4 : b.body+8]))
+				for i, pos := 0, b.body+8; i < count && pos+width <= b.end; i, pos = i+1, pos+width {
+					if width == 4 {
+						offs = append(offs, int64(binary.BigEndian.Uint32(data[pos:])))
+					} else {
+						offs = append(offs, int64(binary.BigEndian.Uint64(data[pos:])))
+					}
+				}
+			}
+		}
+		out = append(out, offs)
+	}
+	return out
+}
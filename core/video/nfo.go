@@ -0,0 +1,205 @@
+package video
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── NFO sidecars ────────────────────────────────────────────────────────────
+// Kodi, Jellyfin and Emby (and Plex with an agent) read a movie.nfo or
+// episode.nfo XML file next to the video. BuildNFO writes one from the
+// container's own metadata; ParseNFO reads one back into edit fields so
+// that values curated in the media server can be stored in the file.
+
+// nfoDoc is the subset of the Kodi NFO schema that maps onto container
+// tags. The root element is <movie> or <episodedetails>.
+type nfoDoc struct {
+	XMLName   xml.Name
+	Title     string     `xml:"title,omitempty"`
+	ShowTitle string     `xml:"showtitle,omitempty"`
+	Season    string     `xml:"season,omitempty"`
+	Episode   string     `xml:"episode,omitempty"`
+	Year      string     `xml:"year,omitempty"`
+	Premiered string     `xml:"premiered,omitempty"`
+	Outline   string     `xml:"outline,omitempty"`
+	Plot      string     `xml:"plot,omitempty"`
+	Runtime   string     `xml:"runtime,omitempty"`
+	MPAA      string     `xml:"mpaa,omitempty"`
+	Genres    []string   `xml:"genre,omitempty"`
+	Studios   []string   `xml:"studio,omitempty"`
+	Directors []string   `xml:"director,omitempty"`
+	Credits   []string   `xml:"credits,omitempty"`
+	Actors    []nfoActor `xml:"actor,omitempty"`
+}
+
+type nfoActor struct {
+	Name string `xml:"name"`
+}
+
+// nfoSources lists, per NFO element, the field keys that can fill it, in
+// order of preference: iTunes names from MP4 and Matroska tag names.
+var nfoSources = map[string][]string{
+	"title":     {"TVEpisodeName", "Title", "TITLE"},
+	"showtitle": {"TVShowName"},
+	"season":    {"TVSeason"},
+	"episode":   {"TVEpisode", "PART_NUMBER"},
+	"date":      {"DATE_RELEASED", "Year", "DATE_RECORDED"},
+	"outline":   {"Description", "SUBTITLE"},
+	"plot":      {"LongDescription", "SYNOPSIS", "DESCRIPTION", "SUMMARY", "Description", "Comment", "COMMENT"},
+	"mpaa":      {"LAW_RATING"},
+	"genre":     {"Genre", "GENRE"},
+	"studio":    {"PRODUCTION_STUDIO", "PUBLISHER", "DISTRIBUTED_BY"},
+	"director":  {"DIRECTOR"},
+	"credits":   {"WRITTEN_BY", "SCREENPLAY_BY"},
+	"actor":     {"ACTOR", "Artist"},
+}
+
+// BuildNFO renders m as a Kodi/Jellyfin NFO document. Files that carry a
+// TV show name or episode number become <episodedetails>, others <movie>.
+func BuildNFO(m *core.Metadata) ([]byte, error) {
+	get := func(elem string) string {
+		for _, key := range nfoSources[elem] {
+			for _, f := range m.Fields {
+				if f.Key == key && strings.TrimSpace(f.Value) != "" {
+					return strings.TrimSpace(f.Value)
+				}
+			}
+		}
+		return ""
+	}
+	list := func(elem string) []string {
+		return strings.FieldsFunc(get(elem), func(r rune) bool { return r == ';' || r == ',' || r == '/' })
+	}
+
+	doc := nfoDoc{
+		Title:     get("title"),
+		ShowTitle: get("showtitle"),
+		Season:    get("season"),
+		Episode:   get("episode"),
+		Outline:   get("outline"),
+		Plot:      get("plot"),
+		MPAA:      get("mpaa"),
+	}
+	doc.XMLName.Local = "movie"
+	if doc.ShowTitle != "" || doc.Episode != "" {
+		doc.XMLName.Local = "episodedetails"
+	}
+	if doc.Plot == doc.Outline {
+		doc.Outline = ""
+	}
+	if date := get("date"); len(date) >= 4 {
+		doc.Year = date[:4]
+		if len(date) >= 10 {
+			doc.Premiered = date[:10]
+		}
+	}
+	for _, f := range m.Fields {
+		if f.Key == "Duration" {
+			if mins, ok := durationMinutes(f.Value); ok {
+				doc.Runtime = strconv.Itoa(mins)
+			}
+			break
+		}
+	}
+	for _, g := range list("genre") {
+		doc.Genres = append(doc.Genres, strings.TrimSpace(g))
+	}
+	for _, s := range list("studio") {
+		doc.Studios = append(doc.Studios, strings.TrimSpace(s))
+	}
+	for _, d := range list("director") {
+		doc.Directors = append(doc.Directors, strings.TrimSpace(d))
+	}
+	for _, c := range list("credits") {
+		doc.Credits = append(doc.Credits, strings.TrimSpace(c))
+	}
+	for _, a := range list("actor") {
+		doc.Actors = append(doc.Actors, nfoActor{Name: strings.TrimSpace(a)})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+var durationPartRe = regexp.MustCompile(`(\d+)([hms])`)
+
+// durationMinutes reads a Duration field ("1h 02m 03s", "45m 10s") as
+// whole minutes, rounded.
+func durationMinutes(s string) (int, bool) {
+	secs := 0
+	for _, p := range durationPartRe.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(p[1])
+		switch p[2] {
+		case "h":
+			secs += n * 3600
+		case "m":
+			secs += n * 60
+		case "s":
+			secs += n
+		}
+	}
+	if secs == 0 {
+		return 0, false
+	}
+	return (secs + 30) / 60, true
+}
+
+// ParseNFO reads an NFO document and returns the fields to write, keyed by
+// the names MP4 edit accepts (Title, Year, TVShowName, MediaKind, …).
+func ParseNFO(data []byte) (map[string]string, error) {
+	var doc nfoDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid NFO: %w", err)
+	}
+	set := make(map[string]string)
+	put := func(key, val string) {
+		if val = strings.TrimSpace(val); val != "" {
+			set[key] = val
+		}
+	}
+	switch doc.XMLName.Local {
+	case "movie":
+		put("Title", doc.Title)
+		put("MediaKind", "Movie")
+	case "episodedetails":
+		put("TVEpisodeName", doc.Title)
+		put("Title", doc.Title)
+		put("TVShowName", doc.ShowTitle)
+		put("TVSeason", doc.Season)
+		put("TVEpisode", doc.Episode)
+		put("MediaKind", "TV Show")
+	default:
+		return nil, fmt.Errorf("NFO root is <%s>; want <movie> or <episodedetails>", doc.XMLName.Local)
+	}
+	if doc.Premiered != "" {
+		put("Year", doc.Premiered)
+	} else {
+		put("Year", doc.Year)
+	}
+	put("LongDescription", doc.Plot)
+	if doc.Outline != "" {
+		put("Description", doc.Outline)
+	} else {
+		put("Description", doc.Plot)
+	}
+	put("Genre", strings.Join(doc.Genres, ", "))
+	var actors []string
+	for _, a := range doc.Actors {
+		actors = append(actors, a.Name)
+	}
+	put("Artist", strings.Join(actors, ", "))
+	return set, nil
+}
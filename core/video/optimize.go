@@ -0,0 +1,71 @@
+package video
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// Optimize removes the free and skip boxes of an MP4 or MOV, at the top
+// level and inside moov, udta and meta, shifting chunk offsets to match.
+func (h *Handler) Optimize(path, outPath string, dryRun bool) (int64, error) {
+	if h.format != core.FmtMP4 && h.format != core.FmtMOV {
+		return 0, fmt.Errorf("%s does not support optimize", formatInfo[h.format].Name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkMP4Rewritable(bytes.NewReader(data)); err != nil {
+		return 0, err
+	}
+	if err := checkMP4SizeLimits(data); err != nil {
+		return 0, err
+	}
+	orig := data
+	var saved int64
+	for {
+		chain, ok := findMP4Free(data, 0, len(data), nil)
+		if !ok {
+			break
+		}
+		b := chain[len(chain)-1]
+		parents := chain[:len(chain)-1]
+		saved += int64(b.end - b.start)
+		out := replaceMP4Range(data, parents, b.start, b.end, nil)
+		if len(parents) == 0 {
+			if moov := findMP4Path(out, 0, len(out), "moov"); moov != nil {
+				shiftMP4ChunkOffsets(out, moov[0], b.start, b.start-b.end)
+			}
+		}
+		data = out
+	}
+	if dryRun || saved == 0 {
+		return saved, nil
+	}
+	if err := verifyMP4Rewrite(orig, data); err != nil {
+		return 0, err
+	}
+	return saved, os.WriteFile(outPath, data, 0644)
+}
+
+// findMP4Free returns the chain of boxes down to the first free or skip
+// box in data[start:end], searching moov, udta and meta.
+func findMP4Free(data []byte, start, end int, parents []mp4Span) ([]mp4Span, bool) {
+	for _, b := range mp4ChildSpans(data, start, end) {
+		switch b.typ {
+		case "free", "skip":
+			return append(parents, b), true
+		case "moov", "udta", "meta":
+			if len(parents) == 0 && b.typ != "moov" {
+				continue
+			}
+			if chain, ok := findMP4Free(data, b.body, b.end, append(parents, b)); ok {
+				return chain, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+package video
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── QuickTime keys in MOV ───────────────────────────────────────────────────
+// Cameras and iPhones write the metadata of a MOV as com.apple.quicktime.*
+// keys in moov/meta (see mdta.go), not as iTunes atoms. In a MOV, edit takes
+// the short names of those keys — title sets com.apple.quicktime.title —
+// while full key names and iTunes atom names (©nam, TVShowName) still work
+// in both MP4 and MOV. creationdate and location.ISO6709 values are checked
+// and written the way Apple devices write them.
+
+const quickTimeKeyPrefix = "com.apple.quicktime."
+
+// quickTimeKeyNames are the keys a MOV edit accepts by short name.
+var quickTimeKeyNames = []string{
+	"title", "artist", "author", "album", "comment", "copyright",
+	"creationdate", "description", "director", "displayname", "genre",
+	"information", "keywords", "location.ISO6709", "location.name",
+	"make", "model", "performer", "producer", "publisher", "software", "year",
+}
+
+// quickTimeKey returns the full key of a short name ("location" stands for
+// location.ISO6709), and false for any other name.
+func quickTimeKey(name string) (string, bool) {
+	if strings.EqualFold(name, "location") {
+		name = "location.ISO6709"
+	}
+	for _, k := range quickTimeKeyNames {
+		if strings.EqualFold(k, name) {
+			return quickTimeKeyPrefix + k, true
+		}
+	}
+	return "", false
+}
+
+// quickTimeValue checks and normalises the value of a keyed item: a
+// creation date becomes 2006-01-02T15:04:05-0700 and a location an ISO 6709
+// string. Other values are returned as given.
+func quickTimeValue(key, v string) (string, error) {
+	switch strings.TrimPrefix(strings.ToLower(key), quickTimeKeyPrefix) {
+	case "creationdate":
+		t, err := parseQuickTimeDate(v)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(quickTimeDateLayout), nil
+	case "location.iso6709":
+		return parseISO6709(v)
+	}
+	return v, nil
+}
+
+// quickTimeDateLayout is how Apple devices write creationdate.
+const quickTimeDateLayout = "2006-01-02T15:04:05-0700"
+
+var quickTimeDateInputs = []string{
+	quickTimeDateLayout,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006:01:02 15:04:05", // EXIF style
+}
+
+// parseQuickTimeDate reads "now" or a date in one of quickTimeDateInputs;
+// one without a zone is taken as local time.
+func parseQuickTimeDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if strings.EqualFold(v, "now") {
+		return time.Now(), nil
+	}
+	for _, layout := range quickTimeDateInputs {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date %q (use e.g. 2024-01-02, 2024-01-02T15:04:05+01:00 or now)", v)
+}
+
+// iso6709 matches a point as ISO 6709 writes it in ©xyz and location keys:
+// signed degrees of latitude and longitude, an optional altitude, then "/".
+var iso6709 = regexp.MustCompile(`^[+-]\d{2}(\.\d+)?[+-]\d{3}(\.\d+)?([+-]\d+(\.\d+)?)?(CRS[^/]*)?/$`)
+
+// parseISO6709 accepts an ISO 6709 point, or "latitude, longitude" with an
+// optional altitude in metres, in decimal degrees, and returns the ISO 6709
+// form.
+func parseISO6709(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if iso6709.MatchString(v) {
+		return v, nil
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return "", fmt.Errorf("cannot parse location %q (use ISO 6709 such as +48.8584+002.2945/, or latitude, longitude)", v)
+	}
+	var n [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return "", fmt.Errorf("cannot parse location %q: %q is not a number", v, strings.TrimSpace(p))
+		}
+		n[i] = f
+	}
+	if n[0] < -90 || n[0] > 90 || n[1] < -180 || n[1] > 180 {
+		return "", fmt.Errorf("location %q is out of range (latitude -90 to 90, longitude -180 to 180)", v)
+	}
+	s := fmt.Sprintf("%+08.4f%+09.4f", n[0], n[1])
+	if len(parts) == 3 {
+		s += fmt.Sprintf("%+08.3f", n[2])
+	}
+	return s + "/", nil
+}
+
+// quickTimeNames replaces the short names among the keys of an edit with
+// full QuickTime key names.
+func quickTimeNames(set map[string]string, del []string) (map[string]string, []string) {
+	out := make(map[string]string, len(set))
+	for k, v := range set {
+		if full, ok := quickTimeKey(k); ok {
+			k = full
+		}
+		out[k] = v
+	}
+	outDel := make([]string, len(del))
+	for i, k := range del {
+		if full, ok := quickTimeKey(k); ok {
+			k = full
+		}
+		outDel[i] = k
+	}
+	return out, outDel
+}
@@ -0,0 +1,54 @@
+package video
+
+import (
+	"os"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// SizeBreakdown lists the top-level boxes of an MP4 or MOV, with the
+// metadata boxes inside moov (udta, the mdta keys meta, XMP) split out.
+// Other formats return nil.
+func (h *Handler) SizeBreakdown(path string) ([]core.SizePart, error) {
+	if h.format != core.FmtMP4 && h.format != core.FmtMOV {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var parts []core.SizePart
+	for _, b := range mp4ChildSpans(data, 0, len(data)) {
+		switch b.typ {
+		case "moov":
+			rest := int64(b.body - b.start)
+			for _, c := range mp4ChildSpans(data, b.body, b.end) {
+				n := int64(c.end - c.start)
+				switch {
+				case c.typ == "udta":
+					parts = append(parts, core.SizePart{Name: "moov/udta", Bytes: n, Metadata: true})
+				case c.typ == "meta":
+					parts = append(parts, core.SizePart{Name: "moov/meta (keys)", Bytes: n, Metadata: true})
+				case c.typ == "uuid" && isXMPUUID(data[c.body:c.end]):
+					parts = append(parts, core.SizePart{Name: "XMP uuid", Bytes: n, Metadata: true})
+				default:
+					rest += n
+				}
+			}
+			parts = append(parts, core.SizePart{Name: "moov (tracks, sample tables)", Bytes: rest})
+		case "mdat":
+			parts = append(parts, core.SizePart{Name: "mdat (media data)", Bytes: int64(b.end - b.start)})
+		case "free", "skip":
+			parts = append(parts, core.SizePart{Name: "free/skip", Bytes: int64(b.end - b.start)})
+		case "uuid":
+			part := core.SizePart{Name: "uuid", Bytes: int64(b.end - b.start)}
+			if isXMPUUID(data[b.body:b.end]) {
+				part.Name, part.Metadata = "XMP uuid", true
+			}
+			parts = append(parts, part)
+		default:
+			parts = append(parts, core.SizePart{Name: b.typ, Bytes: int64(b.end - b.start)})
+		}
+	}
+	return parts, nil
+}
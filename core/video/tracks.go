@@ -0,0 +1,959 @@
+package video
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// ─── Tracks ──────────────────────────────────────────────────────────────────
+// A movie is several streams — video, one audio track per language,
+// subtitles — and each carries its own codec, language and often a title
+// ("Director's commentary", "English SDH"). View lists them as Track1.*,
+// Track2.*, … numbered in file order, which is the order players and
+// ffprobe show them in. In MP4 the title of a track is the name box of its
+// udta and its language the packed ISO 639-2 code in mdhd, refined by a
+// BCP 47 tag in mdia/elng when a region or script matters (pt-BR,
+// zh-Hant). Matroska keeps the same two in a TrackEntry's Language and
+// LanguageBCP47 elements. Both containers can be edited with
+// --set "Track2.Language=fra" or "Track3.Title=Commentary".
+//
+// An MP4 video track also has a rotation: phones record in the sensor's
+// orientation and put the turn the player must apply in the tkhd matrix.
+// View reports it as Track1.Rotation in degrees clockwise, and edit sets it
+// to 0, 90, 180 or 270 in place, which fixes a sideways video without
+// remuxing it.
+
+// mediaTrack is one stream of a container.
+type mediaTrack struct {
+	kind     string // video, audio, subtitle, … or the raw handler type
+	codec    string // sample entry fourcc (avc1, mp4a) or Matroska CodecID
+	language string // ISO 639-2, or BCP 47 when the file gives one
+	title    string
+	handler  string // MP4 hdlr name ("SoundHandler", "Core Media Video")
+	rotation string // from the MP4 tkhd matrix: "90", or "90, mirrored"
+	color    *trackColor
+}
+
+var mp4HandlerKinds = map[string]string{
+	"vide": "video",
+	"soun": "audio",
+	"sbtl": "subtitle",
+	"subt": "subtitle",
+	"text": "text",
+	"clcp": "captions",
+	"tmcd": "timecode",
+	"meta": "metadata",
+	"hint": "hint",
+}
+
+var mkvTrackKinds = map[uint64]string{
+	1: "video", 2: "audio", 3: "complex", 16: "logo",
+	17: "subtitle", 18: "buttons", 32: "control", 33: "metadata",
+}
+
+// trackFields returns the fields of tracks, category "Tracks"; editable
+// marks the Title and Language the handler can write.
+func trackFields(tracks []mediaTrack, editable bool) []core.MetaField {
+	var out []core.MetaField
+	for i, t := range tracks {
+		add := func(name, val string, canEdit bool) {
+			if val == "" {
+				return
+			}
+			out = append(out, core.MetaField{
+				Key:      fmt.Sprintf("Track%d.%s", i+1, name),
+				Value:    val,
+				Category: "Tracks",
+				Editable: canEdit,
+			})
+		}
+		add("Type", t.kind, false)
+		add("Codec", t.codec, false)
+		add("Language", t.language, editable)
+		add("Title", t.title, editable)
+		add("Handler", t.handler, false)
+		add("Rotation", t.rotation, editable)
+		for _, f := range t.color.fields() {
+			add(f[0], f[1], false)
+		}
+	}
+	return out
+}
+
+// ─── MP4 tracks ──────────────────────────────────────────────────────────────
+
+// addMP4Tracks appends the fields of every moov/trak. Only box headers and
+// the few small boxes needed are read, never the sample tables.
+func addMP4Tracks(r io.ReadSeeker, m *core.Metadata) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	var tracks []mediaTrack
+	for _, moov := range readMP4Spans(r, 0, size) {
+		if moov.typ != "moov" {
+			continue
+		}
+		for _, trak := range readMP4Spans(r, int64(moov.body), int64(moov.end)) {
+			if trak.typ == "trak" {
+				tracks = append(tracks, readMP4Track(r, trak))
+			}
+		}
+		break
+	}
+	m.Fields = append(m.Fields, trackFields(tracks, true)...)
+}
+
+func readMP4Track(r io.ReadSeeker, trak mp4Span) mediaTrack {
+	var t mediaTrack
+	child := func(parent mp4Span, typ string) (mp4Span, bool) {
+		for _, b := range readMP4Spans(r, int64(parent.body), int64(parent.end)) {
+			if b.typ == typ {
+				return b, true
+			}
+		}
+		return mp4Span{}, false
+	}
+	var rotation string
+	if tkhd, ok := child(trak, "tkhd"); ok {
+		rotation = tkhdRotation(readMP4Body(r, tkhd, 128))
+	}
+	if mdia, ok := child(trak, "mdia"); ok {
+		if mdhd, ok := child(mdia, "mdhd"); ok {
+			t.language = mdhdLanguage(readMP4Body(r, mdhd, 64))
+		}
+		if elng, ok := child(mdia, "elng"); ok {
+			if tag := elngLanguage(readMP4Body(r, elng, 256)); tag != "" {
+				t.language = tag
+			}
+		}
+		if hdlr, ok := child(mdia, "hdlr"); ok {
+			t.kind, t.handler = parseMP4Hdlr(readMP4Body(r, hdlr, 256))
+		}
+		if minf, ok := child(mdia, "minf"); ok {
+			if stbl, ok := child(minf, "stbl"); ok {
+				if stsd, ok := child(stbl, "stsd"); ok {
+					if b := readMP4Body(r, stsd, 64*1024); len(b) >= 16 {
+						t.codec = strings.TrimSpace(string(b[12:16]))
+						if t.kind == "video" {
+							t.color = parseMP4SampleColor(b)
+						}
+					}
+				}
+			}
+		}
+	}
+	if udta, ok := child(trak, "udta"); ok {
+		if name, ok := child(udta, "name"); ok {
+			t.title = mp4NameText(readMP4Body(r, name, 4096))
+		}
+	}
+	// Every video track has a rotation, if only 0; other tracks are listed
+	// with one only when they are turned.
+	if t.kind == "video" || (rotation != "" && rotation != "0") {
+		t.rotation = rotation
+	}
+	return t
+}
+
+// readMP4Spans lists the boxes laid out back to back in [start, end) of r,
+// reading only their headers.
+func readMP4Spans(r io.ReadSeeker, start, end int64) []mp4Span {
+	var out []mp4Span
+	pos := start
+	for pos+8 <= end {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			break
+		}
+		typ, dataSize, ok := readMP4BoxHeader(r)
+		if !ok {
+			break
+		}
+		body, _ := r.Seek(0, io.SeekCurrent)
+		boxEnd := body + dataSize
+		if dataSize < 0 {
+			boxEnd = end
+		}
+		if boxEnd > end {
+			break
+		}
+		out = append(out, mp4Span{typ: typ, start: int(pos), body: int(body), end: int(boxEnd)})
+		pos = boxEnd
+	}
+	return out
+}
+
+// readMP4Body reads up to max bytes of the payload of b.
+func readMP4Body(r io.ReadSeeker, b mp4Span, max int) []byte {
+	n := b.end - b.body
+	if n > max {
+		n = max
+	}
+	buf := make([]byte, n)
+	if _, err := r.Seek(int64(b.body), io.SeekStart); err != nil {
+		return nil
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil
+	}
+	return buf
+}
+
+// parseMP4Hdlr returns the kind of track a hdlr payload declares and the
+// handler name, which QuickTime writes as a Pascal string and ISO files
+// null-terminated.
+func parseMP4Hdlr(b []byte) (kind, name string) {
+	if len(b) < 12 {
+		return "", ""
+	}
+	typ := string(b[8:12])
+	kind = mp4HandlerKinds[typ]
+	if kind == "" {
+		kind = strings.TrimSpace(typ)
+	}
+	if len(b) > 24 {
+		s := b[24:]
+		if s[0] > 0 && s[0] < 0x20 && int(s[0]) < len(s) {
+			s = s[1 : 1+int(s[0])]
+		}
+		name = strings.TrimSpace(strings.TrimRight(string(s), "\x00"))
+	}
+	return kind, name
+}
+
+// mp4NameText returns the text of a udta name box: the string itself, or
+// a data atom holding it as some writers produce.
+func mp4NameText(b []byte) string {
+	if len(b) >= 16 && string(b[4:8]) == "data" {
+		b = b[16:]
+	}
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+// mdhdLanguageAt returns the offset of the language code in an mdhd
+// payload: past the creation and modification times, the timescale and
+// the duration, which version 1 writes as 64 bits.
+func mdhdLanguageAt(b []byte) int {
+	if len(b) > 0 && b[0] == 1 {
+		return 32
+	}
+	return 20
+}
+
+// mdhdLanguage decodes the language of an mdhd payload: three letters of
+// five bits each, offset from 0x60, or a QuickTime Macintosh language code
+// below 0x400.
+func mdhdLanguage(b []byte) string {
+	at := mdhdLanguageAt(b)
+	if len(b) < at+2 {
+		return ""
+	}
+	v := binary.BigEndian.Uint16(b[at : at+2])
+	if v < 0x400 {
+		return macLanguages[v]
+	}
+	if v == 0x7FFF {
+		return "und"
+	}
+	return string([]byte{byte(v>>10&0x1F) + 0x60, byte(v>>5&0x1F) + 0x60, byte(v&0x1F) + 0x60})
+}
+
+// packMDHDLanguage encodes an ISO 639-2 code for mdhd.
+func packMDHDLanguage(lang string) (uint16, error) {
+	l := strings.ToLower(strings.TrimSpace(lang))
+	if !isISO639Code(l) {
+		return 0, fmt.Errorf("track language %q: MP4 takes a three-letter ISO 639-2 code such as eng or fra", lang)
+	}
+	return uint16(l[0]-0x60)<<10 | uint16(l[1]-0x60)<<5 | uint16(l[2]-0x60), nil
+}
+
+// elngLanguage returns the BCP 47 tag of an elng payload: a full box
+// holding a null-terminated string.
+func elngLanguage(b []byte) string {
+	if len(b) <= 4 {
+		return ""
+	}
+	s, _, _ := strings.Cut(string(b[4:]), "\x00")
+	return strings.TrimSpace(s)
+}
+
+func isISO639Code(s string) bool {
+	return len(s) == 3 && strings.Trim(s, "abcdefghijklmnopqrstuvwxyz") == ""
+}
+
+// trackLanguage parses the value of a TrackN.Language edit. code is the
+// ISO 639-2 code mdhd and the Matroska Language element hold; tag is the
+// BCP 47 tag for elng or LanguageBCP47, set only when the value says more
+// than the language alone. A three-letter code is taken as given, so the
+// bibliographic forms (fre, ger) Matroska files often use still work.
+func trackLanguage(v string) (code, tag string, err error) {
+	v = strings.TrimSpace(v)
+	if l := strings.ToLower(v); isISO639Code(l) {
+		return l, "", nil
+	}
+	t, err := language.Parse(v)
+	if err != nil {
+		return "", "", fmt.Errorf("track language %q: expected an ISO 639-2 code such as fra or a BCP 47 tag such as pt-BR", v)
+	}
+	base, _ := t.Base()
+	if code = base.ISO3(); !isISO639Code(code) {
+		code = "und"
+	}
+	if t.String() != base.String() {
+		tag = t.String()
+	}
+	return code, tag, nil
+}
+
+// macLanguages maps the common QuickTime Macintosh language codes.
+var macLanguages = map[uint16]string{
+	0: "eng", 1: "fra", 2: "deu", 3: "ita", 4: "nld", 5: "swe", 6: "spa",
+	7: "dan", 8: "por", 9: "nor", 10: "heb", 11: "jpn", 12: "ara",
+	13: "fin", 14: "ell", 19: "zho", 23: "kor", 32: "rus",
+}
+
+// tkhdMatrixAt returns the offset of the matrix in a tkhd payload.
+func tkhdMatrixAt(b []byte) int {
+	if len(b) > 0 && b[0] == 1 {
+		return 52 // 64-bit times and duration
+	}
+	return 40
+}
+
+// tkhdRotation returns the turn the tkhd payload b describes, in degrees
+// clockwise, or "" when its matrix is missing or degenerate.
+func tkhdRotation(b []byte) string {
+	at := tkhdMatrixAt(b)
+	if at+36 > len(b) {
+		return ""
+	}
+	fixed := func(i int) float64 { return float64(int32(binary.BigEndian.Uint32(b[at+4*i:]))) / 65536 }
+	a, bb, c, d := fixed(0), fixed(1), fixed(3), fixed(4)
+	if a == 0 && bb == 0 {
+		return ""
+	}
+	deg := math.Round(math.Atan2(bb, a) * 180 / math.Pi)
+	if deg < 0 {
+		deg += 360
+	}
+	s := strconv.Itoa(int(deg) % 360)
+	if a*d-bb*c < 0 {
+		s += ", mirrored"
+	}
+	return s
+}
+
+// rotationMatrix returns the tkhd matrix turning a w×h track (16.16
+// values, as tkhd stores them) by deg degrees clockwise, translated back
+// into view the way QuickTime writes it.
+func rotationMatrix(deg int, w, h uint32) [9]uint32 {
+	const one, minus, w30 = 0x00010000, 0xFFFF0000, 0x40000000
+	switch deg {
+	case 90:
+		return [9]uint32{0, one, 0, minus, 0, 0, h, 0, w30}
+	case 180:
+		return [9]uint32{minus, 0, 0, 0, minus, 0, w, h, w30}
+	case 270:
+		return [9]uint32{0, minus, 0, one, 0, 0, 0, w, w30}
+	}
+	return [9]uint32{one, 0, 0, 0, one, 0, 0, 0, w30}
+}
+
+// parseRotation reads a rotation of 0, 90, 180 or 270 degrees, with -90 and
+// the like taken as their positive turn.
+func parseRotation(v string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), "°"))
+	if err != nil || n%90 != 0 {
+		return 0, fmt.Errorf("rotation %q must be 0, 90, 180 or 270", v)
+	}
+	return (n%360 + 360) % 360, nil
+}
+
+// ─── MP4 track edits ─────────────────────────────────────────────────────────
+
+var reTrackKey = regexp.MustCompile(`(?i)^track(\d+)\.(title|language|rotation)$`)
+
+// trackEdit is one --set or --delete of a TrackN.Title, TrackN.Language
+// or TrackN.Rotation.
+type trackEdit struct {
+	track int // 1-based, in file order
+	field string
+	val   string
+	del   bool
+}
+
+// splitTrackEdits separates the track fields from the rest of set and del.
+func splitTrackEdits(set map[string]string, del []string) ([]trackEdit, map[string]string, []string) {
+	var edits []trackEdit
+	rest := map[string]string{}
+	for _, k := range core.SortedKeys(set) {
+		if e, ok := parseTrackKey(k); ok {
+			e.val = set[k]
+			edits = append(edits, e)
+		} else {
+			rest[k] = set[k]
+		}
+	}
+	var restDel []string
+	for _, k := range del {
+		if e, ok := parseTrackKey(k); ok {
+			e.del = true
+			edits = append(edits, e)
+		} else {
+			restDel = append(restDel, k)
+		}
+	}
+	return edits, rest, restDel
+}
+
+func parseTrackKey(k string) (trackEdit, bool) {
+	m := reTrackKey.FindStringSubmatch(k)
+	if m == nil {
+		return trackEdit{}, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n < 1 {
+		return trackEdit{}, false
+	}
+	return trackEdit{track: n, field: strings.ToLower(m[2])}, true
+}
+
+// patchMP4Tracks applies edits to the traks of moov. A language is
+// rewritten in place in mdhd, and mdia/elng added, replaced or removed to
+// match; a title replaces, adds or removes trak/udta/name; a rotation
+// rewrites the tkhd matrix in place, deleting it meaning 0. The enclosing
+// sizes and the chunk offsets are fixed up.
+func patchMP4Tracks(data []byte, edits []trackEdit) ([]byte, error) {
+	for _, e := range edits {
+		moov := findMP4Path(data, 0, len(data), "moov")
+		if moov == nil {
+			return nil, fmt.Errorf("MP4 has no moov box")
+		}
+		var traks []mp4Span
+		for _, b := range mp4ChildSpans(data, moov[0].body, moov[0].end) {
+			if b.typ == "trak" {
+				traks = append(traks, b)
+			}
+		}
+		if e.track > len(traks) {
+			return nil, fmt.Errorf("Track%d: file has %d tracks", e.track, len(traks))
+		}
+		trak := traks[e.track-1]
+		chain := []mp4Span{moov[0], trak}
+
+		switch e.field {
+		case "language":
+			code, tag := "und", ""
+			if !e.del {
+				var err error
+				if code, tag, err = trackLanguage(e.val); err != nil {
+					return nil, err
+				}
+			}
+			packed, err := packMDHDLanguage(code)
+			if err != nil {
+				return nil, err
+			}
+			mdhd := findMP4Path(data, trak.body, trak.end, "mdia", "mdhd")
+			if mdhd == nil {
+				return nil, fmt.Errorf("Track%d has no mdhd box", e.track)
+			}
+			b := mdhd[1]
+			at := b.body + mdhdLanguageAt(data[b.body:b.end])
+			if at+2 > b.end {
+				return nil, fmt.Errorf("Track%d: mdhd box is truncated", e.track)
+			}
+			binary.BigEndian.PutUint16(data[at:at+2], packed)
+
+			// A plain code needs no elng, and a stale one would override it.
+			var elng []byte
+			if tag != "" {
+				payload := append(make([]byte, 4), tag...)
+				elng = packAtom("elng", append(payload, 0))
+			}
+			mdia := mdhd[0]
+			chain = append(chain, mdia)
+			if old := findMP4Path(data, mdia.body, mdia.end, "elng"); old != nil {
+				data = replaceMP4Range(data, chain, old[0].start, old[0].end, elng)
+			} else if elng != nil {
+				// ISO 14496-12 places elng after hdlr, before minf.
+				at := mdia.end
+				if minf := findMP4Path(data, mdia.body, mdia.end, "minf"); minf != nil {
+					at = minf[0].start
+				}
+				data = replaceMP4Range(data, chain, at, at, elng)
+			}
+
+		case "rotation":
+			deg := 0
+			if !e.del {
+				var err error
+				if deg, err = parseRotation(e.val); err != nil {
+					return nil, fmt.Errorf("Track%d: %w", e.track, err)
+				}
+			}
+			if hdlr := findMP4Path(data, trak.body, trak.end, "mdia", "hdlr"); hdlr != nil {
+				if kind, _ := parseMP4Hdlr(data[hdlr[1].body:hdlr[1].end]); kind != "video" {
+					return nil, fmt.Errorf("Track%d is not a video track", e.track)
+				}
+			}
+			tkhd := findMP4Path(data, trak.body, trak.end, "tkhd")
+			if tkhd == nil {
+				return nil, fmt.Errorf("Track%d has no tkhd box", e.track)
+			}
+			b := data[tkhd[0].body:tkhd[0].end]
+			at := tkhdMatrixAt(b)
+			if at+44 > len(b) {
+				return nil, fmt.Errorf("Track%d: tkhd box is truncated", e.track)
+			}
+			w, h := binary.BigEndian.Uint32(b[at+36:]), binary.BigEndian.Uint32(b[at+40:])
+			for i, v := range rotationMatrix(deg, w, h) {
+				binary.BigEndian.PutUint32(b[at+4*i:], v)
+			}
+
+		case "title":
+			var name []byte
+			if !e.del {
+				name = packAtom("name", []byte(e.val))
+			}
+			udta := findMP4Path(data, trak.body, trak.end, "udta")
+			if udta == nil {
+				if !e.del {
+					data = replaceMP4Range(data, chain, trak.end, trak.end, packAtom("udta", name))
+				}
+				continue
+			}
+			chain = append(chain, udta[0])
+			if old := findMP4Path(data, udta[0].body, udta[0].end, "name"); old != nil {
+				data = replaceMP4Range(data, chain, old[0].start, old[0].end, name)
+			} else if !e.del {
+				data = replaceMP4Range(data, chain, udta[0].end, udta[0].end, name)
+			}
+		}
+	}
+	return data, nil
+}
+
+// ─── Matroska tracks ─────────────────────────────────────────────────────────
+
+const (
+	ebmlIDTracks        = 0x1654AE6B
+	ebmlIDTrackEntry    = 0xAE
+	ebmlIDTrackType     = 0x83
+	ebmlIDCodecID       = 0x86
+	ebmlIDName          = 0x536E
+	ebmlIDLanguage      = 0x22B59C
+	ebmlIDLanguageBCP47 = 0x22B59D
+)
+
+// parseEBMLTracks appends the fields of each TrackEntry. A track without a
+// Language element is English, as the Matroska specification defaults it;
+// LanguageBCP47, when present, takes precedence.
+func parseEBMLTracks(data []byte, m *core.Metadata) {
+	var tracks []mediaTrack
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		i += idLen
+		size, sLen := readEBMLSize(data, i)
+		i += sLen
+		if size < 0 || i+int(size) > len(data) {
+			break
+		}
+		if id == ebmlIDTrackEntry {
+			tracks = append(tracks, parseEBMLTrackEntry(data[i:i+int(size)]))
+		}
+		i += int(size)
+	}
+	m.Fields = append(m.Fields, trackFields(tracks, true)...)
+}
+
+func parseEBMLTrackEntry(data []byte) mediaTrack {
+	t := mediaTrack{language: "eng"}
+	var bcp47 string
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		i += idLen
+		size, sLen := readEBMLSize(data, i)
+		i += sLen
+		if size < 0 || i+int(size) > len(data) {
+			break
+		}
+		payload := data[i : i+int(size)]
+		switch id {
+		case ebmlIDTrackType:
+			var v uint64
+			for _, c := range payload {
+				v = v<<8 | uint64(c)
+			}
+			t.kind = mkvTrackKinds[v]
+		case ebmlIDCodecID:
+			t.codec = strings.TrimRight(string(payload), "\x00")
+		case ebmlIDName:
+			t.title = strings.TrimRight(string(payload), "\x00")
+		case ebmlIDLanguage:
+			t.language = strings.TrimRight(string(payload), "\x00")
+		case ebmlIDLanguageBCP47:
+			bcp47 = strings.TrimRight(string(payload), "\x00")
+		case ebmlIDVideo:
+			t.color = parseEBMLVideoColour(payload)
+		}
+		i += int(size)
+	}
+	if bcp47 != "" {
+		t.language = bcp47
+	}
+	return t
+}
+
+// ─── Matroska track edits ────────────────────────────────────────────────────
+// A TrackEntry is rewritten inside the Tracks element, which is written
+// back at the same offset and the same length: the SeekHead, the Cues and
+// every Cluster keep their positions, which is what lets a multi-gigabyte
+// file be fixed without remuxing. The length is kept by changing the width
+// of the Tracks size field and by growing into, shrinking or adding an
+// EBML Void after it. Muxers leave such padding for this purpose; when a
+// larger value does not fit, the edit is refused rather than shifting the
+// clusters.
+
+const (
+	ebmlIDVoid  = 0xEC
+	ebmlIDCRC32 = 0xBF
+)
+
+// ebmlElement locates one element inside an in-memory file.
+type ebmlElement struct {
+	id    uint32
+	start int // offset of the ID
+	body  int // first payload byte
+	end   int
+}
+
+// ebmlChildren lists the elements laid out back to back in data[start:end];
+// ok is false if they do not fill it exactly.
+func ebmlChildren(data []byte, start, end int) (out []ebmlElement, ok bool) {
+	i := start
+	for i < end {
+		id, idLen := readEBMLID(data[:end], i)
+		if id == 0 {
+			return out, false
+		}
+		size, sLen := readEBMLSize(data[:end], i+idLen)
+		body := i + idLen + sLen
+		if sLen == 0 || size < 0 || int64(body)+size > int64(end) {
+			return out, false
+		}
+		out = append(out, ebmlElement{id: id, start: i, body: body, end: body + int(size)})
+		i = body + int(size)
+	}
+	return out, true
+}
+
+// findEBMLTracks returns the Tracks element of the first Segment and the
+// element after it, when there is one.
+func findEBMLTracks(data []byte) (tracks ebmlElement, next *ebmlElement, err error) {
+	i := 0
+	for i < len(data) {
+		id, idLen := readEBMLID(data, i)
+		if id == 0 {
+			break
+		}
+		size, sLen := readEBMLSize(data, i+idLen)
+		body := i + idLen + sLen
+		if id != ebmlIDSegment {
+			if sLen == 0 || size < 0 {
+				break
+			}
+			i = body + int(size)
+			continue
+		}
+		end := len(data)
+		if size >= 0 && int64(body)+size <= int64(len(data)) {
+			end = body + int(size)
+		}
+		for j := body; j < end; {
+			id, idLen := readEBMLID(data[:end], j)
+			if id == 0 {
+				break
+			}
+			size, sLen := readEBMLSize(data[:end], j+idLen)
+			b := j + idLen + sLen
+			if sLen == 0 || size < 0 || int64(b)+size > int64(end) {
+				// A live-written Cluster of unknown size: Tracks comes first.
+				break
+			}
+			el := ebmlElement{id: id, start: j, body: b, end: b + int(size)}
+			if id == ebmlIDTracks {
+				id, idLen := readEBMLID(data[:end], el.end)
+				size, sLen := readEBMLSize(data[:end], el.end+idLen)
+				if id == 0 || sLen == 0 || size < 0 || int64(el.end+idLen+sLen)+size > int64(end) {
+					return el, nil, nil
+				}
+				body := el.end + idLen + sLen
+				return el, &ebmlElement{id: id, start: el.end, body: body, end: body + int(size)}, nil
+			}
+			j = el.end
+		}
+		break
+	}
+	return ebmlElement{}, nil, fmt.Errorf("Matroska file has no Tracks element")
+}
+
+// patchMKVTracks applies edits to the TrackEntry elements of data in place.
+func patchMKVTracks(data []byte, edits []trackEdit) error {
+	tracks, next, err := findEBMLTracks(data)
+	if err != nil {
+		return err
+	}
+	children, ok := ebmlChildren(data, tracks.body, tracks.end)
+	if !ok {
+		return fmt.Errorf("Matroska Tracks element is malformed")
+	}
+	var entries []ebmlElement
+	for _, c := range children {
+		if c.id == ebmlIDTrackEntry {
+			entries = append(entries, c)
+		}
+	}
+	byTrack := map[int][]trackEdit{}
+	for _, e := range edits {
+		if e.track > len(entries) {
+			return fmt.Errorf("Track%d: file has %d tracks", e.track, len(entries))
+		}
+		byTrack[e.track] = append(byTrack[e.track], e)
+	}
+
+	var payload []byte
+	n := 0
+	for _, c := range children {
+		if c.id != ebmlIDTrackEntry {
+			payload = append(payload, data[c.start:c.end]...)
+			continue
+		}
+		n++
+		if byTrack[n] == nil {
+			payload = append(payload, data[c.start:c.end]...)
+			continue
+		}
+		entry, err := editEBMLTrackEntry(data[c.body:c.end], byTrack[n], n)
+		if err != nil {
+			return err
+		}
+		payload = append(payload, packEBML(c.id, entry, 0)...)
+	}
+	fixEBMLCRC(payload)
+
+	// The room to fill: Tracks, and the Void after it if there is one.
+	room := tracks.end - tracks.start
+	if next != nil && next.id == ebmlIDVoid {
+		room = next.end - tracks.start
+	}
+	out, ok := fitEBML(ebmlIDTracks, payload, room)
+	if !ok {
+		grow := len(packEBML(ebmlIDTracks, payload, 0)) - room
+		return fmt.Errorf("Matroska Tracks element would grow by %d bytes and there is no padding (EBML Void) after it to take them; remux the file to change this track", grow)
+	}
+	copy(data[tracks.start:], out)
+	return nil
+}
+
+// editEBMLTrackEntry returns the payload of a TrackEntry with edits
+// applied. A language is written to Language, plus LanguageBCP47 when it
+// has a region or script; deleting it sets "und", since a missing
+// Language means English.
+func editEBMLTrackEntry(entry []byte, edits []trackEdit, track int) ([]byte, error) {
+	for _, e := range edits {
+		switch e.field {
+		case "language":
+			code, tag := "und", ""
+			if !e.del {
+				var err error
+				if code, tag, err = trackLanguage(e.val); err != nil {
+					return nil, err
+				}
+			}
+			repl := packEBML(ebmlIDLanguage, []byte(code), 0)
+			if tag != "" {
+				repl = append(repl, packEBML(ebmlIDLanguageBCP47, []byte(tag), 0)...)
+			}
+			var err error
+			if entry, err = replaceEBMLChildren(entry, repl, ebmlIDLanguage, ebmlIDLanguageBCP47); err != nil {
+				return nil, fmt.Errorf("Track%d: %w", track, err)
+			}
+		case "title":
+			var repl []byte
+			if !e.del {
+				repl = packEBML(ebmlIDName, []byte(e.val), 0)
+			}
+			var err error
+			if entry, err = replaceEBMLChildren(entry, repl, ebmlIDName); err != nil {
+				return nil, fmt.Errorf("Track%d: %w", track, err)
+			}
+		}
+	}
+	fixEBMLCRC(entry)
+	return entry, nil
+}
+
+// replaceEBMLChildren removes the children of payload with one of ids and
+// puts repl where the first of them was, or at the end.
+func replaceEBMLChildren(payload, repl []byte, ids ...uint32) ([]byte, error) {
+	children, ok := ebmlChildren(payload, 0, len(payload))
+	if !ok {
+		return nil, fmt.Errorf("TrackEntry is malformed")
+	}
+	var out []byte
+	placed := false
+	for _, c := range children {
+		match := false
+		for _, id := range ids {
+			match = match || c.id == id
+		}
+		if !match {
+			out = append(out, payload[c.start:c.end]...)
+			continue
+		}
+		if !placed {
+			out = append(out, repl...)
+			placed = true
+		}
+	}
+	if !placed {
+		out = append(out, repl...)
+	}
+	return out, nil
+}
+
+// fixEBMLCRC recomputes the CRC-32 element that opens a master element's
+// payload, if it has one: the IEEE CRC of the rest of the payload, stored
+// little-endian.
+func fixEBMLCRC(payload []byte) {
+	if len(payload) >= 6 && payload[0] == ebmlIDCRC32 && payload[1] == 0x84 {
+		binary.LittleEndian.PutUint32(payload[2:6], crc32.ChecksumIEEE(payload[6:]))
+	}
+}
+
+// packEBML encodes an element with its size written in width bytes, or
+// the fewest that hold it when width is 0. It returns nil when the size
+// does not fit in width.
+func packEBML(id uint32, payload []byte, width int) []byte {
+	n := int64(len(payload))
+	if width == 0 {
+		width = 1
+		for width < 8 && n >= 1<<(7*width)-1 {
+			width++
+		}
+	}
+	if n >= 1<<(7*width)-1 {
+		return nil
+	}
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	size := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		size[i] = byte(n)
+		n >>= 8
+	}
+	size[0] |= 0x80 >> (width - 1)
+	out = append(out, size...)
+	return append(out, payload...)
+}
+
+// fitEBML encodes an element to exactly room bytes, trying each width of
+// its size field and filling what is left with an EBML Void, which takes
+// at least two bytes.
+func fitEBML(id uint32, payload []byte, room int) ([]byte, bool) {
+	for width := 1; width <= 8; width++ {
+		el := packEBML(id, payload, width)
+		if el == nil {
+			continue
+		}
+		switch rest := room - len(el); {
+		case rest == 0:
+			return el, true
+		case rest >= 2:
+			for vw := 1; vw <= 8; vw++ {
+				if rest-1-vw < 0 {
+					break
+				}
+				if void := packEBML(ebmlIDVoid, make([]byte, rest-1-vw), vw); void != nil {
+					return append(el, void...), true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// editMKV writes TrackN.Title and TrackN.Language edits to the tracks of
+// a Matroska or WebM file, Title to the Segment title and any other key to
+// a SimpleTag of the global Tag (see editMKVInfoTags).
+func editMKV(path, outPath string, opts core.EditOptions) error {
+	edits, set, del := splitTrackEdits(opts.Set, opts.Delete)
+	for _, e := range edits {
+		if e.field == "rotation" {
+			return fmt.Errorf("Track%d.Rotation: Matroska has no track rotation to set", e.track)
+		}
+	}
+	if len(edits) == 0 && len(set) == 0 && len(del) == 0 {
+		return fmt.Errorf("no recognised fields to set")
+	}
+	if opts.DryRun {
+		fmt.Println("Dry-run: Matroska elements would be updated:")
+		for _, e := range edits {
+			key := fmt.Sprintf("Track%d.%s", e.track, strings.ToUpper(e.field[:1])+e.field[1:])
+			if e.del {
+				fmt.Printf("  delete %s\n", key)
+			} else {
+				fmt.Printf("  %s = %s\n", key, e.val)
+			}
+		}
+		for _, k := range core.SortedKeys(set) {
+			if isMKVTitle(k) {
+				fmt.Printf("  Title = %s\n", set[k])
+			} else {
+				fmt.Printf("  %s = %s\n", mkvTagName(k), set[k])
+			}
+		}
+		for _, k := range del {
+			if isMKVTitle(k) {
+				fmt.Println("  delete Title")
+			} else {
+				fmt.Printf("  delete %s\n", mkvTagName(k))
+			}
+		}
+		return nil
+	}
+	orig, err := core.ReadFileProgress(path, opts.Progress)
+	if err != nil {
+		return err
+	}
+	data := append([]byte(nil), orig...)
+	if len(edits) > 0 {
+		if err := patchMKVTracks(data, edits); err != nil {
+			return err
+		}
+	}
+	if data, err = editMKVInfoTags(data, set, del); err != nil {
+		return err
+	}
+	if err := verifyMKVRewrite(orig, data); err != nil {
+		return err
+	}
+	return core.WriteFileProgress(outPath, data, opts.Progress)
+}
@@ -0,0 +1,298 @@
+package video
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// UnknownStructures lists the boxes of an MP4 or MOV and the elements of
+// a Matroska file that view passes over: unknown top-level and moov boxes,
+// udta, meta and ilst atoms it does not decode, non-XMP uuid boxes, and
+// Segment, Info and tag elements outside the Matroska specification.
+func (h *Handler) UnknownStructures(path string) ([]core.UnknownStructure, error) {
+	switch h.format {
+	case core.FmtMP4, core.FmtMOV:
+		return UnknownISOBMFF(path)
+	case core.FmtMKV, core.FmtWebM:
+	default:
+		return nil, fmt.Errorf("unknown-structure report supports MP4, MOV, MKV and WebM video, not %s", strings.ToUpper(string(h.format)))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return mkvUnknown(f, st.Size())
+}
+
+// ─── MP4 / MOV ───────────────────────────────────────────────────────────────
+// Only box headers are read, and the bodies of the metadata boxes, so a
+// large movie costs a few seeks.
+
+// mp4TopBoxes are the top-level boxes of ISO BMFF and QuickTime files.
+var mp4TopBoxes = map[string]bool{
+	"ftyp": true, "moov": true, "mdat": true, "free": true, "skip": true,
+	"wide": true, "uuid": true, "meta": true, "moof": true, "mfra": true,
+	"sidx": true, "ssix": true, "styp": true, "emsg": true, "prft": true,
+	"pdin": true, "pnot": true,
+}
+
+// mp4MoovBoxes are the children of moov.
+var mp4MoovBoxes = map[string]bool{
+	"mvhd": true, "trak": true, "udta": true, "meta": true, "mvex": true,
+	"iods": true, "uuid": true, "free": true, "skip": true, "ctab": true,
+}
+
+// mp4ViewedAtoms are the udta and ilst atoms walkMP4Boxes decodes, and
+// the cover art that cover list reads.
+var mp4ViewedAtoms = map[string]bool{
+	"\xa9nam": true, "\xa9ART": true, "\xa9alb": true, "\xa9day": true,
+	"\xa9gen": true, "\xa9cmt": true, "\xa9lyr": true, "\xa9too": true,
+	"\xa9wrt": true, "aART": true, "cprt": true, "desc": true, "ldes": true,
+	"tvsh": true, "tvsn": true, "tves": true, "tven": true, "purl": true,
+	"catg": true, "keyw": true, "stik": true, "tmpo": true, "cpil": true,
+	"hdvd": true, "rtng": true, "trkn": true, "disk": true, "\xa9grp": true,
+	"\xa9xyz": true, "loci": true, "----": true, "covr": true,
+	"meta": true, "uuid": true, "free": true, "skip": true,
+}
+
+// UnknownISOBMFF lists the structures of an MP4-family file that view
+// passes over. M4A files are reported through it by the audio package.
+func UnknownISOBMFF(path string) ([]core.UnknownStructure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var out []core.UnknownStructure
+	for _, b := range readMP4Spans(f, 0, st.Size()) {
+		switch {
+		case b.typ == "uuid":
+			out = appendUUID(out, f, b)
+		case !mp4TopBoxes[b.typ]:
+			out = appendBox(out, "top-level box", b)
+		case b.typ == "moov":
+			for _, c := range readMP4Spans(f, int64(b.body), int64(b.end)) {
+				switch {
+				case c.typ == "uuid":
+					out = appendUUID(out, f, c)
+				case !mp4MoovBoxes[c.typ]:
+					out = appendBox(out, "moov box", c)
+				case c.typ == "udta":
+					out = mp4UnknownIn(out, f, c, "udta atom")
+				case c.typ == "meta":
+					out = mp4UnknownMeta(out, f, c)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// mp4UnknownIn lists the children of box that view does not decode,
+// looking into meta.
+func mp4UnknownIn(out []core.UnknownStructure, r io.ReadSeeker, box mp4Span, kind string) []core.UnknownStructure {
+	for _, c := range readMP4Spans(r, int64(box.body), int64(box.end)) {
+		switch {
+		case c.typ == "meta":
+			out = mp4UnknownMeta(out, r, c)
+		case c.typ == "uuid":
+			out = appendUUID(out, r, c)
+		case !mp4ViewedAtoms[c.typ]:
+			out = appendBox(out, kind, c)
+		}
+	}
+	return out
+}
+
+// mp4UnknownMeta lists the children of a meta box other than its handler,
+// keys and item list, and the unknown atoms of an iTunes item list. The
+// items of a QuickTime keys list are numbered, not named, and are left to
+// the mdta reader.
+func mp4UnknownMeta(out []core.UnknownStructure, r io.ReadSeeker, meta mp4Span) []core.UnknownStructure {
+	if head := readMP4Body(r, meta, 8); len(head) == 8 && string(head[4:8]) != "hdlr" {
+		meta.body += 4 // ISO meta: version and flags
+	}
+	children := readMP4Spans(r, int64(meta.body), int64(meta.end))
+	keys := false
+	for _, c := range children {
+		keys = keys || c.typ == "keys"
+	}
+	for _, c := range children {
+		switch c.typ {
+		case "hdlr", "keys", "free", "skip":
+		case "ilst":
+			if !keys {
+				out = mp4UnknownIn(out, r, c, "ilst atom")
+			}
+		default:
+			out = appendBox(out, "meta box", c)
+		}
+	}
+	return out
+}
+
+// appendUUID reports a uuid box unless it holds XMP.
+func appendUUID(out []core.UnknownStructure, r io.ReadSeeker, b mp4Span) []core.UnknownStructure {
+	id := readMP4Body(r, b, 16)
+	if isXMPUUID(id) {
+		return out
+	}
+	return append(out, core.UnknownStructure{Kind: "uuid box", ID: hex.EncodeToString(id), Bytes: int64(b.end - b.start)})
+}
+
+func appendBox(out []core.UnknownStructure, kind string, b mp4Span) []core.UnknownStructure {
+	return append(out, core.UnknownStructure{Kind: kind, ID: mp4TypeName(b.typ), Bytes: int64(b.end - b.start)})
+}
+
+// mp4TypeName prints a box type as tools show it, with © for 0xA9 and
+// other bytes outside printable ASCII escaped.
+func mp4TypeName(typ string) string {
+	var sb strings.Builder
+	for i := 0; i < len(typ); i++ {
+		switch c := typ[i]; {
+		case c == 0xA9:
+			sb.WriteString("©")
+		case c < 0x20 || c > 0x7E:
+			fmt.Fprintf(&sb, "\\x%02X", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// ─── Matroska ────────────────────────────────────────────────────────────────
+// The Segment's children are found from their headers alone; only Info
+// and Tags, which are small, are read whole. A live-written Cluster of
+// unknown size cannot be skipped, so the walk stops there.
+
+const (
+	ebmlIDCues     = 0x1C53BB6B
+	ebmlIDChapters = 0x1043A770
+)
+
+// mkvSegmentIDs are the top-level elements of a Segment.
+var mkvSegmentIDs = map[uint32]bool{
+	ebmlIDSeekHead: true, ebmlIDInfo: true, ebmlIDTracks: true, ebmlIDCluster: true,
+	ebmlIDCues: true, ebmlIDAttachments: true, ebmlIDChapters: true, ebmlIDTags: true,
+	ebmlIDVoid: true, ebmlIDCRC32: true,
+}
+
+// mkvInfoIDs are the children of Info: SegmentUUID, SegmentFilename,
+// PrevUUID, PrevFilename, NextUUID, NextFilename, SegmentFamily,
+// ChapterTranslate, TimestampScale and those view reads.
+var mkvInfoIDs = map[uint32]bool{
+	0x73A4: true, 0x7384: true, 0x3CB923: true, 0x3C83AB: true,
+	0x3EB923: true, 0x3E83BB: true, 0x4444: true, 0x6924: true, 0x2AD7B1: true,
+	ebmlIDDuration: true, ebmlIDDateUTC: true, ebmlIDTitle: true,
+	ebmlIDMuxingApp: true, ebmlIDWritingApp: true, ebmlIDVoid: true, ebmlIDCRC32: true,
+}
+
+// mkvTagIDs are the children of Tags, Tag, Targets and SimpleTag; besides
+// those named here, TagLanguage, TagLanguageBCP47, TagDefault,
+// TagDefaultBogus, TargetType and the four Tag*UIDs.
+var mkvTagIDs = map[uint32]bool{
+	ebmlIDTag: true, ebmlIDTargets: true, ebmlIDSimpleTag: true,
+	ebmlIDTagName: true, ebmlIDTagString: true, ebmlIDTagBinary: true,
+	ebmlIDTargetTypeValue: true, ebmlIDVoid: true, ebmlIDCRC32: true,
+	0x447A: true, 0x447B: true, 0x4484: true, 0x44B4: true, 0x63CA: true,
+	0x63C5: true, 0x63C9: true, 0x63C4: true, 0x63C6: true,
+}
+
+// mkvUnknown walks the first Segment of a Matroska file of size bytes.
+func mkvUnknown(r io.ReaderAt, size int64) ([]core.UnknownStructure, error) {
+	var body, end int64
+	for pos := int64(0); ; {
+		id, b, n, ok := readEBMLHeaderAt(r, pos, size)
+		if !ok {
+			return nil, fmt.Errorf("Matroska file has no Segment")
+		}
+		if id == ebmlIDSegment {
+			body, end = min(b, size), size
+			if n >= 0 && b+n < size {
+				end = b + n
+			}
+			break
+		}
+		if n < 0 || b+n > size {
+			return nil, fmt.Errorf("Matroska file has no Segment")
+		}
+		pos = b + n
+	}
+
+	var out []core.UnknownStructure
+	for p := body; p < end; {
+		id, b, n, ok := readEBMLHeaderAt(r, p, end)
+		if !ok || n < 0 || b+n > end {
+			break
+		}
+		switch {
+		case !mkvSegmentIDs[id]:
+			out = append(out, core.UnknownStructure{Kind: "Segment element", ID: fmt.Sprintf("0x%X", id), Bytes: b + n - p})
+		case (id == ebmlIDInfo || id == ebmlIDTags) && n <= maxMKVTags:
+			buf := make([]byte, n)
+			if _, err := r.ReadAt(buf, b); err != nil {
+				return out, err
+			}
+			if id == ebmlIDInfo {
+				out = mkvUnknownIn(out, buf, mkvInfoIDs, "Info element", false)
+			} else {
+				out = mkvUnknownIn(out, buf, mkvTagIDs, "tag element", true)
+			}
+		}
+		p = b + n
+	}
+	return out, nil
+}
+
+// mkvUnknownIn lists the elements of data not in known, descending into
+// master elements when deep is set.
+func mkvUnknownIn(out []core.UnknownStructure, data []byte, known map[uint32]bool, kind string, deep bool) []core.UnknownStructure {
+	children, _ := ebmlChildren(data, 0, len(data))
+	for _, c := range children {
+		switch {
+		case !known[c.id]:
+			out = append(out, core.UnknownStructure{Kind: kind, ID: fmt.Sprintf("0x%X", c.id), Bytes: int64(c.end - c.start)})
+		case deep && (c.id == ebmlIDTag || c.id == ebmlIDTargets || c.id == ebmlIDSimpleTag):
+			out = mkvUnknownIn(out, data[c.body:c.end], known, kind, deep)
+		}
+	}
+	return out
+}
+
+// readEBMLHeaderAt reads the ID and size of the element at pos; n is -1
+// for an unknown size.
+func readEBMLHeaderAt(r io.ReaderAt, pos, limit int64) (id uint32, body, n int64, ok bool) {
+	if pos < 0 || pos+2 > limit {
+		return 0, 0, 0, false
+	}
+	buf := make([]byte, 12)
+	if limit-pos < int64(len(buf)) {
+		buf = buf[:limit-pos]
+	}
+	k, _ := r.ReadAt(buf, pos)
+	buf = buf[:k]
+	id, idLen := readEBMLID(buf, 0)
+	if id == 0 {
+		return 0, 0, 0, false
+	}
+	n, sLen := readEBMLSize(buf, idLen)
+	if sLen == 0 {
+		return 0, 0, 0, false
+	}
+	return id, pos + int64(idLen+sLen), n, true
+}
@@ -35,7 +35,8 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		CanView:     true,
 		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "ISO Base Media File Format atoms. Reads and strips udta/©/meta atoms.",
+		StripsGPS:   true,
+		Notes:       "ISO Base Media File Format atoms. Reads and strips udta/©/meta atoms, mdta keys and XMP uuid boxes; edit com.apple.quicktime.* keys by name.",
 		EditableFields: []string{
 			"title", "artist", "album", "comment", "year",
 			"genre", "description", "copyright",
@@ -47,9 +48,14 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "video",
 		MIMETypes:   []string{"video/quicktime"},
 		CanView:     true,
-		CanEdit:     false,
+		CanEdit:     true,
 		CanStrip:    true,
-		Notes:       "QuickTime atoms. Strip removes udta atom.",
+		StripsGPS:   true,
+		Notes:       "QuickTime atoms. Edits mdta keys (com.apple.quicktime.*) by full or short name — title, creationdate, location — and iTunes atoms by atom name. Strip removes udta, keys and XMP uuid metadata.",
+		EditableFields: []string{
+			"title", "creationdate", "location", "make", "model",
+			"software", "author", "description", "comment", "keywords",
+		},
 	},
 	core.FmtMKV: {
 		Name:        "Matroska MKV",
@@ -57,9 +63,12 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "video",
 		MIMETypes:   []string{"video/x-matroska"},
 		CanView:     true,
-		CanEdit:     false,
-		CanStrip:    false,
-		Notes:       "EBML-based container. View only in v0.1.2.",
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "EBML-based container. Edits the title, tags (any other key, as a global SimpleTag) and track titles and languages (TrackN.Title, TrackN.Language) without moving clusters. Strip removes the title, date, tags and attachments.",
+		EditableFields: []string{
+			"Title", "ARTIST", "DATE_RELEASED", "COMMENT", "DESCRIPTION",
+		},
 	},
 	core.FmtWebM: {
 		Name:        "WebM",
@@ -67,9 +76,12 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 		MediaType:   "video",
 		MIMETypes:   []string{"video/webm"},
 		CanView:     true,
-		CanEdit:     false,
-		CanStrip:    false,
-		Notes:       "EBML-based container. View only in v0.1.2.",
+		CanEdit:     true,
+		CanStrip:    true,
+		Notes:       "EBML-based container. Edits the title, tags (any other key, as a global SimpleTag) and track titles and languages (TrackN.Title, TrackN.Language) without moving clusters. Strip removes the title, date, tags and attachments.",
+		EditableFields: []string{
+			"Title", "ARTIST", "DATE_RELEASED", "COMMENT", "DESCRIPTION",
+		},
 	},
 	core.FmtAVI: {
 		Name:        "AVI",
@@ -109,6 +121,9 @@ var formatInfo = map[core.FormatID]core.FormatInfo{
 
 func (h *Handler) View(path string) (*core.Metadata, error) {
 	m := &core.Metadata{FilePath: path}
+	if err := core.CheckSize(h.format, path); err != nil {
+		return m, err
+	}
 	ext := strings.ToLower(filepath.Ext(path))
 	_ = ext
 
@@ -163,6 +178,10 @@ var itunesAtomNames = map[string]string{
 	"hdvd":    "HDVideo",
 	"stik":    "MediaKind",
 	"rtng":    "ContentRating",
+	"trkn":    "TrackNumber",
+	"disk":    "DiscNumber",
+	"\xa9grp": "Grouping",
+	"covr":    "Cover",
 }
 
 type mp4Box struct {
@@ -181,6 +200,8 @@ func viewMP4(path string, m *core.Metadata) (*core.Metadata, error) {
 
 	// Walk top-level boxes
 	walkMP4Boxes(f, 0, -1, m, 0)
+	addMP4Tracks(f, m)
+	addMP4FragmentInfo(f, m)
 	return m, nil
 }
 
@@ -188,6 +209,15 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 	if depth > 8 {
 		return
 	}
+	if limit < 0 {
+		// The top level runs to the end of the file.
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return
+		}
+		r.Seek(start, io.SeekStart)
+		limit = end
+	}
 	pos := start
 	for {
 		if limit >= 0 && pos >= limit {
@@ -215,6 +245,11 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 		}
 
 		curPos, _ := r.Seek(0, io.SeekCurrent)
+		// Sizes come from the file: a box that claims more than its
+		// parent holds is corrupt, and reading it would allocate that much.
+		if dataSize < 0 || dataSize > limit-curPos {
+			break
+		}
 
 		switch boxType {
 		case "ftyp":
@@ -231,9 +266,21 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 		case "moov", "udta", "meta", "ilst":
 			// Container boxes — recurse
 			if boxType == "meta" {
-				// meta has a 4-byte version/flags prefix
-				r.Seek(4, io.SeekCurrent)
-				walkMP4Boxes(r, curPos+4, curPos+dataSize, m, depth+1)
+				// ISO meta has a 4-byte version/flags prefix; QuickTime's
+				// (used for mdta keys) starts straight with hdlr.
+				body := make([]byte, dataSize)
+				io.ReadFull(r, body)
+				off := int64(4)
+				if len(body) >= 8 && string(body[4:8]) == "hdlr" {
+					off = 0
+				}
+				// A meta box too short for its version/flags holds
+				// nothing and is skipped.
+				short := dataSize < off || int64(len(body)) < off
+				if !short && !addMdtaFields(body[off:], m) {
+					r.Seek(curPos+off, io.SeekStart)
+					walkMP4Boxes(r, curPos+off, curPos+dataSize, m, depth+1)
+				}
 			} else {
 				walkMP4Boxes(r, curPos, curPos+dataSize, m, depth+1)
 			}
@@ -263,13 +310,14 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 			}
 			r.Seek(curPos+dataSize, io.SeekStart)
 
-		case "©nam", "©ART", "©alb", "©day", "©gen", "©cmt", "©lyr",
-			"©too", "©wrt", "aART", "cprt", "desc", "ldes",
-			"tvsh", "tvsn", "tves", "tven", "purl", "catg", "keyw":
+		case "\xa9nam", "\xa9ART", "\xa9alb", "\xa9day", "\xa9gen", "\xa9cmt", "\xa9lyr",
+			"\xa9too", "\xa9wrt", "aART", "cprt", "desc", "ldes",
+			"tvsh", "tvsn", "tves", "tven", "purl", "catg", "keyw",
+			"stik", "tmpo", "cpil", "hdvd", "rtng", "trkn", "disk", "\xa9grp":
 			// iTunes metadata — value is in a child 'data' atom
 			child := make([]byte, dataSize)
 			io.ReadFull(r, child)
-			val := extractiTunesData(child)
+			val := itunesDataValue(boxType, child)
 			if val != "" {
 				name := itunesAtomNames[boxType]
 				if name == "" {
@@ -283,6 +331,20 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 				})
 			}
 
+		case "\xa9xyz", "loci":
+			// Where the video was shot
+			child := make([]byte, dataSize)
+			io.ReadFull(r, child)
+			addMP4Location(boxType, child, m)
+
+		case "uuid":
+			// XMP packet in a uuid box (16-byte extended type, then XML)
+			child := make([]byte, dataSize)
+			io.ReadFull(r, child)
+			if isXMPUUID(child) {
+				parseXMPIntoMP4(child[16:], m)
+			}
+
 		case "----":
 			// Custom freeform atom: ----/mean/name/data
 			child := make([]byte, dataSize)
@@ -295,6 +357,9 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 					Category: "iTunes Custom",
 					Editable: false,
 				})
+				if strings.HasSuffix(key, ":iTunSMPB") {
+					m.Fields = append(m.Fields, GaplessFields(val, "Gapless Playback")...)
+				}
 			}
 
 		default:
@@ -305,18 +370,6 @@ func walkMP4Boxes(r io.ReadSeeker, start, limit int64, m *core.Metadata, depth i
 	}
 }
 
-func extractiTunesData(data []byte) string {
-	// data atom: 4 size + 4 "data" + 1 version + 3 flags + 4 locale + value
-	if len(data) < 16 {
-		return ""
-	}
-	if string(data[4:8]) != "data" {
-		return ""
-	}
-	// flag byte 7: 1=UTF-8, 13=JPEG, 14=PNG
-	return strings.TrimRight(string(data[16:]), "\x00")
-}
-
 func parseFreeformAtom(data []byte) (key, val string) {
 	// Walk mean / name / data sub-atoms
 	i := 0
@@ -385,6 +438,7 @@ func viewMKV(path string, m *core.Metadata) (*core.Metadata, error) {
 	}
 
 	parseEBML(data, m)
+	addMKVSeekedTags(f, data, m)
 	return m, nil
 }
 
@@ -399,6 +453,11 @@ func parseEBML(data []byte, m *core.Metadata) {
 		size, sizeLen := readEBMLSize(data, i)
 		i += sizeLen
 
+		if id == ebmlIDSegment && (size < 0 || i+int(size) > len(data)) {
+			// Only the start of a large or live-written Segment was read.
+			parseEBML(data[i:], m)
+			break
+		}
 		if size < 0 || i+int(size) > len(data)+1 {
 			break
 		}
@@ -415,6 +474,8 @@ func parseEBML(data []byte, m *core.Metadata) {
 			parseEBMLInfo(payload, m)
 		case ebmlIDTags:
 			parseEBMLTags(payload, m)
+		case ebmlIDTracks:
+			parseEBMLTracks(payload, m)
 		case ebmlIDSegment:
 			parseEBML(payload, m) // recurse into Segment
 		}
@@ -464,7 +525,7 @@ func parseEBMLInfo(data []byte, m *core.Metadata) {
 				Key:      "Title",
 				Value:    string(payload),
 				Category: "MKV Info",
-				Editable: false,
+				Editable: true,
 			})
 		case ebmlIDMuxingApp:
 			m.Fields = append(m.Fields, core.MetaField{
@@ -545,7 +606,7 @@ func parseEBMLSimpleTag(data []byte, m *core.Metadata) {
 			Key:      name,
 			Value:    val,
 			Category: "MKV Tags",
-			Editable: false,
+			Editable: true,
 		})
 	}
 }
@@ -596,32 +657,88 @@ func readEBMLSize(data []byte, pos int) (size int64, length int) {
 		return int64(b&0x07)<<32 | int64(data[pos+1])<<24 | int64(data[pos+2])<<16 |
 			int64(data[pos+3])<<8 | int64(data[pos+4]), 5
 	}
+	// Six to eight bytes, as muxers write the Segment size; all ones is
+	// an unknown size.
+	for n := 6; n <= 8; n++ {
+		if b&(0x80>>(n-1)) == 0 {
+			continue
+		}
+		if pos+n > len(data) {
+			break
+		}
+		size = int64(b & (0xFF >> n))
+		all := size == int64(0xFF>>n)
+		for _, c := range data[pos+1 : pos+n] {
+			size = size<<8 | int64(c)
+			all = all && c == 0xFF
+		}
+		if all {
+			return -1, n
+		}
+		return size, n
+	}
 	return -1, 1
 }
 
 // ─── AVI ─────────────────────────────────────────────────────────────────────
 
 func viewAVI(path string, m *core.Metadata) (*core.Metadata, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return m, err
 	}
-	if len(data) < 12 {
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return m, err
+	}
+	if st.Size() < 12 {
 		return m, fmt.Errorf("AVI too short")
 	}
 
-	// Parse RIFF/AVI INFO list
-	offset := 12
-	for offset+8 <= len(data) {
-		chunkID := string(data[offset : offset+4])
-		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
-		offset += 8
-		if offset+chunkSize > len(data) {
-			break
+	// Parse RIFF/AVI lists by their headers; LIST movi, which holds the
+	// frames, is seeked past.
+	chunks, err := core.RIFFChunks(f, 12, st.Size())
+	if err != nil {
+		return m, err
+	}
+	for _, c := range chunks {
+		if c.ID != "LIST" || c.Size < 4 {
+			continue
+		}
+		kind, err := core.ReadRange(f, c.Off, 4)
+		if err != nil {
+			return m, err
 		}
-		if chunkID == "LIST" && chunkSize >= 4 && string(data[offset:offset+4]) == "INFO" {
-			pos := offset + 4
-			end := offset + chunkSize
+		switch string(kind) {
+		case "hdrl":
+			sub, err := core.RIFFChunks(f, c.Off+4, c.Off+c.Size)
+			if err != nil {
+				return m, err
+			}
+			for _, s := range sub {
+				if s.ID != "avih" || s.Size < 40 {
+					continue
+				}
+				// Main AVI header
+				data, err := core.ReadRange(f, s.Off, 40)
+				if err != nil || len(data) < 40 {
+					break
+				}
+				width := binary.LittleEndian.Uint32(data[32:36])
+				height := binary.LittleEndian.Uint32(data[36:40])
+				m.Fields = append(m.Fields,
+					core.MetaField{Key: "Width", Value: fmt.Sprintf("%d px", width), Category: "AVI Header", Editable: false},
+					core.MetaField{Key: "Height", Value: fmt.Sprintf("%d px", height), Category: "AVI Header", Editable: false},
+				)
+			}
+		case "INFO":
+			data, err := c.Read(f)
+			if err != nil {
+				return m, err
+			}
+			pos := 4
+			end := len(data)
 			for pos+8 <= end {
 				infoID := string(data[pos : pos+4])
 				infoSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
@@ -644,44 +761,57 @@ func viewAVI(path string, m *core.Metadata) (*core.Metadata, error) {
 				}
 			}
 		}
-		if chunkID == "avih" && chunkSize >= 32 {
-			// Main AVI header
-			width := binary.LittleEndian.Uint32(data[offset+32 : offset+36])
-			height := binary.LittleEndian.Uint32(data[offset+36 : offset+40])
-			m.Fields = append(m.Fields,
-				core.MetaField{Key: "Width", Value: fmt.Sprintf("%d px", width), Category: "AVI Header", Editable: false},
-				core.MetaField{Key: "Height", Value: fmt.Sprintf("%d px", height), Category: "AVI Header", Editable: false},
-			)
-		}
-		offset += chunkSize
-		if chunkSize%2 != 0 {
-			offset++
-		}
 	}
 	return m, nil
 }
 
 // ─── WMV / ASF ───────────────────────────────────────────────────────────────
 
+var asfHeaderGUID = []byte{
+	0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11,
+	0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C,
+}
+
 var asfContentDescGUID = []byte{
 	0x33, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11,
 	0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C,
 }
 
 func viewWMV(path string, m *core.Metadata) (*core.Metadata, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return m, err
 	}
-	if len(data) < 30 {
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return m, err
+	}
+	hdr, err := core.ReadRange(f, 0, 30)
+	if err != nil {
+		return m, err
+	}
+	if len(hdr) < 30 {
 		return m, fmt.Errorf("WMV too short")
 	}
+	if !bytes.Equal(hdr[:16], asfHeaderGUID) {
+		return m, fmt.Errorf("WMV has no ASF Header Object")
+	}
 
-	// Walk ASF objects
-	offset := 30 // skip ASF Header Object header (16 GUID + 8 size + 4 num headers + 2 reserved)
-	// Actually ASF Header is: 16 GUID + 8 size total. Objects start at 30.
-	// Let's parse from 0
-	offset = 0
+	// Every metadata object lives in the Header Object at the front; the
+	// Data Object after it, with the media, is never read.
+	size := binary.LittleEndian.Uint64(hdr[16:24])
+	if size > uint64(st.Size()) {
+		size = uint64(st.Size())
+	}
+	data, err := core.ReadRange(f, 0, int(size))
+	if err != nil {
+		return m, err
+	}
+
+	// Walk the header's objects, which start after its 16-byte GUID, 8-byte
+	// size, 4-byte object count and 2 reserved bytes.
+	offset := 30
 	limit := len(data)
 	for offset+24 <= limit {
 		guid := data[offset : offset+16]
@@ -704,13 +834,10 @@ func parseASFContentDesc(data []byte, m *core.Metadata) {
 		return
 	}
 	fields := []string{"Title", "Author", "Copyright", "Description", "Rating"}
-	pos := 0
-	for _, name := range fields {
-		if pos+2 > len(data) {
-			break
-		}
-		fLen := int(binary.LittleEndian.Uint16(data[pos : pos+2]))
-		pos += 2
+	// Five 16-bit lengths come first, then the strings themselves.
+	pos := 10
+	for i, name := range fields {
+		fLen := int(binary.LittleEndian.Uint16(data[2*i : 2*i+2]))
 		if pos+fLen > len(data) {
 			break
 		}
@@ -881,10 +1008,15 @@ func math_Float64frombits(b uint64) float64 {
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
-	case core.FmtMP4:
-		return editMP4(path, out, opts)
+	case core.FmtMP4, core.FmtMOV:
+		return editMP4(path, out, opts, h.format == core.FmtMOV)
+	case core.FmtMKV, core.FmtWebM:
+		return editMKV(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanEdit {
@@ -896,7 +1028,20 @@ func (h *Handler) Edit(path string, outPath string, opts core.EditOptions) error
 
 // editMP4 updates iTunes-style metadata atoms.
 // Strategy: find or create moov/udta/meta/ilst and set atom children.
-func editMP4(path, outPath string, opts core.EditOptions) error {
+// In a MOV (mov set) the short names of QuickTime keys address the keys.
+func editMP4(path, outPath string, opts core.EditOptions, mov bool) error {
+	orig, err := core.ReadFileProgress(path, opts.Progress)
+	if err != nil {
+		return err
+	}
+	if err := checkMP4Rewritable(bytes.NewReader(orig)); err != nil {
+		return err
+	}
+	if err := checkMP4SizeLimits(orig); err != nil {
+		return err
+	}
+	data := orig
+
 	if opts.DryRun {
 		fmt.Println("Dry-run: MP4 metadata atoms would be updated:")
 		for k, v := range opts.Set {
@@ -905,100 +1050,237 @@ func editMP4(path, outPath string, opts core.EditOptions) error {
 		return nil
 	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
+	// TrackN.Title and TrackN.Language belong to a trak; reverse-DNS keys
+	// (com.apple.quicktime.*) live in the mdta keys box; everything else
+	// maps to an iTunes ilst atom.
+	trackEdits, set, del := splitTrackEdits(opts.Set, opts.Delete)
+	if mov {
+		set, del = quickTimeNames(set, del)
+	}
+	existingKeys := mdtaKeyNames(data)
+	mdtaSet := map[string]string{}
+	var mdtaDel, ilstDel []string
+	for _, k := range del {
+		if isMdtaKey(k, existingKeys) {
+			mdtaDel = append(mdtaDel, k)
+		} else {
+			ilstDel = append(ilstDel, k)
+		}
 	}
 
 	// Build new ilst children
 	var entries []struct{ name, val string }
-	for k, v := range opts.Set {
+	for _, k := range core.SortedKeys(set) {
+		v := set[k]
+		if isMdtaKey(k, existingKeys) {
+			if mdtaSet[k], err = quickTimeValue(k, v); err != nil {
+				return err
+			}
+			continue
+		}
 		// Map friendly names to atom keys
 		atomKey := ""
 		for aKey, aName := range itunesAtomNames {
-			if strings.EqualFold(aName, k) || strings.EqualFold(aKey, k) {
+			if strings.EqualFold(aName, k) || strings.EqualFold(aKey, atomName(k)) {
 				atomKey = aKey
 				break
 			}
 		}
 		if atomKey == "" {
-			atomKey = k
+			atomKey = atomName(k)
 		}
 		entries = append(entries, struct{ name, val string }{name: atomKey, val: v})
 	}
 
-	if len(entries) == 0 && len(opts.Delete) == 0 {
+	if len(entries) == 0 && len(opts.Delete) == 0 && len(mdtaSet) == 0 && len(trackEdits) == 0 {
 		return fmt.Errorf("no recognised fields to set")
 	}
 
-	// Re-inject: find existing ilst, patch it
-	newData, err := patchMP4Ilst(data, entries, opts.Delete)
-	if err != nil {
-		return err
+	if len(trackEdits) > 0 {
+		if data, err = patchMP4Tracks(data, trackEdits); err != nil {
+			return err
+		}
 	}
 
-	return os.WriteFile(outPath, newData, 0644)
+	if len(mdtaSet) > 0 || len(mdtaDel) > 0 {
+		if data, err = patchMP4Keys(data, mdtaSet, mdtaDel); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 || len(ilstDel) > 0 {
+		// Re-inject: find existing ilst, patch it
+		if data, err = patchMP4Ilst(data, entries, ilstDel); err != nil {
+			return err
+		}
+	}
+
+	if err := verifyMP4Rewrite(orig, data); err != nil {
+		return err
+	}
+	return core.WriteFileProgress(outPath, data, opts.Progress)
 }
 
 func patchMP4Ilst(data []byte, entries []struct{ name, val string }, delKeys []string) ([]byte, error) {
-	// Build new ilst content
+	atoms := make([][]byte, len(entries))
+	for i, e := range entries {
+		atom, err := buildIlstAtom(e.name, e.val)
+		if err != nil {
+			return nil, err
+		}
+		atoms[i] = atom
+	}
+	return rewriteMP4Ilst(data, func(children []ilstChild) []ilstChild {
+		for _, k := range delKeys {
+			children = removeIlstChild(children, strings.TrimPrefix(atomName(k), "----:"))
+		}
+		for i, e := range entries {
+			if ilstType(e.name) == "----" {
+				children = removeIlstChild(children, strings.TrimPrefix(e.name, "----:"))
+			}
+			children = setIlstChild(children, ilstType(e.name), atoms[i])
+		}
+		return children
+	})
+}
+
+// rewriteMP4Ilst replaces the children of moov/udta/meta/ilst with what
+// edit returns for them, creating the boxes when the file has none.
+func rewriteMP4Ilst(data []byte, edit func([]ilstChild) []ilstChild) ([]byte, error) {
+	// Locate moov/udta/meta/ilst; an mdta-keyed moov/meta ilst is not ours.
+	chain := findMP4Path(data, 0, len(data), "moov", "udta", "meta", "ilst")
+
+	// Start from the existing children so that atoms we don't touch —
+	// cover art, freeform ---- atoms such as iTunSMPB (gapless) and
+	// iTunNORM (Sound Check) — survive the rewrite unchanged.
+	var children []ilstChild
+	if chain != nil {
+		children = parseIlstChildren(data[chain[3].body:chain[3].end])
+	}
+	children = edit(children)
+
 	var ilstBuf bytes.Buffer
-	for _, e := range entries {
-		atomData := buildiTunesDataAtom(e.val)
-		atomSize := uint32(8 + len(atomData))
-		sizeBuf := make([]byte, 4)
-		binary.BigEndian.PutUint32(sizeBuf, atomSize)
-		ilstBuf.Write(sizeBuf)
-		ilstBuf.WriteString(e.name)
-		ilstBuf.Write(atomData)
-	}
-
-	ilstContent := ilstBuf.Bytes()
-	ilstSize := uint32(8 + len(ilstContent))
-
-	// Find and replace ilst in the binary data using simple byte search
-	ilstIdx := bytes.Index(data, []byte("ilst"))
-	if ilstIdx > 4 {
-		// Find end of existing ilst
-		existingSize := int(binary.BigEndian.Uint32(data[ilstIdx-4 : ilstIdx]))
-		newAtom := make([]byte, 4+4+len(ilstContent))
-		binary.BigEndian.PutUint32(newAtom[0:4], ilstSize)
-		copy(newAtom[4:8], []byte("ilst"))
-		copy(newAtom[8:], ilstContent)
-
-		result := make([]byte, 0, len(data))
-		result = append(result, data[:ilstIdx-4]...)
-		result = append(result, newAtom...)
-		result = append(result, data[ilstIdx-4+existingSize:]...)
-
-		// Update parent sizes
-		return fixMP4Sizes(result), nil
-	}
-
-	// No ilst found — append udta/meta/ilst at end of moov
-	moovIdx := bytes.Index(data, []byte("moov"))
-	if moovIdx < 4 {
-		return nil, fmt.Errorf("could not find moov atom")
-	}
-
-	// Build udta/meta/ilst wrapper
-	var udtaBuf bytes.Buffer
-	// meta = 8 header + 4 version/flags + ilst
-	metaContent := append([]byte{0x00, 0x00, 0x00, 0x00}, // version+flags
-		packAtom("ilst", ilstContent)...)
-	udtaContent := packAtom("meta", metaContent)
-	udtaBuf.Write(packAtom("udta", udtaContent))
-
-	// Append to moov
-	moovSize := int(binary.BigEndian.Uint32(data[moovIdx-4 : moovIdx]))
-	insertAt := moovIdx - 4 + moovSize - 0 // before moov end
-	// Actually insert before moov's closing (at moovSize offset)
-	result := make([]byte, 0, len(data)+udtaBuf.Len())
-	result = append(result, data[:insertAt]...)
-	result = append(result, udtaBuf.Bytes()...)
-	result = append(result, data[insertAt:]...)
-
-	return fixMP4Sizes(result), nil
+	for _, c := range children {
+		ilstBuf.Write(c.raw)
+	}
+	box := packAtom("ilst", ilstBuf.Bytes())
+	if chain != nil {
+		ilst := chain[3]
+		return replaceMP4Range(data, chain[:3], ilst.start, ilst.end, box), nil
+	}
+
+	// No ilst yet — wrap it in whatever part of udta/meta is missing and
+	// append it to the deepest box that exists.
+	path := []string{"moov", "udta", "meta"}
+	for n := len(path); n >= 1; n-- {
+		if c := findMP4Path(data, 0, len(data), path[:n]...); c != nil {
+			parent := c[n-1]
+			return replaceMP4Range(data, c, parent.end, parent.end, box), nil
+		}
+		switch path[n-1] {
+		case "meta":
+			box = packAtom("meta", append(append([]byte{0, 0, 0, 0}, buildIlstHdlr()...), box...))
+		case "udta":
+			box = packAtom("udta", box)
+		}
+	}
+	return nil, fmt.Errorf("could not find moov atom")
+}
+
+// ilstChild is one metadata item inside ilst, kept as raw bytes so that
+// items we do not understand are written back exactly as read.
+type ilstChild struct {
+	typ  string // atom type, e.g. "\xa9nam" or "----"
+	name string // for "----" atoms: "mean:name", e.g. "com.apple.iTunes:iTunSMPB"
+	raw  []byte // complete atom including its 8-byte header
+}
+
+func parseIlstChildren(data []byte) []ilstChild {
+	var out []ilstChild
+	i := 0
+	for i+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[i : i+4]))
+		if size < 8 || i+size > len(data) {
+			break
+		}
+		c := ilstChild{typ: string(data[i+4 : i+8]), raw: data[i : i+size]}
+		if c.typ == "----" {
+			c.name, _ = parseFreeformAtom(data[i+8 : i+size])
+		}
+		out = append(out, c)
+		i += size
+	}
+	return out
+}
+
+// atomName returns an atom name as typed, with a © entered as UTF-8 turned
+// into the single byte 0xA9 atom types use.
+func atomName(k string) string {
+	return strings.ReplaceAll(k, "©", "\xa9")
+}
+
+// matches reports whether key names this child: the atom type, its
+// friendly name, or for freeform atoms the full "mean:name" or bare name.
+func (c ilstChild) matches(key string) bool {
+	if c.typ == "----" {
+		if c.name == "" {
+			return false
+		}
+		short := c.name[strings.LastIndex(c.name, ":")+1:]
+		return strings.EqualFold(c.name, key) || strings.EqualFold(short, key)
+	}
+	return c.typ == key || strings.EqualFold(itunesAtomNames[c.typ], key)
+}
+
+func removeIlstChild(children []ilstChild, key string) []ilstChild {
+	var out []ilstChild
+	for _, c := range children {
+		if !c.matches(key) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// setIlstChild replaces the first child of the given atom type (dropping any
+// duplicates) or appends a new one.
+func setIlstChild(children []ilstChild, typ string, raw []byte) []ilstChild {
+	var out []ilstChild
+	done := false
+	for _, c := range children {
+		if c.typ == typ && typ != "----" {
+			if !done {
+				out = append(out, ilstChild{typ: typ, raw: raw})
+				done = true
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	if !done {
+		out = append(out, ilstChild{typ: typ, raw: raw})
+	}
+	return out
+}
+
+// GaplessFields decodes an iTunSMPB value (space-separated hex words:
+// reserved, encoder delay, end padding, original sample count, …) into
+// readable fields. It is shared with the M4A handler.
+func GaplessFields(val, category string) []core.MetaField {
+	words := strings.Fields(strings.Trim(val, "\x00"))
+	if len(words) < 4 {
+		return nil
+	}
+	var nums [4]uint64
+	for i := 1; i < 4; i++ {
+		if _, err := fmt.Sscanf(words[i], "%x", &nums[i]); err != nil {
+			return nil
+		}
+	}
+	return []core.MetaField{
+		{Key: "EncoderDelay", Value: fmt.Sprintf("%d samples", nums[1]), Category: category},
+		{Key: "EncoderPadding", Value: fmt.Sprintf("%d samples", nums[2]), Category: category},
+		{Key: "OriginalSampleCount", Value: fmt.Sprintf("%d", nums[3]), Category: category},
+	}
 }
 
 func buildiTunesDataAtom(val string) []byte {
@@ -1006,7 +1288,7 @@ func buildiTunesDataAtom(val string) []byte {
 	dataAtom := make([]byte, 16+len(val))
 	binary.BigEndian.PutUint32(dataAtom[0:4], uint32(16+len(val)))
 	copy(dataAtom[4:8], []byte("data"))
-	dataAtom[7] = 0x01 // UTF-8
+	dataAtom[11] = 0x01 // UTF-8 (low byte of the type indicator)
 	copy(dataAtom[16:], val)
 	return dataAtom
 }
@@ -1019,23 +1301,23 @@ func packAtom(name string, content []byte) []byte {
 	return atom
 }
 
-func fixMP4Sizes(data []byte) []byte {
-	// A simplified fix: update moov and mdat sizes
-	// For production, a full recursive fix is needed.
-	// Here we trust that patching ilst in-place keeps sizes consistent
-	// if we use exact replacement. This is a best-effort approach.
-	return data
-}
-
 // ──────────────────────────────────────────────────────────────────────────────
 // Strip
 // ──────────────────────────────────────────────────────────────────────────────
 
 func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) error {
+	if err := core.CheckSize(h.format, path); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return core.PreviewStrip(h, path, opts)
+	}
 	out := core.ResolveOutPath(path, outPath)
 	switch h.format {
 	case core.FmtMP4, core.FmtMOV:
 		return stripMP4(path, out, opts)
+	case core.FmtMKV, core.FmtWebM:
+		return stripMKV(path, out, opts)
 	default:
 		info := formatInfo[h.format]
 		if !info.CanStrip {
@@ -1046,35 +1328,77 @@ func (h *Handler) Strip(path string, outPath string, opts core.StripOptions) err
 }
 
 func stripMP4(path, outPath string, opts core.StripOptions) error {
-	data, err := os.ReadFile(path)
+	data, err := core.ReadFileProgress(path, opts.Progress)
 	if err != nil {
 		return err
 	}
+	if err := checkMP4Rewritable(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if err := checkMP4SizeLimits(data); err != nil {
+		return err
+	}
 
-	if opts.DryRun {
-		fmt.Println("Dry-run: MP4 udta/ilst metadata atoms would be removed")
-		return nil
+	if opts.StripGPS {
+		result, err := stripMP4GPS(data)
+		if err != nil {
+			return err
+		}
+		if err := verifyMP4Rewrite(data, result); err != nil {
+			return err
+		}
+		return core.WriteFileProgress(outPath, result, opts.Progress)
 	}
 
-	// Remove udta atom: find "udta" and remove the whole atom
-	result := removeMP4Atom(data, "udta")
-	return os.WriteFile(outPath, result, 0644)
+	// Remove the mdta keys meta box (items named in --keep survive), then
+	// the udta atom: find "udta" and remove the whole atom
+	result := stripMP4Keys(data, opts.KeepFields)
+	if !keepXMP(opts) {
+		result = stripMP4XMP(result)
+	}
+	kept := keptIlstChildren(result, opts.KeepFields)
+	if udta := findMP4Path(result, 0, len(result), "moov", "udta"); udta != nil {
+		result = replaceMP4Range(result, udta[:1], udta[1].start, udta[1].end, nil)
+	}
+	if len(kept) > 0 {
+		if result, err = rewriteMP4Ilst(result, func([]ilstChild) []ilstChild { return kept }); err != nil {
+			return err
+		}
+	}
+	if err := verifyMP4Rewrite(data, result); err != nil {
+		return err
+	}
+	return core.WriteFileProgress(outPath, result, opts.Progress)
 }
 
-func removeMP4Atom(data []byte, atomType string) []byte {
-	idx := bytes.Index(data, []byte(atomType))
-	if idx < 4 {
-		return data
+// keptIlstChildren returns the ilst items named in --keep, which a strip
+// writes back into a fresh udta. front-cover and pictures keep covr.
+func keptIlstChildren(data []byte, keep []string) []ilstChild {
+	chain := findMP4Path(data, 0, len(data), "moov", "udta", "meta", "ilst")
+	if chain == nil || len(keep) == 0 {
+		return nil
+	}
+	var kept []ilstChild
+	for _, c := range parseIlstChildren(data[chain[3].body:chain[3].end]) {
+		for _, k := range keep {
+			cover := c.typ == "covr" && (strings.EqualFold(k, "front-cover") || strings.EqualFold(k, "pictures"))
+			if cover || c.matches(strings.TrimPrefix(atomName(k), "----:")) {
+				kept = append(kept, c)
+				break
+			}
+		}
 	}
-	size := int(binary.BigEndian.Uint32(data[idx-4 : idx]))
-	if idx-4+size > len(data) {
-		return data
+	return kept
+}
+
+// keepXMP reports whether --keep xmp asked for the XMP uuid box to stay.
+func keepXMP(opts core.StripOptions) bool {
+	for _, k := range opts.KeepFields {
+		if strings.EqualFold(k, "xmp") {
+			return true
+		}
 	}
-	result := make([]byte, 0, len(data)-size)
-	result = append(result, data[:idx-4]...)
-	result = append(result, data[idx-4+size:]...)
-	// Update parent atom size
-	return result
+	return false
 }
 
 // ─── Helpers ─────────────────────────────────────────────────────────────────
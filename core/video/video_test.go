@@ -0,0 +1,25 @@
+package video
+
+import (
+	"testing"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+)
+
+// A moov/udta/meta box with a 2-byte payload, shorter than the version
+// and flags of an ISO meta box, is skipped rather than sliced.
+func TestViewMP4ShortMeta(t *testing.T) {
+	m, err := New(core.FmtMP4).View("testdata/short-meta.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var brand string
+	for _, f := range m.Fields {
+		if f.Key == "Brand" {
+			brand = f.Value
+		}
+	}
+	if brand != "isom" {
+		t.Errorf("Brand = %q, want %q", brand, "isom")
+	}
+}
@@ -0,0 +1,77 @@
+package video
+
+import (
+	"bytes"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/xmp"
+)
+
+// ─── XMP in MP4 / MOV ────────────────────────────────────────────────────────
+// Adobe tools store an XMP packet in a uuid box whose 16-byte extended type
+// is BE7ACFCB-97A9-42E8-9C71-999491E3AFAC. It usually sits at the top level
+// (after moov) but some writers put it inside moov or moov/udta.
+
+var xmpUUID = []byte{
+	0xBE, 0x7A, 0xCF, 0xCB, 0x97, 0xA9, 0x42, 0xE8,
+	0x9C, 0x71, 0x99, 0x94, 0x91, 0xE3, 0xAF, 0xAC,
+}
+
+// isXMPUUID reports whether a uuid box payload starts with the XMP UUID.
+func isXMPUUID(payload []byte) bool {
+	return len(payload) >= 16 && bytes.Equal(payload[:16], xmpUUID)
+}
+
+// parseXMPIntoMP4 adds the properties of an XMP packet as read-only
+// fields, named xmp:prefix:name as the image formats name them.
+func parseXMPIntoMP4(data []byte, m *core.Metadata) {
+	for _, it := range xmp.Properties(data) {
+		m.Fields = append(m.Fields, core.MetaField{
+			Key:      "xmp:" + it.Key(),
+			Value:    it.Value,
+			Category: "XMP",
+			Editable: false,
+		})
+	}
+}
+
+// findMP4XMP returns the chain (outermost first) leading to the first XMP
+// uuid box at the top level, in moov, or in moov/udta.
+func findMP4XMP(data []byte) ([]mp4Span, bool) {
+	var search func(start, end int, parents []mp4Span) ([]mp4Span, bool)
+	search = func(start, end int, parents []mp4Span) ([]mp4Span, bool) {
+		for _, b := range mp4ChildSpans(data, start, end) {
+			switch {
+			case b.typ == "uuid" && isXMPUUID(data[b.body:b.end]):
+				return append(parents, b), true
+			case b.typ == "moov" && len(parents) == 0,
+				b.typ == "udta" && len(parents) == 1:
+				if chain, ok := search(b.body, b.end, append(parents, b)); ok {
+					return chain, true
+				}
+			}
+		}
+		return nil, false
+	}
+	return search(0, len(data), nil)
+}
+
+// stripMP4XMP removes every XMP uuid box. Removing a top-level box in front
+// of mdat moves the media data, so chunk offsets are shifted to match.
+func stripMP4XMP(data []byte) []byte {
+	for {
+		chain, ok := findMP4XMP(data)
+		if !ok {
+			return data
+		}
+		b := chain[len(chain)-1]
+		parents := chain[:len(chain)-1]
+		out := replaceMP4Range(data, parents, b.start, b.end, nil)
+		if len(parents) == 0 {
+			if moov := findMP4Path(out, 0, len(out), "moov"); moov != nil {
+				shiftMP4ChunkOffsets(out, moov[0], b.start, b.start-b.end)
+			}
+		}
+		data = out
+	}
+}
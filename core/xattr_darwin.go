@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bytes"
+	"syscall"
+	"unsafe"
+)
+
+// The darwin syscall package has no extended attribute calls, so these go
+// through Syscall6 with the listxattr(2) family's signatures.
+
+func listXattrs(path string) ([]string, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(p)), 0, 0, 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, n)
+	n, _, errno = syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	var names []string
+	for _, name := range bytes.Split(buf[:n], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	nm, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(nm)), 0, 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	buf := make([]byte, n)
+	n, _, errno = syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(nm)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return buf[:n], nil
+}
+
+func setXattr(path, name string, value []byte) error {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	nm, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var v uintptr
+	if len(value) > 0 {
+		v = uintptr(unsafe.Pointer(&value[0]))
+	}
+	if _, _, errno := syscall.Syscall6(syscall.SYS_SETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(nm)), v, uintptr(len(value)), 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func removeXattr(path, name string) error {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	nm, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_REMOVEXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(nm)), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
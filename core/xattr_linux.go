@@ -0,0 +1,49 @@
+package core
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// listXattrs returns the names of the extended attributes of path.
+func listXattrs(path string) ([]string, error) {
+	n, err := syscall.Listxattr(path, nil)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if n, err = syscall.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+	return splitXattrNames(buf[:n]), nil
+}
+
+// getXattr returns the value of the extended attribute name of path.
+func getXattr(path, name string) ([]byte, error) {
+	n, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	n, err = syscall.Getxattr(path, name, buf)
+	return buf[:n], err
+}
+
+func setXattr(path, name string, value []byte) error {
+	return syscall.Setxattr(path, name, value, 0)
+}
+
+func removeXattr(path, name string) error {
+	return syscall.Removexattr(path, name)
+}
+
+// splitXattrNames splits a NUL-separated list of attribute names.
+func splitXattrNames(buf []byte) []string {
+	var names []string
+	for _, name := range bytes.Split(buf, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !windows
+
+package core
+
+import "errors"
+
+// Other platforms have no extended attribute calls in the standard
+// library; files there have no filesystem metadata to show.
+
+func listXattrs(path string) ([]string, error) { return nil, nil }
+
+func getXattr(path, name string) ([]byte, error) {
+	return nil, errors.New("extended attributes are not supported on this platform")
+}
+
+func setXattr(path, name string, value []byte) error {
+	return errors.New("extended attributes are not supported on this platform")
+}
+
+func removeXattr(path, name string) error {
+	return errors.New("extended attributes are not supported on this platform")
+}
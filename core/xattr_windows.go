@@ -0,0 +1,71 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// On Windows the metadata beside a file is kept in NTFS alternate data
+// streams, opened as "file:stream". Browsers and mail clients write the
+// Zone.Identifier stream (the "mark of the web") to downloads; other
+// programs leave thumbnails, summary information or their own records.
+// The streams are listed with FindFirstStreamW and FindNextStreamW.
+
+var (
+	kernel32            = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStream = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStream  = kernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+// listXattrs returns the names of the alternate data streams of path,
+// without the ":$DATA" type suffix. The unnamed main stream is left out.
+func listXattrs(path string) ([]string, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	if procFindFirstStream.Find() != nil {
+		return nil, nil // before Windows Vista
+	}
+	var data win32FindStreamData
+	h, _, errno := procFindFirstStream.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		if errno == syscall.ERROR_HANDLE_EOF {
+			return nil, nil // no streams, e.g. on FAT
+		}
+		return nil, errno
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+
+	var names []string
+	for {
+		name := syscall.UTF16ToString(data.StreamName[:])
+		if s, ok := strings.CutSuffix(name, ":$DATA"); ok && s != ":" {
+			names = append(names, strings.TrimPrefix(s, ":"))
+		}
+		r, _, _ := procFindNextStream.Call(h, uintptr(unsafe.Pointer(&data)))
+		if r == 0 {
+			return names, nil
+		}
+	}
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	return os.ReadFile(path + ":" + name)
+}
+
+func setXattr(path, name string, value []byte) error {
+	return os.WriteFile(path+":"+name, value, 0644)
+}
+
+func removeXattr(path, name string) error {
+	return os.Remove(path + ":" + name)
+}
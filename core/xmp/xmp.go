@@ -0,0 +1,420 @@
+// Package xmp reads and writes the properties of an XMP packet in place,
+// leaving the rest of the packet as it was.
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ─── XMP packets ─────────────────────────────────────────────────────────────
+// An XMP packet is RDF/XML wrapped in <?xpacket?> processing instructions.
+// Rather than decode the packet into a model and serialise it again, which
+// would lose whatever a model does not know about, a property is written
+// by splicing the packet text: its old element or attribute is removed and
+// a new element added to the first rdf:Description. A property is named
+// "prefix:local" with one of the prefixes in Namespaces, and written as
+// plain text or as the rdf:Bag, rdf:Seq or rdf:Alt the XMP specification
+// gives it.
+//
+// Writers leave whitespace before the closing <?xpacket end?> so that a
+// packet can grow without moving the bytes after it. Pad keeps a packet at
+// its old length when the edit fits in the padding and gives it fresh
+// padding when it does not, or had none.
+
+// nsMeta is the namespace of the x:xmpmeta wrapper.
+const nsMeta = "adobe:ns:meta/"
+
+const (
+	NSRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NSDC      = "http://purl.org/dc/elements/1.1/"
+	NSLR      = "http://ns.adobe.com/lightroom/1.0/"
+	NSSurgery = "https://github.com/ankit-chaubey/media-metadata-surgery/ns/1.0/"
+)
+
+// Padding is the whitespace given to a new packet, or to one whose edit no
+// longer fits in its old length: the 2 KB the XMP specification suggests.
+const Padding = 2048
+
+// Namespaces maps the prefixes properties can be named with to their URIs.
+var Namespaces = map[string]string{
+	"dc":           NSDC,
+	"xmp":          "http://ns.adobe.com/xap/1.0/",
+	"xmpRights":    "http://ns.adobe.com/xap/1.0/rights/",
+	"xmpMM":        "http://ns.adobe.com/xap/1.0/mm/",
+	"photoshop":    "http://ns.adobe.com/photoshop/1.0/",
+	"Iptc4xmpCore": "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/",
+	"Iptc4xmpExt":  "http://iptc.org/std/Iptc4xmpExt/2008-02-29/",
+	"plus":         "http://ns.useplus.org/ldf/xmp/1.0/",
+	"tiff":         "http://ns.adobe.com/tiff/1.0/",
+	"exif":         "http://ns.adobe.com/exif/1.0/",
+	"aux":          "http://ns.adobe.com/exif/1.0/aux/",
+	"lr":           NSLR,
+	"surgery":      NSSurgery,
+}
+
+// Kind is the form of a property's value.
+type Kind int
+
+const (
+	Text Kind = iota // a simple value
+	Bag              // an unordered array (rdf:Bag)
+	Seq              // an ordered array (rdf:Seq)
+	Alt              // language alternatives (rdf:Alt), written as x-default
+)
+
+// arrays gives the kind of the standard properties that are not text.
+var arrays = map[string]Kind{
+	"dc:contributor":                   Bag,
+	"dc:creator":                       Seq,
+	"dc:date":                          Seq,
+	"dc:description":                   Alt,
+	"dc:language":                      Bag,
+	"dc:publisher":                     Bag,
+	"dc:relation":                      Bag,
+	"dc:rights":                        Alt,
+	"dc:subject":                       Bag,
+	"dc:title":                         Alt,
+	"dc:type":                          Bag,
+	"xmp:Identifier":                   Bag,
+	"xmpRights:Owner":                  Bag,
+	"xmpRights:UsageTerms":             Alt,
+	"photoshop:SupplementalCategories": Bag,
+	"Iptc4xmpCore:Scene":               Bag,
+	"Iptc4xmpCore:SubjectCode":         Bag,
+	"Iptc4xmpExt:PersonInImage":        Bag,
+	"lr:hierarchicalSubject":           Bag,
+}
+
+// Property names one XMP property.
+type Property struct {
+	Prefix string
+	Local  string
+	NS     string
+	Kind   Kind
+}
+
+// Name returns the property as "prefix:local".
+func (p Property) Name() string { return p.Prefix + ":" + p.Local }
+
+// Split turns an edit value into the items Set takes: a "; "-separated
+// list for a Bag or Seq, the whole value otherwise.
+func (p Property) Split(v string) []string {
+	if p.Kind != Bag && p.Kind != Seq {
+		if v = strings.TrimSpace(v); v == "" {
+			return nil
+		}
+		return []string{v}
+	}
+	var out []string
+	for _, it := range strings.Split(v, ";") {
+		if it = strings.TrimSpace(it); it != "" {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// Lookup returns the property called name ("dc:creator"). The prefix is
+// matched without regard to case; the local name is kept as given.
+func Lookup(name string) (Property, error) {
+	prefix, local, ok := strings.Cut(name, ":")
+	if !ok || local == "" || strings.ContainsAny(local, " :<>\"'&/") {
+		return Property{}, fmt.Errorf("XMP property %q: expected prefix:name, such as dc:creator", name)
+	}
+	for p, ns := range Namespaces {
+		if strings.EqualFold(p, prefix) {
+			prop := Property{Prefix: p, Local: local, NS: ns}
+			prop.Kind = arrays[prop.Name()]
+			return prop, nil
+		}
+	}
+	known := make([]string, 0, len(Namespaces))
+	for p := range Namespaces {
+		known = append(known, p)
+	}
+	sort.Strings(known)
+	return Property{}, fmt.Errorf("XMP property %q: unknown namespace prefix %q (known: %s)", name, prefix, strings.Join(known, ", "))
+}
+
+// Prefix returns the prefix of the namespace URI ns, "" when it is not
+// one of Namespaces.
+func Prefix(ns string) string {
+	for p, uri := range Namespaces {
+		if uri == ns {
+			return p
+		}
+	}
+	return ""
+}
+
+const empty = `<?xpacket begin="` + "\uFEFF" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="">
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// New returns a packet with no properties and no padding.
+func New() []byte { return []byte(empty) }
+
+// Values returns the value of p in packet: the items of an array, or the
+// one value of a text property, whether written as an element or as an
+// attribute of rdf:Description.
+func Values(packet []byte, p Property) []string {
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	var items []string
+	depth := 0 // inside the property element
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return items
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+				continue
+			}
+			if t.Name.Space == p.NS && t.Name.Local == p.Local {
+				depth = 1
+				continue
+			}
+			if t.Name.Space == NSRDF && t.Name.Local == "Description" {
+				for _, a := range t.Attr {
+					if a.Name.Space == p.NS && a.Name.Local == p.Local && a.Value != "" {
+						items = append(items, a.Value)
+					}
+				}
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+			}
+		case xml.CharData:
+			if depth > 0 {
+				if v := strings.TrimSpace(string(t)); v != "" {
+					items = append(items, v)
+				}
+			}
+		}
+	}
+}
+
+// Item is one property of a packet, as Properties reads it.
+type Item struct {
+	Name  xml.Name // namespace URI and local name
+	Value string   // an array's items joined with "; "
+	Top   bool     // a property of rdf:Description, not a field of a structure
+}
+
+// Key names the item as edit takes it, "prefix:local", for the namespaces
+// of Namespaces, and by its local name otherwise.
+func (it Item) Key() string {
+	if prefix := Prefix(it.Name.Space); prefix != "" {
+		return prefix + ":" + it.Name.Local
+	}
+	return it.Name.Local
+}
+
+// Properties returns the properties of packet in document order: each
+// child element and attribute of an rdf:Description, an array as its items
+// joined with "; ", and each field of a structure on its own.
+func Properties(packet []byte) []Item {
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	var out []Item
+	var prop *xml.Name // property element being read
+	var items []string
+	depth := 0 // elements open inside prop
+	var current xml.Name
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return out
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			current = t.Name
+			top := prop == nil
+			switch {
+			case !top:
+				depth++
+			case t.Name.Space != NSRDF && t.Name.Space != nsMeta:
+				name := t.Name
+				prop, items, depth = &name, nil, 0
+			}
+			isDesc := t.Name.Space == NSRDF && t.Name.Local == "Description"
+			for _, a := range t.Attr {
+				switch a.Name.Space {
+				case "xmlns", NSRDF, nsMeta, "http://www.w3.org/XML/1998/namespace":
+					continue
+				}
+				if a.Name.Local == "xmlns" || a.Value == "" {
+					continue
+				}
+				out = append(out, Item{Name: a.Name, Value: a.Value, Top: top && isDesc})
+			}
+		case xml.EndElement:
+			if prop == nil {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if len(items) > 0 {
+				out = append(out, Item{Name: *prop, Value: strings.Join(items, "; "), Top: true})
+			}
+			prop = nil
+		case xml.CharData:
+			v := strings.TrimSpace(string(t))
+			if v == "" || prop == nil {
+				continue
+			}
+			if depth == 0 || depth == 2 && current.Space == NSRDF && current.Local == "li" {
+				items = append(items, v) // the property itself, or an array item
+			} else {
+				out = append(out, Item{Name: current, Value: v}) // a structure field
+			}
+		}
+	}
+}
+
+var descOpenRe = regexp.MustCompile(`<rdf:Description\b[^>]*?(/?)>`)
+
+// Set removes p from packet (a new packet when empty) and, when items is
+// not empty, adds it back with those values to the first rdf:Description,
+// declaring the namespace there if the packet does not already.
+func Set(packet []byte, p Property, items []string) []byte {
+	if len(packet) == 0 {
+		packet = New()
+	}
+	name := regexp.QuoteMeta(p.Name())
+	elemRe := regexp.MustCompile(`(?s)\s*<` + name + `\b[^>]*?(?:/>|>.*?</` + name + `>)`)
+	packet = elemRe.ReplaceAll(packet, nil)
+	attrRe := regexp.MustCompile(`\s+` + name + `\s*=\s*(?:"[^"]*"|'[^']*')`)
+	packet = descOpenRe.ReplaceAllFunc(packet, func(tag []byte) []byte {
+		return attrRe.ReplaceAll(tag, nil)
+	})
+	if len(items) == 0 {
+		return packet
+	}
+
+	var el bytes.Buffer
+	switch p.Kind {
+	case Bag, Seq:
+		container := "rdf:Bag"
+		if p.Kind == Seq {
+			container = "rdf:Seq"
+		}
+		fmt.Fprintf(&el, "\n   <%s>\n    <%s>\n", p.Name(), container)
+		for _, it := range items {
+			el.WriteString("     <rdf:li>")
+			xml.EscapeText(&el, []byte(it))
+			el.WriteString("</rdf:li>\n")
+		}
+		fmt.Fprintf(&el, "    </%s>\n   </%s>", container, p.Name())
+	case Alt:
+		fmt.Fprintf(&el, "\n   <%s>\n    <rdf:Alt>\n     <rdf:li xml:lang=\"x-default\">", p.Name())
+		xml.EscapeText(&el, []byte(items[0]))
+		fmt.Fprintf(&el, "</rdf:li>\n    </rdf:Alt>\n   </%s>", p.Name())
+	default:
+		fmt.Fprintf(&el, "\n   <%s>", p.Name())
+		xml.EscapeText(&el, []byte(items[0]))
+		fmt.Fprintf(&el, "</%s>", p.Name())
+	}
+
+	loc := descOpenRe.FindSubmatchIndex(packet)
+	if loc == nil {
+		return packet
+	}
+	open := string(packet[loc[0]:loc[1]])
+	selfClosing := loc[3] > loc[2]
+	if selfClosing {
+		open = strings.TrimSuffix(open, "/>") + ">"
+	}
+	if !bytes.Contains(packet, []byte("xmlns:"+p.Prefix+"=")) {
+		open = strings.TrimSuffix(open, ">") + fmt.Sprintf(` xmlns:%s="%s">`, p.Prefix, p.NS)
+	}
+	var out bytes.Buffer
+	out.Write(packet[:loc[0]])
+	out.WriteString(open)
+	out.Write(el.Bytes())
+	if selfClosing {
+		out.WriteString("\n  </rdf:Description>")
+	}
+	out.Write(packet[loc[1]:])
+	return out.Bytes()
+}
+
+// RemoveAll removes every property of the namespace ns whose local name
+// starts with local ("GPS" for the exif:GPS* location properties), under
+// whichever prefix the packet declares for ns, and returns how many it
+// removed.
+func RemoveAll(packet []byte, ns, local string) ([]byte, int) {
+	declRe := regexp.MustCompile(`xmlns:([A-Za-z][\w.-]*)\s*=\s*["']` + regexp.QuoteMeta(ns) + `["']`)
+	seen := make(map[string]bool)
+	for _, d := range declRe.FindAllSubmatch(packet, -1) {
+		prefix := string(d[1])
+		nameRe := regexp.MustCompile(`[<\s]` + regexp.QuoteMeta(prefix+":") + `(` + regexp.QuoteMeta(local) + `[\w.-]*)`)
+		for _, m := range nameRe.FindAllSubmatch(packet, -1) {
+			name := prefix + ":" + string(m[1])
+			if !seen[name] {
+				seen[name] = true
+				packet = Set(packet, Property{Prefix: prefix, Local: string(m[1]), NS: ns}, nil)
+			}
+		}
+	}
+	return packet, len(seen)
+}
+
+// ─── Padding ─────────────────────────────────────────────────────────────────
+
+var (
+	paddingRe   = regexp.MustCompile(`(</x:xmpmeta>)\s{2,}(<\?xpacket end=)`)
+	packetEndRe = regexp.MustCompile(`<\?xpacket end=`)
+)
+
+// TrimPadding removes the padding of a packet.
+func TrimPadding(packet []byte) []byte {
+	return paddingRe.ReplaceAll(packet, []byte("$1\n$2"))
+}
+
+// Repad pads packet, an edited copy of old, to the length of old when old
+// was padded and the edit fits, so the packet is rewritten in place; with
+// Padding bytes otherwise. A new packet has no old.
+func Repad(packet, old []byte) []byte {
+	size := len(old)
+	if len(TrimPadding(old)) == len(old) {
+		size = 0
+	}
+	return Pad(packet, size)
+}
+
+// Pad returns packet padded to size bytes, or with Padding bytes of
+// padding when it does not fit in size. A packet without a closing
+// <?xpacket end?> is returned as it is.
+func Pad(packet []byte, size int) []byte {
+	packet = TrimPadding(packet)
+	loc := packetEndRe.FindIndex(packet)
+	if loc == nil {
+		return packet
+	}
+	n := size - len(packet)
+	if n < 0 {
+		n = Padding
+	}
+	// Lines of 100 bytes, as Adobe writes them.
+	pad := bytes.Repeat([]byte(strings.Repeat(" ", 99)+"\n"), n/100)
+	pad = append(pad, bytes.Repeat([]byte(" "), n%100)...)
+	out := make([]byte, 0, len(packet)+n)
+	out = append(out, packet[:loc[0]]...)
+	out = append(out, pad...)
+	return append(out, packet[loc[0]:]...)
+}
@@ -6,6 +6,5 @@ require (
 	github.com/bogem/id3v2/v2 v2.1.4
 	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
 	github.com/rwcarlsen/goexif v0.0.0-20190401172101-9e8deecbddbd
+	golang.org/x/text v0.14.0
 )
-
-require golang.org/x/text v0.14.0 // indirect
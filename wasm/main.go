@@ -0,0 +1,124 @@
+//go:build js && wasm
+
+// Command wasm is the WebAssembly build of surgery for browsers. It
+// registers a global "surgery" object whose functions view, edit and strip
+// work on Uint8Arrays through core.MemoryHandler, so no file system is
+// needed; surgery.js wraps it in a promise-based module. Build it with
+// "make wasm".
+//
+// Failures are returned as Error values rather than thrown, since a Go
+// callback cannot throw; the wrapper rethrows them.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/ankit-chaubey/media-metadata-surgery/core"
+	"github.com/ankit-chaubey/media-metadata-surgery/core/batch"
+)
+
+func main() {
+	js.Global().Set("surgery", js.ValueOf(map[string]any{
+		"view":  js.FuncOf(view),
+		"edit":  js.FuncOf(edit),
+		"strip": js.FuncOf(strip),
+	}))
+	select {} // keep the functions callable
+}
+
+// view(bytes) returns the metadata as the JSON document view --json prints.
+func view(this js.Value, args []js.Value) any {
+	data, h, err := input(args)
+	if err != nil {
+		return jsError(err)
+	}
+	m, err := h.ViewBytes(data)
+	if err != nil {
+		return jsError(err)
+	}
+	return string(core.MetadataJSON(m))
+}
+
+// edit(bytes, set, delete) returns the file with the fields of the object
+// set written and the keys in the array delete removed.
+func edit(this js.Value, args []js.Value) any {
+	data, h, err := input(args)
+	if err != nil {
+		return jsError(err)
+	}
+	opts := core.EditOptions{Set: map[string]string{}}
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		keys := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < keys.Length(); i++ {
+			k := keys.Index(i).String()
+			opts.Set[k] = args[1].Get(k).String()
+		}
+	}
+	if len(args) > 2 {
+		opts.Delete = stringList(args[2])
+	}
+	out, err := h.EditBytes(data, core.NormalizeValues(opts))
+	if err != nil {
+		return jsError(err)
+	}
+	return output(out)
+}
+
+// strip(bytes, {keep, gpsOnly, regionsOnly}) returns the file without its
+// metadata; the options mirror the strip command's flags.
+func strip(this js.Value, args []js.Value) any {
+	data, h, err := input(args)
+	if err != nil {
+		return jsError(err)
+	}
+	var opts core.StripOptions
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		o := args[1]
+		opts.KeepFields = stringList(o.Get("keep"))
+		opts.StripGPS = o.Get("gpsOnly").Truthy()
+		opts.StripRegions = o.Get("regionsOnly").Truthy()
+	}
+	opts.StripAll = len(opts.KeepFields) == 0 && !opts.StripGPS && !opts.StripRegions
+	out, err := h.StripBytes(data, opts)
+	if err != nil {
+		return jsError(err)
+	}
+	return output(out)
+}
+
+// input copies the Uint8Array in args[0] and finds its handler.
+func input(args []js.Value) ([]byte, core.MemoryHandler, error) {
+	if len(args) < 1 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, nil, errString("expected a Uint8Array")
+	}
+	data := make([]byte, args[0].Length())
+	js.CopyBytesToGo(data, args[0])
+	h, err := batch.HandlerForBytes(data)
+	return data, h, err
+}
+
+func output(data []byte) js.Value {
+	a := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(a, data)
+	return a
+}
+
+// stringList converts a JS array of strings; undefined gives nil.
+func stringList(v js.Value) []string {
+	if v.Type() != js.TypeObject {
+		return nil
+	}
+	out := make([]string, v.Length())
+	for i := range out {
+		out[i] = v.Index(i).String()
+	}
+	return out
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
+
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
@@ -0,0 +1,29 @@
+// Media Metadata Surgery in the browser: view, edit and strip JPEG, PNG,
+// GIF and WebP files held in Uint8Arrays, without uploading them.
+//
+//   import { load } from "./surgery.js";
+//   const surgery = await load();
+//   const clean = surgery.strip(new Uint8Array(await file.arrayBuffer()));
+//
+// Needs surgery.wasm and Go's wasm_exec.js next to this file ("make wasm").
+
+import "./wasm_exec.js";
+
+export async function load(url = new URL("surgery.wasm", import.meta.url)) {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  const api = globalThis.surgery;
+  const check = (r) => {
+    if (r instanceof Error) throw r;
+    return r;
+  };
+  return {
+    // view(bytes) → { file, format, fields: [{ key, value, category, editable }] }
+    view: (bytes) => JSON.parse(check(api.view(bytes))),
+    // edit(bytes, { Artist: "Jane" }, ["Software"]) → Uint8Array
+    edit: (bytes, set = {}, del = []) => check(api.edit(bytes, set, del)),
+    // strip(bytes, { keep: ["exif"], gpsOnly: false, regionsOnly: false }) → Uint8Array
+    strip: (bytes, opts = {}) => check(api.strip(bytes, opts)),
+  };
+}